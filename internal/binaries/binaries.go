@@ -0,0 +1,553 @@
+// Package binaries installs tools from GitHub release assets. Assets are
+// downloaded, verified against a sha256 checksum and, when configured, a
+// minisign or cosign signature, extracted to a versioned
+// directory below [core.BinariesDir], and symlinked to their install path.
+// The symlink target records which tag is installed.
+package binaries
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/pkgs/atomicwrite"
+)
+
+// ErrUnmanaged is returned when a binary's install path exists and is not a
+// symlink created by mmdot.
+var ErrUnmanaged = errors.New("install path exists and is not managed by mmdot")
+
+// Release is a GitHub release.
+type Release struct {
+	Tag    string  `json:"tag_name"`
+	Assets []Asset `json:"assets"`
+}
+
+// Asset is a file attached to a release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Client resolves and installs release assets for the current platform.
+type Client struct {
+	HTTP   *http.Client
+	APIURL string // GitHub API base URL
+	Token  string // sent to GitHub to raise the rate limit
+	Dir    string // root of the versioned install directories
+	OS     string
+	Arch   string
+
+	// TrustedKeys are the keys signatures are verified with.
+	TrustedKeys []core.TrustedKey
+
+	// RequireSignature fails installs of binaries without a signature.
+	RequireSignature bool
+}
+
+// Verification is how an installed asset was verified.
+type Verification int
+
+const (
+	Unverified Verification = iota
+	ChecksumVerified
+	SignatureVerified
+)
+
+// NewClient returns a Client installing below dir. GITHUB_TOKEN or GH_TOKEN
+// is used for API requests when set.
+func NewClient(dir string) *Client {
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		token = os.Getenv("GH_TOKEN")
+	}
+
+	return &Client{
+		HTTP:   http.DefaultClient,
+		APIURL: "https://api.github.com",
+		Token:  token,
+		Dir:    dir,
+		OS:     runtime.GOOS,
+		Arch:   runtime.GOARCH,
+	}
+}
+
+// Release fetches the release b.Version refers to.
+func (c *Client) Release(ctx context.Context, b core.Binary) (Release, error) {
+	endpoint := fmt.Sprintf("%s/repos/%s/releases/tags/%s", c.APIURL, b.Repo, url.PathEscape(b.Version))
+	if b.Version == core.BinaryLatest {
+		endpoint = fmt.Sprintf("%s/repos/%s/releases/latest", c.APIURL, b.Repo)
+	}
+
+	resp, err := c.get(ctx, endpoint)
+	if err != nil {
+		return Release{}, fmt.Errorf("%s: %w", b.Repo, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var rel Release
+	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
+		return Release{}, fmt.Errorf("%s: failed to decode release: %w", b.Repo, err)
+	}
+	return rel, nil
+}
+
+// Status reports the installed and wanted tag of a binary.
+type Status struct {
+	Installed string // installed tag, empty when not installed
+	Wanted    string // configured tag, resolved when "latest"
+	Unmanaged bool   // install path is occupied by something else
+}
+
+// Current reports whether the wanted tag is installed.
+func (s Status) Current() bool {
+	return s.Installed != "" && s.Installed == s.Wanted
+}
+
+// Status compares the installed tag with the wanted one. The GitHub API is
+// only queried for binaries tracking the latest release.
+func (c *Client) Status(ctx context.Context, b core.Binary) (Status, error) {
+	installed, err := c.Installed(b)
+	st := Status{Installed: installed, Wanted: b.Version, Unmanaged: errors.Is(err, ErrUnmanaged)}
+	if err != nil && !st.Unmanaged {
+		return st, err
+	}
+
+	if b.Version == core.BinaryLatest {
+		rel, err := c.Release(ctx, b)
+		if err != nil {
+			return st, err
+		}
+		st.Wanted = rel.Tag
+	}
+	return st, nil
+}
+
+// Installed returns the tag the install symlink points to, or "" when the
+// binary is not installed.
+func (c *Client) Installed(b core.Binary) (string, error) {
+	target, err := os.Readlink(b.Install)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		if _, statErr := os.Lstat(b.Install); statErr == nil {
+			return "", fmt.Errorf("%s: %w", b.Install, ErrUnmanaged)
+		}
+		return "", err
+	}
+
+	rel, err := filepath.Rel(filepath.Join(c.Dir, b.Name), target)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("%s: %w", b.Install, ErrUnmanaged)
+	}
+	tag, _, _ := strings.Cut(filepath.ToSlash(rel), "/")
+	return tag, nil
+}
+
+// Unused returns the version directories of the configured binaries that
+// their install symlink does not point to. Binaries that are not installed
+// or whose install path is unmanaged are left alone.
+func (c *Client) Unused(bins []core.Binary) ([]string, error) {
+	var unused []string
+	for _, b := range bins {
+		tag, err := c.Installed(b)
+		if err != nil {
+			if errors.Is(err, ErrUnmanaged) {
+				continue
+			}
+			return nil, err
+		}
+		if tag == "" {
+			continue
+		}
+
+		entries, err := os.ReadDir(filepath.Join(c.Dir, b.Name))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		for _, e := range entries {
+			if e.IsDir() && e.Name() != tag {
+				unused = append(unused, filepath.Join(c.Dir, b.Name, e.Name()))
+			}
+		}
+	}
+	return unused, nil
+}
+
+// Install downloads the release asset for the current platform, verifies it,
+// extracts the binary, and points the install symlink at it. It returns how
+// the asset was verified.
+func (c *Client) Install(ctx context.Context, b core.Binary, rel Release) (Verification, error) {
+	if _, err := c.Installed(b); err != nil {
+		return Unverified, err
+	}
+	if c.RequireSignature && b.Signature == nil {
+		return Unverified, fmt.Errorf("binary %s: no signature configured and signatures are required", b.Name)
+	}
+
+	asset, err := c.SelectAsset(b, rel)
+	if err != nil {
+		return Unverified, err
+	}
+
+	data, err := c.download(ctx, asset.URL)
+	if err != nil {
+		return Unverified, fmt.Errorf("failed to download %s: %w", asset.Name, err)
+	}
+
+	verified, err := c.verify(ctx, b, rel, asset, data)
+	if err != nil {
+		return Unverified, err
+	}
+
+	bin, err := extract(asset.Name, data, b.ArchivePath())
+	if err != nil {
+		return Unverified, fmt.Errorf("%s: %w", asset.Name, err)
+	}
+
+	dir := filepath.Join(c.Dir, b.Name, rel.Tag)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return Unverified, err
+	}
+	target := filepath.Join(dir, b.Name)
+	if err := atomicwrite.WriteFile(target, bin, 0o755); err != nil {
+		return Unverified, err
+	}
+
+	return verified, link(target, b.Install)
+}
+
+// SelectAsset returns the release asset matching b.Asset, or the one naming
+// the current OS and architecture when no pattern is configured.
+func (c *Client) SelectAsset(b core.Binary, rel Release) (Asset, error) {
+	names := make([]string, len(rel.Assets))
+	for i, a := range rel.Assets {
+		names[i] = a.Name
+	}
+
+	if b.Asset != "" {
+		pattern, err := c.assetPattern(b.Asset, rel.Tag)
+		if err != nil {
+			return Asset{}, fmt.Errorf("binary %s: invalid asset pattern: %w", b.Name, err)
+		}
+		for _, a := range rel.Assets {
+			if ok, _ := path.Match(pattern, a.Name); ok {
+				return a, nil
+			}
+		}
+		return Asset{}, fmt.Errorf("binary %s: no asset of %s matches %q (assets: %s)", b.Name, rel.Tag, pattern, strings.Join(names, ", "))
+	}
+
+	var candidates []Asset
+	for _, a := range rel.Assets {
+		lower := strings.ToLower(a.Name)
+		if hasAny(lower, osAliases[c.OS]) && hasAny(lower, archAliases[c.Arch]) && !hasSuffix(lower, ignoredSuffixes) {
+			candidates = append(candidates, a)
+		}
+	}
+	if len(candidates) == 0 {
+		return Asset{}, fmt.Errorf("binary %s: no asset of %s for %s/%s; set asset to pick one (assets: %s)", b.Name, rel.Tag, c.OS, c.Arch, strings.Join(names, ", "))
+	}
+
+	slices.SortStableFunc(candidates, func(x, y Asset) int {
+		return formatRank(x.Name) - formatRank(y.Name)
+	})
+	return candidates[0], nil
+}
+
+func (c *Client) assetPattern(pattern, tag string) (string, error) {
+	tmpl, err := template.New("asset").Parse(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]string{
+		"OS":      c.OS,
+		"Arch":    c.Arch,
+		"Tag":     tag,
+		"Version": strings.TrimPrefix(tag, "v"),
+	})
+	return buf.String(), err
+}
+
+var (
+	osAliases = map[string][]string{
+		"linux":   {"linux"},
+		"darwin":  {"darwin", "macos", "apple"},
+		"windows": {"windows", "win64", "win32"},
+		"freebsd": {"freebsd"},
+	}
+	archAliases = map[string][]string{
+		"amd64": {"amd64", "x86_64", "x64"},
+		"arm64": {"arm64", "aarch64"},
+		"386":   {"386", "i386", "i686"},
+		"arm":   {"armv7", "armv6", "armhf"},
+	}
+	ignoredSuffixes = []string{".sha256", ".sha256sum", ".sig", ".asc", ".pem", ".sbom", ".json", ".txt", ".deb", ".rpm", ".apk", ".msi", ".pkg", ".dmg"}
+	formatOrder     = []string{".tar.gz", ".tgz", ".zip", ".tar.bz2", ".gz"}
+)
+
+// formatRank orders asset formats by preference; raw binaries rank last.
+func formatRank(name string) int {
+	for i, ext := range formatOrder {
+		if strings.HasSuffix(name, ext) {
+			return i
+		}
+	}
+	return len(formatOrder)
+}
+
+// verify checks data against the configured checksum, then the signature.
+func (c *Client) verify(ctx context.Context, b core.Binary, rel Release, asset Asset, data []byte) (Verification, error) {
+	var sums []byte
+	want := strings.TrimPrefix(b.Checksum, "sha256:")
+	if want == "" && b.ChecksumAsset != "" {
+		sumsAsset, err := findAsset(b, rel, b.ChecksumAsset)
+		if err != nil {
+			return Unverified, err
+		}
+
+		sums, err = c.download(ctx, sumsAsset.URL)
+		if err != nil {
+			return Unverified, fmt.Errorf("failed to download %s: %w", b.ChecksumAsset, err)
+		}
+		if want = lookupChecksum(sums, asset.Name); want == "" {
+			return Unverified, fmt.Errorf("binary %s: %s has no checksum for %s", b.Name, b.ChecksumAsset, asset.Name)
+		}
+	}
+
+	verified := Unverified
+	if want != "" {
+		sum := sha256.Sum256(data)
+		if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, want) {
+			return Unverified, fmt.Errorf("binary %s: checksum mismatch for %s: got %s, want %s", b.Name, asset.Name, got, want)
+		}
+		verified = ChecksumVerified
+	}
+
+	if b.Signature == nil {
+		return verified, nil
+	}
+	signed, signedName := data, asset.Name
+	if b.Signature.Signs == core.SignsChecksums {
+		signed, signedName = sums, b.ChecksumAsset
+	}
+	if err := c.verifySignature(ctx, b, rel, signedName, signed); err != nil {
+		return Unverified, err
+	}
+	return SignatureVerified, nil
+}
+
+// verifySignature checks the detached signature of the release file name,
+// whose contents are data.
+func (c *Client) verifySignature(ctx context.Context, b core.Binary, rel Release, name string, data []byte) error {
+	idx := slices.IndexFunc(c.TrustedKeys, func(k core.TrustedKey) bool { return k.Name == b.Signature.Key })
+	if idx == -1 {
+		return fmt.Errorf("binary %s: signature key %q is not in trusted_keys", b.Name, b.Signature.Key)
+	}
+	trusted := c.TrustedKeys[idx]
+	key, err := trusted.PublicKey()
+	if err != nil {
+		return fmt.Errorf("trusted key %s: %w", trusted.Name, err)
+	}
+
+	sigName := name + trusted.SignatureExt()
+	if b.Signature.Asset != "" {
+		if sigName, err = c.assetPattern(b.Signature.Asset, rel.Tag); err != nil {
+			return fmt.Errorf("binary %s: invalid signature asset: %w", b.Name, err)
+		}
+	}
+	sigAsset, err := findAsset(b, rel, sigName)
+	if err != nil {
+		return err
+	}
+	sig, err := c.download(ctx, sigAsset.URL)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", sigName, err)
+	}
+
+	if err := key.Verify(data, sig); err != nil {
+		return fmt.Errorf("binary %s: %s does not verify %s with key %s: %w", b.Name, sigName, name, trusted.Name, err)
+	}
+	return nil
+}
+
+// findAsset returns the release asset called name.
+func findAsset(b core.Binary, rel Release, name string) (Asset, error) {
+	idx := slices.IndexFunc(rel.Assets, func(a Asset) bool { return a.Name == name })
+	if idx == -1 {
+		return Asset{}, fmt.Errorf("binary %s: asset %q not found in %s", b.Name, name, rel.Tag)
+	}
+	return rel.Assets[idx], nil
+}
+
+// lookupChecksum finds name in sha256sum formatted output.
+func lookupChecksum(sums []byte, name string) string {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return fields[0]
+		}
+	}
+	return ""
+}
+
+func (c *Client) get(ctx context.Context, endpoint string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.Token != "" && c.sendToken(req.URL) {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", endpoint, resp.Status)
+	}
+	return resp, nil
+}
+
+// sendToken reports whether the token may be sent to u: only to the API host
+// and github.com, never to the CDN hosts downloads redirect to.
+func (c *Client) sendToken(u *url.URL) bool {
+	api, err := url.Parse(c.APIURL)
+	return (err == nil && u.Host == api.Host) || u.Host == "github.com"
+}
+
+func (c *Client) download(ctx context.Context, endpoint string) ([]byte, error) {
+	resp, err := c.get(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	return io.ReadAll(resp.Body)
+}
+
+// extract returns the file at member from the archive, or data itself when
+// the asset is not an archive.
+func extract(name string, data []byte, member string) ([]byte, error) {
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return extractTar(gz, member)
+	case strings.HasSuffix(name, ".tar.bz2"):
+		return extractTar(bzip2.NewReader(bytes.NewReader(data)), member)
+	case strings.HasSuffix(name, ".zip"):
+		return extractZip(data, member)
+	case strings.HasSuffix(name, ".gz"):
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(gz)
+	case strings.HasSuffix(name, ".tar.xz"), strings.HasSuffix(name, ".7z"):
+		return nil, fmt.Errorf("unsupported archive format")
+	}
+	return data, nil
+}
+
+// memberMatches reports whether an archive entry is member, allowing for a
+// leading directory such as "tool-1.0-linux-amd64/".
+func memberMatches(entry, member string) bool {
+	entry = strings.TrimPrefix(path.Clean(entry), "./")
+	return entry == member || strings.HasSuffix(entry, "/"+member)
+}
+
+func extractTar(r io.Reader, member string) ([]byte, error) {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%s not found in archive", member)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag == tar.TypeReg && memberMatches(hdr.Name, member) {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+func extractZip(data []byte, member string) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || !memberMatches(f.Name, member) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = rc.Close() }()
+		return io.ReadAll(rc)
+	}
+	return nil, fmt.Errorf("%s not found in archive", member)
+}
+
+// link points the symlink at install to target, replacing an existing
+// symlink.
+func link(target, install string) error {
+	if err := os.MkdirAll(filepath.Dir(install), 0o755); err != nil {
+		return err
+	}
+	if err := os.Remove(install); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return os.Symlink(target, install)
+}
+
+func hasAny(s string, subs []string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasSuffix(s string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(s, suffix) {
+			return true
+		}
+	}
+	return false
+}
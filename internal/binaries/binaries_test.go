@@ -0,0 +1,340 @@
+package binaries
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hay-kot/mmdot/internal/core"
+)
+
+func tarGz(t *testing.T, name string, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// signingKey signs the checksums.txt of fakeGitHub releases.
+var signingKey, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+// trustedKey returns the cosign public key of key as a trusted key.
+func trustedKey(t *testing.T, name string, key *ecdsa.PrivateKey) core.TrustedKey {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return core.TrustedKey{Name: name, Type: core.KeyCosign, Key: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))}
+}
+
+// fakeGitHub serves releases of owner/tool. Each tag has a linux/amd64
+// tarball holding tool-<tag>/tool, a checksums.txt, and its cosign signature
+// checksums.txt.sig made with signingKey.
+func fakeGitHub(t *testing.T, latest string, tags ...string) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	releases := map[string]Release{}
+	for _, tag := range tags {
+		asset := fmt.Sprintf("tool_%s_linux_amd64.tar.gz", strings.TrimPrefix(tag, "v"))
+		archive := tarGz(t, "tool-"+tag+"/tool", []byte("binary "+tag))
+		sum := sha256.Sum256(archive)
+		sums := fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), asset)
+		digest := sha256.Sum256([]byte(sums))
+		sig, err := ecdsa.SignASN1(rand.Reader, signingKey, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mux.HandleFunc("/download/"+tag+"/"+asset, func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write(archive) })
+		mux.HandleFunc("/download/"+tag+"/checksums.txt", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte(sums)) })
+		mux.HandleFunc("/download/"+tag+"/checksums.txt.sig", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(base64.StdEncoding.EncodeToString(sig)))
+		})
+
+		releases[tag] = Release{Tag: tag, Assets: []Asset{
+			{Name: "checksums.txt", URL: srv.URL + "/download/" + tag + "/checksums.txt"},
+			{Name: "checksums.txt.sig", URL: srv.URL + "/download/" + tag + "/checksums.txt.sig"},
+			{Name: "tool_" + strings.TrimPrefix(tag, "v") + "_darwin_arm64.tar.gz", URL: srv.URL + "/missing"},
+			{Name: asset, URL: srv.URL + "/download/" + tag + "/" + asset},
+		}}
+	}
+
+	mux.HandleFunc("/repos/owner/tool/releases/", func(w http.ResponseWriter, r *http.Request) {
+		tag := strings.TrimPrefix(r.URL.Path, "/repos/owner/tool/releases/tags/")
+		if r.URL.Path == "/repos/owner/tool/releases/latest" {
+			tag = latest
+		}
+		rel, ok := releases[tag]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(rel)
+	})
+
+	return srv
+}
+
+func newTestClient(t *testing.T, srv *httptest.Server) *Client {
+	c := NewClient(t.TempDir())
+	c.APIURL = srv.URL
+	c.Token = ""
+	c.OS, c.Arch = "linux", "amd64"
+	return c
+}
+
+func TestClient_InstallAndUpgrade(t *testing.T) {
+	srv := fakeGitHub(t, "v1.1.0", "v1.0.0", "v1.1.0")
+	c := newTestClient(t, srv)
+	ctx := context.Background()
+
+	b := core.Binary{
+		Name:          "tool",
+		Repo:          "owner/tool",
+		Version:       "v1.0.0",
+		ChecksumAsset: "checksums.txt",
+		Install:       filepath.Join(t.TempDir(), "bin", "tool"),
+	}
+
+	st, err := c.Status(ctx, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Installed != "" || st.Current() {
+		t.Fatalf("Status() before install = %+v", st)
+	}
+
+	rel, err := c.Release(ctx, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verified, err := c.Install(ctx, b, rel)
+	if err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if verified != ChecksumVerified {
+		t.Errorf("Install() verified = %v, want ChecksumVerified", verified)
+	}
+
+	data, err := os.ReadFile(b.Install)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "binary v1.0.0" {
+		t.Errorf("installed binary = %q", data)
+	}
+
+	b.Version = core.BinaryLatest
+	st, err = c.Status(ctx, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Installed != "v1.0.0" || st.Wanted != "v1.1.0" || st.Current() {
+		t.Fatalf("Status() for latest = %+v", st)
+	}
+
+	rel, err = c.Release(ctx, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Install(ctx, b, rel); err != nil {
+		t.Fatalf("Install() upgrade error = %v", err)
+	}
+	if tag, _ := c.Installed(b); tag != "v1.1.0" {
+		t.Errorf("Installed() after upgrade = %q, want v1.1.0", tag)
+	}
+
+	unused, err := c.Unused([]core.Binary{b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(c.Dir, "tool", "v1.0.0"); len(unused) != 1 || unused[0] != want {
+		t.Errorf("Unused() = %v, want [%s]", unused, want)
+	}
+}
+
+func TestClient_InstallChecksumMismatch(t *testing.T) {
+	srv := fakeGitHub(t, "v1.0.0", "v1.0.0")
+	c := newTestClient(t, srv)
+	ctx := context.Background()
+
+	b := core.Binary{
+		Name:     "tool",
+		Repo:     "owner/tool",
+		Version:  "v1.0.0",
+		Checksum: strings.Repeat("0", 64),
+		Install:  filepath.Join(t.TempDir(), "tool"),
+	}
+
+	rel, err := c.Release(ctx, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Install(ctx, b, rel); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Install() error = %v, want checksum mismatch", err)
+	}
+	if _, err := os.Lstat(b.Install); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("install path exists after failed install")
+	}
+}
+
+func TestClient_InstallSignature(t *testing.T) {
+	srv := fakeGitHub(t, "v1.0.0", "v1.0.0")
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed := &core.BinarySignature{Key: "release", Signs: core.SignsChecksums}
+	tests := []struct {
+		name      string
+		key       *ecdsa.PrivateKey
+		signature *core.BinarySignature
+		require   bool
+		want      Verification
+		wantErr   string
+	}{
+		{name: "signed checksums", key: signingKey, signature: signed, want: SignatureVerified},
+		{name: "other key", key: otherKey, signature: signed, wantErr: "does not verify checksums.txt with key release"},
+		{
+			name:      "signature asset missing",
+			key:       signingKey,
+			signature: &core.BinarySignature{Key: "release"},
+			wantErr:   `asset "tool_1.0.0_linux_amd64.tar.gz.sig" not found`,
+		},
+		{name: "unsigned allowed", key: signingKey, want: ChecksumVerified},
+		{name: "unsigned required", key: signingKey, require: true, wantErr: "no signature configured"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, srv)
+			c.TrustedKeys = []core.TrustedKey{trustedKey(t, "release", tt.key)}
+			c.RequireSignature = tt.require
+			ctx := context.Background()
+
+			b := core.Binary{
+				Name:          "tool",
+				Repo:          "owner/tool",
+				Version:       "v1.0.0",
+				ChecksumAsset: "checksums.txt",
+				Signature:     tt.signature,
+				Install:       filepath.Join(t.TempDir(), "tool"),
+			}
+			rel, err := c.Release(ctx, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := c.Install(ctx, b, rel)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Install() error = %v, want %q", err, tt.wantErr)
+				}
+				if _, err := os.Lstat(b.Install); !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("install path exists after failed install")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Install() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Install() verified = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_InstallUnmanaged(t *testing.T) {
+	srv := fakeGitHub(t, "v1.0.0", "v1.0.0")
+	c := newTestClient(t, srv)
+
+	b := core.Binary{Name: "tool", Repo: "owner/tool", Version: "v1.0.0", Install: filepath.Join(t.TempDir(), "tool")}
+	if err := os.WriteFile(b.Install, []byte("mine"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	rel, err := c.Release(context.Background(), b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Install(context.Background(), b, rel); !errors.Is(err, ErrUnmanaged) {
+		t.Errorf("Install() error = %v, want ErrUnmanaged", err)
+	}
+}
+
+func TestClient_SelectAsset(t *testing.T) {
+	rel := Release{Tag: "v2.3.0", Assets: []Asset{
+		{Name: "tool-2.3.0-x86_64-unknown-linux-musl"},
+		{Name: "tool-2.3.0-x86_64-unknown-linux-musl.sha256"},
+		{Name: "tool-2.3.0-x86_64-unknown-linux-musl.tar.gz"},
+		{Name: "tool-2.3.0-aarch64-apple-darwin.zip"},
+		{Name: "tool_2.3.0_linux_amd64.deb"},
+	}}
+
+	tests := []struct {
+		name    string
+		os      string
+		arch    string
+		pattern string
+		want    string
+		wantErr bool
+	}{
+		{name: "prefers archive", os: "linux", arch: "amd64", want: "tool-2.3.0-x86_64-unknown-linux-musl.tar.gz"},
+		{name: "os alias", os: "darwin", arch: "arm64", want: "tool-2.3.0-aarch64-apple-darwin.zip"},
+		{name: "pattern", os: "linux", arch: "amd64", pattern: "tool-{{ .Version }}-x86_64-*-musl", want: "tool-2.3.0-x86_64-unknown-linux-musl"},
+		{name: "no match", os: "windows", arch: "amd64", wantErr: true},
+		{name: "pattern no match", os: "linux", arch: "amd64", pattern: "nope-*", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{OS: tt.os, Arch: tt.arch}
+			got, err := c.SelectAsset(core.Binary{Name: "tool", Asset: tt.pattern}, rel)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SelectAsset() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Name != tt.want {
+				t.Errorf("SelectAsset() = %q, want %q", got.Name, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+// Package bundle writes self-contained tarballs of rendered dotfiles for
+// provisioning machines that have no access to the dotfiles repository or the
+// network.
+//
+// A bundle contains:
+//
+//	apply.sh            installs every file; needs only sh (and age for secrets)
+//	files/HOME/...      rendered files below the exporting user's home
+//	files/ROOT/...      rendered files elsewhere, by absolute path
+//	secrets/...         age encrypted files, same layout, decrypted on apply
+//	brew/<name>.sh      compiled brew scripts, run manually
+//
+// Paths below the exporting user's home are installed below $HOME on the
+// target so bundles work across usernames.
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type entry struct {
+	kind string // "file" or "secret"
+	name string // path inside the archive
+	dest string // shell expression for the install path
+	perm os.FileMode
+}
+
+// Writer builds a gzipped bundle tarball.
+type Writer struct {
+	gz   *gzip.Writer
+	tw   *tar.Writer
+	home string
+	now  time.Time
+
+	entries []entry
+	brews   []string
+}
+
+// NewWriter returns a Writer that writes the bundle to w. home is the
+// exporting user's home directory; destinations below it are installed
+// relative to $HOME on the target.
+func NewWriter(w io.Writer, home string) *Writer {
+	gz := gzip.NewWriter(w)
+	return &Writer{
+		gz:   gz,
+		tw:   tar.NewWriter(gz),
+		home: filepath.Clean(home),
+		now:  time.Now(),
+	}
+}
+
+// AddFile adds a rendered file installed at dest with perm.
+func (b *Writer) AddFile(dest string, data []byte, perm os.FileMode) error {
+	rel, target := b.mapDest(dest)
+	name := path.Join("files", rel)
+	if err := b.write(name, data, perm); err != nil {
+		return err
+	}
+	b.entries = append(b.entries, entry{kind: "file", name: name, dest: target, perm: perm})
+	return nil
+}
+
+// AddSecret adds age ciphertext that apply.sh decrypts to dest with perm.
+func (b *Writer) AddSecret(dest string, ciphertext []byte, perm os.FileMode) error {
+	rel, target := b.mapDest(dest)
+	name := path.Join("secrets", rel+".age")
+	if err := b.write(name, ciphertext, 0o600); err != nil {
+		return err
+	}
+	b.entries = append(b.entries, entry{kind: "secret", name: name, dest: target, perm: perm})
+	return nil
+}
+
+// AddBrewScript adds a compiled brew script for the named brew config.
+func (b *Writer) AddBrewScript(name string, script []byte) error {
+	file := path.Join("brew", name+".sh")
+	if err := b.write(file, script, 0o755); err != nil {
+		return err
+	}
+	b.brews = append(b.brews, file)
+	return nil
+}
+
+// Close writes apply.sh and finishes the archive.
+func (b *Writer) Close() error {
+	if err := b.write("apply.sh", []byte(b.applyScript()), 0o755); err != nil {
+		return err
+	}
+	if err := b.tw.Close(); err != nil {
+		return err
+	}
+	return b.gz.Close()
+}
+
+func (b *Writer) write(name string, data []byte, perm os.FileMode) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    int64(perm.Perm()),
+		Size:    int64(len(data)),
+		ModTime: b.now,
+	}
+	if err := b.tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+	if _, err := b.tw.Write(data); err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+	return nil
+}
+
+// mapDest returns the archive-relative path and the shell expression for the
+// install location of dest.
+func (b *Writer) mapDest(dest string) (rel, target string) {
+	dest = filepath.Clean(dest)
+	if r, err := filepath.Rel(b.home, dest); err == nil && r != "." && !strings.HasPrefix(r, "..") {
+		r = filepath.ToSlash(r)
+		return path.Join("HOME", r), `"$HOME"/` + shellQuote(r)
+	}
+
+	abs := filepath.ToSlash(dest)
+	return path.Join("ROOT", abs), shellQuote(abs)
+}
+
+func (b *Writer) applyScript() string {
+	var sb strings.Builder
+	sb.WriteString(`#!/bin/sh
+# Generated by mmdot bundle export. Installs the bundled files on this machine.
+#
+# Usage: ./apply.sh [--dry-run]
+#
+# Secrets are decrypted with age using $AGE_IDENTITY
+# (default: ~/.config/age/key.txt).
+set -eu
+
+cd "$(dirname "$0")"
+
+DRY_RUN=0
+if [ "${1:-}" = "--dry-run" ]; then
+	DRY_RUN=1
+fi
+IDENTITY="${AGE_IDENTITY:-$HOME/.config/age/key.txt}"
+
+install_file() {
+	if [ "$DRY_RUN" = 1 ]; then
+		echo "would install $3"
+		return
+	fi
+	mkdir -p "$(dirname "$3")"
+	cp "$2" "$3"
+	chmod "$1" "$3"
+	echo "installed $3"
+}
+
+install_secret() {
+	if [ "$DRY_RUN" = 1 ]; then
+		echo "would decrypt $3"
+		return
+	fi
+	if ! command -v age >/dev/null 2>&1; then
+		echo "age is required to decrypt $3" >&2
+		exit 1
+	fi
+	mkdir -p "$(dirname "$3")"
+	age --decrypt --identity "$IDENTITY" --output "$3" "$2"
+	chmod "$1" "$3"
+	echo "decrypted $3"
+}
+
+`)
+
+	for _, e := range b.entries {
+		fn := "install_file"
+		if e.kind == "secret" {
+			fn = "install_secret"
+		}
+		fmt.Fprintf(&sb, "%s %04o %s %s\n", fn, e.perm.Perm(), shellQuote(e.name), e.dest)
+	}
+
+	if len(b.brews) > 0 {
+		sb.WriteString("\necho\necho \"Brew scripts are not run automatically. To install packages run:\"\n")
+		for _, file := range b.brews {
+			fmt.Fprintf(&sb, "echo %s\n", shellQuote("  ./"+file))
+		}
+	}
+
+	return sb.String()
+}
+
+// shellQuote quotes s for POSIX sh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
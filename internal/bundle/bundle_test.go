@@ -0,0 +1,140 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, "/home/alice")
+
+	if err := w.AddFile("/home/alice/.zshrc", []byte("export EDITOR=vim\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddFile("/etc/motd", []byte("hi\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddSecret("/home/alice/.ssh/id_ed25519", []byte("ciphertext"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddBrewScript("personal", []byte("#!/usr/bin/env bash\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files := readArchive(t, &buf)
+
+	wantNames := []string{
+		"files/HOME/.zshrc",
+		"files/ROOT/etc/motd",
+		"secrets/HOME/.ssh/id_ed25519.age",
+		"brew/personal.sh",
+		"apply.sh",
+	}
+	for _, name := range wantNames {
+		if _, ok := files[name]; !ok {
+			t.Errorf("archive missing %s", name)
+		}
+	}
+
+	apply := files["apply.sh"]
+	wantLines := []string{
+		`install_file 0644 'files/HOME/.zshrc' "$HOME"/'.zshrc'`,
+		`install_file 0644 'files/ROOT/etc/motd' '/etc/motd'`,
+		`install_secret 0600 'secrets/HOME/.ssh/id_ed25519.age' "$HOME"/'.ssh/id_ed25519'`,
+		`echo '  ./brew/personal.sh'`,
+	}
+	for _, line := range wantLines {
+		if !strings.Contains(apply, line+"\n") {
+			t.Errorf("apply.sh missing line %q", line)
+		}
+	}
+}
+
+func TestWriter_ApplyScript(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf, "/home/alice")
+	if err := w.AddFile("/home/alice/.config/it's/config", []byte("ok\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	for name, content := range readArchive(t, &buf) {
+		path := filepath.Join(dir, "bundle", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	home := filepath.Join(dir, "home")
+	cmd := exec.Command("sh", filepath.Join(dir, "bundle", "apply.sh"))
+	cmd.Env = append(os.Environ(), "HOME="+home)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("apply.sh failed: %v\n%s", err, out)
+	}
+
+	dest := filepath.Join(home, ".config", "it's", "config")
+	data, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "ok\n" {
+		t.Errorf("installed content = %q, want %q", data, "ok\n")
+	}
+
+	info, err := os.Stat(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("installed mode = %o, want 600", info.Mode().Perm())
+	}
+}
+
+func readArchive(t *testing.T, r io.Reader) map[string]string {
+	t.Helper()
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+
+	files := map[string]string{}
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[hdr.Name] = string(data)
+	}
+	return files
+}
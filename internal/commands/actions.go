@@ -3,12 +3,19 @@ package commands
 import (
 	"context"
 	"fmt"
+	"os/exec"
+	"path"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 
-	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/expr-lang/expr"
 	"github.com/expr-lang/expr/vm"
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/notify"
+	"github.com/hay-kot/mmdot/internal/tui"
 	"github.com/rs/zerolog/log"
 )
 
@@ -17,8 +24,12 @@ type RunnerType = string
 const (
 	RunnerTypeTemplate RunnerType = "template"
 	RunnerTypeScript   RunnerType = "script"
+	RunnerTypeService  RunnerType = "service"
 )
 
+// RunnerTypes lists every runner type in the default execution order.
+var RunnerTypes = []RunnerType{RunnerTypeTemplate, RunnerTypeScript, RunnerTypeService}
+
 // RunnerTypeFromStrings converts a slice of strings to a slice of RunnerType values.
 // Returns an error if any string is not a valid RunnerType.
 func RunnerTypeFromStrings(strs []string) ([]RunnerType, error) {
@@ -28,9 +39,9 @@ func RunnerTypeFromStrings(strs []string) ([]RunnerType, error) {
 		rt := RunnerType(str)
 
 		// Validate that the string is a valid RunnerType
-		if rt != RunnerTypeTemplate && rt != RunnerTypeScript {
-			return nil, fmt.Errorf("invalid runner type at index %d: %q (expected %q or %q)",
-				i, str, RunnerTypeTemplate, RunnerTypeScript)
+		if !slices.Contains(RunnerTypes, rt) {
+			return nil, fmt.Errorf("invalid runner type at index %d: %q (expected %q, %q, or %q)",
+				i, str, RunnerTypeTemplate, RunnerTypeScript, RunnerTypeService)
 		}
 
 		result = append(result, rt)
@@ -39,6 +50,41 @@ func RunnerTypeFromStrings(strs []string) ([]RunnerType, error) {
 	return result, nil
 }
 
+// runnerOrder returns the configured runner order, defaulting to templates,
+// then scripts, then services. Each runner type may appear at most once;
+// types the order leaves out run after the listed ones in default order, so
+// a partial order only moves types and never drops them.
+func runnerOrder(run core.Run) ([]RunnerType, error) {
+	if len(run.Order) == 0 {
+		return slices.Clone(RunnerTypes), nil
+	}
+
+	order, err := RunnerTypeFromStrings(run.Order)
+	if err != nil {
+		return nil, fmt.Errorf("run.order: %w", err)
+	}
+
+	seen := map[RunnerType]bool{}
+	for _, rt := range order {
+		if seen[rt] {
+			return nil, fmt.Errorf("run.order: duplicate runner type %q", rt)
+		}
+		seen[rt] = true
+	}
+	for _, rt := range RunnerTypes {
+		if !seen[rt] {
+			order = append(order, rt)
+		}
+	}
+
+	return order, nil
+}
+
+// inStage reports whether an item with the given stage should run for args.
+func (args ExecuteArgs) inStage(stage core.Stage) bool {
+	return args.Stage == "" || stage.OrDefault() == args.Stage
+}
+
 type ExecuteArgs struct {
 	Types         []RunnerType
 	TerminalWidth int               // Width of the Terminal
@@ -46,13 +92,70 @@ type ExecuteArgs struct {
 	Macros        map[string]string // Macro definitions for expression expansion
 	List          bool              // List matching items without executing
 	Program       *vm.Program       // Pre-compiled expression program (optional, compiled if nil)
+	Stage         core.Stage        // Only execute items in this stage (all stages if empty)
+	Summary       *RunSummary       // Counts of executed items (optional)
+	NoPrivileged  bool              // Skip scripts marked privileged
+	KeepGoing     bool              // Continue with the next item when one fails (requires Summary)
+	Force         bool              // Overwrite outputs of templates marked no_clobber, ignore min_interval
+}
+
+// fail returns err, or records it in the summary and returns nil when
+// args.KeepGoing is set so the runner continues with its next item.
+func (args ExecuteArgs) fail(item string, err error) error {
+	if !args.KeepGoing || args.Summary == nil {
+		return err
+	}
+
+	log.Error().Err(err).Str("item", item).Msg("failed, continuing with the next item")
+	args.Summary.Failed = append(args.Summary.Failed, item)
+	if args.Summary.firstErr == nil {
+		args.Summary.firstErr = err
+	}
+	fmt.Println()
+	return nil
+}
+
+// RunSummary counts the items successfully executed during a run.
+type RunSummary struct {
+	Templates int
+	Scripts   int
+	Services  int
+	Skipped   []notify.Skip // Items not run because a skip condition matched
+	Failed    []string      // Items that failed when running with KeepGoing
+
+	firstErr error
+}
+
+// skip records that item was not run. s may be nil.
+func (s *RunSummary) skip(item, reason string) {
+	if s != nil {
+		s.Skipped = append(s.Skipped, notify.Skip{Item: item, Reason: reason})
+	}
+}
+
+// Err returns an error for the items that failed with KeepGoing, or nil when
+// none failed. It exits with [ExitPartial] when other items succeeded and
+// with the code of the first failure when nothing did.
+func (s *RunSummary) Err() error {
+	if s == nil || len(s.Failed) == 0 {
+		return nil
+	}
+	err := fmt.Errorf("%d item(s) failed (%s): %w", len(s.Failed), strings.Join(s.Failed, ", "), s.firstErr)
+	if s.Templates+s.Scripts+s.Services > 0 {
+		return PartialError(err)
+	}
+	return err
 }
 
 type Runner interface {
-	// Form returns a group reference that is mounted to a huh.Form for users to select
-	// what actions they want to perform. The [Action] implementer should store the
-	// internal state of the selected values for execution later.
-	Field(ctx context.Context) huh.Field
+	// Items returns the entries offered for interactive selection, in config
+	// order.
+	Items(ctx context.Context) []tui.SelectItem
+
+	// Select records the interactively chosen entries, as indexes into the
+	// result of Items. Execute then runs only those instead of evaluating the
+	// expression.
+	Select(indexes []int)
 
 	// Execute the configured [Actions]
 	Execute(ctx context.Context, args ExecuteArgs) error
@@ -124,14 +227,51 @@ func expandTagShortcuts(code string) (string, []string) {
 		}
 	}
 
+	// Drop boolean operators left dangling by the removed shortcuts, e.g. the
+	// "and" in `!bootstrap and installed("tmux")`.
+	isOp := func(w string) bool {
+		switch w {
+		case "and", "or", "&&", "||":
+			return true
+		}
+		return false
+	}
+	for len(remainingParts) > 0 && isOp(remainingParts[0]) {
+		remainingParts = remainingParts[1:]
+	}
+	for len(remainingParts) > 0 && isOp(remainingParts[len(remainingParts)-1]) {
+		remainingParts = remainingParts[:len(remainingParts)-1]
+	}
+
 	// Reconstruct expression without +tag/!tag shortcuts
 	result := strings.Join(remainingParts, " ")
 
 	return result, tagExprs
 }
 
-// compileExpr compiles an expression string once for reuse
-func compileExpr(code string, macros map[string]string, enableExpansions bool) (*vm.Program, error) {
+// tagFilterExpr converts include and exclude tag lists into an expression that
+// requires every included tag and none of the excluded tags (AND semantics).
+// Returns an empty string when both lists are empty.
+func tagFilterExpr(include, exclude []string) string {
+	var parts []string
+	for _, tag := range include {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			parts = append(parts, fmt.Sprintf(`%s in tags`, strconv.Quote(tag)))
+		}
+	}
+	for _, tag := range exclude {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			parts = append(parts, fmt.Sprintf(`not (%s in tags)`, strconv.Quote(tag)))
+		}
+	}
+	return strings.Join(parts, " && ")
+}
+
+// compileExpr compiles an expression string once for reuse. Any non-empty
+// filters are ANDed with the expanded expression and are not subject to macro
+// or tag shortcut expansion. Packages for installed() are listed with ctx, at
+// most once per program.
+func compileExpr(ctx context.Context, code string, macros map[string]string, enableExpansions bool, filters ...string) (*vm.Program, error) {
 	expanded := code
 
 	// Only perform expansions if enabled
@@ -163,15 +303,59 @@ func compileExpr(code string, macros map[string]string, enableExpansions bool) (
 		expanded = "true" // default: match everything when no expression provided
 	}
 
+	for _, filter := range filters {
+		if filter == "" {
+			continue
+		}
+		if expanded == "true" {
+			expanded = filter
+		} else {
+			expanded = "(" + expanded + ") && " + filter
+		}
+	}
+
 	log.Debug().
 		Str("original", code).
 		Str("expanded", expanded).
 		Bool("expansions_enabled", enableExpansions).
 		Msg("compiled expression")
 
-	return expr.Compile(expanded, expr.AsBool())
+	return expr.Compile(expanded, expr.AsBool(), exprInstalled(ctx), exprCommand, exprGlob)
 }
 
+// installedPackages returns the Homebrew packages on the machine through the
+// shared brew cache. Machines without brew report nothing installed.
+var installedPackages = func(ctx context.Context) []string {
+	installed, err := core.LoadInstalledBrews(ctx, core.InstalledBrewsOptions{TTL: core.DefaultBrewCacheTTL})
+	if err != nil {
+		log.Debug().Err(err).Msg("installed() could not list brew packages")
+	}
+	return installed
+}
+
+// exprInstalled provides installed("name"), which reports whether a Homebrew
+// formula or cask is installed. Tap-qualified names match either form. The
+// packages are only listed when the expression first calls it.
+func exprInstalled(ctx context.Context) expr.Option {
+	load := sync.OnceValue(func() []string { return installedPackages(ctx) })
+	return expr.Function("installed", func(params ...any) (any, error) {
+		name := params[0].(string)
+		for _, pkg := range load() {
+			if pkg == name || path.Base(pkg) == name {
+				return true, nil
+			}
+		}
+		return false, nil
+	}, new(func(string) bool))
+}
+
+// exprCommand provides command("name"), which reports whether an executable is
+// on PATH.
+var exprCommand = expr.Function("command", func(params ...any) (any, error) {
+	_, err := exec.LookPath(params[0].(string))
+	return err == nil, nil
+}, new(func(string) bool))
+
 // evalCompiledExpr evaluates a pre-compiled expression with given context
 func evalCompiledExpr(program *vm.Program, env map[string]any) (bool, error) {
 	output, err := expr.Run(program, env)
@@ -195,8 +379,35 @@ var (
 	dividerStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#565f89"))
 )
 
-// createStyledHeader creates a styled header for templates and scripts
+// ciGroupOpen is set while a CI log group started by [ciGroup] is open.
+var ciGroupOpen bool
+
+// ciGroup returns the marker starting a collapsible log group in CI, closing
+// the previous group first.
+func ciGroup(title string) string {
+	marker := "::group::" + title
+	if ciGroupOpen {
+		marker = "::endgroup::\n" + marker
+	}
+	ciGroupOpen = true
+	return marker
+}
+
+// endCIGroup prints the marker closing the open CI log group, if any.
+func endCIGroup() {
+	if ciGroupOpen {
+		fmt.Println("::endgroup::")
+		ciGroupOpen = false
+	}
+}
+
+// createStyledHeader creates a styled header for templates and scripts. In CI
+// mode it starts a log group instead.
 func createStyledHeader(label, name string, terminalWidth int) string {
+	if core.CI {
+		return ciGroup(label + " " + name)
+	}
+
 	// Build the header parts
 	leftPart := fmt.Sprintf("%s %s%s%s %s ",
 		dividerStyle.Render("--"),
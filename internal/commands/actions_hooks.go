@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/profile"
+	"github.com/rs/zerolog/log"
+)
+
+// HookEnvRunError is set for after_failure hooks to the error that failed the run.
+const HookEnvRunError = core.EnvPrefix + "RUN_ERROR"
+
+// runHooks executes each command with the configured exec shell from the config
+// directory, stopping at the first failure. Extra env entries are appended to
+// the current environment.
+func runHooks(ctx context.Context, cfg *core.ConfigFile, label string, commands []string, terminalWidth int, env ...string) error {
+	for _, command := range commands {
+		fmt.Println(createStyledHeader("HOOK", label, terminalWidth))
+		log.Debug().
+			Str("hook", label).
+			Str("command", command).
+			Str("workdir", cfg.ConfigDir).
+			Msg("Executing hook")
+
+		cmd := exec.CommandContext(ctx, hookShell(cfg), "-c", command)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		cmd.Stdin = os.Stdin
+		cmd.Dir = cfg.ConfigDir
+		cmd.Env = append(scriptEnv(cfg), env...)
+
+		done := profile.Track(ctx, "hook", label)
+		err := cmd.Run()
+		done()
+		if err != nil {
+			return ScriptError(fmt.Errorf("%s hook %q failed: %w", label, command, err))
+		}
+
+		fmt.Println()
+	}
+
+	return nil
+}
+
+// scriptEnv returns the environment hooks start from: the caller's, filtered
+// by exec.clean_env and exec.allow_env.
+func scriptEnv(cfg *core.ConfigFile) []string {
+	if env := cfg.Exec.ScriptEnv(os.Environ()); env != nil {
+		return env
+	}
+	return os.Environ()
+}
+
+// hookShell returns the shell used for hooks, falling back to /bin/sh when
+// exec.shell is not configured.
+func hookShell(cfg *core.ConfigFile) string {
+	if cfg.Exec.Shell != "" {
+		return cfg.Exec.Shell
+	}
+	return "/bin/sh"
+}
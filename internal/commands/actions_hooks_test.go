@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hay-kot/mmdot/internal/core"
+)
+
+func Test_runHooks(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &core.ConfigFile{ConfigDir: dir}
+
+	commands := []string{
+		`echo "$` + HookEnvRunError + `" > out.txt`,
+		`echo second >> out.txt`,
+	}
+
+	err := runHooks(context.Background(), cfg, "after_failure", commands, 80, HookEnvRunError+"=boom")
+	if err != nil {
+		t.Fatalf("runHooks() error = %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, "out.txt"))
+	if err != nil {
+		t.Fatalf("reading hook output: %v", err)
+	}
+	if string(got) != "boom\nsecond\n" {
+		t.Errorf("hook output = %q, want %q", got, "boom\nsecond\n")
+	}
+}
+
+func Test_runHooks_StopsOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &core.ConfigFile{ConfigDir: dir}
+
+	err := runHooks(context.Background(), cfg, "before", []string{"exit 3", "touch ran.txt"}, 80)
+	if err == nil {
+		t.Fatal("runHooks() expected error")
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "ran.txt")); !os.IsNotExist(err) {
+		t.Error("hooks after a failure should not run")
+	}
+}
+
+func Test_executeWithHooks_BeforeFailure(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &core.ConfigFile{
+		ConfigDir: dir,
+		Run: core.Run{
+			Before:       []string{"exit 3"},
+			After:        []string{"touch after.txt"},
+			AfterFailure: []string{`echo "$` + HookEnvRunError + `" > failure.txt`},
+		},
+	}
+
+	err := (&RunCmd{}).executeWithHooks(context.Background(), cfg, nil, ExecuteArgs{TerminalWidth: 80})
+	if err == nil {
+		t.Fatal("executeWithHooks() expected error")
+	}
+
+	got, readErr := os.ReadFile(filepath.Join(dir, "failure.txt"))
+	if readErr != nil {
+		t.Fatalf("after_failure hook did not run: %v", readErr)
+	}
+	if string(got) != err.Error()+"\n" {
+		t.Errorf("%s = %q, want %q", HookEnvRunError, got, err.Error()+"\n")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "after.txt")); !os.IsNotExist(err) {
+		t.Error("after hook should not run when a before hook fails")
+	}
+}
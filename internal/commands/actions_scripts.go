@@ -10,25 +10,38 @@ import (
 	"slices"
 	"strings"
 	"syscall"
+	"time"
 
-	"github.com/charmbracelet/huh"
 	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/profile"
+	"github.com/hay-kot/mmdot/internal/tui"
 	"github.com/rs/zerolog/log"
 )
 
 var _ Runner = &ScriptRunner{}
 
+// DefaultPreviewLines is how many lines of a script interactive selection
+// previews unless --preview-lines says otherwise.
+const DefaultPreviewLines = 40
+
 type ScriptRunner struct {
 	cfg *core.ConfigFile
 
+	// PreviewLines is how many lines of each script the interactive
+	// selection previews.
+	PreviewLines int
+
 	formsActivated bool
 	formsScriptMap map[string]core.Script
 	formSelected   []string
+
+	escalation *core.Escalation // set once privileges have been validated
 }
 
 func NewScriptRunner(cfg *core.ConfigFile) *ScriptRunner {
 	return &ScriptRunner{
 		cfg:            cfg,
+		PreviewLines:   DefaultPreviewLines,
 		formsActivated: false,
 		formsScriptMap: map[string]core.Script{},
 		formSelected:   []string{},
@@ -66,6 +79,35 @@ func (sr *ScriptRunner) Execute(ctx context.Context, args ExecuteArgs) error {
 		}
 	}
 
+	if args.NoPrivileged {
+		scriptsToRun = slices.DeleteFunc(scriptsToRun, func(s core.Script) bool {
+			if s.Privileged && args.inStage(s.Stage) {
+				log.Info().Str("path", s.Path).Msg("skipping privileged script")
+				if !args.List {
+					args.Summary.skip(s.Path, "privileged")
+				}
+			}
+			return s.Privileged
+		})
+	}
+
+	// Validate privileges before the first stage runs so the password prompt
+	// happens once, up front, rather than in the middle of the run
+	if !args.List && sr.escalation == nil && slices.ContainsFunc(scriptsToRun, func(s core.Script) bool { return s.Privileged }) {
+		esc, err := core.FindEscalation()
+		if err != nil {
+			return ScriptError(err)
+		}
+		if err := esc.Validate(ctx); err != nil {
+			return ScriptError(fmt.Errorf("failed to acquire privileges with %s: %w", filepath.Base(esc.Tool), err))
+		}
+		sr.escalation = &esc
+	}
+
+	scriptsToRun = slices.DeleteFunc(scriptsToRun, func(s core.Script) bool {
+		return !args.inStage(s.Stage)
+	})
+
 	if len(scriptsToRun) == 0 {
 		log.Debug().Str("type", RunnerTypeScript).Str("expr", args.Expr).Msg("no scripts matching selector found")
 		return nil // nothing to run
@@ -79,6 +121,9 @@ func (sr *ScriptRunner) Execute(ctx context.Context, args ExecuteArgs) error {
 				Name: filepath.Base(script.Path),
 				Tags: script.Tags,
 			}
+			if script.Privileged {
+				items[i].Name += " (privileged)"
+			}
 		}
 		printList("Scripts", items)
 		return nil
@@ -88,6 +133,16 @@ func (sr *ScriptRunner) Execute(ctx context.Context, args ExecuteArgs) error {
 	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
+	// Last successful runs, for scripts with a min_interval
+	var runs map[string]time.Time
+	if !args.Force && slices.ContainsFunc(scriptsToRun, func(s core.Script) bool { return s.MinInterval > 0 }) {
+		var err error
+		runs, err = core.LoadScriptRuns()
+		if err != nil {
+			log.Warn().Err(err).Msg("failed to load script runs, ignoring min_interval")
+		}
+	}
+
 	// Execute matched scripts
 	for _, script := range scriptsToRun {
 		// Create a cancelable context for each script
@@ -102,22 +157,58 @@ func (sr *ScriptRunner) Execute(ctx context.Context, args ExecuteArgs) error {
 			Strs("tags", script.Tags).
 			Msg("Executing script")
 
+		if reason := script.IntervalSkipReason(runs[script.Path], time.Now()); reason != "" {
+			log.Debug().Str("path", script.Path).Str("reason", reason).Msg("skipped script")
+			args.Summary.skip(filepath.Base(script.Path), reason)
+			fmt.Printf("Skipped (%s)\n\n", reason)
+			continue
+		}
+
 		// Make script executable
 		if err := os.Chmod(script.Path, 0o755); err != nil {
 			log.Error().Err(err).Str("path", script.Path).Msg("Failed to set script permissions")
-			return err
+			if err := args.fail(filepath.Base(script.Path), err); err != nil {
+				return err
+			}
+			continue
 		}
 
 		// Execute script with the configured shell
 		cmd := exec.CommandContext(scriptCtx, sr.cfg.Exec.Shell, script.Path)
+		if script.Privileged {
+			cmd = sr.escalation.Command(scriptCtx, sr.cfg.Exec.Shell, script.Path)
+		}
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
 		cmd.Stdin = os.Stdin
 		cmd.Dir = sr.cfg.ConfigDir // Run script in config directory
+		cmd.Env = sr.cfg.Exec.ScriptEnv(os.Environ())
 
-		if err := cmd.Run(); err != nil {
+		done := profile.Track(ctx, "script", filepath.Base(script.Path))
+		err := cmd.Run()
+		done()
+		if err != nil {
 			log.Error().Err(err).Str("path", script.Path).Msg("Script execution failed")
-			return err
+			if err := args.fail(filepath.Base(script.Path), ScriptError(fmt.Errorf("script %s failed: %w", filepath.Base(script.Path), err))); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if args.Summary != nil {
+			args.Summary.Scripts++
+		}
+
+		if script.MinInterval > 0 {
+			if err := core.RecordScriptRun(script.Path, time.Now()); err != nil {
+				log.Warn().Err(err).Str("path", script.Path).Msg("failed to record script run")
+			}
+		}
+
+		if len(script.Manages) > 0 {
+			if err := recordManaged(script); err != nil {
+				log.Warn().Err(err).Str("path", script.Path).Msg("failed to record managed paths")
+			}
 		}
 
 		// Add a newline after script execution for readability
@@ -127,26 +218,78 @@ func (sr *ScriptRunner) Execute(ctx context.Context, args ExecuteArgs) error {
 	return nil
 }
 
-// Form implements Runner.
-func (sr *ScriptRunner) Field(ctx context.Context) huh.Field {
-	sr.formsActivated = true
+// Items implements Runner.
+func (sr *ScriptRunner) Items(ctx context.Context) []tui.SelectItem {
 	sr.formsScriptMap = map[string]core.Script{}
-	sr.formSelected = []string{}
-
-	options := []huh.Option[string]{}
 
+	items := make([]tui.SelectItem, 0, len(sr.cfg.Exec.Scripts))
 	for _, script := range sr.cfg.Exec.Scripts {
-		displayStr := fmt.Sprintf("%s (%s)", script.Path, strings.Join(script.Tags, ", "))
-		options = append(options, huh.NewOption(displayStr, script.Path))
 		sr.formsScriptMap[script.Path] = script
+
+		name := filepath.Base(script.Path)
+		if script.Privileged {
+			name += " [privileged]"
+		}
+		items = append(items, tui.SelectItem{
+			Group:   "Scripts",
+			Name:    name,
+			Tags:    script.Tags,
+			Preview: func() string { return sr.preview(script) },
+		})
 	}
 
-	if len(options) == 0 {
-		return nil
+	return items
+}
+
+// Select implements Runner.
+func (sr *ScriptRunner) Select(indexes []int) {
+	sr.formsActivated = true
+	sr.formSelected = []string{}
+	for _, i := range indexes {
+		sr.formSelected = append(sr.formSelected, sr.cfg.Exec.Scripts[i].Path)
+	}
+}
+
+// recordManaged stores the hashes of the paths the script manages so status
+// can report changes made outside mmdot.
+func recordManaged(script core.Script) error {
+	hashes, err := script.HashManaged()
+	if err != nil {
+		return err
+	}
+	return core.RecordManagedHashes(script.Path, hashes)
+}
+
+// preview describes script for the interactive selection: where it is, what
+// runs it, and its first lines, so similarly named scripts can be told apart
+// before they run.
+func (sr *ScriptRunner) preview(script core.Script) string {
+	var sb strings.Builder
+	field := func(label, value string) {
+		if value != "" {
+			fmt.Fprintf(&sb, "%-10s %s\n", label, value)
+		}
+	}
+	field("path", relToConfig(sr.cfg, script.Path))
+	field("shell", sr.cfg.Exec.Shell)
+	field("stage", string(script.Stage.OrDefault()))
+	field("tags", strings.Join(script.Tags, ", "))
+	if script.Privileged {
+		field("privileged", "yes, runs through sudo or doas")
+	}
+
+	sb.WriteString("\n")
+	sb.WriteString(scriptHead(script.Path, sr.PreviewLines))
+	return sb.String()
+}
+
+// scriptHead returns the first n lines of the script at path.
+func scriptHead(path string, n int) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err.Error()
 	}
 
-	return huh.NewMultiSelect[string]().
-		Title("Select Scripts to Run").
-		Options(options...).
-		Value(&sr.formSelected)
+	lines := strings.SplitN(string(data), "\n", n+1)
+	return strings.Join(lines[:min(len(lines), n)], "\n")
 }
@@ -0,0 +1,227 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/generator"
+	"github.com/hay-kot/mmdot/internal/services"
+	"github.com/hay-kot/mmdot/internal/tui"
+	"github.com/rs/zerolog/log"
+)
+
+var _ Runner = &ServiceRunner{}
+
+type ServiceRunner struct {
+	cfg     *core.ConfigFile
+	engine  *generator.Engine
+	manager services.Manager
+
+	formsActivated  bool
+	formsServiceMap map[string]core.Service
+	formSelected    []string
+}
+
+func NewServiceRunner(cfg *core.ConfigFile) *ServiceRunner {
+	return &ServiceRunner{
+		cfg:             cfg,
+		engine:          generator.NewEngine(cfg),
+		manager:         services.Detect(),
+		formsActivated:  false,
+		formsServiceMap: map[string]core.Service{},
+		formSelected:    []string{},
+	}
+}
+
+// Execute implements Runner.
+func (sr *ServiceRunner) Execute(ctx context.Context, args ExecuteArgs) error {
+	if !slices.Contains(args.Types, RunnerTypeService) {
+		log.Debug().Str("type", RunnerTypeService).Msg("type disabled")
+		return nil // nothing to run
+	}
+
+	servicesToRun := []core.Service{}
+
+	switch {
+	case sr.formsActivated: // Assume form has run and we have user interactions to base selection on
+		for _, selected := range sr.formSelected {
+			servicesToRun = append(servicesToRun, sr.formsServiceMap[selected])
+		}
+	default:
+		for _, svc := range sr.cfg.Services {
+			enabled, err := evalCompiledExpr(args.Program, map[string]any{
+				"tags": svc.Tags,
+				"name": svc.Name,
+			})
+			if err != nil {
+				return fmt.Errorf("expression evaluation failed for service %s: %w", svc.Name, err)
+			}
+
+			if enabled {
+				servicesToRun = append(servicesToRun, svc)
+			}
+		}
+	}
+
+	servicesToRun = slices.DeleteFunc(servicesToRun, func(s core.Service) bool {
+		return !args.inStage(s.Stage)
+	})
+
+	if len(servicesToRun) == 0 {
+		log.Debug().Str("type", RunnerTypeService).Str("expr", args.Expr).Msg("no services matching selector found")
+		return nil // nothing to run
+	}
+
+	// List mode: just print the matched services
+	if args.List {
+		items := make([]ListItem, len(servicesToRun))
+		for i, svc := range servicesToRun {
+			items[i] = ListItem{
+				Name: svc.Name,
+				Tags: svc.Tags,
+			}
+		}
+		printList("Services", items)
+		return nil
+	}
+
+	var (
+		pathStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#bb9af7"))
+		successStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#22c55e"))
+	)
+
+	// Write all unit files first so a single reload picks up every change
+	changed := map[string]bool{}
+	failed := map[string]bool{}
+	for _, svc := range servicesToRun {
+		if err := sr.writeUnit(ctx, svc, changed); err != nil {
+			if err := args.fail(svc.Name, err); err != nil {
+				return err
+			}
+			failed[svc.Name] = true
+		}
+	}
+
+	if len(changed) > 0 {
+		if err := sr.manager.Reload(ctx); err != nil {
+			return err
+		}
+	}
+
+	for _, svc := range servicesToRun {
+		if failed[svc.Name] {
+			continue
+		}
+
+		fmt.Println(createStyledHeader("SERVICE", svc.Name, args.TerminalWidth))
+
+		path, err := sr.manager.UnitPath(svc.Name)
+		if err != nil {
+			return err
+		}
+
+		actions := []string{}
+		if changed[svc.Name] {
+			actions = append(actions, "written")
+		}
+
+		if svc.ShouldEnable() {
+			if err := sr.manager.Enable(ctx, svc.Name); err != nil {
+				if err := args.fail(svc.Name, fmt.Errorf("failed to enable service %s: %w", svc.Name, err)); err != nil {
+					return err
+				}
+				continue
+			}
+			actions = append(actions, "enabled")
+		}
+
+		if svc.ShouldStart() && (changed[svc.Name] || sr.manager.State(ctx, svc.Name) != "active") {
+			if err := sr.manager.Restart(ctx, svc.Name); err != nil {
+				if err := args.fail(svc.Name, fmt.Errorf("failed to start service %s: %w", svc.Name, err)); err != nil {
+					return err
+				}
+				continue
+			}
+			actions = append(actions, "started")
+		}
+
+		if len(actions) == 0 {
+			actions = append(actions, "unchanged")
+		}
+
+		log.Debug().
+			Str("service", svc.Name).
+			Str("unit", path).
+			Strs("actions", actions).
+			Msg("applied service")
+
+		if args.Summary != nil {
+			args.Summary.Services++
+		}
+
+		fmt.Printf("Status       %s\n", successStyle.Render(strings.Join(actions, ", ")))
+		fmt.Printf("Unit Path    %s\n", pathStyle.Render(path))
+		fmt.Println()
+	}
+
+	return nil
+}
+
+// writeUnit renders the unit file of svc and writes it when it changed,
+// recording the change in changed.
+func (sr *ServiceRunner) writeUnit(ctx context.Context, svc core.Service, changed map[string]bool) error {
+	tmpl, err := services.Template(sr.manager, svc)
+	if err != nil {
+		return fmt.Errorf("service %s: %w", svc.Name, err)
+	}
+
+	result, err := sr.engine.Check(ctx, tmpl)
+	if err != nil {
+		return fmt.Errorf("failed to render service %s: %w", svc.Name, err)
+	}
+
+	if result.Status != generator.StatusCurrent {
+		if err := sr.engine.RenderTemplate(ctx, tmpl); err != nil {
+			return fmt.Errorf("failed to write service %s: %w", svc.Name, err)
+		}
+		changed[svc.Name] = true
+	}
+	return nil
+}
+
+// Items implements Runner.
+func (sr *ServiceRunner) Items(ctx context.Context) []tui.SelectItem {
+	sr.formsServiceMap = map[string]core.Service{}
+
+	items := make([]tui.SelectItem, 0, len(sr.cfg.Services))
+	for _, svc := range sr.cfg.Services {
+		sr.formsServiceMap[svc.Name] = svc
+		items = append(items, tui.SelectItem{
+			Group: "Services",
+			Name:  svc.Name,
+			Tags:  svc.Tags,
+			Preview: func() string {
+				body, err := generator.TemplateSource(svc.Template)
+				if err != nil {
+					return err.Error()
+				}
+				return body
+			},
+		})
+	}
+
+	return items
+}
+
+// Select implements Runner.
+func (sr *ServiceRunner) Select(indexes []int) {
+	sr.formsActivated = true
+	sr.formSelected = []string{}
+	for _, i := range indexes {
+		sr.formSelected = append(sr.formSelected, sr.cfg.Services[i].Name)
+	}
+}
@@ -2,14 +2,18 @@ package commands
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"slices"
 	"strings"
 
-	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/hay-kot/mmdot/internal/core"
 	"github.com/hay-kot/mmdot/internal/generator"
+	"github.com/hay-kot/mmdot/internal/tui"
+	"github.com/hay-kot/mmdot/pkgs/printer"
+	"github.com/hay-kot/mmdot/pkgs/redact"
 	"github.com/rs/zerolog/log"
 )
 
@@ -22,6 +26,8 @@ type TemplateRunner struct {
 	formsActivated   bool
 	formsTemplateMap map[string]core.Template
 	formSelected     []string
+
+	varsChecked bool // requires_vars validated for every selected template
 }
 
 func NewTemplateRunner(cfg *core.ConfigFile) *TemplateRunner {
@@ -64,6 +70,19 @@ func (tr *TemplateRunner) Execute(ctx context.Context, args ExecuteArgs) error {
 		}
 	}
 
+	// Check requires_vars for every stage on the first call so that a missing
+	// variable fails the run before any template is rendered
+	if !args.List && !tr.varsChecked {
+		tr.varsChecked = true
+		if err := checkRequiredVars(ctx, &tr.engine, templatesToRun); err != nil {
+			return err
+		}
+	}
+
+	templatesToRun = slices.DeleteFunc(templatesToRun, func(t core.Template) bool {
+		return !args.inStage(t.Stage)
+	})
+
 	if len(templatesToRun) == 0 {
 		log.Debug().Str("type", RunnerTypeTemplate).Str("expr", args.Expr).Msg("no templates matching selector found")
 		return nil // nothing to run
@@ -85,6 +104,7 @@ func (tr *TemplateRunner) Execute(ctx context.Context, args ExecuteArgs) error {
 	var (
 		pathStyle            = lipgloss.NewStyle().Foreground(lipgloss.Color("#bb9af7"))
 		successStyle         = lipgloss.NewStyle().Foreground(lipgloss.Color("#22c55e"))
+		skippedStyle         = lipgloss.NewStyle().Foreground(lipgloss.Color("#e0af68"))
 		templateContentStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#9aa5ce"))
 	)
 
@@ -92,8 +112,27 @@ func (tr *TemplateRunner) Execute(ctx context.Context, args ExecuteArgs) error {
 		// Print styled header for template
 		fmt.Println(createStyledHeader("TEMPLATE", tmpl.Name, args.TerminalWidth))
 
+		reason, err := tmpl.SkipReason(ctx, hookShell(tr.cfg), tr.cfg.ConfigDir, args.Force)
+		if err != nil {
+			if err := args.fail(tmpl.Name, fmt.Errorf("template %s: %w", tmpl.Name, err)); err != nil {
+				return err
+			}
+			continue
+		}
+		if reason != "" {
+			log.Debug().Str("template", tmpl.Name).Str("reason", reason).Msg("skipped template")
+			args.Summary.skip(tmpl.Name, reason)
+			fmt.Printf("Status       %s\n", skippedStyle.Render("Skipped ("+reason+")"))
+			fmt.Printf("Output Path  %s\n", pathStyle.Render(tmpl.Output))
+			fmt.Println()
+			continue
+		}
+
 		if err := tr.engine.RenderTemplate(ctx, tmpl); err != nil {
-			return fmt.Errorf("failed to generate template %s: %w", tmpl.Name, err)
+			if err := args.fail(tmpl.Name, fmt.Errorf("failed to generate template %s: %w", tmpl.Name, err)); err != nil {
+				return err
+			}
+			continue
 		}
 
 		log.Debug().
@@ -102,6 +141,10 @@ func (tr *TemplateRunner) Execute(ctx context.Context, args ExecuteArgs) error {
 			Strs("tags", tmpl.Tags).
 			Msg("rendered template")
 
+		if args.Summary != nil {
+			args.Summary.Templates++
+		}
+
 		// Print Output Path and Status
 		fmt.Printf("Status       %s\n", successStyle.Render("Rendered"))
 		fmt.Printf("Output Path  %s\n", pathStyle.Render(tmpl.Output))
@@ -109,7 +152,7 @@ func (tr *TemplateRunner) Execute(ctx context.Context, args ExecuteArgs) error {
 
 		// Print Template Body label and content
 		fmt.Println("Template Body:")
-		templateLines := strings.SplitSeq(tmpl.Template, "\n")
+		templateLines := strings.SplitSeq(redact.String(tmpl.Template), "\n")
 		for line := range templateLines {
 			fmt.Println(templateContentStyle.Render("  " + line))
 		}
@@ -120,26 +163,61 @@ func (tr *TemplateRunner) Execute(ctx context.Context, args ExecuteArgs) error {
 	return nil
 }
 
-// Form implements Runner.
-func (tr *TemplateRunner) Field(ctx context.Context) huh.Field {
-	tr.formsActivated = true
-	tr.formsTemplateMap = map[string]core.Template{}
-	tr.formSelected = []string{}
+// checkRequiredVars validates the requires_vars of every template and prints
+// a report of all missing or invalid variables.
+func checkRequiredVars(ctx context.Context, engine *generator.Engine, templates []core.Template) error {
+	var problems []printer.KeyValueError
+	for _, tmpl := range templates {
+		err := engine.CheckRequiredVars(ctx, tmpl)
+
+		var varsErr *generator.RequiredVarsError
+		switch {
+		case errors.As(err, &varsErr):
+			for _, p := range varsErr.Problems {
+				problems = append(problems, printer.KeyValueError{Key: tmpl.Name, Message: p})
+			}
+		case err != nil:
+			return err
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
 
-	options := []huh.Option[string]{}
+	printer.New(os.Stdout).KeyValueValidationError("Missing template variables", problems)
+	return ValidationError(fmt.Errorf("%d required template variable(s) missing or invalid", len(problems)))
+}
 
+// Items implements Runner.
+func (tr *TemplateRunner) Items(ctx context.Context) []tui.SelectItem {
+	tr.formsTemplateMap = map[string]core.Template{}
+
+	items := make([]tui.SelectItem, 0, len(tr.cfg.Templates))
 	for _, tmpl := range tr.cfg.Templates {
-		displayStr := fmt.Sprintf("%s (%s)", tmpl.Name, strings.Join(tmpl.Tags, ", "))
-		options = append(options, huh.NewOption(displayStr, tmpl.Name))
 		tr.formsTemplateMap[tmpl.Name] = tmpl
+		items = append(items, tui.SelectItem{
+			Group: "Templates",
+			Name:  tmpl.Name,
+			Tags:  tmpl.Tags,
+			Preview: func() string {
+				body, err := generator.TemplateSource(tmpl.Template)
+				if err != nil {
+					return err.Error()
+				}
+				return body
+			},
+		})
 	}
 
-	if len(options) == 0 {
-		return nil
-	}
+	return items
+}
 
-	return huh.NewMultiSelect[string]().
-		Title("Select Templates to Generate").
-		Options(options...).
-		Value(&tr.formSelected)
+// Select implements Runner.
+func (tr *TemplateRunner) Select(indexes []int) {
+	tr.formsActivated = true
+	tr.formSelected = []string{}
+	for _, i := range indexes {
+		tr.formSelected = append(tr.formSelected, tr.cfg.Templates[i].Name)
+	}
 }
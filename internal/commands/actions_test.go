@@ -1,7 +1,14 @@
 package commands
 
 import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
 	"testing"
+
+	"github.com/hay-kot/mmdot/internal/core"
 )
 
 func Test_expandTagShortcuts(t *testing.T) {
@@ -35,6 +42,12 @@ func Test_expandTagShortcuts(t *testing.T) {
 			wantExpr:     `name == "test"`,
 			wantTagExprs: []string{`"env" in tags`},
 		},
+		{
+			name:         "dangling operator after shortcut",
+			input:        `!bootstrap and installed("tmux")`,
+			wantExpr:     `installed("tmux")`,
+			wantTagExprs: []string{`not ("bootstrap" in tags)`},
+		},
 	}
 
 	for _, tt := range tests {
@@ -165,7 +178,7 @@ func Test_compileExpr(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			program, err := compileExpr(tt.input, tt.macros, tt.enableExpansions)
+			program, err := compileExpr(context.Background(), tt.input, tt.macros, tt.enableExpansions)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("compileExpr() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -221,7 +234,7 @@ func Test_evalCompiledExpr(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			program, err := compileExpr(tt.expression, tt.macros, true)
+			program, err := compileExpr(context.Background(), tt.expression, tt.macros, true)
 			if err != nil {
 				t.Fatalf("compileExpr() unexpected error = %v", err)
 			}
@@ -237,3 +250,256 @@ func Test_evalCompiledExpr(t *testing.T) {
 		})
 	}
 }
+
+func Test_tagFilterExpr(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		want    string
+	}{
+		{
+			name: "empty",
+			want: "",
+		},
+		{
+			name:    "include only",
+			include: []string{"work", "dev"},
+			want:    `"work" in tags && "dev" in tags`,
+		},
+		{
+			name:    "include and exclude",
+			include: []string{"work"},
+			exclude: []string{"brew"},
+			want:    `"work" in tags && not ("brew" in tags)`,
+		},
+		{
+			name:    "blank entries ignored",
+			include: []string{"", " "},
+			exclude: []string{"brew"},
+			want:    `not ("brew" in tags)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tagFilterExpr(tt.include, tt.exclude); got != tt.want {
+				t.Errorf("tagFilterExpr() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_compileExpr_Filters(t *testing.T) {
+	filter := tagFilterExpr([]string{"work"}, []string{"brew"})
+
+	tests := []struct {
+		name       string
+		expression string
+		expansions bool
+		tags       []string
+		want       bool
+	}{
+		{
+			name: "filter only matches",
+			tags: []string{"work"},
+			want: true,
+		},
+		{
+			name: "filter only excludes",
+			tags: []string{"work", "brew"},
+			want: false,
+		},
+		{
+			name:       "expression and filter both match",
+			expression: "+dev",
+			expansions: true,
+			tags:       []string{"work", "dev"},
+			want:       true,
+		},
+		{
+			name:       "expression fails filter matches",
+			expression: "+dev",
+			expansions: true,
+			tags:       []string{"work"},
+			want:       false,
+		},
+		{
+			name:       "expansions disabled",
+			expression: `"dev" in tags`,
+			tags:       []string{"work", "dev"},
+			want:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program, err := compileExpr(context.Background(), tt.expression, nil, tt.expansions, filter)
+			if err != nil {
+				t.Fatalf("compileExpr() unexpected error = %v", err)
+			}
+
+			got, err := evalCompiledExpr(program, map[string]any{"tags": tt.tags})
+			if err != nil {
+				t.Fatalf("evalCompiledExpr() unexpected error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("evaluation result = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_compileExpr_Functions(t *testing.T) {
+	orig := installedPackages
+	installedPackages = func(context.Context) []string { return []string{"tmux", "homebrew/cask/firefox"} }
+	t.Cleanup(func() { installedPackages = orig })
+
+	tests := []struct {
+		expression string
+		tags       []string
+		want       bool
+	}{
+		{expression: `installed("tmux")`, want: true},
+		{expression: `installed("firefox")`, want: true},
+		{expression: `installed("neovim")`, want: false},
+		{expression: `command("sh")`, want: true},
+		{expression: `command("mmdot-does-not-exist")`, want: false},
+		{expression: `!bootstrap and installed("tmux")`, tags: []string{"shell"}, want: true},
+		{expression: `!bootstrap and installed("tmux")`, tags: []string{"bootstrap"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			program, err := compileExpr(context.Background(), tt.expression, nil, true)
+			if err != nil {
+				t.Fatalf("compileExpr() unexpected error = %v", err)
+			}
+
+			got, err := evalCompiledExpr(program, map[string]any{"tags": tt.tags})
+			if err != nil {
+				t.Fatalf("evalCompiledExpr() unexpected error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("evaluation result = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_runnerOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		order   []string
+		want    []RunnerType
+		wantErr bool
+	}{
+		{
+			name: "default",
+			want: []RunnerType{RunnerTypeTemplate, RunnerTypeScript, RunnerTypeService},
+		},
+		{
+			name:  "scripts first",
+			order: []string{"script", "template"},
+			want:  []RunnerType{RunnerTypeScript, RunnerTypeTemplate, RunnerTypeService},
+		},
+		{
+			// written before services existed, they must still run
+			name:  "order predating services",
+			order: []string{"template", "script"},
+			want:  []RunnerType{RunnerTypeTemplate, RunnerTypeScript, RunnerTypeService},
+		},
+		{
+			name:  "missing types appended",
+			order: []string{"script"},
+			want:  []RunnerType{RunnerTypeScript, RunnerTypeTemplate, RunnerTypeService},
+		},
+		{
+			name:    "duplicate",
+			order:   []string{"script", "script"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid",
+			order:   []string{"brew"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := runnerOrder(core.Run{Order: tt.order})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runnerOrder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("runnerOrder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ExecuteArgs_fail(t *testing.T) {
+	scriptErr := ScriptError(errors.New("exit status 1"))
+
+	args := ExecuteArgs{}
+	if err := args.fail("a.sh", scriptErr); err != scriptErr {
+		t.Errorf("fail() without KeepGoing = %v, want the error", err)
+	}
+
+	args = ExecuteArgs{KeepGoing: true, Summary: &RunSummary{}}
+	if err := args.fail("a.sh", scriptErr); err != nil {
+		t.Errorf("fail() with KeepGoing = %v, want nil", err)
+	}
+	if err := args.fail("zshrc", errors.New("render failed")); err != nil {
+		t.Errorf("fail() with KeepGoing = %v, want nil", err)
+	}
+
+	err := args.Summary.Err()
+	if err == nil || !slices.Equal(args.Summary.Failed, []string{"a.sh", "zshrc"}) {
+		t.Fatalf("Summary = %+v, Err() = %v", args.Summary, err)
+	}
+	if code := ExitCodeOf(err); code != int(ExitScript) {
+		t.Errorf("ExitCodeOf(Err()) = %d, want the first failure's %d", code, ExitScript)
+	}
+
+	args.Summary.Scripts = 1
+	if code := ExitCodeOf(args.Summary.Err()); code != int(ExitPartial) {
+		t.Errorf("ExitCodeOf(Err()) with successful items = %d, want %d", code, ExitPartial)
+	}
+
+	if err := (&RunSummary{}).Err(); err != nil {
+		t.Errorf("Err() without failures = %v", err)
+	}
+}
+
+func Test_ScriptRunner_preview(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "scripts", "brew.sh")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("#!/bin/bash\nbrew update\nbrew upgrade\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &core.ConfigFile{ConfigDir: dir}
+	cfg.Exec.Shell = "/bin/bash"
+	sr := NewScriptRunner(cfg)
+	sr.PreviewLines = 2
+
+	got := sr.preview(core.Script{Path: path, Tags: []string{"brew"}, Privileged: true})
+	want := `path       scripts/brew.sh
+shell      /bin/bash
+stage      main
+tags       brew
+privileged yes, runs through sudo or doas
+
+#!/bin/bash
+brew update`
+	if got != want {
+		t.Errorf("preview() = %q, want %q", got, want)
+	}
+}
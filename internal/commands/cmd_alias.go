@@ -0,0 +1,152 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"slices"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/pkgs/printer"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+type AliasCmd struct {
+	coreFlags *core.Flags
+}
+
+func NewAliasCmd(coreFlags *core.Flags) *AliasCmd {
+	return &AliasCmd{coreFlags: coreFlags}
+}
+
+func (ac *AliasCmd) Register(app *cli.Command) *cli.Command {
+	app.Commands = append(app.Commands, &cli.Command{
+		Name:      "x",
+		Usage:     "run an alias from the aliases section of the config",
+		ArgsUsage: "<alias> [args...]",
+		Description: `Expands an alias to the mmdot arguments it names and runs them. Arguments
+after the alias are appended, and global flags such as --config are passed
+through:
+
+  aliases:
+    work: run '@work !slow'
+    drift: status --exit-code --brew personal
+
+  mmdot x work          # mmdot run '@work !slow'
+  mmdot x work --list   # mmdot run '@work !slow' --list
+
+Without an alias the configured aliases are listed. Alias values are split
+like a shell command line, so quote expressions containing spaces.`,
+		SkipFlagParsing: true,
+		ShellComplete:   ac.complete,
+		Action:          ac.run,
+	})
+	return app
+}
+
+func (ac *AliasCmd) run(ctx context.Context, c *cli.Command) error {
+	// loadConfig changes to the config directory, the alias runs from here
+	// so relative --config paths still resolve
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(ac.coreFlags)
+	if err != nil {
+		return err
+	}
+
+	args := c.Args().Slice()
+	if len(args) == 0 {
+		return listAliases(cfg.Aliases)
+	}
+
+	name := args[0]
+	expanded, ok := cfg.Aliases.Args(name)
+	if !ok {
+		err := fmt.Errorf("no alias named %q", name)
+		if s := suggestName(name, slices.Collect(maps.Keys(cfg.Aliases))); s != "" {
+			err = fmt.Errorf("%w (did you mean %q?)", err, s)
+		}
+		return err
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	argv := append(globalArgs(ac.coreFlags, len(args)), expanded...)
+	argv = append(argv, args[1:]...)
+	log.Debug().Str("alias", name).Strs("args", argv).Msg("running alias")
+
+	cmd := exec.CommandContext(ctx, self, argv...)
+	cmd.Dir = wd
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	// the child reports its own errors, only its exit code is passed on
+	var exitErr *exec.ExitError
+	if err := cmd.Run(); errors.As(err, &exitErr) {
+		return Silence(&ExitError{Code: ExitCode(exitErr.ExitCode()), Err: err})
+	} else if err != nil {
+		return fmt.Errorf("failed to run alias %s: %w", name, err)
+	}
+	return nil
+}
+
+// globalArgs returns the global flags mmdot was started with, given the
+// number of arguments after "x", so the alias runs with the same config and
+// options. When they cannot be found only --config is passed on.
+func globalArgs(flags *core.Flags, nargs int) []string {
+	end := len(os.Args) - nargs - 1
+	if end >= 1 && os.Args[end] == "x" {
+		return slices.Clone(os.Args[1:end])
+	}
+
+	var args []string
+	for _, path := range flags.ConfigFilePaths {
+		args = append(args, "--config", path)
+	}
+	return args
+}
+
+func listAliases(aliases core.Aliases) error {
+	if len(aliases) == 0 {
+		fmt.Println("No aliases configured")
+		return nil
+	}
+
+	rows := make([][]string, 0, len(aliases))
+	for _, name := range slices.Sorted(maps.Keys(aliases)) {
+		rows = append(rows, []string{name, "mmdot " + aliases[name]})
+	}
+	printer.New(os.Stdout).Table("Aliases", []string{"NAME", "RUNS"}, rows)
+	return nil
+}
+
+// complete prints the alias names for shell completion. The root Before
+// hook does not run while completing, so the config flag is read directly.
+func (ac *AliasCmd) complete(ctx context.Context, c *cli.Command) {
+	if c.Args().Len() > 0 {
+		return
+	}
+
+	paths := c.Root().StringSlice("config")
+	if len(paths) == 0 {
+		return
+	}
+	cfg, err := core.LoadConfig(paths[0], paths[1:]...)
+	if err != nil {
+		return
+	}
+	for _, name := range slices.Sorted(maps.Keys(cfg.Aliases)) {
+		_, _ = fmt.Fprintln(c.Root().Writer, name)
+	}
+}
@@ -0,0 +1,315 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"syscall"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/plan"
+	"github.com/hay-kot/mmdot/internal/services"
+	"github.com/hay-kot/mmdot/pkgs/atomicwrite"
+	"github.com/hay-kot/mmdot/pkgs/printer"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+	"golang.org/x/term"
+)
+
+type ApplyCmd struct {
+	coreFlags *core.Flags
+}
+
+func NewApplyCmd(coreFlags *core.Flags) *ApplyCmd {
+	return &ApplyCmd{coreFlags: coreFlags}
+}
+
+func (ac *ApplyCmd) Register(app *cli.Command) *cli.Command {
+	cmd := &cli.Command{
+		Name:      "apply",
+		Usage:     "execute a plan file written by 'mmdot plan'",
+		ArgsUsage: "<plan-file>",
+		Description: `Executes exactly the changes recorded by 'mmdot plan', in order: packages are
+installed, then templates are written, scripts run, and services started stage
+by stage. Templates are written from the content in the plan. The config is
+only read for its age identity, to decrypt output the plan stores encrypted
+because it reads secrets.
+
+Nothing is applied when a file the plan writes or a script it runs has changed
+since the plan was computed. Compute a new plan instead.
+
+ ` + ExitCodesHelp,
+		Action: func(ctx context.Context, c *cli.Command) error {
+			if c.Args().Len() != 1 {
+				return fmt.Errorf("expected a single plan file")
+			}
+
+			p, err := plan.Read(c.Args().First())
+			if err != nil {
+				return err
+			}
+			return ac.apply(ctx, p)
+		},
+	}
+
+	app.Commands = append(app.Commands, cmd)
+	return app
+}
+
+func (ac *ApplyCmd) apply(ctx context.Context, p *plan.Plan) error {
+	if len(p.Changes) == 0 {
+		fmt.Println("No changes")
+		return nil
+	}
+
+	unlock, err := runLock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := checkStale(p); err != nil {
+		return err
+	}
+	if err := ac.openSealed(p); err != nil {
+		return err
+	}
+
+	var esc *core.Escalation
+	if slices.ContainsFunc(p.Changes, func(c plan.Change) bool { return c.Privileged }) {
+		found, err := core.FindEscalation()
+		if err != nil {
+			return ScriptError(err)
+		}
+		if err := found.Validate(ctx); err != nil {
+			return ScriptError(fmt.Errorf("failed to acquire privileges with %s: %w", filepath.Base(found.Tool), err))
+		}
+		esc = &found
+	}
+
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		width = 80
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	defer endCIGroup()
+
+	if err := applyPackages(ctx, p.Changes, width); err != nil {
+		return err
+	}
+
+	var (
+		pathStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#bb9af7"))
+		successStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#22c55e"))
+		manager      = services.Detect()
+	)
+
+	for _, c := range p.Changes {
+		switch c.Kind {
+		case plan.KindPackage:
+			continue
+		case plan.KindTemplate:
+			fmt.Println(createStyledHeader("TEMPLATE", c.Name, width))
+			if err := writePlanned(c); err != nil {
+				return fmt.Errorf("failed to write template %s: %w", c.Name, err)
+			}
+		case plan.KindService:
+			fmt.Println(createStyledHeader("SERVICE", c.Name, width))
+			if err := applyService(ctx, manager, c); err != nil {
+				return err
+			}
+		case plan.KindScript:
+			fmt.Println(createStyledHeader("SCRIPT", c.Name, width))
+			if err := runPlannedScript(ctx, p, c, esc); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown change kind %q", c.Kind)
+		}
+
+		log.Debug().Str("kind", string(c.Kind)).Str("name", c.Name).Str("action", string(c.Action)).Msg("applied change")
+
+		fmt.Printf("Status       %s\n", successStyle.Render(string(c.Action)))
+		if c.Path != "" {
+			fmt.Printf("Path         %s\n", pathStyle.Render(c.Path))
+		}
+		fmt.Println()
+	}
+
+	return nil
+}
+
+// checkStale fails when any change was invalidated since the plan was
+// computed, before anything is applied.
+func checkStale(p *plan.Plan) error {
+	var problems []printer.KeyValueError
+	for _, c := range p.Changes {
+		reason, err := c.Stale()
+		if err != nil {
+			return err
+		}
+		if reason != "" {
+			problems = append(problems, printer.KeyValueError{Key: c.Name, Message: reason + ": " + c.Path})
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	printer.New(os.Stdout).KeyValueValidationError("Plan is out of date", problems)
+	return ValidationError(fmt.Errorf("%d change(s) out of date, run 'mmdot plan' again", len(problems)))
+}
+
+// applyPackages installs the planned formulae and casks with one brew
+// command each. Packages with install arguments get a command of their own.
+func applyPackages(ctx context.Context, changes []plan.Change, width int) error {
+	var formulae, casks []string
+	var withArgs [][]string
+	for _, c := range changes {
+		switch {
+		case c.Kind != plan.KindPackage:
+		case len(c.Args) > 0 && c.Cask:
+			withArgs = append(withArgs, slices.Concat([]string{"--cask"}, c.Args, []string{c.Name}))
+		case len(c.Args) > 0:
+			withArgs = append(withArgs, slices.Concat(c.Args, []string{c.Name}))
+		case c.Cask:
+			casks = append(casks, c.Name)
+		default:
+			formulae = append(formulae, c.Name)
+		}
+	}
+	if len(formulae) == 0 && len(casks) == 0 && len(withArgs) == 0 {
+		return nil
+	}
+
+	fmt.Println(createStyledHeader("BREW", "install", width))
+	if core.Offline {
+		fmt.Printf("%d package(s) %v\n\n", len(formulae)+len(casks)+len(withArgs), core.ErrOffline)
+		return nil
+	}
+
+	install := func(args ...string) error {
+		cmd := exec.CommandContext(ctx, "brew", append([]string{"install"}, args...)...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		return cmd.Run()
+	}
+
+	var err error
+	if len(formulae) > 0 {
+		err = install(formulae...)
+	}
+	if err == nil && len(casks) > 0 {
+		err = install(append([]string{"--cask"}, casks...)...)
+	}
+	for _, args := range withArgs {
+		if err != nil {
+			break
+		}
+		err = install(args...)
+	}
+
+	if cacheErr := core.InvalidateBrewCache(); cacheErr != nil {
+		log.Warn().Err(cacheErr).Msg("failed to invalidate brew cache")
+	}
+	if err != nil {
+		return fmt.Errorf("brew install failed: %w", err)
+	}
+
+	fmt.Println()
+	return nil
+}
+
+// openSealed decrypts the content of encrypted changes with the config's age
+// identity before anything is applied.
+func (ac *ApplyCmd) openSealed(p *plan.Plan) error {
+	if !slices.ContainsFunc(p.Changes, func(c plan.Change) bool { return c.Encrypted }) {
+		return nil
+	}
+
+	cfg, err := loadConfig(ac.coreFlags)
+	if err != nil {
+		return err
+	}
+	identity, err := cfg.Age.ReadIdentity()
+	if err != nil {
+		return DecryptError(err)
+	}
+	for i := range p.Changes {
+		if err := p.Changes[i].Open(identity); err != nil {
+			return DecryptError(err)
+		}
+	}
+	return nil
+}
+
+// writePlanned writes the content of a template or service change.
+func writePlanned(c plan.Change) error {
+	perm, err := core.ParseOctalPermissions(c.Perm)
+	if err != nil {
+		return fmt.Errorf("invalid permissions %s: %w", c.Perm, err)
+	}
+	uid, gid, err := core.LookupOwnership(c.Owner, c.Group)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := atomicwrite.WriteFile(c.Path, []byte(c.Content), perm); err != nil {
+		return err
+	}
+	return core.Chown(c.Path, uid, gid)
+}
+
+func applyService(ctx context.Context, manager services.Manager, c plan.Change) error {
+	if c.Action != plan.ActionStart {
+		if err := writePlanned(c); err != nil {
+			return fmt.Errorf("failed to write service %s: %w", c.Name, err)
+		}
+		if err := manager.Reload(ctx); err != nil {
+			return err
+		}
+	}
+
+	if c.Enable {
+		if err := manager.Enable(ctx, c.Name); err != nil {
+			return fmt.Errorf("failed to enable service %s: %w", c.Name, err)
+		}
+	}
+	if c.Start {
+		if err := manager.Restart(ctx, c.Name); err != nil {
+			return fmt.Errorf("failed to start service %s: %w", c.Name, err)
+		}
+	}
+	return nil
+}
+
+func runPlannedScript(ctx context.Context, p *plan.Plan, c plan.Change, esc *core.Escalation) error {
+	if err := os.Chmod(c.Path, 0o755); err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, p.Shell, c.Path)
+	if c.Privileged {
+		cmd = esc.Command(ctx, p.Shell, c.Path)
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+	cmd.Dir = p.ConfigDir
+	cmd.Env = core.Exec{CleanEnv: p.CleanEnv, AllowEnv: p.AllowEnv}.ScriptEnv(os.Environ())
+
+	if err := cmd.Run(); err != nil {
+		return ScriptError(fmt.Errorf("script %s failed: %w", c.Name, err))
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/hay-kot/mmdot/internal/binaries"
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/pkgs/printer"
+	"github.com/urfave/cli/v3"
+)
+
+type BinariesCmd struct {
+	coreFlags *core.Flags
+	flags     struct {
+		Force            bool
+		VerifySignatures bool
+	}
+}
+
+func NewBinariesCmd(coreFlags *core.Flags) *BinariesCmd {
+	return &BinariesCmd{coreFlags: coreFlags}
+}
+
+func (bc *BinariesCmd) Register(app *cli.Command) *cli.Command {
+	cmd := &cli.Command{
+		Name:  "binaries",
+		Usage: "install tools from GitHub release assets",
+		Description: `Binaries declared under 'binaries:' are downloaded from GitHub releases,
+verified against their sha256 checksum and, when a signature is configured,
+a minisign or cosign signature made with one of trusted_keys, extracted below
+the mmdot state directory, and symlinked to their install path (default
+~/.local/bin/<name>). Set GITHUB_TOKEN to avoid API rate limits.`,
+		Commands: []*cli.Command{
+			{
+				Name:      "sync",
+				Usage:     "install binaries that are missing or not at the configured version",
+				ArgsUsage: "[binary-name...]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:        "force",
+						Usage:       "reinstall binaries that are already up to date",
+						Destination: &bc.flags.Force,
+					},
+					&cli.BoolFlag{
+						Name:        "verify-signatures",
+						Usage:       "fail binaries that have no signature configured instead of installing them",
+						Destination: &bc.flags.VerifySignatures,
+					},
+				},
+				Action: bc.sync,
+			},
+			{
+				Name:      "diff",
+				Usage:     "show binaries that are missing or outdated",
+				ArgsUsage: "[binary-name...]",
+				Action:    bc.diff,
+			},
+		},
+	}
+
+	app.Commands = append(app.Commands, cmd)
+	return app
+}
+
+// load returns the configured binaries, limited to names when provided, and
+// a client installing below the binaries directory.
+func (bc *BinariesCmd) load(c *cli.Command) ([]core.Binary, *binaries.Client, error) {
+	cfg, err := loadConfig(bc.coreFlags)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	selected := cfg.Binaries
+	if names := c.Args().Slice(); len(names) > 0 {
+		selected = nil
+		for _, name := range names {
+			idx := slices.IndexFunc(cfg.Binaries, func(b core.Binary) bool { return b.Name == name })
+			if idx == -1 {
+				return nil, nil, fmt.Errorf("binary %q not found", name)
+			}
+			selected = append(selected, cfg.Binaries[idx])
+		}
+	}
+
+	dir, err := core.BinariesDir()
+	if err != nil {
+		return nil, nil, err
+	}
+	client := binaries.NewClient(dir)
+	client.TrustedKeys = cfg.TrustedKeys
+	client.RequireSignature = bc.flags.VerifySignatures
+	return selected, client, nil
+}
+
+func (bc *BinariesCmd) sync(ctx context.Context, c *cli.Command) error {
+	selected, client, err := bc.load(c)
+	if err != nil {
+		return err
+	}
+
+	if len(selected) == 0 {
+		fmt.Println("No binaries configured")
+		return nil
+	}
+
+	failed := 0
+	items := make([]printer.StatusListItem, 0, len(selected))
+	for _, b := range selected {
+		item, ok := bc.syncOne(ctx, client, b)
+		if !ok {
+			failed++
+		}
+		items = append(items, item)
+	}
+
+	printer.New(os.Stdout).StatusList("Binaries:", items)
+
+	if failed > 0 {
+		return PartialError(fmt.Errorf("%d of %d binaries failed to install", failed, len(selected)))
+	}
+	return nil
+}
+
+// syncOne installs b when needed. It returns false when the install failed;
+// binaries skipped offline are not failures.
+func (bc *BinariesCmd) syncOne(ctx context.Context, client *binaries.Client, b core.Binary) (printer.StatusListItem, bool) {
+	fail := func(err error) (printer.StatusListItem, bool) {
+		return printer.StatusListItem{Ok: false, Status: fmt.Sprintf("%s: %v", b.Name, err)}, false
+	}
+
+	installed, err := client.Installed(b)
+	if err != nil {
+		return fail(err)
+	}
+	upToDate := func(tag string) bool { return installed == tag && !bc.flags.Force }
+
+	// Pinned versions are checked without querying GitHub
+	if upToDate(b.Version) {
+		return printer.StatusListItem{Ok: true, Status: fmt.Sprintf("%s %s (up to date)", b.Name, installed)}, true
+	}
+
+	rel, err := client.Release(ctx, b)
+	if errors.Is(err, core.ErrOffline) {
+		return printer.StatusListItem{Ok: false, Status: fmt.Sprintf("%s: %v", b.Name, core.ErrOffline)}, true
+	} else if err != nil {
+		return fail(err)
+	}
+	if upToDate(rel.Tag) {
+		return printer.StatusListItem{Ok: true, Status: fmt.Sprintf("%s %s (up to date)", b.Name, installed)}, true
+	}
+
+	verified, err := client.Install(ctx, b, rel)
+	if err != nil {
+		return fail(err)
+	}
+
+	status := fmt.Sprintf("%s %s -> %s", b.Name, rel.Tag, b.Install)
+	if installed != "" && installed != rel.Tag {
+		status = fmt.Sprintf("%s %s => %s -> %s", b.Name, installed, rel.Tag, b.Install)
+	}
+	switch verified {
+	case binaries.Unverified:
+		status += " (checksum not verified)"
+	case binaries.SignatureVerified:
+		status += " (signature verified)"
+	}
+	return printer.StatusListItem{Ok: true, Status: status}, true
+}
+
+func (bc *BinariesCmd) diff(ctx context.Context, c *cli.Command) error {
+	selected, client, err := bc.load(c)
+	if err != nil {
+		return err
+	}
+
+	changes := 0
+	items := make([]printer.StatusListItem, 0, len(selected))
+	for _, b := range selected {
+		st, err := client.Status(ctx, b)
+		if err != nil && !errors.Is(err, core.ErrOffline) {
+			return fmt.Errorf("binary %s: %w", b.Name, err)
+		}
+
+		var status string
+		switch {
+		case err != nil:
+			status = fmt.Sprintf("%s: latest release not checked, %v", b.Name, core.ErrOffline)
+		case st.Unmanaged:
+			status = fmt.Sprintf("%s: %s exists and is not managed by mmdot", b.Name, b.Install)
+		case st.Installed == "":
+			status = fmt.Sprintf("%s: not installed (want %s)", b.Name, st.Wanted)
+		case !st.Current():
+			status = fmt.Sprintf("%s: %s => %s", b.Name, st.Installed, st.Wanted)
+		default:
+			continue
+		}
+
+		changes++
+		items = append(items, printer.StatusListItem{Ok: false, Status: status})
+	}
+
+	if changes == 0 {
+		fmt.Println("All binaries are up to date")
+		return nil
+	}
+
+	printer.New(os.Stdout).StatusList("Outdated binaries:", items)
+	return nil
+}
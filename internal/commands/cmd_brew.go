@@ -2,15 +2,22 @@ package commands
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"maps"
 	"os"
+	"os/exec"
+	"path"
 	"slices"
 	"strings"
 
+	"github.com/charmbracelet/huh"
 	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/plan"
 	"github.com/hay-kot/mmdot/pkgs/printer"
+	"github.com/rs/zerolog/log"
 	"github.com/urfave/cli/v3"
+	"golang.org/x/term"
 )
 
 type BrewCmd struct {
@@ -29,21 +36,87 @@ func (bc *BrewCmd) Register(app *cli.Command) *cli.Command {
 			{
 				Name:      "diff",
 				Usage:     "Compare installed Homebrew packages with configuration",
-				ArgsUsage: "<brew-name>",
-				Description: `Compares the specified brew configuration with what's installed on the machine.
+				ArgsUsage: "<brew-name>...",
+				Description: `Compares the specified brew configurations with what's installed on the machine.
 Shows absent packages (in config but not installed), extra packages (installed but not in config),
 and optionally present packages (both in config and installed).
 
-Example: mmdot brew diff personal`,
+When several configurations are given (or --all), each one gets its own section of
+present and absent packages, followed by the extras and a summary for their union.
+
+Examples:
+  mmdot brew diff personal
+  mmdot brew diff personal work
+  mmdot brew diff --all`,
 				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "all",
+						Usage: "compare every brew configuration",
+					},
 					&cli.BoolFlag{
 						Name:    "verbose",
 						Aliases: []string{"v"},
 						Usage:   "display packages that are both in config and installed on the machine",
 					},
+					&cli.BoolFlag{
+						Name:  "refresh",
+						Usage: "ignore the cached list of installed packages",
+					},
+					&cli.DurationFlag{
+						Name:  "cache-ttl",
+						Usage: "reuse the installed package list for this long (0 disables caching)",
+						Value: core.DefaultBrewCacheTTL,
+					},
 				},
 				Action: bc.diff,
 			},
+			{
+				Name:      "sync",
+				Usage:     "Install absent packages, and with --cleanup uninstall packages no config lists",
+				ArgsUsage: "<brew-name>...",
+				Description: `Installs the packages of the specified brew configurations that are not
+installed, with the args each package is configured with. Configurations with
+'remove: true' are skipped.
+
+With --cleanup the selected configurations become authoritative: extra
+packages, formulae installed on request and casks that none of them lists, are
+uninstalled after confirmation. Packages matching a 'protected' entry of a
+selected configuration (globs such as 'font-*' work) are always kept. Pass
+--all so packages from every configuration are kept.
+
+Examples:
+  mmdot brew sync personal
+  mmdot brew sync --all --cleanup --dry-run
+  mmdot brew sync --all --cleanup --yes`,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "all",
+						Usage: "sync every brew configuration",
+					},
+					&cli.BoolFlag{
+						Name:  "cleanup",
+						Usage: "uninstall packages that no selected configuration lists",
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "print what would be installed and uninstalled without changing anything",
+					},
+					&cli.BoolFlag{
+						Name:    "yes",
+						Aliases: []string{"y"},
+						Usage:   "uninstall without asking for confirmation",
+					},
+				},
+				Action: bc.sync,
+			},
+			{
+				Name:      "validate",
+				Usage:     "Check brew configurations for missing or circular includes",
+				ArgsUsage: "[brew-name]...",
+				Description: `Resolves the includes of each brew configuration (all of them when no names
+are given) and reports includes that reference undefined configs or form a cycle.`,
+				Action: bc.validate,
+			},
 		},
 	}
 
@@ -52,30 +125,266 @@ Example: mmdot brew diff personal`,
 }
 
 func (bc *BrewCmd) diff(ctx context.Context, c *cli.Command) error {
-	cfg, err := core.SetupEnv(bc.flags.ConfigFilePath)
+	cfg, err := loadConfig(bc.flags)
+	if err != nil {
+		return err
+	}
+
+	names, err := brewNames(&cfg, c)
+	if err != nil {
+		return err
+	}
+
+	installed := core.InstalledBrews(ctx, core.InstalledBrewsOptions{
+		TTL:     c.Duration("cache-ttl"),
+		Refresh: c.Bool("refresh"),
+	})
+
+	verbose := c.Bool("verbose")
+	p := printer.New(os.Stdout)
+	p.LineBreak()
+
+	if len(names) == 1 {
+		diff := cfg.Brews.Get(names[0]).DiffInstalled(installed)
+		printBrewDiff(p, diff, verbose)
+		printBrewSummary(diff)
+		return nil
+	}
+
+	// Per-config sections report what each config is missing; extras are only
+	// meaningful against the union of all selected configs.
+	for _, name := range names {
+		diff := cfg.Brews.Get(name).DiffInstalled(installed)
+		p.Title(fmt.Sprintf("%s (%d present, %d absent)", name, len(diff.Present), len(diff.Absent)))
+		if !verbose && len(diff.Absent) == 0 {
+			p.LineBreak()
+			continue
+		}
+		printBrewDiff(p, &core.DiffResult{Present: diff.Present, Absent: diff.Absent}, verbose)
+	}
+
+	total := cfg.Brews.Merge(names...).DiffInstalled(installed)
+	if len(total.Extra) > 0 {
+		p.List("Extra Brews (not in any selected config):", total.Extra)
+		p.LineBreak()
+	}
+
+	fmt.Printf("Configs: %s\n", strings.Join(names, ", "))
+	printBrewSummary(total)
+
+	return nil
+}
+
+func (bc *BrewCmd) sync(ctx context.Context, c *cli.Command) error {
+	cfg, err := loadConfig(bc.flags)
 	if err != nil {
 		return err
 	}
-	keys := slices.Collect(maps.Keys(cfg.Brews))
-	arg := c.Args().First()
-	if arg == "" || !slices.Contains(keys, arg) {
-		return fmt.Errorf("invalid brew, please provide one of: %v", strings.Join(keys, ", "))
+
+	names, err := brewNames(&cfg, c)
+	if err != nil {
+		return err
 	}
-	brewCfg := cfg.Brews.Get(arg)
-	if brewCfg == nil {
-		return fmt.Errorf("brew config %q not found", arg)
+	names = slices.DeleteFunc(names, func(name string) bool { return cfg.Brews[name].Remove })
+	if len(names) == 0 {
+		return fmt.Errorf("every selected brew config has remove: true, nothing to sync")
 	}
-	diff, err := brewCfg.Diff()
+
+	installed, err := core.LoadInstalledBrews(ctx, core.InstalledBrewsOptions{Refresh: true})
 	if err != nil {
 		return err
 	}
 
-	// Process and display results with consistent spacing
+	merged := cfg.Brews.Merge(names...)
+	diff := merged.DiffInstalled(installed)
+
+	var remove, protected []string
+	if c.Bool("cleanup") {
+		remove, protected = cleanupPackages(merged, diff.Extra)
+	}
+
 	p := printer.New(os.Stdout)
-	p.LineBreak()
+	if len(diff.Absent) > 0 {
+		p.List("Install:", diff.Absent)
+		p.LineBreak()
+	}
+	if len(remove) > 0 {
+		p.List("Uninstall:", remove)
+		p.LineBreak()
+	}
+	if len(protected) > 0 {
+		p.List("Protected (kept):", protected)
+		p.LineBreak()
+	}
+	if len(diff.Absent) == 0 && len(remove) == 0 {
+		fmt.Println("Installed packages match the config")
+		return nil
+	}
+	if c.Bool("dry-run") {
+		return nil
+	}
+	if core.Offline {
+		// brew auto-updates before uninstalling too, so nothing is changed
+		fmt.Printf("%d install(s), %d uninstall(s) %v\n", len(diff.Absent), len(remove), core.ErrOffline)
+		return nil
+	}
 
-	// Present items section
-	if c.Bool("verbose") {
+	if len(remove) > 0 && !c.Bool("yes") {
+		if core.CI || !term.IsTerminal(int(os.Stdin.Fd())) {
+			return fmt.Errorf("refusing to uninstall %d package(s) without confirmation, pass --yes", len(remove))
+		}
+
+		confirmed := false
+		confirm := huh.NewConfirm().
+			Title(fmt.Sprintf("Uninstall %d package(s) no selected config lists?", len(remove))).
+			Value(&confirmed)
+		if err := huh.NewForm(huh.NewGroup(confirm)).RunWithContext(ctx); err != nil {
+			return err
+		}
+		if !confirmed {
+			remove = nil
+		}
+	}
+
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		width = 80
+	}
+	defer endCIGroup()
+
+	changes := make([]plan.Change, 0, len(diff.Absent))
+	for _, pkg := range diff.Absent {
+		changes = append(changes, installChange(merged, pkg))
+	}
+	if err := applyPackages(ctx, changes, width); err != nil {
+		return err
+	}
+
+	if len(remove) > 0 {
+		fmt.Println(createStyledHeader("BREW", "uninstall", width))
+		cmd := exec.CommandContext(ctx, "brew", append([]string{"uninstall"}, remove...)...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		err := cmd.Run()
+		if cacheErr := core.InvalidateBrewCache(); cacheErr != nil {
+			log.Warn().Err(cacheErr).Msg("failed to invalidate brew cache")
+		}
+		if err != nil {
+			return fmt.Errorf("brew uninstall failed: %w", err)
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("Installed %d, uninstalled %d package(s)\n", len(diff.Absent), len(remove))
+	return nil
+}
+
+func (bc *BrewCmd) validate(ctx context.Context, c *cli.Command) error {
+	cfg, err := loadConfig(bc.flags)
+	if err != nil {
+		return err
+	}
+
+	keys := slices.Sorted(maps.Keys(cfg.Brews))
+	names := c.Args().Slice()
+	if len(names) == 0 {
+		names = keys
+	}
+
+	failed := 0
+	items := make([]printer.StatusListItem, 0, len(names))
+	for _, name := range names {
+		if cfg.Brews[name] == nil {
+			failed++
+			items = append(items, printer.StatusListItem{Ok: false, Status: fmt.Sprintf("%s: not defined", name)})
+			continue
+		}
+
+		errs := cfg.Brews.ValidateIncludes(name)
+		if len(errs) == 0 {
+			items = append(items, printer.StatusListItem{Ok: true, Status: name})
+			continue
+		}
+
+		failed++
+		for _, err := range errs {
+			items = append(items, printer.StatusListItem{Ok: false, Status: err.Error()})
+		}
+	}
+
+	printer.New(os.Stdout).StatusList("Brew configs:", items)
+
+	if failed > 0 {
+		return ValidationError(fmt.Errorf("%d brew config(s) failed validation", failed))
+	}
+	return nil
+}
+
+// cleanupPackages splits the extra packages of brews into those to
+// uninstall and those kept because they are protected.
+func cleanupPackages(brews *core.Brews, extra []string) (remove, protected []string) {
+	// brew lists formulae from third-party taps by their full name, so
+	// compare base names too rather than uninstall a configured package
+	configured := map[string]bool{}
+	for _, name := range core.PackageNames(slices.Concat(brews.Brews, brews.Casks)) {
+		configured[path.Base(name)] = true
+	}
+
+	for _, pkg := range extra {
+		switch {
+		case configured[path.Base(pkg)]:
+		case brews.IsProtected(pkg) || brews.IsProtected(path.Base(pkg)):
+			protected = append(protected, pkg)
+		default:
+			remove = append(remove, pkg)
+		}
+	}
+	return remove, protected
+}
+
+// brewNames returns the brew configs named by the arguments, or every config
+// with --all, after checking that they exist and their includes resolve.
+func brewNames(cfg *core.ConfigFile, c *cli.Command) ([]string, error) {
+	keys := slices.Sorted(maps.Keys(cfg.Brews))
+	names := c.Args().Slice()
+	if c.Bool("all") {
+		names = keys
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("invalid brew, please provide one of: %v", strings.Join(keys, ", "))
+	}
+	for _, name := range names {
+		if !slices.Contains(keys, name) {
+			return nil, fmt.Errorf("brew config %q not found, please provide one of: %v", name, strings.Join(keys, ", "))
+		}
+	}
+
+	if err := validateBrewIncludes(cfg.Brews, names); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// validateBrewIncludes returns a validation error listing every broken include
+// reachable from the named configs.
+func validateBrewIncludes(brews core.ConfigMap, names []string) error {
+	var errs []error
+	for _, name := range names {
+		for _, err := range brews.ValidateIncludes(name) {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return ValidationError(errors.Join(errs...))
+	}
+	return nil
+}
+
+// printBrewDiff prints the present (when verbose), absent, and extra sections
+// of diff. Empty sections other than present are omitted.
+func printBrewDiff(p *printer.Printer, diff *core.DiffResult, verbose bool) {
+	if verbose {
 		var statusItems []printer.StatusListItem
 		if len(diff.Present) > 0 {
 			for _, item := range diff.Present {
@@ -94,7 +403,6 @@ func (bc *BrewCmd) diff(ctx context.Context, c *cli.Command) error {
 		p.LineBreak()
 	}
 
-	// Absent items section
 	if len(diff.Absent) > 0 {
 		var statusItems []printer.StatusListItem
 		for _, item := range diff.Absent {
@@ -107,23 +415,19 @@ func (bc *BrewCmd) diff(ctx context.Context, c *cli.Command) error {
 		p.LineBreak()
 	}
 
-	// Extra items section
 	if len(diff.Extra) > 0 {
 		p.List("Extra Brews:", diff.Extra)
 		p.LineBreak()
 	}
+}
 
-	// Display summary
+func printBrewSummary(diff *core.DiffResult) {
 	totalConfig := len(diff.Present) + len(diff.Absent) + len(diff.Extra)
-	summaryText := fmt.Sprintf(
-		"Summary: %d brews in config (%d present, %d absent, %d excluded)",
+	fmt.Printf(
+		"Summary: %d brews in config (%d present, %d absent, %d excluded)\n",
 		totalConfig,
 		len(diff.Present),
 		len(diff.Absent),
 		len(diff.Extra),
 	)
-	fmt.Println(summaryText)
-
-	return nil
 }
-
@@ -0,0 +1,26 @@
+package commands
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/hay-kot/mmdot/internal/core"
+)
+
+func Test_cleanupPackages(t *testing.T) {
+	brews := &core.Brews{
+		Brews:     []core.BrewPackage{{Name: "git"}, {Name: "terraform"}},
+		Casks:     []core.BrewPackage{{Name: "firefox"}},
+		Protected: []string{"mas", "font-*"},
+	}
+	extra := []string{"hashicorp/tap/terraform", "htop", "mas", "font-fira-code", "slack"}
+
+	remove, protected := cleanupPackages(brews, extra)
+
+	if want := []string{"htop", "slack"}; !slices.Equal(remove, want) {
+		t.Errorf("remove = %v, want %v", remove, want)
+	}
+	if want := []string{"mas", "font-fira-code"}; !slices.Equal(protected, want) {
+		t.Errorf("protected = %v, want %v", protected, want)
+	}
+}
@@ -0,0 +1,298 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"filippo.io/age"
+	"github.com/hay-kot/mmdot/internal/bundle"
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/generator"
+	"github.com/hay-kot/mmdot/pkgs/fcrypt"
+	"github.com/hay-kot/mmdot/pkgs/printer"
+	"github.com/urfave/cli/v3"
+)
+
+type BundleCmd struct {
+	coreFlags *core.Flags
+	flags     struct {
+		Expr       string
+		Output     string
+		Recipients []string
+		Brews      []string
+	}
+}
+
+func NewBundleCmd(coreFlags *core.Flags) *BundleCmd {
+	return &BundleCmd{coreFlags: coreFlags}
+}
+
+func (bc *BundleCmd) Register(app *cli.Command) *cli.Command {
+	cmd := &cli.Command{
+		Name:  "bundle",
+		Usage: "package rendered dotfiles for machines without access to this repository",
+		Commands: []*cli.Command{
+			{
+				Name:  "export",
+				Usage: "write a tarball of rendered templates, secrets, and brew scripts",
+				Description: `Renders the templates selected by --expr and writes them, together with
+age.files and compiled brew scripts, to a gzipped tarball that can be copied
+to an air-gapped machine and installed with the apply.sh script inside it:
+
+	tar -xzf mmdot-bundle.tar.gz -C bundle && ./bundle/apply.sh
+
+Files below your home directory are installed below $HOME on the target.
+age.files are decrypted with your identity and re-encrypted to the target's
+public key (--recipient); apply.sh decrypts them with the age CLI using
+$AGE_IDENTITY (default ~/.config/age/key.txt). Without --recipient, age.files
+are left out of the bundle. Brew scripts are included but not run by
+apply.sh.
+
+Templates that read vault or secret:// variables are encrypted to --recipient
+like age.files. Without --recipient such templates are refused; leave them out
+with --expr. The bundle itself is only readable by you.
+
+Examples:
+	 mmdot bundle export --expr '+server' --recipient age1...
+	 mmdot bundle export -o laptop.tar.gz --brew personal`,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "expr",
+						Usage:       "expression selecting the templates to include",
+						Destination: &bc.flags.Expr,
+					},
+					&cli.StringFlag{
+						Name:        "output",
+						Aliases:     []string{"o"},
+						Usage:       "write the bundle to `FILE`",
+						Value:       "mmdot-bundle.tar.gz",
+						Destination: &bc.flags.Output,
+					},
+					&cli.StringSliceFlag{
+						Name:        "recipient",
+						Aliases:     []string{"r"},
+						Usage:       "age public `KEY` of the target machine (repeatable)",
+						Destination: &bc.flags.Recipients,
+					},
+					&cli.StringSliceFlag{
+						Name:        "brew",
+						Usage:       "brew configurations to compile (default: all)",
+						Destination: &bc.flags.Brews,
+					},
+				},
+				Action: bc.export,
+			},
+		},
+	}
+
+	app.Commands = append(app.Commands, cmd)
+	return app
+}
+
+func (bc *BundleCmd) export(ctx context.Context, c *cli.Command) error {
+	cfg, err := loadConfig(bc.coreFlags)
+	if err != nil {
+		return err
+	}
+
+	program, err := compileExpr(ctx, bc.flags.Expr, cfg.Macros, true)
+	if err != nil {
+		return fmt.Errorf("failed to compile expression: %w", err)
+	}
+
+	var templates []core.Template
+	for _, tmpl := range cfg.Templates {
+		enabled, err := evalCompiledExpr(program, map[string]any{
+			"tags": tmpl.Tags,
+			"name": tmpl.Name,
+		})
+		if err != nil {
+			return fmt.Errorf("expression evaluation failed for template %s: %w", tmpl.Name, err)
+		}
+		if enabled {
+			templates = append(templates, tmpl)
+		}
+	}
+
+	brews := bc.flags.Brews
+	if len(brews) == 0 {
+		brews = slices.Sorted(maps.Keys(cfg.Brews))
+	}
+	for _, name := range brews {
+		if cfg.Brews[name] == nil {
+			return ValidationError(fmt.Errorf("brew %q is not defined", name))
+		}
+	}
+	if err := validateBrewIncludes(cfg.Brews, brews); err != nil {
+		return err
+	}
+
+	if err := askPrompts(ctx, &cfg, true); err != nil {
+		return err
+	}
+
+	engine := generator.NewEngine(&cfg)
+	if err := checkRequiredVars(ctx, engine, templates); err != nil {
+		return err
+	}
+
+	var recipients []age.Recipient
+	if len(bc.flags.Recipients) > 0 {
+		if recipients, err = fcrypt.LoadPublicKeys(bc.flags.Recipients); err != nil {
+			return fmt.Errorf("failed to load public keys: %w", err)
+		}
+	}
+
+	// Templates reading secrets are encrypted, so the target key is needed
+	// before anything is written
+	secret := make([]bool, len(templates))
+	for i, tmpl := range templates {
+		if secret[i], err = engine.ReadsSecrets(ctx, tmpl); err != nil {
+			return fmt.Errorf("template %s: %w", tmpl.Name, err)
+		}
+		if secret[i] && len(recipients) == 0 {
+			return ValidationError(printer.WithTitle("Template reads secrets",
+				fmt.Errorf("template %s reads vault or secret:// variables and would be bundled in plaintext", tmpl.Name),
+				"pass --recipient with the target's age public key to encrypt it",
+				"or leave it out with --expr",
+			))
+		}
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(bc.flags.Output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	if err := f.Chmod(0o600); err != nil { // an existing bundle keeps its mode otherwise
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+
+	w := bundle.NewWriter(f, home)
+	var items []printer.StatusListItem
+
+	for i, tmpl := range templates {
+		output, err := engine.Render(ctx, tmpl)
+		if err != nil {
+			return fmt.Errorf("failed to render template %s: %w", tmpl.Name, err)
+		}
+
+		perm := os.FileMode(0o644)
+		if tmpl.Permissions != "" {
+			if perm, err = core.ParseOctalPermissions(tmpl.Permissions); err != nil {
+				return fmt.Errorf("template %s: %w", tmpl.Name, err)
+			}
+		}
+
+		if secret[i] {
+			var ciphertext bytes.Buffer
+			if err := fcrypt.EncryptReader(bytes.NewReader(output), &ciphertext, recipients); err != nil {
+				return fmt.Errorf("failed to encrypt template %s: %w", tmpl.Name, err)
+			}
+			if err := w.AddSecret(tmpl.Output, ciphertext.Bytes(), perm); err != nil {
+				return err
+			}
+			items = append(items, printer.StatusListItem{Ok: true, Status: fmt.Sprintf("template %s -> %s (encrypted)", tmpl.Name, tmpl.Output)})
+			continue
+		}
+
+		if err := w.AddFile(tmpl.Output, output, perm); err != nil {
+			return err
+		}
+		items = append(items, printer.StatusListItem{Ok: true, Status: fmt.Sprintf("template %s -> %s", tmpl.Name, tmpl.Output)})
+	}
+
+	secrets, err := bc.addSecrets(w, cfg.Age, recipients)
+	if err != nil {
+		return err
+	}
+	items = append(items, secrets...)
+
+	for _, name := range brews {
+		script, err := engine.Render(ctx, core.Template{
+			Name:     "brew-" + name,
+			Template: fmt.Sprintf("{{template \"brewscript\" %q}}\n", name),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to compile brew %s: %w", name, err)
+		}
+		if err := w.AddBrewScript(name, script); err != nil {
+			return err
+		}
+		items = append(items, printer.StatusListItem{Ok: true, Status: fmt.Sprintf("brew %s", name)})
+	}
+
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	printer.New(os.Stdout).StatusList("Bundled in "+bc.flags.Output+":", items)
+	return nil
+}
+
+// addSecrets re-encrypts every age file to the target recipients. Nothing is
+// added when no recipients were given.
+func (bc *BundleCmd) addSecrets(w *bundle.Writer, a core.Age, recipients []age.Recipient) ([]printer.StatusListItem, error) {
+	if len(a.Files) == 0 {
+		return nil, nil
+	}
+
+	if len(recipients) == 0 {
+		return []printer.StatusListItem{{
+			Ok:     false,
+			Status: fmt.Sprintf("%d age files skipped (pass --recipient to include them)", len(a.Files)),
+		}}, nil
+	}
+
+	identity, err := a.ReadIdentity()
+	if err != nil {
+		return nil, DecryptError(err)
+	}
+
+	items := make([]printer.StatusListItem, 0, len(a.Files))
+	for _, af := range a.Files {
+		src, err := os.Open(af.Src)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", af.Src, err)
+		}
+
+		var plaintext bytes.Buffer
+		err = fcrypt.DecryptReader(src, &plaintext, identity)
+		_ = src.Close()
+		if err != nil {
+			return nil, DecryptError(fmt.Errorf("failed to decrypt %s: %w", af.Src, err))
+		}
+
+		var ciphertext bytes.Buffer
+		if err := fcrypt.EncryptReader(&plaintext, &ciphertext, recipients); err != nil {
+			return nil, fmt.Errorf("failed to encrypt %s: %w", af.Src, err)
+		}
+
+		perm := os.FileMode(0o600)
+		if af.Permissions != "" {
+			if perm, err = core.ParseOctalPermissions(af.Permissions); err != nil {
+				return nil, fmt.Errorf("age file %s: %w", af.Src, err)
+			}
+		}
+
+		if err := w.AddSecret(af.Dest, ciphertext.Bytes(), perm); err != nil {
+			return nil, err
+		}
+		items = append(items, printer.StatusListItem{Ok: true, Status: fmt.Sprintf("secret %s -> %s", filepath.Base(af.Src), af.Dest)})
+	}
+
+	return items, nil
+}
@@ -0,0 +1,246 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"github.com/hay-kot/mmdot/internal/binaries"
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/pkgs/fcrypt"
+	"github.com/hay-kot/mmdot/pkgs/printer"
+	"github.com/urfave/cli/v3"
+)
+
+// tempFileAge is how old a leftover temp file must be before clean removes
+// it, so files of a command that is still running are not touched.
+const tempFileAge = time.Hour
+
+type CleanCmd struct {
+	coreFlags *core.Flags
+	flags     struct {
+		DryRun bool
+	}
+}
+
+func NewCleanCmd(coreFlags *core.Flags) *CleanCmd {
+	return &CleanCmd{coreFlags: coreFlags}
+}
+
+func (cc *CleanCmd) Register(app *cli.Command) *cli.Command {
+	cmd := &cli.Command{
+		Name:  "clean",
+		Usage: "remove decrypted plaintexts, old binary versions, expired caches, and temp files",
+		Description: `Removes files mmdot leaves behind that are not needed anymore:
+
+  - plaintext copies of vault var files whose contents match their decrypted
+    .age copy; plaintexts that differ or cannot be compared are kept
+  - versions of installed binaries that are no longer linked
+  - the installed Homebrew package cache once it has expired
+  - .mmdot-* temp files in the config directory and binaries directory, and
+    mmdot-services-* directories in the system temp directory, older than an hour
+
+Use --dry-run to list what would be removed.`,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:        "dry-run",
+				Usage:       "list what would be removed without removing anything",
+				Destination: &cc.flags.DryRun,
+			},
+		},
+		Action: cc.clean,
+	}
+
+	app.Commands = append(app.Commands, cmd)
+	return app
+}
+
+// cleanTarget is a file or directory clean removes.
+type cleanTarget struct {
+	Path   string
+	Reason string
+}
+
+func (cc *CleanCmd) clean(ctx context.Context, c *cli.Command) error {
+	cfg, err := loadConfig(cc.coreFlags)
+	if err != nil {
+		return err
+	}
+
+	targets, kept, err := vaultPlaintexts(cfg)
+	if err != nil {
+		return err
+	}
+
+	binDir, err := core.BinariesDir()
+	if err != nil {
+		return err
+	}
+	unused, err := binaries.NewClient(binDir).Unused(cfg.Binaries)
+	if err != nil {
+		return err
+	}
+	for _, dir := range unused {
+		targets = append(targets, cleanTarget{dir, "unused binary version"})
+	}
+
+	cache, err := core.ExpiredBrewCache(core.DefaultBrewCacheTTL)
+	if err != nil {
+		return err
+	}
+	if cache != "" {
+		targets = append(targets, cleanTarget{cache, "expired brew cache"})
+	}
+
+	temps, err := tempFiles(time.Now().Add(-tempFileAge), cfg.ConfigDir, binDir)
+	if err != nil {
+		return err
+	}
+	targets = append(targets, temps...)
+
+	p := printer.New(os.Stdout)
+	if len(kept) > 0 {
+		items := make([]printer.StatusListItem, 0, len(kept))
+		for _, t := range kept {
+			items = append(items, printer.StatusListItem{Status: fmt.Sprintf("%s (%s)", relToCwd(t.Path), t.Reason)})
+		}
+		p.StatusList("Kept:", items)
+	}
+
+	if len(targets) == 0 {
+		fmt.Println("Nothing to clean")
+		return nil
+	}
+
+	items := make([]printer.StatusListItem, 0, len(targets))
+	for _, t := range targets {
+		if !cc.flags.DryRun {
+			if err := os.RemoveAll(t.Path); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", t.Path, err)
+			}
+		}
+		items = append(items, printer.StatusListItem{Ok: true, Status: fmt.Sprintf("%s (%s)", relToCwd(t.Path), t.Reason)})
+	}
+
+	title := "Removed:"
+	if cc.flags.DryRun {
+		title = "Would remove:"
+	}
+	p.StatusList(title, items)
+	return nil
+}
+
+// vaultPlaintexts returns the plaintext vault var files whose contents match
+// their decrypted .age copy, and separately those that could not be confirmed
+// to match, which are kept.
+func vaultPlaintexts(cfg core.ConfigFile) (remove, kept []cleanTarget, err error) {
+	var (
+		identity    age.Identity
+		identityErr error
+		loaded      bool
+	)
+
+	for _, file := range cfg.EncryptedFiles() {
+		plain := strings.TrimSuffix(file, ".age")
+		encrypted := plain + ".age"
+		name := filepath.Base(encrypted)
+
+		plaintext, err := os.ReadFile(plain)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		ciphertext, err := os.Open(encrypted)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // the plaintext is the only copy
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if fcrypt.IsEncrypted(plaintext) {
+			_ = ciphertext.Close()
+			continue // misnamed, not a plaintext
+		}
+
+		if !loaded {
+			identity, identityErr = cfg.Age.ReadIdentity()
+			loaded = true
+		}
+		if identityErr != nil {
+			_ = ciphertext.Close()
+			kept = append(kept, cleanTarget{plain, "no age identity to compare it with " + name})
+			continue
+		}
+
+		var decrypted bytes.Buffer
+		err = fcrypt.DecryptReader(ciphertext, &decrypted, core.IdentityFor(identity, encrypted))
+		_ = ciphertext.Close()
+		switch {
+		case err != nil:
+			kept = append(kept, cleanTarget{plain, "could not decrypt " + name})
+		case !bytes.Equal(plaintext, decrypted.Bytes()):
+			kept = append(kept, cleanTarget{plain, "differs from " + name})
+		default:
+			remove = append(remove, cleanTarget{plain, "decrypted vault file"})
+		}
+	}
+	return remove, kept, nil
+}
+
+// tempFiles returns the temp files mmdot leaves behind when interrupted that
+// were last modified before cutoff. Git directories are not searched.
+func tempFiles(cutoff time.Time, dirs ...string) ([]cleanTarget, error) {
+	var targets []cleanTarget
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if d.IsDir() && d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			if d.IsDir() || !strings.HasPrefix(d.Name(), ".mmdot-") {
+				return nil
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if info.ModTime().Before(cutoff) {
+				targets = append(targets, cleanTarget{path, "temp file"})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	matches, err := filepath.Glob(filepath.Join(os.TempDir(), "mmdot-services-*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err == nil && info.ModTime().Before(cutoff) {
+			targets = append(targets, cleanTarget{path, "temp directory"})
+		}
+	}
+
+	return targets, nil
+}
@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/pkgs/fcrypt"
+)
+
+func TestVaultPlaintexts(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir := t.TempDir()
+
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(dir, "key.txt")
+	if err := os.WriteFile(keyPath, []byte(id.String()+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	encrypt := func(name, content string) {
+		var buf bytes.Buffer
+		if err := fcrypt.EncryptReader(strings.NewReader(content), &buf, []age.Recipient{id.Recipient()}); err != nil {
+			t.Fatal(err)
+		}
+		write(name, buf.String())
+	}
+
+	same := write("same.yml", "token: a\n")
+	encrypt("same.yml.age", "token: a\n")
+	edited := write("edited.yml", "token: b\n")
+	encrypt("edited.yml.age", "token: a\n")
+	corrupt := write("corrupt.yml", "token: c\n")
+	write("corrupt.yml.age", "not age")
+	write("only.yml", "token: d\n") // no encrypted copy
+
+	cfg := core.ConfigFile{
+		Age: core.Age{IdentityFile: keyPath},
+		Variables: core.Variables{VarFiles: []core.VarFile{
+			{Path: filepath.Join(dir, "same.yml.age"), IsVault: true},
+			{Path: filepath.Join(dir, "edited.yml"), IsVault: true},
+			{Path: filepath.Join(dir, "corrupt.yml"), IsVault: true},
+			{Path: filepath.Join(dir, "only.yml"), IsVault: true},
+			{Path: filepath.Join(dir, "missing.yml"), IsVault: true},
+		}},
+	}
+
+	remove, kept, err := vaultPlaintexts(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remove) != 1 || remove[0].Path != same {
+		t.Errorf("remove = %v, want [%s]", remove, same)
+	}
+	if len(kept) != 2 || kept[0].Path != edited || kept[1].Path != corrupt {
+		t.Errorf("kept = %v, want [%s %s]", kept, edited, corrupt)
+	}
+
+	// Without an identity nothing can be confirmed, so everything is kept.
+	cfg.Age = core.Age{}
+	remove, kept, err = vaultPlaintexts(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remove) != 0 || len(kept) != 3 {
+		t.Errorf("without identity: remove = %v, kept = %v", remove, kept)
+	}
+}
+
+func TestTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-2 * tempFileAge)
+
+	paths := map[string]time.Time{
+		".mmdot-encrypt-1":     old,
+		"sub/.mmdot-decrypt-2": old,
+		".mmdot-recent":        time.Now(),
+		".git/.mmdot-ignored":  old,
+		"sub/not-mmdot.txt":    old,
+	}
+	for name, mtime := range paths {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	targets, err := tempFiles(time.Now().Add(-tempFileAge), dir, filepath.Join(dir, "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]bool{}
+	for _, target := range targets {
+		if rel, err := filepath.Rel(dir, target.Path); err == nil {
+			got[filepath.ToSlash(rel)] = true
+		}
+	}
+	if len(got) != 2 || !got[".mmdot-encrypt-1"] || !got["sub/.mmdot-decrypt-2"] {
+		t.Errorf("tempFiles() = %v", targets)
+	}
+}
@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/huh"
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/migrations"
+	"github.com/hay-kot/mmdot/pkgs/atomicwrite"
+	"github.com/hay-kot/mmdot/pkgs/linediff"
+	"github.com/urfave/cli/v3"
+	"golang.org/x/term"
+)
+
+type ConfigCmd struct {
+	coreFlags *core.Flags
+	flags     struct {
+		DryRun bool
+		Yes    bool
+	}
+}
+
+func NewConfigCmd(coreFlags *core.Flags) *ConfigCmd {
+	return &ConfigCmd{coreFlags: coreFlags}
+}
+
+func (cc *ConfigCmd) Register(app *cli.Command) *cli.Command {
+	cmd := &cli.Command{
+		Name:  "config",
+		Usage: "manage the mmdot config file",
+		Commands: []*cli.Command{
+			{
+				Name:  "migrate",
+				Usage: "rewrite the config to the current schema version",
+				Description: `Detects the version of the base config (1 when 'version' is unset) and applies
+every migration up to the current version, printing a diff of the result.
+The file is written after confirmation, or right away with --yes. Comments are
+kept where the YAML library preserves them.
+
+Overlays passed with additional --config flags are not migrated. See
+'mmdot llmtext' for what each version changed.`,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:        "dry-run",
+						Usage:       "print the diff without writing the config",
+						Destination: &cc.flags.DryRun,
+					},
+					&cli.BoolFlag{
+						Name:        "yes",
+						Aliases:     []string{"y"},
+						Usage:       "write the config without asking for confirmation",
+						Destination: &cc.flags.Yes,
+					},
+				},
+				Action: cc.migrate,
+			},
+			{
+				Name:  "schema",
+				Usage: "print the JSON Schema of the config file",
+				Description: `Prints a JSON Schema describing every key of mmdot.yml. Save it next to
+the config and point the YAML language server at it for validation and
+completion in editors:
+
+  mmdot config schema > .mmdot.schema.json
+
+  # yaml-language-server: $schema=./.mmdot.schema.json   (first line of mmdot.yml)`,
+				Action: cc.schema,
+			},
+		},
+	}
+
+	app.Commands = append(app.Commands, cmd)
+	return app
+}
+
+func (cc *ConfigCmd) migrate(ctx context.Context, c *cli.Command) error {
+	path, err := core.PathResolver{}.Resolve(cc.coreFlags.ConfigFilePath)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return ConfigError(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ConfigError(err)
+	}
+
+	out, from, err := migrations.Migrate(data, core.ConfigVersion)
+	if err != nil {
+		return ConfigError(fmt.Errorf("%s: %w", path, err))
+	}
+	if from == core.ConfigVersion {
+		fmt.Printf("%s is already at version %d\n", path, core.ConfigVersion)
+		return nil
+	}
+
+	// diff settings come from the config when it still loads
+	cfg, err := core.LoadConfig(path)
+	if err != nil {
+		cfg = core.ConfigFile{}
+	}
+	printDiff(ctx, &cfg, path, path, linediff.Diff(string(data), string(out)))
+	fmt.Println()
+
+	if cc.flags.DryRun {
+		fmt.Printf("Would migrate %s from version %d to %d\n", path, from, core.ConfigVersion)
+		return nil
+	}
+
+	if !cc.flags.Yes {
+		if core.CI || !term.IsTerminal(int(os.Stdin.Fd())) {
+			return fmt.Errorf("refusing to write %s without confirmation, pass --yes", path)
+		}
+
+		confirmed := false
+		confirm := huh.NewConfirm().
+			Title(fmt.Sprintf("Write the migrated config to %s?", path)).
+			Value(&confirmed)
+		if err := huh.NewForm(huh.NewGroup(confirm)).RunWithContext(ctx); err != nil {
+			return err
+		}
+		if !confirmed {
+			return nil
+		}
+	}
+
+	if err := atomicwrite.WriteFile(path, out, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	fmt.Printf("Migrated %s from version %d to %d\n", path, from, core.ConfigVersion)
+	return nil
+}
+
+func (cc *ConfigCmd) schema(ctx context.Context, c *cli.Command) error {
+	_, err := os.Stdout.Write(core.Schema)
+	return err
+}
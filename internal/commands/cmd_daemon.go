@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/daemon"
+	"github.com/hay-kot/mmdot/pkgs/mmdot"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+type DaemonCmd struct {
+	coreFlags *core.Flags
+	flags     struct {
+		Listen string
+	}
+}
+
+func NewDaemonCmd(coreFlags *core.Flags) *DaemonCmd {
+	return &DaemonCmd{coreFlags: coreFlags}
+}
+
+func (dc *DaemonCmd) Register(app *cli.Command) *cli.Command {
+	cmd := &cli.Command{
+		Name:  "daemon",
+		Usage: "serve a local API for status bars and editor integrations",
+		Description: `Starts a long-running process that serves a small JSON API for the current
+ config file.
+
+ Endpoints:
+	 GET  /status   drift counts and the last run report
+	 GET  /drift    templates whose output is changed or missing
+	 POST /run      render templates and run scripts; filter with ?tag=<tag>
+	 GET  /report   the last run report
+
+ The API listens on a unix socket in the state directory by default. Pass a
+ loopback host:port to --listen to use TCP instead. TCP requests must carry
+ the token in <state-dir>/daemon.token as "Authorization: Bearer <token>".
+ Requests from web pages, which send an Origin header, are refused.
+
+ Examples:
+	 mmdot daemon
+	 mmdot daemon --listen 127.0.0.1:7420
+	 curl --unix-socket ~/.local/state/mmdot/daemon.sock http://mmdot/status
+	 curl -H "Authorization: Bearer $(cat ~/.local/state/mmdot/daemon.token)" http://127.0.0.1:7420/status`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "listen",
+				Usage:       "unix socket path or host:port to listen on (default: <state-dir>/daemon.sock)",
+				Destination: &dc.flags.Listen,
+			},
+		},
+		Action: dc.run,
+	}
+
+	app.Commands = append(app.Commands, cmd)
+	return app
+}
+
+func (dc *DaemonCmd) run(ctx context.Context, c *cli.Command) error {
+	cfg, err := mmdot.LoadConfig(dc.coreFlags.ConfigFilePath, dc.coreFlags.ConfigOverlays()...)
+	if err != nil {
+		return ConfigError(fmt.Errorf("failed to load config %s: %w", strings.Join(dc.coreFlags.ConfigFilePaths, ", "), err))
+	}
+
+	dir, err := core.StateDir()
+	if err != nil {
+		return err
+	}
+	addr := dc.flags.Listen
+	if addr == "" {
+		addr = "unix:" + filepath.Join(dir, "daemon.sock")
+	}
+
+	server := daemon.New(mmdot.New(cfg))
+	if daemon.IsTCP(addr) {
+		tokenPath := filepath.Join(dir, "daemon.token")
+		if server.Token, err = daemon.LoadToken(tokenPath); err != nil {
+			return fmt.Errorf("failed to load daemon token: %w", err)
+		}
+		log.Info().Str("path", tokenPath).Msg("TCP requests require the bearer token in this file")
+	}
+
+	l, err := daemon.Listen(addr)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Info().Str("addr", addr).Msg("mmdot daemon listening")
+	return server.Serve(ctx, l)
+}
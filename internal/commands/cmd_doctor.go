@@ -0,0 +1,155 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/gpg"
+	"github.com/hay-kot/mmdot/pkgs/printer"
+	"github.com/urfave/cli/v3"
+)
+
+type DoctorCmd struct {
+	coreFlags *core.Flags
+}
+
+func NewDoctorCmd(coreFlags *core.Flags) *DoctorCmd {
+	return &DoctorCmd{coreFlags: coreFlags}
+}
+
+func (dc *DoctorCmd) Register(app *cli.Command) *cli.Command {
+	app.Commands = append(app.Commands, &cli.Command{
+		Name:   "doctor",
+		Usage:  "check that this machine has what the config expects",
+		Action: dc.run,
+	})
+	return app
+}
+
+// doctorCheck reports the state of one part of the machine. Checks with
+// nothing to report return no items.
+type doctorCheck struct {
+	title string
+	run   func(ctx context.Context, cfg *core.ConfigFile) []printer.StatusListItem
+}
+
+var doctorChecks = []doctorCheck{
+	{title: "Scripts:", run: checkScripts},
+	{title: "GPG keys:", run: checkGPGKeys},
+}
+
+func (dc *DoctorCmd) run(ctx context.Context, c *cli.Command) error {
+	cfg, err := loadConfig(dc.coreFlags)
+	if err != nil {
+		return err
+	}
+
+	p := printer.New(os.Stdout)
+	failed, reported := 0, 0
+	for _, check := range doctorChecks {
+		items := check.run(ctx, &cfg)
+		if len(items) == 0 {
+			continue
+		}
+		if reported > 0 {
+			p.LineBreak()
+		}
+		reported++
+
+		p.StatusList(check.title, items)
+		for _, item := range items {
+			if !item.Ok {
+				failed++
+			}
+		}
+	}
+
+	if reported == 0 {
+		fmt.Println("Nothing to check")
+		return nil
+	}
+	if failed > 0 {
+		return ValidationError(fmt.Errorf("%d check(s) failed", failed))
+	}
+	return nil
+}
+
+// checkScripts reports every exec script whose path is missing or not a
+// regular file, suggesting a file of a similar name next to it. When all
+// scripts are present a single item says so.
+func checkScripts(ctx context.Context, cfg *core.ConfigFile) []printer.StatusListItem {
+	if len(cfg.Exec.Scripts) == 0 {
+		return nil
+	}
+
+	var items []printer.StatusListItem
+	for _, script := range cfg.Exec.Scripts {
+		if problem := scriptPathProblem(script.Path); problem != "" {
+			items = append(items, printer.StatusListItem{Status: relToConfig(cfg, script.Path) + " " + problem})
+		}
+	}
+	if len(items) == 0 {
+		return []printer.StatusListItem{{Ok: true, Status: fmt.Sprintf("all %d script(s) exist", len(cfg.Exec.Scripts))}}
+	}
+	return items
+}
+
+// scriptPathProblem describes why path cannot be run as a script, or returns
+// "" when it is a regular file.
+func scriptPathProblem(path string) string {
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		entries, _ := os.ReadDir(filepath.Dir(path))
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			if e.Type().IsRegular() {
+				names = append(names, e.Name())
+			}
+		}
+		if s := suggestName(filepath.Base(path), names); s != "" {
+			return fmt.Sprintf("not found (did you mean %s?)", s)
+		}
+		return "not found"
+	case err != nil:
+		return err.Error()
+	case !info.Mode().IsRegular():
+		return "is not a regular file"
+	}
+	return ""
+}
+
+// checkGPGKeys reports configured keys missing from the keyring or without
+// their configured ownertrust.
+func checkGPGKeys(ctx context.Context, cfg *core.ConfigFile) []printer.StatusListItem {
+	if len(cfg.GPG.Keys) == 0 {
+		return nil
+	}
+
+	statuses, err := gpg.New().Status(ctx, cfg.GPG.Keys)
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return []printer.StatusListItem{{Status: "gpg is not installed"}}
+		}
+		return []printer.StatusListItem{{Status: err.Error()}}
+	}
+
+	items := make([]printer.StatusListItem, 0, len(statuses))
+	for _, st := range statuses {
+		status := st.Key.Label()
+		switch {
+		case !st.Imported:
+			status += " not in keyring (run 'mmdot gpg import')"
+		case !st.Trusted:
+			status += fmt.Sprintf(" ownertrust is not %s (run 'mmdot gpg import')", st.Key.Trust)
+		}
+		items = append(items, printer.StatusListItem{Ok: st.Ok(), Status: status})
+	}
+	return items
+}
@@ -0,0 +1,37 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_scriptPathProblem(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "setup.sh"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "scripts"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "regular file", path: "setup.sh", want: ""},
+		{name: "typo", path: "setpu.sh", want: "not found (did you mean setup.sh?)"},
+		{name: "missing", path: "install-everything.sh", want: "not found"},
+		{name: "directory", path: "scripts", want: "is not a regular file"},
+		{name: "missing directory", path: "nope/setup.sh", want: "not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scriptPathProblem(filepath.Join(dir, tt.path)); got != tt.want {
+				t.Errorf("scriptPathProblem() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
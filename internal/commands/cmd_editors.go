@@ -0,0 +1,203 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/editors"
+	"github.com/hay-kot/mmdot/internal/generator"
+	"github.com/hay-kot/mmdot/pkgs/linediff"
+	"github.com/hay-kot/mmdot/pkgs/printer"
+	"github.com/urfave/cli/v3"
+)
+
+type EditorsCmd struct {
+	coreFlags *core.Flags
+}
+
+func NewEditorsCmd(coreFlags *core.Flags) *EditorsCmd {
+	return &EditorsCmd{coreFlags: coreFlags}
+}
+
+func (ec *EditorsCmd) Register(app *cli.Command) *cli.Command {
+	cmd := &cli.Command{
+		Name:  "editors",
+		Usage: "manage VS Code family extensions and settings",
+		Description: `Editors declared under 'editors:' list extensions to install and a settings
+template. The rendered settings are merged key by key into the editor's
+settings.json: managed keys are replaced in place, new keys are appended, and
+comments and unmanaged keys are kept.`,
+		Commands: []*cli.Command{
+			{
+				Name:      "diff",
+				Usage:     "show missing extensions and pending settings changes",
+				ArgsUsage: "[editor-name...]",
+				Action:    ec.diff,
+			},
+			{
+				Name:      "sync",
+				Usage:     "install missing extensions and merge settings",
+				ArgsUsage: "[editor-name...]",
+				Action:    ec.sync,
+			},
+		},
+	}
+
+	app.Commands = append(app.Commands, cmd)
+	return app
+}
+
+// load returns the configured editors, limited to names when provided.
+func (ec *EditorsCmd) load(c *cli.Command) (*core.ConfigFile, []core.Editor, error) {
+	cfg, err := loadConfig(ec.coreFlags)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	selected := cfg.Editors
+	if names := c.Args().Slice(); len(names) > 0 {
+		selected = nil
+		for _, name := range names {
+			idx := slices.IndexFunc(cfg.Editors, func(e core.Editor) bool { return e.Name == name })
+			if idx == -1 {
+				return nil, nil, fmt.Errorf("editor %q not found", name)
+			}
+			selected = append(selected, cfg.Editors[idx])
+		}
+	}
+	return &cfg, selected, nil
+}
+
+// settingsChange renders the editor's settings template and merges it into
+// settings.json. It returns nil when the editor has no settings template.
+func settingsChange(ctx context.Context, engine *generator.Engine, e core.Editor) (*editors.SettingsChange, error) {
+	if e.Settings == "" {
+		return nil, nil
+	}
+
+	rendered, err := engine.Render(ctx, core.Template{
+		Name:     e.Name + " settings",
+		Template: e.Settings,
+		Vars:     e.Vars,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render %s settings: %w", e.Name, err)
+	}
+
+	change, err := editors.MergeSettings(e.SettingsPath, rendered)
+	if err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+func (ec *EditorsCmd) diff(ctx context.Context, c *cli.Command) error {
+	cfg, selected, err := ec.load(c)
+	if err != nil {
+		return err
+	}
+
+	engine := generator.NewEngine(cfg)
+	p := printer.New(os.Stdout)
+	changes := 0
+
+	for _, e := range selected {
+		if len(e.Extensions) > 0 {
+			installed, err := editors.Extensions(ctx, e)
+			if err != nil {
+				return err
+			}
+
+			diff := editors.DiffExtensions(e.Extensions, installed)
+			if len(diff.Missing) > 0 {
+				changes++
+				p.List(fmt.Sprintf("%s: missing extensions", e.Name), diff.Missing)
+			}
+			if len(diff.Extra) > 0 {
+				p.List(fmt.Sprintf("%s: extensions not in config", e.Name), diff.Extra)
+			}
+		}
+
+		change, err := settingsChange(ctx, engine, e)
+		if err != nil {
+			return err
+		}
+		if change != nil && change.Changed() {
+			changes++
+			printDiff(ctx, cfg, change.Path, e.Name+" settings (merged)",
+				linediff.Diff(string(change.Current), string(change.Merged)))
+			fmt.Println()
+		}
+	}
+
+	if changes == 0 {
+		fmt.Println("All editors are up to date")
+	}
+	return nil
+}
+
+func (ec *EditorsCmd) sync(ctx context.Context, c *cli.Command) error {
+	cfg, selected, err := ec.load(c)
+	if err != nil {
+		return err
+	}
+
+	if len(selected) == 0 {
+		fmt.Println("No editors configured")
+		return nil
+	}
+
+	engine := generator.NewEngine(cfg)
+	failed := 0
+	var items []printer.StatusListItem
+
+	for _, e := range selected {
+		if len(e.Extensions) > 0 {
+			installed, err := editors.Extensions(ctx, e)
+			if err != nil {
+				failed++
+				items = append(items, printer.StatusListItem{Ok: false, Status: err.Error()})
+				continue
+			}
+
+			for _, id := range editors.DiffExtensions(e.Extensions, installed).Missing {
+				item := printer.StatusListItem{Ok: true, Status: fmt.Sprintf("%s: installed %s", e.Name, id)}
+				if err := editors.InstallExtension(ctx, e, id); errors.Is(err, core.ErrOffline) {
+					item = printer.StatusListItem{Ok: false, Status: fmt.Sprintf("%s: %s %v", e.Name, id, err)}
+				} else if err != nil {
+					failed++
+					item = printer.StatusListItem{Ok: false, Status: err.Error()}
+				}
+				items = append(items, item)
+			}
+		}
+
+		change, err := settingsChange(ctx, engine, e)
+		if err != nil {
+			return err
+		}
+		if change != nil && change.Changed() {
+			if err := change.Write(); err != nil {
+				return fmt.Errorf("failed to write %s: %w", change.Path, err)
+			}
+			items = append(items, printer.StatusListItem{Ok: true, Status: fmt.Sprintf("%s: updated %s", e.Name, change.Path)})
+		}
+	}
+
+	if len(items) == 0 {
+		fmt.Println("All editors are up to date")
+		return nil
+	}
+
+	p := printer.New(os.Stdout)
+	p.StatusList("Editors:", items)
+
+	if failed > 0 {
+		return PartialError(fmt.Errorf("%d editor changes failed", failed))
+	}
+	return nil
+}
@@ -1,14 +1,20 @@
 package commands
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
+	"filippo.io/age"
 	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/pkgs/atomicwrite"
 	"github.com/hay-kot/mmdot/pkgs/fcrypt"
+	"github.com/hay-kot/mmdot/pkgs/printer"
 	"github.com/rs/zerolog/log"
 	"github.com/urfave/cli/v3"
 )
@@ -16,6 +22,11 @@ import (
 type EncryptCmd struct {
 	coreFlags *core.Flags
 	dryRun    bool
+	quiet     bool
+	value     secretFlags
+	audit     struct {
+		staged bool
+	}
 }
 
 func NewEncryptCmd(coreFlags *core.Flags) *EncryptCmd {
@@ -32,6 +43,8 @@ func (ec *EncryptCmd) Register(app *cli.Command) *cli.Command {
 Files to encrypt are specified in mmdot.yaml under various sections like:
 - [ssh.secrets] for SSH private keys and configurations
 - Template varsFile references
+- Partial vault var files (?partial=true), whose values are encrypted in
+  place while keys stay readable; edit them with "mmdot encrypt edit"
 
 The command will:
 - Use the configured age recipient (public key) for encryption
@@ -44,11 +57,18 @@ corresponding age identity (private key).`,
 			Flags: []cli.Flag{
 				&cli.BoolFlag{
 					Name:        "dry-run",
-					Usage:       "check if files need encryption without encrypting them",
+					Usage:       "list files that need encryption without encrypting them; exits non-zero if any exist",
 					Destination: &ec.dryRun,
 				},
+				&cli.BoolFlag{
+					Name:        "quiet",
+					Aliases:     []string{"q"},
+					Usage:       "with --dry-run, print nothing and report only through the exit code",
+					Destination: &ec.quiet,
+				},
 			},
-			Action: ec.encrypt,
+			Action:   ec.encrypt,
+			Commands: []*cli.Command{ec.dirCommand(false), ec.editCommand(), ec.auditCommand(), ec.verifyCommand()},
 		},
 		{
 			Name:  "decrypt",
@@ -63,7 +83,31 @@ The command will:
 
 This is typically used when you need to edit secret files or when setting up
 a new machine from encrypted configuration files.`,
-			Action: ec.decrypt,
+			Action:   ec.decrypt,
+			Commands: []*cli.Command{ec.dirCommand(true)},
+		},
+		{
+			Name:  "encrypt-value",
+			Usage: "encrypt a single value for use inline in mmdot.yaml",
+			Description: `Encrypts a value for the configured age recipients and prints it as a
+YAML block tagged ` + core.AgeTag + `. Paste the output as the value of any config field:
+
+  notify:
+    webhook: !age |
+      -----BEGIN AGE ENCRYPTED FILE-----
+      ...
+
+Tagged values are decrypted with age.identity_file, or age.identity_files,
+whenever the config is loaded.
+
+The value is never taken as an argument, which would leave it in shell history.
+It is asked for with hidden input, read from stdin when piped, or read from
+--value-env or --value-fd:
+
+  mmdot encrypt-value --value-env NPM_TOKEN
+  mmdot encrypt-value --value-fd 3 3< token.txt`,
+			Flags:  ec.value.flags("value"),
+			Action: ec.encryptValue,
 		},
 	}
 
@@ -72,7 +116,7 @@ a new machine from encrypted configuration files.`,
 }
 
 func (ec *EncryptCmd) encrypt(ctx context.Context, cmd *cli.Command) error {
-	cfg, err := core.SetupEnv(ec.coreFlags.ConfigFilePath)
+	cfg, err := loadConfig(ec.coreFlags)
 	if err != nil {
 		return err
 	}
@@ -105,6 +149,13 @@ func (ec *EncryptCmd) encrypt(ctx context.Context, cmd *cli.Command) error {
 			return fmt.Errorf("failed to stat %s: %w", targetFile, err)
 		}
 
+		if encrypted, err := fcrypt.IsEncryptedFile(sourceFile); err != nil {
+			return err
+		} else if encrypted {
+			log.Warn().Str("file", sourceFile).Msg("File is already encrypted, skipping")
+			continue
+		}
+
 		vaultFilesToEncrypt = append(vaultFilesToEncrypt, sourceFile)
 	}
 
@@ -118,24 +169,65 @@ func (ec *EncryptCmd) encrypt(ctx context.Context, cmd *cli.Command) error {
 			}
 			return fmt.Errorf("failed to stat %s: %w", af.Dest, err)
 		}
+		if encrypted, err := fcrypt.IsEncryptedFile(af.Dest); err != nil {
+			return err
+		} else if encrypted {
+			log.Warn().Str("dest", af.Dest).Msg("Dest is already encrypted, skipping")
+			continue
+		}
 		ageFilesToEncrypt = append(ageFilesToEncrypt, af)
 	}
 
-	totalToEncrypt := len(vaultFilesToEncrypt) + len(ageFilesToEncrypt)
+	// Collect partial vault files that still have plaintext values
+	partialFilesToEncrypt := []string{}
+	for _, file := range cfg.PartialVaultFiles() {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				log.Debug().Str("file", file).Msg("Partial vault file doesn't exist, skipping")
+				continue
+			}
+			return fmt.Errorf("failed to read %s: %w", file, err)
+		}
+
+		hasPlaintext, err := fcrypt.HasPlaintextValues(data)
+		if err != nil {
+			return fmt.Errorf("failed to check %s: %w", file, err)
+		}
+		if hasPlaintext {
+			partialFilesToEncrypt = append(partialFilesToEncrypt, file)
+		}
+	}
+
+	totalToEncrypt := len(vaultFilesToEncrypt) + len(ageFilesToEncrypt) + len(partialFilesToEncrypt)
 
 	if ec.dryRun {
-		if totalToEncrypt > 0 {
-			log.Error().Msg("Found unencrypted files:")
+		if totalToEncrypt == 0 {
+			if !ec.quiet {
+				log.Info().Msg("All files are encrypted")
+			}
+			return nil
+		}
+
+		if !ec.quiet {
+			items := make([]printer.StatusListItem, 0, totalToEncrypt)
 			for _, file := range vaultFilesToEncrypt {
-				log.Error().Str("file", file).Msg("  - vault file needs encryption")
+				items = append(items, printer.StatusListItem{Status: fmt.Sprintf("%s -> %s.age (vault file)", relToCwd(file), relToCwd(file))})
 			}
 			for _, af := range ageFilesToEncrypt {
-				log.Error().Str("dest", af.Dest).Str("src", af.Src).Msg("  - age file needs encryption")
+				items = append(items, printer.StatusListItem{Status: fmt.Sprintf("%s -> %s (age file)", relToCwd(af.Dest), relToCwd(af.Src))})
+			}
+			for _, file := range partialFilesToEncrypt {
+				items = append(items, printer.StatusListItem{Status: fmt.Sprintf("%s (plaintext values)", relToCwd(file))})
 			}
-			return fmt.Errorf("found %d unencrypted file(s)", totalToEncrypt)
+			printer.New(os.Stdout).StatusList("Files that would be encrypted:", items)
 		}
-		log.Info().Msg("All files are encrypted")
-		return nil
+
+		err := ValidationError(fmt.Errorf("found %d unencrypted file(s)", totalToEncrypt))
+		if ec.quiet {
+			return Silence(err)
+		}
+		return err
 	}
 
 	if totalToEncrypt == 0 {
@@ -143,13 +235,11 @@ func (ec *EncryptCmd) encrypt(ctx context.Context, cmd *cli.Command) error {
 		return nil
 	}
 
-	if len(cfg.Age.Recipients) == 0 {
-		return fmt.Errorf("no age recipients configured in mmdot.yaml")
-	}
+	recipients := newRecipientLoader(cfg.Age)
 
-	recipients, err := fcrypt.LoadPublicKeys(cfg.Age.Recipients)
-	if err != nil {
-		return fmt.Errorf("failed to load public keys: %w", err)
+	varFileRecipients := map[string][]string{}
+	for _, vf := range cfg.Variables.AllVarFiles() {
+		varFileRecipients[strings.TrimSuffix(vf.Path, ".age")] = vf.Recipients
 	}
 
 	// Encrypt vault files
@@ -160,10 +250,16 @@ func (ec *EncryptCmd) encrypt(ctx context.Context, cmd *cli.Command) error {
 			sourceFile = strings.TrimSuffix(sourceFile, ".age")
 		}
 
+		fileRecipients, err := recipients.load(varFileRecipients[sourceFile])
+		if err != nil {
+			return fmt.Errorf("%s: %w", sourceFile, err)
+		}
+
 		log.Info().Str("source", sourceFile).Str("target", targetFile).Msg("Encrypting vault file")
-		if err := fcrypt.EncryptFile(sourceFile, targetFile, recipients); err != nil {
+		if err := fcrypt.EncryptFile(sourceFile, targetFile, fileRecipients); err != nil {
 			return fmt.Errorf("failed to encrypt %s: %w", sourceFile, err)
 		}
+		recipients.record(targetFile, varFileRecipients[sourceFile])
 		log.Info().Str("file", targetFile).Msg("Vault file encrypted successfully")
 	}
 
@@ -173,26 +269,83 @@ func (ec *EncryptCmd) encrypt(ctx context.Context, cmd *cli.Command) error {
 			return fmt.Errorf("failed to create parent dir for %s: %w", af.Src, err)
 		}
 
+		fileRecipients, err := recipients.load(af.Recipients)
+		if err != nil {
+			return fmt.Errorf("%s: %w", af.Dest, err)
+		}
+
 		log.Info().Str("source", af.Dest).Str("target", af.Src).Msg("Encrypting age file")
-		if err := fcrypt.EncryptFile(af.Dest, af.Src, recipients); err != nil {
+		if err := fcrypt.EncryptFile(af.Dest, af.Src, fileRecipients); err != nil {
 			return fmt.Errorf("failed to encrypt %s: %w", af.Dest, err)
 		}
+		recipients.record(af.Src, af.Recipients)
 		log.Info().Str("file", af.Src).Msg("Age file encrypted successfully")
 	}
 
+	// Encrypt plaintext values in partial vault files, keeping keys readable
+	for _, file := range partialFilesToEncrypt {
+		fileRecipients, err := recipients.load(varFileRecipients[file])
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+
+		log.Info().Str("file", file).Msg("Encrypting partial vault file values")
+		if err := encryptValuesInPlace(file, fileRecipients); err != nil {
+			return fmt.Errorf("failed to encrypt %s: %w", file, err)
+		}
+		recipients.record(file, varFileRecipients[file])
+		log.Info().Str("file", file).Msg("Partial vault file encrypted successfully")
+	}
+
 	log.Info().Int("count", totalToEncrypt).Msg("Encryption complete")
 	return nil
 }
 
+func (ec *EncryptCmd) encryptValue(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() > 0 {
+		return fmt.Errorf("values are not accepted as arguments since they are saved in shell history; enter it when prompted or use --value-env or --value-fd")
+	}
+
+	cfg, err := loadConfig(ec.coreFlags)
+	if err != nil {
+		return err
+	}
+
+	value, err := readSecret(ctx, "Value", ec.value, true)
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		return fmt.Errorf("value is empty")
+	}
+
+	recipients, err := newRecipientLoader(cfg.Age).load(nil)
+	if err != nil {
+		return err
+	}
+
+	var armored bytes.Buffer
+	if err := fcrypt.EncryptReader(strings.NewReader(value), &armored, recipients); err != nil {
+		return err
+	}
+
+	fmt.Println(core.AgeTag + " |")
+	for line := range strings.SplitSeq(strings.TrimRight(armored.String(), "\n"), "\n") {
+		fmt.Println("  " + line)
+	}
+
+	return nil
+}
+
 func (ec *EncryptCmd) decrypt(ctx context.Context, cmd *cli.Command) error {
-	cfg, err := core.SetupEnv(ec.coreFlags.ConfigFilePath)
+	cfg, err := loadConfig(ec.coreFlags)
 	if err != nil {
 		return err
 	}
 
 	identity, err := cfg.Age.ReadIdentity()
 	if err != nil {
-		return err
+		return DecryptError(err)
 	}
 
 	files := cfg.EncryptedFiles()
@@ -226,8 +379,15 @@ func (ec *EncryptCmd) decrypt(ctx context.Context, cmd *cli.Command) error {
 			return fmt.Errorf("failed to stat %s: %w", targetFile, err)
 		}
 
+		if encrypted, err := fcrypt.IsEncryptedFile(sourceFile); err != nil {
+			return err
+		} else if !encrypted {
+			log.Warn().Str("file", sourceFile).Msg("File is not age encrypted, skipping")
+			continue
+		}
+
 		log.Info().Str("source", sourceFile).Str("target", targetFile).Msg("Decrypting vault file")
-		if err := fcrypt.DecryptFile(sourceFile, targetFile, identity); err != nil {
+		if err := fcrypt.DecryptFile(sourceFile, targetFile, core.IdentityFor(identity, sourceFile)); err != nil {
 			return fmt.Errorf("failed to decrypt %s: %w", sourceFile, err)
 		}
 
@@ -256,12 +416,19 @@ func (ec *EncryptCmd) decrypt(ctx context.Context, cmd *cli.Command) error {
 			return fmt.Errorf("failed to stat %s: %w", af.Dest, err)
 		}
 
+		if encrypted, err := fcrypt.IsEncryptedFile(af.Src); err != nil {
+			return err
+		} else if !encrypted {
+			log.Warn().Str("src", af.Src).Msg("Age file is not encrypted, skipping")
+			continue
+		}
+
 		if err := os.MkdirAll(filepath.Dir(af.Dest), 0o755); err != nil {
 			return fmt.Errorf("failed to create parent dir for %s: %w", af.Dest, err)
 		}
 
 		log.Info().Str("source", af.Src).Str("target", af.Dest).Msg("Decrypting age file")
-		if err := fcrypt.DecryptFile(af.Src, af.Dest, identity); err != nil {
+		if err := fcrypt.DecryptFile(af.Src, af.Dest, core.IdentityFor(identity, af.Src)); err != nil {
 			return fmt.Errorf("failed to decrypt %s: %w", af.Src, err)
 		}
 
@@ -290,6 +457,105 @@ func (ec *EncryptCmd) decrypt(ctx context.Context, cmd *cli.Command) error {
 	return nil
 }
 
+// recipientLoader parses recipient lists, falling back to age.recipients for
+// files without their own list.
+type recipientLoader struct {
+	cfg   core.Age
+	chain *core.IdentityChain // identities present on this machine, if readable
+	self  []string            // public keys of those identities
+}
+
+func newRecipientLoader(cfg core.Age) *recipientLoader {
+	rl := &recipientLoader{cfg: cfg}
+
+	if len(cfg.IdentityPaths()) > 0 {
+		identities, err := cfg.ReadIdentities()
+		if err != nil {
+			log.Warn().Err(err).Msg("cannot read identity; per-file recipients will not be checked")
+			return rl
+		}
+		rl.chain = core.NewIdentityChain(identities)
+		for _, identity := range identities {
+			if r := identity.Recipient(); r != "" {
+				rl.self = append(rl.self, r)
+			}
+		}
+	}
+
+	return rl
+}
+
+// record notes which identity will decrypt file, encrypted with the given
+// override, so the next decrypt tries it first. Only needed with more than
+// one identity.
+func (rl *recipientLoader) record(file string, override []string) {
+	if rl.chain == nil || len(rl.cfg.IdentityPaths()) < 2 {
+		return
+	}
+	keys, err := rl.cfg.RecipientsFor(override)
+	if err != nil {
+		return
+	}
+	rl.chain.RecordRecipients(file, keys)
+}
+
+// load returns the parsed recipients for a file with the given override. A
+// per-file list must include one of the configured identities so the file
+// can still be decrypted on this machine.
+func (rl *recipientLoader) load(override []string) ([]age.Recipient, error) {
+	keys, err := rl.cfg.RecipientsFor(override)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, printer.WithHints(errors.New("no age recipients configured in mmdot.yml"),
+			"add public keys to age.recipients in mmdot.yml",
+			"or point age.recipients_file or age.recipients_dir at files of public keys",
+		)
+	}
+
+	if len(override) > 0 && len(rl.self) > 0 && !slices.ContainsFunc(rl.self, func(self string) bool { return slices.Contains(override, self) }) {
+		return nil, fmt.Errorf("recipients do not include your identity %s; you would not be able to decrypt this file", strings.Join(rl.self, " or "))
+	}
+
+	recipients, err := fcrypt.LoadPublicKeys(keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load public keys: %w", err)
+	}
+	return recipients, nil
+}
+
+func encryptValuesInPlace(path string, recipients []age.Recipient) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	encrypted, err := fcrypt.EncryptValues(data, recipients)
+	if err != nil {
+		return err
+	}
+
+	return atomicwrite.WriteFile(path, encrypted, info.Mode().Perm())
+}
+
+// relToCwd shortens path for display when it is inside the working directory.
+func relToCwd(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
 func ensureGitignored(path string) error {
 	gitignorePath := ".gitignore"
 
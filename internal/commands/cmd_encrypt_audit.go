@@ -0,0 +1,156 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/hay-kot/mmdot/internal/secretscan"
+	"github.com/hay-kot/mmdot/pkgs/printer"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+func (ec *EncryptCmd) auditCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "audit",
+		Usage: "report plaintext files that look like secrets before they are committed",
+		Description: `Scans the files git would commit from the config directory, tracked and
+untracked but not ignored, for likely secrets:
+
+  - names such as id_rsa, *.pem, *.key, *.p12, and .env
+  - private key blocks and age secret keys
+  - long high entropy strings such as API tokens
+
+Age encrypted files and ENC[age,...] values are skipped, as are files
+matching a pattern in .ageignore in the config directory (see 'mmdot encrypt
+dir --help'), and the contents of lock files such as go.sum and checksums
+such as h1: and sha512- values are not checked for entropy.
+
+Exits with code 5 when a secret file name or private key is found: encrypt
+the file, add it to .gitignore, or list it in .ageignore. High entropy
+strings alone are reported as warnings and do not fail the audit.
+
+'mmdot hook install' runs 'encrypt audit --staged' before every commit.`,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:        "staged",
+				Usage:       "only scan files staged for commit",
+				Destination: &ec.audit.staged,
+			},
+		},
+		Action: ec.runAudit,
+	}
+}
+
+func (ec *EncryptCmd) runAudit(ctx context.Context, cmd *cli.Command) error {
+	if _, err := loadConfig(ec.coreFlags); err != nil {
+		return err
+	}
+
+	// loadConfig changed to the config directory
+	files, err := auditFiles(ctx, ec.audit.staged)
+	if err != nil {
+		return err
+	}
+	ignore, err := readIgnoreFile(ageIgnoreFile)
+	if err != nil {
+		return err
+	}
+
+	var findings []secretscan.Finding
+	for _, file := range files {
+		if file == ageIgnoreFile || ignore.match(file, false) || ignoredDir(ignore, file) {
+			continue
+		}
+
+		data, err := readHead(file, secretscan.MaxSize+1)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue // deleted in the work tree
+			}
+			return err
+		}
+		findings = append(findings, secretscan.Scan(file, data)...)
+	}
+
+	if len(findings) == 0 {
+		log.Info().Int("files", len(files)).Msg("No plaintext secrets found")
+		return nil
+	}
+
+	failed := 0
+	for _, f := range findings {
+		if f.Warning {
+			fmt.Println("warning:", f)
+			continue
+		}
+		failed++
+		fmt.Println(f)
+	}
+	if failed == 0 {
+		return nil
+	}
+	return ValidationError(printer.WithTitle("Possible secrets found",
+		fmt.Errorf("%d file(s) or line(s) look like plaintext secrets", failed),
+		"encrypt them with 'mmdot encrypt-value' or 'mmdot encrypt dir'",
+		"or add them to .gitignore",
+		"or list false positives in "+ageIgnoreFile,
+	))
+}
+
+// auditFiles returns the slash separated paths, relative to the working
+// directory, of the files git would commit. Outside a git repository every
+// file below the working directory is returned.
+func auditFiles(ctx context.Context, staged bool) ([]string, error) {
+	args := []string{"ls-files", "-z", "--cached", "--others", "--exclude-standard"}
+	if staged {
+		args = []string{"diff", "-z", "--cached", "--name-only", "--relative", "--diff-filter=ACMR"}
+	}
+
+	out, err := exec.CommandContext(ctx, "git", args...).Output()
+	if err != nil {
+		if staged {
+			return nil, fmt.Errorf("--staged requires a git repository: %w", err)
+		}
+		log.Debug().Err(err).Msg("not a git repository, scanning every file")
+		return walkSecretsDir(".", func(string) (bool, error) { return true, nil })
+	}
+
+	var files []string
+	for file := range strings.SplitSeq(string(out), "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// ignoredDir reports whether a parent directory of file matches a
+// directory pattern.
+func ignoredDir(ignore ignorePatterns, file string) bool {
+	for dir := filepath.ToSlash(filepath.Dir(file)); dir != "." && dir != "/"; dir = filepath.ToSlash(filepath.Dir(dir)) {
+		if ignore.match(dir, true) {
+			return true
+		}
+	}
+	return false
+}
+
+// readHead reads at most n bytes of the file at path.
+func readHead(path string, n int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var buf bytes.Buffer
+	_, err = io.Copy(&buf, io.LimitReader(f, n))
+	return buf.Bytes(), err
+}
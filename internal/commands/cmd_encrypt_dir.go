@@ -0,0 +1,252 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/pkgs/fcrypt"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+// ageIgnoreFile lists the files in a directory that encrypt dir and decrypt
+// dir leave alone.
+const ageIgnoreFile = ".ageignore"
+
+func (ec *EncryptCmd) dirCommand(decrypt bool) *cli.Command {
+	if decrypt {
+		return &cli.Command{
+			Name:      "dir",
+			Usage:     "decrypt every .age file under a directory in place",
+			ArgsUsage: "<path>",
+			Description: `Walks the directory and decrypts every age encrypted file named *.age to
+the same path without the suffix, removing the encrypted copy. Files matching
+a pattern in a .ageignore file at the root of the directory are skipped, and
+decrypted files inside the current directory are added to .gitignore.`,
+			Action: ec.decryptDir,
+		}
+	}
+
+	return &cli.Command{
+		Name:      "dir",
+		Usage:     "encrypt every file under a directory in place",
+		ArgsUsage: "<path>",
+		Description: `Walks the directory and encrypts every file to <file>.age for the configured
+age recipients, removing the plaintext. The directory structure is kept.
+Files that are already encrypted are skipped, as are .git directories and
+files matching a pattern in a .ageignore file at the root of the directory.
+
+.ageignore holds one pattern per line, # starts a comment:
+
+  README.md    # matches the name anywhere in the tree
+  public/*     # patterns with a slash match the path from the root
+  cache/       # a trailing slash matches directories only`,
+		Action: ec.encryptDir,
+	}
+}
+
+func (ec *EncryptCmd) encryptDir(ctx context.Context, cmd *cli.Command) error {
+	root, err := dirArg(cmd)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(ec.coreFlags)
+	if err != nil {
+		return err
+	}
+	loader := newRecipientLoader(cfg.Age)
+	recipients, err := loader.load(nil)
+	if err != nil {
+		return err
+	}
+
+	files, err := walkSecretsDir(root, func(file string) (bool, error) {
+		encrypted, err := fcrypt.IsEncryptedFile(file)
+		return !encrypted, err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		log.Info().Str("file", file).Msg("Encrypting file")
+		if err := fcrypt.EncryptInPlace(file, recipients); err != nil {
+			return fmt.Errorf("failed to encrypt %s: %w", file, err)
+		}
+		loader.record(file, nil)
+	}
+
+	log.Info().Int("count", len(files)).Msg("Encryption complete")
+	return nil
+}
+
+func (ec *EncryptCmd) decryptDir(ctx context.Context, cmd *cli.Command) error {
+	root, err := dirArg(cmd)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(ec.coreFlags)
+	if err != nil {
+		return err
+	}
+	identity, err := cfg.Age.ReadIdentity()
+	if err != nil {
+		return DecryptError(err)
+	}
+
+	files, err := walkSecretsDir(root, func(file string) (bool, error) {
+		if !strings.HasSuffix(file, ".age") {
+			return false, nil
+		}
+		if _, err := os.Stat(strings.TrimSuffix(file, ".age")); err == nil {
+			log.Debug().Str("file", file).Msg("Decrypted file already exists, skipping")
+			return false, nil
+		}
+		return fcrypt.IsEncryptedFile(file)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		log.Info().Str("file", file).Msg("Decrypting file")
+		if err := fcrypt.DecryptInPlace(file, core.IdentityFor(identity, file)); err != nil {
+			return DecryptError(fmt.Errorf("failed to decrypt %s: %w", file, err))
+		}
+
+		plain := strings.TrimSuffix(file, ".age")
+		relPlain, err := filepath.Rel(".", plain)
+		if err != nil || strings.HasPrefix(relPlain, "..") {
+			continue
+		}
+		if err := ensureGitignored(filepath.ToSlash(relPlain)); err != nil {
+			return fmt.Errorf("failed to gitignore %s: %w", plain, err)
+		}
+	}
+
+	log.Info().Int("count", len(files)).Msg("Decryption complete")
+	return nil
+}
+
+func dirArg(cmd *cli.Command) (string, error) {
+	if cmd.Args().Len() != 1 {
+		return "", fmt.Errorf("expected exactly one directory argument")
+	}
+
+	root := cmd.Args().First()
+	info, err := os.Stat(root)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", root)
+	}
+	return root, nil
+}
+
+// walkSecretsDir returns the regular files under root that are not ignored
+// and for which include returns true. Git directories and the ignore file
+// itself are always skipped.
+func walkSecretsDir(root string, include func(path string) (bool, error)) ([]string, error) {
+	ignore, err := readIgnoreFile(filepath.Join(root, ageIgnoreFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if d.Name() == ".git" || ignore.match(rel, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || rel == ageIgnoreFile || ignore.match(rel, false) {
+			return nil
+		}
+
+		ok, err := include(p)
+		if err != nil {
+			return err
+		}
+		if ok {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// ignorePatterns are the patterns of an ignore file.
+type ignorePatterns []string
+
+func readIgnoreFile(name string) (ignorePatterns, error) {
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var patterns ignorePatterns
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if _, err := path.Match(strings.TrimSuffix(line, "/"), ""); err != nil {
+			return nil, fmt.Errorf("%s: invalid pattern %q: %w", name, line, err)
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, scanner.Err()
+}
+
+// match reports whether the slash separated path rel, relative to the root,
+// matches any pattern.
+func (ip ignorePatterns) match(rel string, isDir bool) bool {
+	for _, pattern := range ip {
+		dirOnly := strings.HasSuffix(pattern, "/")
+		if dirOnly && !isDir {
+			continue
+		}
+		pattern = strings.TrimSuffix(pattern, "/")
+
+		target := path.Base(rel)
+		if strings.Contains(pattern, "/") {
+			target = rel
+			pattern = strings.TrimPrefix(pattern, "/")
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/pkgs/atomicwrite"
+	"github.com/hay-kot/mmdot/pkgs/fcrypt"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+func (ec *EncryptCmd) editCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "edit",
+		Usage:     "edit the decrypted values of a partial vault file",
+		ArgsUsage: "<file>",
+		Description: `Decrypts the values of a partial vault file (?partial=true) to a temporary
+file next to it and opens it in $VISUAL or $EDITOR. When the editor exits,
+the values are encrypted again and written back. Values that were not
+changed keep their ciphertext, so the diff only shows the edited values.
+
+Keys, key order, and comments are kept as written.`,
+		Action: ec.edit,
+	}
+}
+
+func (ec *EncryptCmd) edit(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 1 {
+		return fmt.Errorf("expected exactly one file argument")
+	}
+
+	cfg, err := loadConfig(ec.coreFlags)
+	if err != nil {
+		return err
+	}
+
+	file, err := filepath.Abs(cmd.Args().First())
+	if err != nil {
+		return err
+	}
+
+	var vf *core.VarFile
+	for _, candidate := range cfg.Variables.AllVarFiles() {
+		if candidate.Partial && candidate.Path == file {
+			vf = &candidate
+			break
+		}
+	}
+	if vf == nil {
+		return ValidationError(fmt.Errorf("%s is not a partial vault file in the config", relToCwd(file)))
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	original, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	identity, err := cfg.Age.ReadIdentity()
+	if err != nil {
+		return DecryptError(err)
+	}
+	identity = core.IdentityFor(identity, file)
+
+	plaintext, err := fcrypt.DecryptValuesDocument(original, identity)
+	if err != nil {
+		return DecryptError(fmt.Errorf("failed to decrypt %s: %w", relToCwd(file), err))
+	}
+
+	edited, err := editInTemp(ctx, filepath.Dir(file), filepath.Ext(file), plaintext)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(edited, plaintext) {
+		fmt.Println("No changes")
+		return nil
+	}
+
+	recipients := newRecipientLoader(cfg.Age)
+	fileRecipients, err := recipients.load(vf.Recipients)
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+
+	encrypted, err := fcrypt.ReencryptValues(edited, original, identity, fileRecipients)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt %s: %w", relToCwd(file), err)
+	}
+	if err := atomicwrite.WriteFile(file, encrypted, info.Mode().Perm()); err != nil {
+		return err
+	}
+	recipients.record(file, vf.Recipients)
+
+	log.Info().Str("file", file).Msg("Partial vault file updated")
+	return nil
+}
+
+// editInTemp writes data to a private temp file in dir, opens it in the
+// user's editor, and returns the edited content. The temp file is always
+// removed; one left behind by a crash is removed by mmdot clean.
+func editInTemp(ctx context.Context, dir, ext string, data []byte) ([]byte, error) {
+	tmp, err := os.CreateTemp(dir, ".mmdot-edit-*"+ext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return nil, fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	editor := strings.Fields(editorCommand())
+	cmd := exec.CommandContext(ctx, editor[0], append(editor[1:], tmp.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("editor %s failed: %w", editor[0], err)
+	}
+
+	return os.ReadFile(tmp.Name())
+}
+
+// editorCommand returns $VISUAL or $EDITOR, falling back to vi.
+func editorCommand() string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.TrimSpace(os.Getenv(env)); editor != "" {
+			return editor
+		}
+	}
+	return "vi"
+}
@@ -2,6 +2,8 @@ package commands
 
 import (
 	"os"
+	"path/filepath"
+	"slices"
 	"testing"
 )
 
@@ -92,3 +94,43 @@ func Test_ensureGitignored_existingContentWithTrailingNewline(t *testing.T) {
 		t.Errorf(".gitignore content = %q, want %q", string(data), want)
 	}
 }
+
+func Test_walkSecretsDir(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{
+		".ageignore",
+		"id_ed25519",
+		"README.md",
+		"keys/work/id_rsa",
+		"keys/work/README.md",
+		"public/id_ed25519.pub",
+		"cache/token",
+		".git/config",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ignore := "# not secret\nREADME.md\n/public/*\ncache/\n"
+	if err := os.WriteFile(filepath.Join(root, ageIgnoreFile), []byte(ignore), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := walkSecretsDir(root, func(string) (bool, error) { return true, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range got {
+		rel, _ := filepath.Rel(root, got[i])
+		got[i] = filepath.ToSlash(rel)
+	}
+
+	want := []string{"id_ed25519", "keys/work/id_rsa"}
+	if !slices.Equal(got, want) {
+		t.Errorf("walkSecretsDir() = %v, want %v", got, want)
+	}
+}
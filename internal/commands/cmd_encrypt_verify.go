@@ -0,0 +1,136 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/pkgs/fcrypt"
+	"github.com/hay-kot/mmdot/pkgs/printer"
+	"github.com/urfave/cli/v3"
+)
+
+func (ec *EncryptCmd) verifyCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "verify",
+		Usage: "report encrypted files that none of the identities on this machine can open",
+		Description: `Decrypts every encrypted vault file, partial vault file, and age.files
+source in memory with the identities in age.identity_file and
+age.identity_files that exist on this machine. Nothing is written.
+
+Each file is reported with the identity that opened it, which is recorded so
+later decrypts try that identity first. Exits with code 5 when a file cannot
+be opened by any of them, e.g. because it was encrypted before this machine's
+key was added to the recipients.`,
+		Action: ec.verify,
+	}
+}
+
+func (ec *EncryptCmd) verify(ctx context.Context, cmd *cli.Command) error {
+	cfg, err := loadConfig(ec.coreFlags)
+	if err != nil {
+		return err
+	}
+
+	identities, err := cfg.Age.ReadIdentities()
+	if err != nil {
+		return DecryptError(err)
+	}
+	chain := core.NewIdentityChain(identities)
+
+	p := printer.New(os.Stdout)
+	p.StatusList("Identities:", identityItems(cfg.Age, identities))
+	p.LineBreak()
+
+	var items []printer.StatusListItem
+	failed := 0
+	check := func(file string, partial bool) error {
+		data, err := os.ReadFile(file)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case partial && !bytes.Contains(data, []byte("ENC[age,")):
+			return nil
+		case partial:
+			_, err = fcrypt.DecryptValues(data, chain.For(file))
+		case !fcrypt.IsEncrypted(data):
+			return nil
+		default:
+			err = fcrypt.DecryptReader(bytes.NewReader(data), io.Discard, chain.For(file))
+		}
+
+		item := printer.StatusListItem{Ok: err == nil, Status: relToCwd(file)}
+		var noMatch *age.NoIdentityMatchError
+		switch {
+		case errors.As(err, &noMatch):
+			item.Status += " cannot be opened by any identity on this machine"
+		case err != nil:
+			item.Status += ": " + err.Error()
+		default:
+			item.Status += fmt.Sprintf(" (%s)", relToCwd(chain.Hint(file)))
+		}
+		if !item.Ok {
+			failed++
+		}
+		items = append(items, item)
+		return nil
+	}
+
+	for _, file := range cfg.EncryptedFiles() {
+		if !strings.HasSuffix(file, ".age") {
+			file += ".age"
+		}
+		if err := check(file, false); err != nil {
+			return err
+		}
+	}
+	for _, file := range cfg.PartialVaultFiles() {
+		if err := check(file, true); err != nil {
+			return err
+		}
+	}
+	for _, af := range cfg.Age.Files {
+		if err := check(af.Src, false); err != nil {
+			return err
+		}
+	}
+
+	if len(items) == 0 {
+		fmt.Println("No encrypted files found")
+		return nil
+	}
+
+	p.StatusList("Encrypted files:", items)
+	if failed > 0 {
+		return ValidationError(fmt.Errorf("%d file(s) cannot be decrypted on this machine", failed))
+	}
+	return nil
+}
+
+// identityItems reports which configured identity files are present.
+func identityItems(a core.Age, present []core.NamedIdentity) []printer.StatusListItem {
+	paths := a.IdentityPaths()
+	items := make([]printer.StatusListItem, 0, len(paths))
+	for _, path := range paths {
+		ok := slices.ContainsFunc(present, func(n core.NamedIdentity) bool { return n.Path == path })
+		status := relToCwd(path)
+		if !ok {
+			status += " (not found)"
+		}
+		items = append(items, printer.StatusListItem{Ok: ok, Status: status})
+	}
+	return items
+}
@@ -0,0 +1,196 @@
+package commands
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/pkgs/printer"
+	"github.com/hay-kot/mmdot/pkgs/styles"
+	"github.com/urfave/cli/v3"
+)
+
+//go:embed examples/*.yml
+var examplesFS embed.FS
+
+// example is an annotated config snippet. Each file in examples/ starts with
+// a comment header:
+//
+//	# summary: One line describing the example
+//	# command: templates          (optional, the command whose help links it)
+//	#
+//	# $ mmdot templates diff      # what the command does
+//
+// followed by the config itself. Every example is loaded as a config in
+// tests, so the snippets stay valid as the schema changes.
+type example struct {
+	Name     string
+	Summary  string
+	Command  string
+	Commands []exampleCommand
+	Config   string
+}
+
+type exampleCommand struct {
+	Line    string
+	Comment string
+}
+
+// exampleCommentRe separates a trailing comment from an example command.
+var exampleCommentRe = regexp.MustCompile(`\s{2,}#\s*`)
+
+func parseExample(name, src string) (example, error) {
+	ex := example{Name: name}
+
+	lines := strings.Split(src, "\n")
+	i := 0
+	for ; i < len(lines) && strings.HasPrefix(lines[i], "#"); i++ {
+		line := strings.TrimSpace(strings.TrimPrefix(lines[i], "#"))
+		switch {
+		case strings.HasPrefix(line, "summary:"):
+			ex.Summary = strings.TrimSpace(strings.TrimPrefix(line, "summary:"))
+		case strings.HasPrefix(line, "command:"):
+			ex.Command = strings.TrimSpace(strings.TrimPrefix(line, "command:"))
+		case strings.HasPrefix(line, "$ "):
+			parts := exampleCommentRe.Split(strings.TrimPrefix(line, "$ "), 2)
+			cmd := exampleCommand{Line: parts[0]}
+			if len(parts) == 2 {
+				cmd.Comment = parts[1]
+			}
+			ex.Commands = append(ex.Commands, cmd)
+		}
+	}
+	ex.Config = strings.TrimLeft(strings.Join(lines[i:], "\n"), "\n")
+
+	if ex.Summary == "" {
+		return ex, fmt.Errorf("example %s: summary is required", name)
+	}
+	if len(ex.Commands) == 0 {
+		return ex, fmt.Errorf("example %s: at least one command is required", name)
+	}
+	return ex, nil
+}
+
+// loadExamples returns the embedded examples sorted by name.
+func loadExamples() ([]example, error) {
+	files, err := fs.Glob(examplesFS, "examples/*.yml")
+	if err != nil {
+		return nil, err
+	}
+
+	examples := make([]example, 0, len(files))
+	for _, file := range files {
+		data, err := examplesFS.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		ex, err := parseExample(strings.TrimSuffix(path.Base(file), ".yml"), string(data))
+		if err != nil {
+			return nil, err
+		}
+		examples = append(examples, ex)
+	}
+	return examples, nil
+}
+
+// writeCommands writes the example's commands with their comments aligned.
+func (ex example) writeCommands(sb *strings.Builder, indent string) {
+	width := 0
+	for _, cmd := range ex.Commands {
+		width = max(width, len(cmd.Line))
+	}
+	for _, cmd := range ex.Commands {
+		line := indent + cmd.Line
+		if cmd.Comment != "" {
+			line += strings.Repeat(" ", width-len(cmd.Line)) + "  # " + cmd.Comment
+		}
+		sb.WriteString(line + "\n")
+	}
+}
+
+type ExamplesCmd struct {
+	coreFlags *core.Flags
+}
+
+func NewExamplesCmd(coreFlags *core.Flags) *ExamplesCmd {
+	return &ExamplesCmd{coreFlags: coreFlags}
+}
+
+// Register adds the examples command and appends each example's commands to
+// the help of the command it documents, so it must be registered after every
+// other command.
+func (ec *ExamplesCmd) Register(app *cli.Command) *cli.Command {
+	examples, err := loadExamples()
+	if err != nil {
+		// examples are embedded and verified by tests
+		panic(err)
+	}
+
+	for _, ex := range examples {
+		cmd := app.Command(ex.Command)
+		if cmd == nil {
+			continue
+		}
+
+		var sb strings.Builder
+		if cmd.Description != "" {
+			sb.WriteString(cmd.Description + "\n\n")
+		}
+		fmt.Fprintf(&sb, "Example (mmdot examples %s):\n", ex.Name)
+		ex.writeCommands(&sb, "  ")
+		cmd.Description = strings.TrimSuffix(sb.String(), "\n")
+	}
+
+	app.Commands = append(app.Commands, &cli.Command{
+		Name:      "examples",
+		Usage:     "print annotated config snippets and commands for each feature",
+		ArgsUsage: "[name...]",
+		Action: func(ctx context.Context, c *cli.Command) error {
+			return ec.print(examples, c.Args().Slice())
+		},
+	})
+	return app
+}
+
+func (ec *ExamplesCmd) print(examples []example, names []string) error {
+	if len(names) == 0 {
+		items := make([]string, 0, len(examples))
+		width := 0
+		for _, ex := range examples {
+			width = max(width, len(ex.Name))
+		}
+		for _, ex := range examples {
+			items = append(items, fmt.Sprintf("%-*s  %s", width, ex.Name, ex.Summary))
+		}
+		printer.New(os.Stdout).List("Examples (mmdot examples <name>):", items)
+		return nil
+	}
+
+	for i, name := range names {
+		idx := slices.IndexFunc(examples, func(ex example) bool { return ex.Name == name })
+		if idx == -1 {
+			return fmt.Errorf("example %q not found", name)
+		}
+		ex := examples[idx]
+
+		if i > 0 {
+			fmt.Println()
+		}
+
+		var sb strings.Builder
+		sb.WriteString(styles.Bold(ex.Summary) + "\n\n")
+		sb.WriteString("# mmdot.yml\n")
+		sb.WriteString(ex.Config)
+		sb.WriteString("\n")
+		ex.writeCommands(&sb, "$ ")
+		fmt.Print(sb.String())
+	}
+	return nil
+}
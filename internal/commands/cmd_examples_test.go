@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/pkgs/cll"
+	"github.com/urfave/cli/v3"
+)
+
+// exampleApp registers every command the way main does.
+func exampleApp() *cli.Command {
+	flags := &core.Flags{}
+	return cll.Register(&cli.Command{Name: "mmdot"},
+		NewRunCmd(flags), NewAliasCmd(flags), NewApplyCmd(flags), NewBinariesCmd(flags),
+		NewBrewCmd(flags), NewBundleCmd(flags), NewCleanCmd(flags), NewConfigCmd(flags),
+		NewDaemonCmd(flags), NewDoctorCmd(flags), NewEditorsCmd(flags),
+		NewEncryptCmd(flags), NewFontsCmd(flags), NewGenerateCmd(flags), NewGitCmd(flags),
+		NewGPGCmd(flags), NewHookCmd(flags), NewLLMTextCmd(flags), NewMacOSCmd(flags),
+		NewPlanCmd(flags), NewReposCmd(flags), NewScheduleCmd(flags), NewScriptsCmd(flags),
+		NewServicesCmd(flags), NewShellCmd(flags), NewStatusCmd(flags),
+		NewTemplatesCmd(flags), NewTUICmd(flags), NewWhichCmd(flags), NewWorkspaceCmd(flags),
+		NewExamplesCmd(flags),
+	)
+}
+
+func TestExamples(t *testing.T) {
+	examples, err := loadExamples()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(examples) == 0 {
+		t.Fatal("no examples embedded")
+	}
+
+	app := exampleApp()
+
+	for _, ex := range examples {
+		t.Run(ex.Name, func(t *testing.T) {
+			// unknown keys are typos that LoadConfig would silently ignore
+			var cfg core.ConfigFile
+			if err := yaml.UnmarshalWithOptions([]byte(ex.Config), &cfg, yaml.Strict()); err != nil {
+				t.Fatalf("config does not match the schema: %v", err)
+			}
+
+			path := filepath.Join(t.TempDir(), "mmdot.yml")
+			if err := os.WriteFile(path, []byte(ex.Config), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := core.LoadConfig(path); err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+
+			if ex.Command != "" && app.Command(ex.Command) == nil {
+				t.Errorf("command %q does not exist", ex.Command)
+			}
+
+			for _, cmd := range ex.Commands {
+				if err := checkExampleCommand(app, cmd.Line); err != nil {
+					t.Errorf("%s: %v", cmd.Line, err)
+				}
+			}
+		})
+	}
+}
+
+// checkExampleCommand walks the subcommands named in line, stopping at the
+// first flag or argument of a command without subcommands.
+func checkExampleCommand(app *cli.Command, line string) error {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[0] != "mmdot" {
+		return fmt.Errorf("must start with mmdot and a command")
+	}
+
+	cmd := app
+	for _, field := range fields[1:] {
+		if strings.HasPrefix(field, "-") {
+			break
+		}
+		sub := cmd.Command(field)
+		if sub == nil {
+			if len(cmd.Commands) > 0 {
+				return fmt.Errorf("%q is not a subcommand of %s", field, cmd.Name)
+			}
+			break
+		}
+		cmd = sub
+	}
+	return nil
+}
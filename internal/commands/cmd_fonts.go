@@ -0,0 +1,197 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/fonts"
+	"github.com/hay-kot/mmdot/pkgs/printer"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+type FontsCmd struct {
+	coreFlags *core.Flags
+	flags     struct {
+		Force   bool
+		Verbose bool
+	}
+}
+
+func NewFontsCmd(coreFlags *core.Flags) *FontsCmd {
+	return &FontsCmd{coreFlags: coreFlags}
+}
+
+func (fc *FontsCmd) Register(app *cli.Command) *cli.Command {
+	cmd := &cli.Command{
+		Name:  "fonts",
+		Usage: "install fonts declared in the config",
+		Description: `Fonts declared under 'fonts:' are downloaded (by default from the Nerd Fonts
+releases) and installed into ~/Library/Fonts on macOS or ~/.local/share/fonts
+elsewhere. On Linux the fontconfig cache is refreshed with fc-cache after
+installing.`,
+		Commands: []*cli.Command{
+			{
+				Name:      "sync",
+				Usage:     "install fonts that are missing or whose source changed",
+				ArgsUsage: "[font-name...]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:        "force",
+						Usage:       "reinstall fonts that are already up to date",
+						Destination: &fc.flags.Force,
+					},
+				},
+				Action: fc.sync,
+			},
+			{
+				Name:      "diff",
+				Usage:     "compare configured fonts with the installed font files",
+				ArgsUsage: "[font-name...]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:        "verbose",
+						Aliases:     []string{"v"},
+						Usage:       "list font files not installed by mmdot",
+						Destination: &fc.flags.Verbose,
+					},
+				},
+				Action: fc.diff,
+			},
+		},
+	}
+
+	app.Commands = append(app.Commands, cmd)
+	return app
+}
+
+// load returns the configured fonts, limited to names when provided, and the
+// installer for this platform.
+func (fc *FontsCmd) load(c *cli.Command) ([]core.Font, *fonts.Installer, error) {
+	cfg, err := loadConfig(fc.coreFlags)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	selected := cfg.Fonts
+	if names := c.Args().Slice(); len(names) > 0 {
+		selected = nil
+		for _, name := range names {
+			idx := slices.IndexFunc(cfg.Fonts, func(f core.Font) bool { return f.Name == name })
+			if idx == -1 {
+				return nil, nil, fmt.Errorf("font %q not found", name)
+			}
+			selected = append(selected, cfg.Fonts[idx])
+		}
+	}
+
+	installer, err := fonts.NewInstaller()
+	if err != nil {
+		return nil, nil, err
+	}
+	return selected, installer, nil
+}
+
+func (fc *FontsCmd) sync(ctx context.Context, c *cli.Command) error {
+	selected, installer, err := fc.load(c)
+	if err != nil {
+		return err
+	}
+
+	if len(selected) == 0 {
+		fmt.Println("No fonts configured")
+		return nil
+	}
+
+	failed, changed := 0, 0
+	items := make([]printer.StatusListItem, 0, len(selected))
+	for _, f := range selected {
+		st, err := installer.Status(f)
+		if err != nil {
+			return err
+		}
+		if st.Current() && !fc.flags.Force {
+			items = append(items, printer.StatusListItem{Ok: true, Status: fmt.Sprintf("%s (up to date)", f.Name)})
+			continue
+		}
+
+		files, err := installer.Install(ctx, f)
+		if errors.Is(err, core.ErrOffline) {
+			items = append(items, printer.StatusListItem{Ok: false, Status: fmt.Sprintf("%s: %v", f.Name, core.ErrOffline)})
+			continue
+		} else if err != nil {
+			failed++
+			items = append(items, printer.StatusListItem{Ok: false, Status: err.Error()})
+			continue
+		}
+
+		changed++
+		status := fmt.Sprintf("%s (%d files)", f.Name, len(files))
+		if f.Checksum == "" {
+			status += " (checksum not verified)"
+		}
+		items = append(items, printer.StatusListItem{Ok: true, Status: status})
+	}
+
+	if changed > 0 {
+		if err := installer.RefreshCache(ctx); err != nil {
+			log.Warn().Err(err).Msg("failed to refresh font cache")
+		}
+	}
+
+	printer.New(os.Stdout).StatusList(fmt.Sprintf("Fonts (%s):", installer.Dir), items)
+
+	if failed > 0 {
+		return PartialError(fmt.Errorf("%d of %d fonts failed to install", failed, len(selected)))
+	}
+	return nil
+}
+
+func (fc *FontsCmd) diff(ctx context.Context, c *cli.Command) error {
+	selected, installer, err := fc.load(c)
+	if err != nil {
+		return err
+	}
+
+	var items []printer.StatusListItem
+	for _, f := range selected {
+		st, err := installer.Status(f)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case !st.Installed:
+			items = append(items, printer.StatusListItem{Status: fmt.Sprintf("%s: not installed", f.Name)})
+		case st.Stale:
+			items = append(items, printer.StatusListItem{Status: fmt.Sprintf("%s: source changed to %s", f.Name, f.Source())})
+		case len(st.Missing) > 0:
+			items = append(items, printer.StatusListItem{Status: fmt.Sprintf("%s: %d of its files are missing", f.Name, len(st.Missing))})
+		}
+	}
+
+	p := printer.New(os.Stdout)
+	if len(items) == 0 {
+		fmt.Println("All fonts are up to date")
+	} else {
+		p.StatusList("Outdated fonts:", items)
+	}
+
+	unmanaged, err := installer.Unmanaged()
+	if err != nil {
+		return err
+	}
+	if len(unmanaged) > 0 {
+		if fc.flags.Verbose {
+			p.LineBreak()
+			p.List("Font files not installed by mmdot:", unmanaged)
+		} else {
+			fmt.Printf("\n%d font files in %s are not installed by mmdot (use -v to list them)\n", len(unmanaged), installer.Dir)
+		}
+	}
+	return nil
+}
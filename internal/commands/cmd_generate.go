@@ -0,0 +1,151 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/generator"
+	"github.com/hay-kot/mmdot/pkgs/printer"
+	"github.com/urfave/cli/v3"
+)
+
+type GenerateCmd struct {
+	coreFlags *core.Flags
+	flags     struct {
+		OutputDir string
+		Force     bool
+	}
+}
+
+func NewGenerateCmd(coreFlags *core.Flags) *GenerateCmd {
+	return &GenerateCmd{coreFlags: coreFlags}
+}
+
+func (gc *GenerateCmd) Register(app *cli.Command) *cli.Command {
+	cmd := &cli.Command{
+		Name:      "generate",
+		Usage:     "render templates to their outputs, or to a staging directory",
+		ArgsUsage: "[template-name...]",
+		Description: `Renders the named templates, or every template, without running scripts or
+services. Templates whose skip_if_exists or skip_if condition matches are
+skipped, as are existing outputs of no_clobber templates unless --force is set.
+
+With --output-dir, outputs are written beneath the directory at their full
+destination path instead of to the live system, e.g. ~/.zshrc is staged as
+<dir>/home/<user>/.zshrc. Skip conditions are ignored so the staging directory
+always holds every selected template. Use it to inspect changes, tar the
+result for another machine, or diff CI artifacts.
+
+Examples:
+	 mmdot generate                        # Render every template
+	 mmdot generate zshrc gitconfig        # Render specific templates
+	 mmdot generate --output-dir ./stage   # Stage every template under ./stage`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "output-dir",
+				Aliases:     []string{"o"},
+				Usage:       "write outputs beneath `DIR` mirroring their destination paths",
+				Destination: &gc.flags.OutputDir,
+			},
+			&cli.BoolFlag{
+				Name:        "force",
+				Aliases:     []string{"f"},
+				Usage:       "overwrite the outputs of templates marked no_clobber",
+				Destination: &gc.flags.Force,
+			},
+		},
+		Action: gc.generate,
+	}
+
+	app.Commands = append(app.Commands, cmd)
+	return app
+}
+
+func (gc *GenerateCmd) generate(ctx context.Context, c *cli.Command) error {
+	cfg, err := loadConfig(gc.coreFlags)
+	if err != nil {
+		return err
+	}
+
+	names := cleanPatterns(c.Args().Slice())
+	if err := checkPatterns("template", names, itemNames(&cfg, []RunnerType{RunnerTypeTemplate})); err != nil {
+		return err
+	}
+
+	var templates []core.Template
+	for _, tmpl := range cfg.Templates {
+		if len(names) > 0 && !matchAny(names, tmpl.Name) {
+			continue
+		}
+		templates = append(templates, tmpl)
+	}
+
+	if len(templates) == 0 {
+		fmt.Println("No templates to generate")
+		return nil
+	}
+
+	if err := askPrompts(ctx, &cfg, true); err != nil {
+		return err
+	}
+
+	engine := generator.NewEngine(&cfg)
+	if err := checkRequiredVars(ctx, engine, templates); err != nil {
+		return err
+	}
+
+	staging := ""
+	if gc.flags.OutputDir != "" {
+		staging, err = filepath.Abs(gc.flags.OutputDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	items := make([]printer.StatusListItem, 0, len(templates))
+	for _, tmpl := range templates {
+		if staging != "" {
+			tmpl.Output = stagedPath(staging, tmpl.Output)
+		} else {
+			reason, err := tmpl.SkipReason(ctx, hookShell(&cfg), cfg.ConfigDir, gc.flags.Force)
+			if err != nil {
+				return fmt.Errorf("template %s: %w", tmpl.Name, err)
+			}
+			if reason != "" {
+				items = append(items, printer.StatusListItem{Ok: true, Status: fmt.Sprintf("%s (skipped: %s)", tmpl.Name, reason)})
+				continue
+			}
+		}
+
+		if err := engine.RenderTemplate(ctx, tmpl); err != nil {
+			return fmt.Errorf("failed to generate template %s: %w", tmpl.Name, err)
+		}
+		items = append(items, printer.StatusListItem{Ok: true, Status: fmt.Sprintf("%s -> %s", tmpl.Name, tmpl.Output)})
+	}
+
+	title := "Generated:"
+	if staging != "" {
+		title = "Staged in " + staging + ":"
+	}
+	printer.New(os.Stdout).StatusList(title, items)
+	return nil
+}
+
+// stagedPath returns where output is written beneath the staging directory:
+// the absolute destination path, minus any volume name, joined onto dir.
+func stagedPath(dir, output string) string {
+	return filepath.Join(dir, filepath.ToSlash(output[len(filepath.VolumeName(output)):]))
+}
+
+// matchAny reports whether name matches any of the glob patterns.
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if matchName(p, name) {
+			return true
+		}
+	}
+	return false
+}
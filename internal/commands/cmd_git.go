@@ -0,0 +1,188 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/generator"
+	"github.com/hay-kot/mmdot/pkgs/printer"
+	"github.com/urfave/cli/v3"
+)
+
+type GitCmd struct {
+	coreFlags *core.Flags
+}
+
+func NewGitCmd(coreFlags *core.Flags) *GitCmd {
+	return &GitCmd{coreFlags: coreFlags}
+}
+
+func (gc *GitCmd) Register(app *cli.Command) *cli.Command {
+	cmd := &cli.Command{
+		Name:  "git",
+		Usage: "check the git config and profiles declared in the config",
+		Description: `The 'git:' section renders ~/.gitconfig and one file per profile, included
+with includeIf for repositories below the profile's dir. The files are
+templates tagged "git": render them with 'mmdot run +git' or 'mmdot generate
+gitconfig', and preview them with 'mmdot templates diff'.`,
+		Commands: []*cli.Command{
+			{
+				Name:   "validate",
+				Usage:  "check that the signing and SSH keys referenced by each identity exist",
+				Action: gc.validate,
+			},
+		},
+	}
+
+	app.Commands = append(app.Commands, cmd)
+	return app
+}
+
+func (gc *GitCmd) validate(ctx context.Context, c *cli.Command) error {
+	cfg, err := loadConfig(gc.coreFlags)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Git.IsZero() {
+		fmt.Println("No git config declared")
+		return nil
+	}
+
+	engine := generator.NewEngine(&cfg)
+	defaultFormat := ""
+	failed := 0
+	var items []printer.StatusListItem
+
+	for _, tmpl := range cfg.Templates {
+		if !isGitTemplate(tmpl) {
+			continue
+		}
+
+		rendered, err := engine.Render(ctx, tmpl)
+		if err != nil {
+			return fmt.Errorf("failed to render %s: %w", tmpl.Name, err)
+		}
+
+		values := parseGitConfig(string(rendered))
+		format := values["gpg.format"]
+		if tmpl.Name == "gitconfig" {
+			defaultFormat = format
+		} else if format == "" {
+			format = defaultFormat
+		}
+
+		problems := checkGitKeys(ctx, values, format)
+		if len(problems) == 0 {
+			items = append(items, printer.StatusListItem{Ok: true, Status: tmpl.Name})
+		}
+		for _, problem := range problems {
+			failed++
+			items = append(items, printer.StatusListItem{Ok: false, Status: fmt.Sprintf("%s: %s", tmpl.Name, problem)})
+		}
+	}
+
+	printer.New(os.Stdout).StatusList("Git identities:", items)
+
+	if failed > 0 {
+		return ValidationError(fmt.Errorf("%d git key(s) could not be found", failed))
+	}
+	return nil
+}
+
+// isGitTemplate reports whether tmpl was generated from the git section.
+func isGitTemplate(tmpl core.Template) bool {
+	return len(tmpl.Tags) == 1 && tmpl.Tags[0] == core.GitTag &&
+		(tmpl.Name == "gitconfig" || strings.HasPrefix(tmpl.Name, "gitconfig-"))
+}
+
+// checkGitKeys returns a problem for every referenced key that cannot be
+// found: the signing key (a file for SSH signing, a secret key in the GPG
+// keyring otherwise) and the identity file of core.sshCommand.
+func checkGitKeys(ctx context.Context, values map[string]string, format string) []string {
+	var problems []string
+
+	if key := values["user.signingkey"]; key != "" {
+		switch {
+		case format == "ssh":
+			if path, ok := keyPath(key); ok {
+				if _, err := os.Stat(path); err != nil {
+					problems = append(problems, fmt.Sprintf("SSH signing key %s not found", path))
+				}
+			}
+		case format == "" || format == "openpgp":
+			if err := exec.CommandContext(ctx, "gpg", "--list-secret-keys", key).Run(); err != nil {
+				if errors.Is(err, exec.ErrNotFound) {
+					problems = append(problems, "gpg is not installed")
+				} else {
+					problems = append(problems, fmt.Sprintf("GPG secret key %s not in keyring", key))
+				}
+			}
+		}
+	}
+
+	if cmd := values["core.sshcommand"]; cmd != "" {
+		fields := strings.Fields(cmd)
+		for i, f := range fields {
+			if f == "-i" && i+1 < len(fields) {
+				path, _ := keyPath(fields[i+1])
+				if _, err := os.Stat(path); err != nil {
+					problems = append(problems, fmt.Sprintf("SSH key %s not found", path))
+				}
+			}
+		}
+	}
+
+	return problems
+}
+
+// keyPath expands a key file path, reporting false for inline keys such as
+// "key::ssh-ed25519 AAAA...".
+func keyPath(key string) (string, bool) {
+	if strings.HasPrefix(key, "key::") || strings.HasPrefix(key, "ssh-") {
+		return "", false
+	}
+	if rest, ok := strings.CutPrefix(key, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest), true
+		}
+	}
+	return key, true
+}
+
+// parseGitConfig returns the values of a git config file keyed by lowercase
+// "section.key" or "section.subsection.key". Later values win.
+func parseGitConfig(text string) map[string]string {
+	values := map[string]string{}
+	section := ""
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || line[0] == '#' || line[0] == ';':
+			continue
+		case line[0] == '[' && strings.HasSuffix(line, "]"):
+			name, sub, _ := strings.Cut(line[1:len(line)-1], " ")
+			section = strings.ToLower(name)
+			if sub = strings.Trim(sub, `"`); sub != "" {
+				section += "." + sub
+			}
+		default:
+			key, value, _ := strings.Cut(line, "=")
+			value = strings.TrimSpace(value)
+			if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+				value = strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(value[1 : len(value)-1])
+			}
+			values[section+"."+strings.ToLower(strings.TrimSpace(key))] = value
+		}
+	}
+	return values
+}
@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+
+	"filippo.io/age"
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/gpg"
+	"github.com/hay-kot/mmdot/pkgs/fcrypt"
+	"github.com/hay-kot/mmdot/pkgs/printer"
+	"github.com/urfave/cli/v3"
+)
+
+type GPGCmd struct {
+	coreFlags *core.Flags
+}
+
+func NewGPGCmd(coreFlags *core.Flags) *GPGCmd {
+	return &GPGCmd{coreFlags: coreFlags}
+}
+
+func (gc *GPGCmd) Register(app *cli.Command) *cli.Command {
+	cmd := &cli.Command{
+		Name:  "gpg",
+		Usage: "import the GPG secret keys declared in the config",
+		Description: `Keys under 'gpg.keys' are age-encrypted armored secret keys. Keys whose
+fingerprint is already in the keyring are skipped. The 'gpg.agent' section
+renders gpg-agent.conf as a template tagged "gpg"; render it with
+'mmdot run +gpg' and reload the agent with 'gpgconf --reload gpg-agent'.
+'mmdot doctor' reports keys missing from the keyring.`,
+		Commands: []*cli.Command{
+			{
+				Name:   "import",
+				Usage:  "import missing keys and set their ownertrust",
+				Action: gc.importKeys,
+			},
+		},
+	}
+
+	app.Commands = append(app.Commands, cmd)
+	return app
+}
+
+func (gc *GPGCmd) importKeys(ctx context.Context, c *cli.Command) error {
+	cfg, err := loadConfig(gc.coreFlags)
+	if err != nil {
+		return err
+	}
+
+	if len(cfg.GPG.Keys) == 0 {
+		fmt.Println("No gpg keys declared")
+		return nil
+	}
+
+	client := gpg.New()
+	statuses, err := client.Status(ctx, cfg.GPG.Keys)
+	if err != nil {
+		return err
+	}
+
+	var identity age.Identity
+	items := make([]printer.StatusListItem, 0, len(statuses))
+	for _, st := range statuses {
+		k := st.Key
+		if st.Ok() {
+			items = append(items, printer.StatusListItem{Ok: true, Status: fmt.Sprintf("%s (up to date)", k.Label())})
+			continue
+		}
+
+		if !st.Imported {
+			if identity == nil {
+				if identity, err = cfg.Age.ReadIdentity(); err != nil {
+					return DecryptError(err)
+				}
+			}
+
+			armored, err := decryptKeyFile(k.Src, identity)
+			if err != nil {
+				return err
+			}
+			if err := client.Import(ctx, k, armored); err != nil {
+				return err
+			}
+		}
+
+		if !st.Trusted {
+			if err := client.SetTrust(ctx, k); err != nil {
+				return err
+			}
+		}
+
+		items = append(items, printer.StatusListItem{Ok: true, Status: k.Label()})
+	}
+
+	printer.New(os.Stdout).StatusList("GPG keys:", items)
+	return nil
+}
+
+// decryptKeyFile decrypts an age-encrypted key file into memory so the
+// plaintext key is never written to disk.
+func decryptKeyFile(path string, identity age.Identity) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var plaintext bytes.Buffer
+	if err := fcrypt.DecryptReader(f, &plaintext, identity); err != nil {
+		return nil, DecryptError(fmt.Errorf("failed to decrypt %s: %w", path, err))
+	}
+	return plaintext.Bytes(), nil
+}
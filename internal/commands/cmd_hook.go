@@ -8,18 +8,82 @@ import (
 	"strings"
 
 	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/pkgs/atomicwrite"
 	"github.com/rs/zerolog/log"
 	"github.com/urfave/cli/v3"
 )
 
 type HookCmd struct {
 	coreFlags *core.Flags
+	flags     struct {
+		PostMerge bool
+	}
 }
 
 func NewHookCmd(coreFlags *core.Flags) *HookCmd {
 	return &HookCmd{coreFlags: coreFlags}
 }
 
+// gitHook is a section mmdot adds to a git hook script. The section starts
+// with the marker comment and holds one mmdot command per line.
+type gitHook struct {
+	name    string // hook file name in .git/hooks
+	marker  string
+	comment string
+	args    []string // mmdot arguments after --config, one command each
+}
+
+var (
+	preCommitHook = gitHook{
+		name:    "pre-commit",
+		marker:  "mmdot pre-commit hook",
+		comment: "check vault files are encrypted",
+		args:    []string{"encrypt audit --staged || exit 1", "encrypt --dry-run || exit 1"},
+	}
+	postMergeHook = gitHook{
+		name:    "post-merge",
+		marker:  "mmdot post-merge hook",
+		comment: "apply config changes brought in by the merge",
+		args:    []string{"run --since ORIG_HEAD --non-interactive"},
+	}
+)
+
+// section returns the hook section calling mmdotPath with configPath.
+func (h gitHook) section(mmdotPath, configPath string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "\n# %s - %s\n", h.marker, h.comment)
+	for _, args := range h.args {
+		fmt.Fprintf(&sb, "%s --config=\"%s\" %s\n", mmdotPath, configPath, args)
+	}
+	return sb.String()
+}
+
+// remove returns content without the hook section.
+func (h gitHook) remove(content string) string {
+	lines := strings.Split(content, "\n")
+	var newLines []string
+	inMmdotSection := false
+
+	for _, line := range lines {
+		if strings.Contains(line, h.marker) {
+			inMmdotSection = true
+			// drop the blank line written before the section
+			if n := len(newLines); n > 0 && strings.TrimSpace(newLines[n-1]) == "" {
+				newLines = newLines[:n-1]
+			}
+			continue
+		}
+		if inMmdotSection && strings.Contains(line, " --config=") {
+			continue
+		}
+		inMmdotSection = false
+
+		newLines = append(newLines, line)
+	}
+
+	return strings.Join(newLines, "\n")
+}
+
 func (hc *HookCmd) Register(app *cli.Command) *cli.Command {
 	cmds := []*cli.Command{
 		{
@@ -32,15 +96,28 @@ func (hc *HookCmd) Register(app *cli.Command) *cli.Command {
 					Description: `Installs a pre-commit hook that prevents commits containing unencrypted vault files.
 
 The hook will call 'mmdot encrypt --dry-run' before each commit to verify that all vault files
-are properly encrypted with .age extension.
-
-If a pre-commit hook already exists, the mmdot check will be appended to it.`,
+are properly encrypted with .age extension, and 'mmdot encrypt audit --staged' to catch staged
+files outside the config that look like secrets, such as private keys and API tokens.
+
+With --post-merge a post-merge hook is installed as well. It runs
+'mmdot run --since ORIG_HEAD --non-interactive' after every pull or merge, so the
+templates, scripts, and services the pulled commits changed are applied without a
+manual step. Nothing is asked for while it runs and privileged scripts are skipped.
+
+If a hook already exists, the mmdot section will be appended to it.`,
+					Flags: []cli.Flag{
+						&cli.BoolFlag{
+							Name:        "post-merge",
+							Usage:       "also install a post-merge hook that runs changed items after each pull",
+							Destination: &hc.flags.PostMerge,
+						},
+					},
 					Action: hc.install,
 				},
 				{
 					Name:  "uninstall",
-					Usage: "remove the mmdot pre-commit hook",
-					Description: `Removes the mmdot pre-commit hook from .git/hooks/
+					Usage: "remove the mmdot pre-commit and post-merge hooks",
+					Description: `Removes the mmdot pre-commit and post-merge hooks from .git/hooks/
 
 This will only remove the mmdot section from hooks that were created/modified by 'mmdot hook install'.`,
 					Action: hc.uninstall,
@@ -61,7 +138,6 @@ func (hc *HookCmd) install(ctx context.Context, cmd *cli.Command) error {
 	}
 
 	hooksDir := filepath.Join(gitDir, "hooks")
-	hookPath := filepath.Join(hooksDir, "pre-commit")
 
 	// Create hooks directory if it doesn't exist
 	if err := os.MkdirAll(hooksDir, 0755); err != nil {
@@ -74,44 +150,54 @@ func (hc *HookCmd) install(ctx context.Context, cmd *cli.Command) error {
 		return fmt.Errorf("failed to get mmdot executable path: %w", err)
 	}
 
-	// Get config path relative to git root if possible
+	// Get config path relative to git root if possible, hooks run from there
 	configPath := hc.coreFlags.ConfigFilePath
 	gitRoot := filepath.Dir(gitDir)
 	if relPath, err := filepath.Rel(gitRoot, configPath); err == nil && !strings.HasPrefix(relPath, "..") {
 		configPath = relPath
 	}
 
-	// Create the mmdot hook section
-	mmdotHook := fmt.Sprintf(`
-# mmdot pre-commit hook - check vault files are encrypted
-%s --config="%s" encrypt --dry-run || exit 1
-`, mmdotPath, configPath)
+	hooks := []gitHook{preCommitHook}
+	if hc.flags.PostMerge {
+		hooks = append(hooks, postMergeHook)
+	}
+
+	for _, hook := range hooks {
+		if err := installHook(hooksDir, hook, hook.section(mmdotPath, configPath)); err != nil {
+			return err
+		}
+	}
+
+	log.Info().Msg("Installed hooks successfully")
+	return nil
+}
+
+func installHook(hooksDir string, hook gitHook, mmdotHook string) error {
+	hookPath := filepath.Join(hooksDir, hook.name)
 
 	var hookContent string
 
 	// Check if hook already exists
 	if existingContent, err := os.ReadFile(hookPath); err == nil {
 		// Hook exists, check if our section is already there
-		if strings.Contains(string(existingContent), "mmdot pre-commit hook") {
-			log.Info().Str("path", hookPath).Msg("mmdot pre-commit hook already installed")
+		if strings.Contains(string(existingContent), hook.marker) {
+			log.Info().Str("path", hookPath).Msgf("mmdot %s hook already installed", hook.name)
 			return nil
 		}
 
 		// Append to existing hook
 		hookContent = string(existingContent) + mmdotHook
-		log.Info().Str("path", hookPath).Msg("Appending mmdot check to existing pre-commit hook")
+		log.Info().Str("path", hookPath).Msgf("Appending mmdot section to existing %s hook", hook.name)
 	} else {
 		// Create new hook with shebang
 		hookContent = "#!/bin/sh\n" + mmdotHook
-		log.Info().Str("path", hookPath).Msg("Creating new pre-commit hook with mmdot check")
+		log.Info().Str("path", hookPath).Msgf("Creating new %s hook", hook.name)
 	}
 
 	// Write the hook file
-	if err := os.WriteFile(hookPath, []byte(hookContent), 0755); err != nil {
-		return fmt.Errorf("failed to write pre-commit hook: %w", err)
+	if err := atomicwrite.WriteFile(hookPath, []byte(hookContent), 0755); err != nil {
+		return fmt.Errorf("failed to write %s hook: %w", hook.name, err)
 	}
-
-	log.Info().Msg("Installed pre-commit hook successfully")
 	return nil
 }
 
@@ -122,66 +208,51 @@ func (hc *HookCmd) uninstall(ctx context.Context, cmd *cli.Command) error {
 		return fmt.Errorf("failed to find .git directory: %w", err)
 	}
 
-	hookPath := filepath.Join(gitDir, "hooks", "pre-commit")
+	for _, hook := range []gitHook{preCommitHook, postMergeHook} {
+		if err := uninstallHook(filepath.Join(gitDir, "hooks"), hook); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func uninstallHook(hooksDir string, hook gitHook) error {
+	hookPath := filepath.Join(hooksDir, hook.name)
 
 	// Check if hook exists
 	content, err := os.ReadFile(hookPath)
 	if os.IsNotExist(err) {
-		log.Info().Msg("No pre-commit hook found")
+		log.Debug().Msgf("No %s hook found", hook.name)
 		return nil
 	}
 	if err != nil {
-		return fmt.Errorf("failed to read pre-commit hook: %w", err)
+		return fmt.Errorf("failed to read %s hook: %w", hook.name, err)
 	}
 
 	// Check if our section exists
-	if !strings.Contains(string(content), "mmdot pre-commit hook") {
-		log.Info().Msg("mmdot hook not found in pre-commit")
+	if !strings.Contains(string(content), hook.marker) {
+		log.Info().Msgf("mmdot hook not found in %s", hook.name)
 		return nil
 	}
 
-	// Remove our section
-	lines := strings.Split(string(content), "\n")
-	var newLines []string
-	inMmdotSection := false
-
-	for _, line := range lines {
-		if strings.Contains(line, "mmdot pre-commit hook") {
-			inMmdotSection = true
-			continue
-		}
-		if inMmdotSection && (strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#")) {
-			// Skip comment lines and empty lines in mmdot section
-			if strings.HasPrefix(strings.TrimSpace(line), "#") {
-				continue
-			}
-		}
-		if inMmdotSection && strings.Contains(line, "encrypt --dry-run") {
-			inMmdotSection = false
-			continue
-		}
-
-		newLines = append(newLines, line)
-	}
-
-	newContent := strings.Join(newLines, "\n")
+	newContent := hook.remove(string(content))
 
 	// If the file is now empty or only has shebang, remove it entirely
 	trimmed := strings.TrimSpace(newContent)
 	if trimmed == "" || trimmed == "#!/bin/sh" {
 		if err := os.Remove(hookPath); err != nil {
-			return fmt.Errorf("failed to remove pre-commit hook: %w", err)
+			return fmt.Errorf("failed to remove %s hook: %w", hook.name, err)
 		}
-		log.Info().Str("path", hookPath).Msg("Removed empty pre-commit hook")
+		log.Info().Str("path", hookPath).Msgf("Removed empty %s hook", hook.name)
 		return nil
 	}
 
 	// Write back the modified hook
-	if err := os.WriteFile(hookPath, []byte(newContent), 0755); err != nil {
-		return fmt.Errorf("failed to write pre-commit hook: %w", err)
+	if err := atomicwrite.WriteFile(hookPath, []byte(newContent), 0755); err != nil {
+		return fmt.Errorf("failed to write %s hook: %w", hook.name, err)
 	}
 
-	log.Info().Str("path", hookPath).Msg("Removed mmdot section from pre-commit hook")
+	log.Info().Str("path", hookPath).Msgf("Removed mmdot section from %s hook", hook.name)
 	return nil
 }
 
@@ -0,0 +1,47 @@
+package commands
+
+import "testing"
+
+func Test_gitHook_remove(t *testing.T) {
+	existing := "#!/bin/sh\nmake lint\n"
+
+	tests := []struct {
+		name    string
+		hook    gitHook
+		content string
+		want    string
+	}{
+		{
+			name:    "pre-commit appended",
+			hook:    preCommitHook,
+			content: existing + preCommitHook.section("/usr/bin/mmdot", "mmdot.yml"),
+			want:    existing,
+		},
+		{
+			name:    "post-merge appended",
+			hook:    postMergeHook,
+			content: existing + postMergeHook.section("/usr/bin/mmdot", "mmdot.yml"),
+			want:    existing,
+		},
+		{
+			name:    "lines after the section are kept",
+			hook:    postMergeHook,
+			content: existing + postMergeHook.section("/usr/bin/mmdot", "mmdot.yml") + "echo done\n",
+			want:    existing + "echo done\n",
+		},
+		{
+			name:    "other hook untouched",
+			hook:    preCommitHook,
+			content: existing + postMergeHook.section("/usr/bin/mmdot", "mmdot.yml"),
+			want:    existing + postMergeHook.section("/usr/bin/mmdot", "mmdot.yml"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hook.remove(tt.content); got != tt.want {
+				t.Errorf("remove() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -49,9 +49,9 @@ func (lc *LLMTextCmd) config(ctx context.Context, c *cli.Command) error {
 	userVersion := int(c.Int("version"))
 
 	if userVersion == 0 {
-		cfg, err := core.SetupEnv(lc.flags.ConfigFilePath)
+		cfg, err := loadConfig(lc.flags)
 		if err != nil {
-			return fmt.Errorf("failed to load config: %w", err)
+			return err
 		}
 		userVersion = cfg.Version
 	}
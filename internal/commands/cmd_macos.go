@@ -0,0 +1,157 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/macos"
+	"github.com/hay-kot/mmdot/pkgs/linediff"
+	"github.com/hay-kot/mmdot/pkgs/printer"
+	"github.com/urfave/cli/v3"
+)
+
+type MacOSCmd struct {
+	coreFlags *core.Flags
+}
+
+func NewMacOSCmd(coreFlags *core.Flags) *MacOSCmd {
+	return &MacOSCmd{coreFlags: coreFlags}
+}
+
+func (mc *MacOSCmd) Register(app *cli.Command) *cli.Command {
+	cmd := &cli.Command{
+		Name:  "macos",
+		Usage: "diff and apply Dock, Finder, and hot corner preferences",
+		Description: `Preferences declared under 'macos:' are compared with the values reported by
+'defaults read' and written with 'defaults write'. Dock and Finder are
+restarted when their preferences change. Dock items (macos.dock.apps) are
+managed with dockutil.`,
+		Commands: []*cli.Command{
+			{
+				Name:   "diff",
+				Usage:  "show preferences that differ from the config",
+				Action: mc.diff,
+			},
+			{
+				Name:   "apply",
+				Usage:  "write preferences that differ from the config",
+				Action: mc.apply,
+			},
+		},
+	}
+
+	app.Commands = append(app.Commands, cmd)
+	return app
+}
+
+// macosPlan is the preference changes and Dock items needed to match the
+// config.
+type macosPlan struct {
+	cfg      *core.ConfigFile
+	changes  []macos.Change
+	dock     []string // current Dock apps, when managed
+	wantDock []string
+}
+
+func (p macosPlan) dockChanged() bool {
+	return p.wantDock != nil && !slices.Equal(p.dock, p.wantDock)
+}
+
+func (mc *MacOSCmd) plan(ctx context.Context, client *macos.Client) (macosPlan, error) {
+	if runtime.GOOS != "darwin" {
+		return macosPlan{}, errors.New("macos preferences can only be managed on macOS")
+	}
+
+	cfg, err := loadConfig(mc.coreFlags)
+	if err != nil {
+		return macosPlan{}, err
+	}
+
+	settings, err := macos.Settings(cfg.MacOS)
+	if err != nil {
+		return macosPlan{}, err
+	}
+
+	changes, err := client.Diff(ctx, settings)
+	if err != nil {
+		return macosPlan{}, err
+	}
+
+	plan := macosPlan{cfg: &cfg, changes: changes, wantDock: cfg.MacOS.Dock.Apps}
+	if plan.wantDock != nil {
+		if plan.dock, err = client.DockApps(ctx); err != nil {
+			return macosPlan{}, err
+		}
+	}
+	return plan, nil
+}
+
+func (mc *MacOSCmd) diff(ctx context.Context, c *cli.Command) error {
+	plan, err := mc.plan(ctx, macos.New())
+	if err != nil {
+		return err
+	}
+
+	if len(plan.changes) == 0 && !plan.dockChanged() {
+		fmt.Println("All macOS preferences are up to date")
+		return nil
+	}
+
+	if len(plan.changes) > 0 {
+		items := make([]printer.StatusListItem, 0, len(plan.changes))
+		for _, ch := range plan.changes {
+			current := ch.Current
+			if ch.Unset {
+				current = "(unset)"
+			}
+			items = append(items, printer.StatusListItem{Status: fmt.Sprintf("%s: %s => %s", ch.Setting, current, ch.Value)})
+		}
+		printer.New(os.Stdout).StatusList("Preferences:", items)
+	}
+
+	if plan.dockChanged() {
+		fmt.Println()
+		printDiff(ctx, plan.cfg, "dock (current)", "dock (config)",
+			linediff.Diff(strings.Join(plan.dock, "\n")+"\n", strings.Join(plan.wantDock, "\n")+"\n"))
+	}
+	return nil
+}
+
+func (mc *MacOSCmd) apply(ctx context.Context, c *cli.Command) error {
+	client := macos.New()
+	plan, err := mc.plan(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	dockChanged := plan.dockChanged()
+	if len(plan.changes) == 0 && !dockChanged {
+		fmt.Println("All macOS preferences are up to date")
+		return nil
+	}
+
+	if dockChanged {
+		if err := client.SetDockApps(ctx, plan.wantDock); err != nil {
+			return err
+		}
+	}
+	if err := client.Apply(ctx, plan.changes, dockChanged); err != nil {
+		return err
+	}
+
+	items := make([]printer.StatusListItem, 0, len(plan.changes)+1)
+	for _, ch := range plan.changes {
+		items = append(items, printer.StatusListItem{Ok: true, Status: fmt.Sprintf("%s = %s", ch.Setting, ch.Value)})
+	}
+	if dockChanged {
+		items = append(items, printer.StatusListItem{Ok: true, Status: fmt.Sprintf("dock apps (%d)", len(plan.wantDock))})
+	}
+	printer.New(os.Stdout).StatusList("Applied:", items)
+	return nil
+}
@@ -0,0 +1,537 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"github.com/expr-lang/expr/vm"
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/generator"
+	"github.com/hay-kot/mmdot/internal/plan"
+	"github.com/hay-kot/mmdot/internal/services"
+	"github.com/hay-kot/mmdot/pkgs/fcrypt"
+	"github.com/hay-kot/mmdot/pkgs/printer"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+type PlanCmd struct {
+	coreFlags *core.Flags
+	flags     struct {
+		Output       string
+		Types        []string
+		Macros       bool
+		Tags         []string
+		ExcludeTags  []string
+		Only         []string
+		Skip         []string
+		NoPrivileged bool
+		Force        bool
+		Brews        []string
+		CacheTTL     time.Duration
+		Refresh      bool
+		Plaintext    bool
+	}
+}
+
+func NewPlanCmd(coreFlags *core.Flags) *PlanCmd {
+	return &PlanCmd{coreFlags: coreFlags}
+}
+
+func (pc *PlanCmd) Register(app *cli.Command) *cli.Command {
+	cmd := &cli.Command{
+		Name:      "plan",
+		Usage:     "compute the changes a run would make and save them to a plan file",
+		ArgsUsage: "[expression]",
+		Description: `Selects items like 'mmdot run' (expression, tag, and name flags, falling back
+to run.default_expr, then every item) and records what running them would
+change without changing anything:
+
+  - templates and services whose rendered output differs from the file on disk
+  - scripts to run, with a hash of their contents
+  - Homebrew packages missing from the configs passed with --brew
+
+Review the plan, then execute exactly it with 'mmdot apply <file>'. Templates
+are written from the content stored in the plan, so apply does not need var
+files. The plan is written readable by its owner only.
+
+Templates and services that read vault or secret:// variables are stored
+encrypted to the config's age recipients, and apply decrypts them with the age
+identity. Without recipients they are refused; --plaintext-secrets stores them
+in plaintext instead.
+
+Run hooks are not part of a plan.`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "output",
+				Aliases:     []string{"o"},
+				Usage:       "write the plan to `FILE`",
+				Value:       "plan.json",
+				Destination: &pc.flags.Output,
+			},
+			&cli.StringSliceFlag{
+				Name:        "type",
+				Usage:       "filter by type: 'template', 'script', or 'service' (default: all)",
+				Destination: &pc.flags.Types,
+				Value:       slices.Clone(RunnerTypes),
+			},
+			&cli.StringSliceFlag{
+				Name:        "tags",
+				Aliases:     []string{"t"},
+				Usage:       "only plan items that have all of these tags",
+				Destination: &pc.flags.Tags,
+			},
+			&cli.StringSliceFlag{
+				Name:        "exclude-tags",
+				Aliases:     []string{"x"},
+				Usage:       "skip items that have any of these tags",
+				Destination: &pc.flags.ExcludeTags,
+			},
+			&cli.StringSliceFlag{
+				Name:        "only",
+				Usage:       "only plan items whose name matches one of these globs",
+				Destination: &pc.flags.Only,
+			},
+			&cli.StringSliceFlag{
+				Name:        "skip",
+				Usage:       "skip items whose name matches one of these globs",
+				Destination: &pc.flags.Skip,
+			},
+			&cli.BoolFlag{
+				Name:        "no-privileged",
+				Usage:       "leave scripts marked privileged out of the plan",
+				Destination: &pc.flags.NoPrivileged,
+			},
+			&cli.BoolFlag{
+				Name:        "force",
+				Aliases:     []string{"f"},
+				Usage:       "plan overwriting the outputs of templates marked no_clobber",
+				Destination: &pc.flags.Force,
+			},
+			&cli.StringSliceFlag{
+				Name:        "brew",
+				Usage:       "plan installing the missing packages of these brew configs",
+				Destination: &pc.flags.Brews,
+			},
+			&cli.DurationFlag{
+				Name:        "cache-ttl",
+				Usage:       "reuse the installed package list for this long (0 disables caching)",
+				Value:       core.DefaultBrewCacheTTL,
+				Destination: &pc.flags.CacheTTL,
+			},
+			&cli.BoolFlag{
+				Name:        "refresh",
+				Usage:       "ignore the cached list of installed packages",
+				Destination: &pc.flags.Refresh,
+			},
+			&cli.BoolFlag{
+				Name:        "plaintext-secrets",
+				Usage:       "store output that reads secrets in the plan unencrypted",
+				Destination: &pc.flags.Plaintext,
+			},
+			&cli.BoolFlag{
+				Name:        "macros",
+				Usage:       "enable macro (@macro) and tag shortcut (+tag, !tag) expansion (default: true)",
+				Destination: &pc.flags.Macros,
+				Value:       true,
+			},
+		},
+		Action: func(ctx context.Context, c *cli.Command) error {
+			cfg, err := loadConfig(pc.coreFlags)
+			if err != nil {
+				return err
+			}
+
+			p, err := pc.build(ctx, &cfg, strings.Join(c.Args().Slice(), " "))
+			if err != nil {
+				return err
+			}
+
+			if err := p.Write(pc.flags.Output); err != nil {
+				return fmt.Errorf("failed to write plan: %w", err)
+			}
+
+			printPlan(p)
+			fmt.Printf("Plan written to %s, run 'mmdot apply %s' to apply it\n", pc.flags.Output, pc.flags.Output)
+			return nil
+		},
+	}
+
+	app.Commands = append(app.Commands, cmd)
+	return app
+}
+
+// build computes the plan for the items matching expression.
+func (pc *PlanCmd) build(ctx context.Context, cfg *core.ConfigFile, expression string) (*plan.Plan, error) {
+	types, err := RunnerTypeFromStrings(pc.flags.Types)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := runnerOrder(cfg.Run)
+	if err != nil {
+		return nil, err
+	}
+
+	tagFilter := tagFilterExpr(pc.flags.Tags, pc.flags.ExcludeTags)
+	only, skip := cleanPatterns(pc.flags.Only), cleanPatterns(pc.flags.Skip)
+	names := itemNames(cfg, types)
+	if err := checkPatterns("--only", only, names); err != nil {
+		return nil, err
+	}
+	if err := checkPatterns("--skip", skip, names); err != nil {
+		return nil, err
+	}
+	nameFilter := nameFilterExpr(only, skip)
+
+	if expression == "" && tagFilter == "" && nameFilter == "" {
+		expression = cfg.Run.DefaultExpr
+	}
+	if expression == "" && tagFilter == "" && nameFilter == "" {
+		expression = "true"
+	}
+
+	program, err := compileExpr(ctx, expression, cfg.Macros, pc.flags.Macros, tagFilter, nameFilter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression: %w", err)
+	}
+
+	if slices.Contains(types, RunnerTypeTemplate) || slices.Contains(types, RunnerTypeService) {
+		if err := askPrompts(ctx, cfg, true); err != nil {
+			return nil, err
+		}
+	}
+
+	p := &plan.Plan{
+		Version:   plan.Version,
+		Created:   time.Now().UTC().Truncate(time.Second),
+		Config:    pc.coreFlags.ConfigFilePath,
+		ConfigDir: cfg.ConfigDir,
+		Shell:     cfg.Exec.Shell,
+		CleanEnv:  cfg.Exec.CleanEnv,
+		AllowEnv:  cfg.Exec.AllowEnv,
+		Expr:      expression,
+	}
+
+	// Packages are installed first so templates and scripts can rely on them
+	packages, err := pc.planPackages(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	p.Changes = append(p.Changes, packages...)
+
+	sealer := &secretSealer{age: cfg.Age, plaintext: pc.flags.Plaintext}
+	byType := map[RunnerType][]plan.Change{}
+	for _, rt := range order {
+		if !slices.Contains(types, rt) {
+			continue
+		}
+
+		var changes []plan.Change
+		switch rt {
+		case RunnerTypeTemplate:
+			changes, err = planTemplates(ctx, cfg, program, pc.flags.Force, sealer)
+		case RunnerTypeScript:
+			changes, err = planScripts(cfg, program, pc.flags.NoPrivileged)
+		case RunnerTypeService:
+			changes, err = planServices(ctx, cfg, program, sealer)
+		}
+		if err != nil {
+			return nil, err
+		}
+		byType[rt] = changes
+	}
+
+	// Same ordering as a run: stage by stage, runner types in run.order
+	for _, stage := range core.Stages {
+		for _, rt := range order {
+			for _, c := range byType[rt] {
+				if c.Stage == stage {
+					p.Changes = append(p.Changes, c)
+				}
+			}
+		}
+	}
+
+	return p, nil
+}
+
+func (pc *PlanCmd) planPackages(ctx context.Context, cfg *core.ConfigFile) ([]plan.Change, error) {
+	if len(pc.flags.Brews) == 0 {
+		return nil, nil
+	}
+
+	for _, name := range pc.flags.Brews {
+		if cfg.Brews.Get(name) == nil {
+			return nil, fmt.Errorf("brew config %q not found", name)
+		}
+	}
+	if err := validateBrewIncludes(cfg.Brews, pc.flags.Brews); err != nil {
+		return nil, err
+	}
+
+	installed := core.InstalledBrews(ctx, core.InstalledBrewsOptions{
+		TTL:     pc.flags.CacheTTL,
+		Refresh: pc.flags.Refresh,
+	})
+
+	var changes []plan.Change
+	seen := map[string]bool{}
+	for _, name := range pc.flags.Brews {
+		brews := cfg.Brews.Get(name)
+		if brews.Remove {
+			continue
+		}
+		for _, pkg := range brews.DiffInstalled(installed).Absent {
+			if seen[pkg] {
+				continue
+			}
+			seen[pkg] = true
+			changes = append(changes, installChange(brews, pkg))
+		}
+	}
+	return changes, nil
+}
+
+// installChange returns the change installing pkg, a package of brews, with
+// the install arguments it is configured with.
+func installChange(brews *core.Brews, pkg string) plan.Change {
+	change := plan.Change{
+		Kind:   plan.KindPackage,
+		Name:   pkg,
+		Action: plan.ActionInstall,
+	}
+	if i := slices.IndexFunc(brews.Casks, func(c core.BrewPackage) bool { return c.Name == pkg }); i >= 0 {
+		change.Cask = true
+		change.Args = brews.Casks[i].Args
+	} else if i := slices.IndexFunc(brews.Brews, func(b core.BrewPackage) bool { return b.Name == pkg }); i >= 0 {
+		change.Args = brews.Brews[i].Args
+	}
+	return change
+}
+
+func planTemplates(ctx context.Context, cfg *core.ConfigFile, program *vm.Program, force bool, sealer *secretSealer) ([]plan.Change, error) {
+	var selected []core.Template
+	for _, tmpl := range cfg.Templates {
+		enabled, err := evalCompiledExpr(program, map[string]any{
+			"tags": tmpl.Tags,
+			"name": tmpl.Name,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("expression evaluation failed for template %s: %w", tmpl.Name, err)
+		}
+		if enabled {
+			selected = append(selected, tmpl)
+		}
+	}
+
+	engine := generator.NewEngine(cfg)
+	if err := checkRequiredVars(ctx, engine, selected); err != nil {
+		return nil, err
+	}
+
+	var changes []plan.Change
+	for _, tmpl := range selected {
+		reason, err := tmpl.SkipReason(ctx, hookShell(cfg), cfg.ConfigDir, force)
+		if err != nil {
+			return nil, fmt.Errorf("template %s: %w", tmpl.Name, err)
+		}
+		if reason != "" {
+			log.Debug().Str("template", tmpl.Name).Str("reason", reason).Msg("skipped template")
+			continue
+		}
+
+		change, err := planFile(ctx, engine, tmpl, sealer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render template %s: %w", tmpl.Name, err)
+		}
+		if change == nil {
+			continue
+		}
+		change.Kind = plan.KindTemplate
+		change.Stage = tmpl.Stage.OrDefault()
+		changes = append(changes, *change)
+	}
+	return changes, nil
+}
+
+func planScripts(cfg *core.ConfigFile, program *vm.Program, noPrivileged bool) ([]plan.Change, error) {
+	var changes []plan.Change
+	for _, script := range cfg.Exec.Scripts {
+		enabled, err := evalCompiledExpr(program, map[string]any{
+			"tags": script.Tags,
+			"name": filepath.Base(script.Path),
+			"path": script.Path,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("expression evaluation failed for script %s: %w", script.Path, err)
+		}
+		if !enabled || (noPrivileged && script.Privileged) {
+			continue
+		}
+
+		data, err := os.ReadFile(script.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read script: %w", err)
+		}
+
+		changes = append(changes, plan.Change{
+			Kind:       plan.KindScript,
+			Name:       filepath.Base(script.Path),
+			Action:     plan.ActionRun,
+			Stage:      script.Stage.OrDefault(),
+			Path:       script.Path,
+			Before:     plan.Hash(data),
+			Privileged: script.Privileged,
+		})
+	}
+	return changes, nil
+}
+
+func planServices(ctx context.Context, cfg *core.ConfigFile, program *vm.Program, sealer *secretSealer) ([]plan.Change, error) {
+	engine := generator.NewEngine(cfg)
+	manager := services.Detect()
+
+	var changes []plan.Change
+	for _, svc := range cfg.Services {
+		enabled, err := evalCompiledExpr(program, map[string]any{
+			"tags": svc.Tags,
+			"name": svc.Name,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("expression evaluation failed for service %s: %w", svc.Name, err)
+		}
+		if !enabled {
+			continue
+		}
+
+		tmpl, err := services.Template(manager, svc)
+		if err != nil {
+			return nil, fmt.Errorf("service %s: %w", svc.Name, err)
+		}
+
+		change, err := planFile(ctx, engine, tmpl, sealer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render service %s: %w", svc.Name, err)
+		}
+		if change == nil {
+			if !svc.ShouldStart() || manager.State(ctx, svc.Name) == "active" {
+				continue
+			}
+			change = &plan.Change{Name: tmpl.Name, Action: plan.ActionStart, Path: tmpl.Output}
+		}
+		change.Kind = plan.KindService
+		change.Stage = svc.Stage.OrDefault()
+		change.Enable = svc.ShouldEnable()
+		change.Start = svc.ShouldStart()
+		changes = append(changes, *change)
+	}
+	return changes, nil
+}
+
+// planFile renders tmpl and returns the change that writes it, or nil when
+// the output on disk is current. Output that reads secrets is sealed.
+func planFile(ctx context.Context, engine *generator.Engine, tmpl core.Template, sealer *secretSealer) (*plan.Change, error) {
+	result, err := engine.Check(ctx, tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	change := &plan.Change{
+		Name:    tmpl.Name,
+		Path:    tmpl.Output,
+		Perm:    tmpl.Permissions,
+		Owner:   tmpl.Owner,
+		Group:   tmpl.Group,
+		Content: string(result.Rendered),
+	}
+	if change.Perm == "" {
+		change.Perm = "0644"
+	}
+
+	switch result.Status {
+	case generator.StatusCurrent:
+		return nil, nil
+	case generator.StatusMissing:
+		change.Action = plan.ActionCreate
+	default:
+		change.Action = plan.ActionUpdate
+		change.Before = plan.Hash(result.Existing)
+	}
+
+	if err := sealer.seal(ctx, engine, tmpl, change); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// secretSealer encrypts the content of changes whose template reads vault or
+// secret:// variables to the age recipients, so the plan file does not hold
+// secrets in plaintext.
+type secretSealer struct {
+	age        core.Age
+	plaintext  bool // --plaintext-secrets
+	recipients []age.Recipient
+}
+
+func (s *secretSealer) seal(ctx context.Context, engine *generator.Engine, tmpl core.Template, change *plan.Change) error {
+	secret, err := engine.ReadsSecrets(ctx, tmpl)
+	if err != nil {
+		return fmt.Errorf("template %s: %w", tmpl.Name, err)
+	}
+	if !secret || s.plaintext {
+		return nil
+	}
+
+	if s.recipients == nil {
+		keys, err := s.age.RecipientsFor(nil)
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return ValidationError(printer.WithTitle("Template reads secrets",
+				fmt.Errorf("template %s reads vault or secret:// variables and would be stored in the plan in plaintext", tmpl.Name),
+				"configure age.recipients to encrypt it in the plan",
+				"or pass --plaintext-secrets to store it unencrypted",
+			))
+		}
+		if s.recipients, err = fcrypt.LoadPublicKeys(keys); err != nil {
+			return fmt.Errorf("failed to load public keys: %w", err)
+		}
+	}
+	return change.Seal(s.recipients)
+}
+
+// printPlan prints a table of the plan's changes.
+func printPlan(p *plan.Plan) {
+	if len(p.Changes) == 0 {
+		fmt.Println("No changes")
+		return
+	}
+
+	rows := make([][]string, 0, len(p.Changes))
+	for _, c := range p.Changes {
+		detail := displayPath(c.Path)
+		switch {
+		case c.Kind == plan.KindPackage && c.Cask:
+			detail = "cask"
+		case c.Kind == plan.KindPackage:
+			detail = "formula"
+		case c.Privileged:
+			detail += " (privileged)"
+		}
+		rows = append(rows, []string{string(c.Stage), string(c.Kind), c.Name, string(c.Action), detail})
+	}
+
+	printer.New(os.Stdout).Table(
+		fmt.Sprintf("Plan (%d changes):", len(p.Changes)),
+		[]string{"STAGE", "KIND", "NAME", "ACTION", "DETAILS"},
+		rows,
+	)
+}
@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/generator"
+	"github.com/hay-kot/mmdot/pkgs/fcrypt"
+)
+
+func TestPlanFile_SealsSecrets(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir := t.TempDir()
+
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(dir, "key.txt")
+	if err := os.WriteFile(keyPath, []byte(id.String()+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var vault bytes.Buffer
+	if err := fcrypt.EncryptReader(strings.NewReader("token: s3cret\n"), &vault, []age.Recipient{id.Recipient()}); err != nil {
+		t.Fatal(err)
+	}
+	vaultPath := filepath.Join(dir, "vault.yml.age")
+	if err := os.WriteFile(vaultPath, vault.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &core.ConfigFile{
+		Age: core.Age{IdentityFile: keyPath},
+		Variables: core.Variables{
+			VarFiles: []core.VarFile{{Path: vaultPath, IsVault: true}},
+			Vars:     map[string]any{"name": "me"},
+		},
+	}
+	secret := core.Template{Name: "secret", Template: "{{ .token }}", Output: filepath.Join(dir, "secret")}
+	plain := core.Template{Name: "plain", Template: "{{ .name }}", Output: filepath.Join(dir, "plain")}
+	engine := generator.NewEngine(cfg)
+	ctx := context.Background()
+
+	// No recipients: refused unless plaintext is allowed
+	if _, err := planFile(ctx, engine, secret, &secretSealer{age: cfg.Age}); ExitCodeOf(err) != int(ExitValidation) {
+		t.Errorf("planFile() without recipients error = %v, want a validation error", err)
+	}
+	change, err := planFile(ctx, engine, secret, &secretSealer{age: cfg.Age, plaintext: true})
+	if err != nil || change.Encrypted || change.Content != "s3cret" {
+		t.Errorf("planFile() with plaintext = %+v, %v", change, err)
+	}
+
+	cfg.Age.Recipients = []string{id.Recipient().String()}
+	sealer := &secretSealer{age: cfg.Age}
+
+	change, err = planFile(ctx, engine, plain, sealer)
+	if err != nil || change.Encrypted || change.Content != "me" {
+		t.Errorf("planFile(plain) = %+v, %v", change, err)
+	}
+
+	change, err = planFile(ctx, engine, secret, sealer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !change.Encrypted || strings.Contains(change.Content, "s3cret") {
+		t.Fatalf("planFile(secret) stored plaintext: %+v", change)
+	}
+	if err := change.Open(id); err != nil || change.Content != "s3cret" {
+		t.Errorf("Open() = %q, %v", change.Content, err)
+	}
+}
@@ -0,0 +1,136 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/repos"
+	"github.com/hay-kot/mmdot/pkgs/printer"
+	"github.com/hay-kot/mmdot/pkgs/styles"
+	"github.com/urfave/cli/v3"
+)
+
+type ReposCmd struct {
+	coreFlags *core.Flags
+	flags     struct {
+		Jobs int
+	}
+}
+
+func NewReposCmd(coreFlags *core.Flags) *ReposCmd {
+	return &ReposCmd{coreFlags: coreFlags}
+}
+
+func (rc *ReposCmd) Register(app *cli.Command) *cli.Command {
+	cmd := &cli.Command{
+		Name:  "repos",
+		Usage: "clone and update the git repositories declared in the config",
+		Description: `Repositories declared under 'repos:' are cloned when missing. Existing
+repositories are fast-forwarded when on their branch, or checked out at their
+revision. Repositories on another branch or with a detached HEAD are skipped,
+so local work is never modified.`,
+		Commands: []*cli.Command{
+			{
+				Name:      "sync",
+				Usage:     "clone missing repositories and fast-forward existing ones",
+				ArgsUsage: "[path...]",
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:        "jobs",
+						Aliases:     []string{"j"},
+						Usage:       "number of repositories synced at once",
+						Value:       4,
+						Destination: &rc.flags.Jobs,
+					},
+				},
+				Action: rc.sync,
+			},
+		},
+	}
+
+	app.Commands = append(app.Commands, cmd)
+	return app
+}
+
+func (rc *ReposCmd) sync(ctx context.Context, c *cli.Command) error {
+	cfg, err := loadConfig(rc.coreFlags)
+	if err != nil {
+		return err
+	}
+
+	selected := cfg.Repos
+	if paths := c.Args().Slice(); len(paths) > 0 {
+		selected = nil
+		for _, path := range paths {
+			abs, err := core.PathResolver{}.Resolve(path)
+			if err != nil {
+				return err
+			}
+			found := false
+			for _, r := range cfg.Repos {
+				if r.Path == abs {
+					selected = append(selected, r)
+					found = true
+				}
+			}
+			if !found {
+				return fmt.Errorf("repo %q not found", path)
+			}
+		}
+	}
+
+	if len(selected) == 0 {
+		fmt.Println("No repos configured")
+		return nil
+	}
+
+	results := repos.New().SyncAll(ctx, selected, rc.flags.Jobs)
+
+	failed := 0
+	rows := make([][]string, 0, len(results))
+	for _, res := range results {
+		ref := res.Repo.Branch
+		if res.Repo.Revision != "" {
+			ref = res.Repo.Revision
+		}
+
+		status := string(res.Action)
+		var detail string
+		switch res.Action {
+		case repos.Failed:
+			failed++
+			status, detail = styles.Error(status), res.Err.Error()
+		case repos.Skipped:
+			status, detail = styles.Error(status), res.Reason
+		case repos.Updated:
+			detail = shortCommit(res.From) + " => " + shortCommit(res.To)
+		default:
+			detail = shortCommit(res.To)
+		}
+
+		rows = append(rows, []string{displayPath(res.Repo.Path), ref, status, detail})
+	}
+
+	printer.New(os.Stdout).Table("Repositories:", []string{"PATH", "REF", "STATUS", "DETAILS"}, rows)
+
+	if failed > 0 {
+		return PartialError(fmt.Errorf("%d of %d repos failed to sync", failed, len(results)))
+	}
+	return nil
+}
+
+// displayPath abbreviates the home directory in path to ~.
+func displayPath(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	if rest, ok := strings.CutPrefix(path, home+string(filepath.Separator)); ok {
+		return filepath.Join("~", rest)
+	}
+	return path
+}
@@ -2,12 +2,19 @@ package commands
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
+	"time"
 
-	"github.com/charmbracelet/huh"
 	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/metrics"
+	"github.com/hay-kot/mmdot/internal/notify"
+	"github.com/hay-kot/mmdot/internal/profile"
+	"github.com/hay-kot/mmdot/internal/tui"
+	"github.com/hay-kot/mmdot/pkgs/atomicwrite"
 	"github.com/rs/zerolog/log"
 	"github.com/urfave/cli/v3"
 	"golang.org/x/term"
@@ -16,14 +23,31 @@ import (
 type RunCmd struct {
 	coreFlags *core.Flags
 	flags     struct {
-		Types  []string
-		List   bool
-		Macros bool
+		Types             []string
+		List              bool
+		Macros            bool
+		Tags              []string
+		ExcludeTags       []string
+		Interactive       bool
+		NonInteractive    bool
+		PreviewLines      int
+		SkipUndecryptable bool
+		NoPrivileged      bool
+		KeepGoing         bool
+		Force             bool
+		SummaryFile       string
+		Only              []string
+		Skip              []string
+		Since             string
+		ProfileRun        bool
+		ProfileCPU        string
+		ProfileTrace      string
 	}
-	expr string
+	expr  string
+	since []string // items affected since --since, set before run
 }
 
-func NewScriptsCmd(coreFlags *core.Flags) *RunCmd {
+func NewRunCmd(coreFlags *core.Flags) *RunCmd {
 	return &RunCmd{
 		coreFlags: coreFlags,
 	}
@@ -38,7 +62,8 @@ func (sc *RunCmd) Register(app *cli.Command) *cli.Command {
  Items can be filtered using expressions or selected interactively.
 
  Examples:
-	 mmdot run                                    # Interactive selection
+	 mmdot run                                    # Interactive selection (or run.default_expr)
+	 mmdot run --interactive                      # Interactive selection, ignoring run.default_expr
 	 mmdot run "true"                             # Run all templates and scripts
 	 mmdot run +env                               # Run items tagged with 'env'
 	 mmdot run +work +deploy                      # Run items tagged with both 'work' and 'deploy'
@@ -49,6 +74,8 @@ func (sc *RunCmd) Register(app *cli.Command) *cli.Command {
 	 mmdot run --type template                    # Generate all templates
 	 mmdot run --type script +deploy !test        # Run scripts tagged with 'deploy' but NOT 'test'
 	 mmdot run --list +prod                       # List items without executing
+	 mmdot run --tags work,dev --exclude-tags brew # Tag flags instead of an expression
+	 mmdot run --only zshrc,starship --skip brew-bundle # Select items by name
 
  Expression syntax:
 	 - +tag: Include items with this tag (converted to '"tag" in tags')
@@ -56,16 +83,91 @@ func (sc *RunCmd) Register(app *cli.Command) *cli.Command {
 	 - @macro: Expand a macro defined in your config
 	 - Multiple shortcuts are combined with AND logic
 
+ Tag flags:
+	 --tags and --exclude-tags are an alternative to writing expressions. Every tag
+	 passed to --tags must be present and none passed to --exclude-tags may be. When
+	 combined with an expression, both must match.
+
+ Interactive selection:
+	 Type to fuzzy filter by name, or '#tag' to filter by tag. Tab toggles an item,
+	 ctrl+a toggles every visible item (e.g. '#shell' then ctrl+a selects all shell
+	 items), and enter runs the selection. The side pane previews the highlighted
+	 template, or a script's path, shell, stage, tags, and first lines (40 unless
+	 --preview-lines says otherwise). ctrl+o shows the preview full screen, where
+	 up/down scroll it, so a similarly named script can be checked before it runs.
+
+ Changed items:
+	 --since <git-ref> only runs the templates, scripts, and services affected by
+	 commits and uncommitted changes since the ref: their config entry changed,
+	 or a file they read did (template or script file, var files). Templates
+	 and services run when variables change, and templates calling brewConfig
+	 when brews change. e.g. after a pull: mmdot run --since ORIG_HEAD
+
+ Name selection:
+	 --only and --skip select items by name (template name, script basename, or
+	 service name) and accept globs such as 'zsh*'. Script extensions are
+	 optional. An item runs when it matches any --only pattern and no --skip
+	 pattern. Patterns that match nothing are rejected.
+
  Expression variables:
 	 - name: Item name (template name or script basename)
 	 - path: Full path (scripts only)
-	 - tags: Array of tags`,
+	 - tags: Array of tags
+
+ Expression functions:
+	 - installed("name"): Homebrew formula or cask is installed (uses the brew cache)
+	 - command("name"): executable is on PATH
+	 e.g. mmdot run '!bootstrap and installed("tmux")'
+
+ Ordering:
+	 Items run in stages: every 'pre' item, then 'main' (the default), then 'post'.
+	 Within a stage templates run before scripts, then services, unless 'run.order'
+	 says otherwise.
+
+ Prompted variables:
+	 Variables listed under 'variables.prompts' are asked for before templates render
+	 when no var or var file sets them. Answers are cached per machine in the state
+	 directory (passwords are asked every run). Runs without a terminal skip prompts.
+
+ Privileged scripts:
+	 Scripts with 'privileged: true' run through 'sudo -E' (or doas when sudo is not
+	 installed). mmdot asks for your password once before the first script runs.
+	 Use --no-privileged to skip them on machines where you lack admin rights.
+
+ Profiling:
+	 --profile-run prints how long config loading, each var file decryption,
+	 template render, script, and hook took once the run finishes. --profile-cpu
+	 and --profile-trace additionally write pprof and runtime trace files.
+
+ Hooks:
+	 Commands in 'run.before' execute before any item and 'run.after' once all items
+	 succeed. 'run.after_failure' executes instead when the run or a before hook
+	 fails, with the error in $MMDOT_RUN_ERROR. Hooks use 'exec.shell' and run from the config directory.
+
+ CI:
+	 With the global --ci flag (or MMDOT_CI=true) interactive selection and prompts
+	 are disabled, so an expression, tag, or name flag (or run.default_expr) is
+	 required. Each item's output is wrapped in ::group:: markers that GitHub
+	 Actions folds, --keep-going is enabled, and the summary is written to
+	 mmdot-summary.json unless --summary-file says otherwise. Add the global
+	 --timeout flag (or MMDOT_TIMEOUT) so a hung script fails the job instead of
+	 running until the CI runner gives up.
+
+ Non-interactive:
+	 --non-interactive never asks for input, for runs from git hooks and cron: an
+	 expression, tag, name, or --since flag (or run.default_expr) is required,
+	 prompted variables without a value are left unset, and privileged scripts are
+	 skipped since sudo would ask for a password. 'mmdot hook install
+	 --post-merge' runs 'mmdot run --since ORIG_HEAD --non-interactive' after
+	 every pull.
+
+ ` + ExitCodesHelp,
 		Flags: []cli.Flag{
 			&cli.StringSliceFlag{
 				Name:        "type",
-				Usage:       "filter by type: 'template' or 'script' (default: both)",
+				Usage:       "filter by type: 'template', 'script', or 'service' (default: all)",
 				Destination: &sc.flags.Types,
-				Value:       []string{RunnerTypeTemplate, RunnerTypeScript},
+				Value:       slices.Clone(RunnerTypes),
 			},
 			&cli.BoolFlag{
 				Name:        "list",
@@ -73,6 +175,92 @@ func (sc *RunCmd) Register(app *cli.Command) *cli.Command {
 				Usage:       "list matching items without executing them",
 				Destination: &sc.flags.List,
 			},
+			&cli.StringSliceFlag{
+				Name:        "tags",
+				Aliases:     []string{"t"},
+				Usage:       "only run items that have all of these tags",
+				Destination: &sc.flags.Tags,
+			},
+			&cli.StringSliceFlag{
+				Name:        "exclude-tags",
+				Aliases:     []string{"x"},
+				Usage:       "skip items that have any of these tags",
+				Destination: &sc.flags.ExcludeTags,
+			},
+			&cli.StringSliceFlag{
+				Name:        "only",
+				Usage:       "only run items whose name matches one of these globs",
+				Destination: &sc.flags.Only,
+			},
+			&cli.StringSliceFlag{
+				Name:        "skip",
+				Usage:       "skip items whose name matches one of these globs",
+				Destination: &sc.flags.Skip,
+			},
+			&cli.StringFlag{
+				Name:        "since",
+				Usage:       "only run items affected by changes since the git `REF`, e.g. ORIG_HEAD after a pull",
+				Destination: &sc.flags.Since,
+			},
+			&cli.BoolFlag{
+				Name:        "interactive",
+				Aliases:     []string{"i"},
+				Usage:       "always select items interactively, ignoring run.default_expr",
+				Destination: &sc.flags.Interactive,
+			},
+			&cli.IntFlag{
+				Name:        "preview-lines",
+				Usage:       "lines of each script previewed during interactive selection",
+				Value:       DefaultPreviewLines,
+				Destination: &sc.flags.PreviewLines,
+			},
+			&cli.BoolFlag{
+				Name:        "non-interactive",
+				Usage:       "never ask for input: no interactive selection or prompts, and privileged scripts are skipped",
+				Destination: &sc.flags.NonInteractive,
+			},
+			&cli.BoolFlag{
+				Name:        "skip-undecryptable",
+				Usage:       "skip vault var files that cannot be decrypted instead of failing",
+				Destination: &sc.flags.SkipUndecryptable,
+			},
+			&cli.BoolFlag{
+				Name:        "no-privileged",
+				Usage:       "skip scripts marked privileged instead of running them with sudo or doas",
+				Destination: &sc.flags.NoPrivileged,
+			},
+			&cli.BoolFlag{
+				Name:        "keep-going",
+				Aliases:     []string{"k"},
+				Usage:       "continue with the next item when one fails and report every failure at the end",
+				Destination: &sc.flags.KeepGoing,
+			},
+			&cli.BoolFlag{
+				Name:        "force",
+				Aliases:     []string{"f"},
+				Usage:       "overwrite the outputs of templates marked no_clobber and run scripts within their min_interval",
+				Destination: &sc.flags.Force,
+			},
+			&cli.StringFlag{
+				Name:        "summary-file",
+				Usage:       "write a JSON summary of the run to `FILE` (default with --ci: mmdot-summary.json)",
+				Destination: &sc.flags.SummaryFile,
+			},
+			&cli.BoolFlag{
+				Name:        "profile-run",
+				Usage:       "print a breakdown of time spent loading config, decrypting, rendering, and running scripts",
+				Destination: &sc.flags.ProfileRun,
+			},
+			&cli.StringFlag{
+				Name:        "profile-cpu",
+				Usage:       "write a pprof CPU profile of the run to `FILE`",
+				Destination: &sc.flags.ProfileCPU,
+			},
+			&cli.StringFlag{
+				Name:        "profile-trace",
+				Usage:       "write a runtime execution trace of the run to `FILE` (view with 'go tool trace')",
+				Destination: &sc.flags.ProfileTrace,
+			},
 			&cli.BoolFlag{
 				Name:        "macros",
 				Usage:       "enable macro (@macro) and tag shortcut (+tag, !tag) expansion (default: true)",
@@ -81,17 +269,70 @@ func (sc *RunCmd) Register(app *cli.Command) *cli.Command {
 			},
 		},
 		Action: func(ctx context.Context, c *cli.Command) error {
-			cfg, err := core.SetupEnv(sc.coreFlags.ConfigFilePath)
+			stop, err := sc.startProfiles()
+			if err != nil {
+				return err
+			}
+			defer stop()
+
+			var rec *profile.Recorder
+			if sc.flags.ProfileRun {
+				rec = profile.New()
+				ctx = profile.WithRecorder(ctx, rec)
+				defer sc.printProfile(rec)
+			}
+
+			done := profile.Track(ctx, "config", "load")
+			cfg, err := loadConfig(sc.coreFlags)
+			done()
 			if err != nil {
 				return err
 			}
 
+			// Compared before flags below change the loaded config
+			if sc.flags.Since != "" {
+				sc.since, err = changedSince(ctx, &cfg, sc.flags.Since)
+				if err != nil {
+					return err
+				}
+			}
+
+			if sc.flags.SkipUndecryptable {
+				for i := range cfg.Variables.VarFiles {
+					cfg.Variables.VarFiles[i].Optional = true
+				}
+			}
+
 			sc.expr = strings.Join(c.Args().Slice(), " ")
 
+			if sc.flags.PreviewLines < 0 {
+				return fmt.Errorf("--preview-lines must not be negative")
+			}
+
+			if sc.flags.NonInteractive {
+				if sc.flags.Interactive {
+					return fmt.Errorf("--interactive and --non-interactive cannot be used together")
+				}
+				sc.flags.NoPrivileged = true
+			}
+
+			if core.CI {
+				sc.flags.KeepGoing = true
+				if sc.flags.SummaryFile == "" {
+					sc.flags.SummaryFile = "mmdot-summary.json"
+				}
+			}
+
 			log.Debug().
 				Bool("list", sc.flags.List).
 				Strs("types", sc.flags.Types).
 				Bool("macros", sc.flags.Macros).
+				Strs("tags", sc.flags.Tags).
+				Strs("exclude-tags", sc.flags.ExcludeTags).
+				Strs("only", sc.flags.Only).
+				Strs("skip", sc.flags.Skip).
+				Str("since", sc.flags.Since).
+				Strs("changed", sc.since).
 				Str("expr", sc.expr).
 				Msg("run cmd")
 
@@ -116,41 +357,112 @@ func (sc *RunCmd) run(ctx context.Context, cfg core.ConfigFile) error {
 		terminalWidth = 80
 	}
 
-	// Order matters, they will be executed in the order that they are set here.
-	runners := []Runner{
-		NewTemplateRunner(&cfg),
-		NewScriptRunner(&cfg),
+	order, err := runnerOrder(cfg.Run)
+	if err != nil {
+		return err
+	}
+
+	// Order matters, within each stage runners are executed in the configured order.
+	runners := make([]Runner, 0, len(order))
+	for _, rt := range order {
+		switch rt {
+		case RunnerTypeTemplate:
+			runners = append(runners, NewTemplateRunner(&cfg))
+		case RunnerTypeScript:
+			sr := NewScriptRunner(&cfg)
+			sr.PreviewLines = sc.flags.PreviewLines
+			runners = append(runners, sr)
+		case RunnerTypeService:
+			runners = append(runners, NewServiceRunner(&cfg))
+		}
 	}
 
 	// Determine execution mode: interactive vs expression-based
 	// Skip interactive mode if --list flag is set
-	useInteractiveMode := sc.expr == "" && !sc.flags.List
+	tagFilter := tagFilterExpr(sc.flags.Tags, sc.flags.ExcludeTags)
+
+	only, skip := cleanPatterns(sc.flags.Only), cleanPatterns(sc.flags.Skip)
+	names := itemNames(&cfg, types)
+	if err := checkPatterns("--only", only, names); err != nil {
+		return err
+	}
+	if err := checkPatterns("--skip", skip, names); err != nil {
+		return err
+	}
+	nameFilter := nameFilterExpr(only, skip)
+
+	sinceFilter := ""
+	if sc.flags.Since != "" {
+		if len(sc.since) == 0 {
+			fmt.Printf("Nothing changed since %s\n", sc.flags.Since)
+			return nil
+		}
+		sinceFilter = sinceFilterExpr(sc.since)
+	}
+	hasFilter := tagFilter != "" || nameFilter != "" || sinceFilter != ""
+
+	// Fall back to the configured default expression unless the user asked
+	// for the interactive form
+	if sc.expr == "" && !hasFilter && !sc.flags.Interactive && cfg.Run.DefaultExpr != "" {
+		sc.expr = cfg.Run.DefaultExpr
+		log.Debug().Str("expr", sc.expr).Msg("using run.default_expr")
+	}
+
+	useInteractiveMode := sc.flags.Interactive || (sc.expr == "" && !hasFilter && !sc.flags.List)
+
+	if useInteractiveMode && core.CI {
+		return fmt.Errorf("interactive selection is disabled with --ci, pass an expression or a tag or name flag")
+	}
+	if useInteractiveMode && sc.flags.NonInteractive {
+		return fmt.Errorf("interactive selection is disabled with --non-interactive, pass an expression or a tag or name flag")
+	}
 
 	if useInteractiveMode {
-		// Interactive selection mode
-		var fields []huh.Field
+		// Interactive selection mode: offer every runner's items in one list,
+		// then hand each runner back the indexes of its own items
+		var items []tui.SelectItem
+		offsets := make([]int, len(runners))
+		for i, r := range runners {
+			offsets[i] = len(items)
+			items = append(items, r.Items(ctx)...)
+		}
 
-		for _, r := range runners {
-			g := r.Field(ctx)
-			if g != nil {
-				fields = append(fields, g)
-			}
+		if len(items) == 0 {
+			fmt.Println("No templates, scripts, or services available")
+			return nil
+		}
 
+		chosen, err := tui.Select(ctx, "Select items to run", items)
+		if err != nil {
+			return err
 		}
 
-		if len(fields) > 0 {
-			form := huh.NewForm(huh.NewGroup(fields...))
-			if err := form.Run(); err != nil {
-				return err
+		for i, r := range runners {
+			end := len(items)
+			if i+1 < len(runners) {
+				end = offsets[i+1]
 			}
-		} else {
-			fmt.Println("No templates or scripts available")
-			return nil
+
+			var own []int
+			for _, idx := range chosen {
+				if idx >= offsets[i] && idx < end {
+					own = append(own, idx-offsets[i])
+				}
+			}
+			r.Select(own)
+		}
+	}
+
+	// Prompted variables are only needed when something will be rendered
+	renders := slices.Contains(types, RunnerTypeTemplate) || slices.Contains(types, RunnerTypeService)
+	if !sc.flags.List && renders {
+		if err := askPrompts(ctx, &cfg, !sc.flags.NonInteractive); err != nil {
+			return err
 		}
 	}
 
 	// Compile expression once for all runners
-	program, err := compileExpr(sc.expr, cfg.Macros, sc.flags.Macros)
+	program, err := compileExpr(ctx, sc.expr, cfg.Macros, sc.flags.Macros, tagFilter, nameFilter, sinceFilter)
 	if err != nil {
 		return fmt.Errorf("invalid expression: %w", err)
 	}
@@ -163,14 +475,150 @@ func (sc *RunCmd) run(ctx context.Context, cfg core.ConfigFile) error {
 		Macros:        cfg.Macros,
 		List:          sc.flags.List,
 		Program:       program,
+		NoPrivileged:  sc.flags.NoPrivileged,
+		KeepGoing:     sc.flags.KeepGoing,
+		Force:         sc.flags.Force,
 	}
 
-	for _, r := range runners {
-		// Execute templates first (they may generate files that scripts need)
-		if err := r.Execute(ctx, executeArgs); err != nil {
-			return err
+	// List mode prints every matching item once, regardless of stage and
+	// without running hooks
+	if sc.flags.List {
+		for _, r := range runners {
+			if err := r.Execute(ctx, executeArgs); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	unlock, err := runLock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	start := time.Now()
+	summary := &RunSummary{}
+	executeArgs.Summary = summary
+
+	err = sc.executeWithHooks(ctx, &cfg, runners, executeArgs)
+	endCIGroup()
+
+	event := notify.Event{
+		Command:      "run",
+		Success:      err == nil,
+		Templates:    summary.Templates,
+		Scripts:      summary.Scripts,
+		Services:     summary.Services,
+		Skipped:      len(summary.Skipped),
+		SkippedItems: summary.Skipped,
+		Failed:       summary.Failed,
+		Duration:     time.Since(start),
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	notify.Send(ctx, cfg.Notify, event)
+	// recorded even when --timeout cancelled the run, that is worth alerting on
+	metrics.WriteRun(context.WithoutCancel(ctx), cfg.Metrics, event, time.Now())
+
+	if sc.flags.SummaryFile != "" {
+		if writeErr := writeSummary(sc.flags.SummaryFile, event); writeErr != nil {
+			log.Warn().Err(writeErr).Str("path", sc.flags.SummaryFile).Msg("failed to write run summary")
+		}
+	}
+
+	return err
+}
+
+// writeSummary writes the run summary as JSON to path.
+func writeSummary(path string, event notify.Event) error {
+	data, err := json.MarshalIndent(event, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicwrite.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// executeWithHooks wraps stage execution with the configured before, after,
+// and after_failure hooks. A failing before hook fails the run, so
+// after_failure runs for it too.
+func (sc *RunCmd) executeWithHooks(ctx context.Context, cfg *core.ConfigFile, runners []Runner, args ExecuteArgs) error {
+	err := runHooks(ctx, cfg, "before", cfg.Run.Before, args.TerminalWidth)
+	if err == nil {
+		err = sc.executeStages(ctx, runners, args)
+	}
+	if err != nil {
+		if hookErr := runHooks(ctx, cfg, "after_failure", cfg.Run.AfterFailure, args.TerminalWidth, HookEnvRunError+"="+err.Error()); hookErr != nil {
+			log.Error().Err(hookErr).Msg("after_failure hook failed")
 		}
+		return err
+	}
+
+	if err := runHooks(ctx, cfg, "after", cfg.Run.After, args.TerminalWidth); err != nil {
+		return PartialError(err) // every item succeeded, only the after hook failed
 	}
 
 	return nil
 }
+
+// executeStages runs every runner once per stage in [core.Stages] order. With
+// args.KeepGoing, items that failed are reported once every stage ran.
+func (sc *RunCmd) executeStages(ctx context.Context, runners []Runner, args ExecuteArgs) error {
+	for _, stage := range core.Stages {
+		args.Stage = stage
+		for _, r := range runners {
+			if err := r.Execute(ctx, args); err != nil {
+				return err
+			}
+		}
+	}
+
+	return args.Summary.Err()
+}
+
+// startProfiles starts the CPU profile and execution trace requested by flags
+// and returns a function that stops them.
+func (sc *RunCmd) startProfiles() (func(), error) {
+	var stops []func() error
+
+	stop := func() {
+		for _, s := range stops {
+			if err := s(); err != nil {
+				log.Warn().Err(err).Msg("failed to write profile")
+			}
+		}
+	}
+
+	if sc.flags.ProfileCPU != "" {
+		s, err := profile.StartCPUProfile(sc.flags.ProfileCPU)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start cpu profile: %w", err)
+		}
+		stops = append(stops, s)
+	}
+
+	if sc.flags.ProfileTrace != "" {
+		s, err := profile.StartTrace(sc.flags.ProfileTrace)
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("failed to start trace: %w", err)
+		}
+		stops = append(stops, s)
+	}
+
+	return stop, nil
+}
+
+func (sc *RunCmd) printProfile(rec *profile.Recorder) {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		width = 80
+	}
+
+	fmt.Println(createStyledHeader("PROFILE", "timings", width))
+	if err := rec.WriteTable(os.Stdout); err != nil {
+		log.Warn().Err(err).Msg("failed to print profile")
+	}
+	fmt.Println()
+}
@@ -0,0 +1,197 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/schedule"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+type ScheduleCmd struct {
+	coreFlags *core.Flags
+	flags     struct {
+		Backend string
+		Every   time.Duration
+		Expr    string
+		LogFile string
+	}
+}
+
+func NewScheduleCmd(coreFlags *core.Flags) *ScheduleCmd {
+	return &ScheduleCmd{coreFlags: coreFlags}
+}
+
+func (sc *ScheduleCmd) Register(app *cli.Command) *cli.Command {
+	backendFlag := &cli.StringFlag{
+		Name:        "backend",
+		Usage:       "scheduler to use: auto, launchd, systemd, or cron",
+		Value:       string(schedule.BackendAuto),
+		Destination: &sc.flags.Backend,
+	}
+
+	cmd := &cli.Command{
+		Name:  "schedule",
+		Usage: "manage periodic non-interactive runs",
+		Commands: []*cli.Command{
+			{
+				Name:  "install",
+				Usage: "install a launchd agent, systemd user timer, or cron entry that runs mmdot periodically",
+				Description: `Installs a scheduled 'mmdot run' for the current config file.
+
+ The scheduler is chosen automatically: launchd on macOS, a systemd user timer
+ when systemctl is available, and crontab otherwise. Installing again replaces
+ the existing schedule.
+
+ Scheduled runs pass --non-interactive: prompted variables without a value are
+ left unset and privileged scripts are skipped.
+
+ Output from launchd and cron runs is appended to the log file; systemd runs log
+ to the user journal (journalctl --user -u mmdot-run).
+
+ Examples:
+	 mmdot schedule install --every 6h --expr '+auto'
+	 mmdot schedule install --every 30m --backend cron`,
+				Flags: []cli.Flag{
+					backendFlag,
+					&cli.DurationFlag{
+						Name:        "every",
+						Usage:       "interval between runs (e.g. 30m, 6h, 24h)",
+						Value:       6 * time.Hour,
+						Destination: &sc.flags.Every,
+					},
+					&cli.StringFlag{
+						Name:        "expr",
+						Usage:       "run expression selecting items to execute",
+						Value:       "true",
+						Destination: &sc.flags.Expr,
+					},
+					&cli.StringFlag{
+						Name:        "log-file",
+						Usage:       "file receiving output of scheduled runs (default: platform log directory)",
+						Destination: &sc.flags.LogFile,
+					},
+				},
+				Action: sc.install,
+			},
+			{
+				Name:   "remove",
+				Usage:  "remove the installed schedule",
+				Flags:  []cli.Flag{backendFlag},
+				Action: sc.remove,
+			},
+			{
+				Name:   "status",
+				Usage:  "show whether a schedule is installed",
+				Flags:  []cli.Flag{backendFlag},
+				Action: sc.status,
+			},
+		},
+	}
+
+	app.Commands = append(app.Commands, cmd)
+	return app
+}
+
+func (sc *ScheduleCmd) install(ctx context.Context, c *cli.Command) error {
+	if strings.TrimSpace(sc.flags.Expr) == "" {
+		return fmt.Errorf("--expr must not be empty, pass 'true' to run every item")
+	}
+
+	backend, err := schedule.ParseBackend(sc.flags.Backend)
+	if err != nil {
+		return err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get mmdot executable path: %w", err)
+	}
+
+	configPath, err := filepath.Abs(sc.coreFlags.ConfigFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve config path: %w", err)
+	}
+	if _, err := os.Stat(configPath); err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+
+	logPath := sc.flags.LogFile
+	if logPath == "" {
+		logPath, err = schedule.DefaultLogPath()
+		if err != nil {
+			return err
+		}
+	}
+
+	command := []string{exe, "--config=" + configPath}
+	for _, overlay := range sc.coreFlags.ConfigOverlays() {
+		overlayPath, err := filepath.Abs(overlay)
+		if err != nil {
+			return fmt.Errorf("failed to resolve config path: %w", err)
+		}
+		command = append(command, "--config="+overlayPath)
+	}
+
+	spec := schedule.Spec{
+		Every:   sc.flags.Every,
+		Command: append(command, "run", "--non-interactive", sc.flags.Expr),
+		LogPath: logPath,
+	}
+
+	if err := schedule.Install(ctx, backend, spec); err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("backend", string(backend)).
+		Dur("every", spec.Every).
+		Str("expr", sc.flags.Expr).
+		Msg("Installed schedule successfully")
+	return nil
+}
+
+func (sc *ScheduleCmd) remove(ctx context.Context, c *cli.Command) error {
+	backend, err := schedule.ParseBackend(sc.flags.Backend)
+	if err != nil {
+		return err
+	}
+
+	if err := schedule.Remove(ctx, backend); err != nil {
+		return err
+	}
+
+	log.Info().Str("backend", string(backend)).Msg("Removed schedule")
+	return nil
+}
+
+func (sc *ScheduleCmd) status(ctx context.Context, c *cli.Command) error {
+	backend, err := schedule.ParseBackend(sc.flags.Backend)
+	if err != nil {
+		return err
+	}
+
+	st, err := schedule.GetStatus(ctx, backend)
+	if err != nil {
+		return err
+	}
+
+	if !st.Installed {
+		fmt.Printf("No schedule installed (%s)\n", backend)
+		return nil
+	}
+
+	fmt.Printf("Schedule installed (%s)\n", backend)
+	fmt.Printf("Location  %s\n", st.Location)
+	if st.Details != "" {
+		fmt.Println()
+		fmt.Println(st.Details)
+	}
+	return nil
+}
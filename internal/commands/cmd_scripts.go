@@ -0,0 +1,204 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/pkgs/printer"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+// shellcheckSeverities are the values of shellcheck --severity, most severe
+// first.
+var shellcheckSeverities = []string{"error", "warning", "info", "style"}
+
+// shellcheckShells are the shells shellcheck understands.
+var shellcheckShells = []string{"sh", "bash", "dash", "ksh", "busybox"}
+
+type ScriptsCmd struct {
+	coreFlags *core.Flags
+	flags     struct {
+		Severity string
+	}
+}
+
+func NewScriptsCmd(coreFlags *core.Flags) *ScriptsCmd {
+	return &ScriptsCmd{coreFlags: coreFlags}
+}
+
+func (sc *ScriptsCmd) Register(app *cli.Command) *cli.Command {
+	app.Commands = append(app.Commands, &cli.Command{
+		Name:  "scripts",
+		Usage: "check the scripts in exec.scripts",
+		Commands: []*cli.Command{
+			{
+				Name:  "lint",
+				Usage: "run shellcheck against every configured shell script",
+				Description: `Runs shellcheck, which must be installed, against each script in
+exec.scripts. The shell is taken from the script's shebang, or exec.shell for
+scripts without one. Scripts for shells shellcheck does not support, such as
+zsh, fish, or python, are skipped.
+
+Exits with code 5 when any finding at or above --severity is reported, so it
+can gate CI:
+
+  mmdot scripts lint --severity warning`,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "severity",
+						Usage:       "minimum severity to report: " + strings.Join(shellcheckSeverities, ", "),
+						Value:       "style",
+						Destination: &sc.flags.Severity,
+					},
+				},
+				Action: sc.lint,
+			},
+		},
+	})
+	return app
+}
+
+// shellcheckComment is a finding in shellcheck's json1 output.
+type shellcheckComment struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Level   string `json:"level"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (sc *ScriptsCmd) lint(ctx context.Context, c *cli.Command) error {
+	if !slices.Contains(shellcheckSeverities, sc.flags.Severity) {
+		return fmt.Errorf("invalid --severity %q, must be one of %s", sc.flags.Severity, strings.Join(shellcheckSeverities, ", "))
+	}
+
+	cfg, err := loadConfig(sc.coreFlags)
+	if err != nil {
+		return err
+	}
+	if len(cfg.Exec.Scripts) == 0 {
+		fmt.Println("No scripts configured")
+		return nil
+	}
+
+	if _, err := exec.LookPath("shellcheck"); err != nil {
+		return printer.WithTitle("shellcheck not found", err,
+			"install it with 'brew install shellcheck' or your package manager",
+		)
+	}
+
+	items := make([]printer.StatusListItem, 0, len(cfg.Exec.Scripts))
+	findings := 0
+	for _, script := range cfg.Exec.Scripts {
+		name := filepath.Base(script.Path)
+
+		shell, err := scriptShell(script.Path, hookShell(&cfg))
+		if err != nil {
+			items = append(items, printer.StatusListItem{Status: fmt.Sprintf("%s: %v", name, err)})
+			findings++
+			continue
+		}
+		if !slices.Contains(shellcheckShells, shell) {
+			log.Debug().Str("script", script.Path).Str("shell", shell).Msg("skipping script shellcheck does not support")
+			items = append(items, printer.StatusListItem{Ok: true, Status: fmt.Sprintf("%s skipped (%s)", name, shell)})
+			continue
+		}
+
+		comments, err := shellcheck(ctx, cfg.ConfigDir, script.Path, shell, sc.flags.Severity)
+		if err != nil {
+			return fmt.Errorf("shellcheck %s: %w", name, err)
+		}
+		if len(comments) == 0 {
+			items = append(items, printer.StatusListItem{Ok: true, Status: name})
+			continue
+		}
+		for _, comment := range comments {
+			items = append(items, printer.StatusListItem{Status: fmt.Sprintf("%s:%d:%d %s SC%d %s",
+				name, comment.Line, comment.Column, comment.Level, comment.Code, comment.Message)})
+		}
+		findings += len(comments)
+	}
+
+	printer.New(os.Stdout).StatusList("Scripts:", items)
+	if findings > 0 {
+		return ValidationError(fmt.Errorf("%d shellcheck finding(s) at severity %s or above", findings, sc.flags.Severity))
+	}
+	return nil
+}
+
+func shellcheck(ctx context.Context, dir, path, shell, severity string) ([]shellcheckComment, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "shellcheck", "--format=json1", "--shell="+shell, "--severity="+severity, path)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	// exit code 1 means findings were reported
+	var exitErr *exec.ExitError
+	if err := cmd.Run(); err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.New(msg)
+		}
+		return nil, err
+	}
+
+	var out struct {
+		Comments []shellcheckComment `json:"comments"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return nil, fmt.Errorf("failed to parse output: %w", err)
+	}
+	return out.Comments, nil
+}
+
+// scriptShell returns the name of the shell that runs the script at path:
+// the interpreter in its shebang, or defaultShell without one.
+func scriptShell(path, defaultShell string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	line, _ := bufio.NewReader(f).ReadString('\n')
+	return shebangShell(line, defaultShell), nil
+}
+
+// shebangShell returns the interpreter named by the shebang line, e.g. bash
+// for "#!/usr/bin/env bash", or the base name of defaultShell when line is
+// not a shebang.
+func shebangShell(line, defaultShell string) string {
+	interpreter, ok := strings.CutPrefix(strings.TrimSpace(line), "#!")
+	if !ok {
+		return filepath.Base(defaultShell)
+	}
+
+	fields := strings.Fields(interpreter)
+	if len(fields) == 0 {
+		return filepath.Base(defaultShell)
+	}
+	shell := filepath.Base(fields[0])
+	if shell == "env" {
+		// skip env options such as -S
+		shell = ""
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") {
+				shell = filepath.Base(field)
+				break
+			}
+		}
+	}
+	return shell
+}
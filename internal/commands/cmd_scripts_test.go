@@ -0,0 +1,27 @@
+package commands
+
+import "testing"
+
+func Test_shebangShell(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{line: "#!/bin/bash\n", want: "bash"},
+		{line: "#!/usr/bin/env bash\n", want: "bash"},
+		{line: "#!/usr/bin/env -S bash -e\n", want: "bash"},
+		{line: "#! /bin/sh -eu\n", want: "sh"},
+		{line: "#!/usr/bin/env python3\n", want: "python3"},
+		{line: "#!/bin/zsh\n", want: "zsh"},
+		{line: "echo no shebang\n", want: "dash"},
+		{line: "", want: "dash"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if got := shebangShell(tt.line, "/usr/bin/dash"); got != tt.want {
+				t.Errorf("shebangShell(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
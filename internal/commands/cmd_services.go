@@ -0,0 +1,203 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/generator"
+	"github.com/hay-kot/mmdot/internal/services"
+	"github.com/hay-kot/mmdot/pkgs/linediff"
+	"github.com/hay-kot/mmdot/pkgs/printer"
+	"github.com/urfave/cli/v3"
+)
+
+type ServicesCmd struct {
+	coreFlags *core.Flags
+}
+
+func NewServicesCmd(coreFlags *core.Flags) *ServicesCmd {
+	return &ServicesCmd{coreFlags: coreFlags}
+}
+
+func (sc *ServicesCmd) Register(app *cli.Command) *cli.Command {
+	cmd := &cli.Command{
+		Name:  "services",
+		Usage: "inspect systemd user units and launchd agents declared in the config",
+		Description: `Services declared under 'services:' are rendered to unit files, enabled, and
+started by 'mmdot run'. These subcommands inspect them without making changes.`,
+		Commands: []*cli.Command{
+			{
+				Name:      "status",
+				Usage:     "show whether each unit file is current and the service state",
+				ArgsUsage: "[service-name...]",
+				Action:    sc.status,
+			},
+			{
+				Name:      "diff",
+				Usage:     "show pending changes to unit files",
+				ArgsUsage: "[service-name...]",
+				Action:    sc.diff,
+			},
+			{
+				Name:      "validate",
+				Usage:     "render each unit file and check it with the service manager",
+				ArgsUsage: "[service-name...]",
+				Action:    sc.validate,
+			},
+		},
+	}
+
+	app.Commands = append(app.Commands, cmd)
+	return app
+}
+
+// selected returns the configured services, limited to names when provided.
+func (sc *ServicesCmd) selected(cfg core.ConfigFile, names []string) ([]core.Service, error) {
+	if len(names) == 0 {
+		return cfg.Services, nil
+	}
+
+	var out []core.Service
+	for _, name := range names {
+		idx := slices.IndexFunc(cfg.Services, func(s core.Service) bool { return s.Name == name })
+		if idx == -1 {
+			return nil, fmt.Errorf("service %q not found", name)
+		}
+		out = append(out, cfg.Services[idx])
+	}
+	return out, nil
+}
+
+func (sc *ServicesCmd) load(c *cli.Command) (*core.ConfigFile, *generator.Engine, services.Manager, []core.Service, error) {
+	cfg, err := loadConfig(sc.coreFlags)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	selected, err := sc.selected(cfg, c.Args().Slice())
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	return &cfg, generator.NewEngine(&cfg), services.Detect(), selected, nil
+}
+
+func (sc *ServicesCmd) status(ctx context.Context, c *cli.Command) error {
+	_, engine, manager, selected, err := sc.load(c)
+	if err != nil {
+		return err
+	}
+
+	if len(selected) == 0 {
+		fmt.Println("No services configured")
+		return nil
+	}
+
+	p := printer.New(os.Stdout)
+	items := make([]printer.StatusListItem, 0, len(selected))
+	for _, svc := range selected {
+		tmpl, err := services.Template(manager, svc)
+		if err != nil {
+			return err
+		}
+
+		result, err := engine.Check(ctx, tmpl)
+		if err != nil {
+			return fmt.Errorf("failed to render service %s: %w", svc.Name, err)
+		}
+
+		state := manager.State(ctx, svc.Name)
+		items = append(items, printer.StatusListItem{
+			Ok:     result.Status == generator.StatusCurrent && (!svc.ShouldStart() || state == "active" || state == "loaded"),
+			Status: fmt.Sprintf("%s: unit %s, %s", svc.Name, result.Status, state),
+		})
+	}
+
+	p.StatusList(fmt.Sprintf("Services (%s):", manager.Kind()), items)
+	return nil
+}
+
+func (sc *ServicesCmd) diff(ctx context.Context, c *cli.Command) error {
+	cfg, engine, manager, selected, err := sc.load(c)
+	if err != nil {
+		return err
+	}
+
+	changes := 0
+	for _, svc := range selected {
+		tmpl, err := services.Template(manager, svc)
+		if err != nil {
+			return err
+		}
+
+		result, err := engine.Check(ctx, tmpl)
+		if err != nil {
+			return fmt.Errorf("failed to render service %s: %w", svc.Name, err)
+		}
+		if result.Status == generator.StatusCurrent {
+			continue
+		}
+
+		changes++
+		printDiff(ctx, cfg, fmt.Sprintf("%s (%s)", tmpl.Output, result.Status), svc.Name+" (rendered)",
+			linediff.Diff(string(result.Existing), string(result.Rendered)))
+		fmt.Println()
+	}
+
+	if changes == 0 {
+		fmt.Println("All unit files are up to date")
+	}
+	return nil
+}
+
+func (sc *ServicesCmd) validate(ctx context.Context, c *cli.Command) error {
+	_, engine, manager, selected, err := sc.load(c)
+	if err != nil {
+		return err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "mmdot-services-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	failed := 0
+	items := make([]printer.StatusListItem, 0, len(selected))
+	for _, svc := range selected {
+		tmpl, err := services.Template(manager, svc)
+		if err != nil {
+			return err
+		}
+
+		// Render into a temp dir so validation never touches installed units
+		unitPath, err := manager.UnitPath(svc.Name)
+		if err != nil {
+			return err
+		}
+		tmpl.Output = filepath.Join(tmpDir, filepath.Base(unitPath))
+
+		item := printer.StatusListItem{Ok: true, Status: svc.Name}
+		if err := engine.RenderTemplate(ctx, tmpl); err != nil {
+			item.Ok, item.Status = false, fmt.Sprintf("%s: %v", svc.Name, err)
+		} else if err := manager.Verify(ctx, tmpl.Output); err != nil {
+			item.Ok, item.Status = false, fmt.Sprintf("%s: %v", svc.Name, err)
+		}
+
+		if !item.Ok {
+			failed++
+		}
+		items = append(items, item)
+	}
+
+	printer.New(os.Stdout).StatusList(fmt.Sprintf("Services (%s):", manager.Kind()), items)
+
+	if failed > 0 {
+		return ValidationError(fmt.Errorf("%d service(s) failed validation", failed))
+	}
+	return nil
+}
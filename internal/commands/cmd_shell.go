@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/shellplugins"
+	"github.com/hay-kot/mmdot/pkgs/printer"
+	"github.com/urfave/cli/v3"
+)
+
+type ShellCmd struct {
+	coreFlags *core.Flags
+}
+
+func NewShellCmd(coreFlags *core.Flags) *ShellCmd {
+	return &ShellCmd{coreFlags: coreFlags}
+}
+
+func (sc *ShellCmd) Register(app *cli.Command) *cli.Command {
+	cmd := &cli.Command{
+		Name:  "shell",
+		Usage: "clone the shell plugin manager and plugins at their pinned commits",
+		Description: `The plugin manager (antidote or fisher) and every plugin declared under
+'shell:' are cloned below the mmdot state directory and checked out at their
+pinned commit. The plugin list file is the template "shell-plugins" tagged
+"shell" and lists the local clones; render it with 'mmdot run +shell'.`,
+		Commands: []*cli.Command{
+			{
+				Name:   "sync",
+				Usage:  "clone missing plugins and check out their pinned commits",
+				Action: sc.sync,
+			},
+			{
+				Name:   "diff",
+				Usage:  "show plugins that are missing or at the wrong commit",
+				Action: sc.diff,
+			},
+		},
+	}
+
+	app.Commands = append(app.Commands, cmd)
+	return app
+}
+
+func (sc *ShellCmd) status(ctx context.Context, client *shellplugins.Client) ([]shellplugins.Status, error) {
+	cfg, err := loadConfig(sc.coreFlags)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Shell.Manager == "" {
+		return nil, nil
+	}
+	return client.Status(ctx, shellplugins.Repos(cfg.Shell))
+}
+
+func (sc *ShellCmd) sync(ctx context.Context, c *cli.Command) error {
+	client := shellplugins.New()
+	statuses, err := sc.status(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	if len(statuses) == 0 {
+		fmt.Println("No shell plugins configured")
+		return nil
+	}
+
+	failed := 0
+	items := make([]printer.StatusListItem, 0, len(statuses))
+	for _, st := range statuses {
+		name := st.Plugin.Name()
+		if st.Ok() {
+			items = append(items, printer.StatusListItem{Ok: true, Status: fmt.Sprintf("%s %s (up to date)", name, shortCommit(st.Current))})
+			continue
+		}
+
+		if err := client.Sync(ctx, st.Repo); errors.Is(err, core.ErrOffline) {
+			items = append(items, printer.StatusListItem{Ok: false, Status: fmt.Sprintf("%s: %v", name, err)})
+			continue
+		} else if err != nil {
+			failed++
+			items = append(items, printer.StatusListItem{Ok: false, Status: fmt.Sprintf("%s: %v", name, err)})
+			continue
+		}
+
+		status := fmt.Sprintf("%s %s", name, shortCommit(st.Plugin.Commit))
+		if st.Current != "" {
+			status = fmt.Sprintf("%s %s => %s", name, shortCommit(st.Current), shortCommit(st.Plugin.Commit))
+		}
+		items = append(items, printer.StatusListItem{Ok: true, Status: status})
+	}
+
+	printer.New(os.Stdout).StatusList("Shell plugins:", items)
+
+	if failed > 0 {
+		return PartialError(fmt.Errorf("%d of %d shell plugins failed to sync", failed, len(statuses)))
+	}
+	return nil
+}
+
+func (sc *ShellCmd) diff(ctx context.Context, c *cli.Command) error {
+	statuses, err := sc.status(ctx, shellplugins.New())
+	if err != nil {
+		return err
+	}
+
+	var items []printer.StatusListItem
+	for _, st := range statuses {
+		switch {
+		case st.Ok():
+			continue
+		case st.Current == "":
+			items = append(items, printer.StatusListItem{Status: fmt.Sprintf("%s: not cloned (want %s)", st.Plugin.Name(), shortCommit(st.Plugin.Commit))})
+		default:
+			items = append(items, printer.StatusListItem{Status: fmt.Sprintf("%s: %s => %s", st.Plugin.Name(), shortCommit(st.Current), shortCommit(st.Plugin.Commit))})
+		}
+	}
+
+	if len(items) == 0 {
+		fmt.Println("All shell plugins are at their pinned commits")
+		return nil
+	}
+
+	printer.New(os.Stdout).StatusList("Shell plugins at the wrong commit:", items)
+	return nil
+}
+
+// shortCommit abbreviates a commit hash for display.
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
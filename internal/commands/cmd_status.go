@@ -0,0 +1,267 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/generator"
+	"github.com/hay-kot/mmdot/internal/metrics"
+	"github.com/hay-kot/mmdot/pkgs/printer"
+	"github.com/urfave/cli/v3"
+)
+
+type StatusCmd struct {
+	coreFlags *core.Flags
+	flags     struct {
+		ExitCode bool
+		Brews    []string
+	}
+}
+
+func NewStatusCmd(coreFlags *core.Flags) *StatusCmd {
+	return &StatusCmd{coreFlags: coreFlags}
+}
+
+func (sc *StatusCmd) Register(app *cli.Command) *cli.Command {
+	app.Commands = append(app.Commands, &cli.Command{
+		Name:  "status",
+		Usage: "report where this machine has drifted from the config",
+		Description: `Compares the machine against the config without changing anything:
+
+  templates    outputs that differ from the rendered template or are missing
+  age files    age.files that are not decrypted or have the wrong permissions
+  brew         packages of the configs passed with --brew that are not installed
+  scripts      paths in a script's manages list changed since it last ran
+
+With --exit-code the command exits with code 8 when anything has drifted, so a
+cron job or monitoring agent can alert on it:
+
+  mmdot status --exit-code --brew personal || notify-send "dotfiles drifted"
+
+Drift counts per check are also written to the targets in 'metrics'.
+
+` + ExitCodesHelp,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:        "exit-code",
+				Usage:       "exit with code 8 when any drift is found",
+				Destination: &sc.flags.ExitCode,
+			},
+			&cli.StringSliceFlag{
+				Name:        "brew",
+				Usage:       "check that the packages of these brew configs are installed",
+				Destination: &sc.flags.Brews,
+			},
+		},
+		Action: sc.run,
+	})
+	return app
+}
+
+// statusCheck reports one kind of drift. Checks with nothing to report
+// return no items.
+type statusCheck struct {
+	name  string // label of the drift metric
+	title string
+	run   func(ctx context.Context, cfg *core.ConfigFile) ([]printer.StatusListItem, error)
+}
+
+func (sc *StatusCmd) run(ctx context.Context, c *cli.Command) error {
+	cfg, err := loadConfig(sc.coreFlags)
+	if err != nil {
+		return err
+	}
+	for _, name := range sc.flags.Brews {
+		if cfg.Brews.Get(name) == nil {
+			return fmt.Errorf("brew config %q not found", name)
+		}
+	}
+	if err := validateBrewIncludes(cfg.Brews, sc.flags.Brews); err != nil {
+		return err
+	}
+
+	checks := []statusCheck{
+		{name: "templates", title: "Templates:", run: statusTemplates},
+		{name: "age_files", title: "Age files:", run: statusAgeFiles},
+		{name: "brew", title: "Brew:", run: sc.statusBrews},
+		{name: "scripts", title: "Scripts:", run: statusScripts},
+	}
+
+	p := printer.New(os.Stdout)
+	drifted, reported := 0, 0
+	drift := map[string]int{}
+	for _, check := range checks {
+		items, err := check.run(ctx, &cfg)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			continue
+		}
+		if reported > 0 {
+			p.LineBreak()
+		}
+		reported++
+
+		p.StatusList(check.title, items)
+		drift[check.name] = 0
+		for _, item := range items {
+			if !item.Ok {
+				drift[check.name]++
+				drifted++
+			}
+		}
+	}
+	metrics.WriteDrift(ctx, cfg.Metrics, drift, time.Now())
+
+	switch {
+	case reported == 0:
+		fmt.Println("Nothing to check")
+	case drifted == 0:
+		p.LineBreak()
+		fmt.Println("No drift detected")
+	default:
+		p.LineBreak()
+		fmt.Printf("%d item(s) drifted\n", drifted)
+		if sc.flags.ExitCode {
+			return Silence(DriftError(fmt.Errorf("%d item(s) drifted", drifted)))
+		}
+	}
+	return nil
+}
+
+// statusTemplates renders every template in memory and compares it with its
+// output. Templates generate would skip are reported as current.
+func statusTemplates(ctx context.Context, cfg *core.ConfigFile) ([]printer.StatusListItem, error) {
+	engine := generator.NewEngine(cfg)
+
+	items := make([]printer.StatusListItem, 0, len(cfg.Templates))
+	for _, tmpl := range cfg.Templates {
+		reason, err := tmpl.SkipReason(ctx, hookShell(cfg), cfg.ConfigDir, false)
+		if err != nil {
+			return nil, fmt.Errorf("template %s: %w", tmpl.Name, err)
+		}
+		if reason != "" {
+			items = append(items, printer.StatusListItem{Ok: true, Status: fmt.Sprintf("%s skipped (%s)", tmpl.Name, reason)})
+			continue
+		}
+
+		result, err := engine.Check(ctx, tmpl)
+		if err != nil {
+			items = append(items, printer.StatusListItem{Status: fmt.Sprintf("%s failed to render: %v", tmpl.Name, err)})
+			continue
+		}
+
+		item := printer.StatusListItem{Ok: result.Status == generator.StatusCurrent, Status: tmpl.Name}
+		if !item.Ok {
+			item.Status += fmt.Sprintf(" %s (run 'mmdot generate')", result.Status)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// statusAgeFiles reports age.files whose encrypted source exists but whose
+// destination was not decrypted or has different permissions. The contents
+// are not compared, so no identity is needed.
+func statusAgeFiles(ctx context.Context, cfg *core.ConfigFile) ([]printer.StatusListItem, error) {
+	items := make([]printer.StatusListItem, 0, len(cfg.Age.Files))
+	for _, af := range cfg.Age.Files {
+		if _, err := os.Stat(af.Src); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		item := printer.StatusListItem{Ok: true, Status: af.Dest}
+		info, err := os.Stat(af.Dest)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			item.Ok = false
+			item.Status += " missing (run 'mmdot decrypt')"
+		case err != nil:
+			return nil, err
+		case af.Permissions != "":
+			perm, err := core.ParseOctalPermissions(af.Permissions)
+			if err != nil {
+				return nil, err
+			}
+			if info.Mode().Perm() != perm {
+				item.Ok = false
+				item.Status += fmt.Sprintf(" has permissions %04o, want %04o", info.Mode().Perm(), perm)
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// statusBrews reports the configured brew configs with packages that are not
+// installed.
+func (sc *StatusCmd) statusBrews(ctx context.Context, cfg *core.ConfigFile) ([]printer.StatusListItem, error) {
+	if len(sc.flags.Brews) == 0 {
+		return nil, nil
+	}
+
+	installed, err := core.LoadInstalledBrews(ctx, core.InstalledBrewsOptions{TTL: core.DefaultBrewCacheTTL})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list installed brews: %w", err)
+	}
+
+	items := make([]printer.StatusListItem, 0, len(sc.flags.Brews))
+	for _, name := range slices.Sorted(slices.Values(sc.flags.Brews)) {
+		diff := cfg.Brews.Get(name).DiffInstalled(installed)
+
+		item := printer.StatusListItem{Ok: len(diff.Absent) == 0, Status: name}
+		if !item.Ok {
+			item.Status += fmt.Sprintf(" missing %d package(s) (run 'mmdot brew diff %s')", len(diff.Absent), name)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// statusScripts reports scripts whose managed paths changed since their last
+// successful run, or that have not run on this machine yet.
+func statusScripts(ctx context.Context, cfg *core.ConfigFile) ([]printer.StatusListItem, error) {
+	managing := slices.DeleteFunc(slices.Clone(cfg.Exec.Scripts), func(s core.Script) bool { return len(s.Manages) == 0 })
+	if len(managing) == 0 {
+		return nil, nil
+	}
+
+	recorded, err := core.LoadManagedHashes()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]printer.StatusListItem, 0, len(managing))
+	for _, script := range managing {
+		name := filepath.Base(script.Path)
+		rerun := fmt.Sprintf("(run 'mmdot run --only %s')", name)
+
+		hashes, ok := recorded[script.Path]
+		if !ok {
+			items = append(items, printer.StatusListItem{Status: name + " has not run on this machine " + rerun})
+			continue
+		}
+
+		current, err := script.HashManaged()
+		if err != nil {
+			return nil, err
+		}
+
+		item := printer.StatusListItem{Ok: true, Status: name}
+		if changed := script.ChangedManaged(hashes, current); len(changed) > 0 {
+			item.Ok = false
+			item.Status += fmt.Sprintf(" changed outside mmdot: %s %s", strings.Join(changed, ", "), rerun)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hay-kot/mmdot/internal/core"
+)
+
+func Test_statusAgeFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, perm os.FileMode) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("x"), perm); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	cfg := &core.ConfigFile{Age: core.Age{Files: []core.AgeFile{
+		{Src: write("ok.age", 0o644), Dest: write("ok", 0o600), Permissions: "0600"},
+		{Src: write("missing.age", 0o644), Dest: filepath.Join(dir, "missing")},
+		{Src: write("perm.age", 0o644), Dest: write("perm", 0o644), Permissions: "0600"},
+		{Src: filepath.Join(dir, "nosrc.age"), Dest: filepath.Join(dir, "nosrc")},
+	}}}
+
+	items, err := statusAgeFiles(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []bool{true, false, false}
+	if len(items) != len(want) {
+		t.Fatalf("got %d items, want %d: %v", len(items), len(want), items)
+	}
+	for i, ok := range want {
+		if items[i].Ok != ok {
+			t.Errorf("item %d (%s) Ok = %v, want %v", i, items[i].Status, items[i].Ok, ok)
+		}
+	}
+}
+
+func Test_statusTemplates(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	src := write("tmpl.txt", "rendered")
+	cfg := &core.ConfigFile{
+		ConfigDir: dir,
+		Templates: []core.Template{
+			{Name: "changed", Template: src, Output: write("changed", "old")},
+			{Name: "exists", Template: src, Output: write("exists", "old"), SkipIfExists: true},
+			{Name: "condition", Template: src, Output: write("condition", "old"), SkipIf: "true"},
+		},
+	}
+
+	items, err := statusTemplates(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []bool{false, true, true}
+	if len(items) != len(want) {
+		t.Fatalf("got %d items, want %d: %v", len(items), len(want), items)
+	}
+	for i, ok := range want {
+		if items[i].Ok != ok {
+			t.Errorf("item %d (%s) Ok = %v, want %v", i, items[i].Status, items[i].Ok, ok)
+		}
+	}
+}
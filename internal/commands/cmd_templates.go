@@ -0,0 +1,204 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/generator"
+	"github.com/hay-kot/mmdot/pkgs/linediff"
+	"github.com/hay-kot/mmdot/pkgs/printer"
+	"github.com/urfave/cli/v3"
+)
+
+type TemplatesCmd struct {
+	coreFlags *core.Flags
+	flags     struct {
+		Update bool
+		Check  bool
+	}
+}
+
+func NewTemplatesCmd(coreFlags *core.Flags) *TemplatesCmd {
+	return &TemplatesCmd{coreFlags: coreFlags}
+}
+
+func (tc *TemplatesCmd) Register(app *cli.Command) *cli.Command {
+	cmd := &cli.Command{
+		Name:  "templates",
+		Usage: "work with templates defined in the config",
+		Commands: []*cli.Command{
+			{
+				Name:      "test",
+				Usage:     "render templates against fixture variables and compare with golden files",
+				ArgsUsage: "[template-name...]",
+				Description: `Renders every template that declares 'tests:' against each fixture and reports
+mismatches as diffs. Intended for CI to catch regressions in generated files.
+
+Fixtures are rendered with global vars, template vars, and the fixture's vars
+(highest precedence). Var files are not loaded, so tests do not need access to
+encrypted secrets.
+
+Example config:
+
+	templates:
+	  - name: gitconfig
+	    template: templates/gitconfig.tmpl
+	    output: ~/.gitconfig
+	    tests:
+	      - name: work
+	        vars: { email: me@work.com }
+	        expected: testdata/gitconfig.work.golden
+	        contains: ["email = me@work.com"]
+
+Use --update to write the current output to each golden file.`,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:        "update",
+						Usage:       "rewrite golden files with the rendered output",
+						Destination: &tc.flags.Update,
+					},
+				},
+				Action: tc.test,
+			},
+			{
+				Name:      "diff",
+				Usage:     "show how rendered templates differ from their output files",
+				ArgsUsage: "[template-name...]",
+				Description: `Renders templates in memory and prints a diff for every output file that
+would change. Nothing is written.
+
+Set 'diff.external' and 'diff.command' in the config to pipe unified diffs to
+a tool such as delta when stdout is a terminal.
+
+With --check the command exits non-zero when any output is missing or out of
+date. Run it in CI for outputs committed to the repo, such as brew install
+scripts rendered with the brewfile partial, to catch configs that were edited
+without regenerating them.`,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:        "check",
+						Usage:       "exit non-zero when any output is missing or out of date",
+						Destination: &tc.flags.Check,
+					},
+				},
+				Action: tc.diff,
+			},
+		},
+	}
+
+	app.Commands = append(app.Commands, cmd)
+	return app
+}
+
+func (tc *TemplatesCmd) test(ctx context.Context, c *cli.Command) error {
+	cfg, err := loadConfig(tc.coreFlags)
+	if err != nil {
+		return err
+	}
+
+	names := c.Args().Slice()
+	engine := generator.NewEngine(&cfg)
+
+	var results []generator.TestResult
+	for _, tmpl := range cfg.Templates {
+		if len(names) > 0 && !slices.Contains(names, tmpl.Name) {
+			continue
+		}
+		for _, test := range tmpl.Tests {
+			results = append(results, engine.RunTest(ctx, tmpl, test, tc.flags.Update))
+		}
+	}
+
+	if len(results) == 0 {
+		fmt.Println("No template tests defined")
+		return nil
+	}
+
+	failed := 0
+	items := make([]printer.StatusListItem, 0, len(results))
+	for _, r := range results {
+		status := fmt.Sprintf("%s / %s", r.Template, r.Test)
+		if r.Updated {
+			status += " (updated)"
+		}
+		items = append(items, printer.StatusListItem{Ok: r.Passed(), Status: status})
+		if !r.Passed() {
+			failed++
+		}
+	}
+
+	p := printer.New(os.Stdout)
+	p.StatusList("Template Tests:", items)
+	p.LineBreak()
+
+	for _, r := range results {
+		if r.Passed() {
+			continue
+		}
+
+		fmt.Printf("--- %s / %s\n", r.Template, r.Test)
+		if r.Err != nil {
+			fmt.Println(r.Err)
+		}
+		for _, snippet := range r.Missing {
+			fmt.Printf("missing snippet: %q\n", snippet)
+		}
+		if r.Diff != nil {
+			if linediff.HasChanges(r.Diff) {
+				printDiff(ctx, &cfg, "expected", "rendered", r.Diff)
+			} else {
+				fmt.Println("output differs from golden file only in trailing whitespace")
+			}
+		}
+		fmt.Println()
+	}
+
+	if failed > 0 {
+		return ValidationError(fmt.Errorf("%d of %d template test(s) failed", failed, len(results)))
+	}
+
+	fmt.Printf("%d template test(s) passed\n", len(results))
+	return nil
+}
+
+func (tc *TemplatesCmd) diff(ctx context.Context, c *cli.Command) error {
+	cfg, err := loadConfig(tc.coreFlags)
+	if err != nil {
+		return err
+	}
+
+	names := c.Args().Slice()
+	engine := generator.NewEngine(&cfg)
+
+	changes := 0
+	for _, tmpl := range cfg.Templates {
+		if len(names) > 0 && !slices.Contains(names, tmpl.Name) {
+			continue
+		}
+
+		result, err := engine.Check(ctx, tmpl)
+		if err != nil {
+			return fmt.Errorf("failed to render template %s: %w", tmpl.Name, err)
+		}
+		if result.Status == generator.StatusCurrent {
+			continue
+		}
+
+		changes++
+		printDiff(ctx, &cfg, fmt.Sprintf("%s (%s)", tmpl.Output, result.Status), tmpl.Name+" (rendered)",
+			linediff.Diff(string(result.Existing), string(result.Rendered)))
+		fmt.Println()
+	}
+
+	if changes == 0 {
+		fmt.Println("All template outputs are up to date")
+		return nil
+	}
+	if tc.flags.Check {
+		return ValidationError(fmt.Errorf("%d template output(s) out of date, run mmdot generate", changes))
+	}
+	return nil
+}
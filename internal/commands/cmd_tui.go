@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/tui"
+	"github.com/urfave/cli/v3"
+)
+
+type TUICmd struct {
+	coreFlags *core.Flags
+}
+
+func NewTUICmd(coreFlags *core.Flags) *TUICmd {
+	return &TUICmd{coreFlags: coreFlags}
+}
+
+func (tc *TUICmd) Register(app *cli.Command) *cli.Command {
+	cmd := &cli.Command{
+		Name:  "tui",
+		Usage: "Interactive dashboard for templates, scripts, and brew configs",
+		Description: `Opens a full-screen dashboard with a tab for each part of the configuration.
+
+ Templates  shows whether each output is current, changed, or missing. Press
+            enter to render a template or d to view the pending diff.
+ Scripts    lists configured scripts. Press enter to run one and tail its output.
+ Brew       compares each brew config against installed packages. Press enter
+            to view absent, present, and extra packages.`,
+		Action: func(ctx context.Context, c *cli.Command) error {
+			cfg, err := loadConfig(tc.coreFlags)
+			if err != nil {
+				return err
+			}
+
+			return tui.Run(ctx, &cfg)
+		},
+	}
+
+	app.Commands = append(app.Commands, cmd)
+	return app
+}
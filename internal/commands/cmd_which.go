@@ -0,0 +1,354 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/generator"
+	"github.com/hay-kot/mmdot/internal/services"
+	"github.com/hay-kot/mmdot/pkgs/printer"
+	"github.com/hay-kot/mmdot/pkgs/redact"
+	"github.com/urfave/cli/v3"
+)
+
+// maxValueWidth truncates long variable values in `mmdot which` output.
+const maxValueWidth = 60
+
+type WhichCmd struct {
+	coreFlags *core.Flags
+}
+
+func NewWhichCmd(coreFlags *core.Flags) *WhichCmd {
+	return &WhichCmd{coreFlags: coreFlags}
+}
+
+func (wc *WhichCmd) Register(app *cli.Command) *cli.Command {
+	app.Commands = append(app.Commands, &cli.Command{
+		Name:      "which",
+		Usage:     "show where a template, script, service, or brew config is defined and what selects it",
+		ArgsUsage: "<name>",
+		Description: `Looks up every item with the name and prints:
+
+  - the config file and line that define it, following overlays
+  - its tags, stage, source, and rendered destination
+  - the variables it is rendered with, vault values redacted
+  - whether each tag shortcut, macro, and run.default_expr selects it
+
+Scripts are matched by file name with or without the extension. Use it to
+answer "why didn't X run?":
+
+  mmdot which zshrc`,
+		Action: wc.run,
+	})
+	return app
+}
+
+func (wc *WhichCmd) run(ctx context.Context, c *cli.Command) error {
+	name := strings.TrimSpace(c.Args().First())
+	if name == "" {
+		return fmt.Errorf("a template, script, service, or brew config name is required")
+	}
+
+	cfg, err := loadConfig(wc.coreFlags)
+	if err != nil {
+		return err
+	}
+
+	engine := generator.NewEngine(&cfg)
+	p := printer.New(os.Stdout)
+	found := 0
+	section := func() {
+		if found > 0 {
+			p.LineBreak()
+		}
+		found++
+	}
+
+	for i, tmpl := range cfg.Templates {
+		if tmpl.Name != name {
+			continue
+		}
+		section()
+		wc.printItem(ctx, p, &cfg, whichItem{
+			kind:    "Template",
+			name:    tmpl.Name,
+			defined: definedAt(&cfg, "$.templates", "name", tmpl.Name, i),
+			tags:    tmpl.Tags,
+			stage:   tmpl.Stage,
+			source:  tmpl.Template,
+			output:  tmpl.Output,
+		})
+		printVars(ctx, p, engine, tmpl)
+		printSelectors(ctx, p, &cfg, tmpl.Name, tmpl.Tags, map[string]any{"name": tmpl.Name, "tags": tmpl.Tags})
+	}
+
+	for i, script := range cfg.Exec.Scripts {
+		base := filepath.Base(script.Path)
+		if !matchName(name, base) {
+			continue
+		}
+		section()
+		wc.printItem(ctx, p, &cfg, whichItem{
+			kind:    "Script",
+			name:    base,
+			defined: definedAt(&cfg, "$.exec.scripts", "path", base, i),
+			tags:    script.Tags,
+			stage:   script.Stage,
+			source:  script.Path,
+		})
+		printSelectors(ctx, p, &cfg, base, script.Tags, map[string]any{"name": base, "tags": script.Tags, "path": script.Path})
+	}
+
+	for i, svc := range cfg.Services {
+		if svc.Name != name {
+			continue
+		}
+		section()
+		tmpl, err := services.Template(services.Detect(), svc)
+		if err != nil {
+			return err
+		}
+		wc.printItem(ctx, p, &cfg, whichItem{
+			kind:    "Service",
+			name:    svc.Name,
+			defined: definedAt(&cfg, "$.services", "name", svc.Name, i),
+			tags:    svc.Tags,
+			stage:   svc.Stage,
+			source:  svc.Template,
+			output:  tmpl.Output,
+		})
+		printVars(ctx, p, engine, tmpl)
+		printSelectors(ctx, p, &cfg, svc.Name, svc.Tags, map[string]any{"name": svc.Name, "tags": svc.Tags})
+	}
+
+	if brews := cfg.Brews.Get(name); brews != nil {
+		section()
+		printBrewConfig(p, &cfg, name, brews)
+	}
+
+	if found == 0 {
+		err := fmt.Errorf("no template, script, service, or brew config named %q", name)
+		if s := suggestName(name, whichNames(&cfg)); s != "" {
+			err = fmt.Errorf("%w (did you mean %q?)", err, s)
+		}
+		return err
+	}
+	return nil
+}
+
+type whichItem struct {
+	kind    string
+	name    string
+	defined string
+	tags    []string
+	stage   core.Stage
+	source  string // template or script path, or inline template text
+	output  string
+}
+
+func (wc *WhichCmd) printItem(ctx context.Context, p *printer.Printer, cfg *core.ConfigFile, item whichItem) {
+	p.Title(item.kind + " " + item.name)
+
+	field := func(label, value string) {
+		if value != "" {
+			fmt.Printf("  %-8s %s\n", label, value)
+		}
+	}
+	field("defined", item.defined)
+	field("tags", strings.Join(item.tags, ", "))
+	field("stage", string(item.stage.OrDefault()))
+	if strings.ContainsAny(item.source, "\n{") || !filepath.IsAbs(item.source) {
+		field("source", "inline template")
+	} else {
+		field("source", relToConfig(cfg, item.source))
+	}
+	field("output", item.output)
+}
+
+// printVars prints the variables tmpl is rendered with, including
+// requires_vars defaults.
+func printVars(ctx context.Context, p *printer.Printer, engine *generator.Engine, tmpl core.Template) {
+	p.LineBreak()
+
+	vars, err := engine.Vars(ctx, tmpl)
+	if err != nil {
+		p.List("Variables", []string{"unavailable: " + err.Error()})
+		return
+	}
+	core.ApplyRequiredVars(vars, tmpl.RequiresVars)
+	if len(vars) == 0 {
+		p.List("Variables", []string{"none"})
+		return
+	}
+
+	rows := make([][]string, 0, len(vars))
+	for _, key := range slices.Sorted(maps.Keys(vars)) {
+		rows = append(rows, []string{key, formatVar(vars[key])})
+	}
+	p.Table("Variables", []string{"NAME", "VALUE"}, rows)
+}
+
+// formatVar returns value as compact JSON with secrets redacted, truncated
+// to [maxValueWidth].
+func formatVar(value any) string {
+	s := fmt.Sprint(value)
+	if data, err := json.Marshal(value); err == nil {
+		s = string(data)
+	}
+	s = redact.String(s)
+	if r := []rune(s); len(r) > maxValueWidth {
+		s = string(r[:maxValueWidth-1]) + "…"
+	}
+	return s
+}
+
+// printSelectors reports which tag shortcuts, macros, and run.default_expr
+// select an item whose expression environment is env.
+func printSelectors(ctx context.Context, p *printer.Printer, cfg *core.ConfigFile, name string, tags []string, env map[string]any) {
+	p.LineBreak()
+
+	items := []printer.StatusListItem{{Ok: true, Status: "mmdot run --only " + name}}
+	for _, tag := range tags {
+		items = append(items, printer.StatusListItem{Ok: true, Status: "mmdot run +" + tag})
+	}
+
+	selects := func(code string) (bool, error) {
+		program, err := compileExpr(ctx, code, cfg.Macros, true)
+		if err != nil {
+			return false, err
+		}
+		return evalCompiledExpr(program, env)
+	}
+
+	for _, macro := range slices.Sorted(maps.Keys(cfg.Macros)) {
+		ok, err := selects("@" + macro)
+		status := "mmdot run @" + macro
+		if err != nil {
+			status += " (invalid: " + err.Error() + ")"
+		}
+		items = append(items, printer.StatusListItem{Ok: ok, Status: status})
+	}
+
+	if cfg.Run.DefaultExpr != "" {
+		ok, err := selects(cfg.Run.DefaultExpr)
+		status := "mmdot run (run.default_expr: " + cfg.Run.DefaultExpr + ")"
+		if err != nil {
+			status += " (invalid: " + err.Error() + ")"
+		}
+		items = append(items, printer.StatusListItem{Ok: ok, Status: status})
+	}
+
+	p.StatusList("Selected by", items)
+}
+
+func printBrewConfig(p *printer.Printer, cfg *core.ConfigFile, name string, brews *core.Brews) {
+	p.Title("Brew config " + name)
+	fmt.Printf("  %-8s %s\n", "defined", definedAt(cfg, "$.brews", "", name, -1))
+	if includes := cfg.Brews[name].Includes; len(includes) > 0 {
+		fmt.Printf("  %-8s %s\n", "includes", strings.Join(includes, ", "))
+	}
+	fmt.Printf("  %-8s %d brews, %d casks, %d taps, %d mas\n", "packages", len(brews.Brews), len(brews.Casks), len(brews.Taps), len(brews.MAS))
+
+	var users []string
+	for _, tmpl := range cfg.Templates {
+		body, err := generator.TemplateSource(tmpl.Template)
+		if err == nil && strings.Contains(body, fmt.Sprintf("brewConfig %q", name)) {
+			users = append(users, tmpl.Name)
+		}
+	}
+	for _, other := range slices.Sorted(maps.Keys(cfg.Brews)) {
+		if slices.Contains(cfg.Brews[other].Includes, name) {
+			users = append(users, "brew config "+other)
+		}
+	}
+	if len(users) > 0 {
+		p.LineBreak()
+		p.List("Used by", users)
+	}
+}
+
+// definedAt returns the file and line of the entry in the list or map at
+// path. List entries are matched by the value of key, compared by base name,
+// and map entries by their key. Overlays are searched first since their
+// entries win. Without a match the index, or the key, is shown instead.
+func definedAt(cfg *core.ConfigFile, path, key, value string, index int) string {
+	for _, file := range slices.Backward(cfg.ConfigPaths) {
+		if line := findEntryLine(file, path, key, value); line > 0 {
+			return fmt.Sprintf("%s:%d", relToConfig(cfg, file), line)
+		}
+	}
+	if index >= 0 {
+		return fmt.Sprintf("%s[%d]", path, index)
+	}
+	return path + "." + value
+}
+
+func findEntryLine(file, path, key, value string) int {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0
+	}
+	parsed, err := parser.ParseBytes(data, 0)
+	if err != nil {
+		return 0
+	}
+	yamlPath, err := yaml.PathString(path)
+	if err != nil {
+		return 0
+	}
+	node, err := yamlPath.FilterFile(parsed)
+	if err != nil {
+		return 0
+	}
+
+	switch node := node.(type) {
+	case *ast.MappingNode:
+		for _, entry := range node.Values {
+			if entry.Key.String() == value {
+				return entry.Key.GetToken().Position.Line
+			}
+		}
+	case *ast.SequenceNode:
+		for _, item := range node.Values {
+			mapping, ok := item.(*ast.MappingNode)
+			if !ok {
+				continue
+			}
+			for _, entry := range mapping.Values {
+				var s string
+				if entry.Key.String() != key || yaml.NodeToValue(entry.Value, &s) != nil {
+					continue
+				}
+				if s == value || filepath.Base(s) == value {
+					return entry.Key.GetToken().Position.Line
+				}
+			}
+		}
+	}
+	return 0
+}
+
+// relToConfig returns path relative to the config directory when it is
+// inside it.
+func relToConfig(cfg *core.ConfigFile, path string) string {
+	if rel, err := filepath.Rel(cfg.ConfigDir, path); err == nil && filepath.IsLocal(rel) {
+		return rel
+	}
+	return path
+}
+
+// whichNames returns every name `mmdot which` accepts.
+func whichNames(cfg *core.ConfigFile) []string {
+	names := itemNames(cfg, RunnerTypes)
+	return append(names, slices.Collect(maps.Keys(cfg.Brews))...)
+}
@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_findEntryLine(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "mmdot.yml")
+	data := `brews:
+  base:
+    brews: [git]
+templates:
+  - name: zshrc
+    template: zshrc.tmpl
+  - tags: [git]
+    name: gitconfig
+exec:
+  scripts:
+    - path: scripts/setup.sh
+`
+	if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		path  string
+		key   string
+		value string
+		want  int
+	}{
+		{name: "map entry", path: "$.brews", value: "base", want: 2},
+		{name: "list entry", path: "$.templates", key: "name", value: "zshrc", want: 5},
+		{name: "key not first", path: "$.templates", key: "name", value: "gitconfig", want: 8},
+		{name: "base name", path: "$.exec.scripts", key: "path", value: "setup.sh", want: 11},
+		{name: "missing entry", path: "$.templates", key: "name", value: "tmux", want: 0},
+		{name: "missing section", path: "$.services", key: "name", value: "zshrc", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findEntryLine(file, tt.path, tt.key, tt.value); got != tt.want {
+				t.Errorf("findEntryLine() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
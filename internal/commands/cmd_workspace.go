@@ -0,0 +1,171 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/pkgs/printer"
+	"github.com/urfave/cli/v3"
+)
+
+type WorkspaceCmd struct {
+	coreFlags *core.Flags
+	flags     struct {
+		Force bool
+	}
+}
+
+func NewWorkspaceCmd(coreFlags *core.Flags) *WorkspaceCmd {
+	return &WorkspaceCmd{coreFlags: coreFlags}
+}
+
+func (wc *WorkspaceCmd) Register(app *cli.Command) *cli.Command {
+	app.Commands = append(app.Commands, &cli.Command{
+		Name:  "workspace",
+		Usage: "manage named dotfiles configs selected with -w",
+		Description: `Workspaces name the configs of several dotfiles repos so any command can
+run against one of them without its path:
+
+  mmdot workspace add work ~/code/work-dotfiles/mmdot.yml
+  mmdot -w work run
+  MMDOT_WORKSPACE=work mmdot status
+
+The registry is stored in $XDG_CONFIG_HOME/mmdot/workspaces.yml
+(~/.config/mmdot/workspaces.yml). --workspace replaces --config and cannot be
+combined with it; a config from MMDOT_CONFIG_PATH is ignored instead.`,
+		Commands: []*cli.Command{
+			{
+				Name:   "list",
+				Usage:  "list registered workspaces",
+				Action: wc.list,
+			},
+			{
+				Name:      "add",
+				Usage:     "register a config as a workspace",
+				ArgsUsage: "<name> [config-path]",
+				Description: `Registers config-path, or the --config in use when omitted, under name.
+Relative paths are resolved against the current directory.`,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:        "force",
+						Usage:       "replace a workspace that is already registered",
+						Destination: &wc.flags.Force,
+					},
+				},
+				Action: wc.add,
+			},
+			{
+				Name:          "remove",
+				Usage:         "unregister a workspace; its config is left alone",
+				ArgsUsage:     "<name>",
+				ShellComplete: wc.complete,
+				Action:        wc.remove,
+			},
+		},
+	})
+	return app
+}
+
+func (wc *WorkspaceCmd) list(ctx context.Context, c *cli.Command) error {
+	workspaces, err := core.LoadWorkspaces()
+	if err != nil {
+		return err
+	}
+	if len(workspaces) == 0 {
+		fmt.Println("No workspaces registered, add one with `mmdot workspace add <name> <config>`")
+		return nil
+	}
+
+	rows := make([][]string, 0, len(workspaces))
+	for _, name := range workspaces.Names() {
+		path := workspaces[name]
+		if name == wc.coreFlags.Workspace {
+			name += " (current)"
+		}
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			path += " (not found)"
+		}
+		rows = append(rows, []string{name, path})
+	}
+	printer.New(os.Stdout).Table("Workspaces", []string{"NAME", "CONFIG"}, rows)
+	return nil
+}
+
+func (wc *WorkspaceCmd) add(ctx context.Context, c *cli.Command) error {
+	name := c.Args().First()
+	if name == "" || c.Args().Len() > 2 {
+		return fmt.Errorf("usage: mmdot workspace add <name> [config-path]")
+	}
+	if err := core.ValidateWorkspaceName(name); err != nil {
+		return err
+	}
+
+	path := c.Args().Get(1)
+	if path == "" {
+		path = wc.coreFlags.ConfigFilePath
+	}
+	path, err := core.PathResolver{}.Resolve(path)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(path); err != nil {
+		return err
+	} else if info.IsDir() {
+		return fmt.Errorf("%s is a directory, pass the path of its config file", path)
+	}
+
+	workspaces, err := core.LoadWorkspaces()
+	if err != nil {
+		return err
+	}
+	if existing, ok := workspaces[name]; ok && existing != path && !wc.flags.Force {
+		return fmt.Errorf("workspace %q is already registered for %s, pass --force to replace it", name, existing)
+	}
+
+	workspaces[name] = path
+	if err := workspaces.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("Added workspace %s -> %s\n", name, path)
+	return nil
+}
+
+func (wc *WorkspaceCmd) remove(ctx context.Context, c *cli.Command) error {
+	name := c.Args().First()
+	if name == "" || c.Args().Len() > 1 {
+		return fmt.Errorf("usage: mmdot workspace remove <name>")
+	}
+
+	workspaces, err := core.LoadWorkspaces()
+	if err != nil {
+		return err
+	}
+	if _, err := workspaces.Config(name); err != nil {
+		return err
+	}
+
+	delete(workspaces, name)
+	if err := workspaces.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("Removed workspace %s\n", name)
+	return nil
+}
+
+// complete prints the workspace names for shell completion.
+func (wc *WorkspaceCmd) complete(ctx context.Context, c *cli.Command) {
+	if c.Args().Len() > 0 {
+		return
+	}
+	workspaces, err := core.LoadWorkspaces()
+	if err != nil {
+		return
+	}
+	for _, name := range workspaces.Names() {
+		fmt.Println(name)
+	}
+}
@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/pkgs/linediff"
+	"github.com/hay-kot/mmdot/pkgs/redact"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/term"
+)
+
+// printDiff writes the diff between from and to to stdout. When diff.external
+// is configured and stdout is a terminal, a unified diff is piped to
+// diff.command; otherwise, or if the command fails, the built-in renderer is
+// used. Secret values are redacted unless --reveal-secrets is set.
+func printDiff(ctx context.Context, cfg *core.ConfigFile, fromName, toName string, lines []linediff.Line) {
+	if cfg.Diff.External && cfg.Diff.Command != "" && term.IsTerminal(int(os.Stdout.Fd())) {
+		err := runDiffCommand(ctx, cfg, redact.String(linediff.Unified(fromName, toName, lines, 3)))
+		if err == nil {
+			return
+		}
+		log.Warn().Err(err).Str("command", cfg.Diff.Command).Msg("external diff failed, using built-in diff")
+	}
+
+	fmt.Printf("--- %s\n+++ %s\n", fromName, toName)
+	fmt.Print(redact.String(linediff.Format(lines)))
+}
+
+func runDiffCommand(ctx context.Context, cfg *core.ConfigFile, unified string) error {
+	cmd := exec.CommandContext(ctx, hookShell(cfg), "-c", cfg.Diff.Command)
+	cmd.Stdin = strings.NewReader(unified)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Dir = cfg.ConfigDir
+
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		return nil // diff-style tools exit 1 when inputs differ
+	}
+	return err
+}
@@ -0,0 +1,130 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/pkgs/fcrypt"
+	"github.com/hay-kot/mmdot/pkgs/printer"
+)
+
+// ExitCode is the process exit status for a class of failure. Wrapper scripts
+// and CI can branch on these values.
+type ExitCode int
+
+const (
+	ExitOK         ExitCode = 0
+	ExitFailure    ExitCode = 1 // Unclassified failure
+	ExitConfig     ExitCode = 2 // Config file could not be read, parsed, or resolved
+	ExitDecrypt    ExitCode = 3 // Identity could not be loaded or a file could not be decrypted
+	ExitScript     ExitCode = 4 // A script or hook exited non-zero
+	ExitValidation ExitCode = 5 // A check failed (unencrypted files, template tests, unit validation)
+	ExitPartial    ExitCode = 6 // Some work completed but a follow-up step or, with --keep-going, an item failed
+	ExitLocked     ExitCode = 7 // Another mmdot process holds the run lock
+	ExitDrift      ExitCode = 8 // The machine differs from the config (status --exit-code)
+)
+
+// ExitError associates an error with the exit code the process should use.
+type ExitError struct {
+	Code   ExitCode
+	Err    error
+	Silent bool // Exit with Code without printing Err
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+func newExitError(code ExitCode, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ExitError{Code: code, Err: err}
+}
+
+func ConfigError(err error) error     { return newExitError(ExitConfig, err) }
+func DecryptError(err error) error    { return newExitError(ExitDecrypt, err) }
+func ScriptError(err error) error     { return newExitError(ExitScript, err) }
+func ValidationError(err error) error { return newExitError(ExitValidation, err) }
+func PartialError(err error) error    { return newExitError(ExitPartial, err) }
+func LockedError(err error) error     { return newExitError(ExitLocked, err) }
+func DriftError(err error) error      { return newExitError(ExitDrift, err) }
+
+// Silence marks err so the process exits with its code without printing it.
+func Silence(err error) error {
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return &ExitError{Code: exitErr.Code, Err: err, Silent: true}
+	}
+	return &ExitError{Code: ExitCode(ExitCodeOf(err)), Err: err, Silent: true}
+}
+
+// IsSilent reports whether err was marked with [Silence].
+func IsSilent(err error) bool {
+	var exitErr *ExitError
+	return errors.As(err, &exitErr) && exitErr.Silent
+}
+
+// ExitCodeOf returns the exit code for err. The outermost [ExitError] wins;
+// unclassified decryption failures map to [ExitDecrypt] and anything else to
+// [ExitFailure].
+func ExitCodeOf(err error) int {
+	if err == nil {
+		return int(ExitOK)
+	}
+
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return int(exitErr.Code)
+	}
+
+	if errors.Is(err, fcrypt.ErrDecrypt) {
+		return int(ExitDecrypt)
+	}
+
+	return int(ExitFailure)
+}
+
+// loadConfig reads the config file and classifies failures as [ExitConfig].
+func loadConfig(flags *core.Flags) (core.ConfigFile, error) {
+	cfg, err := core.SetupEnv(flags.ConfigFilePath, flags.ConfigOverlays()...)
+	if err != nil {
+		err = fmt.Errorf("failed to load config %s: %w", strings.Join(flags.ConfigFilePaths, ", "), err)
+		if errors.Is(err, fs.ErrNotExist) {
+			err = printer.WithTitle("Config file not found", err,
+				"run mmdot from your dotfiles directory",
+				"or pass the config file with --config",
+			)
+		}
+		return cfg, ConfigError(err)
+	}
+	return cfg, nil
+}
+
+// runLock takes the run lock, classifying contention as [ExitLocked].
+func runLock() (func(), error) {
+	unlock, err := core.AcquireRunLock()
+	if errors.Is(err, core.ErrLocked) {
+		return nil, LockedError(err)
+	}
+	return unlock, err
+}
+
+// ExitCodesHelp documents the exit codes for command help output.
+const ExitCodesHelp = `Exit codes:
+	 0  success
+	 1  unclassified failure
+	 2  config error
+	 3  decryption failure
+	 4  script or hook failure
+	 5  validation failure
+	 6  partial success (an after hook or, with --keep-going, some items failed)
+	 7  another run or apply holds the run lock
+	 8  drift detected (status --exit-code)`
@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/hay-kot/mmdot/pkgs/fcrypt"
+)
+
+func TestExitCodeOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ExitCode
+	}{
+		{name: "nil", err: nil, want: ExitOK},
+		{name: "plain", err: errors.New("boom"), want: ExitFailure},
+		{name: "config", err: ConfigError(errors.New("bad yaml")), want: ExitConfig},
+		{name: "wrapped script", err: fmt.Errorf("run: %w", ScriptError(errors.New("exit 1"))), want: ExitScript},
+		{name: "unclassified decrypt", err: fmt.Errorf("vars: %w", fcrypt.ErrDecrypt), want: ExitDecrypt},
+		{name: "outermost wins", err: PartialError(ScriptError(errors.New("after hook"))), want: ExitPartial},
+		{name: "silenced drift", err: Silence(DriftError(errors.New("2 item(s) drifted"))), want: ExitDrift},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCodeOf(tt.err); got != int(tt.want) {
+				t.Errorf("ExitCodeOf() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSilence(t *testing.T) {
+	err := Silence(ValidationError(errors.New("boom")))
+	if !IsSilent(err) {
+		t.Error("expected silenced error")
+	}
+	if got := ExitCodeOf(err); got != int(ExitValidation) {
+		t.Errorf("ExitCodeOf = %d, want %d", got, ExitValidation)
+	}
+	if IsSilent(ValidationError(errors.New("boom"))) {
+		t.Error("unsilenced error reported as silent")
+	}
+}
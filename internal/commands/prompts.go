@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"context"
+	"os"
+
+	"github.com/charmbracelet/huh"
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/generator"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/term"
+)
+
+// askPrompts asks for every prompted variable that is not set by vars, var
+// files, or a cached answer, and stores the answers in cfg.Variables.Vars.
+// Non-password answers are cached for later runs. Nothing is asked when
+// interactive is false, stdin is not a terminal, or in CI mode.
+func askPrompts(ctx context.Context, cfg *core.ConfigFile, interactive bool) error {
+	missing, err := generator.NewEngine(cfg).UnansweredPrompts(ctx)
+	if err != nil || len(missing) == 0 {
+		return err
+	}
+
+	if !interactive || core.CI || !term.IsTerminal(int(os.Stdin.Fd())) {
+		for _, p := range missing {
+			log.Warn().Str("var", p.Name).Msg("prompted variable not set and input is not interactive")
+		}
+		return nil
+	}
+
+	values := make([]string, len(missing))
+	fields := make([]huh.Field, len(missing))
+	for i, p := range missing {
+		values[i] = p.Default
+
+		switch p.Type {
+		case core.PromptSelect:
+			fields[i] = huh.NewSelect[string]().
+				Title(p.Title()).
+				Options(huh.NewOptions(p.Options...)...).
+				Value(&values[i])
+		case core.PromptPassword:
+			fields[i] = huh.NewInput().
+				Title(p.Title()).
+				EchoMode(huh.EchoModePassword).
+				Value(&values[i])
+		default:
+			fields[i] = huh.NewInput().
+				Title(p.Title()).
+				Value(&values[i])
+		}
+	}
+
+	if err := huh.NewForm(huh.NewGroup(fields...)).RunWithContext(ctx); err != nil {
+		return err
+	}
+
+	if cfg.Variables.Vars == nil {
+		cfg.Variables.Vars = map[string]any{}
+	}
+
+	cached := map[string]string{}
+	for i, p := range missing {
+		cfg.Variables.Vars[p.Name] = values[i]
+		if p.Cached() {
+			cached[p.Name] = values[i]
+		}
+	}
+
+	if len(cached) > 0 {
+		if err := core.SavePromptAnswers(cfg.ConfigDir, cached); err != nil {
+			log.Warn().Err(err).Msg("failed to cache prompt answers")
+		}
+	}
+
+	return nil
+}
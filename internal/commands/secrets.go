@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/urfave/cli/v3"
+	"golang.org/x/term"
+)
+
+// secretFlags are the non-interactive sources of a secret. Secrets are never
+// accepted as arguments, where they end up in shell history and process
+// listings.
+type secretFlags struct {
+	Env string // name of an environment variable holding the secret
+	FD  int    // file descriptor to read the secret from, -1 when unset
+}
+
+// flags returns the --<name>-env and --<name>-fd flags for a secret.
+func (sf *secretFlags) flags(name string) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:        name + "-env",
+			Usage:       "read the " + name + " from the environment variable `VAR`",
+			Destination: &sf.Env,
+		},
+		&cli.IntFlag{
+			Name:        name + "-fd",
+			Usage:       "read the " + name + " from file descriptor `N`",
+			Value:       -1,
+			Destination: &sf.FD,
+		},
+	}
+}
+
+// readSecret returns the secret from the environment variable or file
+// descriptor set in sf. Otherwise it is asked for with hidden input, twice
+// when confirm is set, or read from stdin when stdin is not a terminal or in
+// CI mode. A single trailing newline is removed from secrets that are read.
+func readSecret(ctx context.Context, title string, sf secretFlags, confirm bool) (string, error) {
+	switch {
+	case sf.Env != "" && sf.FD >= 0:
+		return "", errors.New("only one of the -env and -fd flags may be set")
+	case sf.Env != "":
+		value, ok := os.LookupEnv(sf.Env)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", sf.Env)
+		}
+		return value, nil
+	case sf.FD >= 0:
+		f := os.NewFile(uintptr(sf.FD), fmt.Sprintf("fd %d", sf.FD))
+		if f == nil {
+			return "", fmt.Errorf("invalid file descriptor %d", sf.FD)
+		}
+		defer func() { _ = f.Close() }()
+		return readSecretFrom(f)
+	case core.CI || !term.IsTerminal(int(os.Stdin.Fd())):
+		return readSecretFrom(os.Stdin)
+	}
+
+	var value, again string
+	fields := []huh.Field{
+		huh.NewInput().Title(title).EchoMode(huh.EchoModePassword).Value(&value),
+	}
+	if confirm {
+		fields = append(fields, huh.NewInput().
+			Title("Confirm "+strings.ToLower(title)).
+			EchoMode(huh.EchoModePassword).
+			Value(&again).
+			Validate(func(s string) error {
+				if s != value {
+					return errors.New("values do not match")
+				}
+				return nil
+			}))
+	}
+
+	if err := huh.NewForm(huh.NewGroup(fields...)).RunWithContext(ctx); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+func readSecretFrom(r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret: %w", err)
+	}
+	value := strings.TrimSuffix(string(data), "\n")
+	return strings.TrimSuffix(value, "\r"), nil
+}
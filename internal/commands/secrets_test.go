@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestReadSecret(t *testing.T) {
+	ctx := context.Background()
+
+	t.Setenv("MMDOT_TEST_SECRET", "from-env")
+	got, err := readSecret(ctx, "Value", secretFlags{Env: "MMDOT_TEST_SECRET", FD: -1}, false)
+	if err != nil || got != "from-env" {
+		t.Errorf("readSecret(env) = %q, %v", got, err)
+	}
+
+	if _, err := readSecret(ctx, "Value", secretFlags{Env: "MMDOT_TEST_UNSET", FD: -1}, false); err == nil {
+		t.Error("readSecret() accepted an unset environment variable")
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.WriteString("from-fd\n"); err != nil {
+		t.Fatal(err)
+	}
+	_ = w.Close()
+
+	got, err = readSecret(ctx, "Value", secretFlags{FD: int(r.Fd())}, false)
+	if err != nil || got != "from-fd" {
+		t.Errorf("readSecret(fd) = %q, %v", got, err)
+	}
+
+	if _, err := readSecret(ctx, "Value", secretFlags{Env: "MMDOT_TEST_SECRET", FD: 3}, false); err == nil {
+		t.Error("readSecret() accepted both an environment variable and a file descriptor")
+	}
+}
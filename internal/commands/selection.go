@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/expr-lang/expr"
+	"github.com/hay-kot/mmdot/internal/core"
+)
+
+// exprGlob matches a name against a shell glob pattern, e.g.
+// glob("zsh*", name). See [matchName].
+var exprGlob = expr.Function("glob", func(params ...any) (any, error) {
+	pattern, _ := params[0].(string)
+	name, _ := params[1].(string)
+	return matchName(pattern, name), nil
+}, new(func(string, string) bool))
+
+// matchName reports whether pattern matches name, or name without its file
+// extension so that "brew-bundle" selects the script brew-bundle.sh.
+func matchName(pattern, name string) bool {
+	if ok, _ := path.Match(pattern, name); ok {
+		return true
+	}
+	if ext := path.Ext(name); ext != "" {
+		ok, _ := path.Match(pattern, strings.TrimSuffix(name, ext))
+		return ok
+	}
+	return false
+}
+
+// nameFilterExpr converts --only and --skip patterns into an expression that
+// matches items whose name matches any only pattern and no skip pattern.
+// Returns an empty string when both lists are empty.
+func nameFilterExpr(only, skip []string) string {
+	globs := func(patterns []string) string {
+		var parts []string
+		for _, p := range patterns {
+			parts = append(parts, fmt.Sprintf("glob(%s, name)", strconv.Quote(p)))
+		}
+		return strings.Join(parts, " || ")
+	}
+
+	var parts []string
+	if len(only) > 0 {
+		parts = append(parts, "("+globs(only)+")")
+	}
+	if len(skip) > 0 {
+		parts = append(parts, "not ("+globs(skip)+")")
+	}
+	return strings.Join(parts, " && ")
+}
+
+// cleanPatterns trims patterns and drops empty entries.
+func cleanPatterns(patterns []string) []string {
+	out := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// itemNames returns the names of every item of the given types, as seen by
+// the name expression variable.
+func itemNames(cfg *core.ConfigFile, types []RunnerType) []string {
+	var names []string
+	if slices.Contains(types, RunnerTypeTemplate) {
+		for _, tmpl := range cfg.Templates {
+			names = append(names, tmpl.Name)
+		}
+	}
+	if slices.Contains(types, RunnerTypeScript) {
+		for _, script := range cfg.Exec.Scripts {
+			names = append(names, filepath.Base(script.Path))
+		}
+	}
+	if slices.Contains(types, RunnerTypeService) {
+		for _, svc := range cfg.Services {
+			names = append(names, svc.Name)
+		}
+	}
+	return names
+}
+
+// checkPatterns returns an error for the first pattern that matches none of
+// names, suggesting the closest name when there is a likely typo. label
+// prefixes the error, e.g. "--only".
+func checkPatterns(label string, patterns, names []string) error {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("%s %q: %w", label, p, err)
+		}
+
+		if slices.ContainsFunc(names, func(name string) bool { return matchName(p, name) }) {
+			continue
+		}
+
+		if s := suggestName(p, names); s != "" {
+			return fmt.Errorf("%s %q matches nothing (did you mean %q?)", label, p, s)
+		}
+		return fmt.Errorf("%s %q matches nothing", label, p)
+	}
+	return nil
+}
+
+// suggestName returns the name closest to s by edit distance, or "" when no
+// name is close enough to be a plausible typo.
+func suggestName(s string, names []string) string {
+	best, bestDist := "", max(2, len(s)/3)+1
+	for _, name := range names {
+		d := editDistance(s, name)
+		if ext := path.Ext(name); ext != "" {
+			d = min(d, editDistance(s, strings.TrimSuffix(name, ext)))
+		}
+		if d < bestDist {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
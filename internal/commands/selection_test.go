@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"context"
+	"testing"
+)
+
+func Test_nameFilterExpr(t *testing.T) {
+	tests := []struct {
+		name string
+		only []string
+		skip []string
+		item string
+		want bool
+	}{
+		{name: "only exact", only: []string{"zshrc", "starship"}, item: "starship", want: true},
+		{name: "only miss", only: []string{"zshrc"}, item: "starship", want: false},
+		{name: "only glob", only: []string{"zsh*"}, item: "zshenv", want: true},
+		{name: "skip", skip: []string{"brew-bundle"}, item: "brew-bundle", want: false},
+		{name: "without extension", skip: []string{"brew-bundle"}, item: "brew-bundle.sh", want: false},
+		{name: "skip other", skip: []string{"brew-*"}, item: "zshrc", want: true},
+		{name: "skip wins", only: []string{"*"}, skip: []string{"zshrc"}, item: "zshrc", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program, err := compileExpr(context.Background(), "", nil, true, nameFilterExpr(tt.only, tt.skip))
+			if err != nil {
+				t.Fatalf("compileExpr() unexpected error = %v", err)
+			}
+
+			got, err := evalCompiledExpr(program, map[string]any{"name": tt.item, "tags": []string{}})
+			if err != nil {
+				t.Fatalf("evalCompiledExpr() unexpected error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("evaluation result = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_checkPatterns(t *testing.T) {
+	names := []string{"zshrc", "starship", "brew-bundle.sh"}
+
+	tests := []struct {
+		name     string
+		patterns []string
+		wantErr  string
+	}{
+		{name: "known", patterns: []string{"zshrc", "brew-*"}},
+		{name: "typo", patterns: []string{"zshr"}, wantErr: `--only "zshr" matches nothing (did you mean "zshrc"?)`},
+		{name: "typo without extension", patterns: []string{"brew-bundl"}, wantErr: `--only "brew-bundl" matches nothing (did you mean "brew-bundle.sh"?)`},
+		{name: "unknown", patterns: []string{"tmux"}, wantErr: `--only "tmux" matches nothing`},
+		{name: "bad glob", patterns: []string{"[z"}, wantErr: `--only "[z": syntax error in pattern`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPatterns("--only", tt.patterns, names)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("checkPatterns() unexpected error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("checkPatterns() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/generator"
+	"github.com/rs/zerolog/log"
+)
+
+// changedSince returns the names of the templates, scripts, and services
+// affected by commits and uncommitted changes since the git ref. An item is
+// affected when its definition in the config differs from the config at ref,
+// or a file it reads changed:
+//
+//   - templates and services: the template file, var files, and variables
+//   - templates calling brewConfig: the brews section
+//   - scripts: the script file
+//
+// Every item is affected when the config at ref cannot be loaded.
+func changedSince(ctx context.Context, cfg *core.ConfigFile, ref string) ([]string, error) {
+	out, err := git(ctx, cfg.ConfigDir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("--since requires the config to be in a git repository: %w", err)
+	}
+	root := strings.TrimSpace(out)
+
+	if _, err := git(ctx, root, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+		return nil, fmt.Errorf("--since %q is not a commit", ref)
+	}
+
+	// git reports real paths while the config may be reached through a
+	// symlink, e.g. /tmp on macOS
+	realDir, err := filepath.EvalSymlinks(cfg.ConfigDir)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := changedFiles(ctx, root, ref)
+	if err != nil {
+		return nil, err
+	}
+	changed := make(map[string]bool, len(files))
+	for _, file := range files {
+		changed[replacePrefix(file, realDir, cfg.ConfigDir)] = true
+	}
+
+	old, err := core.LoadConfigFrom(func(path string) ([]byte, error) {
+		rel, err := filepath.Rel(root, replacePrefix(path, cfg.ConfigDir, realDir))
+		if err != nil || !filepath.IsLocal(rel) {
+			return os.ReadFile(path) // not tracked by this repository
+		}
+		data, err := git(ctx, root, "show", ref+":"+filepath.ToSlash(rel))
+		return []byte(data), err
+	}, cfg.ConfigPaths[0], cfg.ConfigPaths[1:]...)
+	if err != nil {
+		log.Warn().Err(err).Str("ref", ref).Msg("failed to load the config at ref, running every item")
+		old = core.ConfigFile{}
+	}
+
+	return affectedItems(cfg, &old, changed), nil
+}
+
+// affectedItems compares cfg with the config at an earlier commit, old, given
+// the absolute paths of the files changed since.
+func affectedItems(cfg, old *core.ConfigFile, changed map[string]bool) []string {
+	varsChanged := !reflect.DeepEqual(cfg.Variables, old.Variables)
+	for _, vf := range cfg.Variables.VarFiles {
+		varsChanged = varsChanged || changed[vf.Path] || changed[vf.Path+".age"]
+	}
+	brewsChanged := !reflect.DeepEqual(cfg.Brews, old.Brews)
+
+	var names []string
+	for _, tmpl := range cfg.Templates {
+		prev := findItem(old.Templates, func(t core.Template) bool { return t.Name == tmpl.Name })
+		affected := prev == nil || !reflect.DeepEqual(*prev, tmpl) || varsChanged || changed[tmpl.Template]
+		if !affected && brewsChanged {
+			body, _ := generator.TemplateSource(tmpl.Template)
+			affected = strings.Contains(body, "brewConfig")
+		}
+		if affected {
+			names = append(names, tmpl.Name)
+		}
+	}
+
+	for _, script := range cfg.Exec.Scripts {
+		prev := findItem(old.Exec.Scripts, func(s core.Script) bool { return s.Path == script.Path })
+		if prev == nil || !reflect.DeepEqual(*prev, script) || changed[script.Path] || cfg.Exec.Shell != old.Exec.Shell {
+			names = append(names, filepath.Base(script.Path))
+		}
+	}
+
+	for _, svc := range cfg.Services {
+		prev := findItem(old.Services, func(s core.Service) bool { return s.Name == svc.Name })
+		if prev == nil || !reflect.DeepEqual(*prev, svc) || varsChanged || changed[svc.Template] {
+			names = append(names, svc.Name)
+		}
+	}
+
+	return names
+}
+
+func findItem[T any](items []T, match func(T) bool) *T {
+	if i := slices.IndexFunc(items, match); i >= 0 {
+		return &items[i]
+	}
+	return nil
+}
+
+// changedFiles returns the absolute paths of the files that differ between
+// ref and the work tree, including untracked files.
+func changedFiles(ctx context.Context, root, ref string) ([]string, error) {
+	diff, err := git(ctx, root, "diff", "--name-only", "-z", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := git(ctx, root, "ls-files", "-z", "--others", "--exclude-standard", "--full-name")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for file := range strings.SplitSeq(diff+untracked, "\x00") {
+		if file != "" {
+			files = append(files, filepath.Join(root, filepath.FromSlash(file)))
+		}
+	}
+	return files, nil
+}
+
+// replacePrefix replaces the directory prefix from in path with to, leaving
+// paths outside from unchanged.
+func replacePrefix(path, from, to string) string {
+	rel, err := filepath.Rel(from, path)
+	if err != nil || !filepath.IsLocal(rel) {
+		return path
+	}
+	return filepath.Join(to, rel)
+}
+
+// git runs a git command in dir and returns its stdout. Errors include
+// stderr.
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
+
+// sinceFilterExpr returns an expression matching items named in names.
+func sinceFilterExpr(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = strconv.Quote(name)
+	}
+	return "name in [" + strings.Join(quoted, ", ") + "]"
+}
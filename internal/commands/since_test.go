@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/hay-kot/mmdot/internal/core"
+)
+
+func Test_affectedItems(t *testing.T) {
+	base := func() core.ConfigFile {
+		return core.ConfigFile{
+			Variables: core.Variables{
+				Vars:     map[string]any{"email": "me@example.com"},
+				VarFiles: []core.VarFile{{Path: "/dots/vars.yml"}},
+			},
+			Templates: []core.Template{
+				{Name: "zshrc", Template: "/dots/zshrc.tmpl", Output: "/home/me/.zshrc"},
+				{Name: "gitconfig", Template: "email={{ .email }}", Output: "/home/me/.gitconfig"},
+			},
+			Exec: core.Exec{Scripts: []core.Script{
+				{Path: "/dots/scripts/setup.sh"},
+				{Path: "/dots/scripts/defaults.sh"},
+			}},
+			Services: []core.Service{{Name: "sync.service", Template: "/dots/sync.service"}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(cfg *core.ConfigFile)
+		changed []string
+		want    []string
+	}{
+		{name: "nothing changed"},
+		{
+			name:    "template file",
+			changed: []string{"/dots/zshrc.tmpl"},
+			want:    []string{"zshrc"},
+		},
+		{
+			name:    "script file",
+			changed: []string{"/dots/scripts/defaults.sh", "/dots/README.md"},
+			want:    []string{"defaults.sh"},
+		},
+		{
+			name:   "template definition",
+			modify: func(cfg *core.ConfigFile) { cfg.Templates[1].Output = "/home/me/.config/git/config" },
+			want:   []string{"gitconfig"},
+		},
+		{
+			name: "new script",
+			modify: func(cfg *core.ConfigFile) {
+				cfg.Exec.Scripts = append(cfg.Exec.Scripts, core.Script{Path: "/dots/scripts/new.sh"})
+			},
+			want: []string{"new.sh"},
+		},
+		{
+			name:   "variables",
+			modify: func(cfg *core.ConfigFile) { cfg.Variables.Vars["email"] = "work@example.com" },
+			want:   []string{"zshrc", "gitconfig", "sync.service"},
+		},
+		{
+			name:    "encrypted var file",
+			changed: []string{"/dots/vars.yml.age"},
+			want:    []string{"zshrc", "gitconfig", "sync.service"},
+		},
+		{
+			name:    "service template",
+			changed: []string{"/dots/sync.service"},
+			want:    []string{"sync.service"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old, cfg := base(), base()
+			if tt.modify != nil {
+				tt.modify(&cfg)
+			}
+			changed := map[string]bool{}
+			for _, file := range tt.changed {
+				changed[file] = true
+			}
+
+			got := affectedItems(&cfg, &old, changed)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("affectedItems() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("config at ref missing", func(t *testing.T) {
+		cfg := base()
+		got := affectedItems(&cfg, &core.ConfigFile{}, nil)
+		want := []string{"zshrc", "gitconfig", "setup.sh", "defaults.sh", "sync.service"}
+		if !slices.Equal(got, want) {
+			t.Errorf("affectedItems() = %q, want %q", got, want)
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hay-kot/mmdot/pkgs/atomicwrite"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultBrewCacheTTL is how long the installed package list is reused before
+// `brew list` is called again.
+const DefaultBrewCacheTTL = 15 * time.Minute
+
+const brewCacheFile = "brew-installed.json"
+
+// InstalledBrewsOptions controls caching of the installed package list.
+type InstalledBrewsOptions struct {
+	TTL     time.Duration // Maximum cache age; zero disables the cache
+	Refresh bool          // Ignore any cached list and fetch a fresh one
+}
+
+type brewCache struct {
+	FetchedAt time.Time `json:"fetched_at"`
+	Installed []string  `json:"installed"`
+}
+
+// LoadInstalledBrews returns the installed package list from the cache when it
+// is younger than opts.TTL, otherwise it calls `brew list` and caches the
+// result.
+func LoadInstalledBrews(ctx context.Context, opts InstalledBrewsOptions) ([]string, error) {
+	if installed, ok := cachedInstalledBrews(opts); ok {
+		return installed, nil
+	}
+
+	installed, err := ListInstalledBrews(ctx)
+	if err != nil {
+		return installed, err
+	}
+
+	if opts.TTL > 0 {
+		if err := saveBrewCache(installed); err != nil {
+			log.Debug().Err(err).Msg("failed to write brew cache")
+		}
+	}
+
+	return installed, nil
+}
+
+// InvalidateBrewCache removes the cached installed package list. Call it after
+// installing or removing packages.
+func InvalidateBrewCache() error {
+	path, err := brewCachePath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove brew cache: %w", err)
+	}
+
+	return nil
+}
+
+// ExpiredBrewCache returns the path of the installed package cache when it is
+// older than ttl or unreadable, and an empty string otherwise.
+func ExpiredBrewCache(ttl time.Duration) (string, error) {
+	path, err := brewCachePath()
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	if _, ok := cachedInstalledBrews(InstalledBrewsOptions{TTL: ttl}); ok {
+		return "", nil
+	}
+	return path, nil
+}
+
+func cachedInstalledBrews(opts InstalledBrewsOptions) ([]string, bool) {
+	if opts.Refresh || opts.TTL <= 0 {
+		return nil, false
+	}
+
+	path, err := brewCachePath()
+	if err != nil {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var cache brewCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		log.Debug().Err(err).Str("path", path).Msg("ignoring invalid brew cache")
+		return nil, false
+	}
+
+	age := time.Since(cache.FetchedAt)
+	if age < 0 || age > opts.TTL {
+		return nil, false
+	}
+
+	log.Debug().Dur("age", age).Msg("using cached installed brews")
+	return cache.Installed, true
+}
+
+func saveBrewCache(installed []string) error {
+	path, err := brewCachePath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(brewCache{FetchedAt: time.Now(), Installed: installed})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	return atomicwrite.WriteFile(path, data, 0o644)
+}
+
+func brewCachePath() (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, brewCacheFile), nil
+}
@@ -0,0 +1,46 @@
+package core
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBrewCache(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	opts := InstalledBrewsOptions{TTL: time.Minute}
+
+	if _, ok := cachedInstalledBrews(opts); ok {
+		t.Fatal("empty cache should miss")
+	}
+
+	if err := saveBrewCache([]string{"git", "jq"}); err != nil {
+		t.Fatalf("saveBrewCache() error = %v", err)
+	}
+
+	got, ok := cachedInstalledBrews(opts)
+	if !ok {
+		t.Fatal("expected cache hit")
+	}
+	if want := []string{"git", "jq"}; !slices.Equal(got, want) {
+		t.Errorf("cachedInstalledBrews() = %v, want %v", got, want)
+	}
+
+	if _, ok := cachedInstalledBrews(InstalledBrewsOptions{TTL: time.Minute, Refresh: true}); ok {
+		t.Error("refresh should bypass the cache")
+	}
+	if _, ok := cachedInstalledBrews(InstalledBrewsOptions{}); ok {
+		t.Error("zero TTL should disable the cache")
+	}
+
+	if err := InvalidateBrewCache(); err != nil {
+		t.Fatalf("InvalidateBrewCache() error = %v", err)
+	}
+	if _, ok := cachedInstalledBrews(opts); ok {
+		t.Error("invalidated cache should miss")
+	}
+	if err := InvalidateBrewCache(); err != nil {
+		t.Errorf("InvalidateBrewCache() on missing cache error = %v", err)
+	}
+}
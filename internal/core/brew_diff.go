@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"os/exec"
 	"slices"
@@ -22,10 +23,18 @@ type DiffResult struct {
 // - Present: Items in config and installed on the machine
 // - Absent: Items in config but not installed on the machine
 // - Extra: Items installed on the machine but not in the config (drift detection)
-func (c *Brews) Diff() (*DiffResult, error) {
+//
+// The installed package list is cached according to opts.
+func (c *Brews) Diff(ctx context.Context, opts InstalledBrewsOptions) (*DiffResult, error) {
 	// Get the list of brews installed on the machine with spinner UI
-	installedBrews := getInstalledBrews()
+	installedBrews := InstalledBrews(ctx, opts)
 
+	return c.DiffInstalled(installedBrews), nil
+}
+
+// DiffInstalled compares the brews in the Config against a pre-fetched list of
+// installed brews. See [Brews.Diff] for the categories returned.
+func (c *Brews) DiffInstalled(installedBrews []string) *DiffResult {
 	// Initialize the result structure
 	result := &DiffResult{
 		Present: []string{},
@@ -43,7 +52,7 @@ func (c *Brews) Diff() (*DiffResult, error) {
 	configMap := make(map[string]bool)
 
 	// Check each brew in the config against the installed brews
-	for _, brew := range slices.Concat(c.Brews, c.Casks) {
+	for _, brew := range PackageNames(slices.Concat(c.Brews, c.Casks)) {
 		// Add to config map for tracking
 		configMap[brew] = true
 
@@ -62,77 +71,101 @@ func (c *Brews) Diff() (*DiffResult, error) {
 		}
 	}
 
-	return result, nil
+	return result
 }
 
 var spinnerStyle = lipgloss.NewStyle().
 	Foreground(lipgloss.Color("10")) // Green
 
-func getInstalledBrews() []string {
-	var brews, casks []string
-	var brewsErr, casksErr error
+// InstalledBrews returns the installed package list, showing a spinner while
+// `brew list` runs on a cache miss (except in [CI] mode). Errors are printed
+// rather than returned.
+func InstalledBrews(ctx context.Context, opts InstalledBrewsOptions) []string {
+	if installed, ok := cachedInstalledBrews(opts); ok {
+		return installed
+	}
 
-	// Create an action function that will run both commands in parallel
-	action := func() {
-		var wg sync.WaitGroup
-		wg.Add(2)
-
-		// Get brews in a goroutine
-		go func() {
-			defer wg.Done()
-			cmd := exec.Command("brew", "list", "--full-name", "--installed-on-request")
-			output, err := cmd.Output()
-			brewsErr = err
-			if err == nil {
-				brewsOutput := strings.Split(strings.TrimSpace(string(output)), "\n")
-				for _, name := range brewsOutput {
-					if name != "" {
-						brews = append(brews, name)
-					}
-				}
-			}
-		}()
-
-		// Get casks in a goroutine
-		go func() {
-			defer wg.Done()
-			cmd := exec.Command("brew", "list", "--casks")
-			output, err := cmd.Output()
-			casksErr = err
-			if err == nil {
-				casksOutput := strings.Split(strings.TrimSpace(string(output)), "\n")
-				for _, name := range casksOutput {
-					if name != "" {
-						casks = append(casks, name)
-					}
-				}
-			}
-		}()
-
-		// Wait for both goroutines to complete
-		wg.Wait()
+	if CI {
+		brews, err := LoadInstalledBrews(ctx, InstalledBrewsOptions{TTL: opts.TTL, Refresh: true})
+		if err != nil {
+			fmt.Printf("%v\n", err)
+		}
+		return brews
 	}
 
+	var brews []string
+	var brewsErr error
+
 	// Run the action with a spinner
 	spin := spinner.New().
 		Type(spinner.Line).
 		Style(spinnerStyle).
 		Title(" Fetching installed brews and casks").
-		Action(action)
+		Action(func() { brews, brewsErr = LoadInstalledBrews(ctx, InstalledBrewsOptions{TTL: opts.TTL, Refresh: true}) })
 
 	if err := spin.Run(); err != nil {
 		fmt.Printf("Error with spinner: %v\n", err)
 	}
 
-	// Handle command errors
 	if brewsErr != nil {
-		fmt.Printf("Error getting installed brews: %v\n", brewsErr)
+		fmt.Printf("%v\n", brewsErr)
 	}
-	if casksErr != nil {
-		fmt.Printf("Error getting installed casks: %v\n", casksErr)
+
+	return brews
+}
+
+// ListInstalledBrews returns the formulae installed on request and the casks
+// installed on the machine. Both lists are fetched in parallel; results from a
+// successful command are returned even if the other fails. Both commands are
+// killed when ctx is done.
+func ListInstalledBrews(ctx context.Context) ([]string, error) {
+	var brews, casks []string
+	var brewsErr, casksErr error
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	// Get brews in a goroutine
+	go func() {
+		defer wg.Done()
+		brews, brewsErr = brewList(ctx, "--full-name", "--installed-on-request")
+	}()
+
+	// Get casks in a goroutine
+	go func() {
+		defer wg.Done()
+		casks, casksErr = brewList(ctx, "--casks")
+	}()
+
+	// Wait for both goroutines to complete
+	wg.Wait()
+
+	var err error
+	switch {
+	case brewsErr != nil && casksErr != nil:
+		err = fmt.Errorf("error getting installed brews: %w; error getting installed casks: %w", brewsErr, casksErr)
+	case brewsErr != nil:
+		err = fmt.Errorf("error getting installed brews: %w", brewsErr)
+	case casksErr != nil:
+		err = fmt.Errorf("error getting installed casks: %w", casksErr)
 	}
 
 	// Combine results
-	result := append(brews, casks...)
-	return result
+	return append(brews, casks...), err
+}
+
+func brewList(ctx context.Context, args ...string) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "brew", append([]string{"list"}, args...)...)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for name := range strings.SplitSeq(strings.TrimSpace(string(output)), "\n") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
 }
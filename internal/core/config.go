@@ -1,15 +1,22 @@
 package core
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"filippo.io/age"
 	"github.com/goccy/go-yaml"
 	"github.com/hay-kot/mmdot/pkgs/fcrypt"
+	"github.com/hay-kot/mmdot/pkgs/printer"
 	"github.com/rs/zerolog/log"
 )
 
@@ -19,34 +26,158 @@ import (
 const ConfigVersion = 2
 
 type ConfigFile struct {
-	Version   int               `yaml:"version"`
-	Macros    map[string]string `yaml:"macros"`
-	Exec      Exec              `yaml:"exec"`
-	Age       Age               `yaml:"age"`
-	Brews     ConfigMap         `yaml:"brews"`
-	Variables Variables         `yaml:"variables"`
-	Templates []Template        `yaml:"templates"`
-	ConfigDir string            `yaml:"-"` // Directory containing the config file (not serialized)
+	Version     int               `yaml:"version"`
+	Macros      map[string]string `yaml:"macros"`
+	Aliases     Aliases           `yaml:"aliases"`
+	Run         Run               `yaml:"run"`
+	Exec        Exec              `yaml:"exec"`
+	Age         Age               `yaml:"age"`
+	Brews       ConfigMap         `yaml:"brews"`
+	Variables   Variables         `yaml:"variables"`
+	Templates   []Template        `yaml:"templates"`
+	Services    []Service         `yaml:"services"`
+	Binaries    []Binary          `yaml:"binaries"`
+	TrustedKeys []TrustedKey      `yaml:"trusted_keys"`
+	Fonts       []Font            `yaml:"fonts"`
+	Editors     []Editor          `yaml:"editors"`
+	MacOS       MacOS             `yaml:"macos"`
+	Git         Git               `yaml:"git"`
+	GPG         GPG               `yaml:"gpg"`
+	Shell       Shell             `yaml:"shell"`
+	Repos       []GitRepo         `yaml:"repos"`
+	Notify      []Notification    `yaml:"notifications"`
+	Metrics     Metrics           `yaml:"metrics"`
+	Diff        Diff              `yaml:"diff"`
+	ConfigDir   string            `yaml:"-"` // Directory containing the config file (not serialized)
+	// ConfigPaths are the absolute paths of the base config and its
+	// overlays, in merge order (not serialized).
+	ConfigPaths []string `yaml:"-"`
+}
+
+// Diff configures how diffs are displayed.
+type Diff struct {
+	// External pipes unified diffs to Command instead of using the built-in
+	// renderer. It only applies when stdout is a terminal.
+	External bool   `yaml:"external"`
+	Command  string `yaml:"command"` // e.g. "delta --side-by-side"
+}
+
+// Run configures how `mmdot run` sequences templates, scripts, and services.
+type Run struct {
+	// Order lists runner types ("template", "script", "service") in the order
+	// they are executed within each stage. Types left out run after the
+	// listed ones. Defaults to templates, then scripts, then services.
+	Order []string `yaml:"order"`
+
+	// DefaultExpr is used by `mmdot run` when no expression or tag flags are
+	// given, instead of prompting interactively.
+	DefaultExpr string `yaml:"default_expr"`
+
+	// Before, After, and AfterFailure are shell commands run around every
+	// `mmdot run`. After runs only when the run succeeds, AfterFailure only
+	// when it or a Before command fails.
+	Before       []string `yaml:"before"`
+	After        []string `yaml:"after"`
+	AfterFailure []string `yaml:"after_failure"`
+}
+
+// Stage groups items into phases of a run. All pre items run before any main
+// items, and all main items run before any post items.
+type Stage string
+
+const (
+	StagePre  Stage = "pre"
+	StageMain Stage = "main"
+	StagePost Stage = "post"
+)
+
+// Stages is the ordered list of stages executed during a run.
+var Stages = []Stage{StagePre, StageMain, StagePost}
+
+func (s Stage) Validate() error {
+	switch s {
+	case "", StagePre, StageMain, StagePost:
+		return nil
+	}
+	return fmt.Errorf("invalid stage %q (expected %q, %q, or %q)", s, StagePre, StageMain, StagePost)
+}
+
+// OrDefault returns the stage, defaulting to [StageMain] when unset.
+func (s Stage) OrDefault() Stage {
+	if s == "" {
+		return StageMain
+	}
+	return s
 }
 
 // ExecConfig represents the shell execution configuration
 type Exec struct {
 	Shell   string   `yaml:"shell"`
 	Scripts []Script `yaml:"scripts"`
+
+	// CleanEnv runs scripts with only HOME, PATH, and the variables in
+	// AllowEnv instead of the caller's whole environment.
+	CleanEnv bool     `yaml:"clean_env"`
+	AllowEnv []string `yaml:"allow_env"` // Names or globs such as LC_*, used with clean_env
 }
 
 // Script represents a single executable script with associated tags
 type Script struct {
-	Path string   `yaml:"path"`
-	Tags []string `yaml:"tags"`
+	Path  string   `yaml:"path"`
+	Tags  []string `yaml:"tags"`
+	Stage Stage    `yaml:"stage"` // pre, main, or post (default: main)
+
+	// Privileged scripts run as root through sudo -E or doas.
+	Privileged bool `yaml:"privileged"`
+
+	// MinInterval skips the script when it last succeeded less than this
+	// long ago, e.g. 24h for maintenance such as brew update.
+	MinInterval time.Duration `yaml:"min_interval"`
+
+	// Manages lists the files and directories the script writes. They are
+	// hashed after each successful run so status can report when they were
+	// changed outside mmdot.
+	Manages []string `yaml:"manages"`
+}
+
+// SetupEnv loads the config at cfgpath and changes the working directory to
+// the config directory. Use [LoadConfig] to load without changing directory.
+func SetupEnv(cfgpath string, overlays ...string) (ConfigFile, error) {
+	cfg, err := LoadConfig(cfgpath, overlays...)
+	if err != nil {
+		return cfg, err
+	}
+
+	if err := os.Chdir(cfg.ConfigDir); err != nil {
+		return cfg, err
+	}
+
+	log.Debug().Str("cwd", cfg.ConfigDir).Msg("setting working directory to config dir")
+
+	return cfg, nil
+}
+
+// LoadConfig reads and validates the config at cfgpath, resolving every path
+// in it relative to the config directory. It does not depend on or change the
+// working directory.
+//
+// Overlays are merged on top of cfgpath in order; see [stackConfigs] for the
+// merge rules. Relative paths in each overlay are resolved against the
+// overlay's own directory.
+func LoadConfig(cfgpath string, overlays ...string) (ConfigFile, error) {
+	return LoadConfigFrom(os.ReadFile, cfgpath, overlays...)
 }
 
-func SetupEnv(cfgpath string) (ConfigFile, error) {
+// LoadConfigFrom is [LoadConfig] with each config layer read by read rather
+// than from disk, e.g. from an earlier git commit. Paths in the config are
+// resolved as if the layers were on disk.
+func LoadConfigFrom(read func(path string) ([]byte, error), cfgpath string, overlays ...string) (ConfigFile, error) {
 	cfg := ConfigFile{
 		Age:       Age{},
 		Variables: Variables{},
 	}
-	absolutePath, err := filepath.Abs(cfgpath)
+	// The config path itself may use '~' or be relative to the working directory
+	absolutePath, err := PathResolver{}.Resolve(cfgpath)
 	if err != nil {
 		return cfg, err
 	}
@@ -54,16 +185,41 @@ func SetupEnv(cfgpath string) (ConfigFile, error) {
 	configDir := filepath.Dir(absolutePath)
 	cfg.ConfigDir = configDir
 
-	err = os.Chdir(configDir)
-	if err != nil {
-		return cfg, err
+	paths := []string{absolutePath}
+	for _, overlay := range overlays {
+		resolved, err := PathResolver{}.Resolve(overlay)
+		if err != nil {
+			return cfg, err
+		}
+		paths = append(paths, resolved)
 	}
+	cfg.ConfigPaths = paths
 
-	log.Debug().Str("cwd", configDir).Msg("setting working directory to config dir")
+	layers := make([][]byte, len(paths))
+	for i, path := range paths {
+		layers[i], err = read(path)
+		if err != nil {
+			return cfg, err
+		}
+	}
 
-	data, err := os.ReadFile(cfgpath)
+	layers, err = decryptConfigLayers(paths, layers)
 	if err != nil {
-		return cfg, err
+		return cfg, fmt.Errorf("failed to decrypt %s values: %w", AgeTag, err)
+	}
+
+	for i, data := range layers {
+		if err := validateSecretRefs(data); err != nil {
+			return cfg, fmt.Errorf("%s: %w", paths[i], err)
+		}
+	}
+
+	data := layers[0]
+	if len(layers) > 1 {
+		data, err = stackConfigs(paths, layers)
+		if err != nil {
+			return cfg, err
+		}
 	}
 
 	err = yaml.Unmarshal(data, &cfg)
@@ -76,13 +232,15 @@ func SetupEnv(cfgpath string) (ConfigFile, error) {
 		cfg.Version = 1
 	}
 
-	// Create path resolver and resolve all paths in config
-	pr := PathResolver{configDir: configDir}
-	err = cfg.resolvePaths(pr)
+	// Paths from overlays are already absolute; the rest are relative to the
+	// base config
+	err = cfg.resolvePaths(PathResolver{configDir: configDir})
 	if err != nil {
 		return cfg, err
 	}
 
+	cfg.Variables.applyOS(runtime.GOOS)
+
 	return cfg, nil
 }
 
@@ -97,6 +255,40 @@ func (c *ConfigFile) resolvePaths(pr PathResolver) error {
 		c.Age.IdentityFile = resolved
 	}
 
+	for i, path := range c.Age.IdentityFiles {
+		resolved, err := pr.Resolve(path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve age identity file path: %w", err)
+		}
+		c.Age.IdentityFiles[i] = resolved
+	}
+
+	if c.Age.RecipientsFile != "" {
+		resolved, err := pr.Resolve(c.Age.RecipientsFile)
+		if err != nil {
+			return fmt.Errorf("failed to resolve age recipients file path: %w", err)
+		}
+		c.Age.RecipientsFile = resolved
+	}
+
+	if c.Age.RecipientsDir != "" {
+		resolved, err := pr.Resolve(c.Age.RecipientsDir)
+		if err != nil {
+			return fmt.Errorf("failed to resolve age recipients directory path: %w", err)
+		}
+		c.Age.RecipientsDir = resolved
+	}
+
+	for _, p := range c.Variables.Prompts {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Aliases.Validate(); err != nil {
+		return err
+	}
+
 	// Resolve variable file paths
 	for i := range c.Variables.VarFiles {
 		resolved, err := pr.Resolve(c.Variables.VarFiles[i].Path)
@@ -106,8 +298,33 @@ func (c *ConfigFile) resolvePaths(pr PathResolver) error {
 		c.Variables.VarFiles[i].Path = resolved
 	}
 
+	if err := c.Variables.validateByOS(); err != nil {
+		return err
+	}
+	for goos, overlay := range c.Variables.ByOS {
+		for i := range overlay.VarFiles {
+			resolved, err := pr.Resolve(overlay.VarFiles[i].Path)
+			if err != nil {
+				return fmt.Errorf("failed to resolve var file path: %w", err)
+			}
+			overlay.VarFiles[i].Path = resolved
+		}
+		c.Variables.ByOS[goos] = overlay
+	}
+
 	// Resolve template paths (template input and output)
 	for i := range c.Templates {
+		if c.Templates[i].CreateOnly {
+			c.Templates[i].SkipIfExists = true
+		}
+		if err := c.Templates[i].Stage.Validate(); err != nil {
+			return fmt.Errorf("template %s: %w", c.Templates[i].Name, err)
+		}
+		for _, rv := range c.Templates[i].RequiresVars {
+			if err := rv.Validate(); err != nil {
+				return fmt.Errorf("template %s: %w", c.Templates[i].Name, err)
+			}
+		}
 
 		if c.Templates[i].Template != "" && !strings.Contains(c.Templates[i].Template, "{{") {
 			resolved, err := pr.Resolve(c.Templates[i].Template)
@@ -116,6 +333,19 @@ func (c *ConfigFile) resolvePaths(pr PathResolver) error {
 			}
 			c.Templates[i].Template = resolved
 		}
+		for j := range c.Templates[i].Tests {
+			test := &c.Templates[i].Tests[j]
+			if err := test.Validate(); err != nil {
+				return fmt.Errorf("template %s: %w", c.Templates[i].Name, err)
+			}
+			if test.Expected != "" {
+				resolved, err := pr.Resolve(test.Expected)
+				if err != nil {
+					return fmt.Errorf("failed to resolve template test expected path: %w", err)
+				}
+				test.Expected = resolved
+			}
+		}
 		if c.Templates[i].Output != "" {
 			resolved, err := pr.Resolve(c.Templates[i].Output)
 			if err != nil {
@@ -125,6 +355,18 @@ func (c *ConfigFile) resolvePaths(pr PathResolver) error {
 		}
 	}
 
+	// Templates generated from the git, gpg, and shell sections are added
+	// after user templates are resolved since their sources are never paths
+	if err := c.resolveGit(pr); err != nil {
+		return err
+	}
+	if err := c.resolveGPG(pr); err != nil {
+		return err
+	}
+	if err := c.resolveShell(pr); err != nil {
+		return err
+	}
+
 	// Validate and resolve age file paths
 	for i := range c.Age.Files {
 		if err := c.Age.Files[i].Validate(); err != nil {
@@ -144,13 +386,135 @@ func (c *ConfigFile) resolvePaths(pr PathResolver) error {
 		c.Age.Files[i].Dest = resolved
 	}
 
+	// Validate services and resolve template file paths
+	for i := range c.Services {
+		if err := c.Services[i].Validate(); err != nil {
+			return err
+		}
+
+		if !strings.Contains(c.Services[i].Template, "{{") && !strings.Contains(c.Services[i].Template, "\n") {
+			resolved, err := pr.Resolve(c.Services[i].Template)
+			if err != nil {
+				return fmt.Errorf("failed to resolve service template path: %w", err)
+			}
+			c.Services[i].Template = resolved
+		}
+	}
+
+	// Validate trusted keys, then binaries, and resolve install paths
+	for i, k := range c.TrustedKeys {
+		if err := k.Validate(); err != nil {
+			return err
+		}
+		if slices.ContainsFunc(c.TrustedKeys[:i], func(o TrustedKey) bool { return o.Name == k.Name }) {
+			return fmt.Errorf("trusted key %s: defined more than once", k.Name)
+		}
+	}
+	for i := range c.Binaries {
+		if err := c.Binaries[i].Validate(); err != nil {
+			return err
+		}
+		if sig := c.Binaries[i].Signature; sig != nil {
+			if _, ok := c.TrustedKey(sig.Key); !ok {
+				return fmt.Errorf("binary %s: signature key %q is not in trusted_keys", c.Binaries[i].Name, sig.Key)
+			}
+		}
+
+		if c.Binaries[i].Install == "" {
+			c.Binaries[i].Install = filepath.Join("~", ".local", "bin", c.Binaries[i].Name)
+		}
+		resolved, err := pr.Resolve(c.Binaries[i].Install)
+		if err != nil {
+			return fmt.Errorf("failed to resolve binary install path: %w", err)
+		}
+		c.Binaries[i].Install = resolved
+	}
+
+	// Validate fonts
+	for i := range c.Fonts {
+		if err := c.Fonts[i].Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate editors and resolve settings paths
+	for i := range c.Editors {
+		e := &c.Editors[i]
+		if err := e.Validate(); err != nil {
+			return err
+		}
+
+		if e.Settings != "" && !strings.ContainsAny(e.Settings, "\n{") {
+			resolved, err := pr.Resolve(e.Settings)
+			if err != nil {
+				return fmt.Errorf("failed to resolve editor settings path: %w", err)
+			}
+			e.Settings = resolved
+		}
+
+		if e.SettingsPath == "" {
+			path, err := e.DefaultSettingsPath()
+			if err != nil {
+				return err
+			}
+			e.SettingsPath = path
+		} else {
+			resolved, err := pr.Resolve(e.SettingsPath)
+			if err != nil {
+				return fmt.Errorf("failed to resolve editor settings_path: %w", err)
+			}
+			e.SettingsPath = resolved
+		}
+	}
+
+	if err := c.resolveRepos(pr); err != nil {
+		return err
+	}
+
+	if err := c.MacOS.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Brews.Validate(); err != nil {
+		return err
+	}
+
+	// Validate notification targets
+	for i := range c.Notify {
+		if err := c.Notify[i].Validate(); err != nil {
+			return err
+		}
+	}
+	if err := c.resolveMetrics(pr); err != nil {
+		return err
+	}
+
+	if err := c.Exec.Validate(); err != nil {
+		return err
+	}
+
 	// Resolve exec script paths
 	for i := range c.Exec.Scripts {
+		if err := c.Exec.Scripts[i].Stage.Validate(); err != nil {
+			return fmt.Errorf("script %s: %w", c.Exec.Scripts[i].Path, err)
+		}
+		if c.Exec.Scripts[i].MinInterval < 0 {
+			return fmt.Errorf("script %s: min_interval must not be negative", c.Exec.Scripts[i].Path)
+		}
+
 		resolved, err := pr.Resolve(c.Exec.Scripts[i].Path)
 		if err != nil {
 			return fmt.Errorf("failed to resolve exec script path: %w", err)
 		}
 		c.Exec.Scripts[i].Path = resolved
+
+		for j, managed := range c.Exec.Scripts[i].Manages {
+			resolved, err := pr.Resolve(managed)
+			if err != nil {
+				return fmt.Errorf("failed to resolve script manages path: %w", err)
+			}
+			c.Exec.Scripts[i].Manages[j] = resolved
+		}
 	}
 
 	return nil
@@ -160,8 +524,21 @@ func (c *ConfigFile) resolvePaths(pr PathResolver) error {
 func (c ConfigFile) EncryptedFiles() []string {
 	files := []string{}
 
-	for _, vf := range c.Variables.VarFiles {
-		if vf.IsVault {
+	for _, vf := range c.Variables.AllVarFiles() {
+		if vf.IsVault && !vf.Partial {
+			files = append(files, vf.Path)
+		}
+	}
+
+	return files
+}
+
+// PartialVaultFiles returns the var files whose values are encrypted in place.
+func (c ConfigFile) PartialVaultFiles() []string {
+	files := []string{}
+
+	for _, vf := range c.Variables.AllVarFiles() {
+		if vf.Partial {
 			files = append(files, vf.Path)
 		}
 	}
@@ -170,9 +547,10 @@ func (c ConfigFile) EncryptedFiles() []string {
 }
 
 type AgeFile struct {
-	Src         string `yaml:"src"`
-	Dest        string `yaml:"dest"`
-	Permissions string `yaml:"perm"`
+	Src         string   `yaml:"src"`
+	Dest        string   `yaml:"dest"`
+	Permissions string   `yaml:"perm"`
+	Recipients  []string `yaml:"recipients"` // Overrides age.recipients for this file
 }
 
 func (af AgeFile) Validate() error {
@@ -203,16 +581,151 @@ func ParseOctalPermissions(s string) (os.FileMode, error) {
 }
 
 type Age struct {
-	Recipients   []string  `yaml:"recipients"`
-	IdentityFile string    `yaml:"identity_file"`
-	Files        []AgeFile `yaml:"files"`
+	Recipients     []string  `yaml:"recipients"`
+	RecipientsFile string    `yaml:"recipients_file"` // one public key per line
+	RecipientsDir  string    `yaml:"recipients_dir"`  // one file of public keys per owner
+	IdentityFile   string    `yaml:"identity_file"`
+	IdentityFiles  []string  `yaml:"identity_files"` // tried after identity_file, skipped when missing
+	Files          []AgeFile `yaml:"files"`
 }
 
+// RecipientsFor returns the recipients a file is encrypted to: its own list
+// when set, otherwise the global age.recipients together with the keys in
+// age.recipients_file and age.recipients_dir.
+func (a Age) RecipientsFor(override []string) ([]string, error) {
+	if len(override) > 0 {
+		return override, nil
+	}
+
+	keys := slices.Clone(a.Recipients)
+	if a.RecipientsFile != "" {
+		fileKeys, err := readRecipientsFile(a.RecipientsFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, fileKeys...)
+	}
+
+	if a.RecipientsDir != "" {
+		entries, err := os.ReadDir(a.RecipientsDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read recipients directory: %w", err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			fileKeys, err := readRecipientsFile(filepath.Join(a.RecipientsDir, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, fileKeys...)
+		}
+	}
+
+	// the same key may be listed in more than one place
+	slices.Sort(keys)
+	return slices.Compact(keys), nil
+}
+
+// readRecipientsFile returns the public keys in a recipients file, one per
+// line, skipping comments and empty lines.
+func readRecipientsFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recipients file: %w", err)
+	}
+
+	var keys []string
+	for line := range strings.SplitSeq(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			keys = append(keys, line)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no public keys found in recipients file %s", path)
+	}
+	return keys, nil
+}
+
+// IdentityPaths returns age.identity_file followed by age.identity_files.
+func (a Age) IdentityPaths() []string {
+	var paths []string
+	if a.IdentityFile != "" {
+		paths = append(paths, a.IdentityFile)
+	}
+	for _, path := range a.IdentityFiles {
+		if !slices.Contains(paths, path) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// ReadIdentity returns the configured identity. With more than one identity
+// file it returns an [IdentityChain] of those present on this machine.
 func (a Age) ReadIdentity() (age.Identity, error) {
+	paths := a.IdentityPaths()
+	if len(paths) > 1 {
+		identities, err := a.ReadIdentities()
+		if err != nil {
+			return nil, err
+		}
+		return NewIdentityChain(identities), nil
+	}
+	if len(paths) == 0 {
+		return nil, errNoIdentity
+	}
+	return readIdentityFile(paths[0])
+}
+
+// ReadIdentities returns every configured identity whose file exists, in the
+// order of [Age.IdentityPaths]. It fails when none can be read.
+func (a Age) ReadIdentities() ([]NamedIdentity, error) {
+	paths := a.IdentityPaths()
+	if len(paths) == 0 {
+		return nil, errNoIdentity
+	}
+
+	var identities []NamedIdentity
+	var firstErr error
+	for _, path := range paths {
+		identity, err := readIdentityFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			log.Debug().Str("path", path).Msg("identity file not found, skipping")
+			firstErr = cmp.Or(firstErr, err)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, NamedIdentity{Path: path, Identity: identity})
+	}
+	if len(identities) == 0 {
+		return nil, firstErr
+	}
+	return identities, nil
+}
+
+var errNoIdentity = printer.WithTitle("No age identity configured",
+	errors.New("age.identity_file is not set"),
+	"set age.identity_file in mmdot.yml to the path of your private key",
+	"create a key with `age-keygen -o ~/.config/mmdot/key.txt` if you do not have one",
+)
+
+func readIdentityFile(path string) (age.Identity, error) {
 	// Read the private key from the identity file
-	identityData, err := os.ReadFile(a.IdentityFile)
+	identityData, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, printer.WithTitle("Identity file not found",
+			fmt.Errorf("failed to read identity file %s: %w", path, err),
+			"check age.identity_file in mmdot.yml points at your private key",
+			fmt.Sprintf("create a key with `age-keygen -o %s`", path),
+		)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to read identity file %s: %w", a.IdentityFile, err)
+		return nil, fmt.Errorf("failed to read identity file %s: %w", path, err)
 	}
 
 	// Parse the identity file, skipping comments and empty lines
@@ -226,7 +739,7 @@ func (a Age) ReadIdentity() (age.Identity, error) {
 	}
 
 	if keyLine == "" {
-		return nil, fmt.Errorf("no valid key found in identity file %s", a.IdentityFile)
+		return nil, fmt.Errorf("no valid key found in identity file %s", path)
 	}
 
 	identity, err := fcrypt.LoadPrivateKey(keyLine)
@@ -240,11 +753,23 @@ func (a Age) ReadIdentity() (age.Identity, error) {
 type Variables struct {
 	VarFiles []VarFile      `yaml:"var_files"`
 	Vars     map[string]any `yaml:"vars"`
+	Prompts  []Prompt       `yaml:"prompts"` // Asked at run time when not set elsewhere
+
+	// ByOS is merged over Vars and VarFiles on the matching runtime.GOOS.
+	ByOS map[string]OSVariables `yaml:"by_os"`
 }
 
 type VarFile struct {
 	Path    string
 	IsVault bool
+	// Partial vault files stay plaintext YAML with only their values
+	// encrypted. See [fcrypt.EncryptValues].
+	Partial bool
+	// Optional vault files are skipped with a warning when they cannot be
+	// decrypted, e.g. on machines without the identity for them.
+	Optional bool
+	// Recipients overrides age.recipients when encrypting this file.
+	Recipients []string
 }
 
 func (vf *VarFile) UnmarshalYAML(unmarshal func(any) error) error {
@@ -255,8 +780,10 @@ func (vf *VarFile) UnmarshalYAML(unmarshal func(any) error) error {
 		if idx := strings.Index(path, "?"); idx != -1 {
 			vf.Path = path[:idx]
 			query := path[idx+1:]
-			// Check for vault=true
+			// Check for vault=true and optional=true
 			vf.IsVault = strings.Contains(query, "vault=true")
+			vf.Partial = strings.Contains(query, "partial=true")
+			vf.Optional = strings.Contains(query, "optional=true")
 		} else {
 			vf.Path = path
 			vf.IsVault = false
@@ -266,14 +793,20 @@ func (vf *VarFile) UnmarshalYAML(unmarshal func(any) error) error {
 
 	// Fall back to struct format
 	var v struct {
-		Path    string `yaml:"path"`
-		IsVault bool   `yaml:"vault"`
+		Path       string   `yaml:"path"`
+		IsVault    bool     `yaml:"vault"`
+		Partial    bool     `yaml:"partial"`
+		Optional   bool     `yaml:"optional"`
+		Recipients []string `yaml:"recipients"`
 	}
 	if err := unmarshal(&v); err != nil {
 		return err
 	}
 	vf.Path = v.Path
 	vf.IsVault = v.IsVault
+	vf.Partial = v.Partial
+	vf.Optional = v.Optional
+	vf.Recipients = v.Recipients
 	return nil
 }
 
@@ -282,9 +815,39 @@ type Template struct {
 	Tags        []string       `yaml:"tags"`
 	Template    string         `yaml:"template"` // File or Template
 	Output      string         `yaml:"output"`
-	Permissions string         `yaml:"perm"` // Must be valid permissions
+	Permissions string         `yaml:"perm"`  // Must be valid permissions
+	Owner       string         `yaml:"owner"` // User name or uid the output is given, requires root to change
+	Group       string         `yaml:"group"` // Group name or gid the output is given
 	Vars        map[string]any `yaml:"vars"`
-	Trim        *bool          `yaml:"trim"` // Trim leading/trailing whitespace from output (default: true)
+	Trim        *bool          `yaml:"trim"`  // Trim leading/trailing whitespace from output (default: true)
+	Stage       Stage          `yaml:"stage"` // pre, main, or post (default: main)
+	Tests       []TemplateTest `yaml:"tests"` // Fixtures checked by `mmdot templates test`
+
+	SkipIfExists bool   `yaml:"skip_if_exists"` // Only create the output, never overwrite it
+	CreateOnly   bool   `yaml:"create_only"`    // Alias of skip_if_exists
+	NoClobber    bool   `yaml:"no_clobber"`     // Never overwrite an existing output without --force
+	SkipIf       string `yaml:"skip_if"`        // Shell command; exit status 0 skips rendering
+
+	RequiresVars []RequiredVar `yaml:"requires_vars"` // Checked before any template renders
+}
+
+// TemplateTest renders a template against fixture variables and compares the
+// result to a golden file and/or expected snippets.
+type TemplateTest struct {
+	Name     string         `yaml:"name"`
+	Vars     map[string]any `yaml:"vars"`     // Fixture variables, highest precedence
+	Expected string         `yaml:"expected"` // Golden file containing the full expected output
+	Contains []string       `yaml:"contains"` // Snippets that must appear in the output
+}
+
+func (tt TemplateTest) Validate() error {
+	if tt.Name == "" {
+		return fmt.Errorf("template test: name is required")
+	}
+	if tt.Expected == "" && len(tt.Contains) == 0 {
+		return fmt.Errorf("template test %s: expected or contains is required", tt.Name)
+	}
+	return nil
 }
 
 func (t Template) ShouldTrim() bool {
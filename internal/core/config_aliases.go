@@ -0,0 +1,101 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Aliases maps short names to mmdot arguments, run with `mmdot x <name>`:
+//
+//	aliases:
+//	  work: run '@work !slow'
+type Aliases map[string]string
+
+func (a Aliases) Validate() error {
+	for name, value := range a {
+		if name == "" || strings.ContainsFunc(name, unicode.IsSpace) || strings.HasPrefix(name, "-") {
+			return fmt.Errorf("aliases: invalid name %q", name)
+		}
+		args, err := splitArgs(value)
+		if err != nil {
+			return fmt.Errorf("aliases.%s: %w", name, err)
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("aliases.%s: is empty", name)
+		}
+		if args[0] == "x" {
+			return fmt.Errorf("aliases.%s: aliases cannot run other aliases", name)
+		}
+	}
+	return nil
+}
+
+// Args returns the arguments the alias name expands to, split like a shell
+// command line.
+func (a Aliases) Args(name string) ([]string, bool) {
+	value, ok := a[name]
+	if !ok {
+		return nil, false
+	}
+	args, _ := splitArgs(value) // checked by Validate
+	return args, true
+}
+
+// splitArgs splits s into words on whitespace. Single quotes keep their
+// contents literally; within double quotes and outside quotes a backslash
+// escapes the next character.
+func splitArgs(s string) ([]string, error) {
+	var (
+		args    []string
+		word    strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inWord = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case unicode.IsSpace(r):
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, errors.New("trailing backslash")
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	return args, nil
+}
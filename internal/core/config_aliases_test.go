@@ -0,0 +1,59 @@
+package core
+
+import (
+	"slices"
+	"testing"
+)
+
+func Test_splitArgs(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{in: "run '@work !slow'", want: []string{"run", "@work !slow"}},
+		{in: `run --only "a b" c`, want: []string{"run", "--only", "a b", "c"}},
+		{in: `  status   --exit-code `, want: []string{"status", "--exit-code"}},
+		{in: `run a\ b`, want: []string{"run", "a b"}},
+		{in: `run ''`, want: []string{"run", ""}},
+		{in: `run 'it''s'`, want: []string{"run", "its"}},
+		{in: `run "say \"hi\""`, want: []string{"run", `say "hi"`}},
+		{in: "", want: nil},
+		{in: "run 'open", wantErr: true},
+		{in: `run \`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := splitArgs(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("splitArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAliases_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		aliases Aliases
+		wantErr bool
+	}{
+		{name: "valid", aliases: Aliases{"work": "run '@work !slow'"}},
+		{name: "empty value", aliases: Aliases{"work": "  "}, wantErr: true},
+		{name: "space in name", aliases: Aliases{"my work": "run"}, wantErr: true},
+		{name: "unterminated quote", aliases: Aliases{"work": "run '@work"}, wantErr: true},
+		{name: "nested alias", aliases: Aliases{"work": "x other"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.aliases.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
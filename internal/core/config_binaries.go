@@ -0,0 +1,177 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/hay-kot/mmdot/pkgs/sigverify"
+)
+
+// Binary declares a tool installed from a GitHub release asset, for tools
+// without a Homebrew formula on the machine.
+type Binary struct {
+	Name string `yaml:"name"` // Binary name, also the default symlink name
+	Repo string `yaml:"repo"` // GitHub repository as owner/name
+
+	// Version is the release tag to install, or "latest" for the newest
+	// non-prerelease.
+	Version string `yaml:"version"`
+
+	// Asset is a glob matched against the release asset names. It may use
+	// {{ .OS }}, {{ .Arch }}, {{ .Version }} (tag without a leading v), and
+	// {{ .Tag }}. When empty, the asset naming the current OS and
+	// architecture is used.
+	Asset string `yaml:"asset"`
+
+	// Checksum is the expected sha256 of the asset. ChecksumAsset names a
+	// release asset in sha256sum format (e.g. "checksums.txt") used when
+	// Checksum is not set.
+	Checksum      string `yaml:"checksum"`
+	ChecksumAsset string `yaml:"checksum_asset"`
+
+	// Signature verifies the asset, or its checksum asset, against a
+	// trusted key.
+	Signature *BinarySignature `yaml:"signature"`
+
+	// Path is the binary's path inside an archive asset (default: Name).
+	Path string `yaml:"path"`
+
+	// Install is where the binary is symlinked (default: ~/.local/bin/<name>).
+	Install string `yaml:"install"`
+}
+
+// BinaryLatest selects the newest release of a binary.
+const BinaryLatest = "latest"
+
+var (
+	binaryRepoRe = regexp.MustCompile(`^[\w.-]+/[\w.-]+$`)
+	sha256HexRe  = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+)
+
+func (b Binary) Validate() error {
+	if b.Name == "" {
+		return fmt.Errorf("binary: name is required")
+	}
+	if strings.ContainsAny(b.Name, `/\`) {
+		return fmt.Errorf("binary %s: name must not contain path separators", b.Name)
+	}
+	if !binaryRepoRe.MatchString(b.Repo) {
+		return fmt.Errorf("binary %s: repo must be owner/name, got %q", b.Name, b.Repo)
+	}
+	if b.Version == "" {
+		return fmt.Errorf("binary %s: version is required (a release tag or %q)", b.Name, BinaryLatest)
+	}
+	if b.Checksum != "" && !sha256HexRe.MatchString(strings.TrimPrefix(b.Checksum, "sha256:")) {
+		return fmt.Errorf("binary %s: checksum must be a sha256 hex digest", b.Name)
+	}
+	if b.Checksum != "" && b.Version == BinaryLatest {
+		return fmt.Errorf("binary %s: checksum requires a pinned version", b.Name)
+	}
+	if sig := b.Signature; sig != nil {
+		if sig.Key == "" {
+			return fmt.Errorf("binary %s: signature.key is required", b.Name)
+		}
+		switch sig.Signs {
+		case "", SignsAsset:
+		case SignsChecksums:
+			if b.ChecksumAsset == "" || b.Checksum != "" {
+				return fmt.Errorf("binary %s: signature.signs %q requires checksum_asset and no checksum", b.Name, SignsChecksums)
+			}
+		default:
+			return fmt.Errorf("binary %s: invalid signature.signs %q (expected %q or %q)", b.Name, sig.Signs, SignsAsset, SignsChecksums)
+		}
+	}
+	return nil
+}
+
+// What a binary's signature covers.
+const (
+	SignsAsset     = "asset"
+	SignsChecksums = "checksums"
+)
+
+// BinarySignature declares the detached signature a binary is verified with.
+type BinarySignature struct {
+	Key string `yaml:"key"` // name of an entry in trusted_keys
+
+	// Asset is the release asset holding the signature. It may use the same
+	// placeholders as Binary.Asset. When empty, it is the signed asset's name
+	// with .minisig or .sig appended, per the key's type.
+	Asset string `yaml:"asset"`
+
+	// Signs is "asset" (default) when the binary's asset is signed, or
+	// "checksums" when the checksum_asset is signed and vouches for it.
+	Signs string `yaml:"signs"`
+}
+
+// Types of trusted keys.
+const (
+	KeyMinisign = "minisign"
+	KeyCosign   = "cosign"
+)
+
+// TrustedKey is a public key release signatures are verified with.
+type TrustedKey struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"` // minisign or cosign
+	Key  string `yaml:"key"`  // minisign public key, or PEM encoded cosign.pub
+}
+
+// PublicKey parses the key.
+func (k TrustedKey) PublicKey() (sigverify.PublicKey, error) {
+	switch k.Type {
+	case KeyMinisign:
+		return sigverify.ParseMinisignKey(k.Key)
+	case KeyCosign:
+		return sigverify.ParseCosignKey(k.Key)
+	}
+	return nil, fmt.Errorf("invalid type %q (expected %q or %q)", k.Type, KeyMinisign, KeyCosign)
+}
+
+// SignatureExt returns the extension of the key's detached signatures.
+func (k TrustedKey) SignatureExt() string {
+	if k.Type == KeyMinisign {
+		return ".minisig"
+	}
+	return ".sig"
+}
+
+func (k TrustedKey) Validate() error {
+	if k.Name == "" {
+		return fmt.Errorf("trusted key: name is required")
+	}
+	if _, err := k.PublicKey(); err != nil {
+		return fmt.Errorf("trusted key %s: %w", k.Name, err)
+	}
+	return nil
+}
+
+// TrustedKey returns the trusted key named name.
+func (c *ConfigFile) TrustedKey(name string) (TrustedKey, bool) {
+	for _, k := range c.TrustedKeys {
+		if k.Name == name {
+			return k, true
+		}
+	}
+	return TrustedKey{}, false
+}
+
+// ArchivePath returns the binary's path inside an archive asset.
+func (b Binary) ArchivePath() string {
+	if b.Path != "" {
+		return b.Path
+	}
+	return b.Name
+}
+
+// BinariesDir returns where release assets are extracted, one directory per
+// binary and tag.
+func BinariesDir() (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "binaries"), nil
+}
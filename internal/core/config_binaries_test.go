@@ -0,0 +1,100 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBinary_Validate(t *testing.T) {
+	base := Binary{Name: "tool", Repo: "owner/tool", Version: "v1.0.0"}
+	with := func(f func(b *Binary)) Binary {
+		b := base
+		f(&b)
+		return b
+	}
+
+	tests := []struct {
+		name    string
+		b       Binary
+		wantErr string
+	}{
+		{name: "valid", b: base},
+		{
+			name: "signed asset",
+			b:    with(func(b *Binary) { b.Signature = &BinarySignature{Key: "release"} }),
+		},
+		{
+			name: "signed checksums",
+			b: with(func(b *Binary) {
+				b.ChecksumAsset = "checksums.txt"
+				b.Signature = &BinarySignature{Key: "release", Signs: SignsChecksums}
+			}),
+		},
+		{
+			name:    "signature without key",
+			b:       with(func(b *Binary) { b.Signature = &BinarySignature{} }),
+			wantErr: "signature.key is required",
+		},
+		{
+			name:    "signed checksums without checksum asset",
+			b:       with(func(b *Binary) { b.Signature = &BinarySignature{Key: "release", Signs: SignsChecksums} }),
+			wantErr: "requires checksum_asset",
+		},
+		{
+			name:    "invalid signs",
+			b:       with(func(b *Binary) { b.Signature = &BinarySignature{Key: "release", Signs: "binary"} }),
+			wantErr: "invalid signature.signs",
+		},
+		{
+			name:    "checksum with latest",
+			b:       with(func(b *Binary) { b.Version = BinaryLatest; b.Checksum = strings.Repeat("a", 64) }),
+			wantErr: "requires a pinned version",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.b.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() unexpected error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTrustedKey_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     TrustedKey
+		wantErr string
+	}{
+		{
+			name: "minisign",
+			key:  TrustedKey{Name: "k", Type: KeyMinisign, Key: "RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3"},
+		},
+		{name: "no name", key: TrustedKey{Type: KeyMinisign}, wantErr: "name is required"},
+		{name: "unknown type", key: TrustedKey{Name: "k", Type: "gpg"}, wantErr: `invalid type "gpg"`},
+		{name: "bad cosign key", key: TrustedKey{Name: "k", Type: KeyCosign, Key: "abc"}, wantErr: "expected a PEM PUBLIC KEY block"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.key.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() unexpected error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,12 +1,84 @@
 package core
 
+import (
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+)
+
 type Brews struct {
-	Remove   bool     `yaml:"remove"`
-	Includes []string `yaml:"includes"`
-	Brews    []string `yaml:"brews"`
-	Taps     []string `yaml:"taps"`
-	Casks    []string `yaml:"casks"`
-	MAS      []string `yaml:"mas"`
+	Remove   bool          `yaml:"remove"`
+	Includes []string      `yaml:"includes"`
+	Brews    []BrewPackage `yaml:"brews"`
+	Taps     []string      `yaml:"taps"`
+	Casks    []BrewPackage `yaml:"casks"`
+	MAS      []string      `yaml:"mas"`
+
+	// Protected packages are never uninstalled by brew sync --cleanup, even
+	// when no selected config lists them. Entries may be globs such as
+	// font-*.
+	Protected []string `yaml:"protected"`
+
+	// Shell snippets placed before and after the generated commands. They
+	// are not merged from includes.
+	Preamble  string `yaml:"preamble"`
+	Postamble string `yaml:"postamble"`
+	Strict    *bool  `yaml:"strict"` // set -euo pipefail in brewscript (default: true)
+}
+
+// BrewPackage is a formula or cask. In YAML it is either a bare name or a
+// mapping with name, args, and greedy.
+type BrewPackage struct {
+	Name string   `yaml:"name"`
+	Args []string `yaml:"args"` // Passed to brew install, e.g. --no-quarantine or --appdir=~/Applications
+
+	// Greedy casks are upgraded with brew upgrade --greedy after install,
+	// even when the app updates itself.
+	Greedy bool `yaml:"greedy"`
+}
+
+func (p *BrewPackage) UnmarshalYAML(unmarshal func(any) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		p.Name = name
+		return nil
+	}
+
+	type plain BrewPackage
+	return unmarshal((*plain)(p))
+}
+
+// String returns the package name, so templates can print a package with
+// {{ . }}.
+func (p BrewPackage) String() string {
+	return p.Name
+}
+
+// PackageNames returns the names of pkgs.
+func PackageNames(pkgs []BrewPackage) []string {
+	names := make([]string, len(pkgs))
+	for i, pkg := range pkgs {
+		names[i] = pkg.Name
+	}
+	return names
+}
+
+// GreedyCasks returns the casks marked greedy.
+func (b *Brews) GreedyCasks() []BrewPackage {
+	var greedy []BrewPackage
+	for _, cask := range b.Casks {
+		if cask.Greedy {
+			greedy = append(greedy, cask)
+		}
+	}
+	return greedy
+}
+
+// StrictMode reports whether the brewscript partial enables bash strict mode.
+func (b *Brews) StrictMode() bool {
+	return b.Strict == nil || *b.Strict
 }
 
 func (b *Brews) merge(other *Brews) {
@@ -14,6 +86,15 @@ func (b *Brews) merge(other *Brews) {
 	b.Taps = append(b.Taps, other.Taps...)
 	b.Casks = append(b.Casks, other.Casks...)
 	b.MAS = append(b.MAS, other.MAS...)
+	b.Protected = append(b.Protected, other.Protected...)
+}
+
+// IsProtected reports whether pkg matches one of the protected patterns.
+func (b *Brews) IsProtected(pkg string) bool {
+	return slices.ContainsFunc(b.Protected, func(pattern string) bool {
+		ok, _ := path.Match(pattern, pkg)
+		return ok
+	})
 }
 
 type ConfigMap map[string]*Brews
@@ -30,11 +111,14 @@ func (cm ConfigMap) Get(key string) *Brews {
 	processedConfigs[key] = true
 
 	mergedConfig := &Brews{
-		Remove: baseConfig.Remove,
-		Brews:  make([]string, 0),
-		Taps:   make([]string, 0),
-		Casks:  make([]string, 0),
-		MAS:    make([]string, 0),
+		Remove:    baseConfig.Remove,
+		Brews:     make([]BrewPackage, 0),
+		Taps:      make([]string, 0),
+		Casks:     make([]BrewPackage, 0),
+		MAS:       make([]string, 0),
+		Preamble:  baseConfig.Preamble,
+		Postamble: baseConfig.Postamble,
+		Strict:    baseConfig.Strict,
 	}
 
 	for _, include := range baseConfig.Includes {
@@ -48,6 +132,128 @@ func (cm ConfigMap) Get(key string) *Brews {
 	return mergedConfig
 }
 
+// Validate checks that every package has a name, that only casks are
+// greedy, and that protected patterns are valid globs.
+func (cm ConfigMap) Validate() error {
+	for _, name := range slices.Sorted(maps.Keys(cm)) {
+		b := cm[name]
+		if b == nil {
+			continue
+		}
+		for _, pkg := range slices.Concat(b.Brews, b.Casks) {
+			if pkg.Name == "" {
+				return fmt.Errorf("brews.%s: package name is required", name)
+			}
+		}
+		for _, pkg := range b.Brews {
+			if pkg.Greedy {
+				return fmt.Errorf("brews.%s: %s: greedy only applies to casks", name, pkg.Name)
+			}
+		}
+		for _, pattern := range b.Protected {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("brews.%s: protected %q: %w", name, pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// IncludeError describes an include that [ConfigMap.Get] cannot follow: either
+// a name that is not defined or a circular reference.
+type IncludeError struct {
+	Config string   // Config being resolved
+	Chain  []string // Include chain from Config to the offending entry
+	Cycle  bool     // The last entry re-enters the chain
+}
+
+func (e IncludeError) Error() string {
+	chain := strings.Join(e.Chain, " -> ")
+	if e.Cycle {
+		return fmt.Sprintf("brew config %q: circular include (%s)", e.Config, chain)
+	}
+	return fmt.Sprintf("brew config %q: include %q not found (%s)", e.Config, e.Chain[len(e.Chain)-1], chain)
+}
+
+// ValidateIncludes walks the includes of the named config and returns every
+// missing or circular include reachable from it.
+func (cm ConfigMap) ValidateIncludes(key string) []IncludeError {
+	var errs []IncludeError
+
+	var walk func(chain []string)
+	walk = func(chain []string) {
+		current := chain[len(chain)-1]
+		for _, include := range cm[current].Includes {
+			next := append(slices.Clip(chain), include)
+			switch {
+			case slices.Contains(chain, include):
+				errs = append(errs, IncludeError{Config: key, Chain: next, Cycle: true})
+			case cm[include] == nil:
+				errs = append(errs, IncludeError{Config: key, Chain: next})
+			default:
+				walk(next)
+			}
+		}
+	}
+
+	if cm[key] != nil {
+		walk([]string{key})
+	}
+
+	return errs
+}
+
+// Merge returns the union of the named configs, each resolved with [ConfigMap.Get].
+// Packages listed by more than one config appear once. Unknown keys are skipped.
+func (cm ConfigMap) Merge(keys ...string) *Brews {
+	merged := &Brews{
+		Brews: make([]BrewPackage, 0),
+		Taps:  make([]string, 0),
+		Casks: make([]BrewPackage, 0),
+		MAS:   make([]string, 0),
+	}
+
+	for _, key := range keys {
+		if cfg := cm.Get(key); cfg != nil {
+			merged.Remove = merged.Remove || cfg.Remove
+			merged.merge(cfg)
+		}
+	}
+
+	merged.Brews = uniqPackages(merged.Brews)
+	merged.Taps = uniq(merged.Taps)
+	merged.Casks = uniqPackages(merged.Casks)
+	merged.MAS = uniq(merged.MAS)
+	merged.Protected = uniq(merged.Protected)
+
+	return merged
+}
+
+// uniq removes duplicates from items, keeping the first occurrence.
+func uniq(items []string) []string {
+	seen := make(map[string]bool, len(items))
+	return slices.DeleteFunc(items, func(item string) bool {
+		if seen[item] {
+			return true
+		}
+		seen[item] = true
+		return false
+	})
+}
+
+// uniqPackages removes packages whose name appeared earlier in pkgs, so the
+// options of the first occurrence win.
+func uniqPackages(pkgs []BrewPackage) []BrewPackage {
+	seen := make(map[string]bool, len(pkgs))
+	return slices.DeleteFunc(pkgs, func(pkg BrewPackage) bool {
+		if seen[pkg.Name] {
+			return true
+		}
+		seen[pkg.Name] = true
+		return false
+	})
+}
+
 func mergeIncludes(cm map[string]*Brews, key string, processed map[string]bool) *Brews {
 	if processed[key] {
 		return nil
@@ -61,9 +267,9 @@ func mergeIncludes(cm map[string]*Brews, key string, processed map[string]bool)
 	processed[key] = true
 
 	mergedConfig := &Brews{
-		Brews: make([]string, 0),
+		Brews: make([]BrewPackage, 0),
 		Taps:  make([]string, 0),
-		Casks: make([]string, 0),
+		Casks: make([]BrewPackage, 0),
 		MAS:   make([]string, 0),
 	}
 
@@ -1,7 +1,11 @@
 package core
 
 import (
+	"reflect"
+	"slices"
 	"testing"
+
+	"github.com/goccy/go-yaml"
 )
 
 func TestConfigMap_Get_NotFound(t *testing.T) {
@@ -15,7 +19,7 @@ func TestConfigMap_Get_NoIncludes(t *testing.T) {
 	cm := ConfigMap{
 		"simple": {
 			Taps:  []string{"tap1"},
-			Brews: []string{"pkg1"},
+			Brews: []BrewPackage{{Name: "pkg1"}},
 		},
 	}
 
@@ -26,7 +30,7 @@ func TestConfigMap_Get_NoIncludes(t *testing.T) {
 	if len(got.Taps) != 1 || got.Taps[0] != "tap1" {
 		t.Errorf("Taps = %v, want [tap1]", got.Taps)
 	}
-	if len(got.Brews) != 1 || got.Brews[0] != "pkg1" {
+	if len(got.Brews) != 1 || got.Brews[0].Name != "pkg1" {
 		t.Errorf("Brews = %v, want [pkg1]", got.Brews)
 	}
 }
@@ -34,13 +38,13 @@ func TestConfigMap_Get_NoIncludes(t *testing.T) {
 func TestConfigMap_Get_WithIncludes(t *testing.T) {
 	cm := ConfigMap{
 		"base": {
-			Brews: []string{"curl"},
+			Brews: []BrewPackage{{Name: "curl"}},
 			Taps:  []string{"base/tap"},
 		},
 		"extended": {
 			Includes: []string{"base"},
-			Brews:    []string{"git"},
-			Casks:    []string{"firefox"},
+			Brews:    []BrewPackage{{Name: "git"}},
+			Casks:    []BrewPackage{{Name: "firefox"}},
 		},
 	}
 
@@ -55,7 +59,7 @@ func TestConfigMap_Get_WithIncludes(t *testing.T) {
 		t.Fatalf("Brews = %v, want %v", got.Brews, wantBrews)
 	}
 	for i, want := range wantBrews {
-		if got.Brews[i] != want {
+		if got.Brews[i].Name != want {
 			t.Errorf("Brews[%d] = %q, want %q", i, got.Brews[i], want)
 		}
 	}
@@ -63,7 +67,7 @@ func TestConfigMap_Get_WithIncludes(t *testing.T) {
 	if len(got.Taps) != 1 || got.Taps[0] != "base/tap" {
 		t.Errorf("Taps = %v, want [base/tap]", got.Taps)
 	}
-	if len(got.Casks) != 1 || got.Casks[0] != "firefox" {
+	if len(got.Casks) != 1 || got.Casks[0].Name != "firefox" {
 		t.Errorf("Casks = %v, want [firefox]", got.Casks)
 	}
 }
@@ -72,11 +76,11 @@ func TestConfigMap_Get_CircularIncludes(t *testing.T) {
 	cm := ConfigMap{
 		"a": {
 			Includes: []string{"b"},
-			Brews:    []string{"pkg-a"},
+			Brews:    []BrewPackage{{Name: "pkg-a"}},
 		},
 		"b": {
 			Includes: []string{"a"},
-			Brews:    []string{"pkg-b"},
+			Brews:    []BrewPackage{{Name: "pkg-b"}},
 		},
 	}
 
@@ -92,7 +96,7 @@ func TestConfigMap_Get_CircularIncludes(t *testing.T) {
 		t.Fatalf("Brews = %v, want %v", got.Brews, wantBrews)
 	}
 	for i, want := range wantBrews {
-		if got.Brews[i] != want {
+		if got.Brews[i].Name != want {
 			t.Errorf("Brews[%d] = %q, want %q", i, got.Brews[i], want)
 		}
 	}
@@ -102,7 +106,7 @@ func TestConfigMap_Get_PreservesRemove(t *testing.T) {
 	cm := ConfigMap{
 		"cleanup": {
 			Remove: true,
-			Brews:  []string{"oldpkg"},
+			Brews:  []BrewPackage{{Name: "oldpkg"}},
 		},
 	}
 
@@ -114,3 +118,112 @@ func TestConfigMap_Get_PreservesRemove(t *testing.T) {
 		t.Error("Remove = false, want true")
 	}
 }
+
+func TestConfigMap_Merge(t *testing.T) {
+	cm := ConfigMap{
+		"base": {
+			Brews: []BrewPackage{{Name: "curl"}, {Name: "git"}},
+		},
+		"work": {
+			Includes: []string{"base"},
+			Brews:    []BrewPackage{{Name: "kubectl"}},
+			Casks:    []BrewPackage{{Name: "slack"}},
+		},
+		"personal": {
+			Includes: []string{"base"},
+			Brews:    []BrewPackage{{Name: "git"}, {Name: "jq"}},
+			Casks:    []BrewPackage{{Name: "firefox"}},
+		},
+	}
+
+	got := cm.Merge("work", "personal", "missing")
+
+	wantBrews := []string{"curl", "git", "kubectl", "jq"}
+	if !slices.Equal(PackageNames(got.Brews), wantBrews) {
+		t.Errorf("Brews = %v, want %v", got.Brews, wantBrews)
+	}
+
+	wantCasks := []string{"slack", "firefox"}
+	if !slices.Equal(PackageNames(got.Casks), wantCasks) {
+		t.Errorf("Casks = %v, want %v", got.Casks, wantCasks)
+	}
+}
+
+func TestConfigMap_ValidateIncludes(t *testing.T) {
+	cm := ConfigMap{
+		"base":    {Brews: []BrewPackage{{Name: "git"}}},
+		"a":       {Includes: []string{"b"}},
+		"b":       {Includes: []string{"a"}},
+		"typo":    {Includes: []string{"bsae"}},
+		"nested":  {Includes: []string{"typo", "base"}},
+		"diamond": {Includes: []string{"nested", "base"}},
+	}
+
+	tests := []struct {
+		key  string
+		want []string
+	}{
+		{key: "base"},
+		{key: "a", want: []string{`brew config "a": circular include (a -> b -> a)`}},
+		{key: "typo", want: []string{`brew config "typo": include "bsae" not found (typo -> bsae)`}},
+		{key: "diamond", want: []string{`brew config "diamond": include "bsae" not found (diamond -> nested -> typo -> bsae)`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			var got []string
+			for _, err := range cm.ValidateIncludes(tt.key) {
+				got = append(got, err.Error())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ValidateIncludes(%q) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBrewPackage_UnmarshalYAML(t *testing.T) {
+	data := []byte(`
+casks:
+  - firefox
+  - name: docker
+    args: [--no-quarantine]
+    greedy: true
+`)
+
+	var b Brews
+	if err := yaml.Unmarshal(data, &b); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []BrewPackage{
+		{Name: "firefox"},
+		{Name: "docker", Args: []string{"--no-quarantine"}, Greedy: true},
+	}
+	if !reflect.DeepEqual(b.Casks, want) {
+		t.Errorf("Casks = %+v, want %+v", b.Casks, want)
+	}
+	if got := b.GreedyCasks(); len(got) != 1 || got[0].Name != "docker" {
+		t.Errorf("GreedyCasks() = %+v, want [docker]", got)
+	}
+}
+
+func TestConfigMap_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cm      ConfigMap
+		wantErr bool
+	}{
+		{name: "valid", cm: ConfigMap{"base": {Brews: []BrewPackage{{Name: "git"}}, Casks: []BrewPackage{{Name: "docker", Greedy: true}}}}},
+		{name: "missing name", cm: ConfigMap{"base": {Casks: []BrewPackage{{Args: []string{"--no-quarantine"}}}}}, wantErr: true},
+		{name: "greedy formula", cm: ConfigMap{"base": {Brews: []BrewPackage{{Name: "git", Greedy: true}}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cm.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+)
+
+// Editor declares the extensions and managed settings of a VS Code family
+// editor.
+type Editor struct {
+	// Name is "vscode", "vscodium", or "cursor".
+	Name string `yaml:"name"`
+
+	// Command is the editor CLI (default: code, codium, or cursor).
+	Command string `yaml:"command"`
+
+	// Extensions are extension IDs, e.g. "golang.go".
+	Extensions []string `yaml:"extensions"`
+
+	// Settings is a template (inline or file path) rendering a JSON object.
+	// Its top-level keys are merged into settings.json, leaving other keys
+	// and comments in place.
+	Settings string         `yaml:"settings"`
+	Vars     map[string]any `yaml:"vars"`
+
+	// SettingsPath overrides the location of settings.json.
+	SettingsPath string `yaml:"settings_path"`
+}
+
+type editorDefaults struct {
+	command string
+	dir     string // user data directory name
+}
+
+var editorKinds = map[string]editorDefaults{
+	"vscode":   {command: "code", dir: "Code"},
+	"vscodium": {command: "codium", dir: "VSCodium"},
+	"cursor":   {command: "cursor", dir: "Cursor"},
+}
+
+func (e Editor) Validate() error {
+	if _, ok := editorKinds[e.Name]; !ok {
+		return fmt.Errorf("editor %q: name must be one of %v", e.Name, slices.Sorted(maps.Keys(editorKinds)))
+	}
+	return nil
+}
+
+// CLI returns the editor's command line tool.
+func (e Editor) CLI() string {
+	if e.Command != "" {
+		return e.Command
+	}
+	return editorKinds[e.Name].command
+}
+
+// DefaultSettingsPath returns the platform location of the editor's user
+// settings.json.
+func (e Editor) DefaultSettingsPath() (string, error) {
+	var base string
+	switch runtime.GOOS {
+	case "darwin":
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		base = filepath.Join(home, "Library", "Application Support")
+	case "windows":
+		base = os.Getenv("APPDATA")
+	default:
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		base = dir
+	}
+
+	return filepath.Join(base, editorKinds[e.Name].dir, "User", "settings.json"), nil
+}
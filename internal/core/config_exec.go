@@ -0,0 +1,45 @@
+package core
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// baseScriptEnv is always passed to scripts run with exec.clean_env.
+var baseScriptEnv = []string{"HOME", "PATH"}
+
+func (e Exec) Validate() error {
+	for _, name := range e.AllowEnv {
+		if name == "" || strings.ContainsAny(name, "= ") {
+			return fmt.Errorf("exec.allow_env: invalid variable name %q", name)
+		}
+		if _, err := path.Match(name, ""); err != nil {
+			return fmt.Errorf("exec.allow_env: %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ScriptEnv returns the environment for scripts given the caller's environ,
+// in the KEY=value form of [os.Environ]. It returns nil, meaning the caller's
+// environment is inherited, unless CleanEnv is set.
+func (e Exec) ScriptEnv(environ []string) []string {
+	if !e.CleanEnv {
+		return nil
+	}
+
+	allow := append(slices.Clone(baseScriptEnv), e.AllowEnv...)
+	env := []string{} // non-nil so nothing is inherited
+	for _, kv := range environ {
+		name, _, _ := strings.Cut(kv, "=")
+		if slices.ContainsFunc(allow, func(pattern string) bool {
+			ok, _ := path.Match(pattern, name)
+			return ok
+		}) {
+			env = append(env, kv)
+		}
+	}
+	return env
+}
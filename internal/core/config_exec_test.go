@@ -0,0 +1,65 @@
+package core
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExec_ScriptEnv(t *testing.T) {
+	environ := []string{
+		"HOME=/home/me",
+		"PATH=/usr/bin:/bin",
+		"SSH_AUTH_SOCK=/tmp/agent.sock",
+		"LC_ALL=en_US.UTF-8",
+		"LC_CTYPE=UTF-8",
+		"AWS_PROFILE=work",
+	}
+
+	tests := []struct {
+		name string
+		exec Exec
+		want []string
+	}{
+		{
+			name: "inherit",
+			exec: Exec{AllowEnv: []string{"SSH_AUTH_SOCK"}},
+			want: nil,
+		},
+		{
+			name: "clean",
+			exec: Exec{CleanEnv: true},
+			want: []string{"HOME=/home/me", "PATH=/usr/bin:/bin"},
+		},
+		{
+			name: "allowed names and globs",
+			exec: Exec{CleanEnv: true, AllowEnv: []string{"SSH_AUTH_SOCK", "LC_*"}},
+			want: []string{
+				"HOME=/home/me",
+				"PATH=/usr/bin:/bin",
+				"SSH_AUTH_SOCK=/tmp/agent.sock",
+				"LC_ALL=en_US.UTF-8",
+				"LC_CTYPE=UTF-8",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.exec.ScriptEnv(environ)
+			if !slices.Equal(got, tt.want) || (got == nil) != (tt.want == nil) {
+				t.Errorf("ScriptEnv() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExec_Validate(t *testing.T) {
+	for _, name := range []string{"", "A=B", "[LC"} {
+		if err := (Exec{AllowEnv: []string{name}}).Validate(); err == nil {
+			t.Errorf("Validate() with allow_env %q: expected error", name)
+		}
+	}
+	if err := (Exec{AllowEnv: []string{"LC_*", "TERM"}}).Validate(); err != nil {
+		t.Errorf("Validate() unexpected error = %v", err)
+	}
+}
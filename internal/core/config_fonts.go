@@ -0,0 +1,73 @@
+package core
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Font declares a font archive installed into the user's font directory.
+type Font struct {
+	// Name identifies the font. Without a URL it is the Nerd Fonts release
+	// archive name, e.g. "JetBrainsMono".
+	Name string `yaml:"name"`
+
+	// URL of a .zip, .tar.gz, or single .ttf/.otf file. Defaults to the Nerd
+	// Fonts release archive for Name.
+	URL string `yaml:"url"`
+
+	// Version is the Nerd Fonts release tag used when URL is empty (default:
+	// the latest release).
+	Version string `yaml:"version"`
+
+	// Checksum is the expected sha256 of the download.
+	Checksum string `yaml:"checksum"`
+
+	// Files are glob patterns selecting the font files to install from an
+	// archive (default: *.ttf and *.otf).
+	Files []string `yaml:"files"`
+}
+
+// DefaultFontFiles selects the font files installed from an archive when
+// Font.Files is empty.
+var DefaultFontFiles = []string{"*.ttf", "*.otf"}
+
+func (f Font) Validate() error {
+	if f.Name == "" {
+		return fmt.Errorf("font: name is required")
+	}
+	if strings.ContainsAny(f.Name, `/\`) {
+		return fmt.Errorf("font %s: name must not contain path separators", f.Name)
+	}
+	if f.URL != "" && f.Version != "" {
+		return fmt.Errorf("font %s: version only applies to Nerd Fonts; set it in the url instead", f.Name)
+	}
+	if f.Checksum != "" && !sha256HexRe.MatchString(strings.TrimPrefix(f.Checksum, "sha256:")) {
+		return fmt.Errorf("font %s: checksum must be a sha256 hex digest", f.Name)
+	}
+	for _, pattern := range f.Files {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("font %s: invalid files pattern %q: %w", f.Name, pattern, err)
+		}
+	}
+	return nil
+}
+
+// Source returns the URL the font is downloaded from.
+func (f Font) Source() string {
+	if f.URL != "" {
+		return f.URL
+	}
+	if f.Version != "" {
+		return fmt.Sprintf("https://github.com/ryanoasis/nerd-fonts/releases/download/%s/%s.zip", f.Version, f.Name)
+	}
+	return fmt.Sprintf("https://github.com/ryanoasis/nerd-fonts/releases/latest/download/%s.zip", f.Name)
+}
+
+// FilePatterns returns the patterns selecting files to install.
+func (f Font) FilePatterns() []string {
+	if len(f.Files) > 0 {
+		return f.Files
+	}
+	return DefaultFontFiles
+}
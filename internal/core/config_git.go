@@ -0,0 +1,276 @@
+package core
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Git declares the global git config and per-directory profiles included
+// with includeIf. Each file becomes a template tagged "git", so `mmdot run
+// +git` and the templates commands render and diff them. Values may use
+// template syntax, e.g. signing_key: "{{ .work_signing_key }}".
+type Git struct {
+	Output   string         `yaml:"output"` // default: ~/.gitconfig
+	User     GitUser        `yaml:"user"`
+	Settings map[string]any `yaml:"settings"` // "section.key" or "section.subsection.key"
+	Profiles []GitProfile   `yaml:"profiles"`
+}
+
+// GitUser is an identity written to the [user] section.
+type GitUser struct {
+	Name          string `yaml:"name"`
+	Email         string `yaml:"email"`
+	SigningKey    string `yaml:"signing_key"`    // GPG key ID or SSH public key path
+	SigningFormat string `yaml:"signing_format"` // openpgp (default), ssh, or x509
+	SignCommits   *bool  `yaml:"sign_commits"`
+	SSHKey        string `yaml:"ssh_key"` // private key used for git over SSH
+}
+
+// GitProfile is included for repositories below Dir.
+type GitProfile struct {
+	Name     string         `yaml:"name"`
+	Dir      string         `yaml:"dir"`    // gitdir condition, e.g. ~/work/
+	Output   string         `yaml:"output"` // default: ~/.config/git/<name>.gitconfig
+	User     GitUser        `yaml:"user"`
+	Settings map[string]any `yaml:"settings"`
+}
+
+// GitTag is the tag of the templates generated from the git section.
+const GitTag = "git"
+
+// IsZero reports whether the git section is unset.
+func (g Git) IsZero() bool {
+	return g.Output == "" && g.User == (GitUser{}) && len(g.Settings) == 0 && len(g.Profiles) == 0
+}
+
+func (g Git) Validate() error {
+	if err := g.User.validate("git.user"); err != nil {
+		return err
+	}
+	if err := validateGitSettings("git.settings", g.Settings); err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	for _, p := range g.Profiles {
+		if p.Name == "" {
+			return fmt.Errorf("git.profiles: name is required")
+		}
+		if strings.ContainsAny(p.Name, `/\ `) {
+			return fmt.Errorf("git profile %s: name must not contain spaces or path separators", p.Name)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("git profile %s: duplicate name", p.Name)
+		}
+		seen[p.Name] = true
+
+		if p.Dir == "" {
+			return fmt.Errorf("git profile %s: dir is required", p.Name)
+		}
+		if err := p.User.validate("git profile " + p.Name + " user"); err != nil {
+			return err
+		}
+		if err := validateGitSettings("git profile "+p.Name+" settings", p.Settings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u GitUser) validate(field string) error {
+	switch u.SigningFormat {
+	case "", "openpgp", "ssh", "x509":
+		return nil
+	}
+	return fmt.Errorf("%s: invalid signing_format %q (expected openpgp, ssh, or x509)", field, u.SigningFormat)
+}
+
+func validateGitSettings(field string, settings map[string]any) error {
+	for key := range settings {
+		if _, _, _, ok := splitGitKey(key); !ok {
+			return fmt.Errorf("%s: invalid key %q (expected section.key or section.subsection.key)", field, key)
+		}
+	}
+	return nil
+}
+
+// splitGitKey splits "section.subsection.key" the way git does: the
+// subsection is everything between the first and last dot.
+func splitGitKey(key string) (section, subsection, name string, ok bool) {
+	first, last := strings.Index(key, "."), strings.LastIndex(key, ".")
+	if first <= 0 || last == len(key)-1 {
+		return "", "", "", false
+	}
+	section, name = key[:first], key[last+1:]
+	if first != last {
+		subsection = key[first+1 : last]
+	}
+	return section, subsection, name, true
+}
+
+// templates returns the templates rendering the git config and profiles.
+// Paths must already be resolved.
+func (g Git) templates() []Template {
+	var main gitConfigWriter
+	main.comment()
+	main.user(g.User)
+	main.settings(g.Settings)
+
+	out := []Template{}
+	for _, p := range g.Profiles {
+		main.section("includeIf", "gitdir:"+p.Dir)
+		main.value("path", p.Output)
+
+		var profile gitConfigWriter
+		profile.comment()
+		profile.user(p.User)
+		profile.settings(p.Settings)
+
+		out = append(out, Template{
+			Name:     "gitconfig-" + p.Name,
+			Tags:     []string{GitTag},
+			Template: profile.String(),
+			Output:   p.Output,
+		})
+	}
+
+	return append([]Template{{
+		Name:     "gitconfig",
+		Tags:     []string{GitTag},
+		Template: main.String(),
+		Output:   g.Output,
+	}}, out...)
+}
+
+// resolveGit resolves the git output paths, appends its templates to c, and
+// reports templates whose names collide.
+func (c *ConfigFile) resolveGit(pr PathResolver) error {
+	if c.Git.IsZero() {
+		return nil
+	}
+	if err := c.Git.Validate(); err != nil {
+		return err
+	}
+
+	resolve := func(path, def string) (string, error) {
+		if path == "" {
+			path = def
+		}
+		return pr.Resolve(path)
+	}
+
+	var err error
+	if c.Git.Output, err = resolve(c.Git.Output, "~/.gitconfig"); err != nil {
+		return fmt.Errorf("failed to resolve git output path: %w", err)
+	}
+	for i := range c.Git.Profiles {
+		p := &c.Git.Profiles[i]
+		if p.Output, err = resolve(p.Output, filepath.Join("~", ".config", "git", p.Name+".gitconfig")); err != nil {
+			return fmt.Errorf("failed to resolve git profile output path: %w", err)
+		}
+		// gitdir patterns ending in a slash match every repository below
+		// the directory
+		if !strings.HasSuffix(p.Dir, "/") {
+			p.Dir += "/"
+		}
+	}
+
+	for _, tmpl := range c.Git.templates() {
+		if slices.ContainsFunc(c.Templates, func(t Template) bool { return t.Name == tmpl.Name }) {
+			return fmt.Errorf("template %s: name is reserved for the git section", tmpl.Name)
+		}
+		c.Templates = append(c.Templates, tmpl)
+	}
+	return nil
+}
+
+// gitConfigWriter writes git config syntax.
+type gitConfigWriter struct {
+	sb      strings.Builder
+	current string
+}
+
+func (w *gitConfigWriter) String() string {
+	return w.sb.String()
+}
+
+func (w *gitConfigWriter) comment() {
+	w.sb.WriteString("# Managed by mmdot. Changes will be overwritten.\n")
+}
+
+func (w *gitConfigWriter) section(name, subsection string) {
+	header := "[" + name + "]"
+	if subsection != "" {
+		header = fmt.Sprintf("[%s %s]", name, quoteGitValue(subsection, true))
+	}
+	if header == w.current {
+		return
+	}
+	w.current = header
+	w.sb.WriteString("\n" + header + "\n")
+}
+
+func (w *gitConfigWriter) value(key string, v any) {
+	switch v := v.(type) {
+	case []any:
+		for _, item := range v {
+			w.value(key, item)
+		}
+	case string:
+		fmt.Fprintf(&w.sb, "\t%s = %s\n", key, quoteGitValue(v, false))
+	default:
+		fmt.Fprintf(&w.sb, "\t%s = %v\n", key, v)
+	}
+}
+
+func (w *gitConfigWriter) user(u GitUser) {
+	if u == (GitUser{}) {
+		return
+	}
+
+	w.section("user", "")
+	for _, kv := range [][2]string{{"name", u.Name}, {"email", u.Email}, {"signingkey", u.SigningKey}} {
+		if kv[1] != "" {
+			w.value(kv[0], kv[1])
+		}
+	}
+	if u.SigningFormat != "" {
+		w.section("gpg", "")
+		w.value("format", u.SigningFormat)
+	}
+	if u.SignCommits != nil {
+		w.section("commit", "")
+		w.value("gpgsign", *u.SignCommits)
+	}
+	if u.SSHKey != "" {
+		w.section("core", "")
+		w.value("sshCommand", "ssh -i "+u.SSHKey+" -o IdentitiesOnly=yes")
+	}
+}
+
+func (w *gitConfigWriter) settings(settings map[string]any) {
+	for _, key := range slices.Sorted(maps.Keys(settings)) {
+		section, subsection, name, _ := splitGitKey(key)
+		w.section(section, subsection)
+		w.value(name, settings[key])
+	}
+}
+
+// quoteGitValue quotes a value when git would otherwise misread it. Values
+// containing template actions are written verbatim so the template still
+// parses.
+func quoteGitValue(s string, always bool) string {
+	if strings.Contains(s, "{{") {
+		if always {
+			return `"` + s + `"`
+		}
+		return s
+	}
+	if !always && !strings.ContainsAny(s, "#;\"\\") && strings.TrimSpace(s) == s {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
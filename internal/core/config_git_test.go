@@ -0,0 +1,117 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig_Git(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mmdot.yml")
+	err := os.WriteFile(path, []byte(`
+git:
+  output: out/gitconfig
+  user:
+    name: Jane Doe
+    email: jane@example.com
+    signing_key: ~/.ssh/id_ed25519.pub
+    signing_format: ssh
+    sign_commits: true
+  settings:
+    pull.rebase: true
+    url.git@github.com:.insteadOf: https://github.com/
+    core.excludesFile: "~/.gitignore # global"
+  profiles:
+    - name: work
+      dir: ~/work
+      output: out/work.gitconfig
+      user:
+        email: "{{ .work_email }}"
+        ssh_key: ~/.ssh/work
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	if len(cfg.Templates) != 2 {
+		t.Fatalf("len(Templates) = %d, want 2", len(cfg.Templates))
+	}
+
+	main, work := cfg.Templates[0], cfg.Templates[1]
+	if main.Name != "gitconfig" || main.Output != filepath.Join(dir, "out", "gitconfig") {
+		t.Errorf("main template = %s -> %s", main.Name, main.Output)
+	}
+	if work.Name != "gitconfig-work" || work.Tags[0] != GitTag {
+		t.Errorf("profile template = %s %v", work.Name, work.Tags)
+	}
+
+	wantMain := `# Managed by mmdot. Changes will be overwritten.
+
+[user]
+	name = Jane Doe
+	email = jane@example.com
+	signingkey = ~/.ssh/id_ed25519.pub
+
+[gpg]
+	format = ssh
+
+[commit]
+	gpgsign = true
+
+[core]
+	excludesFile = "~/.gitignore # global"
+
+[pull]
+	rebase = true
+
+[url "git@github.com:"]
+	insteadOf = https://github.com/
+
+[includeIf "gitdir:~/work/"]
+	path = ` + filepath.Join(dir, "out", "work.gitconfig") + "\n"
+	if main.Template != wantMain {
+		t.Errorf("main template =\n%s\nwant:\n%s", main.Template, wantMain)
+	}
+
+	for _, want := range []string{"email = {{ .work_email }}", "sshCommand = ssh -i ~/.ssh/work -o IdentitiesOnly=yes"} {
+		if !strings.Contains(work.Template, want) {
+			t.Errorf("profile template missing %q:\n%s", want, work.Template)
+		}
+	}
+}
+
+func TestGit_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		git     Git
+		wantErr string
+	}{
+		{name: "valid", git: Git{Settings: map[string]any{"core.editor": "nvim"}}},
+		{name: "bad key", git: Git{Settings: map[string]any{"editor": "nvim"}}, wantErr: "invalid key"},
+		{name: "bad format", git: Git{User: GitUser{SigningFormat: "pgp"}}, wantErr: "signing_format"},
+		{name: "profile without dir", git: Git{Profiles: []GitProfile{{Name: "work"}}}, wantErr: "dir is required"},
+		{name: "duplicate profile", git: Git{Profiles: []GitProfile{{Name: "a", Dir: "x"}, {Name: "a", Dir: "y"}}}, wantErr: "duplicate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.git.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// GPG declares secret keys imported into the GnuPG keyring and the
+// gpg-agent configuration. The agent config becomes a template tagged "gpg".
+type GPG struct {
+	Keys  []GPGKey `yaml:"keys"`
+	Agent GPGAgent `yaml:"agent"`
+}
+
+// GPGKey is an armored secret key stored age-encrypted in the repository.
+type GPGKey struct {
+	Name        string `yaml:"name"`        // Label shown in output (default: fingerprint)
+	Src         string `yaml:"src"`         // age-encrypted `gpg --export-secret-keys --armor` output
+	Fingerprint string `yaml:"fingerprint"` // Primary key fingerprint, used to skip keys already imported
+	Trust       string `yaml:"trust"`       // Optional ownertrust: undefined, never, marginal, full, or ultimate
+}
+
+// GPGAgent is rendered to gpg-agent.conf. Options are written verbatim
+// after the typed settings.
+type GPGAgent struct {
+	Output           string   `yaml:"output"`           // default: ~/.gnupg/gpg-agent.conf
+	PinentryProgram  string   `yaml:"pinentry_program"` // e.g. /opt/homebrew/bin/pinentry-mac
+	AllowLoopback    bool     `yaml:"allow_loopback_pinentry"`
+	DefaultCacheTTL  *int     `yaml:"default_cache_ttl"` // seconds
+	MaxCacheTTL      *int     `yaml:"max_cache_ttl"`     // seconds
+	EnableSSHSupport bool     `yaml:"enable_ssh_support"`
+	Options          []string `yaml:"options"`
+}
+
+// GPGTag is the tag of the template generated from the gpg section.
+const GPGTag = "gpg"
+
+// GPGTrustLevels maps ownertrust names to the values used by
+// `gpg --import-ownertrust`.
+var GPGTrustLevels = map[string]int{
+	"undefined": 2,
+	"never":     3,
+	"marginal":  4,
+	"full":      5,
+	"ultimate":  6,
+}
+
+var gpgFingerprintRe = regexp.MustCompile(`^([0-9A-F]{40}|[0-9A-F]{64})$`)
+
+// IsZero reports whether the agent section is unset.
+func (a GPGAgent) IsZero() bool {
+	return a.Output == "" && a.PinentryProgram == "" && !a.AllowLoopback &&
+		a.DefaultCacheTTL == nil && a.MaxCacheTTL == nil && !a.EnableSSHSupport && len(a.Options) == 0
+}
+
+// NormalizeFingerprint uppercases a fingerprint and removes the spaces gpg
+// prints between groups.
+func NormalizeFingerprint(fpr string) string {
+	return strings.ToUpper(strings.ReplaceAll(fpr, " ", ""))
+}
+
+// Label returns the key's name, or its fingerprint when unnamed.
+func (k GPGKey) Label() string {
+	if k.Name != "" {
+		return k.Name
+	}
+	return k.Fingerprint
+}
+
+func (k GPGKey) Validate() error {
+	if k.Src == "" {
+		return fmt.Errorf("gpg key %s: src is required", k.Label())
+	}
+	if !gpgFingerprintRe.MatchString(NormalizeFingerprint(k.Fingerprint)) {
+		return fmt.Errorf("gpg key %s: fingerprint must be the full 40 or 64 character hex fingerprint", k.Label())
+	}
+	if _, ok := GPGTrustLevels[k.Trust]; k.Trust != "" && !ok {
+		return fmt.Errorf("gpg key %s: invalid trust %q (expected undefined, never, marginal, full, or ultimate)", k.Label(), k.Trust)
+	}
+	return nil
+}
+
+// template returns the template rendering gpg-agent.conf. Paths must already
+// be resolved.
+func (a GPGAgent) template() Template {
+	var sb strings.Builder
+	sb.WriteString("# Managed by mmdot. Changes will be overwritten.\n")
+	if a.PinentryProgram != "" {
+		sb.WriteString("pinentry-program " + a.PinentryProgram + "\n")
+	}
+	if a.AllowLoopback {
+		sb.WriteString("allow-loopback-pinentry\n")
+	}
+	if a.DefaultCacheTTL != nil {
+		sb.WriteString("default-cache-ttl " + strconv.Itoa(*a.DefaultCacheTTL) + "\n")
+	}
+	if a.MaxCacheTTL != nil {
+		sb.WriteString("max-cache-ttl " + strconv.Itoa(*a.MaxCacheTTL) + "\n")
+	}
+	if a.EnableSSHSupport {
+		sb.WriteString("enable-ssh-support\n")
+	}
+	for _, opt := range a.Options {
+		sb.WriteString(opt + "\n")
+	}
+
+	return Template{
+		Name:        "gpg-agent",
+		Tags:        []string{GPGTag},
+		Template:    sb.String(),
+		Output:      a.Output,
+		Permissions: "0600",
+	}
+}
+
+// resolveGPG validates the gpg section, resolves its paths, and appends the
+// gpg-agent.conf template to c.
+func (c *ConfigFile) resolveGPG(pr PathResolver) error {
+	for i := range c.GPG.Keys {
+		k := &c.GPG.Keys[i]
+		if err := k.Validate(); err != nil {
+			return err
+		}
+		k.Fingerprint = NormalizeFingerprint(k.Fingerprint)
+
+		resolved, err := pr.Resolve(k.Src)
+		if err != nil {
+			return fmt.Errorf("failed to resolve gpg key src path: %w", err)
+		}
+		k.Src = resolved
+	}
+
+	if c.GPG.Agent.IsZero() {
+		return nil
+	}
+
+	output := c.GPG.Agent.Output
+	if output == "" {
+		output = filepath.Join("~", ".gnupg", "gpg-agent.conf")
+	}
+	resolved, err := pr.Resolve(output)
+	if err != nil {
+		return fmt.Errorf("failed to resolve gpg agent output path: %w", err)
+	}
+	c.GPG.Agent.Output = resolved
+
+	tmpl := c.GPG.Agent.template()
+	for _, t := range c.Templates {
+		if t.Name == tmpl.Name {
+			return fmt.Errorf("template %s: name is reserved for the gpg section", tmpl.Name)
+		}
+	}
+	c.Templates = append(c.Templates, tmpl)
+	return nil
+}
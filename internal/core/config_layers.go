@@ -0,0 +1,145 @@
+package core
+
+import "strings"
+
+// layerPath is a config field holding a path, as YAML keys from the document
+// root. "[]" steps into every list element and "*" into every map value.
+type layerPath struct {
+	keys    []string
+	resolve func(pr PathResolver, v any) (any, error)
+}
+
+// layerPaths lists the fields [ConfigFile.resolvePaths] resolves. Each config
+// layer has them resolved against its own directory before the layers are
+// stacked, so an overlay in another directory keeps its relative paths.
+var layerPaths = []layerPath{
+	{keys: []string{"age", "identity_file"}, resolve: resolvePathValue},
+	{keys: []string{"age", "identity_files", "[]"}, resolve: resolvePathValue},
+	{keys: []string{"age", "recipients_file"}, resolve: resolvePathValue},
+	{keys: []string{"age", "recipients_dir"}, resolve: resolvePathValue},
+	{keys: []string{"age", "files", "[]", "src"}, resolve: resolvePathValue},
+	{keys: []string{"age", "files", "[]", "dest"}, resolve: resolvePathValue},
+	{keys: []string{"variables", "var_files", "[]"}, resolve: resolveVarFileValue},
+	{keys: []string{"variables", "by_os", "*", "var_files", "[]"}, resolve: resolveVarFileValue},
+	{keys: []string{"templates", "[]", "template"}, resolve: resolveSourceValue},
+	{keys: []string{"templates", "[]", "output"}, resolve: resolvePathValue},
+	{keys: []string{"templates", "[]", "tests", "[]", "expected"}, resolve: resolvePathValue},
+	{keys: []string{"services", "[]", "template"}, resolve: resolveSourceValue},
+	{keys: []string{"binaries", "[]", "install"}, resolve: resolvePathValue},
+	{keys: []string{"editors", "[]", "settings"}, resolve: resolveSourceValue},
+	{keys: []string{"editors", "[]", "settings_path"}, resolve: resolvePathValue},
+	{keys: []string{"git", "output"}, resolve: resolvePathValue},
+	{keys: []string{"git", "profiles", "[]", "output"}, resolve: resolvePathValue},
+	{keys: []string{"gpg", "keys", "[]", "src"}, resolve: resolvePathValue},
+	{keys: []string{"gpg", "agent", "output"}, resolve: resolvePathValue},
+	{keys: []string{"shell", "dir"}, resolve: resolvePathValue},
+	{keys: []string{"shell", "plugin_file"}, resolve: resolvePathValue},
+	{keys: []string{"metrics", "textfile_dir"}, resolve: resolvePathValue},
+	{keys: []string{"repos", "[]", "path"}, resolve: resolvePathValue},
+	{keys: []string{"exec", "scripts", "[]", "path"}, resolve: resolvePathValue},
+	{keys: []string{"exec", "scripts", "[]", "manages", "[]"}, resolve: resolvePathValue},
+}
+
+// resolveLayerPaths makes the relative paths in a config layer absolute
+// using pr. Paths already absolute are left for [ConfigFile.resolvePaths].
+func resolveLayerPaths(layer map[string]any, pr PathResolver) error {
+	for _, lp := range layerPaths {
+		if _, err := walkLayer(layer, lp.keys, func(v any) (any, error) {
+			return lp.resolve(pr, v)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkLayer replaces the values found at keys in v with the result of fn.
+func walkLayer(v any, keys []string, fn func(any) (any, error)) (any, error) {
+	if len(keys) == 0 {
+		return fn(v)
+	}
+
+	switch keys[0] {
+	case "[]":
+		list, ok := v.([]any)
+		if !ok {
+			return v, nil
+		}
+		for i := range list {
+			resolved, err := walkLayer(list[i], keys[1:], fn)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = resolved
+		}
+	case "*":
+		m, ok := v.(map[string]any)
+		if !ok {
+			return v, nil
+		}
+		for k := range m {
+			resolved, err := walkLayer(m[k], keys[1:], fn)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = resolved
+		}
+	default:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return v, nil
+		}
+		child, ok := m[keys[0]]
+		if !ok {
+			return v, nil
+		}
+		resolved, err := walkLayer(child, keys[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		m[keys[0]] = resolved
+	}
+	return v, nil
+}
+
+func resolvePathValue(pr PathResolver, v any) (any, error) {
+	s, ok := v.(string)
+	if !ok || s == "" {
+		return v, nil
+	}
+	return pr.Resolve(s)
+}
+
+// resolveSourceValue resolves fields that hold either a path or inline text.
+func resolveSourceValue(pr PathResolver, v any) (any, error) {
+	if s, ok := v.(string); ok && strings.ContainsAny(s, "\n{") {
+		return v, nil
+	}
+	return resolvePathValue(pr, v)
+}
+
+// resolveVarFileValue resolves a var file given either as a path with an
+// optional ?query or as a map with a path key.
+func resolveVarFileValue(pr PathResolver, v any) (any, error) {
+	switch vf := v.(type) {
+	case string:
+		path, query, found := strings.Cut(vf, "?")
+		resolved, err := pr.Resolve(path)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			resolved += "?" + query
+		}
+		return resolved, nil
+	case map[string]any:
+		resolved, err := resolvePathValue(pr, vf["path"])
+		if err != nil {
+			return nil, err
+		}
+		if resolved != nil {
+			vf["path"] = resolved
+		}
+	}
+	return v, nil
+}
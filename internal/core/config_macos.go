@@ -0,0 +1,115 @@
+package core
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// MacOS declares macOS preferences applied with `defaults write`. Unset
+// fields are left as they are on the machine.
+type MacOS struct {
+	Dock       MacDock       `yaml:"dock"`
+	Finder     MacFinder     `yaml:"finder"`
+	HotCorners MacHotCorners `yaml:"hot_corners"`
+	Defaults   []MacDefault  `yaml:"defaults"` // any other preference
+}
+
+// MacDock configures the Dock. Apps requires dockutil.
+type MacDock struct {
+	Apps          []string `yaml:"apps"` // application paths, in order
+	Autohide      *bool    `yaml:"autohide"`
+	TileSize      *int     `yaml:"tile_size"`
+	Magnification *bool    `yaml:"magnification"`
+	ShowRecents   *bool    `yaml:"show_recents"`
+	Orientation   string   `yaml:"orientation"` // left, bottom, or right
+}
+
+// MacFinder configures Finder.
+type MacFinder struct {
+	ShowHidden     *bool  `yaml:"show_hidden"`
+	ShowExtensions *bool  `yaml:"show_extensions"`
+	PathBar        *bool  `yaml:"path_bar"`
+	StatusBar      *bool  `yaml:"status_bar"`
+	DefaultView    string `yaml:"default_view"` // icon, list, column, or gallery
+}
+
+// MacHotCorners assigns an action from [HotCornerActions] to each screen
+// corner.
+type MacHotCorners struct {
+	TopLeft     string `yaml:"top_left"`
+	TopRight    string `yaml:"top_right"`
+	BottomLeft  string `yaml:"bottom_left"`
+	BottomRight string `yaml:"bottom_right"`
+}
+
+// MacDefault is a single preference written with `defaults write`.
+type MacDefault struct {
+	Domain string `yaml:"domain"` // e.g. com.apple.dock or NSGlobalDomain
+	Key    string `yaml:"key"`
+	Type   string `yaml:"type"` // bool, int, float, or string
+	Value  any    `yaml:"value"`
+}
+
+// HotCornerActions maps hot corner action names to their Dock codes.
+var HotCornerActions = map[string]int{
+	"none":                 1,
+	"mission-control":      2,
+	"application-windows":  3,
+	"desktop":              4,
+	"start-screen-saver":   5,
+	"disable-screen-saver": 6,
+	"display-sleep":        10,
+	"launchpad":            11,
+	"notification-center":  12,
+	"lock-screen":          13,
+	"quick-note":           14,
+}
+
+// FinderViews maps Finder view names to their preference codes.
+var FinderViews = map[string]string{
+	"icon":    "icnv",
+	"list":    "Nlsv",
+	"column":  "clmv",
+	"gallery": "glyv",
+}
+
+func (m MacOS) Validate() error {
+	switch m.Dock.Orientation {
+	case "", "left", "bottom", "right":
+	default:
+		return fmt.Errorf("macos.dock.orientation: invalid value %q (expected left, bottom, or right)", m.Dock.Orientation)
+	}
+
+	if v := m.Finder.DefaultView; v != "" {
+		if _, ok := FinderViews[v]; !ok {
+			return fmt.Errorf("macos.finder.default_view: invalid value %q (expected one of %v)", v, slices.Sorted(maps.Keys(FinderViews)))
+		}
+	}
+
+	corners := map[string]string{
+		"top_left":     m.HotCorners.TopLeft,
+		"top_right":    m.HotCorners.TopRight,
+		"bottom_left":  m.HotCorners.BottomLeft,
+		"bottom_right": m.HotCorners.BottomRight,
+	}
+	for _, corner := range slices.Sorted(maps.Keys(corners)) {
+		if action := corners[corner]; action != "" {
+			if _, ok := HotCornerActions[action]; !ok {
+				return fmt.Errorf("macos.hot_corners.%s: invalid action %q (expected one of %v)", corner, action, slices.Sorted(maps.Keys(HotCornerActions)))
+			}
+		}
+	}
+
+	for i, d := range m.Defaults {
+		if d.Domain == "" || d.Key == "" {
+			return fmt.Errorf("macos.defaults[%d]: domain and key are required", i)
+		}
+		switch d.Type {
+		case "bool", "int", "float", "string":
+		default:
+			return fmt.Errorf("macos.defaults[%d]: invalid type %q (expected bool, int, float, or string)", i, d.Type)
+		}
+	}
+	return nil
+}
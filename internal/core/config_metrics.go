@@ -0,0 +1,54 @@
+package core
+
+import (
+	"fmt"
+	"net"
+)
+
+// Metrics configures where run and drift metrics are written for
+// monitoring. Both targets are optional.
+type Metrics struct {
+	// TextfileDir is the directory of the node-exporter textfile collector.
+	// Runs write mmdot.prom and `mmdot status` writes mmdot_drift.prom.
+	TextfileDir string `yaml:"textfile_dir"`
+	Statsd      string `yaml:"statsd"` // host:port of a statsd server, sent over UDP
+	Prefix      string `yaml:"prefix"` // statsd metric prefix (default: mmdot)
+}
+
+// Enabled reports whether any metrics target is configured.
+func (m Metrics) Enabled() bool {
+	return m.TextfileDir != "" || m.Statsd != ""
+}
+
+// StatsdPrefix returns the statsd metric prefix, defaulting to "mmdot".
+func (m Metrics) StatsdPrefix() string {
+	if m.Prefix == "" {
+		return "mmdot"
+	}
+	return m.Prefix
+}
+
+func (m Metrics) Validate() error {
+	if m.Statsd != "" {
+		if _, _, err := net.SplitHostPort(m.Statsd); err != nil {
+			return fmt.Errorf("metrics: invalid statsd address %q: %w", m.Statsd, err)
+		}
+	}
+	return nil
+}
+
+func (c *ConfigFile) resolveMetrics(pr PathResolver) error {
+	if err := c.Metrics.Validate(); err != nil {
+		return err
+	}
+	if c.Metrics.TextfileDir == "" {
+		return nil
+	}
+
+	resolved, err := pr.Resolve(c.Metrics.TextfileDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve metrics textfile_dir: %w", err)
+	}
+	c.Metrics.TextfileDir = resolved
+	return nil
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+type NotificationType string
+
+const (
+	NotificationDesktop  NotificationType = "desktop"
+	NotificationWebhook  NotificationType = "webhook"
+	NotificationNtfy     NotificationType = "ntfy"
+	NotificationPushover NotificationType = "pushover"
+)
+
+const (
+	NotifyOnSuccess = "success"
+	NotifyOnFailure = "failure"
+)
+
+// Notification configures a single target notified when a run completes.
+type Notification struct {
+	Type    NotificationType  `yaml:"type"`
+	On      []string          `yaml:"on"`      // success, failure (default: both)
+	URL     string            `yaml:"url"`     // webhook endpoint or ntfy topic URL
+	Token   string            `yaml:"token"`   // ntfy access token or pushover app token
+	User    string            `yaml:"user"`    // pushover user key
+	Headers map[string]string `yaml:"headers"` // extra HTTP headers for webhook and ntfy
+}
+
+func (n Notification) Validate() error {
+	switch n.Type {
+	case NotificationDesktop:
+	case NotificationWebhook, NotificationNtfy:
+		if n.URL == "" {
+			return fmt.Errorf("notification %s: url is required", n.Type)
+		}
+	case NotificationPushover:
+		if n.Token == "" || n.User == "" {
+			return fmt.Errorf("notification %s: token and user are required", n.Type)
+		}
+	default:
+		return fmt.Errorf("notification: invalid type %q (expected %q, %q, %q, or %q)",
+			n.Type, NotificationDesktop, NotificationWebhook, NotificationNtfy, NotificationPushover)
+	}
+
+	for _, on := range n.On {
+		if on != NotifyOnSuccess && on != NotifyOnFailure {
+			return fmt.Errorf("notification %s: invalid on value %q (expected %q or %q)", n.Type, on, NotifyOnSuccess, NotifyOnFailure)
+		}
+	}
+
+	return nil
+}
+
+// Triggers reports whether the notification should fire for a run with the
+// given outcome.
+func (n Notification) Triggers(success bool) bool {
+	if len(n.On) == 0 {
+		return true
+	}
+	if success {
+		return slices.Contains(n.On, NotifyOnSuccess)
+	}
+	return slices.Contains(n.On, NotifyOnFailure)
+}
+
+// ResolveSecrets returns the notification with secret:// references in its
+// url, token, user, and headers replaced by their secrets.
+func (n Notification) ResolveSecrets(ctx context.Context) (Notification, error) {
+	var err error
+	for _, field := range []*string{&n.URL, &n.Token, &n.User} {
+		if *field, err = ResolveSecretString(ctx, *field); err != nil {
+			return n, err
+		}
+	}
+
+	n.Headers = maps.Clone(n.Headers)
+	for k, v := range n.Headers {
+		if n.Headers[k], err = ResolveSecretString(ctx, v); err != nil {
+			return n, fmt.Errorf("header %s: %w", k, err)
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,148 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/hay-kot/mmdot/pkgs/atomicwrite"
+)
+
+// PromptType is the kind of input shown for a prompted variable.
+type PromptType string
+
+const (
+	PromptString   PromptType = "string"
+	PromptPassword PromptType = "password"
+	PromptSelect   PromptType = "select"
+)
+
+// Prompt is a variable asked for interactively the first time it is needed.
+// Answers are cached per machine in the state directory, except for
+// passwords, which are asked for on every run.
+type Prompt struct {
+	Name    string     `yaml:"name"`
+	Message string     `yaml:"message"` // Question shown to the user (default: name)
+	Type    PromptType `yaml:"type"`    // string, password, or select (default: string)
+	Options []string   `yaml:"options"` // Choices for select prompts
+	Default string     `yaml:"default"`
+}
+
+func (p Prompt) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("prompt: name is required")
+	}
+
+	switch p.Type {
+	case "", PromptString, PromptPassword:
+	case PromptSelect:
+		if len(p.Options) == 0 {
+			return fmt.Errorf("prompt %s: select prompts require options", p.Name)
+		}
+		if p.Default != "" && !slices.Contains(p.Options, p.Default) {
+			return fmt.Errorf("prompt %s: default %q is not one of the options", p.Name, p.Default)
+		}
+	default:
+		return fmt.Errorf("prompt %s: invalid type %q (expected string, password, or select)", p.Name, p.Type)
+	}
+
+	return nil
+}
+
+// Title returns the question shown for the prompt.
+func (p Prompt) Title() string {
+	if p.Message != "" {
+		return p.Message
+	}
+	return p.Name
+}
+
+// Cached reports whether answers to the prompt are stored on disk.
+func (p Prompt) Cached() bool {
+	return p.Type != PromptPassword
+}
+
+const promptAnswersFile = "prompts.json"
+
+// LoadPromptAnswers returns the cached prompt answers for the config in
+// configDir. A missing cache is not an error.
+func LoadPromptAnswers(configDir string) (map[string]string, error) {
+	all, err := readPromptAnswers()
+	if err != nil {
+		return nil, err
+	}
+
+	answers := all[configDir]
+	if answers == nil {
+		answers = map[string]string{}
+	}
+	return answers, nil
+}
+
+// SavePromptAnswers merges answers into the cache for the config in configDir.
+func SavePromptAnswers(configDir string, answers map[string]string) error {
+	all, err := readPromptAnswers()
+	if err != nil {
+		return err
+	}
+
+	if all[configDir] == nil {
+		all[configDir] = map[string]string{}
+	}
+	for k, v := range answers {
+		all[configDir][k] = v
+	}
+
+	path, err := promptAnswersPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// Answers are often personal details such as emails
+	return atomicwrite.WriteFile(path, data, 0o600)
+}
+
+// readPromptAnswers returns every cached answer keyed by config directory.
+func readPromptAnswers() (map[string]map[string]string, error) {
+	path, err := promptAnswersPath()
+	if err != nil {
+		return nil, err
+	}
+
+	all := map[string]map[string]string{}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return all, nil
+		}
+		return nil, fmt.Errorf("failed to read prompt answers: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("failed to parse prompt answers %s: %w", path, err)
+	}
+
+	return all, nil
+}
+
+func promptAnswersPath() (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, promptAnswersFile), nil
+}
@@ -0,0 +1,50 @@
+package core
+
+import "fmt"
+
+// GitRepo declares a git repository cloned to Path. Repositories follow
+// Branch (default: the remote's default branch) and are fast-forwarded on
+// sync, or are checked out at Revision when it is set.
+type GitRepo struct {
+	URL      string `yaml:"url"`
+	Path     string `yaml:"path"`
+	Branch   string `yaml:"branch"`
+	Revision string `yaml:"revision"` // Commit or tag; the checkout is detached
+	Shallow  bool   `yaml:"shallow"`  // Clone and fetch with --depth 1
+}
+
+func (r GitRepo) Validate() error {
+	if r.URL == "" {
+		return fmt.Errorf("repo: url is required")
+	}
+	if r.Path == "" {
+		return fmt.Errorf("repo %s: path is required", r.URL)
+	}
+	if r.Branch != "" && r.Revision != "" {
+		return fmt.Errorf("repo %s: branch and revision are mutually exclusive", r.URL)
+	}
+	return nil
+}
+
+// resolveRepos validates the repos section and resolves their paths.
+func (c *ConfigFile) resolveRepos(pr PathResolver) error {
+	seen := map[string]string{}
+	for i := range c.Repos {
+		r := &c.Repos[i]
+		if err := r.Validate(); err != nil {
+			return err
+		}
+
+		resolved, err := pr.Resolve(r.Path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve repo path: %w", err)
+		}
+		r.Path = resolved
+
+		if other, ok := seen[r.Path]; ok {
+			return fmt.Errorf("repo %s: path %s is also used by %s", r.URL, r.Path, other)
+		}
+		seen[r.Path] = r.URL
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/hay-kot/mmdot/pkgs/fcrypt"
+	"github.com/hay-kot/mmdot/pkgs/redact"
+)
+
+// AgeTag marks a config value holding ASCII-armored age ciphertext. Tagged
+// values are decrypted with the age identities when the config is loaded:
+//
+//	notify:
+//	  webhook: !age |
+//	    -----BEGIN AGE ENCRYPTED FILE-----
+//	    ...
+//	    -----END AGE ENCRYPTED FILE-----
+const AgeTag = "!age"
+
+// decryptConfigLayers replaces every value tagged with [AgeTag] in each config
+// layer with its plaintext. The identities are read from the last layers that
+// set age.identity_file and age.identity_files, and only when a tagged value
+// exists.
+func decryptConfigLayers(paths []string, layers [][]byte) ([][]byte, error) {
+	var identity age.Identity
+	loadIdentity := func() (age.Identity, error) {
+		if identity != nil {
+			return identity, nil
+		}
+
+		var a Age
+		for i, data := range layers {
+			var layer struct {
+				Age Age `yaml:"age"`
+			}
+			if err := yaml.Unmarshal(data, &layer); err != nil {
+				return nil, err
+			}
+
+			// Paths are relative to the layer that sets them.
+			pr := PathResolver{configDir: filepath.Dir(paths[i])}
+			if layer.Age.IdentityFile != "" {
+				resolved, err := pr.Resolve(layer.Age.IdentityFile)
+				if err != nil {
+					return nil, err
+				}
+				a.IdentityFile = resolved
+			}
+			if len(layer.Age.IdentityFiles) > 0 {
+				a.IdentityFiles = make([]string, len(layer.Age.IdentityFiles))
+				for j, path := range layer.Age.IdentityFiles {
+					resolved, err := pr.Resolve(path)
+					if err != nil {
+						return nil, err
+					}
+					a.IdentityFiles[j] = resolved
+				}
+			}
+		}
+		if len(a.IdentityPaths()) == 0 {
+			return nil, fmt.Errorf("config contains %s values but age.identity_file is not set", AgeTag)
+		}
+
+		var err error
+		identity, err = a.ReadIdentity()
+		return identity, err
+	}
+
+	out := make([][]byte, len(layers))
+	for i, data := range layers {
+		decrypted, err := decryptTaggedValues(data, loadIdentity)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = decrypted
+	}
+	return out, nil
+}
+
+// decryptTaggedValues returns data with every [AgeTag] value replaced by its
+// decrypted string. data is returned unchanged when nothing is tagged.
+func decryptTaggedValues(data []byte, loadIdentity func() (age.Identity, error)) ([]byte, error) {
+	if !bytes.Contains(data, []byte(AgeTag)) {
+		return data, nil
+	}
+
+	file, err := parser.ParseBytes(data, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []*ast.TagNode
+	for _, doc := range file.Docs {
+		if doc.Body != nil {
+			ast.Walk(ageTagVisitor{tags: &tags}, doc.Body)
+		}
+	}
+	if len(tags) == 0 {
+		return data, nil
+	}
+
+	identity, err := loadIdentity()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tag := range tags {
+		path := tag.GetPath()
+
+		var armored string
+		if err := yaml.NodeToValue(tag.Value, &armored); err != nil {
+			return nil, fmt.Errorf("%s: %s value must be a string: %w", path, AgeTag, err)
+		}
+
+		var plaintext bytes.Buffer
+		if err := fcrypt.DecryptReader(strings.NewReader(armored), &plaintext, identity); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		redact.Add(strings.TrimPrefix(path, "$."), plaintext.String())
+
+		replacement, err := yaml.Marshal(plaintext.String())
+		if err != nil {
+			return nil, err
+		}
+
+		p, err := yaml.PathString(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if err := p.ReplaceWithReader(file, bytes.NewReader(replacement)); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	return []byte(file.String()), nil
+}
+
+type ageTagVisitor struct {
+	tags *[]*ast.TagNode
+}
+
+func (v ageTagVisitor) Visit(node ast.Node) ast.Visitor {
+	if tag, ok := node.(*ast.TagNode); ok && tag.Start.Value == AgeTag {
+		*v.tags = append(*v.tags, tag)
+	}
+	return v
+}
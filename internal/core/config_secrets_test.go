@@ -0,0 +1,106 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/hay-kot/mmdot/pkgs/fcrypt"
+)
+
+func TestLoadConfig_AgeTaggedValues(t *testing.T) {
+	dir := t.TempDir()
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	write("key.txt", identity.String()+"\n")
+
+	// encrypted returns value as an indented !age block
+	encrypted := func(value, indent string) string {
+		var buf bytes.Buffer
+		if err := fcrypt.EncryptReader(strings.NewReader(value), &buf, []age.Recipient{identity.Recipient()}); err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		return AgeTag + " |\n" + indent + strings.Join(lines, "\n"+indent)
+	}
+
+	base := write("mmdot.yml", `
+age:
+  identity_file: key.txt
+variables:
+  vars:
+    token: `+encrypted("s3cret", "      ")+`
+    plain: visible
+`)
+	overlay := write("work.yml", `
+variables:
+  vars:
+    work_token: `+encrypted("w0rk", "      ")+`
+`)
+
+	cfg, err := LoadConfig(base, overlay)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	want := map[string]string{"token": "s3cret", "plain": "visible", "work_token": "w0rk"}
+	for k, v := range want {
+		if got := cfg.Variables.Vars[k]; got != v {
+			t.Errorf("vars[%s] = %#v, want %q", k, got, v)
+		}
+	}
+}
+
+func TestLoadConfig_AgeTaggedValuesWithoutIdentity(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mmdot.yml")
+	if err := os.WriteFile(path, []byte("variables:\n  vars:\n    token: !age abc\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadConfig(path)
+	if err == nil || !strings.Contains(err.Error(), "age.identity_file is not set") {
+		t.Errorf("LoadConfig() error = %v, want missing identity error", err)
+	}
+}
+
+func BenchmarkDecryptTaggedValues(b *testing.B) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	var doc strings.Builder
+	doc.WriteString("variables:\n  vars:\n")
+	for i := range 20 {
+		var buf bytes.Buffer
+		if err := fcrypt.EncryptReader(strings.NewReader("s3cret"), &buf, []age.Recipient{identity.Recipient()}); err != nil {
+			b.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		fmt.Fprintf(&doc, "    token%d: %s |\n      %s\n", i, AgeTag, strings.Join(lines, "\n      "))
+	}
+	data := []byte(doc.String())
+	loadIdentity := func() (age.Identity, error) { return identity, nil }
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := decryptTaggedValues(data, loadIdentity); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Service declares a systemd user unit (or launchd agent on macOS) whose unit
+// file is rendered from a template and kept enabled and running.
+type Service struct {
+	Name     string         `yaml:"name"`     // Unit name, e.g. "syncthing.service" or "com.me.sync"
+	Tags     []string       `yaml:"tags"`     // for filtering with selectors
+	Template string         `yaml:"template"` // File or Template for the unit file
+	Vars     map[string]any `yaml:"vars"`     // Service-specific template variables
+	Enable   *bool          `yaml:"enable"`   // Enable the unit at login (default: true)
+	Start    *bool          `yaml:"start"`    // Start, or restart when changed (default: true)
+	Stage    Stage          `yaml:"stage"`    // pre, main, or post (default: main)
+}
+
+func (s Service) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("service: name is required")
+	}
+	if strings.ContainsAny(s.Name, `/\`) {
+		return fmt.Errorf("service %s: name must not contain path separators", s.Name)
+	}
+	if s.Template == "" {
+		return fmt.Errorf("service %s: template is required", s.Name)
+	}
+	if err := s.Stage.Validate(); err != nil {
+		return fmt.Errorf("service %s: %w", s.Name, err)
+	}
+	return nil
+}
+
+func (s Service) ShouldEnable() bool {
+	return s.Enable == nil || *s.Enable
+}
+
+func (s Service) ShouldStart() bool {
+	return s.Start == nil || *s.Start
+}
@@ -0,0 +1,176 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Shell declares a shell plugin manager and its plugins. mmdot clones the
+// manager and every plugin to pinned commits and writes the manager's plugin
+// list (a template tagged "shell") pointing at the local clones, so the
+// manager itself never fetches anything.
+type Shell struct {
+	Manager    string        `yaml:"manager"`     // antidote (zsh) or fisher (fish)
+	Commit     string        `yaml:"commit"`      // Commit of the manager repository
+	Dir        string        `yaml:"dir"`         // Where repositories are cloned (default: <state dir>/shell)
+	PluginFile string        `yaml:"plugin_file"` // default: ~/.zsh_plugins.txt or ~/.config/fish/fish_plugins
+	Plugins    []ShellPlugin `yaml:"plugins"`
+}
+
+// ShellPlugin is a git repository checked out at a pinned commit.
+type ShellPlugin struct {
+	Repo        string `yaml:"repo"`        // GitHub owner/name or a git URL
+	Commit      string `yaml:"commit"`      // Full commit hash
+	Annotations string `yaml:"annotations"` // antidote only, e.g. "kind:defer"
+}
+
+// ShellManager describes a supported plugin manager.
+type ShellManager struct {
+	Repo       string
+	PluginFile string
+}
+
+// ShellManagers lists the supported plugin managers.
+var ShellManagers = map[string]ShellManager{
+	"antidote": {Repo: "mattmc3/antidote", PluginFile: "~/.zsh_plugins.txt"},
+	"fisher":   {Repo: "jorgebucaran/fisher", PluginFile: "~/.config/fish/fish_plugins"},
+}
+
+// ShellTag is the tag of the template generated from the shell section.
+const ShellTag = "shell"
+
+var shellCommitRe = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// URL returns the clone URL of the plugin.
+func (p ShellPlugin) URL() string {
+	if binaryRepoRe.MatchString(p.Repo) {
+		return "https://github.com/" + p.Repo + ".git"
+	}
+	return p.Repo
+}
+
+// Name returns the plugin's owner/name, derived from the last two path
+// elements of a URL.
+func (p ShellPlugin) Name() string {
+	if binaryRepoRe.MatchString(p.Repo) {
+		return p.Repo
+	}
+	parts := strings.FieldsFunc(strings.TrimSuffix(strings.TrimRight(p.Repo, "/"), ".git"), func(r rune) bool {
+		return r == '/' || r == ':'
+	})
+	if len(parts) < 2 {
+		return strings.Join(parts, "")
+	}
+	return parts[len(parts)-2] + "/" + parts[len(parts)-1]
+}
+
+func (p ShellPlugin) Validate() error {
+	if p.Repo == "" {
+		return fmt.Errorf("shell plugin: repo is required")
+	}
+	if !shellCommitRe.MatchString(p.Commit) {
+		return fmt.Errorf("shell plugin %s: commit must be a full 40 character commit hash", p.Repo)
+	}
+	return nil
+}
+
+// ManagerPlugin returns the manager repository as a plugin.
+func (s Shell) ManagerPlugin() ShellPlugin {
+	return ShellPlugin{Repo: ShellManagers[s.Manager].Repo, Commit: s.Commit}
+}
+
+// RepoDir returns where a plugin is cloned.
+func (s Shell) RepoDir(p ShellPlugin) string {
+	return filepath.Join(s.Dir, filepath.FromSlash(p.Name()))
+}
+
+func (s Shell) Validate() error {
+	if _, ok := ShellManagers[s.Manager]; !ok {
+		return fmt.Errorf("shell: invalid manager %q (expected antidote or fisher)", s.Manager)
+	}
+	if !shellCommitRe.MatchString(s.Commit) {
+		return fmt.Errorf("shell: commit of %s must be a full 40 character commit hash", s.Manager)
+	}
+
+	seen := map[string]bool{s.ManagerPlugin().Name(): true}
+	for _, p := range s.Plugins {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+		if p.Annotations != "" && s.Manager != "antidote" {
+			return fmt.Errorf("shell plugin %s: annotations are only supported by antidote", p.Repo)
+		}
+		if seen[p.Name()] {
+			return fmt.Errorf("shell plugin %s: duplicate plugin", p.Repo)
+		}
+		seen[p.Name()] = true
+	}
+	return nil
+}
+
+// template returns the template rendering the plugin list. Paths must
+// already be resolved.
+func (s Shell) template() Template {
+	var sb strings.Builder
+	sb.WriteString("# Managed by mmdot. Changes will be overwritten.\n")
+	if s.Manager == "fisher" {
+		// fisher updates itself like any other plugin
+		sb.WriteString(s.RepoDir(s.ManagerPlugin()) + "\n")
+	}
+	for _, p := range s.Plugins {
+		line := s.RepoDir(p)
+		if p.Annotations != "" {
+			line += " " + p.Annotations
+		}
+		sb.WriteString(line + "\n")
+	}
+
+	return Template{
+		Name:     "shell-plugins",
+		Tags:     []string{ShellTag},
+		Template: sb.String(),
+		Output:   s.PluginFile,
+	}
+}
+
+// resolveShell validates the shell section, resolves its paths, and appends
+// the plugin list template to c.
+func (c *ConfigFile) resolveShell(pr PathResolver) error {
+	if c.Shell.Manager == "" && len(c.Shell.Plugins) == 0 {
+		return nil
+	}
+	if err := c.Shell.Validate(); err != nil {
+		return err
+	}
+
+	if c.Shell.Dir == "" {
+		dir, err := StateDir()
+		if err != nil {
+			return err
+		}
+		c.Shell.Dir = filepath.Join(dir, "shell")
+	}
+	resolved, err := pr.Resolve(c.Shell.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve shell dir: %w", err)
+	}
+	c.Shell.Dir = resolved
+
+	if c.Shell.PluginFile == "" {
+		c.Shell.PluginFile = ShellManagers[c.Shell.Manager].PluginFile
+	}
+	if c.Shell.PluginFile, err = pr.Resolve(c.Shell.PluginFile); err != nil {
+		return fmt.Errorf("failed to resolve shell plugin_file: %w", err)
+	}
+
+	tmpl := c.Shell.template()
+	for _, t := range c.Templates {
+		if t.Name == tmpl.Name {
+			return fmt.Errorf("template %s: name is reserved for the shell section", tmpl.Name)
+		}
+	}
+	c.Templates = append(c.Templates, tmpl)
+	return nil
+}
@@ -0,0 +1,51 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// Skip reasons returned by [Template.SkipReason].
+const (
+	SkipReasonExists    = "file exists"
+	SkipReasonNoClobber = "file exists, no_clobber is set; use --force to overwrite"
+	SkipReasonCondition = "condition"
+)
+
+// SkipReason evaluates the template's skip conditions and returns why it
+// should not be rendered, or "" when it should. skip_if commands run with
+// shell -c from dir; a zero exit status skips the template. force overrides
+// no_clobber but not skip_if_exists.
+func (t Template) SkipReason(ctx context.Context, shell, dir string, force bool) (string, error) {
+	if t.SkipIfExists || (t.NoClobber && !force) {
+		if _, err := os.Stat(t.Output); err == nil {
+			if t.SkipIfExists {
+				return SkipReasonExists, nil
+			}
+			return SkipReasonNoClobber, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("failed to stat output: %w", err)
+		}
+	}
+
+	if t.SkipIf != "" {
+		cmd := exec.CommandContext(ctx, shell, "-c", t.SkipIf)
+		cmd.Dir = dir
+		cmd.Stderr = os.Stderr
+
+		err := cmd.Run()
+		if err == nil {
+			return SkipReasonCondition, nil
+		}
+
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return "", fmt.Errorf("skip_if %q: %w", t.SkipIf, err)
+		}
+	}
+
+	return "", nil
+}
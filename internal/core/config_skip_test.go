@@ -0,0 +1,47 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTemplate_SkipReason(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing")
+	if err := os.WriteFile(existing, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing")
+
+	tests := []struct {
+		name  string
+		tmpl  Template
+		force bool
+		want  string
+	}{
+		{name: "no conditions", tmpl: Template{Output: existing}},
+		{name: "exists", tmpl: Template{Output: existing, SkipIfExists: true}, want: SkipReasonExists},
+		{name: "not exists", tmpl: Template{Output: missing, SkipIfExists: true}},
+		{name: "exists forced", tmpl: Template{Output: existing, SkipIfExists: true}, force: true, want: SkipReasonExists},
+		{name: "no clobber", tmpl: Template{Output: existing, NoClobber: true}, want: SkipReasonNoClobber},
+		{name: "no clobber forced", tmpl: Template{Output: existing, NoClobber: true}, force: true},
+		{name: "no clobber missing", tmpl: Template{Output: missing, NoClobber: true}},
+		{name: "command true", tmpl: Template{Output: missing, SkipIf: "true"}, want: SkipReasonCondition},
+		{name: "command false", tmpl: Template{Output: missing, SkipIf: "false"}},
+		{name: "command runs in dir", tmpl: Template{Output: missing, SkipIf: "test -f existing"}, want: SkipReasonCondition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.tmpl.SkipReason(context.Background(), "/bin/sh", dir, tt.force)
+			if err != nil {
+				t.Fatalf("SkipReason() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("SkipReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package core
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// OverrideTag marks a value in an overlay config that replaces the value from
+// earlier configs instead of merging with it.
+const OverrideTag = "!override"
+
+// stackConfigs merges the YAML documents read from paths, in order, into a
+// single document. Later files take precedence:
+//
+//   - maps are merged key by key, recursively
+//   - lists are appended to the earlier list
+//   - scalars replace the earlier value
+//   - any value tagged !override replaces the earlier value entirely
+//
+// Relative paths in each document are resolved against its own directory.
+func stackConfigs(paths []string, layers [][]byte) ([]byte, error) {
+	var merged map[string]any
+	for i, path := range paths {
+		data := layers[i]
+
+		layer := map[string]any{}
+		if err := yaml.Unmarshal(data, &layer); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if err := resolveLayerPaths(layer, PathResolver{configDir: filepath.Dir(path)}); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		overrides, err := overridePaths(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		if merged == nil {
+			merged = layer
+			continue
+		}
+		merged = mergeMaps(merged, layer, "$", overrides)
+	}
+
+	return yaml.Marshal(merged)
+}
+
+// overridePaths returns the YAML paths (e.g. "$.brews.work.brews") of values
+// tagged with [OverrideTag].
+func overridePaths(data []byte) (map[string]bool, error) {
+	file, err := parser.ParseBytes(data, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := map[string]bool{}
+	for _, doc := range file.Docs {
+		if doc.Body != nil {
+			ast.Walk(overrideVisitor(paths), doc.Body)
+		}
+	}
+	return paths, nil
+}
+
+type overrideVisitor map[string]bool
+
+func (v overrideVisitor) Visit(node ast.Node) ast.Visitor {
+	if tag, ok := node.(*ast.TagNode); ok && tag.Start.Value == OverrideTag {
+		v[tag.GetPath()] = true
+	}
+	return v
+}
+
+func mergeMaps(base, overlay map[string]any, path string, overrides map[string]bool) map[string]any {
+	out := maps.Clone(base)
+	for key, value := range overlay {
+		childPath := path + "." + key
+
+		existing, ok := out[key]
+		if !ok || overrides[childPath] {
+			out[key] = value
+			continue
+		}
+
+		switch v := value.(type) {
+		case map[string]any:
+			if e, ok := existing.(map[string]any); ok {
+				out[key] = mergeMaps(e, v, childPath, overrides)
+				continue
+			}
+		case []any:
+			if e, ok := existing.([]any); ok {
+				out[key] = append(append([]any{}, e...), v...)
+				continue
+			}
+		}
+
+		out[key] = value
+	}
+	return out
+}
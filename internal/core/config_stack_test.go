@@ -0,0 +1,156 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLoadConfig_Overlays(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	base := write("base.yml", `
+run:
+  before: ["echo base"]
+  default_expr: "+base"
+exec:
+  shell: /bin/bash
+variables:
+  vars:
+    name: base
+    editor: vim
+brews:
+  work:
+    brews: [git]
+    casks: [slack]
+`)
+	overlay := write("work.yml", `
+run:
+  before: !override ["echo work"]
+variables:
+  vars:
+    name: work
+brews:
+  work:
+    brews: [jq]
+    casks: !override [zoom]
+`)
+
+	cfg, err := LoadConfig(base, overlay)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	if want := []string{"echo work"}; !slices.Equal(cfg.Run.Before, want) {
+		t.Errorf("Run.Before = %v, want %v (override)", cfg.Run.Before, want)
+	}
+	if cfg.Run.DefaultExpr != "+base" {
+		t.Errorf("Run.DefaultExpr = %q, want base value kept", cfg.Run.DefaultExpr)
+	}
+	if cfg.Exec.Shell != "/bin/bash" {
+		t.Errorf("Exec.Shell = %q, want /bin/bash", cfg.Exec.Shell)
+	}
+	if cfg.Variables.Vars["name"] != "work" || cfg.Variables.Vars["editor"] != "vim" {
+		t.Errorf("Vars = %v, want deep merge", cfg.Variables.Vars)
+	}
+
+	work := cfg.Brews["work"]
+	if want := []string{"git", "jq"}; !slices.Equal(PackageNames(work.Brews), want) {
+		t.Errorf("Brews = %v, want %v (append)", work.Brews, want)
+	}
+	if want := []string{"zoom"}; !slices.Equal(PackageNames(work.Casks), want) {
+		t.Errorf("Casks = %v, want %v (override)", work.Casks, want)
+	}
+	if cfg.ConfigDir != dir {
+		t.Errorf("ConfigDir = %q, want %q", cfg.ConfigDir, dir)
+	}
+}
+
+func TestLoadConfig_OverlayPaths(t *testing.T) {
+	dir := t.TempDir()
+	overlayDir := filepath.Join(dir, "work")
+	if err := os.MkdirAll(overlayDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	base := filepath.Join(dir, "base.yml")
+	if err := os.WriteFile(base, []byte(`
+variables:
+  var_files: [vars.yml]
+templates:
+  - name: base
+    template: base.tmpl
+    output: out/base
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	overlay := filepath.Join(overlayDir, "work.yml")
+	if err := os.WriteFile(overlay, []byte(`
+variables:
+  var_files:
+    - secrets.yml?vault=true
+    - path: extra.yml
+templates:
+  - name: work
+    template: work.tmpl
+    output: out/work
+  - name: inline
+    template: "{{ .name }}"
+    output: ~/inline
+exec:
+  scripts:
+    - path: setup.sh
+      manages: [state]
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(base, overlay)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	var varFiles []string
+	for _, vf := range cfg.Variables.VarFiles {
+		varFiles = append(varFiles, vf.Path)
+	}
+	want := []string{
+		filepath.Join(dir, "vars.yml"),
+		filepath.Join(overlayDir, "secrets.yml"),
+		filepath.Join(overlayDir, "extra.yml"),
+	}
+	if !slices.Equal(varFiles, want) {
+		t.Errorf("var files = %v, want %v", varFiles, want)
+	}
+	if !cfg.Variables.VarFiles[1].IsVault {
+		t.Error("overlay var file lost its ?vault=true query")
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates := map[string][2]string{
+		"base":   {filepath.Join(dir, "base.tmpl"), filepath.Join(dir, "out", "base")},
+		"work":   {filepath.Join(overlayDir, "work.tmpl"), filepath.Join(overlayDir, "out", "work")},
+		"inline": {"{{ .name }}", filepath.Join(home, "inline")},
+	}
+	for _, tmpl := range cfg.Templates {
+		if got, want := [2]string{tmpl.Template, tmpl.Output}, templates[tmpl.Name]; got != want {
+			t.Errorf("template %s = %v, want %v", tmpl.Name, got, want)
+		}
+	}
+
+	script := cfg.Exec.Scripts[0]
+	if script.Path != filepath.Join(overlayDir, "setup.sh") || script.Manages[0] != filepath.Join(overlayDir, "state") {
+		t.Errorf("script = %+v, want paths in %s", script, overlayDir)
+	}
+}
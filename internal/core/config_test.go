@@ -3,6 +3,7 @@ package core
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/goccy/go-yaml"
@@ -77,6 +78,42 @@ files:
 	}
 }
 
+func TestVarFile_YAMLParsing(t *testing.T) {
+	input := `
+var_files:
+  - vars.yml
+  - secret.yml?vault=true
+  - work.yml?vault=true&optional=true
+  - path: team.yml
+    vault: true
+    optional: true
+  - shared.yml?partial=true
+  - path: work.yml
+    recipients: [age1work]
+`
+	var vars Variables
+	if err := yaml.Unmarshal([]byte(input), &vars); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+
+	want := []VarFile{
+		{Path: "vars.yml"},
+		{Path: "secret.yml", IsVault: true},
+		{Path: "work.yml", IsVault: true, Optional: true},
+		{Path: "team.yml", IsVault: true, Optional: true},
+		{Path: "shared.yml", Partial: true},
+		{Path: "work.yml", Recipients: []string{"age1work"}},
+	}
+	if len(vars.VarFiles) != len(want) {
+		t.Fatalf("expected %d var files, got %d", len(want), len(vars.VarFiles))
+	}
+	for i, vf := range vars.VarFiles {
+		if !reflect.DeepEqual(vf, want[i]) {
+			t.Errorf("var_files[%d] = %+v, want %+v", i, vf, want[i])
+		}
+	}
+}
+
 func TestResolvePaths_AgeFiles(t *testing.T) {
 	cfg := &ConfigFile{
 		Age: Age{
@@ -106,6 +143,49 @@ func TestResolvePaths_AgeFiles(t *testing.T) {
 	}
 }
 
+func TestAge_RecipientsFor(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "recipients.txt")
+	if err := os.WriteFile(file, []byte("# team\nage1file\n\nage1shared\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	keyring := filepath.Join(dir, "recipients")
+	if err := os.MkdirAll(keyring, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{"alice": "age1alice\n", "bob": "age1shared\n", ".hidden": "age1hidden\n"} {
+		if err := os.WriteFile(filepath.Join(keyring, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a := Age{Recipients: []string{"age1inline"}, RecipientsFile: file, RecipientsDir: keyring}
+
+	got, err := a.RecipientsFor(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"age1alice", "age1file", "age1inline", "age1shared"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RecipientsFor(nil) = %v, want %v", got, want)
+	}
+
+	got, err = a.RecipientsFor([]string{"age1override"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{"age1override"}) {
+		t.Errorf("RecipientsFor(override) = %v", got)
+	}
+
+	if err := os.WriteFile(filepath.Join(keyring, "empty"), []byte("# nobody\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.RecipientsFor(nil); err == nil {
+		t.Error("RecipientsFor() accepted a recipients file without keys")
+	}
+}
+
 func TestParseOctalPermissions(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -194,3 +274,67 @@ func TestResolvePaths_AgeFiles_ValidationFailure(t *testing.T) {
 		t.Fatal("resolvePaths() expected error for invalid AgeFile, got nil")
 	}
 }
+
+func TestConfigFile_InvalidStage(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "mmdot.yml")
+	data := "exec:\n  scripts:\n    - path: setup.sh\n      stage: early\n"
+	if err := os.WriteFile(cfgPath, []byte(data), 0o644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+
+	if _, err := SetupEnv(cfgPath); err == nil {
+		t.Fatal("SetupEnv() expected error for invalid stage")
+	}
+}
+
+func TestConfigFile_CreateOnly(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "mmdot.yml")
+	data := "templates:\n  - name: seed\n    template: seed.tmpl\n    output: seed\n    create_only: true\n"
+	if err := os.WriteFile(cfgPath, []byte(data), 0o644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+
+	cfg, err := LoadConfig(cfgPath)
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+	if !cfg.Templates[0].SkipIfExists {
+		t.Error("create_only did not set SkipIfExists")
+	}
+}
+
+func TestLoadConfig_ConfigPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	dir := filepath.Join(home, "dotfiles")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "mmdot.yml"), []byte("age:\n  identity_file: key.txt\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Chdir(home)
+
+	for _, input := range []string{
+		"~/dotfiles/mmdot.yml",
+		"dotfiles/mmdot.yml",
+		filepath.Join(dir, "mmdot.yml"),
+	} {
+		t.Run(input, func(t *testing.T) {
+			cfg, err := LoadConfig(input)
+			if err != nil {
+				t.Fatalf("LoadConfig(%q) error = %v", input, err)
+			}
+			if cfg.ConfigDir != dir {
+				t.Errorf("ConfigDir = %q, want %q", cfg.ConfigDir, dir)
+			}
+			if want := filepath.Join(dir, "key.txt"); cfg.Age.IdentityFile != want {
+				t.Errorf("IdentityFile = %q, want %q", cfg.Age.IdentityFile, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// VarType is the type a required template variable is coerced to.
+type VarType string
+
+const (
+	VarTypeAny    VarType = ""
+	VarTypeString VarType = "string"
+	VarTypeInt    VarType = "int"
+	VarTypeFloat  VarType = "float"
+	VarTypeBool   VarType = "bool"
+	VarTypeList   VarType = "list"
+	VarTypeMap    VarType = "map"
+)
+
+func (t VarType) Validate() error {
+	switch t {
+	case VarTypeAny, VarTypeString, VarTypeInt, VarTypeFloat, VarTypeBool, VarTypeList, VarTypeMap:
+		return nil
+	default:
+		return fmt.Errorf("invalid type %q (expected string, int, float, bool, list, or map)", t)
+	}
+}
+
+// RequiredVar declares a variable a template needs. In YAML it is either a
+// bare name or a mapping with name, type, and default.
+type RequiredVar struct {
+	Name    string  `yaml:"name"`
+	Type    VarType `yaml:"type"`    // Coerce the value to this type (default: any)
+	Default any     `yaml:"default"` // Used when the variable is not set
+}
+
+func (rv *RequiredVar) UnmarshalYAML(unmarshal func(any) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		rv.Name = name
+		return nil
+	}
+
+	type plain RequiredVar
+	return unmarshal((*plain)(rv))
+}
+
+func (rv RequiredVar) Validate() error {
+	if rv.Name == "" {
+		return fmt.Errorf("requires_vars: name is required")
+	}
+	if err := rv.Type.Validate(); err != nil {
+		return fmt.Errorf("requires_vars %s: %w", rv.Name, err)
+	}
+	if rv.Default != nil {
+		if _, err := rv.Coerce(rv.Default); err != nil {
+			return fmt.Errorf("requires_vars %s: default: %w", rv.Name, err)
+		}
+	}
+	return nil
+}
+
+// Coerce converts v to the declared type. Strings are parsed for numeric and
+// boolean types, so values from the environment or prompts can be used as-is.
+func (rv RequiredVar) Coerce(v any) (any, error) {
+	switch rv.Type {
+	case VarTypeAny:
+		return v, nil
+	case VarTypeString:
+		switch v := v.(type) {
+		case map[string]any, []any:
+			return nil, fmt.Errorf("cannot use %T as string", v)
+		default:
+			return fmt.Sprint(v), nil
+		}
+	case VarTypeInt:
+		switch v := v.(type) {
+		case int:
+			return v, nil
+		case int64:
+			return int(v), nil
+		case uint64:
+			return int(v), nil
+		case float64:
+			if v == math.Trunc(v) {
+				return int(v), nil
+			}
+		case string:
+			if n, err := strconv.Atoi(v); err == nil {
+				return n, nil
+			}
+		}
+		return nil, fmt.Errorf("cannot convert %v to int", v)
+	case VarTypeFloat:
+		switch v := v.(type) {
+		case float64:
+			return v, nil
+		case int:
+			return float64(v), nil
+		case int64:
+			return float64(v), nil
+		case uint64:
+			return float64(v), nil
+		case string:
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return f, nil
+			}
+		}
+		return nil, fmt.Errorf("cannot convert %v to float", v)
+	case VarTypeBool:
+		switch v := v.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if b, err := strconv.ParseBool(v); err == nil {
+				return b, nil
+			}
+		}
+		return nil, fmt.Errorf("cannot convert %v to bool", v)
+	case VarTypeList:
+		if l, ok := v.([]any); ok {
+			return l, nil
+		}
+		return nil, fmt.Errorf("cannot use %T as list", v)
+	case VarTypeMap:
+		if m, ok := v.(map[string]any); ok {
+			return m, nil
+		}
+		return nil, fmt.Errorf("cannot use %T as map", v)
+	}
+
+	return nil, rv.Type.Validate()
+}
+
+// ApplyRequiredVars fills in defaults and coerces each required variable in
+// vars, which is modified in place. It returns one message per variable that
+// is missing or has the wrong type; vars is only fully usable when none are
+// returned.
+func ApplyRequiredVars(vars map[string]any, required []RequiredVar) []string {
+	var problems []string
+	for _, rv := range required {
+		v, ok := vars[rv.Name]
+		if !ok || v == nil {
+			if rv.Default == nil {
+				problems = append(problems, rv.Name+": missing")
+				continue
+			}
+			v = rv.Default
+		}
+
+		coerced, err := rv.Coerce(v)
+		if err != nil {
+			problems = append(problems, rv.Name+": "+err.Error())
+			continue
+		}
+		vars[rv.Name] = coerced
+	}
+	return problems
+}
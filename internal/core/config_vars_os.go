@@ -0,0 +1,72 @@
+package core
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// knownOS are the values of runtime.GOOS accepted as variables.by_os keys.
+var knownOS = []string{"aix", "android", "darwin", "dragonfly", "freebsd", "illumos", "ios", "linux", "netbsd", "openbsd", "plan9", "solaris", "windows"}
+
+// OSVariables are merged over the base variables when mmdot runs on the
+// operating system they are keyed by:
+//
+//	variables:
+//	  vars:
+//	    brew_prefix: /usr/local
+//	  by_os:
+//	    darwin:
+//	      vars:
+//	        brew_prefix: /opt/homebrew
+//	      var_files: [vars/darwin.yml]
+type OSVariables struct {
+	Vars     map[string]any `yaml:"vars"`
+	VarFiles []VarFile      `yaml:"var_files"` // read after the base var_files
+}
+
+func (v Variables) validateByOS() error {
+	for goos := range v.ByOS {
+		if !slices.Contains(knownOS, goos) {
+			hint := ""
+			if goos == "macos" || goos == "osx" {
+				hint = ", use darwin for macOS"
+			}
+			return fmt.Errorf("variables.by_os: unknown operating system %q%s", goos, hint)
+		}
+	}
+	return nil
+}
+
+// applyOS merges the by_os entry for goos over the base vars and var files.
+// by_os itself is kept so files for other systems can still be encrypted.
+func (v *Variables) applyOS(goos string) {
+	overlay, ok := v.ByOS[goos]
+	if !ok {
+		return
+	}
+
+	if len(overlay.Vars) > 0 {
+		vars := maps.Clone(v.Vars)
+		if vars == nil {
+			vars = map[string]any{}
+		}
+		maps.Copy(vars, overlay.Vars)
+		v.Vars = vars
+	}
+	v.VarFiles = append(slices.Clone(v.VarFiles), overlay.VarFiles...)
+}
+
+// AllVarFiles returns the var files for this system followed by the by_os
+// var files of every other system, each path once.
+func (v Variables) AllVarFiles() []VarFile {
+	files := slices.Clone(v.VarFiles)
+	for _, goos := range slices.Sorted(maps.Keys(v.ByOS)) {
+		for _, vf := range v.ByOS[goos].VarFiles {
+			if !slices.ContainsFunc(files, func(f VarFile) bool { return f.Path == vf.Path }) {
+				files = append(files, vf)
+			}
+		}
+	}
+	return files
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestVariables_applyOS(t *testing.T) {
+	base := map[string]any{"prefix": "/usr/local", "shell": "zsh"}
+	v := Variables{
+		Vars:     base,
+		VarFiles: []VarFile{{Path: "/dots/vars.yml"}},
+		ByOS: map[string]OSVariables{
+			"darwin": {Vars: map[string]any{"prefix": "/opt/homebrew"}, VarFiles: []VarFile{{Path: "/dots/darwin.yml"}}},
+			"linux":  {VarFiles: []VarFile{{Path: "/dots/linux.yml", IsVault: true}}},
+		},
+	}
+
+	darwin := v
+	darwin.applyOS("darwin")
+	if darwin.Vars["prefix"] != "/opt/homebrew" || darwin.Vars["shell"] != "zsh" {
+		t.Errorf("darwin vars = %v", darwin.Vars)
+	}
+	if base["prefix"] != "/usr/local" {
+		t.Errorf("base vars modified: %v", base)
+	}
+	paths := func(files []VarFile) []string {
+		var out []string
+		for _, f := range files {
+			out = append(out, f.Path)
+		}
+		return out
+	}
+	if got, want := paths(darwin.VarFiles), []string{"/dots/vars.yml", "/dots/darwin.yml"}; !slices.Equal(got, want) {
+		t.Errorf("darwin var files = %v, want %v", got, want)
+	}
+	if got, want := paths(darwin.AllVarFiles()), []string{"/dots/vars.yml", "/dots/darwin.yml", "/dots/linux.yml"}; !slices.Equal(got, want) {
+		t.Errorf("AllVarFiles() = %v, want %v", got, want)
+	}
+
+	windows := v
+	windows.applyOS("windows")
+	if windows.Vars["prefix"] != "/usr/local" || len(windows.VarFiles) != 1 {
+		t.Errorf("windows variables = %+v, want the base variables", windows)
+	}
+}
+
+func TestLoadConfig_ByOS(t *testing.T) {
+	dir := t.TempDir()
+	data := []byte(`variables:
+  vars:
+    greeting: hello
+  by_os:
+    ` + runtime.GOOS + `:
+      vars:
+        greeting: howdy
+      var_files: [vars/os.yml]
+    plan9:
+      var_files: ["vars/plan9.yml?vault=true"]
+`)
+	if err := os.WriteFile(filepath.Join(dir, "mmdot.yml"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(filepath.Join(dir, "mmdot.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Variables.Vars["greeting"] != "howdy" {
+		t.Errorf("greeting = %v, want howdy", cfg.Variables.Vars["greeting"])
+	}
+	if len(cfg.Variables.VarFiles) != 1 || cfg.Variables.VarFiles[0].Path != filepath.Join(dir, "vars", "os.yml") {
+		t.Errorf("VarFiles = %+v, want the resolved by_os var file", cfg.Variables.VarFiles)
+	}
+	if got := cfg.EncryptedFiles(); !slices.Equal(got, []string{filepath.Join(dir, "vars", "plan9.yml")}) {
+		t.Errorf("EncryptedFiles() = %v, want the vault file of the other system", got)
+	}
+}
+
+func TestLoadConfig_ByOSUnknown(t *testing.T) {
+	dir := t.TempDir()
+	data := []byte("variables:\n  by_os:\n    macos:\n      vars: {a: 1}\n")
+	if err := os.WriteFile(filepath.Join(dir, "mmdot.yml"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(filepath.Join(dir, "mmdot.yml")); err == nil {
+		t.Error("LoadConfig() expected an error for an unknown operating system")
+	}
+}
@@ -0,0 +1,86 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+)
+
+func TestRequiredVar_YAMLParsing(t *testing.T) {
+	input := `
+- hostname
+- name: port
+  type: int
+  default: 8080
+`
+	var got []RequiredVar
+	if err := yaml.Unmarshal([]byte(input), &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	want := []RequiredVar{
+		{Name: "hostname"},
+		{Name: "port", Type: VarTypeInt, Default: uint64(8080)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unmarshal() = %#v, want %#v", got, want)
+	}
+}
+
+func TestRequiredVar_Coerce(t *testing.T) {
+	tests := []struct {
+		typ     VarType
+		in      any
+		want    any
+		wantErr bool
+	}{
+		{typ: VarTypeAny, in: "x", want: "x"},
+		{typ: VarTypeString, in: uint64(42), want: "42"},
+		{typ: VarTypeString, in: []any{"a"}, wantErr: true},
+		{typ: VarTypeInt, in: "8080", want: 8080},
+		{typ: VarTypeInt, in: uint64(8080), want: 8080},
+		{typ: VarTypeInt, in: 1.5, wantErr: true},
+		{typ: VarTypeInt, in: "abc", wantErr: true},
+		{typ: VarTypeFloat, in: "1.5", want: 1.5},
+		{typ: VarTypeBool, in: "true", want: true},
+		{typ: VarTypeBool, in: "yes", wantErr: true},
+		{typ: VarTypeList, in: []any{"a"}, want: []any{"a"}},
+		{typ: VarTypeMap, in: "a", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.typ), func(t *testing.T) {
+			got, err := RequiredVar{Name: "v", Type: tt.typ}.Coerce(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Coerce(%v) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Coerce(%v) = %#v, want %#v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyRequiredVars(t *testing.T) {
+	vars := map[string]any{"port": "9000", "debug": "maybe"}
+	required := []RequiredVar{
+		{Name: "port", Type: VarTypeInt},
+		{Name: "email"},
+		{Name: "shell", Default: "zsh"},
+		{Name: "debug", Type: VarTypeBool},
+	}
+
+	problems := ApplyRequiredVars(vars, required)
+
+	wantProblems := []string{"email: missing", "debug: cannot convert maybe to bool"}
+	if !reflect.DeepEqual(problems, wantProblems) {
+		t.Errorf("problems = %q, want %q", problems, wantProblems)
+	}
+	if vars["port"] != 9000 {
+		t.Errorf("port = %#v, want 9000", vars["port"])
+	}
+	if vars["shell"] != "zsh" {
+		t.Errorf("shell = %#v, want default zsh", vars["shell"])
+	}
+}
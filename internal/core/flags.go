@@ -1,8 +1,31 @@
 package core
 
+import "time"
+
 const EnvPrefix = "MMDOT_"
 
+// CI is set by the global --ci flag. Interactive selection, prompts, and
+// spinners are disabled, and run output is grouped for CI logs.
+var CI bool
+
 type Flags struct {
-	LogLevel       string
+	LogLevel string
+	// ConfigFilePaths holds every --config value. The first is the base
+	// config; the rest are overlays merged on top of it.
+	ConfigFilePaths []string
+	// ConfigFilePath is the base config, the first of ConfigFilePaths.
 	ConfigFilePath string
+	// Workspace names the registered workspace whose config is used in
+	// place of --config.
+	Workspace string
+	// Timeout is the deadline for the whole command, zero for none.
+	Timeout time.Duration
+}
+
+// ConfigOverlays returns the configs stacked on top of ConfigFilePath.
+func (f *Flags) ConfigOverlays() []string {
+	if len(f.ConfigFilePaths) < 2 {
+		return nil
+	}
+	return f.ConfigFilePaths[1:]
 }
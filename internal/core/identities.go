@@ -0,0 +1,243 @@
+package core
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"filippo.io/age"
+	"github.com/hay-kot/mmdot/pkgs/atomicwrite"
+	"github.com/rs/zerolog/log"
+)
+
+const identityHintsFile = "identities.json"
+
+// NamedIdentity is an identity together with the file it was read from.
+type NamedIdentity struct {
+	Path     string
+	Identity age.Identity
+}
+
+// Recipient returns the public key of the identity, or "" for identities
+// other than X25519 keys.
+func (n NamedIdentity) Recipient() string {
+	if x, ok := n.Identity.(*age.X25519Identity); ok {
+		return x.Recipient().String()
+	}
+	return ""
+}
+
+// IdentityChain tries several identities in turn. Through [IdentityChain.For]
+// it starts with the identity that last opened a file, and records which one
+// succeeds in the state directory so the next decrypt tries it first.
+type IdentityChain struct {
+	identities []NamedIdentity
+
+	mu    sync.Mutex
+	hints map[string]string // file to identity path, nil until loaded
+}
+
+func NewIdentityChain(identities []NamedIdentity) *IdentityChain {
+	return &IdentityChain{identities: identities}
+}
+
+// Identities returns the identities of the chain in the order they are
+// tried when no hint applies.
+func (c *IdentityChain) Identities() []NamedIdentity {
+	return c.identities
+}
+
+// CacheKey implements [fcrypt.CacheKeyer] with the recipients, or paths
+// for identities without one, of the chain.
+func (c *IdentityChain) CacheKey() string {
+	keys := make([]string, 0, len(c.identities))
+	for _, n := range c.identities {
+		keys = append(keys, cmp.Or(n.Recipient(), n.Path))
+	}
+	return strings.Join(keys, ",")
+}
+
+// Unwrap implements [age.Identity] by trying each identity in order.
+func (c *IdentityChain) Unwrap(stanzas []*age.Stanza) ([]byte, error) {
+	key, _, err := unwrapWith(c.identities, stanzas)
+	return key, err
+}
+
+// For returns an identity for decrypting file that tries the identity which
+// last opened it first, and records the one that succeeds.
+func (c *IdentityChain) For(file string) age.Identity {
+	return fileIdentity{chain: c, file: hintKey(file)}
+}
+
+// Hint returns the path of the identity that last opened or was used to
+// encrypt file, or "".
+func (c *IdentityChain) Hint(file string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.loadHints()[hintKey(file)]
+}
+
+// RecordRecipients records the first identity of the chain whose public key
+// is in recipients as the one to decrypt file with.
+func (c *IdentityChain) RecordRecipients(file string, recipients []string) {
+	for _, identity := range c.identities {
+		if r := identity.Recipient(); r != "" && slices.Contains(recipients, r) {
+			c.record(hintKey(file), identity.Path)
+			return
+		}
+	}
+}
+
+// ordered returns the identities with the one hinted for file first.
+func (c *IdentityChain) ordered(file string) []NamedIdentity {
+	c.mu.Lock()
+	hint := c.loadHints()[file]
+	c.mu.Unlock()
+
+	i := slices.IndexFunc(c.identities, func(n NamedIdentity) bool { return n.Path == hint })
+	if i <= 0 {
+		return c.identities
+	}
+	ordered := make([]NamedIdentity, 0, len(c.identities))
+	ordered = append(ordered, c.identities[i])
+	ordered = append(ordered, c.identities[:i]...)
+	return append(ordered, c.identities[i+1:]...)
+}
+
+func (c *IdentityChain) record(file, identity string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	hints := c.loadHints()
+	if hints[file] == identity {
+		return
+	}
+	hints[file] = identity
+	if err := RecordIdentityHint(file, identity); err != nil {
+		log.Warn().Err(err).Str("file", file).Msg("failed to record identity hint")
+	}
+}
+
+// loadHints returns the recorded hints, reading them on first use. The
+// caller must hold mu.
+func (c *IdentityChain) loadHints() map[string]string {
+	if c.hints == nil {
+		hints, err := LoadIdentityHints()
+		if err != nil {
+			log.Warn().Err(err).Msg("failed to load identity hints")
+			hints = map[string]string{}
+		}
+		c.hints = hints
+	}
+	return c.hints
+}
+
+type fileIdentity struct {
+	chain *IdentityChain
+	file  string
+}
+
+func (f fileIdentity) CacheKey() string {
+	return f.chain.CacheKey()
+}
+
+func (f fileIdentity) Unwrap(stanzas []*age.Stanza) ([]byte, error) {
+	key, identity, err := unwrapWith(f.chain.ordered(f.file), stanzas)
+	if err == nil {
+		f.chain.record(f.file, identity.Path)
+	}
+	return key, err
+}
+
+// unwrapWith returns the file key from the first identity that can unwrap
+// stanzas, along with that identity.
+func unwrapWith(identities []NamedIdentity, stanzas []*age.Stanza) ([]byte, NamedIdentity, error) {
+	for _, identity := range identities {
+		key, err := identity.Identity.Unwrap(stanzas)
+		if errors.Is(err, age.ErrIncorrectIdentity) {
+			continue
+		}
+		return key, identity, err
+	}
+	return nil, NamedIdentity{}, age.ErrIncorrectIdentity
+}
+
+// IdentityFor returns identity narrowed to decrypting file: an
+// [IdentityChain] tries the identity hinted for the file first, any other
+// identity is returned unchanged.
+func IdentityFor(identity age.Identity, file string) age.Identity {
+	if chain, ok := identity.(*IdentityChain); ok {
+		return chain.For(file)
+	}
+	return identity
+}
+
+// hintKey returns the key file is recorded under, the same for a vault file
+// and its .age counterpart.
+func hintKey(file string) string {
+	return strings.TrimSuffix(file, ".age")
+}
+
+// LoadIdentityHints returns the identity file that last opened each
+// encrypted file, both keyed by absolute path. A missing file is not an
+// error.
+func LoadIdentityHints() (map[string]string, error) {
+	path, err := identityHintsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	hints := map[string]string{}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return hints, nil
+		}
+		return nil, fmt.Errorf("failed to read identity hints: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &hints); err != nil {
+		return nil, fmt.Errorf("failed to parse identity hints %s: %w", path, err)
+	}
+	return hints, nil
+}
+
+// RecordIdentityHint stores identity as the identity file that opens file.
+func RecordIdentityHint(file, identity string) error {
+	hints, err := LoadIdentityHints()
+	if err != nil {
+		return err
+	}
+	hints[hintKey(file)] = identity
+
+	path, err := identityHintsPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(hints, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return atomicwrite.WriteFile(path, data, 0o644)
+}
+
+func identityHintsPath() (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, identityHintsFile), nil
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/hay-kot/mmdot/pkgs/fcrypt"
+)
+
+func TestAge_ReadIdentities(t *testing.T) {
+	dir := t.TempDir()
+	work, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	workPath := filepath.Join(dir, "work.txt")
+	if err := os.WriteFile(workPath, []byte("# work\n"+work.String()+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	a := Age{IdentityFile: filepath.Join(dir, "missing.txt"), IdentityFiles: []string{workPath}}
+	identities, err := a.ReadIdentities()
+	if err != nil {
+		t.Fatalf("ReadIdentities() unexpected error = %v", err)
+	}
+	if len(identities) != 1 || identities[0].Path != workPath || identities[0].Recipient() != work.Recipient().String() {
+		t.Errorf("ReadIdentities() = %v, want only %s", identities, workPath)
+	}
+
+	a.IdentityFiles = nil
+	if _, err := a.ReadIdentities(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ReadIdentities() error = %v, want not exist", err)
+	}
+}
+
+func TestIdentityChain_For(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	personal, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	work, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := NewIdentityChain([]NamedIdentity{
+		{Path: "/keys/personal.txt", Identity: personal},
+		{Path: "/keys/work.txt", Identity: work},
+	})
+
+	var ciphertext bytes.Buffer
+	if err := fcrypt.EncryptReader(bytes.NewReader([]byte("token: abc")), &ciphertext, []age.Recipient{work.Recipient()}); err != nil {
+		t.Fatal(err)
+	}
+
+	file := "/dots/secrets.yml.age"
+	if err := fcrypt.DecryptReader(bytes.NewReader(ciphertext.Bytes()), io.Discard, chain.For(file)); err != nil {
+		t.Fatalf("decrypt with chain: %v", err)
+	}
+	if got := chain.Hint("/dots/secrets.yml"); got != "/keys/work.txt" {
+		t.Errorf("Hint() = %q, want /keys/work.txt", got)
+	}
+
+	// a new chain reads the recorded hint and tries the work key first
+	ordered := NewIdentityChain(chain.Identities()).ordered(hintKey(file))
+	if ordered[0].Path != "/keys/work.txt" || len(ordered) != 2 {
+		t.Errorf("ordered() = %v, want the work key first", ordered)
+	}
+
+	var other bytes.Buffer
+	stranger, _ := age.GenerateX25519Identity()
+	if err := fcrypt.EncryptReader(bytes.NewReader([]byte("x")), &other, []age.Recipient{stranger.Recipient()}); err != nil {
+		t.Fatal(err)
+	}
+	var noMatch *age.NoIdentityMatchError
+	if err := fcrypt.DecryptReader(&other, io.Discard, chain.For("/dots/other.age")); !errors.As(err, &noMatch) {
+		t.Errorf("decrypt with no matching identity error = %v, want NoIdentityMatchError", err)
+	}
+}
+
+func TestIdentityChain_RecordRecipients(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	personal, _ := age.GenerateX25519Identity()
+	work, _ := age.GenerateX25519Identity()
+	chain := NewIdentityChain([]NamedIdentity{
+		{Path: "/keys/personal.txt", Identity: personal},
+		{Path: "/keys/work.txt", Identity: work},
+	})
+
+	chain.RecordRecipients("/dots/work.env", []string{"age1someoneelse", work.Recipient().String()})
+	hints, err := LoadIdentityHints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hints["/dots/work.env"] != "/keys/work.txt" {
+		t.Errorf("recorded hints = %v, want /dots/work.env opened by /keys/work.txt", hints)
+	}
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// Offline is set by the global --offline flag. Work that needs the network
+// is skipped with [ErrOffline], using cached data where there is some.
+var Offline bool
+
+// ErrOffline is returned for network operations skipped in offline mode.
+var ErrOffline = errors.New("skipped (offline)")
+
+// ErrUnreachable is returned for requests to a host that could not be
+// connected to earlier in the run.
+var ErrUnreachable = errors.New("host is unreachable")
+
+// NetTransport guards HTTP requests: they fail with [ErrOffline] in offline
+// mode, and hosts that cannot be connected to are remembered so later
+// requests to them fail at once instead of waiting for another timeout.
+type NetTransport struct {
+	Base http.RoundTripper
+
+	mu          sync.Mutex
+	unreachable map[string]error // host to the dial error
+}
+
+func NewNetTransport(base http.RoundTripper) *NetTransport {
+	return &NetTransport{Base: base, unreachable: map[string]error{}}
+}
+
+func (t *NetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if Offline {
+		return nil, ErrOffline
+	}
+
+	host := req.URL.Host
+	t.mu.Lock()
+	dialErr := t.unreachable[host]
+	t.mu.Unlock()
+	if dialErr != nil {
+		return nil, fmt.Errorf("%s: %w (%w)", host, ErrUnreachable, dialErr)
+	}
+
+	resp, err := t.Base.RoundTrip(req)
+	var opErr *net.OpError
+	if err != nil && errors.As(err, &opErr) && opErr.Op == "dial" {
+		t.mu.Lock()
+		t.unreachable[host] = err
+		t.mu.Unlock()
+	}
+	return resp, err
+}
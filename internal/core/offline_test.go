@@ -0,0 +1,48 @@
+package core
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNetTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(srv.Close)
+
+	// a port nothing listens on
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := "http://" + l.Addr().String()
+	_ = l.Close()
+
+	client := &http.Client{Transport: NewNetTransport(http.DefaultTransport)}
+	get := func(url string) error {
+		resp, err := client.Get(url)
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := get(srv.URL); err != nil {
+		t.Fatalf("Get() online error = %v", err)
+	}
+
+	if err := get(closed); err == nil || errors.Is(err, ErrUnreachable) {
+		t.Fatalf("Get() first dial error = %v, want a connection error", err)
+	}
+	if err := get(closed); !errors.Is(err, ErrUnreachable) {
+		t.Errorf("Get() after a failed dial error = %v, want ErrUnreachable", err)
+	}
+
+	Offline = true
+	t.Cleanup(func() { Offline = false })
+	if err := get(srv.URL); !errors.Is(err, ErrOffline) {
+		t.Errorf("Get() offline error = %v, want ErrOffline", err)
+	}
+}
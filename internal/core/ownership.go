@@ -0,0 +1,59 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"strconv"
+)
+
+// LookupOwnership returns the uid and gid for owner and group, each given by
+// name or numeric id. Unset values are returned as -1, which [os.Chown]
+// leaves unchanged.
+func LookupOwnership(owner, group string) (uid, gid int, err error) {
+	uid, gid = -1, -1
+
+	if owner != "" {
+		u, err := user.Lookup(owner)
+		if err != nil {
+			u, err = user.LookupId(owner)
+		}
+		if err != nil {
+			return -1, -1, fmt.Errorf("unknown owner %q", owner)
+		}
+		if uid, err = strconv.Atoi(u.Uid); err != nil {
+			return -1, -1, fmt.Errorf("owner %q has a non-numeric uid %q", owner, u.Uid)
+		}
+	}
+
+	if group != "" {
+		g, err := user.LookupGroup(group)
+		if err != nil {
+			g, err = user.LookupGroupId(group)
+		}
+		if err != nil {
+			return -1, -1, fmt.Errorf("unknown group %q", group)
+		}
+		if gid, err = strconv.Atoi(g.Gid); err != nil {
+			return -1, -1, fmt.Errorf("group %q has a non-numeric gid %q", group, g.Gid)
+		}
+	}
+
+	return uid, gid, nil
+}
+
+// Chown gives path the uid and gid returned by [LookupOwnership]. Changing
+// the owner, or the group to one the user is not a member of, requires root.
+func Chown(path string, uid, gid int) error {
+	if uid == -1 && gid == -1 {
+		return nil
+	}
+
+	err := os.Chown(path, uid, gid)
+	if errors.Is(err, fs.ErrPermission) {
+		return fmt.Errorf("setting the owner of %s requires root; run mmdot with sudo or remove owner and group: %w", path, err)
+	}
+	return err
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestLookupOwnership(t *testing.T) {
+	current, err := user.Current()
+	if err != nil {
+		t.Skip("no current user:", err)
+	}
+	uid, _ := strconv.Atoi(current.Uid)
+	gid, _ := strconv.Atoi(current.Gid)
+
+	tests := []struct {
+		name     string
+		owner    string
+		group    string
+		wantUID  int
+		wantGID  int
+		wantFail bool
+	}{
+		{name: "unset", wantUID: -1, wantGID: -1},
+		{name: "owner by name", owner: current.Username, wantUID: uid, wantGID: -1},
+		{name: "owner by id", owner: current.Uid, wantUID: uid, wantGID: -1},
+		{name: "group by id", group: current.Gid, wantUID: -1, wantGID: gid},
+		{name: "unknown owner", owner: "mmdot-no-such-user", wantFail: true},
+		{name: "unknown group", group: "mmdot-no-such-group", wantFail: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotUID, gotGID, err := LookupOwnership(tt.owner, tt.group)
+			if (err != nil) != tt.wantFail {
+				t.Fatalf("LookupOwnership() error = %v, wantFail %v", err, tt.wantFail)
+			}
+			if tt.wantFail {
+				return
+			}
+			if gotUID != tt.wantUID || gotGID != tt.wantGID {
+				t.Errorf("LookupOwnership() = %d, %d, want %d, %d", gotUID, gotGID, tt.wantUID, tt.wantGID)
+			}
+		})
+	}
+
+	// chown to the current owner and group needs no privileges
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Chown(path, uid, gid); err != nil {
+		t.Errorf("Chown() error = %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+
+	"github.com/hay-kot/mmdot/pkgs/printer"
+)
+
+// ErrNoEscalation is returned by [FindEscalation] when neither sudo nor doas is
+// available.
+var ErrNoEscalation = errors.New("privileged scripts require sudo or doas, but neither was found on PATH")
+
+// Escalation runs commands as root through sudo or doas.
+type Escalation struct {
+	Tool string   // Path to sudo or doas
+	Args []string // Arguments placed before the wrapped command
+}
+
+// FindEscalation returns an Escalation for the first of sudo or doas found on
+// PATH. sudo is run with -E so scripts keep the caller's environment.
+func FindEscalation() (Escalation, error) {
+	if path, err := exec.LookPath("sudo"); err == nil {
+		return Escalation{Tool: path, Args: []string{"-E"}}, nil
+	}
+	if path, err := exec.LookPath("doas"); err == nil {
+		return Escalation{Tool: path}, nil
+	}
+	return Escalation{}, printer.WithHints(ErrNoEscalation,
+		"install sudo or doas",
+		"pass --no-privileged to skip privileged scripts",
+	)
+}
+
+// NonInteractive returns a copy of e that fails instead of prompting for a
+// password.
+func (e Escalation) NonInteractive() Escalation {
+	return Escalation{Tool: e.Tool, Args: append(slices.Clone(e.Args), "-n")}
+}
+
+// Validate prompts for credentials once so that later commands run without
+// asking again. sudo caches them with -v; doas relies on its persist option.
+func (e Escalation) Validate(ctx context.Context) error {
+	args := []string{"-v"}
+	if filepath.Base(e.Tool) == "doas" {
+		args = []string{"true"}
+	}
+
+	cmd := exec.CommandContext(ctx, e.Tool, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// Command returns a command that runs name with args through the escalation
+// tool.
+func (e Escalation) Command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	full := append(slices.Clone(e.Args), name)
+	return exec.CommandContext(ctx, e.Tool, append(full, args...)...)
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestFindEscalation(t *testing.T) {
+	tests := []struct {
+		name     string
+		tools    []string
+		wantTool string
+		wantArgs []string
+		wantErr  error
+	}{
+		{name: "sudo", tools: []string{"sudo"}, wantTool: "sudo", wantArgs: []string{"-E"}},
+		{name: "doas", tools: []string{"doas"}, wantTool: "doas"},
+		{name: "prefers sudo", tools: []string{"doas", "sudo"}, wantTool: "sudo", wantArgs: []string{"-E"}},
+		{name: "none", wantErr: ErrNoEscalation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, tool := range tt.tools {
+				if err := os.WriteFile(filepath.Join(dir, tool), []byte("#!/bin/sh\n"), 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			t.Setenv("PATH", dir)
+
+			got, err := FindEscalation()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("FindEscalation() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if filepath.Base(got.Tool) != tt.wantTool {
+				t.Errorf("Tool = %q, want %q", got.Tool, tt.wantTool)
+			}
+			if !slices.Equal(got.Args, tt.wantArgs) {
+				t.Errorf("Args = %v, want %v", got.Args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestEscalation_Command(t *testing.T) {
+	esc := Escalation{Tool: "/usr/bin/sudo", Args: []string{"-E"}}
+
+	cmd := esc.NonInteractive().Command(context.Background(), "/bin/sh", "setup.sh")
+	want := []string{"/usr/bin/sudo", "-E", "-n", "/bin/sh", "setup.sh"}
+	if !slices.Equal(cmd.Args, want) {
+		t.Errorf("Command().Args = %v, want %v", cmd.Args, want)
+	}
+
+	// NonInteractive must not modify the original
+	if !slices.Equal(esc.Args, []string{"-E"}) {
+		t.Errorf("original Args modified: %v", esc.Args)
+	}
+}
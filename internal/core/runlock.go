@@ -0,0 +1,59 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const runLockFile = "run.lock"
+
+// ErrLocked is returned by [AcquireRunLock] while another mmdot process
+// holds the run lock.
+var ErrLocked = errors.New("another mmdot process is running")
+
+// AcquireRunLock takes the machine-wide lock held while run or apply change
+// the machine, so two runs (e.g. the daemon and a manual run) cannot write
+// the same files at once. It does not wait: when the lock is held it fails
+// with [ErrLocked]. The lock is released by the returned function or when the
+// process exits.
+func AcquireRunLock() (release func(), err error) {
+	dir, err := StateDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dir, runLockFile)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open run lock: %w", err)
+	}
+
+	if err := lockFile(f); err != nil {
+		pid, _ := os.ReadFile(path)
+		f.Close()
+		if errors.Is(err, errWouldBlock) {
+			if pid := strings.TrimSpace(string(pid)); pid != "" {
+				return nil, fmt.Errorf("%w (pid %s)", ErrLocked, pid)
+			}
+			return nil, ErrLocked
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+
+	// The pid is informational, the lock itself is what excludes other runs
+	_ = f.Truncate(0)
+	_, _ = f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+
+	return func() {
+		_ = f.Truncate(0)
+		_ = unlockFile(f)
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,26 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAcquireRunLock(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	release, err := AcquireRunLock()
+	if err != nil {
+		t.Fatalf("AcquireRunLock() error = %v", err)
+	}
+
+	if _, err := AcquireRunLock(); !errors.Is(err, ErrLocked) {
+		t.Errorf("AcquireRunLock() while held error = %v, want ErrLocked", err)
+	}
+
+	release()
+	release, err = AcquireRunLock()
+	if err != nil {
+		t.Fatalf("AcquireRunLock() after release error = %v", err)
+	}
+	release()
+}
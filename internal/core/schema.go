@@ -0,0 +1,10 @@
+package core
+
+import _ "embed"
+
+// Schema is the JSON Schema of mmdot.yml, generated from [ConfigFile] and the
+// doc comments of its fields. Regenerate it after changing the config structs.
+//
+//go:generate go test -run TestSchema -update .
+//go:embed schema.json
+var Schema []byte
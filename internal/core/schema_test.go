@@ -0,0 +1,100 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/hay-kot/mmdot/internal/jsonschema"
+)
+
+var updateSchema = flag.Bool("update", false, "regenerate schema.json")
+
+func generateSchema(t *testing.T) (string, []byte) {
+	t.Helper()
+
+	// absolute, other tests change the working directory
+	_, file, _, _ := runtime.Caller(0)
+	dir := filepath.Dir(file)
+
+	docs, err := jsonschema.ParseDocs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enum := func(values ...string) *jsonschema.Schema {
+		return &jsonschema.Schema{Type: "string", Enum: values}
+	}
+	enums := map[reflect.Type]*jsonschema.Schema{
+		reflect.TypeFor[Stage]():            enum("pre", "main", "post"),
+		reflect.TypeFor[VarType]():          enum("string", "int", "float", "bool", "list", "map"),
+		reflect.TypeFor[PromptType]():       enum("string", "password", "select"),
+		reflect.TypeFor[NotificationType](): enum("desktop", "webhook", "ntfy", "pushover"),
+		// parsed with time.ParseDuration
+		reflect.TypeFor[time.Duration](): {Type: "string", Pattern: `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`},
+	}
+	def := func(v any, doc string) *jsonschema.Schema {
+		s := (&jsonschema.Reflector{Docs: docs, Types: enums}).Reflect(v)
+		s.Schema = ""
+		s.Description = doc
+		return s
+	}
+
+	// Types with an UnmarshalYAML also accept a bare name or path
+	types := maps.Clone(enums)
+	types[reflect.TypeFor[RequiredVar]()] = &jsonschema.Schema{AnyOf: []*jsonschema.Schema{
+		{Type: "string", Description: "Variable name"},
+		{Ref: "#/$defs/RequiredVar"},
+	}}
+	types[reflect.TypeFor[BrewPackage]()] = &jsonschema.Schema{AnyOf: []*jsonschema.Schema{
+		{Type: "string", Description: "Formula or cask name"},
+		{Ref: "#/$defs/BrewPackage"},
+	}}
+	types[reflect.TypeFor[VarFile]()] = &jsonschema.Schema{AnyOf: []*jsonschema.Schema{
+		{Type: "string", Description: "Path, with ?vault=true, ?partial=true, or ?optional=true options"},
+		{Ref: "#/$defs/VarFile"},
+	}}
+
+	schema := (&jsonschema.Reflector{Docs: docs, Types: types}).Reflect(ConfigFile{})
+	schema.Title = "mmdot.yml"
+	schema.Defs["RequiredVar"] = def(RequiredVar{}, docs["RequiredVar"])
+	schema.Defs["BrewPackage"] = def(BrewPackage{}, docs["BrewPackage"])
+	schema.Defs["VarFile"] = def(struct {
+		Path       string   `yaml:"path"`
+		Vault      bool     `yaml:"vault"`
+		Partial    bool     `yaml:"partial"`
+		Optional   bool     `yaml:"optional"`
+		Recipients []string `yaml:"recipients"`
+	}{}, docs["VarFile"])
+
+	data, err := schema.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, data
+}
+
+func TestSchema(t *testing.T) {
+	dir, got := generateSchema(t)
+
+	if *updateSchema {
+		if err := os.WriteFile(filepath.Join(dir, "schema.json"), got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	if !bytes.Equal(got, Schema) {
+		t.Fatal("schema.json is out of date, run `go generate ./internal/core`")
+	}
+	if !json.Valid(Schema) {
+		t.Fatal("schema.json is not valid JSON")
+	}
+}
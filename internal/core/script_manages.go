@@ -0,0 +1,154 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/hay-kot/mmdot/pkgs/atomicwrite"
+)
+
+const managedHashesFile = "manages.json"
+
+// HashManaged returns the hash of each path in the script's manages list.
+// Missing paths hash to "".
+func (s Script) HashManaged() (map[string]string, error) {
+	hashes := make(map[string]string, len(s.Manages))
+	for _, path := range s.Manages {
+		hash, err := HashPath(path)
+		if err != nil {
+			return nil, err
+		}
+		hashes[path] = hash
+	}
+	return hashes, nil
+}
+
+// HashPath returns a sha256 of the file at path, or of the names, modes,
+// contents, and link targets of every entry of the directory at path. A
+// missing path hashes to "".
+func HashPath(path string) (string, error) {
+	if _, err := os.Lstat(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	h := sha256.New()
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(h, "%s\x00%s\x00", filepath.ToSlash(rel), info.Mode())
+
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			_, _ = io.WriteString(h, target)
+		case info.Mode().IsRegular():
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(h, f)
+			_ = f.Close()
+			if err != nil {
+				return err
+			}
+		}
+		_, _ = h.Write([]byte{0})
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ChangedManaged returns the paths whose hash differs from recorded, in the
+// order they are listed in manages.
+func (s Script) ChangedManaged(recorded, current map[string]string) []string {
+	var changed []string
+	for _, path := range s.Manages {
+		if recorded[path] != current[path] {
+			changed = append(changed, path)
+		}
+	}
+	return changed
+}
+
+// LoadManagedHashes returns the hashes of the managed paths recorded after
+// each script's last successful run, keyed by the script's absolute path. A
+// missing file is not an error.
+func LoadManagedHashes() (map[string]map[string]string, error) {
+	path, err := managedHashesPath()
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := map[string]map[string]string{}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return hashes, nil
+		}
+		return nil, fmt.Errorf("failed to read managed hashes: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &hashes); err != nil {
+		return nil, fmt.Errorf("failed to parse managed hashes %s: %w", path, err)
+	}
+	return hashes, nil
+}
+
+// RecordManagedHashes stores hashes as the state of the paths managed by the
+// script at path.
+func RecordManagedHashes(path string, hashes map[string]string) error {
+	all, err := LoadManagedHashes()
+	if err != nil {
+		return err
+	}
+	all[path] = hashes
+
+	file, err := managedHashesPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	return atomicwrite.WriteFile(file, data, 0o644)
+}
+
+func managedHashesPath() (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, managedHashesFile), nil
+}
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestHashPath(t *testing.T) {
+	dir := t.TempDir()
+	managed := filepath.Join(dir, "foo")
+	if err := os.MkdirAll(filepath.Join(managed, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(managed, "sub", "config"), []byte("a = 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	missing, err := HashPath(filepath.Join(dir, "missing"))
+	if err != nil || missing != "" {
+		t.Fatalf("HashPath(missing) = %q, %v, want \"\", nil", missing, err)
+	}
+
+	before, err := HashPath(managed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := HashPath(managed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before == "" || before != again {
+		t.Fatalf("HashPath() = %q then %q, want a stable hash", before, again)
+	}
+
+	if err := os.WriteFile(filepath.Join(managed, "sub", "config"), []byte("a = 2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	edited, err := HashPath(managed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited == before {
+		t.Error("HashPath() unchanged after editing a file")
+	}
+
+	if err := os.WriteFile(filepath.Join(managed, "extra"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	added, err := HashPath(managed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added == edited {
+		t.Error("HashPath() unchanged after adding a file")
+	}
+}
+
+func TestScript_ChangedManaged(t *testing.T) {
+	s := Script{Manages: []string{"/home/me/.config/a", "/home/me/.config/b", "/home/me/.config/c"}}
+	recorded := map[string]string{"/home/me/.config/a": "1", "/home/me/.config/b": "2"}
+	current := map[string]string{"/home/me/.config/a": "1", "/home/me/.config/b": "3", "/home/me/.config/c": ""}
+
+	got := s.ChangedManaged(recorded, current)
+	want := []string{"/home/me/.config/b"}
+	if !slices.Equal(got, want) {
+		t.Errorf("ChangedManaged() = %v, want %v", got, want)
+	}
+}
+
+func TestRecordManagedHashes(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	if err := RecordManagedHashes("/dots/a.sh", map[string]string{"/home/me/.config/a": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordManagedHashes("/dots/b.sh", map[string]string{"/home/me/.config/b": "2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	hashes, err := LoadManagedHashes()
+	if err != nil {
+		t.Fatalf("LoadManagedHashes() unexpected error = %v", err)
+	}
+	if hashes["/dots/a.sh"]["/home/me/.config/a"] != "1" || hashes["/dots/b.sh"]["/home/me/.config/b"] != "2" {
+		t.Errorf("LoadManagedHashes() = %v", hashes)
+	}
+}
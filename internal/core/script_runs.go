@@ -0,0 +1,100 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hay-kot/mmdot/pkgs/atomicwrite"
+)
+
+const scriptRunsFile = "scripts.json"
+
+// IntervalSkipReason returns why the script is skipped by min_interval given
+// when it last succeeded, or "" when it should run. A zero lastRun never
+// skips.
+func (s Script) IntervalSkipReason(lastRun, now time.Time) string {
+	if s.MinInterval <= 0 || lastRun.IsZero() {
+		return ""
+	}
+
+	since := now.Sub(lastRun)
+	if since < 0 || since >= s.MinInterval {
+		return ""
+	}
+	return fmt.Sprintf("ran %s ago, min_interval %s; use --force to run", shortDuration(since.Round(time.Minute)), shortDuration(s.MinInterval))
+}
+
+// shortDuration formats d without trailing zero units, e.g. 24h rather than
+// 24h0m0s.
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// LoadScriptRuns returns when each script, keyed by its absolute path, last
+// succeeded on this machine. A missing file is not an error.
+func LoadScriptRuns() (map[string]time.Time, error) {
+	path, err := scriptRunsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	runs := map[string]time.Time{}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return runs, nil
+		}
+		return nil, fmt.Errorf("failed to read script runs: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &runs); err != nil {
+		return nil, fmt.Errorf("failed to parse script runs %s: %w", path, err)
+	}
+	return runs, nil
+}
+
+// RecordScriptRun stores at as the last successful run of the script at path.
+func RecordScriptRun(path string, at time.Time) error {
+	runs, err := LoadScriptRuns()
+	if err != nil {
+		return err
+	}
+	runs[path] = at.UTC()
+
+	file, err := scriptRunsPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(runs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	return atomicwrite.WriteFile(file, data, 0o644)
+}
+
+func scriptRunsPath() (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, scriptRunsFile), nil
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScript_IntervalSkipReason(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		interval time.Duration
+		lastRun  time.Time
+		skip     bool
+	}{
+		{name: "no interval", lastRun: now.Add(-time.Minute)},
+		{name: "never ran", interval: 24 * time.Hour},
+		{name: "within interval", interval: 24 * time.Hour, lastRun: now.Add(-3 * time.Hour), skip: true},
+		{name: "within short interval", interval: 90 * time.Minute, lastRun: now.Add(-30 * time.Second), skip: true},
+		{name: "interval elapsed", interval: 24 * time.Hour, lastRun: now.Add(-25 * time.Hour)},
+		{name: "clock moved back", interval: 24 * time.Hour, lastRun: now.Add(time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Script{Path: "/dots/update.sh", MinInterval: tt.interval}
+			if got := s.IntervalSkipReason(tt.lastRun, now); (got != "") != tt.skip {
+				t.Errorf("IntervalSkipReason() = %q, want skip %v", got, tt.skip)
+			}
+		})
+	}
+}
+
+func TestRecordScriptRun(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	runs, err := LoadScriptRuns()
+	if err != nil {
+		t.Fatalf("LoadScriptRuns() unexpected error = %v", err)
+	}
+	if len(runs) != 0 {
+		t.Fatalf("LoadScriptRuns() = %v, want none", runs)
+	}
+
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := RecordScriptRun("/dots/a.sh", at); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordScriptRun("/dots/b.sh", at.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	runs, err = LoadScriptRuns()
+	if err != nil {
+		t.Fatalf("LoadScriptRuns() unexpected error = %v", err)
+	}
+	if !runs["/dots/a.sh"].Equal(at) || !runs["/dots/b.sh"].Equal(at.Add(time.Hour)) {
+		t.Errorf("LoadScriptRuns() = %v", runs)
+	}
+}
+
+func Test_shortDuration(t *testing.T) {
+	tests := map[time.Duration]string{
+		24 * time.Hour:                 "24h",
+		90 * time.Minute:               "1h30m",
+		3*time.Hour + 5*time.Second:    "3h0m5s",
+		0:                              "0s",
+		45 * time.Second:               "45s",
+		2*time.Minute + 30*time.Second: "2m30s",
+	}
+	for d, want := range tests {
+		if got := shortDuration(d); got != want {
+			t.Errorf("shortDuration(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
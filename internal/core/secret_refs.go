@@ -0,0 +1,238 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/hay-kot/mmdot/pkgs/redact"
+)
+
+// SecretRefPrefix starts a reference to a secret held outside the config,
+// e.g. secret://op/Private/GitHub/token. The part after the prefix is the
+// provider name and the path passed to it.
+const SecretRefPrefix = "secret://"
+
+// SecretProvider fetches the secret at path.
+type SecretProvider func(ctx context.Context, path string) (string, error)
+
+var (
+	secretProvidersMu sync.RWMutex
+	secretProviders   = map[string]SecretProvider{
+		"env":      envSecret,
+		"file":     fileSecret,
+		"keychain": keychainSecret,
+		"op":       onePasswordSecret,
+	}
+)
+
+// RegisterSecretProvider makes provider available to references starting
+// with secret://name/, replacing any provider registered under name.
+func RegisterSecretProvider(name string, provider SecretProvider) {
+	secretProvidersMu.Lock()
+	defer secretProvidersMu.Unlock()
+	secretProviders[name] = provider
+}
+
+// SecretProviders returns the names of the registered providers, sorted.
+func SecretProviders() []string {
+	secretProvidersMu.RLock()
+	defer secretProvidersMu.RUnlock()
+	return slices.Sorted(maps.Keys(secretProviders))
+}
+
+// SecretRef is a parsed secret:// reference.
+type SecretRef struct {
+	Provider string
+	Path     string
+}
+
+// IsSecretRef reports whether s is a secret:// reference.
+func IsSecretRef(s string) bool {
+	return strings.HasPrefix(s, SecretRefPrefix)
+}
+
+// ParseSecretRef parses s, which must start with [SecretRefPrefix] and name
+// a registered provider and a path.
+func ParseSecretRef(s string) (SecretRef, error) {
+	rest, ok := strings.CutPrefix(s, SecretRefPrefix)
+	if !ok {
+		return SecretRef{}, fmt.Errorf("%q is not a %s reference", s, SecretRefPrefix)
+	}
+	provider, path, _ := strings.Cut(rest, "/")
+	if provider == "" || path == "" {
+		return SecretRef{}, fmt.Errorf("%q: expected %s<provider>/<path>", s, SecretRefPrefix)
+	}
+
+	secretProvidersMu.RLock()
+	_, ok = secretProviders[provider]
+	secretProvidersMu.RUnlock()
+	if !ok {
+		return SecretRef{}, fmt.Errorf("%q: unknown secret provider %q (expected one of %s)", s, provider, strings.Join(SecretProviders(), ", "))
+	}
+
+	return SecretRef{Provider: provider, Path: path}, nil
+}
+
+func (r SecretRef) String() string {
+	return SecretRefPrefix + r.Provider + "/" + r.Path
+}
+
+// secretCache holds fetched secrets so each is fetched once per process, or
+// until [ClearSecretCache] is called.
+var secretCache sync.Map // reference to value
+
+// ClearSecretCache forgets every fetched secret so the next [SecretRef.Resolve]
+// fetches it from its provider again. Long-running processes call it after
+// each run so secrets are not kept in memory between runs.
+func ClearSecretCache() {
+	secretCache.Clear()
+}
+
+// Resolve fetches the secret from its provider, or returns it from the cache
+// when it was fetched before. Fetched values are registered with the
+// redactor.
+func (r SecretRef) Resolve(ctx context.Context) (string, error) {
+	key := r.String()
+	if v, ok := secretCache.Load(key); ok {
+		return v.(string), nil
+	}
+
+	secretProvidersMu.RLock()
+	provider := secretProviders[r.Provider]
+	secretProvidersMu.RUnlock()
+	if provider == nil {
+		return "", fmt.Errorf("%s: unknown secret provider %q", key, r.Provider)
+	}
+
+	value, err := provider(ctx, r.Path)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", key, err)
+	}
+
+	redact.Add(key, value)
+	secretCache.Store(key, value)
+	return value, nil
+}
+
+// ResolveSecretString returns s, or the secret it references when s is a
+// secret:// reference.
+func ResolveSecretString(ctx context.Context, s string) (string, error) {
+	if !IsSecretRef(s) {
+		return s, nil
+	}
+	ref, err := ParseSecretRef(s)
+	if err != nil {
+		return "", err
+	}
+	return ref.Resolve(ctx)
+}
+
+// secretRefLocations are the config paths whose values are resolved when
+// used: variables, template vars, and notification targets. References
+// anywhere else would be used verbatim, so they are rejected at load.
+var secretRefLocations = regexp.MustCompile(`^\$\.(variables\.|templates\[\d+\]\.vars\.|notifications\[\d+\]\.)`)
+
+// validateSecretRefs checks every secret:// reference in a config layer
+// names a registered provider and appears where references are resolved.
+// Nothing is fetched; secrets are resolved when they are used.
+func validateSecretRefs(data []byte) error {
+	if !bytes.Contains(data, []byte(SecretRefPrefix)) {
+		return nil
+	}
+
+	file, err := parser.ParseBytes(data, 0)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, doc := range file.Docs {
+		if doc.Body == nil {
+			continue
+		}
+		ast.Walk(secretRefVisitor(func(node *ast.StringNode) {
+			path := node.GetPath()
+			if _, err := ParseSecretRef(node.Value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", path, err))
+				return
+			}
+			if !secretRefLocations.MatchString(path) {
+				errs = append(errs, fmt.Errorf("%s: %s references are only resolved in variables, template vars, and notifications", path, SecretRefPrefix))
+			}
+		}), doc.Body)
+	}
+	return errors.Join(errs...)
+}
+
+type secretRefVisitor func(*ast.StringNode)
+
+func (v secretRefVisitor) Visit(node ast.Node) ast.Visitor {
+	if s, ok := node.(*ast.StringNode); ok && IsSecretRef(s.Value) {
+		v(s)
+	}
+	return v
+}
+
+// envSecret reads the environment variable named path.
+func envSecret(ctx context.Context, path string) (string, error) {
+	value, ok := os.LookupEnv(path)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", path)
+	}
+	return value, nil
+}
+
+// fileSecret reads the file at path without its trailing newline. path may
+// start with ~/; secret://file//etc/token is the absolute /etc/token.
+func fileSecret(ctx context.Context, path string) (string, error) {
+	path, err := PathResolver{}.Resolve(path)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+// keychainSecret reads a generic password from the macOS keychain. path is
+// the service name, optionally followed by /account.
+func keychainSecret(ctx context.Context, path string) (string, error) {
+	service, account, _ := strings.Cut(path, "/")
+	args := []string{"find-generic-password", "-w", "-s", service}
+	if account != "" {
+		args = append(args, "-a", account)
+	}
+	return secretCommand(ctx, "security", args...)
+}
+
+// onePasswordSecret reads path, vault/item/field, with the 1Password CLI.
+func onePasswordSecret(ctx context.Context, path string) (string, error) {
+	return secretCommand(ctx, "op", "read", "--no-newline", "op://"+path)
+}
+
+func secretCommand(ctx context.Context, name string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %s", name, msg)
+		}
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return strings.TrimSuffix(stdout.String(), "\n"), nil
+}
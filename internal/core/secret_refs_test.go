@@ -0,0 +1,134 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseSecretRef(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    SecretRef
+		wantErr string
+	}{
+		{in: "secret://env/GITHUB_TOKEN", want: SecretRef{Provider: "env", Path: "GITHUB_TOKEN"}},
+		{in: "secret://op/Private/GitHub/token", want: SecretRef{Provider: "op", Path: "Private/GitHub/token"}},
+		{in: "secret://file//etc/token", want: SecretRef{Provider: "file", Path: "/etc/token"}},
+		{in: "secret://env", wantErr: "expected secret://<provider>/<path>"},
+		{in: "secret://vault/kv/token", wantErr: `unknown secret provider "vault"`},
+		{in: "env/TOKEN", wantErr: "is not a secret:// reference"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseSecretRef(tt.in)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseSecretRef() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSecretRef() unexpected error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSecretRef() = %+v, want %+v", got, tt.want)
+			}
+			if got.String() != tt.in {
+				t.Errorf("String() = %q, want %q", got.String(), tt.in)
+			}
+		})
+	}
+}
+
+func TestSecretRef_Resolve(t *testing.T) {
+	t.Setenv("MMDOT_TEST_SECRET", "hunter2")
+
+	got, err := ResolveSecretString(context.Background(), "secret://env/MMDOT_TEST_SECRET")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "hunter2" {
+		t.Errorf("ResolveSecretString() = %q, want hunter2", got)
+	}
+
+	got, err = ResolveSecretString(context.Background(), "plain")
+	if err != nil || got != "plain" {
+		t.Errorf("ResolveSecretString(plain) = %q, %v", got, err)
+	}
+
+	_, err = ResolveSecretString(context.Background(), "secret://env/MMDOT_TEST_SECRET_UNSET")
+	if err == nil || !strings.Contains(err.Error(), "is not set") {
+		t.Errorf("ResolveSecretString(unset) error = %v, want not set", err)
+	}
+}
+
+func TestClearSecretCache(t *testing.T) {
+	ref := "secret://env/MMDOT_TEST_SECRET_ROTATED"
+	t.Setenv("MMDOT_TEST_SECRET_ROTATED", "old")
+	if got, err := ResolveSecretString(context.Background(), ref); err != nil || got != "old" {
+		t.Fatalf("ResolveSecretString() = %q, %v", got, err)
+	}
+
+	t.Setenv("MMDOT_TEST_SECRET_ROTATED", "new")
+	if got, _ := ResolveSecretString(context.Background(), ref); got != "old" {
+		t.Errorf("ResolveSecretString() = %q before clearing, want cached old", got)
+	}
+
+	ClearSecretCache()
+	if got, _ := ResolveSecretString(context.Background(), ref); got != "new" {
+		t.Errorf("ResolveSecretString() = %q after clearing, want new", got)
+	}
+}
+
+func TestValidateSecretRefs(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			name: "allowed locations",
+			yaml: `
+variables:
+  vars:
+    token: secret://env/TOKEN
+templates:
+  - name: t
+    vars:
+      nested:
+        key: secret://op/Private/Key/password
+notifications:
+  - type: ntfy
+    url: https://ntfy.sh/topic
+    token: secret://keychain/ntfy
+`,
+		},
+		{
+			name:    "unknown provider",
+			yaml:    "variables:\n  vars:\n    token: secret://vault/token\n",
+			wantErr: `$.variables.vars.token: "secret://vault/token": unknown secret provider "vault"`,
+		},
+		{
+			name:    "not resolved here",
+			yaml:    "exec:\n  shell: secret://env/SHELL\n",
+			wantErr: "$.exec.shell: secret:// references are only resolved in",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSecretRefs([]byte(tt.yaml))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateSecretRefs() unexpected error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateSecretRefs() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
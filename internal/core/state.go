@@ -0,0 +1,37 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// StateDir returns the directory used for mmdot's machine-local state such as
+// caches. It honors $XDG_STATE_HOME and defaults to ~/.local/state/mmdot.
+func StateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "mmdot"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".local", "state", "mmdot"), nil
+}
+
+// UserConfigDir returns the directory for mmdot's user-level settings, such
+// as the workspace registry. It honors $XDG_CONFIG_HOME and defaults to
+// ~/.config/mmdot on every platform.
+func UserConfigDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "mmdot"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".config", "mmdot"), nil
+}
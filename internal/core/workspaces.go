@@ -0,0 +1,108 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/hay-kot/mmdot/pkgs/atomicwrite"
+)
+
+const workspacesFile = "workspaces.yml"
+
+// Workspaces maps workspace names to the absolute path of their config, so
+// several dotfiles repos can be driven with `mmdot -w <name>`. It is stored
+// in workspaces.yml below [UserConfigDir]:
+//
+//	workspaces:
+//	  personal: /Users/me/dotfiles/mmdot.yml
+//	  work: /Users/me/work/dotfiles/mmdot.yml
+type Workspaces map[string]string
+
+var workspaceNameRe = regexp.MustCompile(`^[\w.-]+$`)
+
+// ValidateWorkspaceName reports whether name can be used for a workspace.
+func ValidateWorkspaceName(name string) error {
+	if !workspaceNameRe.MatchString(name) {
+		return fmt.Errorf("invalid workspace name %q: use letters, digits, '.', '-', and '_'", name)
+	}
+	return nil
+}
+
+// WorkspacesPath returns the path of the workspace registry.
+func WorkspacesPath() (string, error) {
+	dir, err := UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, workspacesFile), nil
+}
+
+// LoadWorkspaces reads the workspace registry. A missing registry is empty.
+func LoadWorkspaces() (Workspaces, error) {
+	path, err := WorkspacesPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return Workspaces{}, nil
+		}
+		return nil, fmt.Errorf("failed to read workspaces: %w", err)
+	}
+
+	var file struct {
+		Workspaces Workspaces `yaml:"workspaces"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse workspaces %s: %w", path, err)
+	}
+	if file.Workspaces == nil {
+		file.Workspaces = Workspaces{}
+	}
+	return file.Workspaces, nil
+}
+
+// Save writes the registry.
+func (w Workspaces) Save() error {
+	path, err := WorkspacesPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(map[string]Workspaces{"workspaces": w})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return atomicwrite.WriteFile(path, data, 0o644)
+}
+
+// Names returns the workspace names, sorted.
+func (w Workspaces) Names() []string {
+	return slices.Sorted(maps.Keys(w))
+}
+
+// Config returns the config path of the workspace called name.
+func (w Workspaces) Config(name string) (string, error) {
+	path, ok := w[name]
+	if !ok {
+		if len(w) == 0 {
+			return "", fmt.Errorf("no workspace named %q (none registered, add one with `mmdot workspace add`)", name)
+		}
+		return "", fmt.Errorf("no workspace named %q (expected one of %s)", name, strings.Join(w.Names(), ", "))
+	}
+	return path, nil
+}
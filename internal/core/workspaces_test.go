@@ -0,0 +1,51 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWorkspaces_SaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	empty, err := LoadWorkspaces()
+	if err != nil {
+		t.Fatalf("LoadWorkspaces() without a registry error = %v", err)
+	}
+	if len(empty) != 0 {
+		t.Errorf("LoadWorkspaces() = %v, want empty", empty)
+	}
+	if _, err := empty.Config("work"); err == nil || !strings.Contains(err.Error(), "none registered") {
+		t.Errorf("Config() error = %v, want none registered", err)
+	}
+
+	w := Workspaces{"personal": "/home/me/dotfiles/mmdot.yml", "work": "/home/me/work/mmdot.yml"}
+	if err := w.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "mmdot", "workspaces.yml")); err != nil {
+		t.Fatalf("registry not written: %v", err)
+	}
+
+	loaded, err := LoadWorkspaces()
+	if err != nil {
+		t.Fatalf("LoadWorkspaces() error = %v", err)
+	}
+	if got, err := loaded.Config("work"); err != nil || got != "/home/me/work/mmdot.yml" {
+		t.Errorf("Config(work) = %q, %v", got, err)
+	}
+	if _, err := loaded.Config("wrok"); err == nil || !strings.Contains(err.Error(), "expected one of personal, work") {
+		t.Errorf("Config(wrok) error = %v, want the registered names", err)
+	}
+}
+
+func TestValidateWorkspaceName(t *testing.T) {
+	for name, valid := range map[string]bool{"work": true, "home-2.old_x": true, "": false, "a b": false, "a/b": false} {
+		if err := ValidateWorkspaceName(name); (err == nil) != valid {
+			t.Errorf("ValidateWorkspaceName(%q) error = %v, want valid %v", name, err, valid)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+// Package daemon serves a small local HTTP API over the mmdot library so
+// status bar widgets and editor plugins can show drift and trigger runs.
+//
+// Endpoints:
+//
+//	GET  /status   drift counts and the last run report
+//	GET  /drift    per-template status for templates that are not current
+//	POST /run      render templates and run scripts; ?tag= filters (repeatable)
+//	GET  /report   the last run report
+//
+// Browsers can reach loopback ports, so requests carrying an Origin header
+// are refused. TCP listeners are limited to loopback addresses, and requests
+// to them must name a loopback Host, which defeats DNS rebinding, and carry
+// the bearer token from [LoadToken].
+package daemon
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/pkgs/atomicwrite"
+	"github.com/hay-kot/mmdot/pkgs/mmdot"
+	"github.com/rs/zerolog/log"
+)
+
+// Counts summarizes template drift.
+type Counts struct {
+	Current int `json:"current"`
+	Changed int `json:"changed"`
+	Missing int `json:"missing"`
+}
+
+// Drifted returns the number of templates whose output is not current.
+func (c Counts) Drifted() int {
+	return c.Changed + c.Missing
+}
+
+// Report describes a run triggered through the API.
+type Report struct {
+	StartedAt time.Time `json:"started_at"`
+	Duration  string    `json:"duration"`
+	Tags      []string  `json:"tags,omitempty"`
+	Templates []string  `json:"templates"`
+	Success   bool      `json:"success"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// StatusResponse is returned by GET /status.
+type StatusResponse struct {
+	Config  string  `json:"config"`
+	Counts  Counts  `json:"counts"`
+	Drifted int     `json:"drifted"`
+	Running bool    `json:"running"`
+	LastRun *Report `json:"last_run,omitempty"`
+}
+
+// DriftItem is a single entry returned by GET /drift.
+type DriftItem struct {
+	Name   string       `json:"name"`
+	Output string       `json:"output"`
+	Status mmdot.Status `json:"status"`
+}
+
+// Server handles API requests for a single config.
+type Server struct {
+	client *mmdot.Client
+
+	// Token, when set, is required as "Authorization: Bearer <token>" and
+	// requests must name a loopback Host. Set it for TCP listeners.
+	Token string
+
+	mu      sync.Mutex
+	running bool
+	last    *Report
+}
+
+// New returns a Server backed by client.
+func New(client *mmdot.Client) *Server {
+	return &Server{client: client}
+}
+
+// Handler returns the HTTP handler for the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", s.handleStatus)
+	mux.HandleFunc("GET /drift", s.handleDrift)
+	mux.HandleFunc("POST /run", s.handleRun)
+	mux.HandleFunc("GET /report", s.handleReport)
+	return s.guard(mux)
+}
+
+// guard refuses cross-origin requests and, with a Token, requests without
+// it or for a Host that is not loopback.
+func (s *Server) guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "" {
+			writeError(w, http.StatusForbidden, errors.New("cross-origin requests are not allowed"))
+			return
+		}
+
+		if s.Token != "" {
+			host := r.Host
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+			if !isLoopback(host) {
+				writeError(w, http.StatusForbidden, errors.New("host must be a loopback address"))
+				return
+			}
+
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) != 1 {
+				writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isLoopback reports whether host is localhost or a loopback IP.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// IsTCP reports whether [Listen] opens a TCP listener for addr.
+func IsTCP(addr string) bool {
+	return !strings.HasPrefix(addr, "unix:") && !strings.ContainsRune(addr, filepath.Separator)
+}
+
+// Listen opens the listener for addr. Addresses starting with "unix:" or
+// containing a path separator are unix sockets; anything else is TCP, which
+// must be a loopback address. Stale socket files are removed.
+func Listen(addr string) (net.Listener, error) {
+	if IsTCP(addr) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if !isLoopback(host) {
+			return nil, fmt.Errorf("refusing to listen on %s: only loopback addresses such as 127.0.0.1 are allowed", addr)
+		}
+		return net.Listen("tcp", addr)
+	}
+
+	path := strings.TrimPrefix(addr, "unix:")
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	return net.Listen("unix", path)
+}
+
+// LoadToken returns the bearer token stored in path, generating it into a
+// file only the user can read when there is none.
+func LoadToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if token := strings.TrimSpace(string(data)); token != "" {
+			return token, nil
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	if err := atomicwrite.WriteFile(path, []byte(token+"\n"), 0o600); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Serve serves the API on l until ctx is canceled.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	srv := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	err := srv.Serve(l)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	statuses, err := s.client.CheckTemplates(r.Context(), nil)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	var counts Counts
+	for _, st := range statuses {
+		switch st.Status {
+		case mmdot.StatusCurrent:
+			counts.Current++
+		case mmdot.StatusChanged:
+			counts.Changed++
+		case mmdot.StatusMissing:
+			counts.Missing++
+		}
+	}
+
+	s.mu.Lock()
+	resp := StatusResponse{
+		Config:  s.client.Config().ConfigDir,
+		Counts:  counts,
+		Drifted: counts.Drifted(),
+		Running: s.running,
+		LastRun: s.last,
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleDrift(w http.ResponseWriter, r *http.Request) {
+	statuses, err := s.client.CheckTemplates(r.Context(), nil)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	items := []DriftItem{}
+	for _, st := range statuses {
+		if st.Status == mmdot.StatusCurrent {
+			continue
+		}
+		items = append(items, DriftItem{Name: st.Template.Name, Output: st.Template.Output, Status: st.Status})
+	}
+
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		writeError(w, http.StatusConflict, errors.New("a run is already in progress"))
+		return
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	// A manual run or apply may hold the machine-wide lock
+	unlock, err := core.AcquireRunLock()
+	if err != nil {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+		status := http.StatusInternalServerError
+		if errors.Is(err, core.ErrLocked) {
+			status = http.StatusConflict
+		}
+		writeError(w, status, err)
+		return
+	}
+	defer unlock()
+
+	// Decrypted vault contents and fetched secrets are not kept in memory
+	// between runs
+	defer s.client.ClearCache()
+	defer core.ClearSecretCache()
+
+	tags := r.URL.Query()["tag"]
+	var filter mmdot.Filter
+	if len(tags) > 0 {
+		filter = mmdot.WithTags(tags...)
+	}
+
+	report := &Report{StartedAt: time.Now(), Tags: tags, Templates: []string{}}
+
+	written, err := s.client.RenderTemplates(r.Context(), filter)
+	report.Templates = append(report.Templates, written...)
+	if err == nil {
+		err = s.client.RunScripts(r.Context(), filter)
+	}
+
+	report.Duration = time.Since(report.StartedAt).Round(time.Millisecond).String()
+	report.Success = err == nil
+	if err != nil {
+		report.Error = err.Error()
+		log.Warn().Err(err).Msg("daemon run failed")
+	}
+
+	s.mu.Lock()
+	s.running = false
+	s.last = report
+	s.mu.Unlock()
+
+	status := http.StatusOK
+	if err != nil {
+		status = http.StatusInternalServerError
+	}
+	writeJSON(w, status, report)
+}
+
+func (s *Server) handleReport(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	last := s.last
+	s.mu.Unlock()
+
+	if last == nil {
+		writeError(w, http.StatusNotFound, errors.New("no run has been triggered yet"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, last)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Debug().Err(err).Msg("failed to write response")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
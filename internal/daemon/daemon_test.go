@@ -0,0 +1,211 @@
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hay-kot/mmdot/pkgs/mmdot"
+)
+
+func newTestServer(t *testing.T, token string) *httptest.Server {
+	t.Helper()
+	t.Setenv("XDG_STATE_HOME", t.TempDir()) // POST /run takes the run lock
+
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "mmdot.yml")
+	config := `
+templates:
+  - name: one
+    tags: [a]
+    template: one
+    output: one.txt
+  - name: two
+    tags: [b]
+    template: two
+    output: two.txt
+`
+	if err := os.WriteFile(cfgPath, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"one", "two"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg, err := mmdot.LoadConfig(cfgPath)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	server := New(mmdot.New(cfg))
+	server.Token = token
+	srv := httptest.NewServer(server.Handler())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func getJSON(t *testing.T, url string, want int, v any) {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != want {
+		t.Fatalf("GET %s: status %d, want %d", url, resp.StatusCode, want)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("decode %s: %v", url, err)
+	}
+}
+
+func TestServer(t *testing.T) {
+	srv := newTestServer(t, "")
+
+	var status StatusResponse
+	getJSON(t, srv.URL+"/status", http.StatusOK, &status)
+	if status.Counts.Missing != 2 || status.Drifted != 2 || status.LastRun != nil {
+		t.Fatalf("initial status = %+v", status)
+	}
+
+	var errResp map[string]string
+	getJSON(t, srv.URL+"/report", http.StatusNotFound, &errResp)
+
+	resp, err := http.Post(srv.URL+"/run?tag=a", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report Report
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if !report.Success || len(report.Templates) != 1 {
+		t.Fatalf("run report = %+v", report)
+	}
+
+	var drift []DriftItem
+	getJSON(t, srv.URL+"/drift", http.StatusOK, &drift)
+	if len(drift) != 1 || drift[0].Name != "two" || drift[0].Status != mmdot.StatusMissing {
+		t.Errorf("drift = %+v, want only two missing", drift)
+	}
+
+	getJSON(t, srv.URL+"/status", http.StatusOK, &status)
+	if status.Counts.Current != 1 || status.Drifted != 1 || status.LastRun == nil {
+		t.Errorf("status after run = %+v", status)
+	}
+}
+
+func TestServer_Guard(t *testing.T) {
+	const token = "secret-token"
+	srv := newTestServer(t, token)
+
+	tests := []struct {
+		name   string
+		host   string
+		header map[string]string
+		want   int
+	}{
+		{name: "token", header: map[string]string{"Authorization": "Bearer " + token}, want: http.StatusOK},
+		{name: "no token", want: http.StatusUnauthorized},
+		{name: "wrong token", header: map[string]string{"Authorization": "Bearer nope"}, want: http.StatusUnauthorized},
+		{
+			name:   "cross origin",
+			header: map[string]string{"Authorization": "Bearer " + token, "Origin": "https://example.com"},
+			want:   http.StatusForbidden,
+		},
+		{
+			name:   "rebound host",
+			host:   "attacker.example.com",
+			header: map[string]string{"Authorization": "Bearer " + token},
+			want:   http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, srv.URL+"/drift", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.host != "" {
+				req.Host = tt.host
+			}
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_Guard_Origin(t *testing.T) {
+	srv := newTestServer(t, "")
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/run", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Origin", "https://example.com")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("cross-origin POST /run status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+}
+
+func TestListen_RejectsNonLoopback(t *testing.T) {
+	for _, addr := range []string{"0.0.0.0:0", ":0", "192.0.2.1:0"} {
+		if l, err := Listen(addr); err == nil {
+			_ = l.Close()
+			t.Errorf("Listen(%q) succeeded, want an error", addr)
+		}
+	}
+
+	l, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen(127.0.0.1:0) error = %v", err)
+	}
+	_ = l.Close()
+}
+
+func TestLoadToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mmdot", "daemon.token")
+
+	token, err := LoadToken(path)
+	if err != nil || len(token) != 64 {
+		t.Fatalf("LoadToken() = %q, %v", token, err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("token perm = %o, want 600", perm)
+	}
+
+	again, err := LoadToken(path)
+	if err != nil || again != token {
+		t.Errorf("LoadToken() again = %q, %v, want the stored token", again, err)
+	}
+}
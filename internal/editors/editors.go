@@ -0,0 +1,114 @@
+// Package editors manages the extensions and user settings of VS Code family
+// editors through their command line tools.
+package editors
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/pkgs/atomicwrite"
+)
+
+// Extensions returns the installed extension IDs, lowercased.
+func Extensions(ctx context.Context, e core.Editor) ([]string, error) {
+	out, err := exec.CommandContext(ctx, e.CLI(), "--list-extensions").Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s --list-extensions: %w", e.CLI(), err)
+	}
+
+	var ids []string
+	for line := range strings.SplitSeq(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			ids = append(ids, strings.ToLower(line))
+		}
+	}
+	return ids, nil
+}
+
+// InstallExtension installs an extension with the editor's CLI. It returns
+// [core.ErrOffline] in offline mode since the CLI downloads the extension.
+func InstallExtension(ctx context.Context, e core.Editor, id string) error {
+	if core.Offline {
+		return core.ErrOffline
+	}
+
+	out, err := exec.CommandContext(ctx, e.CLI(), "--install-extension", id).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s --install-extension %s: %w: %s", e.CLI(), id, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// ExtensionDiff compares configured extensions with installed ones.
+type ExtensionDiff struct {
+	Missing []string // configured but not installed
+	Extra   []string // installed but not configured
+}
+
+// DiffExtensions compares extension IDs case-insensitively.
+func DiffExtensions(want, installed []string) ExtensionDiff {
+	var diff ExtensionDiff
+
+	wanted := make([]string, len(want))
+	for i, id := range want {
+		wanted[i] = strings.ToLower(id)
+		if !slices.Contains(installed, wanted[i]) {
+			diff.Missing = append(diff.Missing, id)
+		}
+	}
+	for _, id := range installed {
+		if !slices.Contains(wanted, id) {
+			diff.Extra = append(diff.Extra, id)
+		}
+	}
+	return diff
+}
+
+// SettingsChange is the current and merged content of a settings.json.
+type SettingsChange struct {
+	Path    string
+	Current []byte
+	Merged  []byte
+}
+
+// Changed reports whether writing Merged would modify the file.
+func (c SettingsChange) Changed() bool {
+	return !bytes.Equal(c.Current, c.Merged)
+}
+
+// MergeSettings merges the rendered settings object into the settings.json
+// at path. The file is not written.
+func MergeSettings(path string, rendered []byte) (SettingsChange, error) {
+	current, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return SettingsChange{}, err
+	}
+
+	merged, err := mergeSettings(current, rendered)
+	if err != nil {
+		return SettingsChange{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return SettingsChange{Path: path, Current: current, Merged: merged}, nil
+}
+
+// Write saves the merged settings.
+func (c SettingsChange) Write() error {
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0o755); err != nil {
+		return err
+	}
+
+	perm := os.FileMode(0o644)
+	if info, err := os.Stat(c.Path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	return atomicwrite.WriteFile(c.Path, c.Merged, perm)
+}
@@ -0,0 +1,337 @@
+package editors
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// member is a top-level key of a JSONC object and the byte span of its value.
+type member struct {
+	key        string
+	start, end int
+}
+
+// object is the layout of a top-level JSONC object.
+type object struct {
+	open    int // index of '{'
+	members []member
+}
+
+// scanObject locates the members of the top-level object in a JSONC
+// document. Comments and trailing commas are allowed.
+func scanObject(data []byte) (object, error) {
+	s := &scanner{data: data}
+	var obj object
+
+	s.skip()
+	if !s.consume('{') {
+		return obj, s.errorf("expected '{'")
+	}
+	obj.open = s.pos - 1
+
+	for {
+		s.skip()
+		if s.consume('}') {
+			break
+		}
+
+		keyStart := s.pos
+		if err := s.string(); err != nil {
+			return obj, err
+		}
+		var key string
+		if err := json.Unmarshal(data[keyStart:s.pos], &key); err != nil {
+			return obj, s.errorf("invalid key: %v", err)
+		}
+
+		s.skip()
+		if !s.consume(':') {
+			return obj, s.errorf("expected ':' after %q", key)
+		}
+		s.skip()
+
+		start := s.pos
+		if err := s.value(); err != nil {
+			return obj, err
+		}
+		obj.members = append(obj.members, member{key: key, start: start, end: s.pos})
+
+		s.skip()
+		if s.consume(',') {
+			continue
+		}
+		s.skip()
+		if !s.consume('}') {
+			return obj, s.errorf("expected ',' or '}'")
+		}
+		break
+	}
+
+	s.skip()
+	if s.pos != len(data) {
+		return obj, s.errorf("unexpected content after object")
+	}
+	return obj, nil
+}
+
+// mergeSettings sets every top-level key of patch in settings, replacing
+// existing values in place and appending new keys. Comments and keys not in
+// patch are left untouched. Both documents may be JSONC.
+func mergeSettings(settings, patch []byte) ([]byte, error) {
+	if len(bytes.TrimSpace(settings)) == 0 {
+		settings = []byte("{}\n")
+	}
+
+	src, err := scanObject(patch)
+	if err != nil {
+		return nil, fmt.Errorf("settings template: %w", err)
+	}
+
+	out := settings
+	for _, m := range src.members {
+		value := stripComments(patch[m.start:m.end])
+		if !json.Valid(value) {
+			return nil, fmt.Errorf("settings template: %q: invalid value", m.key)
+		}
+		if out, err = setMember(out, m.key, value); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// setMember replaces the value of key in the top-level object of data, or
+// appends the key when missing. value is JSON, formatted to match the
+// indentation of data.
+func setMember(data []byte, key string, raw []byte) ([]byte, error) {
+	obj, err := scanObject(data)
+	if err != nil {
+		return nil, err
+	}
+
+	indent, inline := detectIndent(data, obj)
+	value, err := formatValue(raw, indent, inline)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for _, m := range obj.members {
+		if m.key != key {
+			continue
+		}
+		buf.Write(data[:m.start])
+		buf.WriteString(value)
+		buf.Write(data[m.end:])
+		return buf.Bytes(), nil
+	}
+
+	encodedKey, _ := json.Marshal(key)
+	entry := "\n" + indent + string(encodedKey) + ": " + value
+	if inline {
+		entry = " " + string(encodedKey) + ": " + value
+	}
+
+	if len(obj.members) == 0 {
+		buf.Write(data[:obj.open+1])
+		buf.WriteString(entry + "\n")
+		buf.Write(bytes.TrimLeft(data[obj.open+1:], " \t\r\n"))
+		return buf.Bytes(), nil
+	}
+
+	last := obj.members[len(obj.members)-1]
+	buf.Write(data[:last.end])
+	buf.WriteString("," + entry)
+	buf.Write(data[last.end:])
+	return buf.Bytes(), nil
+}
+
+// formatValue formats a JSON value for a member at the given indentation,
+// or compactly for an inline object.
+func formatValue(raw []byte, indent string, inline bool) (string, error) {
+	var buf bytes.Buffer
+	if inline {
+		if err := json.Compact(&buf, raw); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+
+	if err := json.Indent(&buf, raw, indent, indent); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// detectIndent returns the indentation of the first member, defaulting to
+// the four spaces VS Code writes. inline reports whether members share a
+// line with the opening brace, as in {"a": 1}.
+func detectIndent(data []byte, obj object) (indent string, inline bool) {
+	if len(obj.members) == 0 {
+		return "    ", false
+	}
+
+	keyLine := data[:obj.members[0].start]
+	lineStart := bytes.LastIndexByte(keyLine, '\n') + 1
+	if lineStart <= obj.open {
+		return "", true
+	}
+
+	line := keyLine[lineStart:]
+	ws := line[:len(line)-len(bytes.TrimLeft(line, " \t"))]
+	if len(ws) == 0 {
+		return "    ", false
+	}
+	return string(ws), false
+}
+
+// stripComments removes comments and trailing commas from a JSONC value so
+// it can be decoded as JSON.
+func stripComments(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	s := &scanner{data: data}
+	for s.pos < len(data) {
+		c := data[s.pos]
+		switch {
+		case c == '"':
+			start := s.pos
+			_ = s.string()
+			out = append(out, data[start:s.pos]...)
+		case s.comment():
+		case c == ',':
+			s.pos++
+			save := s.pos
+			s.skip()
+			if s.pos < len(data) && (data[s.pos] == '}' || data[s.pos] == ']') {
+				continue
+			}
+			s.pos = save
+			out = append(out, ',')
+		default:
+			out = append(out, c)
+			s.pos++
+		}
+	}
+	return out
+}
+
+type scanner struct {
+	data []byte
+	pos  int
+}
+
+func (s *scanner) errorf(format string, args ...any) error {
+	line := bytes.Count(s.data[:min(s.pos, len(s.data))], []byte("\n")) + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (s *scanner) consume(c byte) bool {
+	if s.pos < len(s.data) && s.data[s.pos] == c {
+		s.pos++
+		return true
+	}
+	return false
+}
+
+// comment skips a comment at the current position, reporting whether there
+// was one.
+func (s *scanner) comment() bool {
+	rest := s.data[s.pos:]
+	switch {
+	case bytes.HasPrefix(rest, []byte("//")):
+		end := bytes.IndexByte(rest, '\n')
+		if end == -1 {
+			end = len(rest)
+		}
+		s.pos += end
+		return true
+	case bytes.HasPrefix(rest, []byte("/*")):
+		end := bytes.Index(rest[2:], []byte("*/"))
+		if end == -1 {
+			s.pos = len(s.data)
+		} else {
+			s.pos += end + 4
+		}
+		return true
+	}
+	return false
+}
+
+// skip advances past whitespace and comments.
+func (s *scanner) skip() {
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case ' ', '\t', '\r', '\n':
+			s.pos++
+		default:
+			if !s.comment() {
+				return
+			}
+		}
+	}
+}
+
+func (s *scanner) string() error {
+	if !s.consume('"') {
+		return s.errorf("expected string")
+	}
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case '\\':
+			s.pos += 2
+		case '"':
+			s.pos++
+			return nil
+		default:
+			s.pos++
+		}
+	}
+	return s.errorf("unterminated string")
+}
+
+// value advances past a value: a string, a nested object or array, or a
+// literal.
+func (s *scanner) value() error {
+	if s.pos >= len(s.data) {
+		return s.errorf("expected value")
+	}
+
+	switch s.data[s.pos] {
+	case '"':
+		return s.string()
+	case '{', '[':
+		depth := 0
+		for s.pos < len(s.data) {
+			switch c := s.data[s.pos]; {
+			case c == '"':
+				if err := s.string(); err != nil {
+					return err
+				}
+				continue
+			case s.comment():
+				continue
+			case c == '{' || c == '[':
+				depth++
+			case c == '}' || c == ']':
+				depth--
+				if depth == 0 {
+					s.pos++
+					return nil
+				}
+			}
+			s.pos++
+		}
+		return s.errorf("unterminated object or array")
+	default:
+		start := s.pos
+		for s.pos < len(s.data) && !strings.ContainsRune(",}] \t\r\n/", rune(s.data[s.pos])) {
+			s.pos++
+		}
+		if s.pos == start {
+			return s.errorf("expected value")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,125 @@
+package editors
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestMergeSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings string
+		patch    string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "empty file",
+			settings: "",
+			patch:    `{"editor.fontSize": 14}`,
+			want:     "{\n    \"editor.fontSize\": 14\n}\n",
+		},
+		{
+			name: "replace in place keeps comments",
+			settings: `{
+  // font
+  "editor.fontSize": 12, // small
+  "files.autoSave": "off",
+}
+`,
+			patch: `{"editor.fontSize": 14}`,
+			want: `{
+  // font
+  "editor.fontSize": 14, // small
+  "files.autoSave": "off",
+}
+`,
+		},
+		{
+			name: "append new key with detected indent",
+			settings: `{
+	"a": 1
+	/* trailing comment */
+}
+`,
+			patch: `{"b": {"c": [1, 2]}}`,
+			want: `{
+	"a": 1,
+	"b": {
+		"c": [
+			1,
+			2
+		]
+	}
+	/* trailing comment */
+}
+`,
+		},
+		{
+			name:     "jsonc patch",
+			settings: `{"a": 1}`,
+			patch: `{
+  // managed by mmdot
+  "a": [1, 2,], /* two */
+}`,
+			want: `{"a": [1,2]}`,
+		},
+		{
+			name:     "strings containing braces and slashes",
+			settings: `{"url": "http://x/{y}", "n": 1}`,
+			patch:    `{"n": 2}`,
+			want:     `{"url": "http://x/{y}", "n": 2}`,
+		},
+		{
+			name:     "append to inline object",
+			settings: `{"a": 1}`,
+			patch:    `{"b": {"c": true}}`,
+			want:     `{"a": 1, "b": {"c":true}}`,
+		},
+		{
+			name:     "invalid settings",
+			settings: `[1, 2]`,
+			patch:    `{"a": 1}`,
+			wantErr:  true,
+		},
+		{
+			name:     "invalid patch",
+			settings: `{}`,
+			patch:    `{"a": }`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeSettings([]byte(tt.settings), []byte(tt.patch))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("mergeSettings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if string(got) != tt.want {
+				t.Errorf("mergeSettings() =\n%s\nwant:\n%s", got, tt.want)
+			}
+			if !json.Valid(stripComments(got)) {
+				t.Errorf("mergeSettings() produced invalid JSONC:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestDiffExtensions(t *testing.T) {
+	diff := DiffExtensions(
+		[]string{"golang.Go", "esbenp.prettier-vscode"},
+		[]string{"golang.go", "ms-python.python"},
+	)
+
+	if !slices.Equal(diff.Missing, []string{"esbenp.prettier-vscode"}) {
+		t.Errorf("Missing = %v", diff.Missing)
+	}
+	if !slices.Equal(diff.Extra, []string{"ms-python.python"}) {
+		t.Errorf("Extra = %v", diff.Extra)
+	}
+}
@@ -0,0 +1,368 @@
+// Package fonts installs font archives into the user's font directory:
+// ~/Library/Fonts on macOS and $XDG_DATA_HOME/fonts (~/.local/share/fonts)
+// elsewhere. The files installed for each font are recorded in a manifest in
+// the mmdot state directory so they can be diffed and replaced later.
+package fonts
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/pkgs/atomicwrite"
+)
+
+// Dir returns the user font directory for the current platform.
+func Dir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	if runtime.GOOS == "darwin" {
+		return filepath.Join(home, "Library", "Fonts"), nil
+	}
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "fonts"), nil
+	}
+	return filepath.Join(home, ".local", "share", "fonts"), nil
+}
+
+// Manifest records what was installed for a font.
+type Manifest struct {
+	Source   string   `json:"source"`
+	Checksum string   `json:"checksum"` // sha256 of the download
+	Files    []string `json:"files"`    // absolute paths of installed files
+}
+
+// Installer downloads fonts into Dir and keeps manifests in StateDir.
+type Installer struct {
+	HTTP     *http.Client
+	Dir      string
+	StateDir string
+
+	// Flat installs files directly in Dir rather than in a directory per
+	// font. macOS does not look for fonts in subdirectories of
+	// ~/Library/Fonts.
+	Flat bool
+}
+
+// NewInstaller returns an Installer for the current platform.
+func NewInstaller() (*Installer, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := core.StateDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Installer{
+		HTTP:     http.DefaultClient,
+		Dir:      dir,
+		StateDir: filepath.Join(state, "fonts"),
+		Flat:     runtime.GOOS == "darwin",
+	}, nil
+}
+
+// Status describes how an installed font compares with its config.
+type Status struct {
+	Installed bool
+	Stale     bool     // installed from a different source
+	Missing   []string // manifest files that no longer exist
+}
+
+// Current reports whether the font is installed from its configured source
+// with every file present.
+func (s Status) Current() bool {
+	return s.Installed && !s.Stale && len(s.Missing) == 0
+}
+
+// Status compares the manifest of f with its config and the files on disk.
+func (i *Installer) Status(f core.Font) (Status, error) {
+	m, err := i.manifest(f.Name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return Status{}, nil
+		}
+		return Status{}, err
+	}
+
+	st := Status{Installed: true, Stale: m.Source != f.Source()}
+	for _, file := range m.Files {
+		if _, err := os.Stat(file); err != nil {
+			st.Missing = append(st.Missing, file)
+		}
+	}
+	return st, nil
+}
+
+// Install downloads f, verifies its checksum when configured, and writes the
+// selected font files, replacing any files from a previous install. It
+// returns the installed file paths.
+func (i *Installer) Install(ctx context.Context, f core.Font) ([]string, error) {
+	data, err := i.download(ctx, f.Source())
+	if err != nil {
+		return nil, fmt.Errorf("font %s: %w", f.Name, err)
+	}
+
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+	if want := strings.TrimPrefix(f.Checksum, "sha256:"); want != "" && !strings.EqualFold(want, checksum) {
+		return nil, fmt.Errorf("font %s: checksum mismatch: got %s, want %s", f.Name, checksum, want)
+	}
+
+	files, err := extract(f.Source(), data, f.FilePatterns())
+	if err != nil {
+		return nil, fmt.Errorf("font %s: %w", f.Name, err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("font %s: no files match %s", f.Name, strings.Join(f.FilePatterns(), ", "))
+	}
+
+	if err := i.remove(f.Name); err != nil {
+		return nil, err
+	}
+
+	dir := i.Dir
+	if !i.Flat {
+		dir = filepath.Join(i.Dir, f.Name)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	installed := make([]string, 0, len(files))
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		dest := filepath.Join(dir, name)
+		if err := atomicwrite.WriteFile(dest, files[name], 0o644); err != nil {
+			return nil, err
+		}
+		installed = append(installed, dest)
+	}
+
+	m := Manifest{Source: f.Source(), Checksum: checksum, Files: installed}
+	return installed, i.saveManifest(f.Name, m)
+}
+
+// Unmanaged returns the font files in Dir that were not installed by any
+// manifest in the state directory.
+func (i *Installer) Unmanaged() ([]string, error) {
+	managed := map[string]bool{}
+	entries, err := os.ReadDir(i.StateDir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	for _, e := range entries {
+		m, err := i.manifest(strings.TrimSuffix(e.Name(), ".json"))
+		if err != nil {
+			continue
+		}
+		for _, file := range m.Files {
+			managed[file] = true
+		}
+	}
+
+	var out []string
+	err = filepath.WalkDir(i.Dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return filepath.SkipAll
+			}
+			return err
+		}
+		if !d.IsDir() && isFontFile(p) && !managed[p] {
+			out = append(out, p)
+		}
+		return nil
+	})
+	return out, err
+}
+
+// RefreshCache rebuilds the fontconfig cache for Dir. It does nothing on
+// macOS or when fc-cache is not installed.
+func (i *Installer) RefreshCache(ctx context.Context) error {
+	if runtime.GOOS == "darwin" {
+		return nil
+	}
+	if _, err := exec.LookPath("fc-cache"); err != nil {
+		return nil
+	}
+
+	out, err := exec.CommandContext(ctx, "fc-cache", "-f", i.Dir).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("fc-cache: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// remove deletes the files recorded for a previous install of name.
+func (i *Installer) remove(name string) error {
+	m, err := i.manifest(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	for _, file := range m.Files {
+		if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i *Installer) manifestPath(name string) string {
+	return filepath.Join(i.StateDir, name+".json")
+}
+
+func (i *Installer) manifest(name string) (Manifest, error) {
+	var m Manifest
+	data, err := os.ReadFile(i.manifestPath(name))
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("invalid font manifest %s: %w", i.manifestPath(name), err)
+	}
+	return m, nil
+}
+
+func (i *Installer) saveManifest(name string, m Manifest) error {
+	if err := os.MkdirAll(i.StateDir, 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicwrite.WriteFile(i.manifestPath(name), data, 0o644)
+}
+
+func (i *Installer) download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := i.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// extract returns the files of an archive whose base name matches any of
+// patterns, keyed by base name. A download that is not an archive is
+// returned as a single file.
+func extract(source string, data []byte, patterns []string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	add := func(name string, r io.Reader) error {
+		base := path.Base(name)
+		if !matchAny(patterns, base) {
+			return nil
+		}
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		files[base] = content
+		return nil
+	}
+
+	name := strings.ToLower(path.Base(source))
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			err = add(f.Name, rc)
+			_ = rc.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		tr := tar.NewReader(gz)
+		for {
+			hdr, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if hdr.Typeflag != tar.TypeReg {
+				continue
+			}
+			if err := add(hdr.Name, tr); err != nil {
+				return nil, err
+			}
+		}
+	case isFontFile(name):
+		if err := add(name, bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported download format %s (expected .zip, .tar.gz, or a font file)", path.Base(source))
+	}
+
+	return files, nil
+}
+
+var fontExts = []string{".ttf", ".otf", ".ttc", ".otc"}
+
+func isFontFile(name string) bool {
+	return slices.Contains(fontExts, strings.ToLower(filepath.Ext(name)))
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
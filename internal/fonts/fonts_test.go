@@ -0,0 +1,132 @@
+package fonts
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/hay-kot/mmdot/internal/core"
+)
+
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestInstaller(t *testing.T) {
+	v1 := zipArchive(t, map[string]string{
+		"Mono-Regular.ttf": "regular",
+		"Mono-Bold.ttf":    "bold",
+		"OFL.txt":          "license",
+	})
+	v2 := zipArchive(t, map[string]string{"fonts/Mono-Regular.otf": "regular v2"})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/Mono.zip", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write(v1) })
+	mux.HandleFunc("/v2/Mono.zip", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write(v2) })
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	inst := &Installer{
+		HTTP:     srv.Client(),
+		Dir:      filepath.Join(dir, "fonts"),
+		StateDir: filepath.Join(dir, "state"),
+	}
+	ctx := context.Background()
+
+	font := core.Font{Name: "Mono", URL: srv.URL + "/v1/Mono.zip"}
+
+	st, err := inst.Status(font)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Installed {
+		t.Fatalf("Status() before install = %+v", st)
+	}
+
+	files, err := inst.Install(ctx, font)
+	if err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	want := []string{
+		filepath.Join(inst.Dir, "Mono", "Mono-Bold.ttf"),
+		filepath.Join(inst.Dir, "Mono", "Mono-Regular.ttf"),
+	}
+	if !slices.Equal(files, want) {
+		t.Errorf("Install() = %v, want %v", files, want)
+	}
+
+	if st, _ := inst.Status(font); !st.Current() {
+		t.Errorf("Status() after install = %+v, want current", st)
+	}
+
+	extra := filepath.Join(inst.Dir, "Other.otf")
+	if err := os.WriteFile(extra, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	unmanaged, err := inst.Unmanaged()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(unmanaged, []string{extra}) {
+		t.Errorf("Unmanaged() = %v, want [%s]", unmanaged, extra)
+	}
+
+	// Changing the source marks the font stale and reinstalling replaces
+	// the previous files
+	font.URL = srv.URL + "/v2/Mono.zip"
+	if st, _ := inst.Status(font); !st.Stale {
+		t.Errorf("Status() after source change = %+v, want stale", st)
+	}
+	if _, err := inst.Install(ctx, font); err != nil {
+		t.Fatalf("Install() v2 error = %v", err)
+	}
+	for _, old := range want {
+		if _, err := os.Stat(old); !os.IsNotExist(err) {
+			t.Errorf("%s still exists after reinstall", old)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(inst.Dir, "Mono", "Mono-Regular.otf")); err != nil {
+		t.Errorf("v2 font not installed: %v", err)
+	}
+}
+
+func TestInstaller_ChecksumMismatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("font data"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	inst := &Installer{HTTP: srv.Client(), Dir: filepath.Join(dir, "fonts"), StateDir: filepath.Join(dir, "state")}
+
+	font := core.Font{Name: "Single", URL: srv.URL + "/Single.ttf", Checksum: "sha256:" + string(bytes.Repeat([]byte("a"), 64))}
+	if _, err := inst.Install(context.Background(), font); err == nil {
+		t.Fatal("Install() error = nil, want checksum mismatch")
+	}
+	if _, err := os.Stat(inst.Dir); !os.IsNotExist(err) {
+		t.Error("font dir created despite checksum mismatch")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"embed"
+	"errors"
 	"fmt"
 	"maps"
 	"os"
@@ -14,7 +15,10 @@ import (
 	"filippo.io/age"
 	"github.com/goccy/go-yaml"
 	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/profile"
+	"github.com/hay-kot/mmdot/pkgs/atomicwrite"
 	"github.com/hay-kot/mmdot/pkgs/fcrypt"
+	"github.com/hay-kot/mmdot/pkgs/redact"
 	"github.com/rs/zerolog/log"
 )
 
@@ -22,46 +26,204 @@ import (
 var partialsFS embed.FS
 
 type Engine struct {
-	cfg *core.ConfigFile
+	cfg   *core.ConfigFile
+	cache *fcrypt.Cache // decrypted vault contents, shared across engines by default
 
 	varsLoaded bool
 	globalVars map[string]any
 	fileVars   map[string]any
+	vaultKeys  map[string]bool // top-level fileVars keys set by vault or partial files
 }
 
 func NewEngine(cfg *core.ConfigFile) *Engine {
 	return &Engine{
 		cfg:        cfg,
+		cache:      fcrypt.SharedCache,
 		globalVars: make(map[string]any),
 		fileVars:   make(map[string]any),
+		vaultKeys:  make(map[string]bool),
 	}
 }
 
+// WithCache makes the engine decrypt vault files through cache instead of
+// [fcrypt.SharedCache].
+func (e *Engine) WithCache(cache *fcrypt.Cache) *Engine {
+	e.cache = cache
+	return e
+}
+
+// RenderTemplate renders the template and writes the result to its output path
+// with the configured permissions.
 func (e *Engine) RenderTemplate(ctx context.Context, tmpl core.Template) error {
+	output, err := e.Render(ctx, tmpl)
+	if err != nil {
+		return err
+	}
+
+	// Create output directory if needed
+	if err := os.MkdirAll(filepath.Dir(tmpl.Output), 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	// Parse permissions
+	perm := os.FileMode(0o644)
+	if tmpl.Permissions != "" {
+		p, err := core.ParseOctalPermissions(tmpl.Permissions)
+		if err != nil {
+			return fmt.Errorf("invalid permissions %s: %w", tmpl.Permissions, err)
+		}
+		perm = p
+	}
+
+	// Looked up first so an unknown user fails before anything is written
+	uid, gid, err := core.LookupOwnership(tmpl.Owner, tmpl.Group)
+	if err != nil {
+		return err
+	}
+
+	// Write output file
+	if err := atomicwrite.WriteFile(tmpl.Output, output, perm); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+
+	return core.Chown(tmpl.Output, uid, gid)
+}
+
+// Render executes the template against the merged variables and returns the
+// output without writing it to disk.
+func (e *Engine) Render(ctx context.Context, tmpl core.Template) ([]byte, error) {
 	if !e.varsLoaded {
-		if err := e.preloadVars(); err != nil {
+		if err := e.preloadVars(ctx); err != nil {
+			return nil, fmt.Errorf("failed to preload vars: %w", err)
+		}
+	}
+
+	defer profile.Track(ctx, "render", tmpl.Name)()
+
+	t, err := e.parse(tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	// Merge variables: global < file < template-specific
+	vars := MergeMaps(e.globalVars, e.fileVars, tmpl.Vars)
+	literal, err := e.literalVars(tmpl, vars)
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", tmpl.Name, err)
+	}
+	vars, err = e.resolveSecrets(ctx, vars, t, literal)
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", tmpl.Name, err)
+	}
+	vars, err = e.interpolateVars(vars, literal)
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", tmpl.Name, err)
+	}
+	if problems := core.ApplyRequiredVars(vars, tmpl.RequiresVars); len(problems) > 0 {
+		return nil, &RequiredVarsError{Template: tmpl.Name, Problems: problems}
+	}
+
+	return e.execute(tmpl, t, vars)
+}
+
+// RequiredVarsError reports the requires_vars of a template that are missing
+// or cannot be coerced to their declared type.
+type RequiredVarsError struct {
+	Template string
+	Problems []string
+}
+
+func (e *RequiredVarsError) Error() string {
+	return fmt.Sprintf("template %s: required vars: %s", e.Template, strings.Join(e.Problems, "; "))
+}
+
+// CheckRequiredVars loads variables without rendering and returns a
+// [RequiredVarsError] when any of the template's requires_vars are missing or
+// have the wrong type.
+func (e *Engine) CheckRequiredVars(ctx context.Context, tmpl core.Template) error {
+	if !e.varsLoaded {
+		if err := e.preloadVars(ctx); err != nil {
 			return fmt.Errorf("failed to preload vars: %w", err)
 		}
 	}
 
-	// Parse built-in partials, then the user's template
+	vars, err := e.templateVars(tmpl)
+	if err != nil {
+		return fmt.Errorf("template %s: %w", tmpl.Name, err)
+	}
+	if problems := core.ApplyRequiredVars(vars, tmpl.RequiresVars); len(problems) > 0 {
+		return &RequiredVarsError{Template: tmpl.Name, Problems: problems}
+	}
+	return nil
+}
+
+// Vars returns the variables tmpl is rendered with: global vars, var files,
+// and the template's own vars, in increasing precedence, with references
+// between them resolved.
+func (e *Engine) Vars(ctx context.Context, tmpl core.Template) (map[string]any, error) {
+	if !e.varsLoaded {
+		if err := e.preloadVars(ctx); err != nil {
+			return nil, fmt.Errorf("failed to preload vars: %w", err)
+		}
+	}
+	return e.templateVars(tmpl)
+}
+
+// templateVars merges the loaded variables for tmpl and resolves references
+// between them, leaving secrets as they are.
+func (e *Engine) templateVars(tmpl core.Template) (map[string]any, error) {
+	vars := MergeMaps(e.globalVars, e.fileVars, tmpl.Vars)
+	literal, err := e.literalVars(tmpl, vars)
+	if err != nil {
+		return nil, err
+	}
+	return e.interpolateVars(vars, literal)
+}
+
+// RenderIsolated executes the template with global, template-specific, and the
+// provided vars (in increasing precedence), without loading var files. It is
+// used to render against fixtures where secrets may not be available, so
+// secret:// references are not resolved either.
+func (e *Engine) RenderIsolated(ctx context.Context, tmpl core.Template, vars map[string]any) ([]byte, error) {
+	t, err := e.parse(tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	merged, err := e.interpolateVars(MergeMaps(e.cfg.Variables.Vars, tmpl.Vars, vars), nil)
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", tmpl.Name, err)
+	}
+	if problems := core.ApplyRequiredVars(merged, tmpl.RequiresVars); len(problems) > 0 {
+		return nil, &RequiredVarsError{Template: tmpl.Name, Problems: problems}
+	}
+
+	return e.execute(tmpl, t, merged)
+}
+
+// parse parses the built-in partials, then the template.
+func (e *Engine) parse(tmpl core.Template) (*template.Template, error) {
 	t := template.New(tmpl.Name).Funcs(e.funcMap())
 	for name, body := range builtinPartials {
 		if _, err := t.New(name).Parse(body); err != nil {
-			return fmt.Errorf("failed to parse builtin partial %q: %w", name, err)
+			return nil, fmt.Errorf("failed to parse builtin partial %q: %w", name, err)
 		}
 	}
-	t, err := t.Parse(tmpl.Template)
+	body, err := templateBody(tmpl.Template)
 	if err != nil {
-		return NewTemplateError(tmpl.Name, err)
+		return nil, err
 	}
+	t, err = t.Parse(body)
+	if err != nil {
+		return nil, NewTemplateError(tmpl.Name, err)
+	}
+	return t, nil
+}
 
-	// Merge variables: global < file < template-specific
-	vars := MergeMaps(e.globalVars, e.fileVars, tmpl.Vars)
-
+func (e *Engine) execute(tmpl core.Template, t *template.Template, vars map[string]any) ([]byte, error) {
 	var buf bytes.Buffer
 	if err := t.Execute(&buf, vars); err != nil {
-		return NewTemplateError(tmpl.Name, err)
+		return nil, NewTemplateError(tmpl.Name, err)
 	}
 
 	// Get output bytes
@@ -72,41 +234,45 @@ func (e *Engine) RenderTemplate(ctx context.Context, tmpl core.Template) error {
 		output = bytes.TrimSpace(output)
 	}
 
-	// Create output directory if needed
-	if err := os.MkdirAll(filepath.Dir(tmpl.Output), 0o755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
-	}
+	return output, nil
+}
 
-	// Parse permissions
-	perm := os.FileMode(0o644)
-	if tmpl.Permissions != "" {
-		p, err := core.ParseOctalPermissions(tmpl.Permissions)
-		if err != nil {
-			return fmt.Errorf("invalid permissions %s: %w", tmpl.Permissions, err)
-		}
-		perm = p
+// TemplateSource returns the unrendered source of a template or service unit
+// template field, reading it from disk when it is a path.
+func TemplateSource(tmpl string) (string, error) {
+	return templateBody(tmpl)
+}
+
+// templateBody returns the template source. The template field holds either
+// inline template text or a path, which the config loader resolves to an
+// absolute path; paths are read from disk and must exist.
+func templateBody(tmpl string) (string, error) {
+	if strings.ContainsAny(tmpl, "\n{") || !filepath.IsAbs(tmpl) {
+		return tmpl, nil
 	}
 
-	// Write output file
-	if err := os.WriteFile(tmpl.Output, output, perm); err != nil {
-		return fmt.Errorf("failed to write output file: %w", err)
+	data, err := os.ReadFile(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("failed to read template file: %w", err)
 	}
 
-	return nil
+	return string(data), nil
 }
 
 // preloadVars loads variables from the [core.ConfigFile] based on the var files
 // this sets the globalVars and fileVars properties and should be called before
 // rendering a template.
-func (e *Engine) preloadVars() error {
+func (e *Engine) preloadVars(ctx context.Context) error {
 	e.varsLoaded = true
 	e.globalVars = e.cfg.Variables.Vars
 
 	// Load identity for encrypted files
 	var identity age.Identity
 	var err error
-	if e.cfg.Age.IdentityFile != "" {
+	if len(e.cfg.Age.IdentityPaths()) > 0 {
+		done := profile.Track(ctx, "decrypt", "identity")
 		identity, err = e.cfg.Age.ReadIdentity()
+		done()
 		if err != nil {
 			log.Warn().Err(err).Msg("failed to load identity file")
 		}
@@ -114,21 +280,97 @@ func (e *Engine) preloadVars() error {
 
 	// Load variable files
 	for _, vf := range e.cfg.Variables.VarFiles {
+		category := "vars"
+		if vf.IsVault || vf.Partial {
+			category = "decrypt"
+		}
+
+		done := profile.Track(ctx, category, filepath.Base(vf.Path))
 		vars, err := e.loadVarsFile(vf, identity)
+		done()
 		if err != nil {
+			if vf.Optional && undecryptable(err) {
+				log.Warn().Err(err).Str("path", vf.Path).Msg("skipping optional vars file")
+				continue
+			}
 			return fmt.Errorf("failed to load vars file %s: %w", vf.Path, err)
 		}
 
+		// Keep secrets out of logs and printed output
+		if vf.IsVault || vf.Partial {
+			redact.AddVars(vars)
+		}
+		for k := range vars {
+			e.vaultKeys[k] = vf.IsVault || vf.Partial
+		}
+
 		// Merge into fileVars
 		maps.Copy(e.fileVars, vars)
 	}
 
+	e.applyPromptAnswers()
+
 	return nil
 }
 
+// UnansweredPrompts returns the prompted variables that are not set by vars,
+// var files, or a cached answer.
+func (e *Engine) UnansweredPrompts(ctx context.Context) ([]core.Prompt, error) {
+	if len(e.cfg.Variables.Prompts) == 0 {
+		return nil, nil
+	}
+
+	if !e.varsLoaded {
+		if err := e.preloadVars(ctx); err != nil {
+			return nil, fmt.Errorf("failed to preload vars: %w", err)
+		}
+	}
+
+	var missing []core.Prompt
+	for _, p := range e.cfg.Variables.Prompts {
+		_, inGlobals := e.globalVars[p.Name]
+		_, inFiles := e.fileVars[p.Name]
+		if !inGlobals && !inFiles {
+			missing = append(missing, p)
+		}
+	}
+	return missing, nil
+}
+
+// applyPromptAnswers fills in prompted variables that are not set by vars or
+// var files from the answers cached on this machine.
+func (e *Engine) applyPromptAnswers() {
+	if len(e.cfg.Variables.Prompts) == 0 {
+		return
+	}
+
+	answers, err := core.LoadPromptAnswers(e.cfg.ConfigDir)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to load prompt answers")
+		return
+	}
+
+	globals := maps.Clone(e.globalVars)
+	if globals == nil {
+		globals = map[string]any{}
+	}
+	for _, p := range e.cfg.Variables.Prompts {
+		_, inGlobals := globals[p.Name]
+		_, inFiles := e.fileVars[p.Name]
+		if answer, ok := answers[p.Name]; ok && !inGlobals && !inFiles {
+			globals[p.Name] = answer
+		}
+	}
+	e.globalVars = globals
+}
+
 func (e *Engine) loadVarsFile(vf core.VarFile, identity age.Identity) (map[string]any, error) {
 	path := vf.Path
 
+	if vf.Partial {
+		return e.loadPartialVaultFile(path, identity)
+	}
+
 	// If it's a vault file, try encrypted version first, then fall back to unencrypted
 	if vf.IsVault {
 		encryptedPath := path
@@ -138,28 +380,7 @@ func (e *Engine) loadVarsFile(vf core.VarFile, identity age.Identity) (map[strin
 
 		// Try encrypted file first
 		if _, err := os.Stat(encryptedPath); err == nil {
-			if identity == nil {
-				return nil, fmt.Errorf("no identity loaded for encrypted file %s", encryptedPath)
-			}
-
-			buff := bytes.NewBuffer([]byte{})
-			file, err := os.Open(encryptedPath)
-			if err != nil {
-				return nil, err
-			}
-			defer func() { _ = file.Close() }()
-
-			err = fcrypt.DecryptReader(file, buff, identity)
-			if err != nil {
-				return nil, err
-			}
-
-			vars := map[string]any{}
-			if err = yaml.Unmarshal(buff.Bytes(), &vars); err != nil {
-				return nil, err
-			}
-
-			return vars, nil
+			return e.readVarsFile(encryptedPath, identity)
 		}
 
 		// Fall back to unencrypted file
@@ -172,11 +393,39 @@ func (e *Engine) loadVarsFile(vf core.VarFile, identity age.Identity) (map[strin
 		return nil, nil
 	}
 
+	return e.readVarsFile(path, identity)
+}
+
+// errNoIdentity is returned for an encrypted vars file when no age identity
+// could be loaded.
+var errNoIdentity = errors.New("no identity loaded")
+
+// undecryptable reports whether err means a vars file could not be decrypted
+// on this machine, the only failure an optional vars file is skipped for.
+func undecryptable(err error) bool {
+	return errors.Is(err, fcrypt.ErrDecrypt) || errors.Is(err, errNoIdentity)
+}
+
+// readVarsFile reads a vars file, decrypting it when its content is age
+// encrypted whatever its name.
+func (e *Engine) readVarsFile(path string, identity age.Identity) (map[string]any, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
+	if fcrypt.IsEncrypted(data) {
+		if identity == nil {
+			return nil, fmt.Errorf("%w for encrypted file %s", errNoIdentity, path)
+		}
+		data, err = e.cache.Decrypt(data, core.IdentityFor(identity, path))
+		if err != nil {
+			return nil, err
+		}
+	} else if filepath.Ext(path) == ".age" {
+		log.Warn().Str("path", path).Msg("vars file is not encrypted, reading it as plaintext")
+	}
+
 	vars := map[string]any{}
 	if err = yaml.Unmarshal(data, &vars); err != nil {
 		return nil, err
@@ -185,6 +434,35 @@ func (e *Engine) loadVarsFile(vf core.VarFile, identity age.Identity) (map[strin
 	return vars, nil
 }
 
+// loadPartialVaultFile loads a YAML file whose values were encrypted with
+// [fcrypt.EncryptValues]. Plaintext values are allowed and used as-is.
+func (e *Engine) loadPartialVaultFile(path string, identity age.Identity) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Warn().Str("path", path).Msg("vars file does not exist, skipping")
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if identity == nil && bytes.Contains(data, []byte("ENC[age,")) {
+		return nil, fmt.Errorf("%w for partially encrypted file %s", errNoIdentity, path)
+	}
+
+	doc, err := fcrypt.DecryptValues(data, core.IdentityFor(identity, path))
+	if err != nil {
+		return nil, err
+	}
+
+	vars, ok := doc.(map[string]any)
+	if !ok && doc != nil {
+		return nil, fmt.Errorf("expected a mapping at the top level of %s", path)
+	}
+
+	return vars, nil
+}
+
 // builtinPartials are named templates loaded from embedded files in partials/.
 // Each file becomes a partial named after the filename without extension.
 // Invoke with {{template "name" arg}}.
@@ -217,7 +495,7 @@ func mustLoadPartials() map[string]string {
 
 // funcMap returns template functions available to all templates.
 func (e *Engine) funcMap() template.FuncMap {
-	return template.FuncMap{
+	funcs := template.FuncMap{
 		// brewConfig resolves a named brew configuration (with includes merged)
 		// and returns it for use in templates.
 		//
@@ -228,29 +506,49 @@ func (e *Engine) funcMap() template.FuncMap {
 			if b == nil {
 				return nil, fmt.Errorf("brew config %q not found", name)
 			}
+			if errs := e.cfg.Brews.ValidateIncludes(name); len(errs) > 0 {
+				return nil, errs[0]
+			}
 			return b, nil
 		},
 		// brewBlock renders a batch install block with backslash continuation.
-		// e.g. brewBlock "brew install" ["git", "vim"] produces:
+		// Packages with args get a command of their own after the block.
+		// e.g. brewBlock "brew install" ["git", "vim", {name: neovim, args: [--HEAD]}]
+		// produces:
 		//   brew install \
 		//     git \
 		//     vim
-		"brewBlock": func(cmd string, pkgs []string) string {
-			if len(pkgs) == 0 {
-				return ""
+		//   brew install --HEAD neovim
+		"brewBlock": func(cmd string, pkgs []core.BrewPackage) string {
+			var plain, withArgs []core.BrewPackage
+			for _, pkg := range pkgs {
+				if len(pkg.Args) > 0 {
+					withArgs = append(withArgs, pkg)
+				} else {
+					plain = append(plain, pkg)
+				}
 			}
+
 			var sb strings.Builder
-			sb.WriteString(cmd + " \\\n")
-			for i, pkg := range pkgs {
-				sb.WriteString("  " + pkg)
-				if i < len(pkgs)-1 {
-					sb.WriteString(" \\")
+			if len(plain) > 0 {
+				sb.WriteString(cmd + " \\\n")
+				for i, pkg := range plain {
+					sb.WriteString("  " + pkg.Name)
+					if i < len(plain)-1 {
+						sb.WriteString(" \\")
+					}
+					sb.WriteString("\n")
 				}
-				sb.WriteString("\n")
+			}
+			for _, pkg := range withArgs {
+				sb.WriteString(cmd + " " + strings.Join(pkg.Args, " ") + " " + pkg.Name + "\n")
 			}
 			return sb.String()
 		},
 	}
+
+	maps.Copy(funcs, encodingFuncs())
+	return funcs
 }
 
 // MergeMaps merges multiple maps with later maps taking precedence over earlier ones.
@@ -3,10 +3,13 @@ package generator
 import (
 	"bytes"
 	"context"
+	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"filippo.io/age"
 	"github.com/hay-kot/mmdot/internal/core"
 )
 
@@ -17,13 +20,13 @@ func TestBrewfilePartial(t *testing.T) {
 	cfg := &core.ConfigFile{
 		Brews: core.ConfigMap{
 			"base": &core.Brews{
-				Brews: []string{"curl", "wget"},
+				Brews: []core.BrewPackage{{Name: "curl"}, {Name: "wget"}},
 			},
 			"personal": &core.Brews{
 				Includes: []string{"base"},
 				Taps:     []string{"homebrew/cask"},
-				Brews:    []string{"git", "vim"},
-				Casks:    []string{"firefox"},
+				Brews:    []core.BrewPackage{{Name: "git"}, {Name: "vim"}},
+				Casks:    []core.BrewPackage{{Name: "firefox"}},
 				MAS:      []string{"497799835"},
 			},
 		},
@@ -67,6 +70,50 @@ set -euo pipefail
 	}
 }
 
+func TestBrewfilePartialPackageOptions(t *testing.T) {
+	dir := t.TempDir()
+	outfile := filepath.Join(dir, "brew.sh")
+
+	cfg := &core.ConfigFile{
+		Brews: core.ConfigMap{
+			"apps": &core.Brews{
+				Brews: []core.BrewPackage{{Name: "git"}, {Name: "neovim", Args: []string{"--HEAD"}}},
+				Casks: []core.BrewPackage{
+					{Name: "firefox"},
+					{Name: "docker", Args: []string{"--no-quarantine", "--appdir=~/Applications"}, Greedy: true},
+					{Name: "slack", Greedy: true},
+				},
+			},
+		},
+		Variables: core.Variables{},
+	}
+
+	tmpl := core.Template{
+		Name:     "test-options",
+		Output:   outfile,
+		Template: `{{template "brewfile" "apps"}}`,
+	}
+
+	if err := NewEngine(cfg).RenderTemplate(context.Background(), tmpl); err != nil {
+		t.Fatalf("RenderTemplate failed: %v", err)
+	}
+
+	got, err := os.ReadFile(outfile)
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+
+	for _, want := range []string{
+		"brew install \\\n  git\nbrew install --HEAD neovim\n",
+		"brew install --cask \\\n  firefox \\\n  slack\nbrew install --cask --no-quarantine --appdir=~/Applications docker\n",
+		"brew upgrade --cask --greedy \\\n  slack\nbrew upgrade --cask --greedy --no-quarantine --appdir=~/Applications docker",
+	} {
+		if !bytes.Contains(got, []byte(want)) {
+			t.Errorf("output missing %q\n\ngot:\n%s", want, got)
+		}
+	}
+}
+
 func TestBrewfilePartialRemove(t *testing.T) {
 	dir := t.TempDir()
 	outfile := filepath.Join(dir, "brew-remove.sh")
@@ -76,8 +123,8 @@ func TestBrewfilePartialRemove(t *testing.T) {
 			"cleanup": &core.Brews{
 				Remove: true,
 				Taps:   []string{"old/tap"},
-				Brews:  []string{"oldpkg"},
-				Casks:  []string{"oldcask"},
+				Brews:  []core.BrewPackage{{Name: "oldpkg"}},
+				Casks:  []core.BrewPackage{{Name: "oldcask"}},
 				MAS:    []string{"123456"},
 			},
 		},
@@ -116,6 +163,59 @@ func TestBrewfilePartialRemove(t *testing.T) {
 	}
 }
 
+func TestBrewscriptPartial(t *testing.T) {
+	noStrict := false
+	cfg := &core.ConfigFile{
+		Brews: core.ConfigMap{
+			"base": &core.Brews{
+				Brews:     []core.BrewPackage{{Name: "git"}},
+				Preamble:  "ignored: not merged from includes",
+				Postamble: "ignored: not merged from includes",
+			},
+			"work": &core.Brews{
+				Includes:  []string{"base"},
+				Casks:     []core.BrewPackage{{Name: "slack"}},
+				Preamble:  "eval \"$(/opt/homebrew/bin/brew shellenv)\"\nexport HOMEBREW_NO_AUTO_UPDATE=1\n",
+				Postamble: "brew cleanup\n",
+			},
+			"loose": &core.Brews{Brews: []core.BrewPackage{{Name: "jq"}}, Strict: &noStrict},
+		},
+		Variables: core.Variables{},
+	}
+	engine := NewEngine(cfg)
+
+	got, err := engine.Render(context.Background(), core.Template{Name: "work", Template: `{{template "brewscript" "work"}}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `#!/usr/bin/env bash
+set -euo pipefail
+
+eval "$(/opt/homebrew/bin/brew shellenv)"
+export HOMEBREW_NO_AUTO_UPDATE=1
+
+# Installing Homebrew Packages
+brew install \
+  git
+
+# Installing Homebrew Casks
+brew install --cask \
+  slack
+
+brew cleanup`
+	if string(got) != want {
+		t.Errorf("brewscript output:\n%s\n\nwant:\n%s", got, want)
+	}
+
+	got, err = engine.Render(context.Background(), core.Template{Name: "loose", Template: `{{template "brewscript" "loose"}}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(got, []byte("set -euo pipefail")) {
+		t.Errorf("strict mode set with strict: false:\n%s", got)
+	}
+}
+
 func TestBrewfilePartialUnknownConfig(t *testing.T) {
 	dir := t.TempDir()
 	outfile := filepath.Join(dir, "out.sh")
@@ -138,3 +238,134 @@ func TestBrewfilePartialUnknownConfig(t *testing.T) {
 		t.Fatal("expected error for unknown brew config, got nil")
 	}
 }
+
+func TestEngine_PromptAnswers(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	dir := t.TempDir()
+	cfg := &core.ConfigFile{
+		ConfigDir: dir,
+		Variables: core.Variables{
+			Vars: map[string]any{"name": "from vars"},
+			Prompts: []core.Prompt{
+				{Name: "name"},
+				{Name: "email"},
+				{Name: "editor"},
+			},
+		},
+	}
+
+	if err := core.SavePromptAnswers(dir, map[string]string{"email": "me@example.com", "name": "cached"}); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := NewEngine(cfg)
+
+	missing, err := engine.UnansweredPrompts(context.Background())
+	if err != nil {
+		t.Fatalf("UnansweredPrompts() error = %v", err)
+	}
+	if len(missing) != 1 || missing[0].Name != "editor" {
+		t.Errorf("UnansweredPrompts() = %v, want only editor", missing)
+	}
+
+	out, err := engine.Render(context.Background(), core.Template{Name: "t", Template: "{{ .name }} {{ .email }}"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if got, want := string(out), "from vars me@example.com"; got != want {
+		t.Errorf("Render() = %q, want %q (vars win over cached answers)", got, want)
+	}
+}
+
+func TestTemplateBody(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "tmpl.txt")
+	if err := os.WriteFile(file, []byte("from file"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		tmpl    string
+		want    string
+		wantErr bool
+	}{
+		{name: "inline", tmpl: "hello {{ .name }}", want: "hello {{ .name }}"},
+		{name: "relative text", tmpl: "plain text", want: "plain text"},
+		{name: "file", tmpl: file, want: "from file"},
+		{name: "missing file", tmpl: filepath.Join(dir, "missing.txt"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := templateBody(tt.tmpl)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("templateBody() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("templateBody() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngine_Render_MissingTemplateFile(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	missing := filepath.Join(t.TempDir(), "missing.tmpl")
+	engine := NewEngine(&core.ConfigFile{})
+
+	out, err := engine.Render(context.Background(), core.Template{Name: "t", Template: missing})
+	if err == nil {
+		t.Fatalf("Render() = %q, want an error for a missing template file", out)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Render() error = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestEngine_OptionalVarFiles(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	ageCfg, vault := writeVault(t, "token: secret\n")
+	vault.Optional = true
+
+	other, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey := filepath.Join(t.TempDir(), "other.txt")
+	if err := os.WriteFile(otherKey, []byte(other.String()+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := filepath.Join(t.TempDir(), "invalid.yml")
+	if err := os.WriteFile(invalid, []byte("key: [unclosed\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		age     core.Age
+		varFile core.VarFile
+		wantErr bool
+	}{
+		{name: "decrypts", age: ageCfg, varFile: vault},
+		{name: "wrong identity", age: core.Age{IdentityFile: otherKey}, varFile: vault},
+		{name: "no identity", varFile: vault},
+		{name: "invalid yaml", varFile: core.VarFile{Path: invalid, Optional: true}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngine(&core.ConfigFile{
+				Age:       tt.age,
+				Variables: core.Variables{VarFiles: []core.VarFile{tt.varFile}},
+			})
+
+			err := engine.preloadVars(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("preloadVars() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/pkgs/linediff"
+)
+
+// TestResult is the outcome of rendering a template against one fixture.
+type TestResult struct {
+	Template string
+	Test     string
+	Err      error           // Render or golden file read failure
+	Diff     []linediff.Line // Golden file mismatch, nil when matching
+	Missing  []string        // Expected snippets absent from the output
+	Updated  bool            // Golden file was (re)written
+}
+
+func (tr TestResult) Passed() bool {
+	return tr.Err == nil && tr.Diff == nil && len(tr.Missing) == 0
+}
+
+// RunTest renders tmpl with the fixture's variables and checks the output
+// against the golden file and snippets. When update is true the golden file
+// is written with the rendered output instead of compared.
+func (e *Engine) RunTest(ctx context.Context, tmpl core.Template, test core.TemplateTest, update bool) TestResult {
+	result := TestResult{Template: tmpl.Name, Test: test.Name}
+
+	rendered, err := e.RenderIsolated(ctx, tmpl, test.Vars)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	for _, snippet := range test.Contains {
+		if !bytes.Contains(rendered, []byte(snippet)) {
+			result.Missing = append(result.Missing, snippet)
+		}
+	}
+
+	if test.Expected == "" {
+		return result
+	}
+
+	if update {
+		if err := os.MkdirAll(filepath.Dir(test.Expected), 0o755); err != nil {
+			result.Err = fmt.Errorf("failed to create golden file directory: %w", err)
+			return result
+		}
+		if err := os.WriteFile(test.Expected, rendered, 0o644); err != nil {
+			result.Err = fmt.Errorf("failed to write golden file: %w", err)
+			return result
+		}
+		result.Updated = true
+		return result
+	}
+
+	expected, err := os.ReadFile(test.Expected)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			result.Err = fmt.Errorf("golden file %s does not exist (run with --update to create it)", test.Expected)
+		} else {
+			result.Err = fmt.Errorf("failed to read golden file: %w", err)
+		}
+		return result
+	}
+
+	if tmpl.ShouldTrim() {
+		expected = bytes.TrimSpace(expected)
+	}
+
+	if !bytes.Equal(expected, rendered) {
+		result.Diff = linediff.Diff(string(expected), string(rendered))
+	}
+
+	return result
+}
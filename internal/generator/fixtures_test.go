@@ -0,0 +1,72 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hay-kot/mmdot/internal/core"
+)
+
+func TestEngine_RunTest(t *testing.T) {
+	dir := t.TempDir()
+	golden := filepath.Join(dir, "greeting.golden")
+
+	cfg := &core.ConfigFile{
+		Variables: core.Variables{
+			Vars: map[string]any{"greeting": "hello"},
+			// Var files must not be loaded for fixtures
+			VarFiles: []core.VarFile{{Path: filepath.Join(dir, "missing.yml.age"), IsVault: true}},
+		},
+	}
+	engine := NewEngine(cfg)
+
+	tmpl := core.Template{
+		Name:     "greeting",
+		Template: "{{ .greeting }} {{ .name }}",
+	}
+	test := core.TemplateTest{
+		Name:     "world",
+		Vars:     map[string]any{"name": "world"},
+		Expected: golden,
+		Contains: []string{"hello"},
+	}
+
+	// Missing golden file fails
+	if r := engine.RunTest(context.Background(), tmpl, test, false); r.Passed() {
+		t.Fatal("expected failure for missing golden file")
+	}
+
+	// Update writes the golden file
+	r := engine.RunTest(context.Background(), tmpl, test, true)
+	if !r.Passed() || !r.Updated {
+		t.Fatalf("update failed: %+v", r)
+	}
+	got, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("reading golden: %v", err)
+	}
+	if string(got) != "hello world" {
+		t.Errorf("golden = %q, want %q", got, "hello world")
+	}
+
+	// Matching output passes
+	if r := engine.RunTest(context.Background(), tmpl, test, false); !r.Passed() {
+		t.Errorf("expected pass, got %+v", r)
+	}
+
+	// Changed fixture produces a diff and missing snippet
+	test.Vars = map[string]any{"name": "there"}
+	test.Contains = []string{"world"}
+	r = engine.RunTest(context.Background(), tmpl, test, false)
+	if r.Passed() {
+		t.Fatal("expected failure for changed output")
+	}
+	if r.Diff == nil {
+		t.Error("expected diff for changed output")
+	}
+	if len(r.Missing) != 1 || r.Missing[0] != "world" {
+		t.Errorf("Missing = %v, want [world]", r.Missing)
+	}
+}
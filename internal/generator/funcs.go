@@ -0,0 +1,55 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"text/template"
+
+	"github.com/goccy/go-yaml"
+	"github.com/hay-kot/mmdot/pkgs/tomlenc"
+)
+
+// encodingFuncs returns the template functions that convert variables to and
+// from structured formats, so config files can be built from variable maps.
+//
+// Usage: {{toPrettyJson .vscode_settings}}
+//
+//	{{toToml .starship}}
+//	{{$v := fromJson .raw}}{{$v.name}}
+func encodingFuncs() template.FuncMap {
+	return template.FuncMap{
+		"toJson": func(v any) (string, error) {
+			return encodeJSON(v, "")
+		},
+		"toPrettyJson": func(v any) (string, error) {
+			return encodeJSON(v, "  ")
+		},
+		"fromJson": func(s string) (any, error) {
+			var v any
+			err := json.Unmarshal([]byte(s), &v)
+			return v, err
+		},
+		"toYaml": func(v any) (string, error) {
+			data, err := yaml.Marshal(v)
+			return strings.TrimSuffix(string(data), "\n"), err
+		},
+		"toToml": func(v any) (string, error) {
+			data, err := tomlenc.Marshal(v)
+			return strings.TrimSuffix(string(data), "\n"), err
+		},
+	}
+}
+
+// encodeJSON marshals v without escaping <, >, and &, which are common in
+// settings files and would otherwise come out as \u003c.
+func encodeJSON(v any, indent string) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", indent)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
@@ -0,0 +1,77 @@
+package generator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hay-kot/mmdot/internal/core"
+)
+
+func TestEncodingFuncs(t *testing.T) {
+	vars := map[string]any{
+		"settings": map[string]any{
+			"editor.fontSize": 14,
+			"files.exclude":   map[string]any{"**/.git": true},
+			"terminal":        "<zsh>",
+		},
+		"starship": map[string]any{
+			"add_newline": false,
+			"character":   map[string]any{"success_symbol": "[➜](bold green)"},
+		},
+		"raw": `{"name": "mmdot", "tags": ["a", "b"]}`,
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			name:     "toJson",
+			template: `{{ toJson .settings }}`,
+			want:     `{"editor.fontSize":14,"files.exclude":{"**/.git":true},"terminal":"<zsh>"}`,
+		},
+		{
+			name:     "toPrettyJson",
+			template: `{{ toPrettyJson .starship.character }}`,
+			want:     "{\n  \"success_symbol\": \"[➜](bold green)\"\n}",
+		},
+		{
+			name:     "fromJson",
+			template: `{{ $v := fromJson .raw }}{{ $v.name }} {{ index $v.tags 1 }}`,
+			want:     "mmdot b",
+		},
+		{
+			name:     "toYaml",
+			template: `{{ toYaml .starship }}`,
+			want:     "add_newline: false\ncharacter:\n  success_symbol: \"[➜](bold green)\"",
+		},
+		{
+			name:     "toToml",
+			template: `{{ toToml .starship }}`,
+			want:     "add_newline = false\n\n[character]\nsuccess_symbol = \"[➜](bold green)\"",
+		},
+	}
+
+	engine := NewEngine(&core.ConfigFile{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := engine.Render(context.Background(), core.Template{Name: tt.name, Template: tt.template, Vars: vars})
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if got := string(out); got != tt.want {
+				t.Errorf("Render() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodingFuncs_Errors(t *testing.T) {
+	engine := NewEngine(&core.ConfigFile{})
+	for _, tmpl := range []string{`{{ fromJson "{" }}`, `{{ toToml "not a map" }}`} {
+		if _, err := engine.Render(context.Background(), core.Template{Name: "t", Template: tmpl}); err == nil {
+			t.Errorf("Render(%s) error = nil, want an error", tmpl)
+		}
+	}
+}
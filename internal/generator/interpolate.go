@@ -0,0 +1,247 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// MaxInterpolationDepth limits how many variables a chain of references
+// between variables may pass through.
+const MaxInterpolationDepth = 10
+
+// interpolated is a string variable, at path, holding template actions.
+type interpolated struct {
+	path  []string
+	tmpl  *template.Template
+	refs  [][]string // variable paths the template reads
+	state int        // 0 unresolved, 1 resolving, 2 resolved
+}
+
+// interpolateVars renders variable values that reference other variables,
+// e.g. email: "{{ .user }}@example.com", and returns the resolved variables.
+// Values are resolved after the ones they reference, so references may
+// chain up to [MaxInterpolationDepth] deep; cycles are an error. vars is not
+// modified. A literal "{{" is written {{ "{{" }}. Variables for which literal
+// reports true, such as secrets, are used as they are; literal may be nil.
+func (e *Engine) interpolateVars(vars map[string]any, literal func(path []string) bool) (map[string]any, error) {
+	values, err := e.collectInterpolated(vars, literal)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return vars, nil
+	}
+
+	// sorted so errors are reported the same way on every run
+	slices.SortFunc(values, func(a, b *interpolated) int {
+		return strings.Compare(strings.Join(a.path, "."), strings.Join(b.path, "."))
+	})
+
+	resolved, _ := deepCopy(vars).(map[string]any)
+
+	var stack []string
+	var resolve func(v *interpolated) error
+	resolve = func(v *interpolated) error {
+		name := strings.Join(v.path, ".")
+		switch v.state {
+		case 2:
+			return nil
+		case 1:
+			i := slices.Index(stack, name)
+			return fmt.Errorf("variables reference each other in a cycle: %s", strings.Join(append(stack[i:], name), " -> "))
+		}
+		if len(stack) >= MaxInterpolationDepth {
+			return fmt.Errorf("variable %s: references are nested more than %d deep: %s", name, MaxInterpolationDepth, strings.Join(stack, " -> "))
+		}
+
+		v.state = 1
+		stack = append(stack, name)
+		for _, ref := range v.refs {
+			if len(ref) == 0 {
+				continue // reads dot itself; nothing to order against
+			}
+			for _, dep := range values {
+				if hasPrefix(dep.path, ref) || hasPrefix(ref, dep.path) {
+					if err := resolve(dep); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+
+		var buf bytes.Buffer
+		if err := v.tmpl.Execute(&buf, resolved); err != nil {
+			return fmt.Errorf("variable %s: %w", name, err)
+		}
+		setPath(resolved, v.path, buf.String())
+		v.state = 2
+		return nil
+	}
+
+	for _, v := range values {
+		if err := resolve(v); err != nil {
+			return nil, err
+		}
+	}
+	return resolved, nil
+}
+
+// collectInterpolated returns the string variables in vars that hold
+// template actions, parsed, skipping those for which literal reports true.
+func (e *Engine) collectInterpolated(vars map[string]any, literal func(path []string) bool) ([]*interpolated, error) {
+	var values []*interpolated
+	var collect func(path []string, v any) error
+	collect = func(path []string, v any) error {
+		if literal != nil && literal(path) {
+			return nil
+		}
+		switch v := v.(type) {
+		case string:
+			if !strings.Contains(v, "{{") {
+				return nil
+			}
+			name := strings.Join(path, ".")
+			t, err := template.New(name).Funcs(e.funcMap()).Parse(v)
+			if err != nil {
+				return fmt.Errorf("variable %s: %w", name, err)
+			}
+			values = append(values, &interpolated{path: slices.Clone(path), tmpl: t, refs: templateRefs(t.Tree.Root)})
+		case map[string]any:
+			for k, sub := range v {
+				if err := collect(append(path, k), sub); err != nil {
+					return err
+				}
+			}
+		case []any:
+			for i, sub := range v {
+				if err := collect(append(path, strconv.Itoa(i)), sub); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	for k, v := range vars {
+		if err := collect([]string{k}, v); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// templateRefs returns the field chains read from the template's data, such
+// as [git name] for {{ .git.name }} or {{ $.git.name }}. Fields read inside
+// range and with blocks are included even though dot may differ there, which
+// only makes resolution order stricter. An empty chain means the template
+// passes the data itself somewhere, e.g. {{ toJson . }}, and may read any of
+// it.
+func templateRefs(node parse.Node) [][]string {
+	var refs [][]string
+	var walk func(node parse.Node, inner bool)
+	walk = func(node parse.Node, inner bool) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child, inner)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe, inner)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd, inner)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg, inner)
+			}
+		case *parse.FieldNode:
+			refs = append(refs, n.Ident)
+		case *parse.DotNode:
+			// inside range and with, dot is part of what the block's pipeline read
+			if !inner {
+				refs = append(refs, []string{})
+			}
+		case *parse.VariableNode:
+			if n.Ident[0] == "$" {
+				refs = append(refs, n.Ident[1:])
+			}
+		case *parse.ChainNode:
+			walk(n.Node, inner)
+		case *parse.IfNode:
+			walk(n.Pipe, inner)
+			walk(n.List, inner)
+			walk(n.ElseList, inner)
+		case *parse.RangeNode:
+			walk(n.Pipe, inner)
+			walk(n.List, true)
+			walk(n.ElseList, inner)
+		case *parse.WithNode:
+			walk(n.Pipe, inner)
+			walk(n.List, true)
+			walk(n.ElseList, inner)
+		case *parse.TemplateNode:
+			walk(n.Pipe, inner)
+		}
+	}
+	walk(node, false)
+	return refs
+}
+
+func hasPrefix(path, prefix []string) bool {
+	return len(prefix) <= len(path) && slices.Equal(path[:len(prefix)], prefix)
+}
+
+// setPath replaces the value at path in v, which must exist.
+func setPath(v any, path []string, value any) {
+	last := len(path) - 1
+	for i, key := range path {
+		switch c := v.(type) {
+		case map[string]any:
+			if i == last {
+				c[key] = value
+				return
+			}
+			v = c[key]
+		case []any:
+			idx, _ := strconv.Atoi(key)
+			if i == last {
+				c[idx] = value
+				return
+			}
+			v = c[idx]
+		}
+	}
+}
+
+// deepCopy copies the maps and lists in v so resolved values can be set
+// without modifying the config or other templates' variables.
+func deepCopy(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, sub := range v {
+			out[k] = deepCopy(sub)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, sub := range v {
+			out[i] = deepCopy(sub)
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,173 @@
+package generator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hay-kot/mmdot/internal/core"
+)
+
+func TestEngine_interpolateVars(t *testing.T) {
+	tests := []struct {
+		name    string
+		vars    map[string]any
+		key     string // dotted path of the value to check
+		want    string
+		wantErr string
+	}{
+		{
+			name: "reference",
+			vars: map[string]any{"user": "hay", "email": "{{ .user }}@example.com"},
+			key:  "email",
+			want: "hay@example.com",
+		},
+		{
+			name: "chain",
+			vars: map[string]any{"a": "{{ .b }}!", "b": "{{ .c }}?", "c": "c"},
+			key:  "a",
+			want: "c?!",
+		},
+		{
+			name: "nested sibling",
+			vars: map[string]any{"git": map[string]any{"name": "hay", "email": "{{ .git.name }}@example.com"}},
+			key:  "git.email",
+			want: "hay@example.com",
+		},
+		{
+			name: "list item and funcs",
+			vars: map[string]any{"host": "box", "paths": []any{"/home/{{ .host | printf \"%s-1\" }}"}},
+			key:  "paths.0",
+			want: "/home/box-1",
+		},
+		{
+			name: "escaped braces",
+			vars: map[string]any{"literal": `{{ "{{" }} .name }}`},
+			key:  "literal",
+			want: "{{ .name }}",
+		},
+		{
+			name:    "cycle",
+			vars:    map[string]any{"a": "{{ .b }}", "b": "{{ .a }}"},
+			wantErr: "cycle: a -> b -> a",
+		},
+		{
+			name:    "self reference",
+			vars:    map[string]any{"a": "x{{ .a }}"},
+			wantErr: "cycle: a -> a",
+		},
+		{
+			name:    "parse error",
+			vars:    map[string]any{"a": "{{ .b "},
+			wantErr: "variable a:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEngine(&core.ConfigFile{})
+			got, err := e.interpolateVars(tt.vars, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("interpolateVars() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("interpolateVars() unexpected error = %v", err)
+			}
+
+			var v any = got
+			for key := range strings.SplitSeq(tt.key, ".") {
+				switch c := v.(type) {
+				case map[string]any:
+					v = c[key]
+				case []any:
+					v = c[0]
+				}
+			}
+			if v != tt.want {
+				t.Errorf("interpolateVars()[%s] = %v, want %q", tt.key, v, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngine_interpolateVars_depth(t *testing.T) {
+	// k -> kx -> kxx -> ... each referencing the next
+	keys := []string{}
+	for i := 0; i <= MaxInterpolationDepth+1; i++ {
+		keys = append(keys, "k"+strings.Repeat("x", i))
+	}
+	vars := map[string]any{keys[len(keys)-1]: "end"}
+	for i := 0; i < len(keys)-1; i++ {
+		vars[keys[i]] = "{{ ." + keys[i+1] + " }}"
+	}
+
+	_, err := NewEngine(&core.ConfigFile{}).interpolateVars(vars, nil)
+	if err == nil || !strings.Contains(err.Error(), "nested more than") {
+		t.Errorf("interpolateVars() error = %v, want depth limit", err)
+	}
+}
+
+func TestEngine_Render_interpolatesWithoutModifyingConfig(t *testing.T) {
+	cfg := &core.ConfigFile{
+		Variables: core.Variables{Vars: map[string]any{
+			"user":  "hay",
+			"email": "{{ .user }}@example.com",
+		}},
+	}
+	e := NewEngine(cfg)
+
+	tmpl := core.Template{Name: "t", Template: "{{ .email }}", Vars: map[string]any{"user": "work"}}
+	got, err := e.Render(context.Background(), tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "work@example.com" {
+		t.Errorf("Render() = %q, want template vars to take part in interpolation", got)
+	}
+	if cfg.Variables.Vars["email"] != "{{ .user }}@example.com" {
+		t.Errorf("config vars modified: %v", cfg.Variables.Vars)
+	}
+}
+
+func TestEngine_interpolateVars_secretsUnchanged(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Setenv("MMDOT_GENERATOR_TEST_SECRET", "env {{ .name }}")
+	ageCfg, vault := writeVault(t, "password: \"p{{ .name }}w\"\n")
+
+	cfg := &core.ConfigFile{
+		Age: ageCfg,
+		Variables: core.Variables{
+			VarFiles: []core.VarFile{vault},
+			Vars: map[string]any{
+				"name":  "me",
+				"api":   "secret://env/MMDOT_GENERATOR_TEST_SECRET",
+				"login": "{{ .name }}:{{ .password }}",
+			},
+		},
+	}
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{template: "{{ .password }}", want: "p{{ .name }}w"},
+		{template: "{{ .api }}", want: "env {{ .name }}"},
+		{template: "{{ .login }}", want: "me:p{{ .name }}w"},
+	}
+
+	engine := NewEngine(cfg)
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			out, err := engine.Render(context.Background(), core.Template{Name: "t", Template: tt.template})
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if string(out) != tt.want {
+				t.Errorf("Render() = %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/hay-kot/mmdot/internal/core"
+)
+
+// secretVar is a string variable, at path, holding a secret:// reference.
+type secretVar struct {
+	path []string
+	ref  core.SecretRef
+}
+
+// resolveSecrets returns vars with the secret:// references that t may read,
+// directly or through interpolated variables, replaced by their secrets.
+// References the template never reads are left as they are, so their secrets
+// are not fetched. vars is not modified.
+func (e *Engine) resolveSecrets(ctx context.Context, vars map[string]any, t *template.Template, literal func(path []string) bool) (map[string]any, error) {
+	secrets, err := collectSecrets(vars)
+	if err != nil || len(secrets) == 0 {
+		return vars, err
+	}
+
+	readsPath, err := e.readsPaths(vars, t, literal)
+	if err != nil {
+		return nil, err
+	}
+
+	var resolved map[string]any
+	for _, s := range secrets {
+		if !readsPath(s.path) {
+			continue
+		}
+		value, err := s.ref.Resolve(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("variable %s: %w", strings.Join(s.path, "."), err)
+		}
+		if resolved == nil {
+			resolved, _ = deepCopy(vars).(map[string]any)
+		}
+		setPath(resolved, s.path, value)
+	}
+	if resolved == nil {
+		return vars, nil
+	}
+	return resolved, nil
+}
+
+// readsPaths returns a function reporting whether t may read the variable at
+// path, directly or through the interpolated variables it reads.
+func (e *Engine) readsPaths(vars map[string]any, t *template.Template, literal func(path []string) bool) (func(path []string) bool, error) {
+	values, err := e.collectInterpolated(vars, literal)
+	if err != nil {
+		return nil, err
+	}
+
+	reads := templateRefs(t.Tree.Root)
+	readsPath := func(path []string) bool {
+		return slices.ContainsFunc(reads, func(ref []string) bool {
+			return hasPrefix(path, ref) || hasPrefix(ref, path)
+		})
+	}
+
+	// add what the interpolated variables the template reads read in turn
+	for added := true; added; {
+		added = false
+		for i, v := range values {
+			if v != nil && readsPath(v.path) {
+				reads = append(reads, v.refs...)
+				values[i] = nil
+				added = true
+			}
+		}
+	}
+	return readsPath, nil
+}
+
+// ReadsSecrets reports whether rendering tmpl reads a value from a vault or
+// partial var file or a secret:// reference, directly or through variables
+// referencing them. Templates passing the data itself somewhere, e.g.
+// {{ toJson . }}, read every variable.
+func (e *Engine) ReadsSecrets(ctx context.Context, tmpl core.Template) (bool, error) {
+	if !e.varsLoaded {
+		if err := e.preloadVars(ctx); err != nil {
+			return false, fmt.Errorf("failed to preload vars: %w", err)
+		}
+	}
+
+	t, err := e.parse(tmpl)
+	if err != nil {
+		return false, err
+	}
+
+	vars := MergeMaps(e.globalVars, e.fileVars, tmpl.Vars)
+	secrets, err := collectSecrets(vars)
+	if err != nil {
+		return false, fmt.Errorf("template %s: %w", tmpl.Name, err)
+	}
+	readsPath, err := e.readsPaths(vars, t, e.secretPaths(tmpl, secrets))
+	if err != nil {
+		return false, fmt.Errorf("template %s: %w", tmpl.Name, err)
+	}
+
+	for key, vault := range e.vaultKeys {
+		if _, overridden := tmpl.Vars[key]; vault && !overridden && readsPath([]string{key}) {
+			return true, nil
+		}
+	}
+	return slices.ContainsFunc(secrets, func(s secretVar) bool { return readsPath(s.path) }), nil
+}
+
+// secretPaths returns a function reporting whether the variable at path holds
+// a secret: a value from a vault or partial var file that tmpl does not
+// override, or one of secrets. Secrets are never interpolated, so a decrypted
+// value containing "{{" is used as it is.
+func (e *Engine) secretPaths(tmpl core.Template, secrets []secretVar) func(path []string) bool {
+	return func(path []string) bool {
+		if _, overridden := tmpl.Vars[path[0]]; e.vaultKeys[path[0]] && !overridden {
+			return true
+		}
+		return slices.ContainsFunc(secrets, func(s secretVar) bool { return hasPrefix(path, s.path) })
+	}
+}
+
+// literalVars returns the [Engine.secretPaths] of vars for tmpl.
+func (e *Engine) literalVars(tmpl core.Template, vars map[string]any) (func(path []string) bool, error) {
+	secrets, err := collectSecrets(vars)
+	if err != nil {
+		return nil, err
+	}
+	return e.secretPaths(tmpl, secrets), nil
+}
+
+// collectSecrets returns the string variables in vars that are secret://
+// references, sorted by path.
+func collectSecrets(vars map[string]any) ([]secretVar, error) {
+	var secrets []secretVar
+	var collect func(path []string, v any) error
+	collect = func(path []string, v any) error {
+		switch v := v.(type) {
+		case string:
+			if !core.IsSecretRef(v) {
+				return nil
+			}
+			ref, err := core.ParseSecretRef(v)
+			if err != nil {
+				return fmt.Errorf("variable %s: %w", strings.Join(path, "."), err)
+			}
+			secrets = append(secrets, secretVar{path: slices.Clone(path), ref: ref})
+		case map[string]any:
+			for k, sub := range v {
+				if err := collect(append(path, k), sub); err != nil {
+					return err
+				}
+			}
+		case []any:
+			for i, sub := range v {
+				if err := collect(append(path, strconv.Itoa(i)), sub); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	for k, v := range vars {
+		if err := collect([]string{k}, v); err != nil {
+			return nil, err
+		}
+	}
+
+	slices.SortFunc(secrets, func(a, b secretVar) int {
+		return strings.Compare(strings.Join(a.path, "."), strings.Join(b.path, "."))
+	})
+	return secrets, nil
+}
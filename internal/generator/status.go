@@ -0,0 +1,55 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/hay-kot/mmdot/internal/core"
+)
+
+type Status string
+
+const (
+	StatusCurrent Status = "current" // Output on disk matches the rendered template
+	StatusChanged Status = "changed" // Output on disk differs from the rendered template
+	StatusMissing Status = "missing" // Output has not been generated yet
+)
+
+// CheckResult is the outcome of comparing a rendered template with its output file.
+type CheckResult struct {
+	Status   Status
+	Rendered []byte // Freshly rendered template output
+	Existing []byte // Current contents of the output file, nil when missing
+}
+
+// Check renders the template in memory and compares the result against the
+// file at the template's output path without modifying anything on disk.
+func (e *Engine) Check(ctx context.Context, tmpl core.Template) (CheckResult, error) {
+	rendered, err := e.Render(ctx, tmpl)
+	if err != nil {
+		return CheckResult{}, err
+	}
+
+	existing, err := os.ReadFile(tmpl.Output)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return CheckResult{Status: StatusMissing, Rendered: rendered}, nil
+		}
+		return CheckResult{}, fmt.Errorf("failed to read output file: %w", err)
+	}
+
+	status := StatusCurrent
+	if !bytes.Equal(existing, rendered) {
+		status = StatusChanged
+	}
+
+	return CheckResult{
+		Status:   status,
+		Rendered: rendered,
+		Existing: existing,
+	}, nil
+}
@@ -0,0 +1,141 @@
+// Package gpg imports secret keys into the GnuPG keyring and reports which
+// configured keys are present.
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/hay-kot/mmdot/internal/core"
+)
+
+// Client runs gpg. Exec is replaceable in tests; stdin may be nil.
+type Client struct {
+	Exec func(ctx context.Context, stdin []byte, name string, args ...string) ([]byte, error)
+}
+
+// New returns a Client that runs the real gpg.
+func New() *Client {
+	return &Client{Exec: func(ctx context.Context, stdin []byte, name string, args ...string) ([]byte, error) {
+		cmd := exec.CommandContext(ctx, name, args...)
+		if stdin != nil {
+			cmd.Stdin = bytes.NewReader(stdin)
+		}
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil && stderr.Len() > 0 {
+			return out, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		return out, err
+	}}
+}
+
+// KeyStatus reports whether a configured key is in the keyring and has the
+// configured ownertrust.
+type KeyStatus struct {
+	Key      core.GPGKey
+	Imported bool
+	Trusted  bool // true when the key sets no trust
+}
+
+// Ok reports whether the key needs no changes.
+func (s KeyStatus) Ok() bool {
+	return s.Imported && s.Trusted
+}
+
+// Status returns the status of every key, in order.
+func (c *Client) Status(ctx context.Context, keys []core.GPGKey) ([]KeyStatus, error) {
+	secret, err := c.SecretKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var trust map[string]int
+	for _, k := range keys {
+		if k.Trust != "" {
+			if trust, err = c.OwnerTrust(ctx); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	statuses := make([]KeyStatus, 0, len(keys))
+	for _, k := range keys {
+		statuses = append(statuses, KeyStatus{
+			Key:      k,
+			Imported: secret[k.Fingerprint],
+			Trusted:  k.Trust == "" || trust[k.Fingerprint] == core.GPGTrustLevels[k.Trust],
+		})
+	}
+	return statuses, nil
+}
+
+// SecretKeys returns the fingerprints of every secret key and subkey in the
+// keyring.
+func (c *Client) SecretKeys(ctx context.Context) (map[string]bool, error) {
+	out, err := c.Exec(ctx, nil, "gpg", "--batch", "--with-colons", "--list-secret-keys")
+	if err != nil {
+		return nil, fmt.Errorf("gpg --list-secret-keys: %w", err)
+	}
+
+	fprs := map[string]bool{}
+	for line := range strings.SplitSeq(string(out), "\n") {
+		fields := strings.Split(line, ":")
+		if len(fields) > 9 && fields[0] == "fpr" {
+			fprs[fields[9]] = true
+		}
+	}
+	return fprs, nil
+}
+
+// OwnerTrust returns the ownertrust value of every key that has one.
+func (c *Client) OwnerTrust(ctx context.Context) (map[string]int, error) {
+	out, err := c.Exec(ctx, nil, "gpg", "--batch", "--export-ownertrust")
+	if err != nil {
+		return nil, fmt.Errorf("gpg --export-ownertrust: %w", err)
+	}
+
+	trust := map[string]int{}
+	for line := range strings.SplitSeq(string(out), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fpr, level, _ := strings.Cut(strings.TrimSuffix(line, ":"), ":")
+		if n, err := strconv.Atoi(level); err == nil {
+			trust[fpr] = n
+		}
+	}
+	return trust, nil
+}
+
+// Import imports an armored secret key and checks that it provides the
+// key's fingerprint.
+func (c *Client) Import(ctx context.Context, key core.GPGKey, armored []byte) error {
+	if _, err := c.Exec(ctx, armored, "gpg", "--batch", "--import"); err != nil {
+		return fmt.Errorf("gpg --import %s: %w", key.Label(), err)
+	}
+
+	secret, err := c.SecretKeys(ctx)
+	if err != nil {
+		return err
+	}
+	if !secret[key.Fingerprint] {
+		return fmt.Errorf("gpg key %s: %s does not contain fingerprint %s", key.Label(), key.Src, key.Fingerprint)
+	}
+	return nil
+}
+
+// SetTrust sets the key's ownertrust.
+func (c *Client) SetTrust(ctx context.Context, key core.GPGKey) error {
+	line := fmt.Sprintf("%s:%d:\n", key.Fingerprint, core.GPGTrustLevels[key.Trust])
+	if _, err := c.Exec(ctx, []byte(line), "gpg", "--batch", "--import-ownertrust"); err != nil {
+		return fmt.Errorf("gpg --import-ownertrust %s: %w", key.Label(), err)
+	}
+	return nil
+}
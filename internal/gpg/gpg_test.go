@@ -0,0 +1,93 @@
+package gpg
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hay-kot/mmdot/internal/core"
+)
+
+const (
+	fprA = "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"
+	fprB = "BBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBB"
+)
+
+// fakeGPG keeps a keyring in memory. Importing adds the fingerprint given
+// as the armored key.
+type fakeGPG struct {
+	secret []string
+	trust  map[string]string
+	calls  []string
+}
+
+func (f *fakeGPG) exec(_ context.Context, stdin []byte, name string, args ...string) ([]byte, error) {
+	f.calls = append(f.calls, strings.Join(append([]string{name}, args...), " "))
+
+	var out strings.Builder
+	switch args[len(args)-1] {
+	case "--list-secret-keys":
+		for _, fpr := range f.secret {
+			out.WriteString("sec:u:255:22:0000:1700000000:::u:::scESC:::+:::ed25519:::0:\n")
+			out.WriteString("fpr:::::::::" + fpr + ":\n")
+		}
+	case "--export-ownertrust":
+		out.WriteString("# List of assigned trustvalues\n")
+		for fpr, level := range f.trust {
+			out.WriteString(fpr + ":" + level + ":\n")
+		}
+	case "--import":
+		f.secret = append(f.secret, string(stdin))
+	case "--import-ownertrust":
+		fpr, level, _ := strings.Cut(strings.TrimSuffix(strings.TrimSpace(string(stdin)), ":"), ":")
+		f.trust[fpr] = level
+	}
+	return []byte(out.String()), nil
+}
+
+func TestClient_ImportAndTrust(t *testing.T) {
+	fake := &fakeGPG{secret: []string{fprA}, trust: map[string]string{fprA: "6"}}
+	client := &Client{Exec: fake.exec}
+	ctx := context.Background()
+
+	keys := []core.GPGKey{
+		{Name: "personal", Fingerprint: fprA, Trust: "ultimate"},
+		{Name: "work", Fingerprint: fprB, Trust: "full"},
+	}
+
+	statuses, err := client.Status(ctx, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !statuses[0].Ok() {
+		t.Errorf("Status(personal) = %+v, want ok", statuses[0])
+	}
+	if statuses[1].Imported || statuses[1].Trusted {
+		t.Errorf("Status(work) = %+v, want not imported or trusted", statuses[1])
+	}
+
+	if err := client.Import(ctx, keys[1], []byte(fprB)); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.SetTrust(ctx, keys[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	statuses, err = client.Status(ctx, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !statuses[1].Ok() {
+		t.Errorf("Status(work) after import = %+v, want ok", statuses[1])
+	}
+
+	if err := client.Import(ctx, core.GPGKey{Name: "wrong", Fingerprint: "DDDD"}, []byte("CCCC")); err == nil {
+		t.Error("Import() with a mismatched fingerprint succeeded")
+	}
+
+	imports := slices.DeleteFunc(slices.Clone(fake.calls), func(c string) bool { return !strings.HasSuffix(c, "--import") })
+	if len(imports) != 2 {
+		t.Errorf("ran %d imports, want 2", len(imports))
+	}
+}
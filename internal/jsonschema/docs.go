@@ -0,0 +1,74 @@
+package jsonschema
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Docs maps type names ("Template") and field names ("Template.Output") to
+// their doc comments.
+type Docs map[string]string
+
+// ParseDocs reads the doc comments of the struct types in the Go package in
+// dir. A field's doc comment is preferred over its trailing line comment.
+func ParseDocs(dir string) (Docs, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	docs := Docs{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				st, ok := ts.Type.(*ast.StructType)
+				if !ok {
+					continue
+				}
+
+				doc := ts.Doc
+				if doc == nil && len(gen.Specs) == 1 {
+					doc = gen.Doc
+				}
+				docs.add(ts.Name.Name, doc)
+
+				for _, field := range st.Fields.List {
+					comment := field.Doc
+					if comment == nil {
+						comment = field.Comment
+					}
+					for _, ident := range field.Names {
+						docs.add(ts.Name.Name+"."+ident.Name, comment)
+					}
+				}
+			}
+		}
+	}
+	return docs, nil
+}
+
+func (d Docs) add(key string, comment *ast.CommentGroup) {
+	text := strings.Join(strings.Fields(comment.Text()), " ")
+	if text != "" {
+		d[key] = text
+	}
+}
@@ -0,0 +1,156 @@
+// Package jsonschema builds a JSON Schema from Go structs decoded from YAML,
+// so editors can validate and complete config files through the YAML
+// language server.
+package jsonschema
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Draft is the JSON Schema dialect the generated schemas declare.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema document or subschema. Only the keywords the
+// generator emits are supported.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Properties           *Properties        `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+
+	// False marks the schema that matches nothing, used to disallow keys
+	// that are not listed in Properties.
+	False bool `json:"-"`
+}
+
+// Reflector generates schemas from Go types.
+type Reflector struct {
+	// Docs are the descriptions of types and fields, see [ParseDocs].
+	Docs Docs
+
+	// Types replaces the generated schema of a type, for types with a custom
+	// UnmarshalYAML or a fixed set of values.
+	Types map[reflect.Type]*Schema
+
+	defs map[string]*Schema
+}
+
+// Reflect returns the schema of v's type, a struct, with every named struct
+// it refers to in $defs.
+func (r *Reflector) Reflect(v any) *Schema {
+	r.defs = map[string]*Schema{}
+
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	root := r.structSchema(t)
+	root.Schema = Draft
+	if len(r.defs) > 0 {
+		root.Defs = r.defs
+	}
+	return root
+}
+
+func (r *Reflector) typeSchema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if s, ok := r.Types[t]; ok {
+		return s
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: r.typeSchema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.typeSchema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.structSchema(t)
+		}
+		if _, ok := r.defs[t.Name()]; !ok {
+			r.defs[t.Name()] = nil // reserve the name for recursive types
+			r.defs[t.Name()] = r.structSchema(t)
+		}
+		return &Schema{Ref: "#/$defs/" + t.Name()}
+	}
+
+	// interfaces accept any value
+	return &Schema{}
+}
+
+func (r *Reflector) structSchema(t reflect.Type) *Schema {
+	s := &Schema{
+		Type:                 "object",
+		Description:          r.Docs[t.Name()],
+		Properties:           &Properties{},
+		AdditionalProperties: &Schema{False: true},
+	}
+
+	for field := range t.Fields() {
+		if !field.IsExported() {
+			continue
+		}
+
+		name, inline := yamlName(field)
+		if name == "-" {
+			continue
+		}
+		if inline {
+			embedded := r.structSchema(field.Type)
+			for _, key := range embedded.Properties.keys {
+				s.Properties.Set(key, embedded.Properties.values[key])
+			}
+			continue
+		}
+
+		prop := r.typeSchema(field.Type)
+		if doc := r.Docs[t.Name()+"."+field.Name]; doc != "" && t.Name() != "" {
+			// copy, the schema may be shared through Types
+			described := *prop
+			described.Description = doc
+			prop = &described
+		}
+		s.Properties.Set(name, prop)
+	}
+
+	return s
+}
+
+// yamlName returns the key of a field the way goccy/go-yaml decodes it: the
+// yaml tag name, or the lowercased field name without one.
+func yamlName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("yaml")
+	name, opts, _ := strings.Cut(tag, ",")
+	inline := false
+	for opt := range strings.SplitSeq(opts, ",") {
+		if opt == "inline" {
+			inline = true
+		}
+	}
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	return name, inline
+}
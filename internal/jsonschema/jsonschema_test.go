@@ -0,0 +1,87 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type testInner struct {
+	Value string `yaml:"value"`
+}
+
+type testConfig struct {
+	Name     string               `yaml:"name"`
+	Enabled  *bool                `yaml:"enabled"`
+	Count    int                  `yaml:"count"`
+	Tags     []string             `yaml:"tags"`
+	Inner    testInner            `yaml:"inner"`
+	Named    map[string]testInner `yaml:"named"`
+	Vars     map[string]any       `yaml:"vars"`
+	Kind     string               `yaml:"kind"`
+	Untagged string
+	Ignored  string `yaml:"-"`
+	hidden   string
+}
+
+func TestReflect(t *testing.T) {
+	r := Reflector{
+		Docs: Docs{"testConfig.Name": "The name"},
+	}
+	s := r.Reflect(testConfig{})
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	props := got["properties"].(map[string]any)
+	want := map[string]any{
+		"name":     map[string]any{"type": "string", "description": "The name"},
+		"enabled":  map[string]any{"type": "boolean"},
+		"count":    map[string]any{"type": "integer"},
+		"tags":     map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		"inner":    map[string]any{"$ref": "#/$defs/testInner"},
+		"named":    map[string]any{"type": "object", "additionalProperties": map[string]any{"$ref": "#/$defs/testInner"}},
+		"vars":     map[string]any{"type": "object", "additionalProperties": map[string]any{}},
+		"kind":     map[string]any{"type": "string"},
+		"untagged": map[string]any{"type": "string"},
+	}
+	if !reflect.DeepEqual(props, want) {
+		t.Errorf("properties =\n%v\nwant\n%v", props, want)
+	}
+	if got["additionalProperties"] != false {
+		t.Errorf("additionalProperties = %v, want false", got["additionalProperties"])
+	}
+	if _, ok := got["$defs"].(map[string]any)["testInner"]; !ok {
+		t.Errorf("$defs missing testInner: %v", got["$defs"])
+	}
+}
+
+func TestReflect_Types(t *testing.T) {
+	r := Reflector{
+		Types: map[reflect.Type]*Schema{
+			reflect.TypeFor[testInner](): {Type: "string", Enum: []string{"a", "b"}},
+		},
+	}
+	s := r.Reflect(testConfig{})
+
+	if got := s.Properties.Get("inner"); got.Type != "string" || len(got.Enum) != 2 {
+		t.Errorf("inner = %+v, want the override", got)
+	}
+	if _, ok := s.Defs["testInner"]; ok {
+		t.Error("overridden type should not be added to $defs")
+	}
+}
+
+func TestProperties_Order(t *testing.T) {
+	s := (&Reflector{}).Reflect(testConfig{})
+	want := []string{"name", "enabled", "count", "tags", "inner", "named", "vars", "kind", "untagged"}
+	if got := s.Properties.Keys(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Keys() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,74 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Properties are the properties of an object schema, kept in the order of
+// the struct fields so the schema reads like the Go source.
+type Properties struct {
+	keys   []string
+	values map[string]*Schema
+}
+
+// Set adds or replaces the schema of a property.
+func (p *Properties) Set(key string, s *Schema) {
+	if p.values == nil {
+		p.values = map[string]*Schema{}
+	}
+	if _, ok := p.values[key]; !ok {
+		p.keys = append(p.keys, key)
+	}
+	p.values[key] = s
+}
+
+// Get returns the schema of a property, or nil.
+func (p *Properties) Get(key string) *Schema {
+	return p.values[key]
+}
+
+// Keys returns the property names in order.
+func (p *Properties) Keys() []string {
+	return p.keys
+}
+
+func (p *Properties) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range p.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(p.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	if s.False {
+		return []byte("false"), nil
+	}
+	type plain Schema
+	return json.Marshal((*plain)(s))
+}
+
+// JSON returns the schema as indented JSON with a trailing newline.
+func (s *Schema) JSON() ([]byte, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
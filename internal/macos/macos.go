@@ -0,0 +1,251 @@
+// Package macos diffs and applies macOS preferences with the defaults command
+// and manages Dock items with dockutil.
+package macos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hay-kot/mmdot/internal/core"
+)
+
+// Setting is a preference value normalized to how `defaults read` prints it.
+type Setting struct {
+	Domain string
+	Key    string
+	Type   string // bool, int, float, or string
+	Value  string
+}
+
+func (s Setting) String() string {
+	return s.Domain + " " + s.Key
+}
+
+// restarts maps preference domains to the process that must restart to pick
+// up changes.
+var restarts = map[string]string{
+	"com.apple.dock":   "Dock",
+	"com.apple.finder": "Finder",
+}
+
+// Settings flattens the configured preferences.
+func Settings(m core.MacOS) ([]Setting, error) {
+	var out []Setting
+	addBool := func(domain, key string, v *bool) {
+		if v != nil {
+			out = append(out, Setting{Domain: domain, Key: key, Type: "bool", Value: boolValue(*v)})
+		}
+	}
+
+	const dock, finder, global = "com.apple.dock", "com.apple.finder", "NSGlobalDomain"
+
+	addBool(dock, "autohide", m.Dock.Autohide)
+	if m.Dock.TileSize != nil {
+		out = append(out, Setting{Domain: dock, Key: "tilesize", Type: "int", Value: strconv.Itoa(*m.Dock.TileSize)})
+	}
+	addBool(dock, "magnification", m.Dock.Magnification)
+	addBool(dock, "show-recents", m.Dock.ShowRecents)
+	if m.Dock.Orientation != "" {
+		out = append(out, Setting{Domain: dock, Key: "orientation", Type: "string", Value: m.Dock.Orientation})
+	}
+
+	addBool(finder, "AppleShowAllFiles", m.Finder.ShowHidden)
+	addBool(global, "AppleShowAllExtensions", m.Finder.ShowExtensions)
+	addBool(finder, "ShowPathbar", m.Finder.PathBar)
+	addBool(finder, "ShowStatusBar", m.Finder.StatusBar)
+	if m.Finder.DefaultView != "" {
+		out = append(out, Setting{Domain: finder, Key: "FXPreferredViewStyle", Type: "string", Value: core.FinderViews[m.Finder.DefaultView]})
+	}
+
+	corners := []struct{ id, action string }{
+		{"tl", m.HotCorners.TopLeft},
+		{"tr", m.HotCorners.TopRight},
+		{"bl", m.HotCorners.BottomLeft},
+		{"br", m.HotCorners.BottomRight},
+	}
+	for _, c := range corners {
+		if c.action == "" {
+			continue
+		}
+		out = append(out,
+			Setting{Domain: dock, Key: "wvous-" + c.id + "-corner", Type: "int", Value: strconv.Itoa(core.HotCornerActions[c.action])},
+			Setting{Domain: dock, Key: "wvous-" + c.id + "-modifier", Type: "int", Value: "0"},
+		)
+	}
+
+	for _, d := range m.Defaults {
+		value, err := normalize(d.Type, d.Value)
+		if err != nil {
+			return nil, fmt.Errorf("macos.defaults %s %s: %w", d.Domain, d.Key, err)
+		}
+		out = append(out, Setting{Domain: d.Domain, Key: d.Key, Type: d.Type, Value: value})
+	}
+
+	return out, nil
+}
+
+func boolValue(v bool) string {
+	if v {
+		return "1"
+	}
+	return "0"
+}
+
+// normalize formats a config value the way `defaults read` prints it.
+func normalize(typ string, v any) (string, error) {
+	switch typ {
+	case "bool":
+		b, ok := v.(bool)
+		if !ok {
+			return "", fmt.Errorf("value %v is not a bool", v)
+		}
+		return boolValue(b), nil
+	case "int":
+		switch n := v.(type) {
+		case int:
+			return strconv.Itoa(n), nil
+		case int64:
+			return strconv.FormatInt(n, 10), nil
+		case uint64:
+			return strconv.FormatUint(n, 10), nil
+		}
+		return "", fmt.Errorf("value %v is not an int", v)
+	case "float":
+		switch n := v.(type) {
+		case float64:
+			return strconv.FormatFloat(n, 'f', -1, 64), nil
+		case int, int64, uint64:
+			return fmt.Sprint(n), nil
+		}
+		return "", fmt.Errorf("value %v is not a number", v)
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// Change is a setting whose current value differs from the configured one.
+type Change struct {
+	Setting
+	Current string // empty when unset
+	Unset   bool
+}
+
+// Client runs defaults, dockutil, and killall. Exec is replaceable in tests.
+type Client struct {
+	Exec func(ctx context.Context, name string, args ...string) ([]byte, error)
+}
+
+// New returns a Client that runs the real commands.
+func New() *Client {
+	return &Client{Exec: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return exec.CommandContext(ctx, name, args...).Output()
+	}}
+}
+
+// Diff returns the settings whose current value differs.
+func (c *Client) Diff(ctx context.Context, settings []Setting) ([]Change, error) {
+	var changes []Change
+	for _, s := range settings {
+		out, err := c.Exec(ctx, "defaults", "read", s.Domain, s.Key)
+		if err != nil {
+			if errors.Is(err, exec.ErrNotFound) {
+				return nil, fmt.Errorf("defaults read %s: %w", s, err)
+			}
+			// defaults exits non-zero when the key does not exist
+			changes = append(changes, Change{Setting: s, Unset: true})
+			continue
+		}
+
+		if current := strings.TrimSpace(string(out)); current != s.Value {
+			changes = append(changes, Change{Setting: s, Current: current})
+		}
+	}
+	return changes, nil
+}
+
+// Apply writes the changed settings and restarts Dock and Finder when their
+// preferences changed. restartDock forces a Dock restart, e.g. after
+// changing its items.
+func (c *Client) Apply(ctx context.Context, changes []Change, restartDock bool) error {
+	restart := map[string]bool{}
+	if restartDock {
+		restart["Dock"] = true
+	}
+
+	for _, ch := range changes {
+		if _, err := c.Exec(ctx, "defaults", "write", ch.Domain, ch.Key, "-"+ch.Type, ch.Value); err != nil {
+			return fmt.Errorf("defaults write %s: %w", ch.Setting, err)
+		}
+		if proc, ok := restarts[ch.Domain]; ok {
+			restart[proc] = true
+		}
+	}
+
+	for _, proc := range []string{"Dock", "Finder"} {
+		if restart[proc] {
+			// killall fails when the process is not running, which is fine
+			_, _ = c.Exec(ctx, "killall", proc)
+		}
+	}
+	return nil
+}
+
+// DockApps returns the application paths in the Dock, in order.
+func (c *Client) DockApps(ctx context.Context) ([]string, error) {
+	out, err := c.Exec(ctx, "dockutil", "--list")
+	if err != nil {
+		return nil, dockutilError(err)
+	}
+
+	var apps []string
+	for line := range strings.SplitSeq(string(out), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 3 || fields[2] != "persistentApps" {
+			continue
+		}
+
+		u, err := url.Parse(fields[1])
+		if err != nil || u.Scheme != "file" {
+			continue
+		}
+		apps = append(apps, strings.TrimSuffix(u.Path, "/"))
+	}
+	return apps, nil
+}
+
+// SetDockApps replaces the applications in the Dock. The Dock is restarted
+// by [Client.Apply].
+func (c *Client) SetDockApps(ctx context.Context, apps []string) error {
+	current, err := c.DockApps(ctx)
+	if err != nil {
+		return err
+	}
+	if slices.Equal(current, apps) {
+		return nil
+	}
+
+	for _, app := range current {
+		if _, err := c.Exec(ctx, "dockutil", "--remove", app, "--no-restart"); err != nil {
+			return fmt.Errorf("dockutil --remove %s: %w", app, err)
+		}
+	}
+	for _, app := range apps {
+		if _, err := c.Exec(ctx, "dockutil", "--add", app, "--no-restart"); err != nil {
+			return fmt.Errorf("dockutil --add %s: %w", app, err)
+		}
+	}
+	return nil
+}
+
+func dockutilError(err error) error {
+	if errors.Is(err, exec.ErrNotFound) {
+		return fmt.Errorf("macos.dock.apps requires dockutil (brew install dockutil)")
+	}
+	return fmt.Errorf("dockutil --list: %w", err)
+}
@@ -0,0 +1,122 @@
+package macos
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hay-kot/mmdot/internal/core"
+)
+
+// fakeExec serves `defaults read` from values and records every other
+// command.
+type fakeExec struct {
+	values map[string]string // "domain key" -> value
+	dock   string
+	calls  []string
+}
+
+func (f *fakeExec) exec(_ context.Context, name string, args ...string) ([]byte, error) {
+	call := strings.Join(append([]string{name}, args...), " ")
+	switch {
+	case name == "defaults" && args[0] == "read":
+		v, ok := f.values[args[1]+" "+args[2]]
+		if !ok {
+			return nil, errors.New("exit status 1")
+		}
+		return []byte(v + "\n"), nil
+	case name == "dockutil" && args[0] == "--list":
+		return []byte(f.dock), nil
+	}
+	f.calls = append(f.calls, call)
+	return nil, nil
+}
+
+func TestClient_DiffAndApply(t *testing.T) {
+	yes, size := true, 48
+	cfg := core.MacOS{
+		Dock:       core.MacDock{Autohide: &yes, TileSize: &size},
+		Finder:     core.MacFinder{ShowExtensions: &yes, DefaultView: "list"},
+		HotCorners: core.MacHotCorners{BottomRight: "lock-screen"},
+		Defaults:   []core.MacDefault{{Domain: "com.apple.screencapture", Key: "type", Type: "string", Value: "png"}},
+	}
+
+	settings, err := Settings(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &fakeExec{values: map[string]string{
+		"com.apple.dock autohide":               "1",
+		"com.apple.dock tilesize":               "36",
+		"NSGlobalDomain AppleShowAllExtensions": "1",
+		"com.apple.finder FXPreferredViewStyle": "icnv",
+		"com.apple.dock wvous-br-corner":        "13",
+		"com.apple.dock wvous-br-modifier":      "0",
+		"com.apple.screencapture type":          "png",
+	}}
+	client := &Client{Exec: fake.exec}
+
+	changes, err := client.Diff(context.Background(), settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, ch := range changes {
+		got = append(got, ch.String()+"="+ch.Value)
+	}
+	want := []string{"com.apple.dock tilesize=48", "com.apple.finder FXPreferredViewStyle=Nlsv"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Diff() = %v, want %v", got, want)
+	}
+
+	if err := client.Apply(context.Background(), changes, false); err != nil {
+		t.Fatal(err)
+	}
+	wantCalls := []string{
+		"defaults write com.apple.dock tilesize -int 48",
+		"defaults write com.apple.finder FXPreferredViewStyle -string Nlsv",
+		"killall Dock",
+		"killall Finder",
+	}
+	if !slices.Equal(fake.calls, wantCalls) {
+		t.Errorf("Apply() ran %v, want %v", fake.calls, wantCalls)
+	}
+}
+
+func TestClient_SetDockApps(t *testing.T) {
+	fake := &fakeExec{dock: "Safari\tfile:///Applications/Safari.app/\tpersistentApps\t/Users/me/Library/Preferences/com.apple.dock.plist\n" +
+		"Visual Studio Code\tfile:///Applications/Visual%20Studio%20Code.app/\tpersistentApps\t/Users/me/Library/Preferences/com.apple.dock.plist\n" +
+		"Downloads\tfile:///Users/me/Downloads/\tpersistentOthers\t/Users/me/Library/Preferences/com.apple.dock.plist\n"}
+	client := &Client{Exec: fake.exec}
+
+	apps, err := client.DockApps(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"/Applications/Safari.app", "/Applications/Visual Studio Code.app"}; !slices.Equal(apps, want) {
+		t.Fatalf("DockApps() = %v, want %v", apps, want)
+	}
+
+	if err := client.SetDockApps(context.Background(), apps); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.calls) != 0 {
+		t.Errorf("SetDockApps() with unchanged apps ran %v", fake.calls)
+	}
+
+	if err := client.SetDockApps(context.Background(), []string{"/Applications/Safari.app"}); err != nil {
+		t.Fatal(err)
+	}
+	wantCalls := []string{
+		"dockutil --remove /Applications/Safari.app --no-restart",
+		"dockutil --remove /Applications/Visual Studio Code.app --no-restart",
+		"dockutil --add /Applications/Safari.app --no-restart",
+	}
+	if !slices.Equal(fake.calls, wantCalls) {
+		t.Errorf("SetDockApps() ran %v, want %v", fake.calls, wantCalls)
+	}
+}
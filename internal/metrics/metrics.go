@@ -0,0 +1,193 @@
+// Package metrics writes run and drift metrics to a node-exporter textfile
+// collector directory or a statsd server configured in mmdot.yml, so a fleet
+// of machines can be monitored from one dashboard.
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/notify"
+	"github.com/hay-kot/mmdot/pkgs/atomicwrite"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// RunFile is written to the textfile directory after every run.
+	RunFile = "mmdot.prom"
+	// DriftFile is written to the textfile directory by `mmdot status`.
+	DriftFile = "mmdot_drift.prom"
+
+	lastSuccessMetric = "mmdot_last_success_timestamp_seconds"
+)
+
+// metric is a single gauge, written to Prometheus as name{label} and to
+// statsd as <prefix>.<statsd>.
+type metric struct {
+	name   string
+	label  string // e.g. type="template", empty for none
+	help   string
+	value  float64
+	statsd string // empty to leave the metric out of statsd
+	timing bool   // sent to statsd as a timing in milliseconds
+}
+
+// WriteRun records the outcome of a run. The last success timestamp is kept
+// from the previous textfile when the run failed. Failures are logged and do
+// not affect the run.
+func WriteRun(ctx context.Context, cfg core.Metrics, event notify.Event, now time.Time) {
+	if !cfg.Enabled() {
+		return
+	}
+
+	lastSuccess := 0.0
+	if event.Success {
+		lastSuccess = float64(now.Unix())
+	} else if cfg.TextfileDir != "" {
+		lastSuccess = readGauge(filepath.Join(cfg.TextfileDir, RunFile), lastSuccessMetric)
+	}
+
+	success := 0.0
+	if event.Success {
+		success = 1
+	}
+
+	metrics := []metric{
+		{name: "mmdot_run_success", help: "Whether the last run succeeded.", value: success, statsd: "run.success"},
+		{name: "mmdot_run_duration_seconds", help: "Duration of the last run.", value: event.Duration.Seconds(), statsd: "run.duration", timing: true},
+		{name: "mmdot_run_items", label: `type="template"`, help: "Items the last run changed, by type.", value: float64(event.Templates), statsd: "run.templates"},
+		{name: "mmdot_run_items", label: `type="script"`, value: float64(event.Scripts), statsd: "run.scripts"},
+		{name: "mmdot_run_items", label: `type="service"`, value: float64(event.Services), statsd: "run.services"},
+		{name: "mmdot_run_skipped_items", help: "Items the last run skipped.", value: float64(event.Skipped), statsd: "run.skipped"},
+		{name: "mmdot_run_failed_items", help: "Items that failed in the last run.", value: float64(len(event.Failed)), statsd: "run.failed"},
+		{name: "mmdot_last_run_timestamp_seconds", help: "Unix time the last run finished.", value: float64(now.Unix())},
+		{name: lastSuccessMetric, help: "Unix time of the last successful run.", value: lastSuccess},
+	}
+	if event.Success {
+		metrics[len(metrics)-1].statsd = "last_success"
+	}
+
+	write(ctx, cfg, RunFile, metrics)
+}
+
+// WriteDrift records the number of drifted items of each check reported by
+// `mmdot status`, keyed by check name.
+func WriteDrift(ctx context.Context, cfg core.Metrics, drift map[string]int, now time.Time) {
+	if !cfg.Enabled() {
+		return
+	}
+
+	var metrics []metric
+	for i, check := range slices.Sorted(maps.Keys(drift)) {
+		m := metric{
+			name:   "mmdot_drift_items",
+			label:  fmt.Sprintf("check=%q", check),
+			value:  float64(drift[check]),
+			statsd: "drift." + check,
+		}
+		if i == 0 {
+			m.help = "Items that differ from the config, by check."
+		}
+		metrics = append(metrics, m)
+	}
+	metrics = append(metrics, metric{
+		name:  "mmdot_drift_last_check_timestamp_seconds",
+		help:  "Unix time drift was last checked.",
+		value: float64(now.Unix()),
+	})
+
+	write(ctx, cfg, DriftFile, metrics)
+}
+
+func write(ctx context.Context, cfg core.Metrics, file string, metrics []metric) {
+	if cfg.TextfileDir != "" {
+		path := filepath.Join(cfg.TextfileDir, file)
+		// atomic so the collector never reads a partial file
+		if err := atomicwrite.WriteFile(path, textfile(metrics), 0o644); err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("failed to write metrics textfile")
+		}
+	}
+
+	if cfg.Statsd != "" {
+		if err := sendStatsd(ctx, cfg.Statsd, statsd(cfg.StatsdPrefix(), metrics)); err != nil {
+			log.Warn().Err(err).Str("address", cfg.Statsd).Msg("failed to send statsd metrics")
+		}
+	}
+}
+
+// textfile renders metrics in the Prometheus text exposition format.
+func textfile(metrics []metric) []byte {
+	var buf bytes.Buffer
+	for _, m := range metrics {
+		if m.help != "" {
+			fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s gauge\n", m.name, m.help, m.name)
+		}
+		name := m.name
+		if m.label != "" {
+			name += "{" + m.label + "}"
+		}
+		fmt.Fprintf(&buf, "%s %s\n", name, strconv.FormatFloat(m.value, 'f', -1, 64))
+	}
+	return buf.Bytes()
+}
+
+// statsd renders metrics as newline separated statsd gauges and timings.
+func statsd(prefix string, metrics []metric) []byte {
+	var buf bytes.Buffer
+	for _, m := range metrics {
+		if m.statsd == "" {
+			continue
+		}
+		if m.timing {
+			fmt.Fprintf(&buf, "%s.%s:%d|ms\n", prefix, m.statsd, int64(m.value*1000))
+			continue
+		}
+		fmt.Fprintf(&buf, "%s.%s:%s|g\n", prefix, m.statsd, strconv.FormatFloat(m.value, 'f', -1, 64))
+	}
+	return buf.Bytes()
+}
+
+func sendStatsd(ctx context.Context, address string, payload []byte) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", address)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	_, err = conn.Write(payload)
+	return err
+}
+
+// readGauge returns the value of an unlabeled gauge in a textfile, or 0 when
+// the file or metric does not exist.
+func readGauge(path, name string) float64 {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), name+" ")
+		if !ok {
+			continue
+		}
+		if v, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+			return v
+		}
+	}
+	return 0
+}
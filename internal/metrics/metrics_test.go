@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/notify"
+)
+
+func TestWriteRun_Textfile(t *testing.T) {
+	dir := t.TempDir()
+	cfg := core.Metrics{TextfileDir: dir}
+	path := filepath.Join(dir, RunFile)
+
+	first := time.Unix(1700000000, 0)
+	WriteRun(context.Background(), cfg, notify.Event{Success: true, Templates: 2, Duration: 1500 * time.Millisecond}, first)
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# TYPE mmdot_run_success gauge\nmmdot_run_success 1\n",
+		"mmdot_run_duration_seconds 1.5\n",
+		`mmdot_run_items{type="template"} 2` + "\n",
+		`mmdot_run_items{type="script"} 0` + "\n",
+		"mmdot_last_success_timestamp_seconds 1700000000\n",
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("textfile missing %q:\n%s", want, got)
+		}
+	}
+
+	// a failed run keeps the previous success time
+	WriteRun(context.Background(), cfg, notify.Event{Failed: []string{"a"}}, first.Add(time.Hour))
+
+	got, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"mmdot_run_success 0\n",
+		"mmdot_run_failed_items 1\n",
+		"mmdot_last_run_timestamp_seconds 1700003600\n",
+		"mmdot_last_success_timestamp_seconds 1700000000\n",
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("textfile missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestWriteDrift_Statsd(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	cfg := core.Metrics{Statsd: conn.LocalAddr().String(), Prefix: "box"}
+	WriteDrift(context.Background(), cfg, map[string]int{"templates": 2, "brew": 0}, time.Unix(1700000000, 0))
+
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "box.drift.brew:0|g\nbox.drift.templates:2|g\n"
+	if got := string(buf[:n]); got != want {
+		t.Errorf("statsd payload = %q, want %q", got, want)
+	}
+}
+
+func Test_statsd_Timing(t *testing.T) {
+	got := string(statsd("mmdot", []metric{
+		{statsd: "run.duration", value: 1.25, timing: true},
+		{name: "not_sent", value: 1},
+	}))
+	if want := "mmdot.run.duration:1250|ms\n"; got != want {
+		t.Errorf("statsd() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,173 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// Migrate rewrites the config in data from its version to version to,
+// applying every note in between. Comments are kept where the YAML library
+// preserves them. It returns the rewritten config and the version it was
+// migrated from; data is returned unchanged when it is already current.
+func Migrate(data []byte, to int) ([]byte, int, error) {
+	file, err := parser.ParseBytes(data, parser.ParseComments)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(file.Docs) == 0 || file.Docs[0].Body == nil {
+		return nil, 0, errors.New("config is empty")
+	}
+	root, ok := file.Docs[0].Body.(*ast.MappingNode)
+	if !ok {
+		return nil, 0, errors.New("config is not a mapping")
+	}
+
+	from, err := version(root)
+	if err != nil {
+		return nil, 0, err
+	}
+	if from > to {
+		return nil, from, fmt.Errorf("config version %d is newer than this mmdot supports (%d)", from, to)
+	}
+	if from == to {
+		return data, from, nil
+	}
+
+	for _, note := range Notes {
+		if note.Version <= from || note.Version > to || note.Apply == nil {
+			continue
+		}
+		if err := note.Apply(root); err != nil {
+			return nil, from, fmt.Errorf("migrating to version %d: %w", note.Version, err)
+		}
+	}
+
+	if err := setVersion(root, to); err != nil {
+		return nil, from, err
+	}
+	return []byte(file.String()), from, nil
+}
+
+// version returns the version field of the config, 1 when it is not set.
+func version(root *ast.MappingNode) (int, error) {
+	mv := field(root, "version")
+	if mv == nil {
+		return 1, nil
+	}
+	v, err := strconv.Atoi(mv.Value.String())
+	if err != nil {
+		return 0, fmt.Errorf("invalid version %q", mv.Value.String())
+	}
+	return v, nil
+}
+
+func setVersion(root *ast.MappingNode, v int) error {
+	if mv := field(root, "version"); mv != nil {
+		node, err := yaml.ValueToNode(v)
+		if err != nil {
+			return err
+		}
+		return mv.Replace(node)
+	}
+
+	node, err := valueNode(yaml.MapSlice{{Key: "version", Value: v}})
+	if err != nil {
+		return err
+	}
+	root.Values = slices.Insert(root.Values, 0, node.Values...)
+	return nil
+}
+
+// field returns the entry for key in m, or nil.
+func field(m *ast.MappingNode, key string) *ast.MappingValueNode {
+	for _, mv := range m.Values {
+		if mv.Key.String() == key {
+			return mv
+		}
+	}
+	return nil
+}
+
+// valueNode converts an ordered map to a mapping node, writing multiline
+// strings as literal blocks.
+func valueNode(v yaml.MapSlice) (*ast.MappingNode, error) {
+	node, err := yaml.ValueToNode(v, yaml.UseLiteralStyleIfMultiline(true))
+	if err != nil {
+		return nil, err
+	}
+	m, ok := node.(*ast.MappingNode)
+	if !ok {
+		return nil, fmt.Errorf("expected a mapping, got %s", node.Type())
+	}
+	return m, nil
+}
+
+// brewOutfilesToTemplates removes brews.*.outfile and adds a template
+// rendering the brewfile partial to each removed outfile.
+func brewOutfilesToTemplates(root *ast.MappingNode) error {
+	brews := field(root, "brews")
+	if brews == nil {
+		return nil
+	}
+	configs, ok := brews.Value.(*ast.MappingNode)
+	if !ok {
+		return nil
+	}
+
+	var templates []ast.Node
+	for _, cfg := range configs.Values {
+		m, ok := cfg.Value.(*ast.MappingNode)
+		if !ok {
+			continue
+		}
+		outfile := field(m, "outfile")
+		if outfile == nil {
+			continue
+		}
+		m.Values = slices.DeleteFunc(m.Values, func(mv *ast.MappingValueNode) bool { return mv == outfile })
+
+		name := cfg.Key.String()
+		tmpl, err := valueNode(yaml.MapSlice{
+			{Key: "name", Value: "brew-" + name},
+			{Key: "tags", Value: []string{"brew"}},
+			{Key: "output", Value: outfile.Value.String()},
+			{Key: "perm", Value: "0755"},
+			{Key: "template", Value: "#!/bin/bash\nset -euo pipefail\n{{template \"brewfile\" " + strconv.Quote(name) + "}}\n"},
+		})
+		if err != nil {
+			return err
+		}
+		templates = append(templates, tmpl)
+	}
+
+	if len(templates) == 0 {
+		return nil
+	}
+
+	if mv := field(root, "templates"); mv != nil {
+		seq, ok := mv.Value.(*ast.SequenceNode)
+		if !ok {
+			return errors.New("templates is not a list")
+		}
+		seq.Values = append(seq.Values, templates...)
+		return nil
+	}
+
+	node, err := valueNode(yaml.MapSlice{{Key: "templates", Value: []any{}}})
+	if err != nil {
+		return err
+	}
+	seq, ok := node.Values[0].Value.(*ast.SequenceNode)
+	if !ok {
+		return errors.New("failed to create templates list")
+	}
+	seq.Values = templates
+	root.Values = append(root.Values, node.Values...)
+	return nil
+}
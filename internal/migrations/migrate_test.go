@@ -0,0 +1,91 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+)
+
+func TestMigrate(t *testing.T) {
+	src := `# machines
+brews:
+  # personal laptop
+  personal:
+    outfile: ./generated/brew.sh
+    brews: [git, fd]
+  work:
+    brews: [jq]
+
+templates:
+  - name: zshrc
+    output: ~/.zshrc
+    template: zshrc.tmpl
+`
+
+	out, from, err := Migrate([]byte(src), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from != 1 {
+		t.Errorf("from = %d, want 1", from)
+	}
+
+	got := string(out)
+	for _, want := range []string{"# machines", "# personal laptop", "version: 2"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("migrated config is missing %q:\n%s", want, got)
+		}
+	}
+
+	var cfg struct {
+		Version   int
+		Brews     map[string]map[string]any
+		Templates []struct {
+			Name     string
+			Output   string
+			Perm     string
+			Template string
+		}
+	}
+	if err := yaml.Unmarshal(out, &cfg); err != nil {
+		t.Fatalf("migrated config does not parse: %v\n%s", err, got)
+	}
+	if _, ok := cfg.Brews["personal"]["outfile"]; ok {
+		t.Error("outfile was not removed")
+	}
+	if len(cfg.Templates) != 2 {
+		t.Fatalf("templates = %+v", cfg.Templates)
+	}
+	tmpl := cfg.Templates[1]
+	if tmpl.Name != "brew-personal" || tmpl.Output != "./generated/brew.sh" || tmpl.Perm != "0755" ||
+		!strings.Contains(tmpl.Template, `{{template "brewfile" "personal"}}`) {
+		t.Errorf("brew template = %+v", tmpl)
+	}
+
+	again, from, err := Migrate(out, 2)
+	if err != nil || from != 2 || string(again) != got {
+		t.Errorf("Migrate() of a current config = %v, %d, changed: %t", err, from, string(again) != got)
+	}
+
+	if _, _, err := Migrate([]byte("version: 3\n"), 2); err == nil {
+		t.Error("Migrate() accepted a newer version")
+	}
+}
+
+func TestMigrate_NoTemplates(t *testing.T) {
+	out, _, err := Migrate([]byte("brews:\n  home:\n    outfile: brew.sh\n    brews: [git]\n"), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg struct {
+		Templates []struct{ Name string }
+	}
+	if err := yaml.Unmarshal(out, &cfg); err != nil {
+		t.Fatalf("migrated config does not parse: %v\n%s", err, out)
+	}
+	if len(cfg.Templates) != 1 || cfg.Templates[0].Name != "brew-home" {
+		t.Errorf("templates = %+v\n%s", cfg.Templates, out)
+	}
+}
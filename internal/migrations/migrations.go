@@ -2,6 +2,8 @@
 // Each entry describes what changed from the previous version and how to update.
 package migrations
 
+import "github.com/goccy/go-yaml/ast"
+
 // Note describes a single config version migration.
 type Note struct {
 	// Version is the target version (e.g., 2 means "changes from v1 to v2").
@@ -10,6 +12,9 @@ type Note struct {
 	Summary string
 	// Body is the full migration guide in markdown.
 	Body string
+	// Apply rewrites the root mapping of a config from the previous version.
+	// Setting the version field is handled by [Migrate].
+	Apply func(root *ast.MappingNode) error
 }
 
 // Notes is the ordered list of all config migrations.
@@ -47,6 +52,9 @@ templates:
 
 3. Set ` + "`version: 2`" + ` in your config file
 4. Run ` + "`mmdot generate`" + ` instead of ` + "`mmdot brew compile`" + `
+
+` + "`mmdot config migrate`" + ` performs steps 1-3.
 `,
+		Apply: brewOutfilesToTemplates,
 	},
 }
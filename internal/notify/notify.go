@@ -0,0 +1,190 @@
+// Package notify delivers run completion notifications to desktop, webhook,
+// ntfy, and pushover targets configured in mmdot.yml.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/rs/zerolog/log"
+)
+
+// PushoverURL is the pushover messages endpoint.
+var PushoverURL = "https://api.pushover.net/1/messages.json"
+
+// Event summarizes a completed run.
+type Event struct {
+	Command      string        `json:"command"`
+	Success      bool          `json:"success"`
+	Error        string        `json:"error,omitempty"`
+	Templates    int           `json:"templates"`
+	Scripts      int           `json:"scripts"`
+	Services     int           `json:"services"`
+	Skipped      int           `json:"skipped"`
+	SkippedItems []Skip        `json:"skipped_items,omitempty"`
+	Failed       []string      `json:"failed,omitempty"` // items that failed in a run that kept going
+	Duration     time.Duration `json:"-"`                // sent as a string, see [Event.MarshalJSON]
+}
+
+// MarshalJSON encodes the event with its duration as a string such as
+// "1m2.5s", matching the daemon's run reports.
+func (e Event) MarshalJSON() ([]byte, error) {
+	type event Event
+	return json.Marshal(struct {
+		event
+		Duration string `json:"duration"`
+	}{event(e), e.Duration.Round(time.Millisecond).String()})
+}
+
+// Skip is an item a run did not execute and why.
+type Skip struct {
+	Item   string `json:"item"`
+	Reason string `json:"reason"`
+}
+
+// Title returns a short headline for the event.
+func (e Event) Title() string {
+	if e.Success {
+		return fmt.Sprintf("mmdot %s succeeded", e.Command)
+	}
+	return fmt.Sprintf("mmdot %s failed", e.Command)
+}
+
+// Message returns the summary body for the event.
+func (e Event) Message() string {
+	msg := fmt.Sprintf("%d template(s), %d script(s)", e.Templates, e.Scripts)
+	if e.Services > 0 {
+		msg += fmt.Sprintf(", %d service(s)", e.Services)
+	}
+	if e.Skipped > 0 {
+		msg += fmt.Sprintf(", %d skipped", e.Skipped)
+	}
+	if len(e.Failed) > 0 {
+		msg += fmt.Sprintf(", %d failed", len(e.Failed))
+	}
+	msg += " in " + e.Duration.Round(time.Second).String()
+	if e.Error != "" {
+		msg += "\n" + e.Error
+	}
+	return msg
+}
+
+// Send delivers the event to every target configured to fire for its outcome.
+// Delivery failures are logged and do not stop other targets.
+func Send(ctx context.Context, targets []core.Notification, event Event) {
+	for _, target := range targets {
+		if !target.Triggers(event.Success) {
+			continue
+		}
+
+		if err := send(ctx, target, event); errors.Is(err, core.ErrOffline) {
+			log.Info().Str("type", string(target.Type)).Msg("notification skipped (offline)")
+			continue
+		} else if err != nil {
+			log.Warn().Err(err).Str("type", string(target.Type)).Msg("failed to send notification")
+			continue
+		}
+
+		log.Debug().Str("type", string(target.Type)).Msg("notification sent")
+	}
+}
+
+func send(ctx context.Context, target core.Notification, event Event) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	target, err := target.ResolveSecrets(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch target.Type {
+	case core.NotificationDesktop:
+		return sendDesktop(ctx, event)
+	case core.NotificationWebhook:
+		body, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		return post(ctx, target.URL, "application/json", bytes.NewReader(body), target.Headers)
+	case core.NotificationNtfy:
+		headers := map[string]string{"Title": event.Title()}
+		if !event.Success {
+			headers["Priority"] = "high"
+			headers["Tags"] = "warning"
+		}
+		if target.Token != "" {
+			headers["Authorization"] = "Bearer " + target.Token
+		}
+		maps.Copy(headers, target.Headers)
+		return post(ctx, target.URL, "text/plain", strings.NewReader(event.Message()), headers)
+	case core.NotificationPushover:
+		form := url.Values{
+			"token":   {target.Token},
+			"user":    {target.User},
+			"title":   {event.Title()},
+			"message": {event.Message()},
+		}
+		return post(ctx, PushoverURL, "application/x-www-form-urlencoded", strings.NewReader(form.Encode()), nil)
+	}
+
+	return fmt.Errorf("unsupported notification type %q", target.Type)
+}
+
+func post(ctx context.Context, endpoint, contentType string, body io.Reader, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s responded with status %s", endpoint, resp.Status)
+	}
+
+	return nil
+}
+
+// sendDesktop shows a native notification using osascript on macOS and
+// notify-send elsewhere.
+func sendDesktop(ctx context.Context, event Event) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %q with title %q", event.Message(), event.Title())
+		cmd = exec.CommandContext(ctx, "osascript", "-e", script)
+	default:
+		urgency := "normal"
+		if !event.Success {
+			urgency = "critical"
+		}
+		cmd = exec.CommandContext(ctx, "notify-send", "--urgency="+urgency, event.Title(), event.Message())
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
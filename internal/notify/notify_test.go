@@ -0,0 +1,80 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hay-kot/mmdot/internal/core"
+)
+
+func TestSend_Webhook(t *testing.T) {
+	var got Event
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("X-Token") != "secret" {
+			t.Errorf("missing custom header")
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	targets := []core.Notification{
+		{Type: core.NotificationWebhook, URL: srv.URL, Headers: map[string]string{"X-Token": "secret"}},
+		{Type: core.NotificationWebhook, URL: srv.URL, On: []string{core.NotifyOnSuccess}},
+	}
+
+	Send(context.Background(), targets, Event{Command: "run", Error: "boom", Templates: 2, Scripts: 1})
+
+	if calls != 1 {
+		t.Fatalf("webhook called %d times, want 1 (success-only target should be skipped)", calls)
+	}
+	if got.Templates != 2 || got.Scripts != 1 || got.Error != "boom" {
+		t.Errorf("unexpected payload: %+v", got)
+	}
+}
+
+func TestEvent_MarshalJSON(t *testing.T) {
+	data, err := json.Marshal(Event{Command: "run", Success: true, Duration: 62*time.Second + 512345*time.Microsecond})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if got["duration"] != "1m2.512s" {
+		t.Errorf("duration = %v, want %q", got["duration"], "1m2.512s")
+	}
+	if got["command"] != "run" || got["success"] != true {
+		t.Errorf("unexpected payload: %s", data)
+	}
+}
+
+func TestSend_Ntfy(t *testing.T) {
+	var title, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		title = r.Header.Get("Title")
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer srv.Close()
+
+	event := Event{Command: "run", Success: true, Templates: 1}
+	Send(context.Background(), []core.Notification{{Type: core.NotificationNtfy, URL: srv.URL}}, event)
+
+	if title != event.Title() {
+		t.Errorf("Title header = %q, want %q", title, event.Title())
+	}
+	if body != event.Message() {
+		t.Errorf("body = %q, want %q", body, event.Message())
+	}
+}
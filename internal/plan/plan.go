@@ -0,0 +1,197 @@
+// Package plan describes a reviewable change set computed by `mmdot plan`
+// and executed by `mmdot apply`.
+package plan
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/pkgs/atomicwrite"
+	"github.com/hay-kot/mmdot/pkgs/fcrypt"
+)
+
+// Version is the plan file format version. Apply rejects other versions.
+const Version = 1
+
+// Kind is the type of item a change applies to.
+type Kind string
+
+const (
+	KindPackage  Kind = "package"
+	KindTemplate Kind = "template"
+	KindScript   Kind = "script"
+	KindService  Kind = "service"
+)
+
+// Action is what applying a change does.
+type Action string
+
+const (
+	ActionInstall Action = "install" // install a Homebrew package
+	ActionCreate  Action = "create"  // write a file that does not exist
+	ActionUpdate  Action = "update"  // overwrite a file with new content
+	ActionRun     Action = "run"     // run a script
+	ActionStart   Action = "start"   // enable and start an unchanged service
+)
+
+// Plan is a change set. Changes are applied in order. CleanEnv and AllowEnv
+// record exec.clean_env and exec.allow_env so scripts get the environment
+// they were planned with; the values themselves are never stored.
+type Plan struct {
+	Version   int       `json:"version"`
+	Created   time.Time `json:"created"`
+	Config    string    `json:"config"`     // config file the plan was computed from
+	ConfigDir string    `json:"config_dir"` // working directory for scripts
+	Shell     string    `json:"shell"`      // shell scripts are run with
+	CleanEnv  bool      `json:"clean_env,omitempty"`
+	AllowEnv  []string  `json:"allow_env,omitempty"`
+	Expr      string    `json:"expr,omitempty"`
+	Changes   []Change  `json:"changes"`
+}
+
+// Change is a single planned change.
+type Change struct {
+	Kind   Kind       `json:"kind"`
+	Name   string     `json:"name"`
+	Action Action     `json:"action"`
+	Stage  core.Stage `json:"stage,omitempty"`
+
+	// Path is the output of a template or service, or the script to run.
+	Path  string `json:"path,omitempty"`
+	Perm  string `json:"perm,omitempty"`
+	Owner string `json:"owner,omitempty"`
+	Group string `json:"group,omitempty"`
+
+	// Content is the rendered output written by apply. When Encrypted is
+	// set it is armored age ciphertext of the output; see [Change.Seal].
+	Content   string `json:"content,omitempty"`
+	Encrypted bool   `json:"encrypted,omitempty"`
+
+	// Before is the sha256 of the file at Path when the plan was computed,
+	// empty when it did not exist. For scripts it is the script's hash.
+	Before string `json:"before,omitempty"`
+
+	Privileged bool `json:"privileged,omitempty"` // scripts
+	Cask       bool `json:"cask,omitempty"`       // packages
+	Enable     bool `json:"enable,omitempty"`     // services
+	Start      bool `json:"start,omitempty"`      // services
+
+	// Args are extra brew install arguments for a package.
+	Args []string `json:"args,omitempty"`
+}
+
+// Seal encrypts Content to recipients, for outputs holding secrets that must
+// not be stored in the plan in plaintext.
+func (c *Change) Seal(recipients []age.Recipient) error {
+	var buf bytes.Buffer
+	if err := fcrypt.EncryptReader(strings.NewReader(c.Content), &buf, recipients); err != nil {
+		return fmt.Errorf("failed to encrypt %s: %w", c.Name, err)
+	}
+	c.Content = buf.String()
+	c.Encrypted = true
+	return nil
+}
+
+// Open decrypts a sealed Content with identity. Changes that are not
+// encrypted are left as they are.
+func (c *Change) Open(identity age.Identity) error {
+	if !c.Encrypted {
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := fcrypt.DecryptReader(strings.NewReader(c.Content), &buf, identity); err != nil {
+		return fmt.Errorf("failed to decrypt %s: %w", c.Name, err)
+	}
+	c.Content = buf.String()
+	c.Encrypted = false
+	return nil
+}
+
+// Hash returns the hex sha256 of data.
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// HashFile returns the hash of the file at path, or an empty string when it
+// does not exist.
+func HashFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", nil
+		}
+		return "", err
+	}
+	return Hash(data), nil
+}
+
+// Stale reports why the change can no longer be applied as planned: the file
+// it writes or the script it runs has changed since the plan was computed.
+// Packages are never stale; installing an installed package is a no-op.
+func (c Change) Stale() (string, error) {
+	if c.Kind == KindPackage || c.Action == ActionStart {
+		return "", nil
+	}
+
+	current, err := HashFile(c.Path)
+	if err != nil {
+		return "", err
+	}
+	if current == c.Before {
+		return "", nil
+	}
+
+	switch {
+	case c.Kind == KindScript && current == "":
+		return "script was removed", nil
+	case c.Kind == KindScript:
+		return "script was modified", nil
+	case c.Before == "":
+		return "file was created", nil
+	case current == "":
+		return "file was removed", nil
+	default:
+		return "file was modified", nil
+	}
+}
+
+// Write saves the plan to path. Rendered templates may contain secrets, so
+// the file is only readable by the owner.
+func (p *Plan) Write(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicwrite.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// Read loads the plan at path.
+func Read(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var p Plan
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("failed to parse plan %s: %w", path, err)
+	}
+	if p.Version != Version {
+		return nil, fmt.Errorf("plan %s has version %d, expected %d", path, p.Version, Version)
+	}
+	return &p, nil
+}
@@ -0,0 +1,120 @@
+package plan
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+)
+
+func TestPlan_WriteRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+
+	p := &Plan{
+		Version:  Version,
+		Shell:    "/bin/bash",
+		CleanEnv: true,
+		AllowEnv: []string{"LC_*"},
+		Changes: []Change{
+			{Kind: KindTemplate, Name: "zshrc", Action: ActionCreate, Path: "/tmp/zshrc", Perm: "0644", Content: "export A=1\n"},
+			{Kind: KindPackage, Name: "ripgrep", Action: ActionInstall},
+		},
+	}
+	if err := p.Write(path); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("plan perm = %o, want 600", perm)
+	}
+
+	got, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Changes) != 2 || got.Changes[0].Content != "export A=1\n" || got.Changes[1].Name != "ripgrep" ||
+		!got.CleanEnv || len(got.AllowEnv) != 1 {
+		t.Errorf("Read() = %+v", got)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version": 99}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(path); err == nil {
+		t.Error("Read() accepted an unknown version")
+	}
+}
+
+func TestChange_Stale(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing")
+	if err := os.WriteFile(existing, []byte("current"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing")
+
+	tests := []struct {
+		name   string
+		change Change
+		want   string
+	}{
+		{"create", Change{Kind: KindTemplate, Action: ActionCreate, Path: missing}, ""},
+		{"created since", Change{Kind: KindTemplate, Action: ActionCreate, Path: existing}, "file was created"},
+		{"update", Change{Kind: KindTemplate, Action: ActionUpdate, Path: existing, Before: Hash([]byte("current"))}, ""},
+		{"modified since", Change{Kind: KindTemplate, Action: ActionUpdate, Path: existing, Before: Hash([]byte("old"))}, "file was modified"},
+		{"removed since", Change{Kind: KindService, Action: ActionUpdate, Path: missing, Before: Hash([]byte("old"))}, "file was removed"},
+		{"script", Change{Kind: KindScript, Action: ActionRun, Path: existing, Before: Hash([]byte("current"))}, ""},
+		{"script modified", Change{Kind: KindScript, Action: ActionRun, Path: existing, Before: Hash([]byte("old"))}, "script was modified"},
+		{"script removed", Change{Kind: KindScript, Action: ActionRun, Path: missing, Before: Hash([]byte("old"))}, "script was removed"},
+		{"package", Change{Kind: KindPackage, Action: ActionInstall, Name: "ripgrep"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.change.Stale()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Stale() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChange_SealOpen(t *testing.T) {
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := Change{Kind: KindTemplate, Name: "netrc", Content: "password s3cret\n"}
+	if err := c.Seal([]age.Recipient{id.Recipient()}); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Encrypted || strings.Contains(c.Content, "s3cret") {
+		t.Fatalf("Seal() left plaintext: %+v", c)
+	}
+
+	other, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrong := c
+	if err := wrong.Open(other); err == nil {
+		t.Error("Open() succeeded with the wrong identity")
+	}
+
+	if err := c.Open(id); err != nil {
+		t.Fatal(err)
+	}
+	if c.Encrypted || c.Content != "password s3cret\n" {
+		t.Errorf("Open() = %+v", c)
+	}
+}
@@ -0,0 +1,167 @@
+// Package profile records wall-clock timings of the phases of a run (config
+// load, decryption, template renders, scripts) for `mmdot run --profile-run`.
+//
+// Timings are collected through the context so that code deep in the call
+// stack can report them without extra parameters. When no [Recorder] is
+// attached, [Track] is a no-op.
+package profile
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime/pprof"
+	"runtime/trace"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Span is a single timed step.
+type Span struct {
+	Category string // e.g. "config", "decrypt", "render", "script"
+	Name     string
+	Duration time.Duration
+}
+
+// Recorder collects spans. It is safe for concurrent use.
+type Recorder struct {
+	start time.Time
+
+	mu    sync.Mutex
+	spans []Span
+}
+
+// New returns a Recorder whose total time starts now.
+func New() *Recorder {
+	return &Recorder{start: time.Now()}
+}
+
+type ctxKey struct{}
+
+// WithRecorder returns a context that records spans to r.
+func WithRecorder(ctx context.Context, r *Recorder) context.Context {
+	return context.WithValue(ctx, ctxKey{}, r)
+}
+
+// FromContext returns the Recorder attached to ctx, or nil.
+func FromContext(ctx context.Context) *Recorder {
+	r, _ := ctx.Value(ctxKey{}).(*Recorder)
+	return r
+}
+
+// Track starts timing a step and returns a function that records it. It does
+// nothing when ctx has no Recorder.
+//
+//	defer profile.Track(ctx, "render", tmpl.Name)()
+func Track(ctx context.Context, category, name string) func() {
+	r := FromContext(ctx)
+	if r == nil {
+		return func() {}
+	}
+
+	start := time.Now()
+	return func() {
+		r.Add(Span{Category: category, Name: name, Duration: time.Since(start)})
+	}
+}
+
+// Add records a span.
+func (r *Recorder) Add(s Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, s)
+}
+
+// Spans returns the recorded spans in the order they finished.
+func (r *Recorder) Spans() []Span {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.spans)
+}
+
+// WriteTable writes a breakdown of every span, a subtotal per category, and
+// the total elapsed time since the Recorder was created.
+func (r *Recorder) WriteTable(w io.Writer) error {
+	spans := r.Spans()
+	total := time.Since(r.start)
+
+	var categories []string
+	subtotals := map[string]time.Duration{}
+	nameWidth := len("total")
+	for _, s := range spans {
+		if _, ok := subtotals[s.Category]; !ok {
+			categories = append(categories, s.Category)
+		}
+		subtotals[s.Category] += s.Duration
+		nameWidth = max(nameWidth, len(s.Category)+len(s.Name)+2)
+	}
+
+	var sb strings.Builder
+	row := func(label string, d time.Duration) {
+		pct := 0.0
+		if total > 0 {
+			pct = float64(d) / float64(total) * 100
+		}
+		fmt.Fprintf(&sb, "  %-*s  %10s  %5.1f%%\n", nameWidth, label, d.Round(time.Microsecond), pct)
+	}
+
+	for _, cat := range categories {
+		for _, s := range spans {
+			if s.Category == cat {
+				row(cat+": "+s.Name, s.Duration)
+			}
+		}
+	}
+
+	if len(categories) > 0 {
+		sb.WriteString("\n")
+	}
+	for _, cat := range categories {
+		row(cat, subtotals[cat])
+	}
+	row("total", total)
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// StartCPUProfile writes a pprof CPU profile to path until the returned stop
+// function is called.
+func StartCPUProfile(path string) (stop func() error, err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pprof.StartCPUProfile(f); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return func() error {
+		pprof.StopCPUProfile()
+		return f.Close()
+	}, nil
+}
+
+// StartTrace writes a runtime execution trace to path until the returned stop
+// function is called. View it with `go tool trace`.
+func StartTrace(path string) (stop func() error, err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := trace.Start(f); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return func() error {
+		trace.Stop()
+		return f.Close()
+	}, nil
+}
@@ -0,0 +1,46 @@
+package profile
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrack(t *testing.T) {
+	// Without a recorder Track is a no-op
+	Track(context.Background(), "render", "zshrc")()
+
+	rec := New()
+	ctx := WithRecorder(context.Background(), rec)
+
+	Track(ctx, "render", "zshrc")()
+	Track(ctx, "script", "setup.sh")()
+
+	spans := rec.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("Spans() = %d spans, want 2", len(spans))
+	}
+	if spans[0].Category != "render" || spans[0].Name != "zshrc" {
+		t.Errorf("spans[0] = %+v, want render/zshrc", spans[0])
+	}
+}
+
+func TestRecorder_WriteTable(t *testing.T) {
+	rec := New()
+	rec.Add(Span{Category: "decrypt", Name: "secrets.yml", Duration: 30 * time.Millisecond})
+	rec.Add(Span{Category: "render", Name: "zshrc", Duration: 2 * time.Millisecond})
+	rec.Add(Span{Category: "render", Name: "gitconfig", Duration: 3 * time.Millisecond})
+
+	var sb strings.Builder
+	if err := rec.WriteTable(&sb); err != nil {
+		t.Fatal(err)
+	}
+	out := sb.String()
+
+	for _, want := range []string{"decrypt: secrets.yml", "render: zshrc", "render: gitconfig", "5ms", "total"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("WriteTable() missing %q in:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+// Package repos clones git repositories and keeps them on their configured
+// branch or revision.
+package repos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/hay-kot/mmdot/internal/core"
+)
+
+// Action is what syncing did to a repository.
+type Action string
+
+const (
+	Cloned   Action = "cloned"
+	Updated  Action = "updated"
+	UpToDate Action = "up to date"
+	Skipped  Action = "skipped"
+	Failed   Action = "failed"
+)
+
+// Result is the outcome of syncing one repository.
+type Result struct {
+	Repo   core.GitRepo
+	Action Action
+	From   string // HEAD before syncing, empty when cloned
+	To     string // HEAD after syncing
+	Reason string // why the repository was skipped
+	Err    error
+}
+
+// Client runs git. Exec is replaceable in tests.
+type Client struct {
+	Exec func(ctx context.Context, args ...string) ([]byte, error)
+
+	// Offline skips repositories that would need a clone or fetch.
+	Offline bool
+}
+
+// New returns a Client that runs the real git. Credential prompts are
+// disabled since repositories are synced concurrently.
+func New() *Client {
+	return &Client{Offline: core.Offline, Exec: func(ctx context.Context, args ...string) ([]byte, error) {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+		out, err := cmd.Output()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return out, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return out, err
+	}}
+}
+
+// SyncAll syncs repos with at most jobs running at once. Results are in the
+// order of repos.
+func (c *Client) SyncAll(ctx context.Context, repos []core.GitRepo, jobs int) []Result {
+	results := make([]Result, len(repos))
+	sem := make(chan struct{}, max(jobs, 1))
+
+	var wg sync.WaitGroup
+	for i, r := range repos {
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = c.Sync(ctx, r)
+		})
+	}
+	wg.Wait()
+
+	return results
+}
+
+// Sync clones the repository when missing. Existing repositories are checked
+// out at their revision, or fast-forwarded when on their branch; they are
+// skipped when on another branch so local work is never touched.
+func (c *Client) Sync(ctx context.Context, r core.GitRepo) Result {
+	res := Result{Repo: r}
+
+	_, err := os.Stat(filepath.Join(r.Path, ".git"))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		res.Action = Cloned
+		err = c.clone(ctx, r)
+	case err == nil:
+		if res.From, err = c.git(ctx, r.Path, "rev-parse", "HEAD"); err != nil {
+			break
+		}
+		if r.Revision != "" {
+			err = c.checkout(ctx, r)
+		} else {
+			res.Reason, err = c.pull(ctx, r)
+		}
+	}
+
+	if errors.Is(err, core.ErrOffline) {
+		res.Reason, err = "offline", nil
+	}
+	if err == nil && res.Reason == "" {
+		res.To, err = c.git(ctx, r.Path, "rev-parse", "HEAD")
+	}
+
+	switch {
+	case err != nil:
+		res.Action, res.Err = Failed, err
+	case res.Reason != "":
+		res.Action = Skipped
+	case res.Action == Cloned:
+	case res.From == res.To:
+		res.Action = UpToDate
+	default:
+		res.Action = Updated
+	}
+	return res
+}
+
+func (c *Client) clone(ctx context.Context, r core.GitRepo) error {
+	if c.Offline {
+		return core.ErrOffline
+	}
+	if err := os.MkdirAll(filepath.Dir(r.Path), 0o755); err != nil {
+		return err
+	}
+
+	args := []string{"clone", "--quiet"}
+	if r.Shallow {
+		args = append(args, "--depth", "1")
+	}
+	if r.Branch != "" {
+		args = append(args, "--branch", r.Branch)
+	}
+	if r.Revision != "" {
+		args = append(args, "--no-checkout")
+	}
+	if _, err := c.Exec(ctx, append(args, r.URL, r.Path)...); err != nil {
+		return fmt.Errorf("failed to clone %s: %w", r.URL, err)
+	}
+
+	if r.Revision != "" {
+		return c.checkout(ctx, r)
+	}
+	return nil
+}
+
+// checkout detaches HEAD at the revision, fetching it when it is not in the
+// repository yet.
+func (c *Client) checkout(ctx context.Context, r core.GitRepo) error {
+	rev, err := c.git(ctx, r.Path, "rev-parse", "--verify", "--quiet", r.Revision+"^{commit}")
+	if err != nil {
+		if c.Offline {
+			return core.ErrOffline
+		}
+		args := []string{"fetch", "--quiet"}
+		if r.Shallow {
+			args = append(args, "--depth", "1")
+		}
+		if _, err := c.git(ctx, r.Path, append(args, "origin", r.Revision)...); err != nil {
+			return fmt.Errorf("failed to fetch %s: %w", r.Revision, err)
+		}
+		if rev, err = c.git(ctx, r.Path, "rev-parse", "FETCH_HEAD^{commit}"); err != nil {
+			return err
+		}
+	}
+
+	if _, err := c.git(ctx, r.Path, "checkout", "--quiet", "--detach", rev); err != nil {
+		return fmt.Errorf("failed to check out %s: %w", r.Revision, err)
+	}
+	return nil
+}
+
+// pull fast-forwards the current branch, returning a reason instead when the
+// repository is not on its configured or default branch.
+func (c *Client) pull(ctx context.Context, r core.GitRepo) (string, error) {
+	branch, err := c.git(ctx, r.Path, "symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil {
+		return "detached HEAD", nil
+	}
+	want := r.Branch
+	if want == "" {
+		// origin/HEAD is set by clone to the remote's default branch
+		if ref, err := c.git(ctx, r.Path, "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD"); err == nil {
+			want = strings.TrimPrefix(ref, "origin/")
+		}
+	}
+	if want != "" && branch != want {
+		return fmt.Sprintf("on branch %s, not %s", branch, want), nil
+	}
+
+	if c.Offline {
+		return "", core.ErrOffline
+	}
+	if _, err := c.git(ctx, r.Path, "pull", "--quiet", "--ff-only"); err != nil {
+		return "", fmt.Errorf("failed to fast-forward %s: %w", branch, err)
+	}
+	return "", nil
+}
+
+func (c *Client) git(ctx context.Context, dir string, args ...string) (string, error) {
+	out, err := c.Exec(ctx, append([]string{"-C", dir}, args...)...)
+	return strings.TrimSpace(string(out)), err
+}
@@ -0,0 +1,106 @@
+package repos
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hay-kot/mmdot/internal/core"
+)
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@example.com",
+		"GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@example.com",
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestClient_SyncAll(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	src := t.TempDir()
+	commit := func(msg string) string {
+		if err := os.WriteFile(filepath.Join(src, "file"), []byte(msg), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		git(t, src, "add", ".")
+		git(t, src, "commit", "--quiet", "-m", msg)
+		return git(t, src, "rev-parse", "HEAD")
+	}
+
+	git(t, src, "init", "--quiet", "--initial-branch", "main")
+	first := commit("first")
+	git(t, src, "tag", "v1")
+	second := commit("second")
+
+	dest := t.TempDir()
+	repos := []core.GitRepo{
+		{URL: src, Path: filepath.Join(dest, "tracking")},
+		{URL: "file://" + src, Path: filepath.Join(dest, "shallow"), Branch: "main", Shallow: true},
+		{URL: src, Path: filepath.Join(dest, "pinned"), Revision: "v1"},
+		{URL: src, Path: filepath.Join(dest, "feature")},
+		{URL: filepath.Join(src, "missing"), Path: filepath.Join(dest, "missing")},
+	}
+
+	ctx := context.Background()
+	client := New()
+
+	type want struct {
+		action Action
+		to     string
+	}
+
+	check := func(step string, results []Result, wants []want) {
+		t.Helper()
+		for i, w := range wants {
+			res := results[i]
+			if res.Action != w.action || (w.to != "" && res.To != w.to) {
+				t.Errorf("%s: %s = %s %s (err: %v), want %s %s", step, filepath.Base(res.Repo.Path), res.Action, res.To, res.Err, w.action, w.to)
+			}
+		}
+	}
+
+	check("clone", client.SyncAll(ctx, repos, 2), []want{
+		{Cloned, second}, {Cloned, second}, {Cloned, first}, {Cloned, second}, {Failed, ""},
+	})
+
+	third := commit("third")
+	git(t, repos[3].Path, "checkout", "--quiet", "-b", "feature")
+	repos[2].Revision = second
+
+	check("update", client.SyncAll(ctx, repos[:4], 2), []want{
+		{Updated, third}, {Updated, third}, {Updated, second}, {Skipped, ""},
+	})
+
+	check("unchanged", client.SyncAll(ctx, repos[:3], 2), []want{
+		{UpToDate, third}, {UpToDate, third}, {UpToDate, second},
+	})
+
+	// offline, only revisions already fetched can be checked out
+	commit("fourth")
+	client.Offline = true
+	repos[2].Revision = first
+	offline := []core.GitRepo{repos[0], repos[2], {URL: src, Path: filepath.Join(dest, "new")}}
+	results := client.SyncAll(ctx, offline, 2)
+	check("offline", results, []want{
+		{Skipped, ""}, {Updated, first}, {Skipped, ""},
+	})
+	if results[0].Reason != "offline" {
+		t.Errorf("offline: reason = %q, want offline", results[0].Reason)
+	}
+	if _, err := os.Stat(offline[2].Path); !os.IsNotExist(err) {
+		t.Errorf("offline: %s was cloned", offline[2].Path)
+	}
+}
@@ -0,0 +1,441 @@
+// Package schedule installs periodic, non-interactive mmdot runs using the
+// platform scheduler: launchd agents on macOS, systemd user timers on Linux,
+// and crontab as a fallback.
+package schedule
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/hay-kot/mmdot/pkgs/atomicwrite"
+)
+
+type Backend string
+
+const (
+	BackendAuto    Backend = "auto"
+	BackendLaunchd Backend = "launchd"
+	BackendSystemd Backend = "systemd"
+	BackendCron    Backend = "cron"
+)
+
+const (
+	// Name is the systemd unit name used for the service and timer.
+	Name = "mmdot-run"
+	// Label is the launchd agent label.
+	Label = "com.mmdot.run"
+
+	cronMarker = "# mmdot schedule"
+)
+
+// ParseBackend validates a backend name and resolves [BackendAuto] to the
+// scheduler available on this machine.
+func ParseBackend(s string) (Backend, error) {
+	switch b := Backend(s); b {
+	case "", BackendAuto:
+		return DetectBackend(), nil
+	case BackendLaunchd, BackendSystemd, BackendCron:
+		return b, nil
+	}
+	return "", fmt.Errorf("invalid backend %q (expected %q, %q, %q, or %q)", s, BackendAuto, BackendLaunchd, BackendSystemd, BackendCron)
+}
+
+// DetectBackend returns launchd on macOS, systemd when systemctl is on the
+// PATH, and cron otherwise.
+func DetectBackend() Backend {
+	if runtime.GOOS == "darwin" {
+		return BackendLaunchd
+	}
+	if _, err := exec.LookPath("systemctl"); err == nil {
+		return BackendSystemd
+	}
+	return BackendCron
+}
+
+// Spec describes a scheduled invocation.
+type Spec struct {
+	Every   time.Duration // Interval between runs
+	Command []string      // Full argv, starting with the mmdot executable
+	LogPath string        // File receiving stdout and stderr (launchd and cron)
+}
+
+func (s Spec) Validate() error {
+	if s.Every < time.Minute {
+		return fmt.Errorf("interval must be at least 1m, got %s", s.Every)
+	}
+	if len(s.Command) == 0 {
+		return fmt.Errorf("command is required")
+	}
+	return nil
+}
+
+// DefaultLogPath returns the log file used for scheduled runs.
+func DefaultLogPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	if runtime.GOOS == "darwin" {
+		return filepath.Join(home, "Library", "Logs", "mmdot", "schedule.log"), nil
+	}
+	return filepath.Join(home, ".local", "state", "mmdot", "schedule.log"), nil
+}
+
+// LaunchdPath returns the path of the launchd agent plist.
+func LaunchdPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Library", "LaunchAgents", Label+".plist"), nil
+}
+
+// SystemdDir returns the systemd user unit directory.
+func SystemdDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "systemd", "user"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "systemd", "user"), nil
+}
+
+// LaunchdPlist renders a launchd agent that runs the command every interval.
+func LaunchdPlist(spec Spec) string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+  <key>Label</key>
+  <string>` + Label + `</string>
+  <key>ProgramArguments</key>
+  <array>
+`)
+	for _, arg := range spec.Command {
+		sb.WriteString("    <string>" + html.EscapeString(arg) + "</string>\n")
+	}
+	fmt.Fprintf(&sb, `  </array>
+  <key>StartInterval</key>
+  <integer>%d</integer>
+  <key>RunAtLoad</key>
+  <false/>
+  <key>StandardOutPath</key>
+  <string>%s</string>
+  <key>StandardErrorPath</key>
+  <string>%s</string>
+</dict>
+</plist>
+`, int(spec.Every.Seconds()), html.EscapeString(spec.LogPath), html.EscapeString(spec.LogPath))
+
+	return sb.String()
+}
+
+// SystemdUnits renders a oneshot service and a timer that triggers it every
+// interval. Output goes to the user journal.
+func SystemdUnits(spec Spec) (service, timer string) {
+	args := make([]string, len(spec.Command))
+	for i, arg := range spec.Command {
+		args[i] = systemdQuote(arg)
+	}
+
+	service = fmt.Sprintf(`[Unit]
+Description=mmdot scheduled run
+
+[Service]
+Type=oneshot
+ExecStart=%s
+`, strings.Join(args, " "))
+
+	timer = fmt.Sprintf(`[Unit]
+Description=mmdot scheduled run every %s
+
+[Timer]
+OnBootSec=5min
+OnUnitActiveSec=%ds
+Persistent=true
+
+[Install]
+WantedBy=timers.target
+`, spec.Every, int(spec.Every.Seconds()))
+
+	return service, timer
+}
+
+// CronLine renders a crontab entry for the interval. Only intervals that map
+// cleanly onto cron fields are supported: whole minutes dividing an hour,
+// whole hours dividing a day, or whole days.
+func CronLine(spec Spec) (string, error) {
+	var schedule string
+	switch every := spec.Every; {
+	case every%(24*time.Hour) == 0:
+		schedule = fmt.Sprintf("0 0 */%d * *", int(every.Hours()/24))
+	case every%time.Hour == 0 && 24%int(every.Hours()) == 0:
+		schedule = fmt.Sprintf("0 */%d * * *", int(every.Hours()))
+	case every%time.Minute == 0 && every < time.Hour && 60%int(every.Minutes()) == 0:
+		schedule = fmt.Sprintf("*/%d * * * *", int(every.Minutes()))
+	default:
+		return "", fmt.Errorf("interval %s cannot be expressed as a cron schedule", spec.Every)
+	}
+
+	// cron treats unescaped % as a newline
+	args := make([]string, len(spec.Command))
+	for i, arg := range spec.Command {
+		args[i] = strings.ReplaceAll(shellQuote(arg), "%", `\%`)
+	}
+
+	return fmt.Sprintf("%s %s >> %s 2>&1 %s", schedule, strings.Join(args, " "), shellQuote(spec.LogPath), cronMarker), nil
+}
+
+// Install writes the scheduler configuration for spec and activates it,
+// replacing any previous installation.
+func Install(ctx context.Context, backend Backend, spec Spec) error {
+	if err := spec.Validate(); err != nil {
+		return err
+	}
+
+	if spec.LogPath != "" {
+		if err := os.MkdirAll(filepath.Dir(spec.LogPath), 0o755); err != nil {
+			return fmt.Errorf("failed to create log directory: %w", err)
+		}
+	}
+
+	switch backend {
+	case BackendLaunchd:
+		path, err := LaunchdPath()
+		if err != nil {
+			return err
+		}
+		if err := writeFile(path, LaunchdPlist(spec)); err != nil {
+			return err
+		}
+		domain := fmt.Sprintf("gui/%d", os.Getuid())
+		_ = run(ctx, nil, "launchctl", "bootout", domain, path) // ignore: may not be loaded yet
+		return run(ctx, nil, "launchctl", "bootstrap", domain, path)
+	case BackendSystemd:
+		dir, err := SystemdDir()
+		if err != nil {
+			return err
+		}
+		service, timer := SystemdUnits(spec)
+		if err := writeFile(filepath.Join(dir, Name+".service"), service); err != nil {
+			return err
+		}
+		if err := writeFile(filepath.Join(dir, Name+".timer"), timer); err != nil {
+			return err
+		}
+		if err := run(ctx, nil, "systemctl", "--user", "daemon-reload"); err != nil {
+			return err
+		}
+		return run(ctx, nil, "systemctl", "--user", "enable", "--now", Name+".timer")
+	case BackendCron:
+		line, err := CronLine(spec)
+		if err != nil {
+			return err
+		}
+		current, err := readCrontab(ctx)
+		if err != nil {
+			return err
+		}
+		return run(ctx, []byte(withoutCronEntry(current)+line+"\n"), "crontab", "-")
+	}
+
+	return fmt.Errorf("unsupported backend %q", backend)
+}
+
+// Remove deactivates and deletes a previous installation. Removing a schedule
+// that does not exist is not an error.
+func Remove(ctx context.Context, backend Backend) error {
+	switch backend {
+	case BackendLaunchd:
+		path, err := LaunchdPath()
+		if err != nil {
+			return err
+		}
+		_ = run(ctx, nil, "launchctl", "bootout", fmt.Sprintf("gui/%d", os.Getuid()), path)
+		return removeFile(path)
+	case BackendSystemd:
+		dir, err := SystemdDir()
+		if err != nil {
+			return err
+		}
+		_ = run(ctx, nil, "systemctl", "--user", "disable", "--now", Name+".timer")
+		if err := removeFile(filepath.Join(dir, Name+".timer")); err != nil {
+			return err
+		}
+		if err := removeFile(filepath.Join(dir, Name+".service")); err != nil {
+			return err
+		}
+		return run(ctx, nil, "systemctl", "--user", "daemon-reload")
+	case BackendCron:
+		current, err := readCrontab(ctx)
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(current, cronMarker) {
+			return nil
+		}
+		return run(ctx, []byte(withoutCronEntry(current)), "crontab", "-")
+	}
+
+	return fmt.Errorf("unsupported backend %q", backend)
+}
+
+// Status describes an installed schedule.
+type Status struct {
+	Installed bool
+	Location  string // File or crontab holding the schedule
+	Details   string // Scheduler-reported state, when available
+}
+
+// GetStatus reports whether a schedule is installed for the backend.
+func GetStatus(ctx context.Context, backend Backend) (Status, error) {
+	switch backend {
+	case BackendLaunchd:
+		path, err := LaunchdPath()
+		if err != nil {
+			return Status{}, err
+		}
+		st := Status{Location: path, Installed: exists(path)}
+		if st.Installed {
+			st.Details, _ = output(ctx, "launchctl", "print", fmt.Sprintf("gui/%d/%s", os.Getuid(), Label))
+		}
+		return st, nil
+	case BackendSystemd:
+		dir, err := SystemdDir()
+		if err != nil {
+			return Status{}, err
+		}
+		path := filepath.Join(dir, Name+".timer")
+		st := Status{Location: path, Installed: exists(path)}
+		if st.Installed {
+			st.Details, _ = output(ctx, "systemctl", "--user", "list-timers", Name+".timer", "--no-pager")
+		}
+		return st, nil
+	case BackendCron:
+		current, err := readCrontab(ctx)
+		if err != nil {
+			return Status{}, err
+		}
+		st := Status{Location: "crontab"}
+		for line := range strings.SplitSeq(current, "\n") {
+			if strings.HasSuffix(line, cronMarker) {
+				st.Installed = true
+				st.Details = line
+			}
+		}
+		return st, nil
+	}
+
+	return Status{}, fmt.Errorf("unsupported backend %q", backend)
+}
+
+// withoutCronEntry removes the mmdot entry from a crontab, returning the
+// remaining lines with a trailing newline when non-empty.
+func withoutCronEntry(crontab string) string {
+	var kept []string
+	for line := range strings.SplitSeq(strings.TrimRight(crontab, "\n"), "\n") {
+		if line == "" && len(kept) == 0 {
+			continue
+		}
+		if strings.HasSuffix(line, cronMarker) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if len(kept) == 0 {
+		return ""
+	}
+	return strings.Join(kept, "\n") + "\n"
+}
+
+func readCrontab(ctx context.Context) (string, error) {
+	return crontabResult(exec.CommandContext(ctx, "crontab", "-l").Output())
+}
+
+// crontabResult interprets the result of `crontab -l`. Only a user without a
+// crontab reads as empty; any other failure is returned so the crontab is
+// never rewritten from a partial read.
+func crontabResult(out []byte, err error) (string, error) {
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && strings.Contains(string(exitErr.Stderr), "no crontab for") {
+			return "", nil
+		}
+		if exitErr != nil && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("failed to read crontab: %w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("failed to read crontab: %w", err)
+	}
+	return string(out), nil
+}
+
+func writeFile(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	if err := atomicwrite.WriteFile(path, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+func removeFile(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	return nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func run(ctx context.Context, stdin []byte, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func output(ctx context.Context, name string, args ...string) (string, error) {
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	return strings.TrimSpace(string(out)), err
+}
+
+// shellQuote single-quotes s for use in a POSIX shell command line.
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\$`!*?[]{}()<>|&;#~") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// systemdQuote quotes s for an ExecStart= line.
+func systemdQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\"'\\$%") {
+		return s
+	}
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "$", "$$")
+	s = strings.ReplaceAll(s, "%", "%%")
+	return `"` + s + `"`
+}
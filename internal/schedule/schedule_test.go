@@ -0,0 +1,116 @@
+package schedule
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCronLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		every   time.Duration
+		want    string
+		wantErr bool
+	}{
+		{name: "minutes", every: 15 * time.Minute, want: "*/15 * * * *"},
+		{name: "hours", every: 6 * time.Hour, want: "0 */6 * * *"},
+		{name: "days", every: 48 * time.Hour, want: "0 0 */2 * *"},
+		{name: "uneven minutes", every: 7 * time.Minute, wantErr: true},
+		{name: "uneven hours", every: 5 * time.Hour, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := Spec{Every: tt.every, Command: []string{"/usr/bin/mmdot", "run", "+auto"}, LogPath: "/tmp/mmdot.log"}
+			got, err := CronLine(spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CronLine() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !strings.HasPrefix(got, tt.want+" ") {
+				t.Errorf("CronLine() = %q, want prefix %q", got, tt.want)
+			}
+			if !strings.HasSuffix(got, cronMarker) {
+				t.Errorf("CronLine() = %q, missing marker", got)
+			}
+		})
+	}
+}
+
+func TestCronLine_Quoting(t *testing.T) {
+	spec := Spec{
+		Every:   time.Hour,
+		Command: []string{"/usr/bin/mmdot", "run", `"auto" in tags`, "100%"},
+		LogPath: "/tmp/mmdot.log",
+	}
+
+	got, err := CronLine(spec)
+	if err != nil {
+		t.Fatalf("CronLine() error = %v", err)
+	}
+
+	want := `0 */1 * * * /usr/bin/mmdot run '"auto" in tags' 100\% >> /tmp/mmdot.log 2>&1 # mmdot schedule`
+	if got != want {
+		t.Errorf("CronLine() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWithoutCronEntry(t *testing.T) {
+	crontab := "0 * * * * backup.sh\n*/5 * * * * mmdot run true >> log 2>&1 # mmdot schedule\n"
+	if got := withoutCronEntry(crontab); got != "0 * * * * backup.sh\n" {
+		t.Errorf("withoutCronEntry() = %q", got)
+	}
+	if got := withoutCronEntry(""); got != "" {
+		t.Errorf("withoutCronEntry(\"\") = %q", got)
+	}
+}
+
+func TestSystemdUnits(t *testing.T) {
+	spec := Spec{Every: 6 * time.Hour, Command: []string{"/usr/bin/mmdot", "--config=/home/me/dots/mmdot.yml", "run", "+auto"}}
+
+	service, timer := SystemdUnits(spec)
+
+	if !strings.Contains(service, "ExecStart=/usr/bin/mmdot --config=/home/me/dots/mmdot.yml run +auto\n") {
+		t.Errorf("unexpected service unit:\n%s", service)
+	}
+	if !strings.Contains(timer, "OnUnitActiveSec=21600s\n") {
+		t.Errorf("unexpected timer unit:\n%s", timer)
+	}
+}
+
+func TestLaunchdPlist(t *testing.T) {
+	spec := Spec{Every: 30 * time.Minute, Command: []string{"/usr/local/bin/mmdot", "run", `"a" in tags && true`}, LogPath: "/tmp/mmdot.log"}
+
+	plist := LaunchdPlist(spec)
+
+	for _, want := range []string{
+		"<string>" + Label + "</string>",
+		"<integer>1800</integer>",
+		"<string>&#34;a&#34; in tags &amp;&amp; true</string>",
+	} {
+		if !strings.Contains(plist, want) {
+			t.Errorf("plist missing %q:\n%s", want, plist)
+		}
+	}
+}
+
+func TestCrontabResult(t *testing.T) {
+	exitErr := func(stderr string) error {
+		return &exec.ExitError{ProcessState: &os.ProcessState{}, Stderr: []byte(stderr)}
+	}
+
+	if got, err := crontabResult([]byte("0 * * * * job\n"), nil); err != nil || got != "0 * * * * job\n" {
+		t.Errorf("crontabResult(ok) = %q, %v", got, err)
+	}
+	if got, err := crontabResult(nil, exitErr("no crontab for alice\n")); err != nil || got != "" {
+		t.Errorf("crontabResult(no crontab) = %q, %v, want empty", got, err)
+	}
+	if _, err := crontabResult(nil, exitErr("crontab: PAM authentication failed\n")); err == nil || !strings.Contains(err.Error(), "PAM") {
+		t.Errorf("crontabResult(PAM failure) error = %v, want it propagated", err)
+	}
+}
@@ -0,0 +1,183 @@
+// Package secretscan finds files that look like they hold secrets, by name
+// and by content, so they can be reported before they are committed in
+// plaintext.
+package secretscan
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/hay-kot/mmdot/pkgs/fcrypt"
+)
+
+// MaxSize is the largest file whose content is scanned. Larger files are
+// only checked by name.
+const MaxSize = 1 << 20
+
+// MinEntropy is the Shannon entropy, in bits per character, above which a
+// token is reported as a likely secret. Base64 encoded keys score around 5,
+// English words and paths well below 4.
+const MinEntropy = 4.5
+
+// namePatterns match the base names of files that hold secrets by
+// convention.
+var namePatterns = []string{
+	"id_rsa", "id_dsa", "id_ecdsa", "id_ed25519",
+	"*.pem", "*.key", "*.p12", "*.pfx",
+	".env", ".env.*",
+}
+
+// lockFiles are the base names of dependency lock files. Their content is
+// checksums, which are as random as secrets, so only their names are checked.
+var lockFiles = []string{
+	"go.sum", "flake.lock", "package-lock.json", "npm-shrinkwrap.json",
+	"yarn.lock", "pnpm-lock.yaml", "bun.lock", "Cargo.lock", "Gemfile.lock",
+	"poetry.lock", "uv.lock", "composer.lock", "Package.resolved",
+}
+
+// checksumPrefixes start base64 checksums such as go.sum's h1: hashes and
+// the sha512- integrity values of npm and Nix.
+var checksumPrefixes = []string{"h1:", "sha1-", "sha256-", "sha384-", "sha512-"}
+
+var (
+	privateKey = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY( BLOCK)?-----|AGE-SECRET-KEY-1[0-9A-Z]+`)
+	token      = regexp.MustCompile(`[A-Za-z0-9+/=_\-]{32,}`)
+	hexOnly    = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+
+	// armored age blocks and ENC[age,...] values are ciphertext, which is
+	// as random as any secret
+	ageArmor = regexp.MustCompile(`(?s)-----BEGIN AGE ENCRYPTED FILE-----.*?-----END AGE ENCRYPTED FILE-----`)
+	ageValue = regexp.MustCompile(`ENC\[age,[^\]]*\]`)
+)
+
+// Finding is a reason to believe a file holds a secret.
+type Finding struct {
+	Path   string
+	Line   int // 0 when the whole file matched, e.g. by name
+	Reason string
+
+	// Warning is set for findings based on entropy alone, which are often
+	// random but harmless values.
+	Warning bool
+}
+
+func (f Finding) String() string {
+	if f.Line == 0 {
+		return fmt.Sprintf("%s: %s", f.Path, f.Reason)
+	}
+	return fmt.Sprintf("%s:%d: %s", f.Path, f.Line, f.Reason)
+}
+
+// Scan checks the file at name, a slash separated path, with the given
+// content. Files that are age encrypted have no findings. Binary content is
+// only checked by name.
+func Scan(name string, data []byte) []Finding {
+	if fcrypt.IsEncrypted(data) {
+		return nil
+	}
+
+	var findings []Finding
+	base := path.Base(name)
+	for _, pattern := range namePatterns {
+		if ok, _ := path.Match(pattern, base); ok {
+			findings = append(findings, Finding{Path: name, Reason: "file name matches " + pattern})
+			break
+		}
+	}
+
+	if len(data) > MaxSize || bytes.IndexByte(data, 0) >= 0 || slices.Contains(lockFiles, base) {
+		return findings
+	}
+
+	data = ageArmor.ReplaceAllFunc(data, blankLines)
+	data = ageValue.ReplaceAll(data, nil)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxSize)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if reason, warning := scanLine(line); reason != "" {
+			findings = append(findings, Finding{Path: name, Line: n, Reason: reason, Warning: warning})
+		}
+	}
+	return findings
+}
+
+// scanLine returns why line looks like it holds a secret, or "", and whether
+// that is only a warning.
+func scanLine(line string) (string, bool) {
+	if privateKey.MatchString(line) {
+		return "private key", false
+	}
+
+	// public keys are as random as private ones but safe to commit
+	if strings.Contains(line, "ssh-") || strings.Contains(line, "ecdsa-sha2-") {
+		return "", false
+	}
+
+	for _, loc := range token.FindAllStringIndex(line, -1) {
+		tok := line[loc[0]:loc[1]]
+		if strings.HasPrefix(tok, "age1") || hexOnly.MatchString(tok) || charClasses(tok) < 3 || isChecksum(line[:loc[0]], tok) {
+			continue
+		}
+		if e := entropy(tok); e >= MinEntropy {
+			return fmt.Sprintf("high entropy string (%.1f bits/char)", e), true
+		}
+	}
+	return "", false
+}
+
+// isChecksum reports whether tok, preceded on its line by before, is a
+// checksum such as "h1:<base64>" or "sha512-<base64>".
+func isChecksum(before, tok string) bool {
+	for _, prefix := range checksumPrefixes {
+		if strings.HasSuffix(before, prefix) || strings.HasPrefix(tok, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// charClasses counts which of lower case letters, upper case letters, and
+// digits appear in s. Generated secrets use all three.
+func charClasses(s string) int {
+	var lower, upper, digit int
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = 1
+		case r >= 'A' && r <= 'Z':
+			upper = 1
+		case r >= '0' && r <= '9':
+			digit = 1
+		}
+	}
+	return lower + upper + digit
+}
+
+// entropy returns the Shannon entropy of s in bits per character.
+func entropy(s string) float64 {
+	counts := map[rune]int{}
+	for _, r := range s {
+		counts[r]++
+	}
+
+	var e float64
+	n := float64(len(s))
+	for _, c := range counts {
+		p := float64(c) / n
+		e -= p * math.Log2(p)
+	}
+	return e
+}
+
+// blankLines replaces b with its newlines so line numbers stay correct.
+func blankLines(b []byte) []byte {
+	return bytes.Repeat([]byte("\n"), bytes.Count(b, []byte("\n")))
+}
@@ -0,0 +1,160 @@
+// Package services manages user-level service definitions: systemd user units
+// on Linux and launchd agents on macOS.
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/schedule"
+)
+
+// Manager abstracts the platform service manager.
+type Manager interface {
+	// Kind returns the manager name ("systemd" or "launchd").
+	Kind() string
+	// UnitPath returns where the unit file for the service is written.
+	UnitPath(name string) (string, error)
+	// Reload makes the manager pick up changed unit files.
+	Reload(ctx context.Context) error
+	// Enable configures the unit to start at login.
+	Enable(ctx context.Context, name string) error
+	// Restart starts the unit, restarting it if already running.
+	Restart(ctx context.Context, name string) error
+	// Verify checks the unit file at path for errors.
+	Verify(ctx context.Context, path string) error
+	// State returns a short, manager-reported state such as "active".
+	State(ctx context.Context, name string) string
+}
+
+// Detect returns the service manager for the current platform.
+func Detect() Manager {
+	if runtime.GOOS == "darwin" {
+		return Launchd{}
+	}
+	return Systemd{}
+}
+
+// Template returns the template that renders the service's unit file.
+func Template(m Manager, svc core.Service) (core.Template, error) {
+	path, err := m.UnitPath(svc.Name)
+	if err != nil {
+		return core.Template{}, err
+	}
+
+	return core.Template{
+		Name:        svc.Name,
+		Tags:        svc.Tags,
+		Template:    svc.Template,
+		Output:      path,
+		Permissions: "0644",
+		Vars:        svc.Vars,
+	}, nil
+}
+
+// Systemd manages units in the systemd user instance.
+type Systemd struct{}
+
+var systemdUnitSuffixes = []string{".service", ".timer", ".socket", ".path", ".target", ".mount"}
+
+// unitName appends ".service" when name has no unit type suffix.
+func (Systemd) unitName(name string) string {
+	for _, suffix := range systemdUnitSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return name
+		}
+	}
+	return name + ".service"
+}
+
+func (Systemd) Kind() string { return "systemd" }
+
+func (s Systemd) UnitPath(name string) (string, error) {
+	dir, err := schedule.SystemdDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, s.unitName(name)), nil
+}
+
+func (Systemd) Reload(ctx context.Context) error {
+	return run(ctx, "systemctl", "--user", "daemon-reload")
+}
+
+func (s Systemd) Enable(ctx context.Context, name string) error {
+	return run(ctx, "systemctl", "--user", "enable", s.unitName(name))
+}
+
+func (s Systemd) Restart(ctx context.Context, name string) error {
+	return run(ctx, "systemctl", "--user", "restart", s.unitName(name))
+}
+
+func (Systemd) Verify(ctx context.Context, path string) error {
+	return run(ctx, "systemd-analyze", "--user", "verify", path)
+}
+
+func (s Systemd) State(ctx context.Context, name string) string {
+	out, _ := exec.CommandContext(ctx, "systemctl", "--user", "is-active", s.unitName(name)).Output()
+	if state := strings.TrimSpace(string(out)); state != "" {
+		return state
+	}
+	return "unknown"
+}
+
+// Launchd manages agents in the user's launchd GUI domain.
+type Launchd struct{}
+
+func (Launchd) Kind() string { return "launchd" }
+
+func (Launchd) label(name string) string {
+	return strings.TrimSuffix(name, ".plist")
+}
+
+func (l Launchd) UnitPath(name string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Library", "LaunchAgents", l.label(name)+".plist"), nil
+}
+
+// Reload is a no-op; launchd reads the plist when the agent is bootstrapped.
+func (Launchd) Reload(ctx context.Context) error { return nil }
+
+// Enable is a no-op; agents in ~/Library/LaunchAgents load at login.
+func (Launchd) Enable(ctx context.Context, name string) error { return nil }
+
+func (l Launchd) Restart(ctx context.Context, name string) error {
+	path, err := l.UnitPath(name)
+	if err != nil {
+		return err
+	}
+	domain := fmt.Sprintf("gui/%d", os.Getuid())
+	_ = run(ctx, "launchctl", "bootout", domain, path) // ignore: may not be loaded yet
+	return run(ctx, "launchctl", "bootstrap", domain, path)
+}
+
+func (Launchd) Verify(ctx context.Context, path string) error {
+	return run(ctx, "plutil", "-lint", path)
+}
+
+func (l Launchd) State(ctx context.Context, name string) string {
+	err := exec.CommandContext(ctx, "launchctl", "print", fmt.Sprintf("gui/%d/%s", os.Getuid(), l.label(name))).Run()
+	if err != nil {
+		return "not loaded"
+	}
+	return "loaded"
+}
+
+func run(ctx context.Context, name string, args ...string) error {
+	if out, err := exec.CommandContext(ctx, name, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/hay-kot/mmdot/internal/core"
+)
+
+func TestSystemd_UnitPath(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/home/me/.config")
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "syncthing", want: "/home/me/.config/systemd/user/syncthing.service"},
+		{name: "backup.timer", want: "/home/me/.config/systemd/user/backup.timer"},
+		{name: "agent.socket", want: "/home/me/.config/systemd/user/agent.socket"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Systemd{}.UnitPath(tt.name)
+			if err != nil {
+				t.Fatalf("UnitPath() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("UnitPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/home/me/.config")
+
+	svc := core.Service{
+		Name:     "syncthing",
+		Tags:     []string{"personal"},
+		Template: "[Service]\nExecStart={{ .bin }}\n",
+		Vars:     map[string]any{"bin": "/usr/bin/syncthing"},
+	}
+
+	tmpl, err := Template(Systemd{}, svc)
+	if err != nil {
+		t.Fatalf("Template() error = %v", err)
+	}
+
+	if tmpl.Output != filepath.Join("/home/me/.config/systemd/user", "syncthing.service") {
+		t.Errorf("Output = %v", tmpl.Output)
+	}
+	if tmpl.Template != svc.Template || tmpl.Vars["bin"] != "/usr/bin/syncthing" {
+		t.Errorf("template fields not carried over: %+v", tmpl)
+	}
+}
@@ -0,0 +1,115 @@
+// Package shellplugins clones shell plugin repositories and checks them out
+// at pinned commits.
+package shellplugins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/hay-kot/mmdot/internal/core"
+)
+
+// Repo is a plugin and the directory it is cloned to.
+type Repo struct {
+	Plugin core.ShellPlugin
+	Dir    string
+}
+
+// Repos returns the manager repository followed by every plugin.
+func Repos(s core.Shell) []Repo {
+	repos := []Repo{{Plugin: s.ManagerPlugin(), Dir: s.RepoDir(s.ManagerPlugin())}}
+	for _, p := range s.Plugins {
+		repos = append(repos, Repo{Plugin: p, Dir: s.RepoDir(p)})
+	}
+	return repos
+}
+
+// Status is the checked out commit of a repository.
+type Status struct {
+	Repo
+	Current string // empty when not cloned
+}
+
+// Ok reports whether the repository is at its pinned commit.
+func (s Status) Ok() bool {
+	return s.Current == s.Plugin.Commit
+}
+
+// Client runs git. Exec is replaceable in tests.
+type Client struct {
+	Exec func(ctx context.Context, args ...string) ([]byte, error)
+
+	// Offline fails syncs that need a clone or fetch with [core.ErrOffline].
+	Offline bool
+}
+
+// New returns a Client that runs the real git.
+func New() *Client {
+	return &Client{Offline: core.Offline, Exec: func(ctx context.Context, args ...string) ([]byte, error) {
+		out, err := exec.CommandContext(ctx, "git", args...).Output()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return out, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return out, err
+	}}
+}
+
+// Status returns the checked out commit of every repository, in order.
+func (c *Client) Status(ctx context.Context, repos []Repo) ([]Status, error) {
+	statuses := make([]Status, 0, len(repos))
+	for _, r := range repos {
+		head, err := c.head(ctx, r.Dir)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, Status{Repo: r, Current: head})
+	}
+	return statuses, nil
+}
+
+func (c *Client) head(ctx context.Context, dir string) (string, error) {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	out, err := c.Exec(ctx, "-C", dir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to read the commit of %s: %w", dir, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Sync clones the repository when missing and checks out its pinned commit,
+// fetching only when the commit is not already present.
+func (c *Client) Sync(ctx context.Context, r Repo) error {
+	if _, err := os.Stat(filepath.Join(r.Dir, ".git")); errors.Is(err, os.ErrNotExist) {
+		if c.Offline {
+			return core.ErrOffline
+		}
+		if err := os.MkdirAll(filepath.Dir(r.Dir), 0o755); err != nil {
+			return err
+		}
+		if _, err := c.Exec(ctx, "clone", "--quiet", "--no-checkout", r.Plugin.URL(), r.Dir); err != nil {
+			return fmt.Errorf("failed to clone %s: %w", r.Plugin.Repo, err)
+		}
+	}
+
+	if _, err := c.Exec(ctx, "-C", r.Dir, "cat-file", "-e", r.Plugin.Commit+"^{commit}"); err != nil {
+		if c.Offline {
+			return core.ErrOffline
+		}
+		if _, err := c.Exec(ctx, "-C", r.Dir, "fetch", "--quiet", "origin"); err != nil {
+			return fmt.Errorf("failed to fetch %s: %w", r.Plugin.Repo, err)
+		}
+	}
+
+	if _, err := c.Exec(ctx, "-C", r.Dir, "checkout", "--quiet", "--detach", r.Plugin.Commit); err != nil {
+		return fmt.Errorf("failed to check out %s at %s: %w", r.Plugin.Repo, r.Plugin.Commit, err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package shellplugins
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hay-kot/mmdot/internal/core"
+)
+
+// gitRepo creates a repository and returns a function committing a file to
+// it, returning the new commit hash.
+func gitRepo(t *testing.T) (string, func(msg string) string) {
+	t.Helper()
+	dir := t.TempDir()
+
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@example.com",
+			"GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@example.com",
+		)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	git("init", "--quiet")
+	return dir, func(msg string) string {
+		if err := os.WriteFile(filepath.Join(dir, "plugin.zsh"), []byte(msg), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		git("add", ".")
+		git("commit", "--quiet", "-m", msg)
+		return git("rev-parse", "HEAD")
+	}
+}
+
+func TestClient_Sync(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	src, commit := gitRepo(t)
+	first := commit("first")
+	second := commit("second")
+
+	ctx := context.Background()
+	client := New()
+	repo := Repo{Plugin: core.ShellPlugin{Repo: src, Commit: first}, Dir: filepath.Join(t.TempDir(), "owner", "plugin")}
+
+	status := func() Status {
+		t.Helper()
+		statuses, err := client.Status(ctx, []Repo{repo})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return statuses[0]
+	}
+
+	if st := status(); st.Current != "" || st.Ok() {
+		t.Fatalf("Status() before sync = %+v, want not cloned", st)
+	}
+
+	// the last commit is created after the clone so syncing it fetches
+	steps := []struct {
+		name   string
+		commit func() string
+	}{
+		{name: "clone", commit: func() string { return first }},
+		{name: "checkout", commit: func() string { return second }},
+		{name: "fetch", commit: func() string { return commit("third") }},
+	}
+
+	for _, step := range steps {
+		repo.Plugin.Commit = step.commit()
+		if err := client.Sync(ctx, repo); err != nil {
+			t.Fatalf("%s: Sync() error = %v", step.name, err)
+		}
+		if st := status(); !st.Ok() {
+			t.Errorf("%s: Status() = %s, want %s", step.name, st.Current, repo.Plugin.Commit)
+		}
+	}
+}
+
+func TestShellPlugin_Name(t *testing.T) {
+	tests := []struct {
+		repo string
+		want string
+	}{
+		{repo: "zsh-users/zsh-autosuggestions", want: "zsh-users/zsh-autosuggestions"},
+		{repo: "https://gitlab.com/group/plugin.git", want: "group/plugin"},
+		{repo: "git@github.com:owner/plugin.git", want: "owner/plugin"},
+	}
+
+	for _, tt := range tests {
+		if got := (core.ShellPlugin{Repo: tt.repo}).Name(); got != tt.want {
+			t.Errorf("Name(%q) = %q, want %q", tt.repo, got, tt.want)
+		}
+	}
+}
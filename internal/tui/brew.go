@@ -0,0 +1,65 @@
+package tui
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/hay-kot/mmdot/internal/core"
+)
+
+type brewsFetchedMsg struct {
+	installed []string
+	err       error
+}
+
+func (m *Model) fetchInstalledBrews(refresh bool) tea.Cmd {
+	return func() tea.Msg {
+		installed, err := core.LoadInstalledBrews(m.ctx, core.InstalledBrewsOptions{TTL: core.DefaultBrewCacheTTL, Refresh: refresh})
+		return brewsFetchedMsg{installed: installed, err: err}
+	}
+}
+
+func brewRows(cfg *core.ConfigFile, installed []string) []row {
+	names := slices.Sorted(maps.Keys(cfg.Brews))
+
+	rows := make([]row, 0, len(names))
+	for _, name := range names {
+		diff := cfg.Brews.Get(name).DiffInstalled(installed)
+
+		r := row{name: name, status: "in sync", ok: true}
+		if len(diff.Absent) > 0 {
+			r.status = fmt.Sprintf("%d absent", len(diff.Absent))
+			r.ok = false
+		}
+		rows = append(rows, r)
+	}
+
+	return rows
+}
+
+func (m *Model) showBrewDiff(name string) {
+	b := m.cfg.Brews.Get(name)
+	if b == nil {
+		return
+	}
+	diff := b.DiffInstalled(m.installedBrews)
+
+	var sb strings.Builder
+	writeSection := func(title string, items []string, render func(...string) string) {
+		sb.WriteString(nameStyle.Render(fmt.Sprintf("%s (%d)", title, len(items))))
+		sb.WriteString("\n")
+		for _, item := range items {
+			sb.WriteString("  " + render(item) + "\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	writeSection("Absent", diff.Absent, errStyle.Render)
+	writeSection("Present", diff.Present, okStyle.Render)
+	writeSection("Extra", diff.Extra, helpStyle.Render)
+
+	m.setDetail("Brew: "+name, sb.String())
+}
@@ -0,0 +1,172 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/hay-kot/mmdot/internal/core"
+)
+
+type scriptOutputMsg struct {
+	line string
+}
+
+type scriptDoneMsg struct {
+	name string
+	err  error
+}
+
+// scriptRun tracks a script started from the dashboard and streams its
+// combined output into the detail pane.
+type scriptRun struct {
+	name    string
+	running bool
+	output  strings.Builder
+	lines   chan string
+	done    chan error
+}
+
+func scriptRows(cfg *core.ConfigFile) []row {
+	rows := make([]row, 0, len(cfg.Exec.Scripts))
+	for _, script := range cfg.Exec.Scripts {
+		r := row{name: filepath.Base(script.Path), tags: script.Tags, status: "ready", ok: true}
+		if _, err := os.Stat(script.Path); err != nil {
+			r.status = "missing"
+			r.ok = false
+		}
+		rows = append(rows, r)
+	}
+	return rows
+}
+
+func (m *Model) startScript(idx int) tea.Cmd {
+	if m.script != nil && m.script.running {
+		return nil // one script at a time
+	}
+
+	script := m.cfg.Exec.Scripts[idx]
+	run := &scriptRun{
+		name:    filepath.Base(script.Path),
+		running: true,
+		lines:   make(chan string),
+		done:    make(chan error, 1),
+	}
+	m.script = run
+	m.tabs[tabScripts].rows[idx].status = "running"
+	m.setDetail("Output: "+run.name, "")
+
+	if err := os.Chmod(script.Path, 0o755); err != nil {
+		run.done <- err
+		close(run.lines)
+		return run.wait()
+	}
+
+	// The TUI owns the terminal, so privileged scripts cannot prompt for a
+	// password and rely on cached sudo/doas credentials instead.
+	cmd := exec.CommandContext(m.ctx, m.cfg.Exec.Shell, script.Path)
+	if script.Privileged {
+		esc, err := core.FindEscalation()
+		if err != nil {
+			run.done <- err
+			close(run.lines)
+			return run.wait()
+		}
+		cmd = esc.NonInteractive().Command(m.ctx, m.cfg.Exec.Shell, script.Path)
+	}
+
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+	cmd.Dir = m.cfg.ConfigDir
+	cmd.Env = m.cfg.Exec.ScriptEnv(os.Environ())
+
+	if err := cmd.Start(); err != nil {
+		run.done <- err
+		close(run.lines)
+		return run.wait()
+	}
+
+	go func() {
+		err := cmd.Wait()
+		_ = pw.Close()
+		run.done <- err
+	}()
+
+	go func() {
+		defer close(run.lines)
+		streamLines(pr, run.lines)
+	}()
+
+	return run.wait()
+}
+
+// streamLines sends each line read from r to lines until r is exhausted. A
+// line too long for the scanner stops it, so the rest of r is drained to keep
+// the writer from blocking on the pipe.
+func streamLines(r io.Reader, lines chan<- string) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines <- scanner.Text()
+	}
+	if err := scanner.Err(); err != nil {
+		lines <- fmt.Sprintf("[output truncated: %v]", err)
+		_, _ = io.Copy(io.Discard, r)
+	}
+}
+
+// wait returns a command that blocks until the next line of output or, once
+// output is exhausted, the script's exit status.
+func (sr *scriptRun) wait() tea.Cmd {
+	return func() tea.Msg {
+		line, ok := <-sr.lines
+		if ok {
+			return scriptOutputMsg{line: line}
+		}
+		return scriptDoneMsg{name: sr.name, err: <-sr.done}
+	}
+}
+
+func (m *Model) appendScriptOutput(msg scriptOutputMsg) tea.Cmd {
+	if m.script == nil {
+		return nil
+	}
+
+	m.script.output.WriteString(msg.line)
+	m.script.output.WriteString("\n")
+
+	m.detail.SetContent(m.script.output.String())
+	m.detail.GotoBottom()
+
+	return m.script.wait()
+}
+
+func (m *Model) finishScript(msg scriptDoneMsg) {
+	if m.script == nil {
+		return
+	}
+	m.script.running = false
+
+	status, ok := "ok", true
+	footer := okStyle.Render("Script finished successfully")
+	if msg.err != nil {
+		status, ok = "failed", false
+		footer = errStyle.Render(fmt.Sprintf("Script failed: %v", msg.err))
+	}
+
+	for i := range m.tabs[tabScripts].rows {
+		r := &m.tabs[tabScripts].rows[i]
+		if r.name == msg.name && r.status == "running" {
+			r.status, r.ok = status, ok
+		}
+	}
+
+	m.script.output.WriteString("\n" + footer + "\n")
+	m.detail.SetContent(m.script.output.String())
+	m.detail.GotoBottom()
+}
@@ -0,0 +1,43 @@
+package tui
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamLines_DrainsLongLines(t *testing.T) {
+	pr, pw := io.Pipe()
+	lines := make(chan string, 10)
+
+	go func() {
+		defer close(lines)
+		streamLines(pr, lines)
+	}()
+
+	written := make(chan error, 1)
+	go func() {
+		_, err := io.WriteString(pw, "first\n"+strings.Repeat("x", bufio.MaxScanTokenSize+1)+"\nafter\n")
+		_ = pw.Close()
+		written <- err
+	}()
+
+	select {
+	case err := <-written:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("writer blocked after a long line")
+	}
+
+	var got []string
+	for line := range lines {
+		got = append(got, line)
+	}
+	if len(got) != 2 || got[0] != "first" || !strings.HasPrefix(got[1], "[output truncated") {
+		t.Errorf("lines = %q", got)
+	}
+}
@@ -0,0 +1,394 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ErrSelectAborted is returned by [Select] when the user cancels the selection.
+var ErrSelectAborted = errors.New("user aborted")
+
+// SelectItem is an entry offered by [Select].
+type SelectItem struct {
+	Group   string        // Section header, e.g. "Templates"
+	Name    string        // Matched against the query
+	Tags    []string      // Shown as badges and matched by #tag queries
+	Preview func() string // Content for the preview pane, loaded on demand (optional)
+}
+
+var (
+	groupStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#7aa2f7")).Bold(true)
+	badgeStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#1a1b26")).Background(lipgloss.Color("#565f89")).Padding(0, 1)
+	checkedStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#22c55e")).Bold(true)
+	previewBorder = lipgloss.NewStyle().Border(lipgloss.NormalBorder(), false, false, false, true).BorderForeground(lipgloss.Color("#565f89")).PaddingLeft(1)
+)
+
+// Select shows a filterable multi-select list of items grouped under headers
+// with a preview of the highlighted item, and returns the indexes of the
+// chosen items in their original order.
+//
+// Typing filters items by fuzzy name match; words starting with '#' match
+// tags instead. Tab toggles the highlighted item and ctrl+a toggles every
+// visible item, so "#shell" followed by ctrl+a selects everything tagged
+// shell. Enter with nothing toggled selects the highlighted item. Ctrl+o
+// shows the preview of the highlighted item full screen.
+func Select(ctx context.Context, title string, items []SelectItem) ([]int, error) {
+	m := newSelector(title, items)
+
+	final, err := tea.NewProgram(m, tea.WithAltScreen(), tea.WithContext(ctx)).Run()
+	if err != nil {
+		return nil, err
+	}
+
+	m = final.(*selector)
+	if m.aborted {
+		return nil, ErrSelectAborted
+	}
+	return m.chosen(), nil
+}
+
+type selector struct {
+	title    string
+	items    []SelectItem
+	previews map[int]string
+
+	query    textinput.Model
+	visible  []int // indexes into items matching the query
+	cursor   int   // index into visible
+	selected map[int]bool
+
+	zoomed bool // preview shown full screen
+	scroll int  // first preview line shown while zoomed
+
+	done    bool
+	aborted bool
+
+	width  int
+	height int
+}
+
+func newSelector(title string, items []SelectItem) *selector {
+	query := textinput.New()
+	query.Prompt = "> "
+	query.Placeholder = "filter, or #tag"
+	query.Focus()
+
+	m := &selector{
+		title:    title,
+		items:    items,
+		previews: map[int]string{},
+		query:    query,
+		selected: map[int]bool{},
+		width:    80,
+		height:   24,
+	}
+	m.filter()
+	return m
+}
+
+func (m *selector) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m *selector) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		return m, nil
+	case tea.KeyMsg:
+		if m.zoomed {
+			return m.updateZoomed(msg)
+		}
+		switch msg.String() {
+		case "ctrl+o":
+			if len(m.visible) > 0 {
+				m.zoomed = true
+				m.scroll = 0
+			}
+			return m, nil
+		case "ctrl+c", "esc":
+			m.aborted = true
+			return m, tea.Quit
+		case "enter":
+			if len(m.selected) == 0 && len(m.visible) > 0 {
+				m.selected[m.visible[m.cursor]] = true
+			}
+			m.done = true
+			return m, tea.Quit
+		case "up", "ctrl+p", "ctrl+k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+			return m, nil
+		case "down", "ctrl+n", "ctrl+j":
+			if m.cursor < len(m.visible)-1 {
+				m.cursor++
+			}
+			return m, nil
+		case "tab":
+			if len(m.visible) > 0 {
+				m.toggle(m.visible[m.cursor])
+				if m.cursor < len(m.visible)-1 {
+					m.cursor++
+				}
+			}
+			return m, nil
+		case "ctrl+a":
+			m.toggleVisible()
+			return m, nil
+		}
+	}
+
+	prev := m.query.Value()
+	var cmd tea.Cmd
+	m.query, cmd = m.query.Update(msg)
+	if m.query.Value() != prev {
+		m.filter()
+	}
+	return m, cmd
+}
+
+// updateZoomed handles keys while the preview is shown full screen: up and
+// down scroll it, tab toggles the item, and enter confirms as usual.
+func (m *selector) updateZoomed(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		m.aborted = true
+		return m, tea.Quit
+	case "ctrl+o", "esc", "q":
+		m.zoomed = false
+	case "enter":
+		if len(m.selected) == 0 {
+			m.selected[m.visible[m.cursor]] = true
+		}
+		m.done = true
+		return m, tea.Quit
+	case "tab":
+		m.toggle(m.visible[m.cursor])
+	case "up", "ctrl+p", "ctrl+k", "k":
+		m.scroll = max(m.scroll-1, 0)
+	case "down", "ctrl+n", "ctrl+j", "j":
+		lines := strings.Count(m.preview(m.visible[m.cursor]), "\n") + 1
+		m.scroll = min(m.scroll+1, max(lines-m.zoomHeight(), 0))
+	}
+	return m, nil
+}
+
+// zoomHeight is the number of preview lines shown full screen, below the
+// title and above the help line.
+func (m *selector) zoomHeight() int {
+	return max(m.height-3, 3)
+}
+
+func (m *selector) toggle(idx int) {
+	if m.selected[idx] {
+		delete(m.selected, idx)
+	} else {
+		m.selected[idx] = true
+	}
+}
+
+// toggleVisible selects every visible item, or clears them when they are all
+// already selected.
+func (m *selector) toggleVisible() {
+	all := true
+	for _, idx := range m.visible {
+		all = all && m.selected[idx]
+	}
+	for _, idx := range m.visible {
+		if all {
+			delete(m.selected, idx)
+		} else {
+			m.selected[idx] = true
+		}
+	}
+}
+
+func (m *selector) filter() {
+	words := strings.Fields(strings.ToLower(m.query.Value()))
+
+	m.visible = m.visible[:0]
+	for i, item := range m.items {
+		if matchItem(item, words) {
+			m.visible = append(m.visible, i)
+		}
+	}
+	m.cursor = max(min(m.cursor, len(m.visible)-1), 0)
+}
+
+// matchItem reports whether every query word matches item. Words starting
+// with '#' fuzzy match one of the tags; others fuzzy match the name.
+func matchItem(item SelectItem, words []string) bool {
+	for _, word := range words {
+		if tag, ok := strings.CutPrefix(word, "#"); ok {
+			found := false
+			for _, t := range item.Tags {
+				if fuzzyMatch(tag, strings.ToLower(t)) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+			continue
+		}
+
+		if !fuzzyMatch(word, strings.ToLower(item.Name)) {
+			return false
+		}
+	}
+	return true
+}
+
+// fuzzyMatch reports whether the characters of pattern appear in s in order.
+func fuzzyMatch(pattern, s string) bool {
+	for _, r := range pattern {
+		i := strings.IndexRune(s, r)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(string(r)):]
+	}
+	return true
+}
+
+func (m *selector) chosen() []int {
+	var out []int
+	for i := range m.items {
+		if m.selected[i] {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+func (m *selector) preview(idx int) string {
+	if p, ok := m.previews[idx]; ok {
+		return p
+	}
+	p := ""
+	if m.items[idx].Preview != nil {
+		p = m.items[idx].Preview()
+	}
+	m.previews[idx] = p
+	return p
+}
+
+func (m *selector) View() string {
+	if m.done || m.aborted {
+		return ""
+	}
+
+	if m.zoomed {
+		return m.viewZoomed()
+	}
+
+	// title + query + blank + help
+	listHeight := max(m.height-4, 3)
+	listWidth := max(m.width/2, 30)
+
+	var sb strings.Builder
+	sb.WriteString(nameStyle.Bold(true).Render(m.title))
+	sb.WriteString("\n")
+	sb.WriteString(m.query.View())
+	sb.WriteString("\n\n")
+
+	list := m.viewList(listHeight)
+	pane := ""
+	if len(m.visible) > 0 && m.width > listWidth+10 {
+		pane = previewBorder.
+			Width(m.width - listWidth - 2).
+			Height(listHeight).
+			MaxHeight(listHeight).
+			Render(clipLines(m.preview(m.visible[m.cursor]), listHeight))
+	}
+
+	sb.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, lipgloss.NewStyle().Width(listWidth).Render(list), pane))
+	sb.WriteString("\n")
+	sb.WriteString(helpStyle.Render(fmt.Sprintf("%d/%d selected • ↑/↓ move • tab toggle • ctrl+a toggle visible • ctrl+o full preview • #tag filter tags • enter confirm • esc cancel",
+		len(m.selected), len(m.items))))
+
+	return sb.String()
+}
+
+func (m *selector) viewZoomed() string {
+	idx := m.visible[m.cursor]
+
+	check := "[ ] "
+	if m.selected[idx] {
+		check = checkedStyle.Render("[x] ")
+	}
+
+	lines := strings.Split(m.preview(idx), "\n")
+	lines = lines[min(m.scroll, len(lines)):]
+
+	var sb strings.Builder
+	sb.WriteString(check + nameStyle.Bold(true).Render(m.items[idx].Name))
+	sb.WriteString("\n\n")
+	sb.WriteString(clipLines(strings.Join(lines, "\n"), m.zoomHeight()))
+	sb.WriteString("\n")
+	sb.WriteString(helpStyle.Render("↑/↓ scroll • tab toggle • enter confirm • esc back"))
+
+	return sb.String()
+}
+
+func (m *selector) viewList(height int) string {
+	if len(m.visible) == 0 {
+		return helpStyle.Render("  no matches")
+	}
+
+	// Render every visible row with its group header, then scroll so the
+	// cursor line stays on screen
+	var lines []string
+	cursorLine := 0
+	group := ""
+	for i, idx := range m.visible {
+		item := m.items[idx]
+		if i == 0 || item.Group != group {
+			group = item.Group
+			lines = append(lines, groupStyle.Render(group))
+		}
+
+		prefix := "  "
+		if i == m.cursor {
+			prefix = cursorStyle.Render("> ")
+			cursorLine = len(lines)
+		}
+
+		check := "[ ] "
+		if m.selected[idx] {
+			check = checkedStyle.Render("[x] ")
+		}
+
+		badges := make([]string, len(item.Tags))
+		for j, tag := range item.Tags {
+			badges[j] = badgeStyle.Render(tag)
+		}
+
+		line := prefix + check + nameStyle.Render(item.Name)
+		if len(badges) > 0 {
+			line += " " + strings.Join(badges, " ")
+		}
+		lines = append(lines, line)
+	}
+
+	start := max(cursorLine-height+1, 0)
+	end := min(start+height, len(lines))
+	return strings.Join(lines[start:end], "\n")
+}
+
+// clipLines returns at most n lines of s.
+func clipLines(s string, n int) string {
+	lines := strings.Split(s, "\n")
+	if len(lines) > n {
+		lines = lines[:n]
+	}
+	return strings.Join(lines, "\n")
+}
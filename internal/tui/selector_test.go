@@ -0,0 +1,92 @@
+package tui
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestSelector(t *testing.T) {
+	items := []SelectItem{
+		{Group: "Templates", Name: "zshrc", Tags: []string{"shell"}},
+		{Group: "Templates", Name: "starship", Tags: []string{"shell", "prompt"}},
+		{Group: "Scripts", Name: "brew-bundle.sh", Tags: []string{"brew"}},
+	}
+
+	typeText := func(m *selector, s string) {
+		for _, r := range s {
+			m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		keys  []tea.KeyType
+		want  []int
+	}{
+		{name: "enter selects highlighted", keys: []tea.KeyType{tea.KeyEnter}, want: []int{0}},
+		{name: "fuzzy name", query: "stshp", keys: []tea.KeyType{tea.KeyEnter}, want: []int{1}},
+		{name: "select all by tag", query: "#shell", keys: []tea.KeyType{tea.KeyCtrlA, tea.KeyEnter}, want: []int{0, 1}},
+		{name: "tag and name", query: "#shell star", keys: []tea.KeyType{tea.KeyCtrlA, tea.KeyEnter}, want: []int{1}},
+		{name: "tab toggles and advances", keys: []tea.KeyType{tea.KeyDown, tea.KeyTab, tea.KeyTab, tea.KeyEnter}, want: []int{1, 2}},
+		{name: "no matches", query: "tmux", keys: []tea.KeyType{tea.KeyEnter}},
+		{name: "toggle in full preview", keys: []tea.KeyType{tea.KeyDown, tea.KeyCtrlO, tea.KeyTab, tea.KeyEsc, tea.KeyEnter}, want: []int{1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newSelector("test", items)
+			typeText(m, tt.query)
+			if m.View() == "" {
+				t.Error("View() rendered nothing")
+			}
+			for _, k := range tt.keys {
+				m.Update(tea.KeyMsg{Type: k})
+			}
+
+			if !m.done {
+				t.Fatal("selector not done after enter")
+			}
+			if got := m.chosen(); !slices.Equal(got, tt.want) {
+				t.Errorf("chosen() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelector_Abort(t *testing.T) {
+	m := newSelector("test", []SelectItem{{Name: "zshrc"}})
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+
+	if !m.aborted {
+		t.Error("esc did not abort")
+	}
+}
+
+func TestSelector_Zoom(t *testing.T) {
+	m := newSelector("test", []SelectItem{{Name: "brew.sh", Preview: func() string { return "line 1\nline 2\nline 3\nline 4\nline 5" }}})
+	m.height = 6 // three preview lines fit
+
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlO})
+	if !m.zoomed {
+		t.Fatal("ctrl+o did not show the full preview")
+	}
+	if view := m.View(); !strings.Contains(view, "line 1") || strings.Contains(view, "line 4") {
+		t.Errorf("View() = %q, want the first three preview lines", view)
+	}
+
+	for range 3 { // one more than needed, scrolling stops at the end
+		m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	}
+	if view := m.View(); strings.Contains(view, "line 2") || !strings.Contains(view, "line 5") {
+		t.Errorf("View() after scrolling = %q, want the last three preview lines", view)
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.zoomed || m.aborted {
+		t.Error("esc in the full preview should return to the list")
+	}
+}
@@ -0,0 +1,116 @@
+package tui
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/hay-kot/mmdot/internal/generator"
+	"github.com/hay-kot/mmdot/pkgs/linediff"
+	"github.com/hay-kot/mmdot/pkgs/redact"
+)
+
+type templatesCheckedMsg struct {
+	rows []row
+	err  error
+}
+
+type templateRenderedMsg struct {
+	name   string
+	output string
+	err    error
+}
+
+type templateDiffMsg struct {
+	name   string
+	output string
+	result generator.CheckResult
+	err    error
+}
+
+var (
+	insertStyle = okStyle
+	deleteStyle = errStyle
+)
+
+// checkTemplates renders every template in memory and reports whether its
+// output on disk is current, changed, or missing.
+func (m *Model) checkTemplates() tea.Cmd {
+	return func() tea.Msg {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		rows := make([]row, 0, len(m.cfg.Templates))
+		for _, tmpl := range m.cfg.Templates {
+			r := row{name: tmpl.Name, tags: tmpl.Tags}
+
+			result, err := m.engine.Check(m.ctx, tmpl)
+			switch {
+			case err != nil:
+				r.status = "error"
+			case result.Status == generator.StatusCurrent:
+				r.status = string(result.Status)
+				r.ok = true
+			default:
+				r.status = string(result.Status)
+			}
+
+			rows = append(rows, r)
+		}
+
+		return templatesCheckedMsg{rows: rows}
+	}
+}
+
+func (m *Model) renderTemplate(idx int) tea.Cmd {
+	tmpl := m.cfg.Templates[idx]
+	return func() tea.Msg {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		err := m.engine.RenderTemplate(m.ctx, tmpl)
+		return templateRenderedMsg{name: tmpl.Name, output: tmpl.Output, err: err}
+	}
+}
+
+// diffTemplate renders a template in the background and reports how its
+// output differs from the file on disk.
+func (m *Model) diffTemplate(idx int) tea.Cmd {
+	tmpl := m.cfg.Templates[idx]
+	return func() tea.Msg {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		result, err := m.engine.Check(m.ctx, tmpl)
+		return templateDiffMsg{name: tmpl.Name, output: tmpl.Output, result: result, err: err}
+	}
+}
+
+func (m *Model) showTemplateDiff(msg templateDiffMsg) {
+	if msg.err != nil {
+		m.setDetail("Error: "+msg.name, errStyle.Render(msg.err.Error()))
+		return
+	}
+
+	if msg.result.Status == generator.StatusCurrent {
+		m.setDetail("Diff: "+msg.name, okStyle.Render("Output is up to date"))
+		return
+	}
+
+	m.setDetail("Diff: "+msg.name+" ("+msg.output+")", renderDiff(linediff.Diff(string(msg.result.Existing), string(msg.result.Rendered))))
+}
+
+func renderDiff(lines []linediff.Line) string {
+	var sb strings.Builder
+	for _, l := range lines {
+		switch l.Op {
+		case linediff.OpInsert:
+			sb.WriteString(insertStyle.Render(redact.String(l.String())))
+		case linediff.OpDelete:
+			sb.WriteString(deleteStyle.Render(redact.String(l.String())))
+		default:
+			sb.WriteString(helpStyle.Render(redact.String(l.String())))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,329 @@
+// Package tui implements the interactive dashboard for mmdot. It shows the
+// status of templates, scripts, and brew configs and allows running them.
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/generator"
+)
+
+type tabKind int
+
+const (
+	tabTemplates tabKind = iota
+	tabScripts
+	tabBrew
+)
+
+var tabTitles = []string{"Templates", "Scripts", "Brew"}
+
+// row is a single selectable line in a tab.
+type row struct {
+	name   string
+	tags   []string
+	status string
+	ok     bool
+}
+
+type tab struct {
+	rows    []row
+	cursor  int
+	loading bool
+	err     error
+}
+
+var (
+	activeTabStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#7aa2f7")).Bold(true).Underline(true).Padding(0, 1)
+	inactiveTabStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#565f89")).Padding(0, 1)
+	cursorStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("#bb9af7")).Bold(true)
+	nameStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("#c0caf5"))
+	tagStyle         = lipgloss.NewStyle().Foreground(lipgloss.Color("#565f89")).Italic(true)
+	okStyle          = lipgloss.NewStyle().Foreground(lipgloss.Color("#22c55e"))
+	warnStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("#e0af68"))
+	errStyle         = lipgloss.NewStyle().Foreground(lipgloss.Color("#d75f6b"))
+	helpStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("#565f89"))
+	dividerStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#565f89"))
+)
+
+// Model is the root bubbletea model for the dashboard.
+type Model struct {
+	ctx    context.Context
+	cfg    *core.ConfigFile
+	engine *generator.Engine
+	mu     sync.Mutex // guards engine, which is used from command goroutines
+
+	tabs   [3]tab
+	active tabKind
+
+	detail      viewport.Model
+	detailTitle string
+
+	installedBrews []string
+	script         *scriptRun
+
+	width  int
+	height int
+}
+
+func New(ctx context.Context, cfg *core.ConfigFile) *Model {
+	m := &Model{
+		ctx:    ctx,
+		cfg:    cfg,
+		engine: generator.NewEngine(cfg),
+		detail: viewport.New(80, 10),
+	}
+
+	m.tabs[tabScripts].rows = scriptRows(cfg)
+	m.tabs[tabTemplates].loading = true
+	m.tabs[tabBrew].loading = true
+
+	return m
+}
+
+// Run starts the dashboard and blocks until the user quits.
+func Run(ctx context.Context, cfg *core.ConfigFile) error {
+	_, err := tea.NewProgram(New(ctx, cfg), tea.WithAltScreen(), tea.WithContext(ctx)).Run()
+	return err
+}
+
+func (m *Model) Init() tea.Cmd {
+	return tea.Batch(m.checkTemplates(), m.fetchInstalledBrews(false))
+}
+
+func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.resize()
+		return m, nil
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	case templatesCheckedMsg:
+		m.tabs[tabTemplates] = tab{rows: msg.rows, cursor: m.tabs[tabTemplates].cursor, err: msg.err}
+		m.clampCursor()
+		return m, nil
+	case templateRenderedMsg:
+		if msg.err != nil {
+			m.setDetail("Error: "+msg.name, errStyle.Render(msg.err.Error()))
+		} else {
+			m.setDetail("Rendered: "+msg.name, okStyle.Render("Wrote "+msg.output))
+		}
+		return m, m.checkTemplates()
+	case templateDiffMsg:
+		m.showTemplateDiff(msg)
+		return m, nil
+	case brewsFetchedMsg:
+		m.installedBrews = msg.installed
+		m.tabs[tabBrew] = tab{rows: brewRows(m.cfg, msg.installed), cursor: m.tabs[tabBrew].cursor, err: msg.err}
+		m.clampCursor()
+		return m, nil
+	case scriptOutputMsg:
+		return m, m.appendScriptOutput(msg)
+	case scriptDoneMsg:
+		m.finishScript(msg)
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.detail, cmd = m.detail.Update(msg)
+	return m, cmd
+}
+
+func (m *Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	t := &m.tabs[m.active]
+
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "tab", "right", "l":
+		m.active = (m.active + 1) % tabKind(len(m.tabs))
+		m.clearDetail()
+	case "shift+tab", "left", "h":
+		m.active = (m.active + tabKind(len(m.tabs)) - 1) % tabKind(len(m.tabs))
+		m.clearDetail()
+	case "up", "k":
+		if t.cursor > 0 {
+			t.cursor--
+		}
+	case "down", "j":
+		if t.cursor < len(t.rows)-1 {
+			t.cursor++
+		}
+	case "r":
+		switch m.active {
+		case tabTemplates:
+			t.loading = true
+			return m, m.checkTemplates()
+		case tabBrew:
+			t.loading = true
+			return m, m.fetchInstalledBrews(true)
+		}
+	case "esc":
+		m.clearDetail()
+	case "d":
+		if len(t.rows) == 0 {
+			return m, nil
+		}
+		switch m.active {
+		case tabTemplates:
+			m.setDetail("Diff: "+t.rows[t.cursor].name, helpStyle.Render("Rendering..."))
+			return m, m.diffTemplate(t.cursor)
+		case tabBrew:
+			m.showBrewDiff(t.rows[t.cursor].name)
+		}
+	case "enter":
+		if len(t.rows) == 0 {
+			return m, nil
+		}
+		switch m.active {
+		case tabTemplates:
+			return m, m.renderTemplate(t.cursor)
+		case tabScripts:
+			return m, m.startScript(t.cursor)
+		case tabBrew:
+			m.showBrewDiff(t.rows[t.cursor].name)
+		}
+	default:
+		var cmd tea.Cmd
+		m.detail, cmd = m.detail.Update(msg)
+		return m, cmd
+	}
+
+	return m, nil
+}
+
+func (m *Model) clampCursor() {
+	for i := range m.tabs {
+		t := &m.tabs[i]
+		t.cursor = max(min(t.cursor, len(t.rows)-1), 0)
+	}
+}
+
+func (m *Model) setDetail(title, content string) {
+	m.detailTitle = title
+	m.detail.SetContent(content)
+	m.detail.GotoTop()
+}
+
+func (m *Model) clearDetail() {
+	if m.script != nil && m.script.running {
+		return // keep streaming output visible while a script runs
+	}
+	m.detailTitle = ""
+	m.detail.SetContent("")
+}
+
+// listHeight is the number of rows reserved for the item list.
+func (m *Model) listHeight() int {
+	return max(m.height/3, 3)
+}
+
+func (m *Model) resize() {
+	// tabs + divider + list + divider + detail title + help
+	m.detail.Width = m.width
+	m.detail.Height = max(m.height-m.listHeight()-5, 1)
+}
+
+func (m *Model) View() string {
+	var sb strings.Builder
+
+	// Tab bar
+	titles := make([]string, len(tabTitles))
+	for i, title := range tabTitles {
+		if tabKind(i) == m.active {
+			titles[i] = activeTabStyle.Render(title)
+		} else {
+			titles[i] = inactiveTabStyle.Render(title)
+		}
+	}
+	sb.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, titles...))
+	sb.WriteString("\n")
+	sb.WriteString(dividerStyle.Render(strings.Repeat("─", max(m.width, 1))))
+	sb.WriteString("\n")
+
+	sb.WriteString(m.viewList())
+
+	sb.WriteString(dividerStyle.Render(strings.Repeat("─", max(m.width, 1))))
+	sb.WriteString("\n")
+	sb.WriteString(nameStyle.Render(m.detailTitle))
+	sb.WriteString("\n")
+	sb.WriteString(m.detail.View())
+	sb.WriteString("\n")
+	sb.WriteString(helpStyle.Render(m.help()))
+
+	return sb.String()
+}
+
+func (m *Model) viewList() string {
+	t := m.tabs[m.active]
+	height := m.listHeight()
+
+	var lines []string
+	switch {
+	case t.loading:
+		lines = append(lines, helpStyle.Render("  loading..."))
+	case t.err != nil:
+		lines = append(lines, errStyle.Render("  "+t.err.Error()))
+	case len(t.rows) == 0:
+		lines = append(lines, helpStyle.Render("  nothing configured"))
+	}
+
+	if !t.loading {
+		nameWidth := 0
+		for _, r := range t.rows {
+			nameWidth = max(nameWidth, len(r.name))
+		}
+
+		// Scroll the list so the cursor stays visible
+		start := max(t.cursor-height+1, 0)
+		for i := start; i < len(t.rows) && len(lines) < height; i++ {
+			r := t.rows[i]
+
+			prefix := "  "
+			if i == t.cursor {
+				prefix = cursorStyle.Render("> ")
+			}
+
+			status := okStyle.Render(r.status)
+			if !r.ok {
+				status = warnStyle.Render(r.status)
+			}
+
+			tags := ""
+			if len(r.tags) > 0 {
+				tags = " " + tagStyle.Render("("+strings.Join(r.tags, ", ")+")")
+			}
+
+			padding := strings.Repeat(" ", nameWidth-len(r.name))
+			lines = append(lines, fmt.Sprintf("%s%s%s  %s%s", prefix, nameStyle.Render(r.name), padding, status, tags))
+		}
+	}
+
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func (m *Model) help() string {
+	keys := []string{"←/→ tabs", "↑/↓ select"}
+	switch m.active {
+	case tabTemplates:
+		keys = append(keys, "enter render", "d diff", "r refresh")
+	case tabScripts:
+		keys = append(keys, "enter run")
+	case tabBrew:
+		keys = append(keys, "enter/d diff", "r refresh")
+	}
+	keys = append(keys, "pgup/pgdn scroll", "esc clear", "q quit")
+	return strings.Join(keys, " • ")
+}
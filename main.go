@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"time"
 
@@ -14,6 +16,7 @@ import (
 	"github.com/hay-kot/mmdot/internal/core"
 	"github.com/hay-kot/mmdot/pkgs/cll"
 	"github.com/hay-kot/mmdot/pkgs/printer"
+	"github.com/hay-kot/mmdot/pkgs/redact"
 )
 
 var (
@@ -37,11 +40,16 @@ func build() string {
 func main() {
 	flags := &core.Flags{}
 
-	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: redact.Writer(os.Stderr)})
+	http.DefaultClient.Transport = core.NewNetTransport(http.DefaultTransport)
 
 	var (
 		ctx    = context.Background()
 		writer = printer.NewDeferedWriter(os.Stdout)
+
+		// set by --timeout, nil when the command has no deadline
+		timeoutCtx context.Context
+		cancel     = context.CancelFunc(func() {})
 	)
 
 	ctx = printer.WithWriter(ctx, writer)
@@ -52,6 +60,7 @@ func main() {
 		Name:                  "mmdot",
 		Usage:                 `A tiny and terrible dotfiles utility for managing my machines. Probably don't use this.`,
 		Version:               build(),
+		Description:           commands.ExitCodesHelp,
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:        "log-level",
@@ -61,14 +70,54 @@ func main() {
 				Sources:     envvars("LOG_LEVEL"),
 				Destination: &flags.LogLevel,
 			},
-			&cli.StringFlag{
+			&cli.StringSliceFlag{
 				Name:        "config",
 				Aliases:     []string{"c"},
-				Usage:       "path to the mmdot configuration file",
+				Usage:       "path to the mmdot configuration file; repeat to stack overlays on a base config",
 				Required:    false,
-				Value:       "mmdot.yml",
+				Value:       []string{"mmdot.yml"},
 				Sources:     envvars("CONFIG_PATH"),
-				Destination: &flags.ConfigFilePath,
+				Destination: &flags.ConfigFilePaths,
+				// Runs only for --config on the command line, after Before has
+				// replaced the paths with the workspace config. A path from
+				// MMDOT_CONFIG_PATH gives way to --workspace instead.
+				Action: func(_ context.Context, _ *cli.Command, _ []string) error {
+					if flags.Workspace != "" {
+						return fmt.Errorf("--workspace and --config cannot be combined")
+					}
+					return nil
+				},
+			},
+			&cli.StringFlag{
+				Name:        "workspace",
+				Aliases:     []string{"w"},
+				Usage:       "use the config of a workspace registered with `mmdot workspace add` instead of --config",
+				Sources:     envvars("WORKSPACE"),
+				Destination: &flags.Workspace,
+			},
+			&cli.BoolFlag{
+				Name:        "ci",
+				Usage:       "non-interactive mode for CI: group run output with ::group:: markers, keep going after failures, and write a JSON summary",
+				Sources:     envvars("CI"),
+				Destination: &core.CI,
+			},
+			&cli.BoolFlag{
+				Name:        "offline",
+				Usage:       "skip downloads, git fetches, brew and editor extension changes, and notifications, reporting them as skipped; scripts, hooks, and secret providers still run",
+				Sources:     envvars("OFFLINE"),
+				Destination: &core.Offline,
+			},
+			&cli.DurationFlag{
+				Name:        "timeout",
+				Usage:       "abort the command after this long (e.g. 10m), stopping running scripts, brew, and downloads; 0 waits forever",
+				Sources:     envvars("TIMEOUT"),
+				Destination: &flags.Timeout,
+			},
+			&cli.BoolFlag{
+				Name:        "reveal-secrets",
+				Usage:       "print values from vault files and !age config values in logs and output instead of «redacted:key»",
+				Sources:     envvars("REVEAL_SECRETS"),
+				Destination: &redact.Reveal,
 			},
 		},
 		Before: func(ctx context.Context, c *cli.Command) (context.Context, error) {
@@ -79,9 +128,36 @@ func main() {
 
 			log.Logger = log.Level(level)
 
+			if flags.Workspace != "" {
+				workspaces, err := core.LoadWorkspaces()
+				if err != nil {
+					return ctx, err
+				}
+				path, err := workspaces.Config(flags.Workspace)
+				if err != nil {
+					return ctx, err
+				}
+				flags.ConfigFilePaths = []string{path}
+			}
+
+			if len(flags.ConfigFilePaths) == 0 {
+				return ctx, fmt.Errorf("at least one --config is required")
+			}
+			flags.ConfigFilePath = flags.ConfigFilePaths[0]
+
+			if flags.Timeout > 0 {
+				ctx, cancel = context.WithTimeoutCause(ctx, flags.Timeout, fmt.Errorf("timed out after %s", flags.Timeout))
+				timeoutCtx = ctx
+			}
+
 			log.Debug().
 				Str("log-level", flags.LogLevel).
-				Str("config", flags.ConfigFilePath).
+				Strs("config", flags.ConfigFilePaths).
+				Str("workspace", flags.Workspace).
+				Bool("ci", core.CI).
+				Bool("offline", core.Offline).
+				Dur("timeout", flags.Timeout).
+				Bool("reveal-secrets", redact.Reveal).
 				Msg("global flags")
 
 			return ctx, nil
@@ -92,21 +168,57 @@ func main() {
 	}
 
 	app = cll.Register(app,
-		commands.NewScriptsCmd(flags),
+		commands.NewRunCmd(flags),
+		commands.NewAliasCmd(flags),
+		commands.NewApplyCmd(flags),
+		commands.NewBinariesCmd(flags),
 		commands.NewBrewCmd(flags),
+		commands.NewBundleCmd(flags),
+		commands.NewCleanCmd(flags),
+		commands.NewConfigCmd(flags),
+		commands.NewDaemonCmd(flags),
+		commands.NewDoctorCmd(flags),
+		commands.NewEditorsCmd(flags),
 		commands.NewEncryptCmd(flags),
+		commands.NewFontsCmd(flags),
+		commands.NewGenerateCmd(flags),
+		commands.NewGitCmd(flags),
+		commands.NewGPGCmd(flags),
 		commands.NewHookCmd(flags),
 		commands.NewLLMTextCmd(flags),
+		commands.NewMacOSCmd(flags),
+		commands.NewPlanCmd(flags),
+		commands.NewReposCmd(flags),
+		commands.NewScheduleCmd(flags),
+		commands.NewScriptsCmd(flags),
+		commands.NewServicesCmd(flags),
+		commands.NewShellCmd(flags),
+		commands.NewStatusCmd(flags),
+		commands.NewTemplatesCmd(flags),
+		commands.NewTUICmd(flags),
+		commands.NewWhichCmd(flags),
+		commands.NewWorkspaceCmd(flags),
+		// links examples from the help of the commands above
+		commands.NewExamplesCmd(flags),
 	)
 
 	exitCode := 0
-	if err := app.Run(context.Background(), os.Args); err != nil {
-		printer.Ctx(ctx).FatalError(err)
-		exitCode = 1
+	err := app.Run(context.Background(), os.Args)
+	cancel()
+	if err != nil && timeoutCtx != nil && errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+		err = printer.WithTitle("Command timed out",
+			fmt.Errorf("%w: %w", context.Cause(timeoutCtx), err),
+			"raise --timeout or MMDOT_TIMEOUT, or pass --timeout 0 to wait forever",
+		)
 	}
-
-	err := writer.Flush()
 	if err != nil {
+		if !commands.IsSilent(err) {
+			printer.Ctx(ctx).FatalError(err)
+		}
+		exitCode = commands.ExitCodeOf(err)
+	}
+
+	if err := writer.Flush(); err != nil {
 		panic(err)
 	}
 	os.Exit(exitCode)
@@ -0,0 +1,140 @@
+// Package atomicwrite writes files through a temporary file in the target
+// directory that is renamed over the destination, so a crash or a full disk
+// leaves either the old or the new content and never a truncated file.
+package atomicwrite
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// tempPattern names the temporary files, which `mmdot clean` removes when a
+// write was interrupted before the rename.
+const tempPattern = ".mmdot-*"
+
+// WriteFile writes data to path with the permissions perm. When path is a
+// symlink the file it points to is replaced and the link is kept.
+func WriteFile(path string, data []byte, perm fs.FileMode) error {
+	return write(path, data, perm, "")
+}
+
+// WriteFileBackup is WriteFile, but an existing file at path is first kept
+// as backup, replacing an older backup.
+func WriteFileBackup(path string, data []byte, perm fs.FileMode, backup string) error {
+	return write(path, data, perm, backup)
+}
+
+func write(path string, data []byte, perm fs.FileMode, backup string) (err error) {
+	target, err := resolve(path)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), tempPattern)
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	// set explicitly, CreateTemp uses 0600 and the umask applies to neither
+	if err = os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+
+	if backup != "" {
+		if err = copyFile(target, backup); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+	}
+
+	if err = os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	syncDir(filepath.Dir(target))
+	return nil
+}
+
+// resolve follows symlinks at path to the file that is written. A path or
+// link target that does not exist yet is returned as is.
+func resolve(path string) (string, error) {
+	for range 255 {
+		info, err := os.Lstat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return path, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			return path, nil
+		}
+
+		dest, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(dest) {
+			dest = filepath.Join(filepath.Dir(path), dest)
+		}
+		path = dest
+	}
+	return "", fmt.Errorf("%s: too many levels of symbolic links", path)
+}
+
+// copyFile copies src to dst with the permissions of src. A missing src is
+// not an error.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// syncDir flushes the rename to disk. Not every platform supports syncing a
+// directory, so failures are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	_ = d.Close()
+}
@@ -0,0 +1,96 @@
+package fcrypt
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+)
+
+func benchIdentity(b *testing.B) *age.X25519Identity {
+	b.Helper()
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		b.Fatal(err)
+	}
+	return id
+}
+
+func BenchmarkEncryptReader(b *testing.B) {
+	recipients := []age.Recipient{benchIdentity(b).Recipient()}
+
+	for _, size := range []int{256, 64 * 1024, 1024 * 1024} {
+		plaintext := bytes.Repeat([]byte("x"), size)
+		b.Run(fmt.Sprintf("%dB", size), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+			for b.Loop() {
+				if err := EncryptReader(bytes.NewReader(plaintext), io.Discard, recipients); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDecryptReader(b *testing.B) {
+	id := benchIdentity(b)
+
+	for _, size := range []int{256, 64 * 1024, 1024 * 1024} {
+		var ciphertext bytes.Buffer
+		if err := EncryptReader(bytes.NewReader(bytes.Repeat([]byte("x"), size)), &ciphertext, []age.Recipient{id.Recipient()}); err != nil {
+			b.Fatal(err)
+		}
+		b.Run(fmt.Sprintf("%dB", size), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+			for b.Loop() {
+				if err := DecryptReader(bytes.NewReader(ciphertext.Bytes()), io.Discard, id); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkEncryptDecryptFile round-trips a small file, the common case for
+// vault files.
+func BenchmarkEncryptDecryptFile(b *testing.B) {
+	id := benchIdentity(b)
+	recipients := []age.Recipient{id.Recipient()}
+	dir := b.TempDir()
+	plain := filepath.Join(dir, "vars.yml")
+	encrypted := plain + ".age"
+	content := bytes.Repeat([]byte("token: s3cret\n"), 32)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := os.WriteFile(plain, content, 0o600); err != nil {
+			b.Fatal(err)
+		}
+		if err := EncryptFile(plain, encrypted, recipients); err != nil {
+			b.Fatal(err)
+		}
+		if err := DecryptFile(encrypted, plain, id); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkIsEncryptedFile(b *testing.B) {
+	path := filepath.Join(b.TempDir(), "vars.yml")
+	if err := os.WriteFile(path, bytes.Repeat([]byte("token: s3cret\n"), 1024), 0o600); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := IsEncryptedFile(path); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package fcrypt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+
+	"filippo.io/age"
+)
+
+// SharedCache is the process-wide decryption cache. Commands that run several
+// phases over the same encrypted files should decrypt through this cache so
+// slow identities (e.g. hardware-backed plugins) are only invoked once per file.
+var SharedCache = NewCache()
+
+// Cache memoizes decrypted plaintext keyed by the identity and the SHA-256
+// hash of the ciphertext. Keying on content rather than path means an edited
+// or re-encrypted file is always decrypted again, and keying on the identity
+// means a cached plaintext is never returned to an identity that could not
+// decrypt the file itself.
+type Cache struct {
+	mu      sync.Mutex
+	entries map[cacheKey][]byte
+}
+
+type cacheKey struct {
+	identity any
+	sum      [sha256.Size]byte
+}
+
+func NewCache() *Cache {
+	return &Cache{
+		entries: map[cacheKey][]byte{},
+	}
+}
+
+// CacheKeyer is implemented by identities that wrap others, such as a chain
+// of keys, to name the keys they decrypt with. Identities returning the same
+// key must be able to decrypt the same files.
+type CacheKeyer interface {
+	CacheKey() string
+}
+
+// identityKey returns the key entries decrypted with identity are cached
+// under. X25519 identities are keyed by their recipient so a key read again
+// from disk still hits the cache. Identities that cannot be told apart, which
+// are neither a [CacheKeyer] nor a pointer, are not cached.
+func identityKey(identity age.Identity) (any, bool) {
+	switch id := identity.(type) {
+	case CacheKeyer:
+		return "keyer:" + id.CacheKey(), true
+	case *age.X25519Identity:
+		return "x25519:" + id.Recipient().String(), true
+	}
+	if identity != nil && reflect.TypeOf(identity).Kind() == reflect.Pointer {
+		return identity, true
+	}
+	return nil, false
+}
+
+// DecryptFile reads the encrypted file at path and returns its plaintext,
+// using a cached result when the ciphertext has been decrypted before.
+func (c *Cache) DecryptFile(path string, identity age.Identity) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encrypted file: %w", err)
+	}
+
+	return c.Decrypt(data, identity)
+}
+
+// Decrypt returns the plaintext for the given ciphertext, decrypting and
+// storing it on a cache miss. The returned slice is a copy and may be modified
+// by the caller.
+func (c *Cache) Decrypt(ciphertext []byte, identity age.Identity) ([]byte, error) {
+	id, ok := identityKey(identity)
+	if !ok {
+		var buff bytes.Buffer
+		if err := DecryptReader(bytes.NewReader(ciphertext), &buff, identity); err != nil {
+			return nil, err
+		}
+		return buff.Bytes(), nil
+	}
+	key := cacheKey{identity: id, sum: sha256.Sum256(ciphertext)}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if plaintext, ok := c.entries[key]; ok {
+		return bytes.Clone(plaintext), nil
+	}
+
+	// the plaintext is never larger than the ciphertext
+	buff := bytes.NewBuffer(make([]byte, 0, len(ciphertext)))
+	if err := DecryptReader(bytes.NewReader(ciphertext), buff, identity); err != nil {
+		return nil, err
+	}
+
+	c.entries[key] = buff.Bytes()
+	return bytes.Clone(buff.Bytes()), nil
+}
+
+// Len returns the number of cached entries.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Clear drops all cached plaintext.
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
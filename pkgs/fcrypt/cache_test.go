@@ -0,0 +1,118 @@
+package fcrypt
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+)
+
+// countingIdentity wraps an identity and records how many times Unwrap is called.
+type countingIdentity struct {
+	age.Identity
+	calls int
+}
+
+func (ci *countingIdentity) Unwrap(stanzas []*age.Stanza) ([]byte, error) {
+	ci.calls++
+	return ci.Identity.Unwrap(stanzas)
+}
+
+func TestCache_DecryptFile(t *testing.T) {
+	const plaintext = "key: value\n"
+
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("generate identity: %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "vars.yml.age")
+
+	var encrypted bytes.Buffer
+	if err := EncryptReader(bytes.NewBufferString(plaintext), &encrypted, []age.Recipient{id.Recipient()}); err != nil {
+		t.Fatalf("EncryptReader: %v", err)
+	}
+	if err := os.WriteFile(path, encrypted.Bytes(), 0o600); err != nil {
+		t.Fatalf("write encrypted: %v", err)
+	}
+
+	counting := &countingIdentity{Identity: id}
+	cache := NewCache()
+
+	for i := range 3 {
+		got, err := cache.DecryptFile(path, counting)
+		if err != nil {
+			t.Fatalf("DecryptFile call %d: %v", i, err)
+		}
+		if string(got) != plaintext {
+			t.Errorf("call %d: got %q, want %q", i, got, plaintext)
+		}
+	}
+
+	if counting.calls != 1 {
+		t.Errorf("identity unwrapped %d times, want 1", counting.calls)
+	}
+	if cache.Len() != 1 {
+		t.Errorf("cache has %d entries, want 1", cache.Len())
+	}
+
+	// Changing the ciphertext must bypass the cached entry.
+	encrypted.Reset()
+	if err := EncryptReader(bytes.NewBufferString("other: value\n"), &encrypted, []age.Recipient{id.Recipient()}); err != nil {
+		t.Fatalf("EncryptReader: %v", err)
+	}
+	if err := os.WriteFile(path, encrypted.Bytes(), 0o600); err != nil {
+		t.Fatalf("rewrite encrypted: %v", err)
+	}
+
+	got, err := cache.DecryptFile(path, counting)
+	if err != nil {
+		t.Fatalf("DecryptFile after rewrite: %v", err)
+	}
+	if string(got) != "other: value\n" {
+		t.Errorf("after rewrite: got %q", got)
+	}
+	if counting.calls != 2 {
+		t.Errorf("identity unwrapped %d times after rewrite, want 2", counting.calls)
+	}
+}
+
+func TestCache_Decrypt_KeyedByIdentity(t *testing.T) {
+	owner, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("generate identity: %v", err)
+	}
+	other, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("generate identity: %v", err)
+	}
+
+	var encrypted bytes.Buffer
+	if err := EncryptReader(bytes.NewBufferString("secret\n"), &encrypted, []age.Recipient{owner.Recipient()}); err != nil {
+		t.Fatalf("EncryptReader: %v", err)
+	}
+
+	cache := NewCache()
+	if _, err := cache.Decrypt(encrypted.Bytes(), owner); err != nil {
+		t.Fatalf("Decrypt with owner: %v", err)
+	}
+
+	if got, err := cache.Decrypt(encrypted.Bytes(), other); err == nil {
+		t.Errorf("Decrypt with another identity = %q, want an error", got)
+	}
+
+	// The same key read again is a different value but hits the cache.
+	reloaded, err := LoadPrivateKey(owner.String())
+	if err != nil {
+		t.Fatalf("LoadPrivateKey: %v", err)
+	}
+	if _, err := cache.Decrypt(encrypted.Bytes(), reloaded); err != nil {
+		t.Fatalf("Decrypt with reloaded owner: %v", err)
+	}
+	if cache.Len() != 1 {
+		t.Errorf("cache has %d entries, want 1", cache.Len())
+	}
+}
@@ -0,0 +1,66 @@
+package fcrypt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// headerProbeSize is how many bytes are read to detect the format, enough
+// for the armor header after a few blank lines.
+const headerProbeSize = 64
+
+var (
+	// binaryHeader starts every age file written without armor.
+	binaryHeader = []byte("age-encryption.org/v1\n")
+	// armorHeader starts every armored age file.
+	armorHeader = []byte("-----BEGIN AGE ENCRYPTED FILE-----")
+)
+
+// Format is the encoding of age encrypted data.
+type Format int
+
+const (
+	FormatNone    Format = iota // not age encrypted
+	FormatBinary                // age binary format
+	FormatArmored               // PEM-like ASCII armor
+)
+
+// DetectFormat returns the age format data is encoded in, judged by its first
+// bytes only. Leading whitespace before an armored header is ignored, as the
+// armor reader does.
+func DetectFormat(data []byte) Format {
+	switch {
+	case bytes.HasPrefix(data, binaryHeader):
+		return FormatBinary
+	case bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), armorHeader):
+		return FormatArmored
+	default:
+		return FormatNone
+	}
+}
+
+// IsEncrypted reports whether data starts with an age header, binary or
+// armored.
+func IsEncrypted(data []byte) bool {
+	return DetectFormat(data) != FormatNone
+}
+
+// IsEncryptedFile reports whether the file at path is age encrypted. Only the
+// first bytes of the file are read.
+func IsEncryptedFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = f.Close() }()
+
+	header := make([]byte, headerProbeSize)
+	n, err := io.ReadFull(f, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return IsEncrypted(header[:n]), nil
+}
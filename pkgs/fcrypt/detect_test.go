@@ -0,0 +1,64 @@
+package fcrypt
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+)
+
+func TestDetectFormat(t *testing.T) {
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var armored bytes.Buffer
+	if err := EncryptReader(strings.NewReader("secret"), &armored, []age.Recipient{id.Recipient()}); err != nil {
+		t.Fatal(err)
+	}
+	var binary bytes.Buffer
+	w, err := age.Encrypt(&binary, id.Recipient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want Format
+	}{
+		{"armored", armored.Bytes(), FormatArmored},
+		{"armored with leading newline", append([]byte("\n"), armored.Bytes()...), FormatArmored},
+		{"binary", binary.Bytes(), FormatBinary},
+		{"plaintext", []byte("token: s3cret\n"), FormatNone},
+		{"header mentioned later", []byte("# age-encryption.org/v1\n"), FormatNone},
+		{"empty", nil, FormatNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectFormat(tt.data); got != tt.want {
+				t.Errorf("DetectFormat() = %v, want %v", got, tt.want)
+			}
+
+			path := filepath.Join(t.TempDir(), "file")
+			if err := os.WriteFile(path, tt.data, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := IsEncryptedFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := tt.want != FormatNone; got != want {
+				t.Errorf("IsEncryptedFile() = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -1,15 +1,33 @@
 package fcrypt
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"filippo.io/age"
 	"filippo.io/age/armor"
 )
 
+// copyBuffers holds the buffers data is copied through to and from the age
+// streams, so encrypting many small files does not allocate one per file.
+var copyBuffers = sync.Pool{
+	New: func() any {
+		buf := make([]byte, 64*1024)
+		return &buf
+	},
+}
+
+func copyBuffer(dst io.Writer, src io.Reader) (int64, error) {
+	buf := copyBuffers.Get().(*[]byte)
+	defer copyBuffers.Put(buf)
+	return io.CopyBuffer(dst, src, *buf)
+}
+
 // EncryptReader encrypts data from an io.Reader and writes the encrypted result to an io.Writer
 func EncryptReader(r io.Reader, w io.Writer, recipients []age.Recipient) error {
 	armorWriter := armor.NewWriter(w)
@@ -27,7 +45,7 @@ func EncryptReader(r io.Reader, w io.Writer, recipients []age.Recipient) error {
 	}()
 
 	// Copy data from input to encryptor
-	_, err = io.Copy(encryptor, r)
+	_, err = copyBuffer(encryptor, r)
 	if err != nil {
 		return fmt.Errorf("failed to encrypt: %w", err)
 	}
@@ -66,10 +84,16 @@ func EncryptFile(inputPath, outputPath string, recipients []age.Recipient) (err
 		}
 	}()
 
-	if err = EncryptReader(inputFile, tmpFile, recipients); err != nil {
+	// the armor writer emits one short line at a time
+	w := bufio.NewWriter(tmpFile)
+	if err = EncryptReader(inputFile, w, recipients); err != nil {
 		_ = tmpFile.Close()
 		return err
 	}
+	if err = w.Flush(); err != nil {
+		_ = tmpFile.Close()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
 
 	if err = tmpFile.Close(); err != nil {
 		return fmt.Errorf("failed to close temp file: %w", err)
@@ -86,21 +110,42 @@ func EncryptFile(inputPath, outputPath string, recipients []age.Recipient) (err
 	return nil
 }
 
-// DecryptReader decrypts data from an io.Reader and writes the decrypted result to an io.Writer
+// ErrDecrypt is wrapped by every decryption failure so callers can classify
+// them with errors.Is.
+var ErrDecrypt = errors.New("decryption failed")
+
+// ErrNotEncrypted is returned when data to decrypt has no age header.
+var ErrNotEncrypted = errors.New("data is not age encrypted")
+
+// DecryptReader decrypts data from an io.Reader and writes the decrypted result to an io.Writer.
+// Both armored and binary age data are accepted; anything else fails with [ErrNotEncrypted].
 func DecryptReader(r io.Reader, w io.Writer, identity age.Identity) error {
-	// Create armor reader
-	armorReader := armor.NewReader(r)
+	br := bufio.NewReader(r)
+	header, err := br.Peek(headerProbeSize)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: %w", ErrDecrypt, err)
+	}
+
+	var src io.Reader
+	switch DetectFormat(header) {
+	case FormatBinary:
+		src = br
+	case FormatArmored:
+		src = armor.NewReader(br)
+	default:
+		return fmt.Errorf("%w: %w", ErrDecrypt, ErrNotEncrypted)
+	}
 
 	// Create decryptor
-	decryptor, err := age.Decrypt(armorReader, identity)
+	decryptor, err := age.Decrypt(src, identity)
 	if err != nil {
-		return fmt.Errorf("failed to create decryptor: %w", err)
+		return fmt.Errorf("%w: failed to create decryptor: %w", ErrDecrypt, err)
 	}
 
 	// Copy data from decryptor to output
-	_, err = io.Copy(w, decryptor)
+	_, err = copyBuffer(w, decryptor)
 	if err != nil {
-		return fmt.Errorf("failed to decrypt: %w", err)
+		return fmt.Errorf("%w: %w", ErrDecrypt, err)
 	}
 
 	return nil
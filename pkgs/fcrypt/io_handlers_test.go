@@ -1,6 +1,8 @@
 package fcrypt
 
 import (
+	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -99,8 +101,8 @@ func TestDecryptFile_NoOutputOnFailure(t *testing.T) {
 	}
 
 	err = DecryptFile(badInput, outputPath, id)
-	if err == nil {
-		t.Fatal("expected error for non-encrypted input")
+	if !errors.Is(err, ErrNotEncrypted) {
+		t.Fatalf("DecryptFile() error = %v, want ErrNotEncrypted", err)
 	}
 
 	if _, statErr := os.Stat(outputPath); !os.IsNotExist(statErr) {
@@ -114,6 +116,33 @@ func TestDecryptFile_NoOutputOnFailure(t *testing.T) {
 	}
 }
 
+func TestDecryptReader_Binary(t *testing.T) {
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("generate identity: %v", err)
+	}
+
+	var ciphertext bytes.Buffer
+	w, err := age.Encrypt(&ciphertext, id.Recipient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("binary secret")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var plaintext bytes.Buffer
+	if err := DecryptReader(&ciphertext, &plaintext, id); err != nil {
+		t.Fatalf("DecryptReader() error = %v", err)
+	}
+	if plaintext.String() != "binary secret" {
+		t.Errorf("DecryptReader() = %q, want %q", plaintext.String(), "binary secret")
+	}
+}
+
 func TestLoadPublicKeys(t *testing.T) {
 	const numKeys = 3
 
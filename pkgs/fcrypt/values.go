@@ -0,0 +1,381 @@
+package fcrypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+const (
+	valuePrefix = "ENC[age,"
+	valueSuffix = "]"
+
+	// boundVersion marks values whose ciphertext includes the key path they
+	// were encrypted under. Values without it predate key binding and are
+	// still decrypted.
+	boundVersion = "v2,"
+)
+
+// ErrValueMoved is returned when an encrypted value is decrypted under a
+// different key path than the one it was encrypted for, e.g. when values were
+// swapped between keys.
+var ErrValueMoved = errors.New("encrypted value was moved from another key")
+
+// IsEncryptedValue reports whether s is a value produced by [EncryptValues].
+func IsEncryptedValue(s string) bool {
+	return strings.HasPrefix(s, valuePrefix) && strings.HasSuffix(s, valueSuffix)
+}
+
+// EncryptValues encrypts every scalar value in a YAML document while leaving
+// keys and structure readable. Values that are already encrypted are kept
+// as-is, so re-encrypting a file only changes the values that were edited.
+// Key order and comments are preserved; anchors and aliases are not
+// supported.
+//
+// Each value is stored as ENC[age,v2,<base64 ciphertext>]. The ciphertext
+// holds the value's key path with the value, so a value moved to another key
+// fails to decrypt, and decrypts to its original YAML type.
+func EncryptValues(data []byte, recipients []age.Recipient) ([]byte, error) {
+	return encryptValues(data, recipients, nil)
+}
+
+// ReencryptValues is [EncryptValues] for an edited copy of original, a
+// document written by EncryptValues whose values edited holds in plaintext.
+// Values whose plaintext is unchanged keep their ciphertext from original, so
+// only edited values change in the file.
+func ReencryptValues(edited, original []byte, identity age.Identity, recipients []age.Recipient) ([]byte, error) {
+	type sealed struct {
+		plaintext  []byte
+		ciphertext string
+	}
+	previous := map[string]sealed{}
+
+	file, err := parseValues(original)
+	if err != nil {
+		return nil, err
+	}
+	err = walkScalars(file, func(path valuePath, node ast.Node) (ast.Node, error) {
+		s, ok := encryptedString(node)
+		if !ok || !strings.HasPrefix(s, valuePrefix+boundVersion) {
+			return nil, nil // legacy values are re-encrypted with their key path
+		}
+		plaintext, err := openValue(path, s, identity)
+		if err != nil {
+			return nil, err
+		}
+		previous[path.key()] = sealed{plaintext: plaintext, ciphertext: s}
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return encryptValues(edited, recipients, func(path valuePath, plaintext []byte) (string, bool) {
+		p, ok := previous[path.key()]
+		if !ok || !bytes.Equal(p.plaintext, plaintext) {
+			return "", false
+		}
+		return p.ciphertext, true
+	})
+}
+
+func encryptValues(data []byte, recipients []age.Recipient, reuse func(path valuePath, plaintext []byte) (string, bool)) ([]byte, error) {
+	file, err := parseValues(data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = walkScalars(file, func(path valuePath, node ast.Node) (ast.Node, error) {
+		if _, ok := encryptedString(node); ok {
+			return nil, nil
+		}
+
+		plaintext, err := scalarYAML(node)
+		if err != nil {
+			return nil, err
+		}
+
+		ciphertext, ok := "", false
+		if reuse != nil {
+			ciphertext, ok = reuse(path, plaintext)
+		}
+		if !ok {
+			if ciphertext, err = sealValue(path, plaintext, recipients); err != nil {
+				return nil, err
+			}
+		}
+		return yaml.ValueToNode(ciphertext)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return documentBytes(file), nil
+}
+
+// DecryptValues parses a YAML document written by [EncryptValues] and returns
+// it with every encrypted value replaced by its plaintext. Plaintext values are
+// returned unchanged.
+func DecryptValues(data []byte, identity age.Identity) (any, error) {
+	file, err := decryptValues(data, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc any
+	if len(file.Docs) == 0 || file.Docs[0].Body == nil {
+		return doc, nil
+	}
+	if err := yaml.NodeToValue(file.Docs[0].Body, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse decrypted values: %w", err)
+	}
+	return doc, nil
+}
+
+// DecryptValuesDocument is [DecryptValues] returning the document itself, with
+// its key order and comments, for editing. [ReencryptValues] encrypts it
+// again.
+func DecryptValuesDocument(data []byte, identity age.Identity) ([]byte, error) {
+	file, err := decryptValues(data, identity)
+	if err != nil {
+		return nil, err
+	}
+	return documentBytes(file), nil
+}
+
+func decryptValues(data []byte, identity age.Identity) (*ast.File, error) {
+	file, err := parseValues(data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = walkScalars(file, func(path valuePath, node ast.Node) (ast.Node, error) {
+		s, ok := encryptedString(node)
+		if !ok {
+			return nil, nil
+		}
+
+		plaintext, err := openValue(path, s, identity)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := parser.ParseBytes(plaintext, 0)
+		if err != nil || len(value.Docs) != 1 || !isScalar(value.Docs[0].Body) {
+			return nil, fmt.Errorf("%s: failed to parse decrypted value: %w", path, err)
+		}
+		return value.Docs[0].Body, nil
+	})
+	return file, err
+}
+
+// HasPlaintextValues reports whether a YAML document contains scalar values
+// that [EncryptValues] would encrypt.
+func HasPlaintextValues(data []byte) (bool, error) {
+	file, err := parseValues(data)
+	if err != nil {
+		return false, err
+	}
+
+	found := false
+	err = walkScalars(file, func(_ valuePath, node ast.Node) (ast.Node, error) {
+		if _, ok := encryptedString(node); !ok {
+			found = true
+		}
+		return nil, nil
+	})
+	return found, err
+}
+
+func sealValue(path valuePath, plaintext []byte, recipients []age.Recipient) (string, error) {
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, recipients...)
+	if err != nil {
+		return "", fmt.Errorf("failed to create encryptor: %w", err)
+	}
+	if _, err := io.WriteString(w, path.key()+"\n"); err != nil {
+		return "", fmt.Errorf("failed to encrypt: %w", err)
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		return "", fmt.Errorf("failed to encrypt: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("failed to finalize encryption: %w", err)
+	}
+
+	return valuePrefix + boundVersion + base64.StdEncoding.EncodeToString(buf.Bytes()) + valueSuffix, nil
+}
+
+// openValue decrypts s, stored at path, to the YAML of its value.
+func openValue(path valuePath, s string, identity age.Identity) ([]byte, error) {
+	body := strings.TrimSuffix(strings.TrimPrefix(s, valuePrefix), valueSuffix)
+	body, bound := strings.CutPrefix(body, boundVersion)
+
+	ciphertext, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: invalid encrypted value: %w", path, ErrDecrypt, err)
+	}
+
+	r, err := age.Decrypt(bytes.NewReader(ciphertext), identity)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", path, ErrDecrypt, err)
+	}
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", path, ErrDecrypt, err)
+	}
+	if !bound {
+		return plaintext, nil
+	}
+
+	key, value, ok := bytes.Cut(plaintext, []byte("\n"))
+	if !ok || string(key) != path.key() {
+		return nil, fmt.Errorf("%s: %w", path, ErrValueMoved)
+	}
+	return value, nil
+}
+
+// valuePath is the location of a value in a document: string mapping keys
+// and int sequence indexes.
+type valuePath []any
+
+// key returns the path in the form bound into ciphertexts.
+func (p valuePath) key() string {
+	data, _ := json.Marshal([]any(p))
+	return string(data)
+}
+
+func (p valuePath) String() string {
+	var sb strings.Builder
+	for _, seg := range p {
+		switch seg := seg.(type) {
+		case int:
+			sb.WriteString("[" + strconv.Itoa(seg) + "]")
+		default:
+			if sb.Len() > 0 {
+				sb.WriteString(".")
+			}
+			fmt.Fprint(&sb, seg)
+		}
+	}
+	return sb.String()
+}
+
+func parseValues(data []byte) (*ast.File, error) {
+	file, err := parser.ParseBytes(data, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse yaml: %w", err)
+	}
+	return file, nil
+}
+
+// walkScalars calls fn for every non-null scalar in file with its path. When
+// fn returns a node, it replaces the scalar, keeping the scalar's comment.
+func walkScalars(file *ast.File, fn func(path valuePath, node ast.Node) (ast.Node, error)) error {
+	replace := func(path valuePath, node ast.Node, set func(ast.Node) error) error {
+		repl, err := fn(path, node)
+		if err != nil || repl == nil {
+			return err
+		}
+		if comment := node.GetComment(); comment != nil {
+			if err := repl.SetComment(comment); err != nil {
+				return err
+			}
+		}
+		return set(repl)
+	}
+
+	var walk func(path valuePath, node ast.Node) error
+	walkValue := func(path valuePath, node ast.Node, set func(ast.Node) error) error {
+		if isScalar(node) {
+			return replace(path, node, set)
+		}
+		return walk(path, node)
+	}
+	walkMapping := func(path valuePath, mv *ast.MappingValueNode) error {
+		key := mv.Key.String()
+		if s, ok := mv.Key.(*ast.StringNode); ok {
+			key = s.Value
+		}
+		child := append(path[:len(path):len(path)], key)
+		return walkValue(child, mv.Value, mv.Replace)
+	}
+
+	walk = func(path valuePath, node ast.Node) error {
+		switch n := node.(type) {
+		case *ast.MappingNode:
+			for _, mv := range n.Values {
+				if err := walkMapping(path, mv); err != nil {
+					return err
+				}
+			}
+		case *ast.MappingValueNode:
+			return walkMapping(path, n)
+		case *ast.SequenceNode:
+			for i, v := range n.Values {
+				child := append(path[:len(path):len(path)], i)
+				if err := walkValue(child, v, func(repl ast.Node) error { return n.Replace(i, repl) }); err != nil {
+					return err
+				}
+			}
+		case *ast.AnchorNode, *ast.AliasNode:
+			return fmt.Errorf("%s: anchors and aliases are not supported in files with encrypted values", path)
+		}
+		return nil
+	}
+
+	for _, doc := range file.Docs {
+		if doc.Body == nil || isScalar(doc.Body) {
+			continue
+		}
+		if err := walk(nil, doc.Body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// documentBytes renders file with a single trailing newline.
+func documentBytes(file *ast.File) []byte {
+	return []byte(strings.TrimRight(file.String(), "\n") + "\n")
+}
+
+// isScalar reports whether node is a non-null scalar value.
+func isScalar(node ast.Node) bool {
+	switch node.(type) {
+	case nil, *ast.NullNode, *ast.CommentGroupNode,
+		*ast.MappingNode, *ast.MappingValueNode, *ast.SequenceNode,
+		*ast.AnchorNode, *ast.AliasNode:
+		return false
+	}
+	return true
+}
+
+// encryptedString returns the value of node when it is an encrypted value.
+func encryptedString(node ast.Node) (string, bool) {
+	s, ok := node.(*ast.StringNode)
+	if !ok || !IsEncryptedValue(s.Value) {
+		return "", false
+	}
+	return s.Value, true
+}
+
+// scalarYAML returns the YAML of the value of a scalar node, which decrypts
+// back to the same type.
+func scalarYAML(node ast.Node) ([]byte, error) {
+	var value any
+	if err := yaml.NodeToValue(node, &value); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(value)
+}
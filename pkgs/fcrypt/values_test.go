@@ -0,0 +1,209 @@
+package fcrypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+)
+
+func TestEncryptValues_Roundtrip(t *testing.T) {
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("generate identity: %v", err)
+	}
+
+	input := `
+zeta: secret
+alpha:
+  token: abc123
+  port: 8080
+  enabled: true
+  quoted: "42"
+hosts:
+  - one
+  - two
+empty: null
+`
+
+	encrypted, err := EncryptValues([]byte(input), []age.Recipient{id.Recipient()})
+	if err != nil {
+		t.Fatalf("EncryptValues: %v", err)
+	}
+
+	out := string(encrypted)
+	for _, plain := range []string{"secret", "abc123", "8080", "one", "two"} {
+		if strings.Contains(out, plain) {
+			t.Errorf("encrypted output contains plaintext %q:\n%s", plain, out)
+		}
+	}
+	if strings.Index(out, "alpha:") < strings.Index(out, "zeta:") {
+		t.Errorf("expected key order to be kept:\n%s", out)
+	}
+
+	if has, err := HasPlaintextValues(encrypted); err != nil || has {
+		t.Errorf("HasPlaintextValues(encrypted) = %v, %v; want false", has, err)
+	}
+
+	// Re-encrypting leaves existing ciphertexts untouched.
+	again, err := EncryptValues(encrypted, []age.Recipient{id.Recipient()})
+	if err != nil {
+		t.Fatalf("EncryptValues (again): %v", err)
+	}
+	if string(again) != out {
+		t.Errorf("re-encryption changed output:\n%s\nwant:\n%s", again, out)
+	}
+
+	got, err := DecryptValues(encrypted, id)
+	if err != nil {
+		t.Fatalf("DecryptValues: %v", err)
+	}
+
+	want := map[string]any{
+		"zeta": "secret",
+		"alpha": map[string]any{
+			"token":   "abc123",
+			"port":    uint64(8080),
+			"enabled": true,
+			"quoted":  "42",
+		},
+		"hosts": []any{"one", "two"},
+		"empty": nil,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DecryptValues = %#v, want %#v", got, want)
+	}
+}
+
+func TestDecryptValues_WrongIdentity(t *testing.T) {
+	id, _ := age.GenerateX25519Identity()
+	other, _ := age.GenerateX25519Identity()
+
+	encrypted, err := EncryptValues([]byte("key: value\n"), []age.Recipient{id.Recipient()})
+	if err != nil {
+		t.Fatalf("EncryptValues: %v", err)
+	}
+
+	if _, err := DecryptValues(encrypted, other); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("DecryptValues with wrong identity: got %v, want ErrDecrypt", err)
+	}
+}
+
+func TestHasPlaintextValues(t *testing.T) {
+	has, err := HasPlaintextValues([]byte("key: ENC[age,AAAA]\nother: plain\n"))
+	if err != nil {
+		t.Fatalf("HasPlaintextValues: %v", err)
+	}
+	if !has {
+		t.Error("expected plaintext value to be detected")
+	}
+}
+
+func TestEncryptValues_KeepsLayout(t *testing.T) {
+	id, _ := age.GenerateX25519Identity()
+
+	input := `# shared secrets
+zeta: secret # inline
+alpha:
+  # nested comment
+  token: abc123
+`
+
+	encrypted, err := EncryptValues([]byte(input), []age.Recipient{id.Recipient()})
+	if err != nil {
+		t.Fatalf("EncryptValues: %v", err)
+	}
+
+	out := string(encrypted)
+	for _, comment := range []string{"# shared secrets", "# inline", "# nested comment"} {
+		if !strings.Contains(out, comment) {
+			t.Errorf("encrypted output lost comment %q:\n%s", comment, out)
+		}
+	}
+
+	doc, err := DecryptValuesDocument(encrypted, id)
+	if err != nil {
+		t.Fatalf("DecryptValuesDocument: %v", err)
+	}
+	if string(doc) != input {
+		t.Errorf("DecryptValuesDocument =\n%s\nwant:\n%s", doc, input)
+	}
+}
+
+func TestDecryptValues_MovedValue(t *testing.T) {
+	id, _ := age.GenerateX25519Identity()
+
+	encrypted, err := EncryptValues([]byte("admin: hunter2\nguest: guest\n"), []age.Recipient{id.Recipient()})
+	if err != nil {
+		t.Fatalf("EncryptValues: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(encrypted)), "\n")
+	admin := strings.TrimPrefix(lines[0], "admin: ")
+	guest := strings.TrimPrefix(lines[1], "guest: ")
+	swapped := "admin: " + guest + "\nguest: " + admin + "\n"
+
+	if _, err := DecryptValues([]byte(swapped), id); !errors.Is(err, ErrValueMoved) {
+		t.Errorf("DecryptValues with swapped values: got %v, want ErrValueMoved", err)
+	}
+}
+
+func TestDecryptValues_Legacy(t *testing.T) {
+	id, _ := age.GenerateX25519Identity()
+
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, id.Recipient())
+	if err != nil {
+		t.Fatalf("age.Encrypt: %v", err)
+	}
+	_, _ = w.Write([]byte("hunter2"))
+	if err := w.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	data := "password: " + valuePrefix + base64.StdEncoding.EncodeToString(buf.Bytes()) + valueSuffix + "\n"
+
+	got, err := DecryptValues([]byte(data), id)
+	if err != nil {
+		t.Fatalf("DecryptValues: %v", err)
+	}
+	if want := map[string]any{"password": "hunter2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DecryptValues = %#v, want %#v", got, want)
+	}
+}
+
+func TestReencryptValues(t *testing.T) {
+	id, _ := age.GenerateX25519Identity()
+	recipients := []age.Recipient{id.Recipient()}
+
+	original, err := EncryptValues([]byte("kept: one\nedited: two\n"), recipients)
+	if err != nil {
+		t.Fatalf("EncryptValues: %v", err)
+	}
+
+	out, err := ReencryptValues([]byte("kept: one\nedited: three\nadded: four\n"), original, id, recipients)
+	if err != nil {
+		t.Fatalf("ReencryptValues: %v", err)
+	}
+
+	before := strings.Split(string(original), "\n")
+	after := strings.Split(string(out), "\n")
+	if before[0] != after[0] {
+		t.Errorf("unchanged value was re-encrypted:\n%s\nwant:\n%s", after[0], before[0])
+	}
+	if before[1] == after[1] {
+		t.Error("edited value kept its old ciphertext")
+	}
+
+	got, err := DecryptValues(out, id)
+	if err != nil {
+		t.Fatalf("DecryptValues: %v", err)
+	}
+	want := map[string]any{"kept": "one", "edited": "three", "added": "four"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DecryptValues = %#v, want %#v", got, want)
+	}
+}
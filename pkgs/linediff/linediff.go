@@ -0,0 +1,177 @@
+// Package linediff provides a minimal line-oriented diff for displaying changes
+// between small text files such as rendered templates.
+package linediff
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Op rune
+
+const (
+	OpEqual  Op = ' '
+	OpInsert Op = '+'
+	OpDelete Op = '-'
+)
+
+// Line is a single line of diff output.
+type Line struct {
+	Op   Op
+	Text string
+}
+
+func (l Line) String() string {
+	return string(l.Op) + " " + l.Text
+}
+
+// Diff computes the line differences required to turn a into b using a longest
+// common subsequence. It is quadratic in the number of lines and intended for
+// config-sized inputs.
+func Diff(a, b string) []Line {
+	al := splitLines(a)
+	bl := splitLines(b)
+
+	// lcs[i][j] holds the LCS length of al[i:] and bl[j:]
+	lcs := make([][]int, len(al)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bl)+1)
+	}
+	for i := len(al) - 1; i >= 0; i-- {
+		for j := len(bl) - 1; j >= 0; j-- {
+			if al[i] == bl[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]Line, 0, max(len(al), len(bl)))
+	i, j := 0, 0
+	for i < len(al) && j < len(bl) {
+		switch {
+		case al[i] == bl[j]:
+			lines = append(lines, Line{Op: OpEqual, Text: al[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, Line{Op: OpDelete, Text: al[i]})
+			i++
+		default:
+			lines = append(lines, Line{Op: OpInsert, Text: bl[j]})
+			j++
+		}
+	}
+	for ; i < len(al); i++ {
+		lines = append(lines, Line{Op: OpDelete, Text: al[i]})
+	}
+	for ; j < len(bl); j++ {
+		lines = append(lines, Line{Op: OpInsert, Text: bl[j]})
+	}
+
+	return lines
+}
+
+// HasChanges reports whether any line in the diff is an insertion or deletion.
+func HasChanges(lines []Line) bool {
+	for _, l := range lines {
+		if l.Op != OpEqual {
+			return true
+		}
+	}
+	return false
+}
+
+// Format renders the diff with a "+", "-", or " " prefix on each line.
+func Format(lines []Line) string {
+	var sb strings.Builder
+	for _, l := range lines {
+		sb.WriteString(l.String())
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Unified renders the diff in unified format with the given number of context
+// lines around each change, suitable for tools such as delta or diff-so-fancy.
+// It returns "" when there are no changes.
+func Unified(fromName, toName string, lines []Line, context int) string {
+	if !HasChanges(lines) {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+
+	// aLine and bLine hold the 1-based line number of lines[i] in each input
+	aLine := make([]int, len(lines)+1)
+	bLine := make([]int, len(lines)+1)
+	a, b := 1, 1
+	for i, l := range lines {
+		aLine[i], bLine[i] = a, b
+		if l.Op != OpInsert {
+			a++
+		}
+		if l.Op != OpDelete {
+			b++
+		}
+	}
+	aLine[len(lines)], bLine[len(lines)] = a, b
+
+	for i := 0; i < len(lines); {
+		if lines[i].Op == OpEqual {
+			i++
+			continue
+		}
+
+		// Extend the hunk while changes are within 2*context lines of each other
+		start := max(i-context, 0)
+		end := i
+		for end < len(lines) {
+			if lines[end].Op != OpEqual {
+				end++
+				continue
+			}
+			next := end
+			for next < len(lines) && lines[next].Op == OpEqual {
+				next++
+			}
+			if next == len(lines) || next-end > 2*context {
+				end = min(end+context, len(lines))
+				break
+			}
+			end = next
+		}
+
+		aCount := aLine[end] - aLine[start]
+		bCount := bLine[end] - bLine[start]
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(aLine[start], aCount), hunkRange(bLine[start], bCount))
+		for _, l := range lines[start:end] {
+			sb.WriteByte(byte(l.Op))
+			sb.WriteString(l.Text)
+			sb.WriteByte('\n')
+		}
+
+		i = end
+	}
+
+	return sb.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start-- // empty ranges point at the line before the hunk
+	}
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
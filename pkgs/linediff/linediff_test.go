@@ -0,0 +1,97 @@
+package linediff
+
+import (
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want string
+	}{
+		{
+			name: "identical",
+			a:    "one\ntwo\n",
+			b:    "one\ntwo\n",
+			want: "  one\n  two\n",
+		},
+		{
+			name: "insert",
+			a:    "one\nthree",
+			b:    "one\ntwo\nthree",
+			want: "  one\n+ two\n  three\n",
+		},
+		{
+			name: "delete",
+			a:    "one\ntwo\nthree",
+			b:    "one\nthree",
+			want: "  one\n- two\n  three\n",
+		},
+		{
+			name: "replace",
+			a:    "key=old",
+			b:    "key=new",
+			want: "- key=old\n+ key=new\n",
+		},
+		{
+			name: "from empty",
+			a:    "",
+			b:    "one",
+			want: "+ one\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Format(Diff(tt.a, tt.b))
+			if got != tt.want {
+				t.Errorf("Diff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasChanges(t *testing.T) {
+	if HasChanges(Diff("a\nb", "a\nb")) {
+		t.Error("HasChanges() = true for identical input")
+	}
+	if !HasChanges(Diff("a", "b")) {
+		t.Error("HasChanges() = false for differing input")
+	}
+}
+
+func TestUnified(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n"
+
+	want := `--- a
++++ b
+@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+@@ -8,3 +8,4 @@
+ 8
+ 9
+ 10
++11
+`
+	if got := Unified("a", "b", Diff(a, b), 3); got != want {
+		t.Errorf("Unified() =\n%s\nwant:\n%s", got, want)
+	}
+
+	if got := Unified("a", "b", Diff(a, a), 3); got != "" {
+		t.Errorf("Unified() of identical input = %q, want empty", got)
+	}
+
+	wantNew := "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+x\n+y\n"
+	if got := Unified("a", "b", Diff("", "x\ny\n"), 3); got != wantNew {
+		t.Errorf("Unified() of new file =\n%s\nwant:\n%s", got, wantNew)
+	}
+}
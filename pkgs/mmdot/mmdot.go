@@ -0,0 +1,255 @@
+// Package mmdot exposes mmdot's core operations as a Go API so other tools can
+// embed them without shelling out to the CLI.
+//
+// Unlike the CLI, nothing in this package changes the working directory:
+// every path in a loaded [Config] is resolved against the config directory,
+// and each [Client] owns its own template engine and decryption cache. Some
+// state is still shared: secret:// references are fetched once per process,
+// and the installed Homebrew packages and the identity that last opened each
+// vault file are cached in the mmdot state directory.
+//
+//	cfg, err := mmdot.LoadConfig("dotfiles/mmdot.yml")
+//	if err != nil {
+//		return err
+//	}
+//	client := mmdot.New(cfg)
+//	written, err := client.RenderTemplates(ctx, mmdot.WithTags("shell"))
+package mmdot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"sync"
+
+	"github.com/hay-kot/mmdot/internal/core"
+	"github.com/hay-kot/mmdot/internal/generator"
+	"github.com/hay-kot/mmdot/pkgs/fcrypt"
+)
+
+type (
+	Config     = core.ConfigFile
+	Template   = core.Template
+	Script     = core.Script
+	BrewDiff   = core.DiffResult
+	BrewConfig = core.Brews
+	Status     = generator.Status
+
+	// BrewOptions controls caching of the installed Homebrew package list.
+	BrewOptions = core.InstalledBrewsOptions
+)
+
+// Template status values reported by [Client.CheckTemplates].
+const (
+	StatusCurrent = generator.StatusCurrent
+	StatusChanged = generator.StatusChanged
+	StatusMissing = generator.StatusMissing
+)
+
+// LoadConfig reads the config file at path, merging any overlays on top of it
+// in order. Relative and '~' paths inside the config are resolved against the
+// directory containing path.
+func LoadConfig(path string, overlays ...string) (*Config, error) {
+	cfg, err := core.LoadConfig(path, overlays...)
+	if err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Client runs mmdot operations against a loaded config. It is safe for
+// concurrent use.
+type Client struct {
+	cfg *Config
+
+	mu     sync.Mutex // guards engine
+	engine *generator.Engine
+	cache  *fcrypt.Cache
+
+	// Stdout and Stderr receive script output. They default to os.Stdout and
+	// os.Stderr.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// New returns a Client for cfg.
+func New(cfg *Config) *Client {
+	cache := fcrypt.NewCache()
+	return &Client{
+		cfg:    cfg,
+		engine: generator.NewEngine(cfg).WithCache(cache),
+		cache:  cache,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// ClearCache drops the decrypted vault contents the client holds in memory.
+func (c *Client) ClearCache() {
+	c.cache.Clear()
+}
+
+// Config returns the config the client was created with.
+func (c *Client) Config() *Config {
+	return c.cfg
+}
+
+// Filter selects the items an operation applies to. A nil Filter matches
+// everything.
+type Filter func(name string, tags []string) bool
+
+// WithTags matches items that have all of the given tags.
+func WithTags(tags ...string) Filter {
+	return func(_ string, itemTags []string) bool {
+		for _, tag := range tags {
+			if !slices.Contains(itemTags, tag) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// WithNames matches items by name. Scripts are matched by path.
+func WithNames(names ...string) Filter {
+	return func(name string, _ []string) bool {
+		return slices.Contains(names, name)
+	}
+}
+
+func (f Filter) match(name string, tags []string) bool {
+	return f == nil || f(name, tags)
+}
+
+// TemplateStatus reports whether a template's output is up to date.
+type TemplateStatus struct {
+	Template Template
+	Status   Status
+	Rendered []byte
+	Existing []byte
+}
+
+// CheckTemplates renders the matching templates in memory and compares them
+// with their outputs without writing anything.
+func (c *Client) CheckTemplates(ctx context.Context, filter Filter) ([]TemplateStatus, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var results []TemplateStatus
+	for _, tmpl := range c.cfg.Templates {
+		if !filter.match(tmpl.Name, tmpl.Tags) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+
+		res, err := c.engine.Check(ctx, tmpl)
+		if err != nil {
+			return results, fmt.Errorf("template %s: %w", tmpl.Name, err)
+		}
+
+		results = append(results, TemplateStatus{
+			Template: tmpl,
+			Status:   res.Status,
+			Rendered: res.Rendered,
+			Existing: res.Existing,
+		})
+	}
+
+	return results, nil
+}
+
+// RenderTemplates renders the matching templates to their outputs and returns
+// the paths written. Templates whose skip_if_exists or skip_if condition
+// matches are not rendered, nor are existing outputs of no_clobber templates.
+func (c *Client) RenderTemplates(ctx context.Context, filter Filter) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var written []string
+	for _, tmpl := range c.cfg.Templates {
+		if !filter.match(tmpl.Name, tmpl.Tags) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+
+		reason, err := tmpl.SkipReason(ctx, c.shell(), c.cfg.ConfigDir, false)
+		if err != nil {
+			return written, fmt.Errorf("template %s: %w", tmpl.Name, err)
+		}
+		if reason != "" {
+			continue
+		}
+
+		if err := c.engine.RenderTemplate(ctx, tmpl); err != nil {
+			return written, fmt.Errorf("template %s: %w", tmpl.Name, err)
+		}
+		written = append(written, tmpl.Output)
+	}
+
+	return written, nil
+}
+
+// RunScripts runs the matching scripts in order with the configured shell from
+// the config directory, stopping at the first failure. Privileged scripts run
+// through sudo or doas without prompting, so they fail unless credentials are
+// already cached.
+func (c *Client) RunScripts(ctx context.Context, filter Filter) error {
+	shell := c.shell()
+	for _, script := range c.cfg.Exec.Scripts {
+		if !filter.match(script.Path, script.Tags) {
+			continue
+		}
+
+		cmd := exec.CommandContext(ctx, shell, script.Path)
+		if script.Privileged {
+			esc, err := core.FindEscalation()
+			if err != nil {
+				return fmt.Errorf("script %s: %w", script.Path, err)
+			}
+			cmd = esc.NonInteractive().Command(ctx, shell, script.Path)
+		}
+		cmd.Dir = c.cfg.ConfigDir
+		cmd.Env = c.cfg.Exec.ScriptEnv(os.Environ())
+		cmd.Stdout = c.Stdout
+		cmd.Stderr = c.Stderr
+
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("script %s: %w", script.Path, err)
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) shell() string {
+	if c.cfg.Exec.Shell != "" {
+		return c.cfg.Exec.Shell
+	}
+	return "/bin/sh"
+}
+
+// DiffBrew compares the named brew config, with includes merged, against the
+// packages installed on the machine.
+func (c *Client) DiffBrew(ctx context.Context, name string, opts BrewOptions) (*BrewDiff, error) {
+	brews := c.cfg.Brews.Get(name)
+	if brews == nil {
+		return nil, fmt.Errorf("brew config %q not found", name)
+	}
+	if errs := c.cfg.Brews.ValidateIncludes(name); len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	installed, err := core.LoadInstalledBrews(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return brews.DiffInstalled(installed), ctx.Err()
+}
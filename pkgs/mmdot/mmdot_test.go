@@ -0,0 +1,92 @@
+package mmdot
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClient_NoChdir(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "mmdot.yml")
+
+	config := `
+exec:
+  shell: /bin/sh
+  scripts:
+    - path: hello.sh
+      tags: [greet]
+templates:
+  - name: greeting
+    tags: [greet]
+    template: "hello {{ .Name }}"
+    output: out/greeting.txt
+    vars:
+      Name: world
+  - name: other
+    template: other
+    output: out/other.txt
+`
+	if err := os.WriteFile(cfgPath, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "hello.sh"), []byte("echo hi from $PWD\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "other"), []byte("other"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(cfgPath)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	client := New(cfg)
+	ctx := context.Background()
+
+	statuses, err := client.CheckTemplates(ctx, nil)
+	if err != nil {
+		t.Fatalf("CheckTemplates: %v", err)
+	}
+	if len(statuses) != 2 || statuses[0].Status != StatusMissing {
+		t.Fatalf("CheckTemplates = %+v, want 2 missing", statuses)
+	}
+
+	written, err := client.RenderTemplates(ctx, WithTags("greet"))
+	if err != nil {
+		t.Fatalf("RenderTemplates: %v", err)
+	}
+	want := filepath.Join(dir, "out", "greeting.txt")
+	if len(written) != 1 || written[0] != want {
+		t.Fatalf("RenderTemplates wrote %v, want [%s]", written, want)
+	}
+	got, err := os.ReadFile(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "hello world" {
+		t.Errorf("greeting = %q, want %q", got, "hello world")
+	}
+
+	var out bytes.Buffer
+	client.Stdout = &out
+	if err := client.RunScripts(ctx, WithTags("greet")); err != nil {
+		t.Fatalf("RunScripts: %v", err)
+	}
+	if !strings.Contains(out.String(), "hi from") {
+		t.Errorf("script output = %q", out.String())
+	}
+
+	if now, _ := os.Getwd(); now != wd {
+		t.Errorf("working directory changed from %s to %s", wd, now)
+	}
+}
@@ -41,6 +41,10 @@ func List(title string, items []string) {
 	ConsolePrinter.List(title, items)
 }
 
+func Table(title string, headers []string, rows [][]string) {
+	ConsolePrinter.Table(title, headers, rows)
+}
+
 func LineBreak() {
 	ConsolePrinter.LineBreak()
 }
@@ -0,0 +1,46 @@
+package printer
+
+import "errors"
+
+// HintError is an error that [Printer.FatalError] prints with a title and
+// hints on how to fix it. It is found anywhere in the error chain, so the
+// error can be wrapped with more context on the way up.
+type HintError struct {
+	Title string   // Headline of the error box, a generic one when empty
+	Err   error    // Printed as the detail
+	Hints []string // Suggested fixes, one per line
+}
+
+func (e *HintError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *HintError) Unwrap() error {
+	return e.Err
+}
+
+// WithHints returns err with hints printed below it, or nil when err is nil.
+func WithHints(err error, hints ...string) error {
+	if err == nil {
+		return nil
+	}
+	return &HintError{Err: err, Hints: hints}
+}
+
+// WithTitle returns err with a title and hints printed with it, or nil when
+// err is nil.
+func WithTitle(title string, err error, hints ...string) error {
+	if err == nil {
+		return nil
+	}
+	return &HintError{Title: title, Err: err, Hints: hints}
+}
+
+// hintsOf returns the title and hints of the outermost [HintError] in err.
+func hintsOf(err error) (string, []string) {
+	var hintErr *HintError
+	if !errors.As(err, &hintErr) {
+		return "", nil
+	}
+	return hintErr.Title, hintErr.Hints
+}
@@ -0,0 +1,52 @@
+package printer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFatalError_Hints(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want []string
+	}{
+		{
+			name: "plain",
+			err:  errors.New("boom"),
+			want: []string{"An error occurred", "boom"},
+		},
+		{
+			name: "hints",
+			err:  WithHints(errors.New("boom"), "try this", "or that"),
+			want: []string{"An error occurred", "boom", "→ try this", "→ or that"},
+		},
+		{
+			name: "wrapped title",
+			err:  fmt.Errorf("context: %w", WithTitle("Thing not found", errors.New("boom"), "make one")),
+			want: []string{"Thing not found", "context: boom", "→ make one"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			New(&buf).FatalError(tt.err)
+
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("output missing %q:\n%s", want, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestWithHints_Nil(t *testing.T) {
+	if err := WithHints(nil, "hint"); err != nil {
+		t.Errorf("WithHints(nil) = %v, want nil", err)
+	}
+}
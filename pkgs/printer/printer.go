@@ -6,6 +6,9 @@ import (
 	"io"
 	"strings"
 
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/hay-kot/mmdot/pkgs/redact"
 	"github.com/hay-kot/mmdot/pkgs/styles"
 )
 
@@ -60,7 +63,8 @@ func (c *Printer) write(s string) {
 // should be displayed to the user.
 //
 // If the error implements the ConsoleOutput interface, the ConsoleOutput method
-// will be called to get the error output.
+// will be called to get the error output. The title and hints of a [HintError]
+// in the chain are printed in the box.
 func (c *Printer) FatalError(err error) {
 	bldr := &strings.Builder{}
 
@@ -71,8 +75,13 @@ func (c *Printer) FatalError(err error) {
 		return
 	}
 
+	title, hints := hintsOf(err)
+	if title == "" {
+		title = "An error occurred"
+	}
+
 	// Create error box with red border
-	errorBox := styles.ErrorBox("An error occurred", err.Error())
+	errorBox := styles.ErrorBox(title, redact.String(err.Error()), hints...)
 	bldr.WriteString(errorBox)
 	bldr.WriteString("\n")
 
@@ -177,6 +186,45 @@ func (c *Printer) List(title string, items []string) {
 	c.write(bldr.String())
 }
 
+// Table prints rows under a title with aligned columns and a header rule.
+//
+// Example:
+//
+//	Some Title
+//	  NAME   STATUS
+//	 ───────────────
+//	  one    ok
+//	  two    failed
+func (c *Printer) Table(title string, headers []string, rows [][]string) {
+	bldr := strings.Builder{}
+
+	bldr.WriteString(styles.Padding(styles.Bold(c.base(title))))
+	bldr.WriteString("\n")
+
+	t := table.New().
+		Border(lipgloss.NormalBorder()).
+		BorderTop(false).
+		BorderBottom(false).
+		BorderLeft(false).
+		BorderRight(false).
+		BorderColumn(false).
+		BorderStyle(lipgloss.NewStyle().Foreground(lipgloss.Color(styles.ColorSubtle))).
+		StyleFunc(func(row, _ int) lipgloss.Style {
+			style := lipgloss.NewStyle().PaddingLeft(2)
+			if row == table.HeaderRow {
+				return style.Bold(true)
+			}
+			return style
+		}).
+		Headers(headers...).
+		Rows(rows...)
+
+	bldr.WriteString(t.Render())
+	bldr.WriteString("\n")
+
+	c.write(bldr.String())
+}
+
 func (c *Printer) LineBreak() {
 	c.write("\n")
 }
@@ -0,0 +1,143 @@
+// Package redact replaces secret values in logs and printed output with
+// «redacted:key», so debug output can be shared without leaking the contents
+// of vault files.
+package redact
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// MinLength is the length below which values are not redacted. Replacing
+// every "true" or "22" in the output would make it unreadable while hiding
+// next to nothing.
+const MinLength = 4
+
+// Reveal disables redaction. It is set by the global --reveal-secrets flag.
+var Reveal bool
+
+// Default holds the secrets registered while loading the config.
+var Default = &Redactor{}
+
+// Redactor replaces registered secret values with a placeholder naming the
+// key they were read from. It is safe for concurrent use.
+type Redactor struct {
+	mu       sync.Mutex
+	secrets  map[string]string // value to key
+	replacer *strings.Replacer // rebuilt after Add, nil when stale
+}
+
+// Add registers value, read from key, as a secret. Each line of a multi-line
+// value is also registered so values printed line by line are caught.
+func (r *Redactor) Add(key, value string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.add(key, value)
+	if strings.Contains(value, "\n") {
+		for line := range strings.SplitSeq(value, "\n") {
+			r.add(key, strings.TrimSpace(line))
+		}
+	}
+}
+
+func (r *Redactor) add(key, value string) {
+	if len(value) < MinLength {
+		return
+	}
+	if r.secrets == nil {
+		r.secrets = map[string]string{}
+	}
+	if _, ok := r.secrets[value]; !ok {
+		r.secrets[value] = key
+		r.replacer = nil
+	}
+}
+
+// AddVars registers every scalar in vars, decoded from a vault file, keyed
+// by its dotted path.
+func (r *Redactor) AddVars(vars map[string]any) {
+	for key, value := range vars {
+		r.addValue(key, value)
+	}
+}
+
+func (r *Redactor) addValue(key string, value any) {
+	switch v := value.(type) {
+	case nil:
+	case map[string]any:
+		for k, sub := range v {
+			r.addValue(key+"."+k, sub)
+		}
+	case []any:
+		for i, sub := range v {
+			r.addValue(fmt.Sprintf("%s.%d", key, i), sub)
+		}
+	default:
+		r.Add(key, fmt.Sprint(v))
+	}
+}
+
+// String returns s with every registered secret replaced, or s unchanged
+// when [Reveal] is set.
+func (r *Redactor) String(s string) string {
+	if Reveal {
+		return s
+	}
+
+	r.mu.Lock()
+	if r.replacer == nil && len(r.secrets) > 0 {
+		// longest first so a secret containing another is replaced whole
+		values := slices.SortedFunc(maps.Keys(r.secrets), func(a, b string) int {
+			return cmp.Or(cmp.Compare(len(b), len(a)), cmp.Compare(a, b))
+		})
+		oldnew := make([]string, 0, 2*len(values))
+		for _, value := range values {
+			oldnew = append(oldnew, value, "«redacted:"+r.secrets[value]+"»")
+		}
+		r.replacer = strings.NewReplacer(oldnew...)
+	}
+	replacer := r.replacer
+	r.mu.Unlock()
+
+	if replacer == nil {
+		return s
+	}
+	return replacer.Replace(s)
+}
+
+// Writer returns a writer that redacts each write before passing it to w.
+// Secrets split across two writes are not caught, so it suits writers such
+// as loggers that write whole lines at once.
+func (r *Redactor) Writer(w io.Writer) io.Writer {
+	return writer{r: r, w: w}
+}
+
+type writer struct {
+	r *Redactor
+	w io.Writer
+}
+
+func (w writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, w.r.String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Add registers a secret with [Default].
+func Add(key, value string) { Default.Add(key, value) }
+
+// AddVars registers the values of a vault file with [Default].
+func AddVars(vars map[string]any) { Default.AddVars(vars) }
+
+// String redacts s with [Default].
+func String(s string) string { return Default.String(s) }
+
+// Writer redacts writes to w with [Default].
+func Writer(w io.Writer) io.Writer { return Default.Writer(w) }
@@ -0,0 +1,74 @@
+package redact
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRedactor_String(t *testing.T) {
+	r := &Redactor{}
+	r.Add("token", "s3cr3t-token")
+	r.Add("short", "abc")
+	r.Add("long", "s3cr3t-token-extended")
+	r.Add("key", "-----BEGIN KEY-----\nQUJDREVGR0g=\n-----END KEY-----")
+	r.AddVars(map[string]any{
+		"db":    map[string]any{"password": "hunter22"},
+		"ports": []any{8080, "p4ssw0rd"},
+		"empty": nil,
+	})
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "no secrets", in: "hello world", want: "hello world"},
+		{name: "value", in: "token=s3cr3t-token", want: "token=«redacted:token»"},
+		{name: "short values kept", in: "abc", want: "abc"},
+		{name: "longest first", in: "s3cr3t-token-extended", want: "«redacted:long»"},
+		{name: "nested map", in: "pw: hunter22", want: "pw: «redacted:db.password»"},
+		{name: "list", in: "8080 p4ssw0rd", want: "«redacted:ports.0» «redacted:ports.1»"},
+		{name: "single line of multi-line value", in: "  QUJDREVGR0g=", want: "  «redacted:key»"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.String(tt.in); got != tt.want {
+				t.Errorf("String(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactor_Reveal(t *testing.T) {
+	r := &Redactor{}
+	r.Add("token", "s3cr3t-token")
+
+	Reveal = true
+	t.Cleanup(func() { Reveal = false })
+
+	if got := r.String("s3cr3t-token"); got != "s3cr3t-token" {
+		t.Errorf("String() = %q, want the value with Reveal set", got)
+	}
+}
+
+func TestRedactor_Writer(t *testing.T) {
+	r := &Redactor{}
+	var buf bytes.Buffer
+	w := r.Writer(&buf)
+
+	// secrets added after the writer was created are redacted
+	r.Add("token", "s3cr3t-token")
+
+	in := []byte("debug token=s3cr3t-token\n")
+	n, err := w.Write(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(in) {
+		t.Errorf("Write() = %d, want %d", n, len(in))
+	}
+	if got, want := buf.String(), "debug token=«redacted:token»\n"; got != want {
+		t.Errorf("wrote %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,160 @@
+// Package sigverify verifies detached signatures made with minisign or with
+// a cosign key pair (cosign sign-blob --key). Keyless cosign signatures,
+// which depend on certificates and a transparency log, are not supported.
+package sigverify
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// ErrInvalidSignature is returned when a signature does not verify.
+var ErrInvalidSignature = errors.New("signature does not match")
+
+// PublicKey verifies detached signatures over data.
+type PublicKey interface {
+	Verify(data, sig []byte) error
+}
+
+// ParseMinisignKey parses a minisign public key: the base64 line of a
+// minisign.pub file, or the whole file including its comment line.
+func ParseMinisignKey(s string) (PublicKey, error) {
+	raw, err := decodeLine(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid minisign public key: %w", err)
+	}
+	if len(raw) != 2+8+ed25519.PublicKeySize || string(raw[:2]) != "Ed" {
+		return nil, errors.New("invalid minisign public key: not an Ed25519 key")
+	}
+
+	var key minisignKey
+	copy(key.id[:], raw[2:10])
+	key.pub = ed25519.PublicKey(raw[10:])
+	return key, nil
+}
+
+type minisignKey struct {
+	id  [8]byte
+	pub ed25519.PublicKey
+}
+
+// Verify checks a .minisig file, including its trusted comment.
+func (k minisignKey) Verify(data, sig []byte) error {
+	var lines []string
+	for line := range strings.Lines(string(sig)) {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "untrusted comment:") {
+		return errors.New("invalid minisign signature: expected 4 lines")
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(raw) != 2+8+ed25519.SignatureSize {
+		return errors.New("invalid minisign signature")
+	}
+	if !bytes.Equal(raw[2:10], k.id[:]) {
+		return fmt.Errorf("minisign signature was made with key %X, not %X", raw[2:10], k.id[:])
+	}
+	signature := raw[10:]
+
+	message := data
+	switch string(raw[:2]) {
+	case "Ed":
+	case "ED":
+		sum := blake2b.Sum512(data)
+		message = sum[:]
+	default:
+		return fmt.Errorf("invalid minisign signature: unknown algorithm %q", raw[:2])
+	}
+	if !ed25519.Verify(k.pub, message, signature) {
+		return ErrInvalidSignature
+	}
+
+	comment, ok := strings.CutPrefix(lines[2], "trusted comment: ")
+	if !ok {
+		return errors.New("invalid minisign signature: missing trusted comment")
+	}
+	global, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return errors.New("invalid minisign signature: invalid trusted comment signature")
+	}
+	if !ed25519.Verify(k.pub, append(bytes.Clone(signature), comment...), global) {
+		return fmt.Errorf("%w: trusted comment", ErrInvalidSignature)
+	}
+	return nil
+}
+
+// ParseCosignKey parses a PEM encoded cosign public key (cosign.pub). ECDSA,
+// Ed25519, and RSA keys are supported.
+func ParseCosignKey(s string) (PublicKey, error) {
+	block, _ := pem.Decode([]byte(strings.TrimSpace(s)))
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, errors.New("invalid cosign public key: expected a PEM PUBLIC KEY block")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cosign public key: %w", err)
+	}
+
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey, ed25519.PublicKey, *rsa.PublicKey:
+		return cosignKey{pub: pub}, nil
+	}
+	return nil, fmt.Errorf("invalid cosign public key: unsupported key type %T", pub)
+}
+
+type cosignKey struct {
+	pub crypto.PublicKey
+}
+
+// Verify checks a base64 signature as written by cosign sign-blob.
+func (k cosignKey) Verify(data, sig []byte) error {
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil {
+		return fmt.Errorf("invalid cosign signature: %w", err)
+	}
+
+	digest := sha256.Sum256(data)
+	ok := false
+	switch pub := k.pub.(type) {
+	case *ecdsa.PublicKey:
+		ok = ecdsa.VerifyASN1(pub, digest[:], signature)
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(pub, data, signature)
+	case *rsa.PublicKey:
+		ok = rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) == nil
+	}
+	if !ok {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// decodeLine decodes the last non-empty line of s that is not a comment.
+func decodeLine(s string) ([]byte, error) {
+	var last string
+	for line := range strings.Lines(s) {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "untrusted comment:") {
+			last = line
+		}
+	}
+	if last == "" {
+		return nil, errors.New("empty")
+	}
+	return base64.StdEncoding.DecodeString(last)
+}
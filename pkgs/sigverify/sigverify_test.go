@@ -0,0 +1,118 @@
+package sigverify
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// minisign returns a minisign.pub file and a signing function producing
+// .minisig files, prehashed when algorithm is "ED".
+func minisign(t *testing.T, algorithm string) (string, func(data []byte, comment string) []byte) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := []byte("12345678")
+
+	key := base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), id...), pub...))
+	sign := func(data []byte, comment string) []byte {
+		message := data
+		if algorithm == "ED" {
+			sum := blake2b.Sum512(data)
+			message = sum[:]
+		}
+		sig := ed25519.Sign(priv, message)
+		global := ed25519.Sign(priv, append(append([]byte{}, sig...), comment...))
+
+		return []byte("untrusted comment: signature from minisign secret key\n" +
+			base64.StdEncoding.EncodeToString(append(append([]byte(algorithm), id...), sig...)) + "\n" +
+			"trusted comment: " + comment + "\n" +
+			base64.StdEncoding.EncodeToString(global) + "\n")
+	}
+	return "untrusted comment: minisign public key 3837363534333231\n" + key + "\n", sign
+}
+
+func TestMinisign(t *testing.T) {
+	data := []byte("release asset")
+
+	for _, algorithm := range []string{"Ed", "ED"} {
+		t.Run(algorithm, func(t *testing.T) {
+			pubFile, sign := minisign(t, algorithm)
+			key, err := ParseMinisignKey(pubFile)
+			if err != nil {
+				t.Fatalf("ParseMinisignKey() error = %v", err)
+			}
+
+			if err := key.Verify(data, sign(data, "timestamp:1")); err != nil {
+				t.Errorf("Verify() error = %v", err)
+			}
+			if err := key.Verify([]byte("tampered"), sign(data, "timestamp:1")); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Verify(tampered) error = %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+
+	t.Run("other key", func(t *testing.T) {
+		pubFile, _ := minisign(t, "Ed")
+		_, sign := minisign(t, "Ed")
+		key, err := ParseMinisignKey(pubFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := key.Verify(data, sign(data, "c")); err == nil {
+			t.Error("Verify() with another key's signature succeeded")
+		}
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		if _, err := ParseMinisignKey("RWQ="); err == nil {
+			t.Error("ParseMinisignKey() error = nil, want error")
+		}
+	})
+}
+
+func TestCosign(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	data := []byte("checksums")
+	digest := sha256.Sum256(data)
+	sig, err := ecdsa.SignASN1(rand.Reader, priv, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := []byte(base64.StdEncoding.EncodeToString(sig) + "\n")
+
+	key, err := ParseCosignKey(pubPEM)
+	if err != nil {
+		t.Fatalf("ParseCosignKey() error = %v", err)
+	}
+	if err := key.Verify(data, encoded); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	if err := key.Verify([]byte("tampered"), encoded); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify(tampered) error = %v, want ErrInvalidSignature", err)
+	}
+
+	if _, err := ParseCosignKey("not pem"); err == nil {
+		t.Error("ParseCosignKey() error = nil, want error")
+	}
+}
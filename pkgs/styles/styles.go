@@ -31,16 +31,23 @@ var (
 	Subtle  = lipgloss.NewStyle().Foreground(lipgloss.Color(ColorSubtle)).PaddingLeft(1).Render
 )
 
-// ErrorBox creates a bordered error box with title and message
-func ErrorBox(title, message string) string {
+// ErrorBox creates a bordered error box with title and message, followed by
+// hints on how to resolve the error.
+func ErrorBox(title, message string, hints ...string) string {
 	redStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorError))
 	subtleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ColorSubtle))
 
 	lines := []string{
 		redStyle.Render("╭ " + title),
 		redStyle.Render("│") + " " + subtleStyle.Render(message),
-		redStyle.Render("╵"),
 	}
+	if len(hints) > 0 {
+		lines = append(lines, redStyle.Render("│"))
+		for _, hint := range hints {
+			lines = append(lines, redStyle.Render("│")+" → "+hint)
+		}
+	}
+	lines = append(lines, redStyle.Render("╵"))
 
 	return lipgloss.JoinVertical(lipgloss.Left, lines...)
 }
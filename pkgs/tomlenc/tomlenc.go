@@ -0,0 +1,258 @@
+// Package tomlenc encodes maps, slices, and scalars decoded from YAML or JSON
+// as TOML. It covers the value shapes template variables take, not arbitrary
+// Go structs.
+package tomlenc
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var bareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Marshal encodes v, a map with string keys, as a TOML document. Keys are
+// sorted, plain values come before tables, and nil values are left out since
+// TOML has no null.
+func Marshal(v any) ([]byte, error) {
+	table, ok := asTable(reflect.ValueOf(v))
+	if !ok {
+		return nil, fmt.Errorf("toml: top level value must be a map with string keys, got %T", v)
+	}
+
+	var sb strings.Builder
+	if err := writeTable(&sb, nil, table); err != nil {
+		return nil, err
+	}
+	return []byte(strings.TrimPrefix(sb.String(), "\n")), nil
+}
+
+// writeTable writes the key/value pairs of table, then its sub-tables and
+// arrays of tables under their dotted path.
+func writeTable(sb *strings.Builder, path []string, table map[string]reflect.Value) error {
+	var tables, arrays []string
+	for _, key := range sortedKeys(table) {
+		value := table[key]
+		if _, ok := asTable(value); ok {
+			tables = append(tables, key)
+			continue
+		}
+		if isArrayOfTables(value) {
+			arrays = append(arrays, key)
+			continue
+		}
+
+		s, err := encodeValue(value)
+		if err != nil {
+			return fmt.Errorf("toml: %s: %w", encodePath(append(slices.Clone(path), key)), err)
+		}
+		if s == "" {
+			continue
+		}
+		sb.WriteString(encodeKey(key) + " = " + s + "\n")
+	}
+
+	for _, key := range tables {
+		sub, _ := asTable(table[key])
+		subPath := append(slices.Clone(path), key)
+		sb.WriteString("\n[" + encodePath(subPath) + "]\n")
+		if err := writeTable(sb, subPath, sub); err != nil {
+			return err
+		}
+	}
+
+	for _, key := range arrays {
+		value := elem(table[key])
+		subPath := append(slices.Clone(path), key)
+		for i := range value.Len() {
+			sub, _ := asTable(value.Index(i))
+			sb.WriteString("\n[[" + encodePath(subPath) + "]]\n")
+			if err := writeTable(sb, subPath, sub); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// encodeValue returns the inline TOML form of v, or "" for nil.
+func encodeValue(v reflect.Value) (string, error) {
+	v = elem(v)
+	if !v.IsValid() {
+		return "", nil
+	}
+
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339Nano), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return quote(v.String()), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return encodeFloat(v.Float()), nil
+	case reflect.Slice, reflect.Array:
+		items := make([]string, 0, v.Len())
+		for i := range v.Len() {
+			s, err := encodeValue(v.Index(i))
+			if err != nil {
+				return "", err
+			}
+			if s == "" {
+				return "", fmt.Errorf("arrays cannot hold null")
+			}
+			items = append(items, s)
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case reflect.Map:
+		table, ok := asTable(v)
+		if !ok {
+			return "", fmt.Errorf("map keys must be strings, got %s", v.Type().Key())
+		}
+		pairs := make([]string, 0, len(table))
+		for _, key := range sortedKeys(table) {
+			s, err := encodeValue(table[key])
+			if err != nil {
+				return "", err
+			}
+			if s != "" {
+				pairs = append(pairs, encodeKey(key)+" = "+s)
+			}
+		}
+		if len(pairs) == 0 {
+			return "{}", nil
+		}
+		return "{ " + strings.Join(pairs, ", ") + " }", nil
+	}
+	return "", fmt.Errorf("unsupported type %s", v.Type())
+}
+
+func encodeFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "nan"
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eEn") {
+		s += ".0" // keep the value a float when read back
+	}
+	return s
+}
+
+func encodeKey(key string) string {
+	if bareKey.MatchString(key) {
+		return key
+	}
+	return quote(key)
+}
+
+// quote returns s as a TOML basic string. strconv.Quote is not used since
+// TOML has no \x or \a escapes.
+func quote(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\r':
+			sb.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&sb, `\u%04X`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+func encodePath(path []string) string {
+	keys := make([]string, len(path))
+	for i, key := range path {
+		keys[i] = encodeKey(key)
+	}
+	return strings.Join(keys, ".")
+}
+
+// asTable returns the entries of v when it is a map with string keys.
+func asTable(v reflect.Value) (map[string]reflect.Value, bool) {
+	v = elem(v)
+	if !v.IsValid() || v.Kind() != reflect.Map {
+		return nil, false
+	}
+
+	table := make(map[string]reflect.Value, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key := elem(iter.Key())
+		if key.Kind() != reflect.String {
+			return nil, false
+		}
+		table[key.String()] = iter.Value()
+	}
+	return table, true
+}
+
+// isArrayOfTables reports whether v is a non-empty list holding only tables,
+// written as [[key]] sections rather than inline.
+func isArrayOfTables(v reflect.Value) bool {
+	v = elem(v)
+	if !v.IsValid() || (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || v.Len() == 0 {
+		return false
+	}
+	for i := range v.Len() {
+		if _, ok := asTable(v.Index(i)); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// elem unwraps interfaces and pointers, returning the zero Value for nil.
+func elem(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func sortedKeys(table map[string]reflect.Value) []string {
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
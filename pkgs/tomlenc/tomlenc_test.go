@@ -0,0 +1,139 @@
+package tomlenc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		name string
+		in   any
+		want string
+	}{
+		{
+			name: "scalars",
+			in: map[string]any{
+				"name":    "starship",
+				"enabled": true,
+				"count":   3,
+				"ratio":   0.5,
+				"whole":   2.0,
+				"missing": nil,
+			},
+			want: `count = 3
+enabled = true
+name = "starship"
+ratio = 0.5
+whole = 2.0
+`,
+		},
+		{
+			name: "tables after values",
+			in: map[string]any{
+				"format": "$all",
+				"git_branch": map[string]any{
+					"symbol": " ",
+					"style":  "bold purple",
+				},
+				"aws": map[string]any{"disabled": true},
+			},
+			want: `format = "$all"
+
+[aws]
+disabled = true
+
+[git_branch]
+style = "bold purple"
+symbol = " "
+`,
+		},
+		{
+			name: "nested tables and quoted keys",
+			in: map[string]any{
+				"a": map[string]any{
+					"b":      map[string]any{"c": 1},
+					"my key": "x",
+				},
+			},
+			want: `
+[a]
+"my key" = "x"
+
+[a.b]
+c = 1
+`[1:],
+		},
+		{
+			name: "arrays",
+			in: map[string]any{
+				"list":  []any{"a", "b"},
+				"mixed": []any{1, map[string]any{"k": "v"}},
+				"empty": []any{},
+			},
+			want: `empty = []
+list = ["a", "b"]
+mixed = [1, { k = "v" }]
+`,
+		},
+		{
+			name: "array of tables",
+			in: map[string]any{
+				"servers": []any{
+					map[string]any{"name": "one"},
+					map[string]any{"name": "two"},
+				},
+			},
+			want: `[[servers]]
+name = "one"
+
+[[servers]]
+name = "two"
+`,
+		},
+		{
+			name: "escapes",
+			in: map[string]any{
+				"s": "quote \" backslash \\ newline \n bell \a",
+			},
+			want: `s = "quote \" backslash \\ newline \n bell \u0007"
+`,
+		},
+		{
+			name: "special floats",
+			in:   map[string]any{"inf": math.Inf(1), "nan": math.NaN()},
+			want: "inf = inf\nnan = nan\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Marshal(tt.in)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Marshal() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshal_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   any
+	}{
+		{name: "not a map", in: []any{1}},
+		{name: "null in array", in: map[string]any{"a": []any{1, nil}}},
+		{name: "non string keys", in: map[string]any{"a": map[int]any{1: "x"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Marshal(tt.in); err == nil {
+				t.Error("Marshal() error = nil, want an error")
+			}
+		})
+	}
+}